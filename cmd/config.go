@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/searge/quokka/internal/config"
+	"github.com/searge/quokka/pkg/display"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect application configuration",
+}
+
+var configCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Load and validate configuration from the environment",
+	Args:  cobra.NoArgs,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		cfg, err := config.FromEnv()
+		if err != nil {
+			fmt.Println(display.Error(err.Error()))
+			return err
+		}
+
+		fmt.Println(renderConfigSummary(cfg))
+
+		if err := cfg.Validate(); err != nil {
+			fmt.Println(renderConfigProblems(err))
+			return errors.New("configuration is invalid")
+		}
+
+		fmt.Println(display.Success("configuration is valid"))
+		return nil
+	},
+}
+
+// renderConfigSummary lists the effective settings, hiding credentials.
+// Pure function.
+func renderConfigSummary(cfg config.Config) string {
+	return strings.Join([]string{
+		display.Header("Configuration"),
+		display.KeyValue("Log level", cfg.LogLevel),
+		display.KeyValue("Debug", strconv.FormatBool(cfg.Debug)),
+		display.KeyValue("Testing mode", strconv.FormatBool(cfg.TestingMode)),
+		display.KeyValue("Provisioning", strconv.FormatBool(cfg.ProvisioningEnabled)),
+		display.KeyValue("TLS", strconv.FormatBool(cfg.Server.TLSEnabled())),
+		display.KeyValue("Database URL", redactURL(cfg.Database.URL)),
+		display.KeyValue("Pool size", fmt.Sprintf("%d-%d", cfg.Database.MinConns, cfg.Database.MaxConns)),
+		display.KeyValue("Query timeout", cfg.Database.QueryTimeout.String()),
+		display.KeyValue("Plugin audit", strconv.FormatBool(cfg.Plugins.Audit)),
+		display.KeyValue("Plugin allowlist", valueOr(strings.Join(cfg.Plugins.Allowed, ", "), "(all registered)")),
+		display.KeyValue("Plugin attempts", strconv.Itoa(cfg.Plugins.RetryAttempts)),
+		display.KeyValue("Plugin budget", cfg.Plugins.RetryBudget.String()),
+		display.KeyValue("Proxmox CLI", valueOr(cfg.Proxmox.CLIPath, "forge-ovh-cli")),
+		display.KeyValue("Proxmox output", cfg.Proxmox.OutputFormat),
+	}, "\n")
+}
+
+// renderConfigProblems renders one error line per validation problem.
+// Pure function.
+func renderConfigProblems(err error) string {
+	var verr *config.ValidationError
+	if !errors.As(err, &verr) {
+		return display.Error(err.Error())
+	}
+	lines := make([]string, len(verr.Problems))
+	for i, p := range verr.Problems {
+		lines[i] = display.Error(p)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// redactURL masks the password of a connection URL.
+// Pure function.
+func redactURL(raw string) string {
+	if raw == "" {
+		return "(not set)"
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "(unparseable)"
+	}
+	return u.Redacted()
+}
+
+// valueOr returns value, or fallback when value is empty.
+// Pure function.
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+func init() {
+	configCmd.AddCommand(configCheckCmd)
+	rootCmd.AddCommand(configCmd)
+}
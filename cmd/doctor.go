@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/searge/quokka/internal/config"
+	"github.com/searge/quokka/internal/doctor"
+	"github.com/searge/quokka/migrations"
+	"github.com/searge/quokka/pkg/display"
+)
+
+var doctorTimeout time.Duration
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Run environment preflight checks",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		cfg, cfgErr := config.FromEnv()
+		if cfgErr == nil {
+			cfgErr = cfg.Validate()
+		}
+		dbURL := cfg.Database.URL
+		if dbURL == "" {
+			dbURL = os.Getenv("DATABASE_URL")
+		}
+
+		cliPath := cfg.Proxmox.CLIPath
+		if cliPath == "" {
+			cliPath = "forge-ovh-cli"
+		}
+
+		checks := []doctor.Check{
+			doctor.Config(cfgErr),
+			doctor.Executable("forge-ovh-cli", cliPath),
+			doctor.Database(dbURL),
+			doctor.Migrations(dbURL, migrations.FS),
+		}
+
+		ctx, cancel := context.WithTimeout(cmd.Context(), doctorTimeout)
+		defer cancel()
+
+		results := doctor.Run(ctx, checks)
+		fmt.Println(renderDoctor(results))
+
+		if n := doctor.Failed(results); n > 0 {
+			return fmt.Errorf("%d check(s) failed", n)
+		}
+		return nil
+	},
+}
+
+// renderDoctor formats one pass/fail line per check under a header.
+// Pure function.
+func renderDoctor(results []doctor.Result) string {
+	lines := []string{display.Header("Preflight checks")}
+	for _, r := range results {
+		if r.Passed() {
+			lines = append(lines, display.Success(r.Name))
+			continue
+		}
+		lines = append(lines, display.Error(fmt.Sprintf("%s: %v", r.Name, r.Err)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func init() {
+	doctorCmd.Flags().DurationVar(&doctorTimeout, "timeout", 15*time.Second, "overall time allowed for all checks")
+	rootCmd.AddCommand(doctorCmd)
+}
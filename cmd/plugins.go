@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/searge/quokka/internal/config"
+	"github.com/searge/quokka/internal/integration"
+	"github.com/searge/quokka/internal/plugin"
+	"github.com/searge/quokka/pkg/display"
+)
+
+var pluginsHealthTimeout time.Duration
+
+var pluginsCmd = &cobra.Command{
+	Use:   "plugins",
+	Short: "Inspect configured integrations",
+}
+
+var pluginsHealthCmd = &cobra.Command{
+	Use:   "health",
+	Short: "Check the health of every configured plugin",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		cfg, err := config.FromEnv()
+		if err != nil {
+			return err
+		}
+
+		registry, err := integration.NewRegistry(cfg, nil)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(cmd.Context(), pluginsHealthTimeout)
+		defer cancel()
+
+		results := registry.HealthAll(ctx)
+		fmt.Println(renderPluginHealth(results))
+
+		if n := countUnhealthy(results); n > 0 {
+			return fmt.Errorf("%d plugin(s) unhealthy", n)
+		}
+		return nil
+	},
+}
+
+// renderPluginHealth formats one status line per plugin under a header.
+// Pure function.
+func renderPluginHealth(results []plugin.HealthResult) string {
+	lines := []string{display.Header("Plugin health")}
+	if len(results) == 0 {
+		lines = append(lines, display.Warn("no plugins registered"))
+	}
+	for _, r := range results {
+		if r.Healthy() {
+			lines = append(lines, display.Success(r.Name))
+			continue
+		}
+		lines = append(lines, display.Error(fmt.Sprintf("%s: %v", r.Name, r.Err)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// countUnhealthy returns how many plugins failed their health check.
+// Pure function.
+func countUnhealthy(results []plugin.HealthResult) int {
+	n := 0
+	for _, r := range results {
+		if !r.Healthy() {
+			n++
+		}
+	}
+	return n
+}
+
+func init() {
+	pluginsHealthCmd.Flags().DurationVar(&pluginsHealthTimeout, "timeout", 10*time.Second, "overall time allowed for health checks")
+	pluginsCmd.AddCommand(pluginsHealthCmd)
+	rootCmd.AddCommand(pluginsCmd)
+}
@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/searge/quokka/internal/projects"
+	"github.com/searge/quokka/internal/watch"
+	"github.com/searge/quokka/pkg/display"
+)
+
+var (
+	projectsServer       string
+	projectsWatchTimeout time.Duration
+)
+
+// defaultServer is the API root used when neither --server nor QKA_SERVER
+// is set.
+const defaultServer = "http://localhost:8080/api/v1"
+
+var projectsCmd = &cobra.Command{
+	Use:   "projects",
+	Short: "Work with projects through the API",
+}
+
+var projectsWatchCmd = &cobra.Command{
+	Use:   "watch <id>",
+	Short: "Follow a project until its provisioning completes or fails",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		if projectsWatchTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, projectsWatchTimeout)
+			defer cancel()
+		}
+
+		opts := watch.Options{
+			BaseURL:    projectsServer,
+			RetryDelay: 2 * time.Second,
+			MaxRetries: 5,
+		}
+		final, err := watchWithProgress(ctx, opts, args[0])
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(renderSettled(final))
+		if provisionFailed(final) {
+			return fmt.Errorf("project %s failed to provision", args[0])
+		}
+		return nil
+	},
+}
+
+// readOnlyNotice is shown once when the API reports read-only mode.
+const readOnlyNotice = "The API is in read-only maintenance mode: projects can be watched, but changes are rejected until it ends."
+
+// watchWithProgress runs watch.Project, animating a spinner with the latest
+// status on a terminal and printing one line per event otherwise. A banner
+// warns once if the API is in read-only mode.
+func watchWithProgress(ctx context.Context, opts watch.Options, id string) (projects.Event, error) {
+	var bannerOnce sync.Once
+	if !isTerminal(os.Stdout) {
+		opts.OnReadOnly = func() {
+			bannerOnce.Do(func() { fmt.Println(display.Banner(readOnlyNotice, "warn")) })
+		}
+		return watch.Project(ctx, opts, id, func(ev projects.Event) {
+			fmt.Println(display.Info(describeEvent(ev)))
+		})
+	}
+
+	var mu sync.Mutex
+	status := "connecting"
+	banner := ""
+	opts.OnReadOnly = func() {
+		bannerOnce.Do(func() {
+			mu.Lock()
+			banner = display.Banner(readOnlyNotice, "warn")
+			mu.Unlock()
+		})
+	}
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		for frame := 0; ; frame++ {
+			mu.Lock()
+			line := display.Spinner(frame, status)
+			pending := banner
+			banner = ""
+			mu.Unlock()
+			// The banner stays above the spinner redrawn below it
+			if pending != "" {
+				fmt.Printf("\r\033[K%s\n", pending)
+			}
+			fmt.Printf("\r\033[K%s", line)
+			select {
+			case <-done:
+				fmt.Print("\r\033[K")
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	final, err := watch.Project(ctx, opts, id, func(ev projects.Event) {
+		mu.Lock()
+		status = describeEvent(ev)
+		mu.Unlock()
+	})
+	close(done)
+	wg.Wait()
+	if banner != "" {
+		fmt.Println(banner)
+	}
+	return final, err
+}
+
+// describeEvent summarises an event as the project's name and status, or
+// its provisioning progress.
+// Pure function.
+func describeEvent(ev projects.Event) string {
+	if ev.Progress != nil {
+		return fmt.Sprintf("provisioning: %d%%", *ev.Progress)
+	}
+	if ev.Project == nil {
+		return ev.Type
+	}
+	return fmt.Sprintf("%s: %s", ev.Project.UnixName, ev.Project.Status)
+}
+
+// renderSettled formats the event that ended a watch as COMPLETE or ERROR.
+// Pure function.
+func renderSettled(ev projects.Event) string {
+	name := ev.ProjectID
+	if ev.Project != nil {
+		name = ev.Project.UnixName
+	}
+	if provisionCancelled(ev) {
+		return display.Error(name + " provisioning cancelled")
+	}
+	if provisionFailed(ev) {
+		if ev.Error != "" {
+			return display.Error(fmt.Sprintf("%s failed to provision: %s", name, ev.Error))
+		}
+		return display.Error(name + " failed to provision")
+	}
+	return display.Success(name + " provisioned")
+}
+
+// provisionFailed reports whether a watch ended without the project
+// provisioned: provisioning failed or was cancelled.
+// Pure function.
+func provisionFailed(ev projects.Event) bool {
+	if ev.Type == projects.EventProvisionFailed || provisionCancelled(ev) {
+		return true
+	}
+	return ev.Project != nil && ev.Project.Status == projects.StatusFailed
+}
+
+// provisionCancelled reports whether a watch ended with provisioning
+// cancelled.
+// Pure function.
+func provisionCancelled(ev projects.Event) bool {
+	if ev.Type == projects.EventProvisionCancelled {
+		return true
+	}
+	return ev.Project != nil && ev.Project.Status == projects.StatusCancelled
+}
+
+// isTerminal reports whether f is an interactive terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// serverFromEnv returns QKA_SERVER, or the local default when it is unset.
+func serverFromEnv() string {
+	if server := os.Getenv("QKA_SERVER"); server != "" {
+		return server
+	}
+	return defaultServer
+}
+
+func init() {
+	projectsCmd.PersistentFlags().StringVar(&projectsServer, "server", serverFromEnv(), "API root URL (env QKA_SERVER)")
+	projectsWatchCmd.Flags().DurationVar(&projectsWatchTimeout, "timeout", 0, "give up after this long; 0 waits indefinitely")
+	projectsCmd.AddCommand(projectsWatchCmd)
+	rootCmd.AddCommand(projectsCmd)
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"github.com/searge/quokka/internal/config"
+	"github.com/searge/quokka/internal/server"
+	"github.com/searge/quokka/pkg/display"
+)
+
+var serveOverrides config.Overrides
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run the API server",
+	Long: "Run the API server until interrupted. Configuration is read from the " +
+		"environment; flags take precedence over it.",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		cfg, err := config.FromEnv()
+		if err != nil {
+			fmt.Println(display.Error(err.Error()))
+			return err
+		}
+		cfg = serveOverrides.Apply(cfg)
+		if err := cfg.Validate(); err != nil {
+			fmt.Println(renderConfigProblems(err))
+			return errors.New("configuration is invalid")
+		}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return server.Run(ctx, cfg, server.Deps{Version: version})
+	},
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&serveOverrides.Addr, "addr", "", "address to listen on, e.g. :8080 (overrides LISTEN_ADDR)")
+	serveCmd.Flags().StringVar(&serveOverrides.LogLevel, "log-level", "", "debug, info, warn or error (overrides LOG_LEVEL)")
+	serveCmd.Flags().StringVar(&serveOverrides.DatabaseURL, "database-url", "", "PostgreSQL connection URL (overrides DATABASE_URL)")
+	rootCmd.AddCommand(serveCmd)
+}
@@ -0,0 +1,114 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Channel is the PostgreSQL notification channel entries are shared on.
+const Channel = "quokka_audit"
+
+// maxNotifyPayload is the largest notification payload PostgreSQL accepts
+// by default, less one byte.
+const maxNotifyPayload = 7999
+
+// listenRetry is how long Listen waits before listening again after its
+// connection failed.
+const listenRetry = 5 * time.Second
+
+// PGFeed is a Feed over PostgreSQL LISTEN/NOTIFY, sharing entries between
+// every instance using the same database. Notifications are not stored:
+// an instance only receives the entries sent while it listens.
+type PGFeed struct {
+	pool *pgxpool.Pool
+	log  *slog.Logger
+}
+
+// NewPGFeed creates a PGFeed notifying through pool.
+func NewPGFeed(pool *pgxpool.Pool, logger *slog.Logger) *PGFeed {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &PGFeed{pool: pool, log: logger}
+}
+
+// Send implements Feed. An entry too large for a notification is sent
+// without its input and output, marked truncated.
+func (f *PGFeed) Send(ctx context.Context, entry Entry) error {
+	payload, err := encodeNotification(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := f.pool.Exec(ctx, "SELECT pg_notify($1, $2)", Channel, payload); err != nil {
+		f.log.Warn("failed to share audit entry", "entry_id", entry.ID, "error", err)
+		return err
+	}
+	return nil
+}
+
+// encodeNotification returns entry as a notification payload, truncated
+// to fit.
+// Pure function.
+func encodeNotification(entry Entry) (string, error) {
+	payload, err := json.Marshal(entry)
+	if err != nil {
+		return "", err
+	}
+	if len(payload) > maxNotifyPayload {
+		entry.Input, entry.Output, entry.Truncated = nil, nil, true
+		if payload, err = json.Marshal(entry); err != nil {
+			return "", err
+		}
+	}
+	return string(payload), nil
+}
+
+// Listen passes every entry sent by any instance, this one included, to
+// l until ctx is done. A failed connection is logged and replaced after
+// listenRetry; entries sent meanwhile are missed.
+func (f *PGFeed) Listen(ctx context.Context, l *Log) {
+	for {
+		err := f.listen(ctx, l)
+		if ctx.Err() != nil {
+			return
+		}
+		f.log.Warn("audit feed interrupted", "error", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(listenRetry):
+		}
+	}
+}
+
+// listen passes notifications on Channel to l until its connection fails
+// or ctx is done.
+func (f *PGFeed) listen(ctx context.Context, l *Log) error {
+	pooled, err := f.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// A listening connection must not go back to the pool
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+Channel); err != nil {
+		return err
+	}
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		var entry Entry
+		if err := json.Unmarshal([]byte(n.Payload), &entry); err != nil {
+			f.log.Warn("ignoring malformed audit entry", "error", err)
+			continue
+		}
+		l.Receive(entry)
+	}
+}
@@ -0,0 +1,52 @@
+//go:build integration
+
+package audit
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/searge/quokka/internal/plugin"
+)
+
+func TestPGFeedSharesEntriesBetweenInstances(t *testing.T) {
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("DATABASE_URL is not set")
+	}
+	pool, err := pgxpool.New(context.Background(), dbURL)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	feed := NewPGFeed(pool, nil)
+	a, b := NewLog(0, WithFeed(feed)), NewLog(0, WithFeed(feed))
+	go feed.Listen(ctx, a)
+	go feed.Listen(ctx, b)
+	_, entries, unsubscribe := b.Subscribe(0)
+	defer unsubscribe()
+
+	// Listening starts asynchronously: send until b hears
+	deadline := time.After(10 * time.Second)
+	tick := time.NewTicker(100 * time.Millisecond)
+	defer tick.Stop()
+	for {
+		a.Record(ctx, plugin.Operation{Plugin: "proxmox", Name: plugin.OpProvision})
+		select {
+		case entry := <-entries:
+			if entry.Plugin != "proxmox" || entry.Operation != plugin.OpProvision {
+				t.Fatalf("b received %+v, want a's provision", entry)
+			}
+			return
+		case <-deadline:
+			t.Fatal("b never received an entry recorded by a")
+		case <-tick.C:
+		}
+	}
+}
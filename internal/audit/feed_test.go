@@ -0,0 +1,38 @@
+package audit
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestEncodeNotificationTruncatesLargeEntries(t *testing.T) {
+	small := Entry{ID: 1, Operation: "project.created", Output: map[string]string{"name": "alpha"}}
+	payload, err := encodeNotification(small)
+	if err != nil {
+		t.Fatalf("encodeNotification() error = %v", err)
+	}
+	var got Entry
+	if err := json.Unmarshal([]byte(payload), &got); err != nil {
+		t.Fatalf("failed to decode payload: %v", err)
+	}
+	if got.Truncated || got.Output == nil {
+		t.Errorf("small entry sent as %+v, want it whole", got)
+	}
+
+	large := Entry{ID: 2, Operation: "project.created", Output: map[string]string{"description": strings.Repeat("x", maxNotifyPayload)}}
+	payload, err = encodeNotification(large)
+	if err != nil {
+		t.Fatalf("encodeNotification() error = %v", err)
+	}
+	if len(payload) > maxNotifyPayload {
+		t.Fatalf("payload is %d bytes, over the %d limit", len(payload), maxNotifyPayload)
+	}
+	got = Entry{}
+	if err := json.Unmarshal([]byte(payload), &got); err != nil {
+		t.Fatalf("failed to decode payload: %v", err)
+	}
+	if !got.Truncated || got.Output != nil || got.ID != 2 || got.Operation != "project.created" {
+		t.Errorf("large entry sent as %+v, want it without output, marked truncated", got)
+	}
+}
@@ -0,0 +1,79 @@
+package audit
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/searge/quokka/internal/platform"
+)
+
+// EventEntry names the server-sent event carrying an audit log entry.
+const EventEntry = "audit.entry"
+
+// streamKeepalive is how often an idle stream sends a comment.
+const streamKeepalive = 15 * time.Second
+
+// Handler serves the audit log stream. It does not check who calls it:
+// mount it behind platform.RequireAdmin.
+type Handler struct {
+	log    *Log
+	logger *slog.Logger
+}
+
+// NewHandler creates a new Handler streaming log.
+func NewHandler(log *Log, logger *slog.Logger) *Handler {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Handler{log: log, logger: logger}
+}
+
+// Stream serves GET /admin/audit/stream?since= as server-sent events: the
+// kept entries written after the one with ID since, if given, then every
+// entry as it is written until the client disconnects. Each entry is an
+// audit.entry event whose data carries its ID. IDs are write times, so a
+// cursor from before a restart neither skips nor repeats later entries.
+func (h *Handler) Stream(w http.ResponseWriter, r *http.Request) {
+	query := platform.QueryParams(r)
+	since := query.Int64("since", 0)
+	if err := query.Err(); err != nil {
+		platform.RespondQueryError(w, err)
+		return
+	}
+
+	replay, entries, unsubscribe := h.log.Subscribe(since)
+	defer unsubscribe()
+
+	sw := platform.NewEventStreamWriter(w)
+	// Send nothing but the headers when there is nothing to replay, so
+	// clients know the stream is open
+	if err := sw.Comment("audit stream"); err != nil {
+		h.logger.Warn("audit stream closed", "error", err)
+		return
+	}
+	for _, entry := range replay {
+		if err := sw.Send(EventEntry, entry); err != nil {
+			h.logger.Warn("audit stream closed", "error", err)
+			return
+		}
+	}
+
+	keepalive := time.NewTicker(streamKeepalive)
+	defer keepalive.Stop()
+	for {
+		var err error
+		select {
+		case <-r.Context().Done():
+			return
+		case entry := <-entries:
+			err = sw.Send(EventEntry, entry)
+		case <-keepalive.C:
+			err = sw.Comment("keepalive")
+		}
+		if err != nil {
+			h.logger.Warn("audit stream closed", "error", err)
+			return
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/searge/quokka/internal/platform"
+	"github.com/searge/quokka/internal/plugin"
+	"github.com/searge/quokka/internal/projects"
+)
+
+const adminToken = "s3cret"
+
+type fakePlugin struct{}
+
+func (fakePlugin) Name() string                 { return "proxmox" }
+func (fakePlugin) Health(context.Context) error { return nil }
+func (fakePlugin) Provision(context.Context, plugin.ProvisionRequest) (*plugin.ProvisionResult, error) {
+	return &plugin.ProvisionResult{ResourceID: "vm-1", Status: "provisioned"}, nil
+}
+func (fakePlugin) Status(context.Context, string) (*plugin.StatusResult, error) {
+	return &plugin.StatusResult{Status: "running"}, nil
+}
+func (fakePlugin) Deprovision(context.Context, string) error { return nil }
+
+// newStreamServer serves the stream of l as the API does, admins only.
+func newStreamServer(t *testing.T, l *Log) *httptest.Server {
+	t.Helper()
+
+	r := chi.NewRouter()
+	r.With(platform.RequireAdmin(adminToken)).Get("/admin/audit/stream", NewHandler(l, nil).Stream)
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// openStream subscribes to srv's audit stream and returns a function
+// reading the next entry from it.
+func openStream(t *testing.T, srv *httptest.Server, query string) func() Entry {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/admin/audit/stream"+query, nil)
+	if err != nil {
+		t.Fatalf("build request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET audit stream: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := resp.Body.Close(); err != nil {
+			t.Errorf("failed to close body: %v", err)
+		}
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q, want text/event-stream", ct)
+	}
+
+	lines := bufio.NewScanner(resp.Body)
+	return func() Entry {
+		t.Helper()
+		for lines.Scan() {
+			if lines.Text() != "event: "+EventEntry {
+				continue
+			}
+			if !lines.Scan() {
+				break
+			}
+			var entry Entry
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(lines.Text(), "data: ")), &entry); err != nil {
+				t.Fatalf("decode entry %q: %v", lines.Text(), err)
+			}
+			return entry
+		}
+		t.Fatalf("stream ended early: %v", lines.Err())
+		return Entry{}
+	}
+}
+
+func TestStreamDeliversEntryOfMutation(t *testing.T) {
+	l := NewLog(0)
+	next := openStream(t, newStreamServer(t, l), "")
+
+	err := l.Publish(context.Background(), projects.Event{
+		Type:       projects.EventUpdated,
+		ProjectID:  "p-1",
+		Project:    &projects.Project{ID: "p-1", Name: "beta"},
+		OccurredAt: time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	entry := next()
+	if entry.ID == 0 || entry.ProjectID != "p-1" || entry.Operation != projects.EventUpdated {
+		t.Fatalf("received %+v, want the update of p-1", entry)
+	}
+	output, _ := entry.Output.(map[string]any)
+	if output["name"] != "beta" {
+		t.Errorf("entry output = %v, want the updated project", entry.Output)
+	}
+}
+
+func TestStreamDeliversEntryOfPluginOperation(t *testing.T) {
+	l := NewLog(0)
+	p := plugin.WithAudit(fakePlugin{}, l)
+	next := openStream(t, newStreamServer(t, l), "")
+
+	if _, err := p.Provision(context.Background(), plugin.ProvisionRequest{ProjectID: "p-1", ProjectName: "alpha"}); err != nil {
+		t.Fatalf("Provision() error = %v", err)
+	}
+
+	entry := next()
+	if entry.Plugin != "proxmox" || entry.Operation != plugin.OpProvision {
+		t.Fatalf("received %+v, want the provision", entry)
+	}
+	input, _ := entry.Input.(map[string]any)
+	if input["project_id"] != "p-1" {
+		t.Errorf("entry input = %v, want the provision request", entry.Input)
+	}
+}
+
+func TestStreamReplaysSinceCursorThenTails(t *testing.T) {
+	l := NewLog(0)
+	p := plugin.WithAudit(fakePlugin{}, l)
+	for _, id := range []string{"vm-1", "vm-2", "vm-3"} {
+		if err := p.Deprovision(context.Background(), id); err != nil {
+			t.Fatalf("Deprovision() error = %v", err)
+		}
+	}
+	written, _, unsubscribe := l.Subscribe(1)
+	unsubscribe()
+	next := openStream(t, newStreamServer(t, l), fmt.Sprintf("?since=%d", written[0].ID))
+
+	for i, want := range []string{"vm-2", "vm-3"} {
+		if entry := next(); entry.ID != written[i+1].ID || entry.Input != want {
+			t.Fatalf("replayed %+v, want the deprovision of %s", entry, want)
+		}
+	}
+
+	if err := p.Deprovision(context.Background(), "vm-4"); err != nil {
+		t.Fatalf("Deprovision() error = %v", err)
+	}
+	if entry := next(); entry.ID <= written[2].ID || entry.Input != "vm-4" {
+		t.Errorf("received %+v, want the deprovision of vm-4 once tailing", entry)
+	}
+}
+
+func TestStreamRequiresAdmin(t *testing.T) {
+	srv := newStreamServer(t, NewLog(0))
+
+	resp, err := http.Get(srv.URL + "/admin/audit/stream")
+	if err != nil {
+		t.Fatalf("GET audit stream: %v", err)
+	}
+	if err := resp.Body.Close(); err != nil {
+		t.Errorf("failed to close body: %v", err)
+	}
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401, got %d", resp.StatusCode)
+	}
+}
+
+func TestStreamRejectsMalformedSince(t *testing.T) {
+	h := NewHandler(NewLog(0), nil)
+
+	rr := httptest.NewRecorder()
+	h.Stream(rr, httptest.NewRequest(http.MethodGet, "/admin/audit/stream?since=yesterday", nil))
+
+	if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), "INVALID_QUERY_PARAM") {
+		t.Fatalf("expected 400 INVALID_QUERY_PARAM, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
@@ -0,0 +1,230 @@
+// Package audit keeps the recent audit log of project mutations and plugin
+// operations in memory, shares it between instances, and streams it to
+// admins as it is written.
+package audit
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/searge/quokka/internal/plugin"
+	"github.com/searge/quokka/internal/projects"
+)
+
+// DefaultHistory is how many entries a Log keeps for replay by default.
+const DefaultHistory = 1000
+
+// subscriberBuffer is how many entries a subscriber may fall behind by
+// before it starts missing them.
+const subscriberBuffer = 64
+
+// Entry is one audit log entry: a project mutation as its event reported
+// it, or a plugin operation as the audit decorator recorded it, inputs and
+// outputs already redacted.
+type Entry struct {
+	// ID is when the entry was written, in nanoseconds since the Unix
+	// epoch, bumped where needed so that IDs keep increasing. It is the
+	// cursor a stream resumes after, and stays one across restarts.
+	ID int64 `json:"id"`
+	// Plugin is set for plugin operations, ProjectID for project
+	// mutations, whose Operation is the event type.
+	Plugin     string    `json:"plugin,omitempty"`
+	ProjectID  string    `json:"project_id,omitempty"`
+	Operation  string    `json:"operation"`
+	Input      any       `json:"input,omitempty"`
+	Output     any       `json:"output,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	DurationMS int64     `json:"duration_ms"`
+	// Truncated is set when Input and Output were dropped for the entry to
+	// fit in a Feed.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// Feed shares entries between the Logs of every instance, each passing
+// what it receives to its Log's Receive.
+type Feed interface {
+	Send(ctx context.Context, entry Entry) error
+}
+
+// Log is a projects.EventPublisher and plugin.Recorder keeping the most
+// recent entries for replay and fanning each new one out to its
+// subscribers. Like the project event Broadcaster, recording never blocks:
+// a subscriber that falls behind misses entries. Entries live only as long
+// as the process; with a Feed, it also keeps those other instances write
+// while it runs.
+type Log struct {
+	feed     Feed
+	redacted []string
+
+	mu      sync.Mutex
+	lastID  int64
+	history []Entry
+	size    int
+	subs    map[chan Entry]struct{}
+}
+
+// LogOption configures optional Log behaviour.
+type LogOption func(*Log)
+
+// WithFeed sends every entry written here to feed, for the other
+// instances' Logs. Receive takes theirs.
+func WithFeed(feed Feed) LogOption {
+	return func(l *Log) {
+		l.feed = feed
+	}
+}
+
+// WithRedactedFields masks the provision resources named by fields in
+// project snapshots, on top of those any audit record masks, e.g. the
+// fields stored encrypted.
+func WithRedactedFields(fields ...string) LogOption {
+	return func(l *Log) {
+		l.redacted = fields
+	}
+}
+
+// NewLog returns an empty Log keeping up to size entries for replay;
+// size below one keeps DefaultHistory.
+func NewLog(size int, opts ...LogOption) *Log {
+	if size < 1 {
+		size = DefaultHistory
+	}
+	l := &Log{size: size, subs: map[chan Entry]struct{}{}}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Publish implements projects.EventPublisher. Progress reports change
+// nothing and are not kept. Sensitive provision resources of the project
+// snapshot are masked.
+func (l *Log) Publish(ctx context.Context, event projects.Event) error {
+	if event.Type == projects.EventProgress {
+		return nil
+	}
+	entry := Entry{
+		ProjectID: event.ProjectID,
+		Operation: event.Type,
+		Error:     event.Error,
+		StartedAt: event.OccurredAt,
+	}
+	if event.PreviousOrgID != "" {
+		entry.Input = map[string]string{"previous_org_id": event.PreviousOrgID}
+	}
+	if event.Project != nil {
+		entry.Output = l.redactProject(event.Project)
+	}
+	l.write(ctx, entry)
+	return nil
+}
+
+// redactProject returns a copy of project with sensitive provision
+// resources masked.
+func (l *Log) redactProject(project *projects.Project) *projects.Project {
+	if project.ProvisionParams == nil {
+		return project
+	}
+	copied := *project
+	params := *project.ProvisionParams
+	params.Resources = plugin.RedactResources(params.Resources, l.redacted...)
+	copied.ProvisionParams = &params
+	return &copied
+}
+
+// Record implements plugin.Recorder. Status checks change nothing and are
+// not kept.
+func (l *Log) Record(ctx context.Context, op plugin.Operation) {
+	if op.Name == plugin.OpStatus {
+		return
+	}
+	entry := Entry{
+		Plugin:     op.Plugin,
+		Operation:  op.Name,
+		Input:      op.Input,
+		Output:     op.Output,
+		StartedAt:  op.StartedAt,
+		DurationMS: op.Duration.Milliseconds(),
+	}
+	if op.Err != nil {
+		entry.Error = op.Err.Error()
+	}
+	l.write(ctx, entry)
+}
+
+// write numbers entry, keeps it and sends it to the feed. An entry the
+// feed fails to take is kept here only.
+func (l *Log) write(ctx context.Context, entry Entry) {
+	l.mu.Lock()
+	entry.ID = max(time.Now().UnixNano(), l.lastID+1)
+	l.lastID = entry.ID
+	l.mu.Unlock()
+
+	l.Receive(entry)
+	if l.feed != nil {
+		// Recording never fails what it records; the entry is still
+		// kept and streamed here.
+		_ = l.feed.Send(ctx, entry)
+	}
+}
+
+// Receive keeps entry, written here or by another instance, in ID order
+// and sends it to every subscriber. An entry already kept, e.g. one of
+// this Log's own coming back from the feed, is ignored.
+func (l *Log) Receive(entry Entry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	i, found := slices.BinarySearchFunc(l.history, entry.ID, func(e Entry, id int64) int {
+		return cmp.Compare(e.ID, id)
+	})
+	if found {
+		return
+	}
+	if len(l.history) == l.size {
+		if i == 0 {
+			return // older than anything kept
+		}
+		l.history = append(l.history[:0], l.history[1:]...)
+		i--
+	}
+	l.history = slices.Insert(l.history, i, entry)
+	l.lastID = max(l.lastID, entry.ID)
+	for ch := range l.subs {
+		select {
+		case ch <- entry:
+		default:
+		}
+	}
+}
+
+// Subscribe returns the kept entries written after the one with ID since,
+// oldest first, and a channel receiving every entry written from now on
+// until unsubscribe is called. Nothing is lost or repeated between the
+// two. A since of zero or less replays nothing.
+func (l *Log) Subscribe(since int64) (replay []Entry, entries <-chan Entry, unsubscribe func()) {
+	ch := make(chan Entry, subscriberBuffer)
+
+	l.mu.Lock()
+	if since > 0 {
+		for _, entry := range l.history {
+			if entry.ID > since {
+				replay = append(replay, entry)
+			}
+		}
+	}
+	l.subs[ch] = struct{}{}
+	l.mu.Unlock()
+
+	var once sync.Once
+	return replay, ch, func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			delete(l.subs, ch)
+		})
+	}
+}
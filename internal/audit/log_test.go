@@ -0,0 +1,205 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"testing"
+
+	"github.com/searge/quokka/internal/plugin"
+	"github.com/searge/quokka/internal/projects"
+)
+
+func TestLogReplaysEntriesAfterSince(t *testing.T) {
+	l := NewLog(3)
+	for _, name := range []string{"a", "b", "c", "d"} {
+		l.Record(context.Background(), plugin.Operation{Plugin: "proxmox", Name: plugin.OpProvision, Input: name})
+	}
+
+	// Entry a fell out of the history
+	all, _, unsubscribe := l.Subscribe(1)
+	unsubscribe()
+	if len(all) != 3 || all[0].Input != "b" {
+		t.Fatalf("replayed %+v, want entries b to d", all)
+	}
+
+	// b is the cursor itself
+	replay, _, unsubscribe := l.Subscribe(all[0].ID)
+	defer unsubscribe()
+	if len(replay) != 2 || replay[0].Input != "c" || replay[1].Input != "d" {
+		t.Fatalf("replayed %+v, want entries c and d", replay)
+	}
+	if replay[0].ID <= all[0].ID || replay[1].ID <= replay[0].ID {
+		t.Errorf("IDs %d, %d, %d do not increase", all[0].ID, replay[0].ID, replay[1].ID)
+	}
+}
+
+func TestLogIDsOutlastRestarts(t *testing.T) {
+	before := NewLog(0)
+	before.Record(context.Background(), plugin.Operation{Name: plugin.OpProvision})
+	replay, _, unsubscribe := before.Subscribe(1)
+	unsubscribe()
+	cursor := replay[0].ID
+
+	// A new Log stands for the process after a restart
+	after := NewLog(0)
+	after.Record(context.Background(), plugin.Operation{Name: plugin.OpDeprovision})
+	replay, _, unsubscribe = after.Subscribe(cursor)
+	unsubscribe()
+	if len(replay) != 1 || replay[0].Operation != plugin.OpDeprovision {
+		t.Errorf("resuming after %d replayed %+v, want the entry written since", cursor, replay)
+	}
+}
+
+func TestLogDeliversNewEntriesToSubscribers(t *testing.T) {
+	l := NewLog(0)
+	l.Record(context.Background(), plugin.Operation{Name: plugin.OpProvision})
+
+	replay, entries, unsubscribe := l.Subscribe(0)
+	if len(replay) != 0 {
+		t.Errorf("since 0 replayed %+v, want nothing", replay)
+	}
+
+	l.Record(context.Background(), plugin.Operation{Plugin: "proxmox", Name: plugin.OpDeprovision, Err: errors.New("exit status 1")})
+	select {
+	case entry := <-entries:
+		if entry.ID == 0 || entry.Operation != plugin.OpDeprovision || entry.Error != "exit status 1" {
+			t.Errorf("received %+v, want the failed deprovision", entry)
+		}
+	default:
+		t.Fatal("expected the subscriber to receive the entry")
+	}
+
+	unsubscribe()
+	unsubscribe()
+	l.Record(context.Background(), plugin.Operation{Name: plugin.OpTag})
+	select {
+	case entry := <-entries:
+		t.Errorf("unsubscribed channel received %+v", entry)
+	default:
+	}
+}
+
+func TestLogKeepsOnlyMutations(t *testing.T) {
+	l := NewLog(0)
+	_, entries, unsubscribe := l.Subscribe(0)
+	defer unsubscribe()
+
+	progress := 40
+	l.Record(context.Background(), plugin.Operation{Plugin: "proxmox", Name: plugin.OpStatus})
+	if err := l.Publish(context.Background(), projects.Event{Type: projects.EventProgress, ProjectID: "p-1", Progress: &progress}); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	err := l.Publish(context.Background(), projects.Event{
+		Type:          projects.EventTransferred,
+		ProjectID:     "p-1",
+		Project:       &projects.Project{ID: "p-1", OrgID: "org-b"},
+		PreviousOrgID: "org-a",
+	})
+	if err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	select {
+	case entry := <-entries:
+		if entry.Operation != projects.EventTransferred || entry.ProjectID != "p-1" || entry.Plugin != "" {
+			t.Errorf("received %+v, want the transfer of p-1", entry)
+		}
+		if project, _ := entry.Output.(*projects.Project); project == nil || project.OrgID != "org-b" {
+			t.Errorf("entry output = %v, want the transferred project", entry.Output)
+		}
+	default:
+		t.Fatal("expected the subscriber to receive the transfer")
+	}
+	select {
+	case entry := <-entries:
+		t.Errorf("received %+v, want status checks and progress left out", entry)
+	default:
+	}
+}
+
+func TestLogNeverBlocksOnSlowSubscribers(t *testing.T) {
+	l := NewLog(0)
+	_, _, unsubscribe := l.Subscribe(0)
+	defer unsubscribe()
+
+	for range subscriberBuffer * 2 {
+		l.Record(context.Background(), plugin.Operation{Name: plugin.OpTag})
+	}
+}
+
+func TestLogRedactsProjectSnapshots(t *testing.T) {
+	l := NewLog(0, WithRedactedFields("license"))
+	_, entries, unsubscribe := l.Subscribe(0)
+	defer unsubscribe()
+
+	project := &projects.Project{ID: "p-1", ProvisionParams: &projects.ProvisionParams{
+		Plugin:    "proxmox",
+		Resources: map[string]interface{}{"root_password": "hunter2", "license": "ABC-123", "cores": 2},
+	}}
+	if err := l.Publish(context.Background(), projects.Event{Type: projects.EventCreated, ProjectID: "p-1", Project: project}); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	entry := <-entries
+	output, _ := entry.Output.(*projects.Project)
+	if output == nil {
+		t.Fatalf("entry output = %v, want the project", entry.Output)
+	}
+	want := map[string]interface{}{"root_password": "***", "license": "***", "cores": 2}
+	if !maps.Equal(output.ProvisionParams.Resources, want) {
+		t.Errorf("recorded resources = %v, want %v", output.ProvisionParams.Resources, want)
+	}
+	if project.ProvisionParams.Resources["root_password"] != "hunter2" {
+		t.Errorf("Publish() changed the event's project to %v", project.ProvisionParams.Resources)
+	}
+}
+
+// loopFeed delivers every entry sent to each of its Logs, as PGFeed does
+// between instances.
+type loopFeed struct {
+	logs []*Log
+}
+
+func (f *loopFeed) Send(_ context.Context, entry Entry) error {
+	for _, l := range f.logs {
+		l.Receive(entry)
+	}
+	return nil
+}
+
+func TestLogSharesEntriesThroughFeed(t *testing.T) {
+	feed := &loopFeed{}
+	a, b := NewLog(0, WithFeed(feed)), NewLog(0, WithFeed(feed))
+	feed.logs = []*Log{a, b}
+	_, fromA, unsubscribeA := a.Subscribe(0)
+	defer unsubscribeA()
+	_, fromB, unsubscribeB := b.Subscribe(0)
+	defer unsubscribeB()
+
+	a.Record(context.Background(), plugin.Operation{Plugin: "proxmox", Name: plugin.OpProvision})
+
+	for name, entries := range map[string]<-chan Entry{"a": fromA, "b": fromB} {
+		select {
+		case entry := <-entries:
+			if entry.Operation != plugin.OpProvision {
+				t.Errorf("%s received %+v, want the provision", name, entry)
+			}
+		default:
+			t.Fatalf("expected %s to receive the entry", name)
+		}
+		select {
+		case entry := <-entries:
+			t.Errorf("%s received %+v again", name, entry)
+		default:
+		}
+	}
+
+	// b resumes after a's entries like after its own
+	b.Record(context.Background(), plugin.Operation{Plugin: "proxmox", Name: plugin.OpDeprovision})
+	replay, _, unsubscribe := a.Subscribe(1)
+	unsubscribe()
+	if len(replay) != 2 || replay[0].Operation != plugin.OpProvision || replay[1].Operation != plugin.OpDeprovision {
+		t.Errorf("a replayed %+v, want both instances' entries in order", replay)
+	}
+}
@@ -0,0 +1,94 @@
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/searge/quokka/internal/platform"
+)
+
+// Handler serves the admin backup and restore endpoints. It does not check
+// who calls it: mount it behind platform.RequireAdmin.
+type Handler struct {
+	service *Service
+	log     *slog.Logger
+}
+
+// NewHandler creates a new Handler.
+func NewHandler(service *Service, logger *slog.Logger) *Handler {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Handler{service: service, log: logger}
+}
+
+// Backup streams an archive of every organization and project as a
+// gzip-compressed NDJSON download. A backup that fails once the archive has
+// started is cut short, so clients see a broken download rather than a
+// truncated archive that looks complete.
+func (h *Handler) Backup(w http.ResponseWriter, r *http.Request) {
+	// A full dump may outlive the server's write timeout
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		platform.RespondServerError(w, h.log, err)
+		return
+	}
+
+	archive := &archiveWriter{w: w, filename: fmt.Sprintf("quokka-backup-%s.ndjson.gz", time.Now().UTC().Format("20060102T150405Z"))}
+	if err := h.service.Backup(r.Context(), archive); err != nil {
+		if !archive.started {
+			platform.RespondServerError(w, h.log, err)
+			return
+		}
+		h.log.Error("backup failed after the archive started", "error", err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// Restore imports the archive in the request body into an empty database
+// and reports how many organizations and projects it restored.
+func (h *Handler) Restore(w http.ResponseWriter, r *http.Request) {
+	// Uploading a full archive may outlive the server's read timeout
+	rc := http.NewResponseController(w)
+	if err := rc.SetReadDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		platform.RespondServerError(w, h.log, err)
+		return
+	}
+
+	result, err := h.service.Restore(r.Context(), r.Body)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidArchive):
+			platform.RespondError(w, http.StatusBadRequest, "INVALID_ARCHIVE", err.Error())
+		case errors.Is(err, ErrDatabaseNotEmpty):
+			platform.RespondError(w, http.StatusConflict, "DATABASE_NOT_EMPTY", "restore needs a database without organizations or projects")
+		default:
+			platform.RespondServerError(w, h.log, err)
+		}
+		return
+	}
+
+	platform.RespondJSON(w, http.StatusOK, result)
+}
+
+// archiveWriter answers with the headers of an archive download once the
+// archive's first bytes are written. Until then the handler may still
+// respond with an error.
+type archiveWriter struct {
+	w        http.ResponseWriter
+	filename string
+	started  bool
+}
+
+func (a *archiveWriter) Write(p []byte) (int, error) {
+	if !a.started {
+		a.w.Header().Set("Content-Type", "application/gzip")
+		a.w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", a.filename))
+		a.w.WriteHeader(http.StatusOK)
+		a.started = true
+	}
+	return a.w.Write(p)
+}
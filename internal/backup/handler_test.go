@@ -0,0 +1,157 @@
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestHandlerBackupServesArchive(t *testing.T) {
+	h := NewHandler(newService(fleet(), nil), nil)
+
+	rr := httptest.NewRecorder()
+	h.Backup(rr, httptest.NewRequest(http.MethodGet, "/admin/backup", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body)
+	}
+	if got := rr.Header().Get("Content-Type"); got != "application/gzip" {
+		t.Errorf("Content-Type = %q, want application/gzip", got)
+	}
+	if got := rr.Header().Get("Content-Disposition"); !strings.HasPrefix(got, `attachment; filename="quokka-backup-`) {
+		t.Errorf("Content-Disposition = %q, want an attachment", got)
+	}
+	if _, err := gzip.NewReader(rr.Body); err != nil {
+		t.Errorf("body is not gzip: %v", err)
+	}
+}
+
+func TestHandlerBackupReportsEarlyFailure(t *testing.T) {
+	h := NewHandler(newService(&memoryStore{dumpErr: errors.New("connection refused")}, nil), nil)
+
+	rr := httptest.NewRecorder()
+	h.Backup(rr, httptest.NewRequest(http.MethodGet, "/admin/backup", nil))
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", rr.Code)
+	}
+	if got := rr.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want a JSON error", got)
+	}
+}
+
+func TestHandlerRestore(t *testing.T) {
+	var valid bytes.Buffer
+	if err := newService(fleet(), nil).Backup(context.Background(), &valid); err != nil {
+		t.Fatalf("Backup() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		target   *memoryStore
+		body     []byte
+		wantCode int
+		wantErr  string
+	}{
+		{name: "restores into an empty database", target: &memoryStore{}, body: valid.Bytes(), wantCode: http.StatusOK},
+		{name: "non-empty database", target: fleet(), body: valid.Bytes(), wantCode: http.StatusConflict, wantErr: "DATABASE_NOT_EMPTY"},
+		{name: "not an archive", target: &memoryStore{}, body: []byte(`{"projects":[]}`), wantCode: http.StatusBadRequest, wantErr: "INVALID_ARCHIVE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(newService(tt.target, nil), nil)
+
+			rr := httptest.NewRecorder()
+			h.Restore(rr, httptest.NewRequest(http.MethodPost, "/admin/restore", bytes.NewReader(tt.body)))
+
+			if rr.Code != tt.wantCode {
+				t.Fatalf("expected %d, got %d: %s", tt.wantCode, rr.Code, rr.Body)
+			}
+			if tt.wantErr != "" {
+				var body struct {
+					Error struct {
+						Code string `json:"code"`
+					} `json:"error"`
+				}
+				if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+					t.Fatalf("failed to decode response body: %v", err)
+				}
+				if body.Error.Code != tt.wantErr {
+					t.Errorf("error code = %q, want %q", body.Error.Code, tt.wantErr)
+				}
+				return
+			}
+			var result RestoreResult
+			if err := json.NewDecoder(rr.Body).Decode(&result); err != nil {
+				t.Fatalf("failed to decode response body: %v", err)
+			}
+			if want := (RestoreResult{Organizations: 2, Projects: 3}); result != want {
+				t.Errorf("restored %+v, want %+v", result, want)
+			}
+		})
+	}
+}
+
+func TestHandlerRestoreStreamsLargeArchive(t *testing.T) {
+	// Random descriptions keep the archive from compressing below a few MB
+	source := &memoryStore{orgs: fleet().orgs}
+	for i := range 30000 {
+		description := rand.Text() + rand.Text() + rand.Text() + rand.Text()
+		source.projects = append(source.projects, &Project{
+			ID: uuid.NewString(), Name: "Project", UnixName: fmt.Sprintf("project-%d", i),
+			Description: &description, Status: "pending", Labels: json.RawMessage(`{}`),
+		})
+	}
+	var archive bytes.Buffer
+	if err := newService(source, nil).Backup(context.Background(), &archive); err != nil {
+		t.Fatalf("Backup() error = %v", err)
+	}
+	if archive.Len() < 2<<20 {
+		t.Fatalf("archive is %d bytes, want a multi-MB one", archive.Len())
+	}
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(NewHandler(newService(&memoryStore{}, nil), nil).Restore))
+	srv.Config.ReadTimeout = 100 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+
+	// Upload slower than the read timeout allows for the whole body
+	body, upload := io.Pipe()
+	go func() {
+		for chunk := range slices.Chunk(archive.Bytes(), 256<<10) {
+			time.Sleep(20 * time.Millisecond)
+			if _, err := upload.Write(chunk); err != nil {
+				return
+			}
+		}
+		upload.Close()
+	}()
+	resp, err := http.Post(srv.URL, "application/gzip", body)
+	if err != nil {
+		t.Fatalf("POST restore: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	var result RestoreResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("failed to decode response body: %v", err)
+	}
+	if want := (RestoreResult{Organizations: 2, Projects: 30000}); result != want {
+		t.Errorf("restored %+v, want %+v", result, want)
+	}
+}
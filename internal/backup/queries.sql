@@ -0,0 +1,34 @@
+-- name: DatabaseIsEmpty :one
+SELECT NOT EXISTS(SELECT 1 FROM organizations) AND NOT EXISTS(SELECT 1 FROM projects) AS empty;
+
+-- name: ListOrganizationsAfter :many
+-- Pages through every organization in id order, starting after the given
+-- id, or from the first when it is NULL.
+SELECT id, name, unix_name, max_active_projects, created_at, updated_at, provision_priority, default_labels, synced_labels
+FROM organizations
+WHERE sqlc.narg('after')::uuid IS NULL OR id > sqlc.narg('after')::uuid
+ORDER BY id
+LIMIT sqlc.arg('limit');
+
+-- name: ListProjectsAfter :many
+-- Pages through every project, soft-deleted ones included, in id order,
+-- starting after the given id, or from the first when it is NULL.
+SELECT id, name, unix_name, description, active, created_at, updated_at, provision_params, labels, status, deleted_at, org_id, resource_id, archived_unix_name, network, last_checked_at, node
+FROM projects
+WHERE sqlc.narg('after')::uuid IS NULL OR id > sqlc.narg('after')::uuid
+ORDER BY id
+LIMIT sqlc.arg('limit');
+
+-- name: RestoreOrganization :exec
+INSERT INTO organizations (
+    id, name, unix_name, max_active_projects, created_at, updated_at, provision_priority, default_labels, synced_labels
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9
+);
+
+-- name: RestoreProject :exec
+INSERT INTO projects (
+    id, name, unix_name, description, active, created_at, updated_at, provision_params, labels, status, deleted_at, org_id, resource_id, archived_unix_name, network, last_checked_at, node
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17
+);
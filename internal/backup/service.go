@@ -0,0 +1,135 @@
+package backup
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"log/slog"
+	"time"
+)
+
+var (
+	ErrInvalidArchive   = errors.New("invalid backup archive")
+	ErrDatabaseNotEmpty = errors.New("database is not empty")
+)
+
+// backupBatchSize is how many rows a backup reads from the database at a
+// time.
+const backupBatchSize = 500
+
+// Service writes and restores backup archives.
+type Service struct {
+	store backupStore
+	log   *slog.Logger
+	now   func() time.Time
+}
+
+type backupStore interface {
+	Dump(ctx context.Context, batchSize int32, emit func(Record) error) error
+	Restore(ctx context.Context, records iter.Seq2[Record, error]) error
+}
+
+// NewService creates a new Service.
+func NewService(store *Store, logger *slog.Logger) *Service {
+	return newService(store, logger)
+}
+
+func newService(store backupStore, logger *slog.Logger) *Service {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Service{store: store, log: logger, now: time.Now}
+}
+
+// Backup writes an archive of every organization and project to w, as it
+// reads them. Nothing is written to w until the first row has been read,
+// so a backup that fails early leaves w untouched; one that fails midway
+// leaves a truncated archive, which Restore rejects.
+func (s *Service) Backup(ctx context.Context, w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	enc := json.NewEncoder(gz)
+
+	started := false
+	start := func() error {
+		if started {
+			return nil
+		}
+		started = true
+		return enc.Encode(Record{Kind: KindHeader, Header: &Header{Format: FormatVersion, CreatedAt: s.now().UTC()}})
+	}
+	err := s.store.Dump(ctx, backupBatchSize, func(record Record) error {
+		if err := start(); err != nil {
+			return err
+		}
+		return enc.Encode(record)
+	})
+	if err != nil {
+		return fmt.Errorf("dump: %w", err)
+	}
+	// An empty database still gets an archive, holding the header only
+	if err := start(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// Restore imports an archive written by Backup into an empty database,
+// streaming it from r. Either every record is restored or, on error, none
+// is. It returns ErrDatabaseNotEmpty if the database holds any
+// organization or project, and an error wrapping ErrInvalidArchive if r is
+// not a complete archive of a supported format.
+func (s *Service) Restore(ctx context.Context, r io.Reader) (*RestoreResult, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidArchive, err)
+	}
+	defer gz.Close()
+	dec := json.NewDecoder(gz)
+
+	var first Record
+	if err := dec.Decode(&first); err != nil {
+		return nil, fmt.Errorf("%w: read header: %w", ErrInvalidArchive, err)
+	}
+	if first.Kind != KindHeader || first.Header == nil {
+		return nil, fmt.Errorf("%w: missing header", ErrInvalidArchive)
+	}
+	if first.Header.Format != FormatVersion {
+		return nil, fmt.Errorf("%w: unsupported format %d", ErrInvalidArchive, first.Header.Format)
+	}
+
+	var result RestoreResult
+	records := func(yield func(Record, error) bool) {
+		for {
+			var record Record
+			err := dec.Decode(&record)
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				yield(Record{}, fmt.Errorf("%w: %w", ErrInvalidArchive, err))
+				return
+			}
+			switch record.Kind {
+			case KindOrganization:
+				result.Organizations++
+			case KindProject:
+				result.Projects++
+			}
+			if !yield(record, nil) {
+				return
+			}
+		}
+	}
+	if err := s.store.Restore(ctx, records); err != nil {
+		return nil, err
+	}
+
+	s.log.Info("restored backup",
+		"created_at", first.Header.CreatedAt,
+		"organizations", result.Organizations, "projects", result.Projects)
+	return &result, nil
+}
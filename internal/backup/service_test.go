@@ -0,0 +1,253 @@
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// memoryStore keeps the rows of a backup in memory and restores them all
+// or none, like the database does.
+type memoryStore struct {
+	orgs     []*Organization
+	projects []*Project
+	// dumpErr fails Dump before any row is read.
+	dumpErr error
+}
+
+func (m *memoryStore) Dump(_ context.Context, _ int32, emit func(Record) error) error {
+	if m.dumpErr != nil {
+		return m.dumpErr
+	}
+	for _, org := range m.orgs {
+		if err := emit(Record{Kind: KindOrganization, Organization: org}); err != nil {
+			return err
+		}
+	}
+	for _, project := range m.projects {
+		if err := emit(Record{Kind: KindProject, Project: project}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *memoryStore) Restore(_ context.Context, records iter.Seq2[Record, error]) error {
+	if len(m.orgs) > 0 || len(m.projects) > 0 {
+		return ErrDatabaseNotEmpty
+	}
+	var orgs []*Organization
+	var projects []*Project
+	known := make(map[string]bool)
+	for record, err := range records {
+		if err != nil {
+			return err
+		}
+		switch record.Kind {
+		case KindOrganization:
+			known[record.Organization.ID] = true
+			orgs = append(orgs, record.Organization)
+		case KindProject:
+			if record.Project.OrgID != "" && !known[record.Project.OrgID] {
+				return fmt.Errorf("%w: project %s: unknown organization", ErrInvalidArchive, record.Project.ID)
+			}
+			projects = append(projects, record.Project)
+		default:
+			return fmt.Errorf("%w: unexpected %q record", ErrInvalidArchive, record.Kind)
+		}
+	}
+	m.orgs, m.projects = orgs, projects
+	return nil
+}
+
+// fleet returns a small dataset covering every kind of row a backup holds.
+func fleet() *memoryStore {
+	created := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	deleted := created.Add(48 * time.Hour)
+	checked := created.Add(time.Hour)
+	quota := int32(5)
+	description := "Customer portal"
+	resourceID := "101"
+	archivedName := "legacy"
+	return &memoryStore{
+		orgs: []*Organization{
+			{ID: "0d1f6a2e-7c3b-4e58-9a40-2b6c8e1f3d57", Name: "Acme", UnixName: "acme", MaxActiveProjects: &quota,
+				ProvisionPriority: "high", CreatedAt: created, UpdatedAt: created},
+			{ID: "5b0e1c9d-3f47-4a2e-8d61-9c7f2a4b8e10", Name: "Globex", UnixName: "globex", CreatedAt: created, UpdatedAt: created},
+		},
+		projects: []*Project{
+			{ID: "1a7c3e5f-2b4d-4c6e-8f90-a1b2c3d4e5f6", Name: "Portal", UnixName: "portal", Description: &description,
+				Active: true, Status: "provisioned", OrgID: "0d1f6a2e-7c3b-4e58-9a40-2b6c8e1f3d57",
+				Labels:          json.RawMessage(`{"env":"prod"}`),
+				ProvisionParams: json.RawMessage(`{"plugin":"proxmox","node":"pve1"}`),
+				ResourceID:      &resourceID,
+				Network:         json.RawMessage(`{"hostname":"portal","ipv4":["10.0.0.5"]}`),
+				CreatedAt:       created, UpdatedAt: checked, LastCheckedAt: &checked},
+			{ID: "2b8d4f60-3c5e-4d7f-9a01-b2c3d4e5f607", Name: "Sandbox", UnixName: "sandbox", Status: "pending",
+				Labels: json.RawMessage(`{}`), CreatedAt: created, UpdatedAt: created},
+			{ID: "3c9e5071-4d6f-4e80-ab12-c3d4e5f60718", Name: "Legacy", UnixName: "legacy-deleted-1772539200000",
+				ArchivedUnixName: &archivedName, Status: "failed", OrgID: "5b0e1c9d-3f47-4a2e-8d61-9c7f2a4b8e10",
+				Labels: json.RawMessage(`{}`), CreatedAt: created, UpdatedAt: deleted, DeletedAt: &deleted},
+		},
+	}
+}
+
+// archive gzips lines, one record each, as Backup would write them.
+func archive(t *testing.T, lines ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := io.WriteString(gz, strings.Join(lines, "\n")+"\n"); err != nil {
+		t.Fatalf("failed to write archive: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("failed to close archive: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestBackupRoundTripsThroughRestore(t *testing.T) {
+	source := fleet()
+	var buf bytes.Buffer
+	if err := newService(source, nil).Backup(context.Background(), &buf); err != nil {
+		t.Fatalf("Backup() error = %v", err)
+	}
+
+	target := &memoryStore{}
+	result, err := newService(target, nil).Restore(context.Background(), &buf)
+	if err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if want := (RestoreResult{Organizations: 2, Projects: 3}); *result != want {
+		t.Errorf("Restore() = %+v, want %+v", *result, want)
+	}
+	if !reflect.DeepEqual(target.orgs, source.orgs) {
+		t.Errorf("restored organizations differ:\n got %+v\nwant %+v", target.orgs, source.orgs)
+	}
+	if !reflect.DeepEqual(target.projects, source.projects) {
+		t.Errorf("restored projects differ:\n got %+v\nwant %+v", target.projects, source.projects)
+	}
+}
+
+func TestBackupWritesHeaderFirst(t *testing.T) {
+	now := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+	s := newService(fleet(), nil)
+	s.now = func() time.Time { return now }
+
+	var buf bytes.Buffer
+	if err := s.Backup(context.Background(), &buf); err != nil {
+		t.Fatalf("Backup() error = %v", err)
+	}
+	gz, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatalf("archive is not gzip: %v", err)
+	}
+	raw, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("failed to read archive: %v", err)
+	}
+
+	var kinds []string
+	for i, line := range strings.Split(strings.TrimSuffix(string(raw), "\n"), "\n") {
+		var record Record
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("line %d is not a JSON record: %v", i+1, err)
+		}
+		if i == 0 && (record.Header == nil || record.Header.Format != FormatVersion || !record.Header.CreatedAt.Equal(now)) {
+			t.Errorf("header = %+v, want format %d created at %s", record.Header, FormatVersion, now)
+		}
+		kinds = append(kinds, record.Kind)
+	}
+	want := []string{KindHeader, KindOrganization, KindOrganization, KindProject, KindProject, KindProject}
+	if !reflect.DeepEqual(kinds, want) {
+		t.Errorf("records = %v, want %v", kinds, want)
+	}
+}
+
+func TestBackupOfEmptyDatabaseRestores(t *testing.T) {
+	var buf bytes.Buffer
+	if err := newService(&memoryStore{}, nil).Backup(context.Background(), &buf); err != nil {
+		t.Fatalf("Backup() error = %v", err)
+	}
+	result, err := newService(&memoryStore{}, nil).Restore(context.Background(), &buf)
+	if err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if *result != (RestoreResult{}) {
+		t.Errorf("Restore() = %+v, want nothing restored", *result)
+	}
+}
+
+func TestBackupFailingBeforeFirstRowWritesNothing(t *testing.T) {
+	var buf bytes.Buffer
+	err := newService(&memoryStore{dumpErr: errors.New("connection refused")}, nil).Backup(context.Background(), &buf)
+	if err == nil {
+		t.Fatal("Backup() error = nil, want the dump error")
+	}
+	if buf.Len() != 0 {
+		t.Errorf("wrote %d bytes, want none so the caller can still report the error", buf.Len())
+	}
+}
+
+func TestRestoreRefusesNonEmptyDatabase(t *testing.T) {
+	var buf bytes.Buffer
+	if err := newService(fleet(), nil).Backup(context.Background(), &buf); err != nil {
+		t.Fatalf("Backup() error = %v", err)
+	}
+	target := fleet()
+	target.projects = target.projects[:1]
+
+	if _, err := newService(target, nil).Restore(context.Background(), &buf); !errors.Is(err, ErrDatabaseNotEmpty) {
+		t.Fatalf("Restore() error = %v, want ErrDatabaseNotEmpty", err)
+	}
+	if len(target.projects) != 1 {
+		t.Errorf("target holds %d projects, want it left alone", len(target.projects))
+	}
+}
+
+func TestRestoreRejectsInvalidArchives(t *testing.T) {
+	header := `{"kind":"header","header":{"format":1,"created_at":"2026-03-01T12:00:00Z"}}`
+	org := `{"kind":"organization","organization":{"id":"0d1f6a2e-7c3b-4e58-9a40-2b6c8e1f3d57","name":"Acme","unix_name":"acme"}}`
+	orphan := `{"kind":"project","project":{"id":"1a7c3e5f-2b4d-4c6e-8f90-a1b2c3d4e5f6","name":"Portal","unix_name":"portal",` +
+		`"status":"pending","org_id":"5b0e1c9d-3f47-4a2e-8d61-9c7f2a4b8e10","labels":{}}}`
+
+	var full bytes.Buffer
+	if err := newService(fleet(), nil).Backup(context.Background(), &full); err != nil {
+		t.Fatalf("Backup() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		body []byte
+	}{
+		{name: "not gzip", body: []byte(header + "\n")},
+		{name: "empty archive", body: archive(t)},
+		{name: "missing header", body: archive(t, org)},
+		{name: "unsupported format", body: archive(t, `{"kind":"header","header":{"format":2}}`, org)},
+		{name: "malformed record", body: archive(t, header, org, `{"kind":`)},
+		{name: "unknown kind", body: archive(t, header, `{"kind":"user"}`)},
+		{name: "project before its organization", body: archive(t, header, orphan, org)},
+		{name: "truncated", body: full.Bytes()[:full.Len()-8]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := &memoryStore{}
+			_, err := newService(target, nil).Restore(context.Background(), bytes.NewReader(tt.body))
+			if !errors.Is(err, ErrInvalidArchive) {
+				t.Fatalf("Restore() error = %v, want ErrInvalidArchive", err)
+			}
+			if len(target.orgs) != 0 || len(target.projects) != 0 {
+				t.Errorf("restored %d organizations and %d projects, want none", len(target.orgs), len(target.projects))
+			}
+		})
+	}
+}
@@ -0,0 +1,335 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/searge/quokka/internal/backup/db"
+	"github.com/searge/quokka/internal/platform"
+)
+
+// Store reads and writes whole tables for backups via sqlc.
+type Store struct {
+	pool         *pgxpool.Pool
+	queries      *db.Queries
+	queryTimeout time.Duration
+}
+
+// StoreOption configures optional Store behaviour.
+type StoreOption func(*Store)
+
+// WithQueryTimeout bounds every store query by d. A zero duration leaves
+// queries bounded only by the caller's context. A dump or restore as a
+// whole is not bounded by it, only each query it runs.
+func WithQueryTimeout(d time.Duration) StoreOption {
+	return func(s *Store) {
+		s.queryTimeout = d
+	}
+}
+
+// NewStore initializes a new Store instance.
+func NewStore(pool *pgxpool.Pool, opts ...StoreOption) *Store {
+	s := &Store{
+		pool:    pool,
+		queries: db.New(platform.NewPoolDB(pool)),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *Store) queryContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.queryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, s.queryTimeout)
+}
+
+// Dump passes every organization, then every project, to emit, reading
+// them batchSize rows at a time in id order so that memory stays flat
+// however many there are. All batches are read from one snapshot: rows
+// written while the dump runs are left out of it. Dump stops at the first
+// error emit returns.
+func (s *Store) Dump(ctx context.Context, batchSize int32, emit func(Record) error) error {
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return err
+	}
+	// Nothing is written: the snapshot is simply released
+	defer func() { _ = tx.Rollback(ctx) }()
+	queries := s.queries.WithTx(tx)
+
+	var after pgtype.UUID
+	for {
+		queryCtx, cancel := s.queryContext(ctx)
+		rows, err := queries.ListOrganizationsAfter(queryCtx, db.ListOrganizationsAfterParams{After: after, Limit: batchSize})
+		cancel()
+		if err != nil {
+			return fmt.Errorf("list organizations: %w", err)
+		}
+		for _, row := range rows {
+			if err := emit(Record{Kind: KindOrganization, Organization: mapToBackupOrganization(row)}); err != nil {
+				return err
+			}
+		}
+		if len(rows) < int(batchSize) {
+			break
+		}
+		after = rows[len(rows)-1].ID
+	}
+
+	after = pgtype.UUID{}
+	for {
+		queryCtx, cancel := s.queryContext(ctx)
+		rows, err := queries.ListProjectsAfter(queryCtx, db.ListProjectsAfterParams{After: after, Limit: batchSize})
+		cancel()
+		if err != nil {
+			return fmt.Errorf("list projects: %w", err)
+		}
+		for _, row := range rows {
+			if err := emit(Record{Kind: KindProject, Project: mapToBackupProject(row)}); err != nil {
+				return err
+			}
+		}
+		if len(rows) < int(batchSize) {
+			break
+		}
+		after = rows[len(rows)-1].ID
+	}
+	return nil
+}
+
+// Restore inserts the organization and project records yielded by
+// records, as they come, in a single transaction that only commits once
+// all of them are in. It returns ErrDatabaseNotEmpty, inserting nothing,
+// if the database already holds an organization or a project, and an
+// error wrapping ErrInvalidArchive for records the schema rejects, such as
+// a project whose organization was not restored before it.
+func (s *Store) Restore(ctx context.Context, records iter.Seq2[Record, error]) (err error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(ctx); rbErr != nil && !errors.Is(rbErr, pgx.ErrTxClosed) {
+			err = errors.Join(err, rbErr)
+		}
+	}()
+	queries := s.queries.WithTx(tx)
+
+	emptyCtx, cancel := s.queryContext(ctx)
+	empty, err := queries.DatabaseIsEmpty(emptyCtx)
+	cancel()
+	if err != nil {
+		return err
+	}
+	if !empty {
+		return ErrDatabaseNotEmpty
+	}
+
+	for record, err := range records {
+		if err != nil {
+			return err
+		}
+		if err := s.restoreRecord(ctx, queries, record); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// restoreRecord inserts a single organization or project record.
+func (s *Store) restoreRecord(ctx context.Context, queries *db.Queries, record Record) error {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	switch {
+	case record.Kind == KindOrganization && record.Organization != nil:
+		params, err := restoreOrganizationParams(record.Organization)
+		if err != nil {
+			return err
+		}
+		if err := queries.RestoreOrganization(ctx, params); err != nil {
+			return classifyRestoreError(fmt.Sprintf("organization %s", record.Organization.ID), err)
+		}
+	case record.Kind == KindProject && record.Project != nil:
+		params, err := restoreProjectParams(record.Project)
+		if err != nil {
+			return err
+		}
+		if err := queries.RestoreProject(ctx, params); err != nil {
+			return classifyRestoreError(fmt.Sprintf("project %s", record.Project.ID), err)
+		}
+	default:
+		return fmt.Errorf("%w: unexpected %q record", ErrInvalidArchive, record.Kind)
+	}
+	return nil
+}
+
+// classifyRestoreError tags the constraint violations of restoring what as
+// ErrInvalidArchive; other errors are returned as they are.
+// Pure function.
+func classifyRestoreError(what string, err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+	switch pgErr.Code {
+	case "23502", "23503", "23505", "23514", "22P02":
+		// not null, foreign key, unique, check, invalid JSON
+		return fmt.Errorf("%w: %s: %s", ErrInvalidArchive, what, pgErr.Message)
+	}
+	return err
+}
+
+func restoreOrganizationParams(org *Organization) (db.RestoreOrganizationParams, error) {
+	id, err := parseArchivedID(org.ID)
+	if err != nil {
+		return db.RestoreOrganizationParams{}, err
+	}
+	params := db.RestoreOrganizationParams{
+		ID:                id,
+		Name:              org.Name,
+		UnixName:          org.UnixName,
+		ProvisionPriority: pgtype.Text{String: org.ProvisionPriority, Valid: org.ProvisionPriority != ""},
+		DefaultLabels:     jsonObject(org.DefaultLabels),
+		SyncedLabels:      jsonObject(org.SyncedLabels),
+		CreatedAt:         pgtype.Timestamptz{Time: org.CreatedAt, Valid: true},
+		UpdatedAt:         pgtype.Timestamptz{Time: org.UpdatedAt, Valid: true},
+	}
+	if org.MaxActiveProjects != nil {
+		params.MaxActiveProjects = pgtype.Int4{Int32: *org.MaxActiveProjects, Valid: true}
+	}
+	return params, nil
+}
+
+func restoreProjectParams(project *Project) (db.RestoreProjectParams, error) {
+	id, err := parseArchivedID(project.ID)
+	if err != nil {
+		return db.RestoreProjectParams{}, err
+	}
+	var orgID pgtype.UUID
+	if project.OrgID != "" {
+		if orgID, err = parseArchivedID(project.OrgID); err != nil {
+			return db.RestoreProjectParams{}, err
+		}
+	}
+	return db.RestoreProjectParams{
+		ID:               id,
+		Name:             project.Name,
+		UnixName:         project.UnixName,
+		Description:      toPgText(project.Description),
+		Active:           project.Active,
+		CreatedAt:        pgtype.Timestamptz{Time: project.CreatedAt, Valid: true},
+		UpdatedAt:        pgtype.Timestamptz{Time: project.UpdatedAt, Valid: true},
+		ProvisionParams:  project.ProvisionParams,
+		Labels:           project.Labels,
+		Status:           project.Status,
+		DeletedAt:        toPgTimestamptz(project.DeletedAt),
+		OrgID:            orgID,
+		ResourceID:       toPgText(project.ResourceID),
+		Node:             toPgText(project.Node),
+		ArchivedUnixName: toPgText(project.ArchivedUnixName),
+		Network:          project.Network,
+		LastCheckedAt:    toPgTimestamptz(project.LastCheckedAt),
+	}, nil
+}
+
+// jsonObject returns raw, or an empty JSON object if raw is empty. Pure
+// function.
+func jsonObject(raw json.RawMessage) []byte {
+	if len(raw) == 0 {
+		return []byte("{}")
+	}
+	return raw
+}
+
+// parseArchivedID parses an id read from an archive.
+func parseArchivedID(id string) (pgtype.UUID, error) {
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return pgtype.UUID{}, fmt.Errorf("%w: invalid id %q", ErrInvalidArchive, id)
+	}
+	return pgtype.UUID{Bytes: uid, Valid: true}, nil
+}
+
+func toPgText(s *string) pgtype.Text {
+	if s == nil {
+		return pgtype.Text{}
+	}
+	return pgtype.Text{String: *s, Valid: true}
+}
+
+func toPgTimestamptz(t *time.Time) pgtype.Timestamptz {
+	if t == nil {
+		return pgtype.Timestamptz{}
+	}
+	return pgtype.Timestamptz{Time: *t, Valid: true}
+}
+
+func fromPgText(t pgtype.Text) *string {
+	if !t.Valid {
+		return nil
+	}
+	return &t.String
+}
+
+func fromPgTimestamptz(t pgtype.Timestamptz) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func mapToBackupOrganization(row db.Organization) *Organization {
+	org := &Organization{
+		ID:                uuid.UUID(row.ID.Bytes).String(),
+		Name:              row.Name,
+		UnixName:          row.UnixName,
+		ProvisionPriority: row.ProvisionPriority.String,
+		DefaultLabels:     row.DefaultLabels,
+		SyncedLabels:      row.SyncedLabels,
+		CreatedAt:         row.CreatedAt.Time,
+		UpdatedAt:         row.UpdatedAt.Time,
+	}
+	if row.MaxActiveProjects.Valid {
+		limit := row.MaxActiveProjects.Int32
+		org.MaxActiveProjects = &limit
+	}
+	return org
+}
+
+func mapToBackupProject(row db.Project) *Project {
+	project := &Project{
+		ID:               uuid.UUID(row.ID.Bytes).String(),
+		Name:             row.Name,
+		UnixName:         row.UnixName,
+		Description:      fromPgText(row.Description),
+		Active:           row.Active,
+		Status:           row.Status,
+		Labels:           row.Labels,
+		ProvisionParams:  row.ProvisionParams,
+		ResourceID:       fromPgText(row.ResourceID),
+		Node:             fromPgText(row.Node),
+		Network:          row.Network,
+		ArchivedUnixName: fromPgText(row.ArchivedUnixName),
+		CreatedAt:        row.CreatedAt.Time,
+		UpdatedAt:        row.UpdatedAt.Time,
+		DeletedAt:        fromPgTimestamptz(row.DeletedAt),
+		LastCheckedAt:    fromPgTimestamptz(row.LastCheckedAt),
+	}
+	if row.OrgID.Valid {
+		project.OrgID = uuid.UUID(row.OrgID.Bytes).String()
+	}
+	return project
+}
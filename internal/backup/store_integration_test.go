@@ -0,0 +1,90 @@
+//go:build integration
+
+package backup
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestStoreDumpPagesThroughEveryRow(t *testing.T) {
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("DATABASE_URL is not set")
+	}
+	pool, err := pgxpool.New(context.Background(), dbURL)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	ctx := context.Background()
+
+	orgID, suffix := uuid.New(), uuid.NewString()[:8]
+	if _, err := pool.Exec(ctx,
+		"INSERT INTO organizations (id, name, unix_name) VALUES ($1, 'Backup', $2)", orgID, "backup-"+suffix); err != nil {
+		t.Fatalf("failed to seed organization: %v", err)
+	}
+	liveID, deletedID := uuid.New(), uuid.New()
+	if _, err := pool.Exec(ctx,
+		`INSERT INTO projects (id, name, unix_name, org_id, resource_id, deleted_at) VALUES
+		    ($1, 'Live', $3, $5, '101', NULL),
+		    ($2, 'Deleted', $4, $5, NULL, NOW())`,
+		liveID, deletedID, "live-"+suffix, "deleted-"+suffix, orgID); err != nil {
+		t.Fatalf("failed to seed projects: %v", err)
+	}
+	t.Cleanup(func() {
+		ctx := context.Background()
+		if _, err := pool.Exec(ctx, "DELETE FROM projects WHERE id = ANY($1)", []uuid.UUID{liveID, deletedID}); err != nil {
+			t.Errorf("cleanup failed: %v", err)
+		}
+		if _, err := pool.Exec(ctx, "DELETE FROM organizations WHERE id = $1", orgID); err != nil {
+			t.Errorf("cleanup failed: %v", err)
+		}
+	})
+
+	store := NewStore(pool, WithQueryTimeout(5*time.Second))
+	seen := make(map[string]Record)
+	var kinds []string
+	// A batch of one row makes every row its own page
+	err = store.Dump(ctx, 1, func(record Record) error {
+		kinds = append(kinds, record.Kind)
+		switch record.Kind {
+		case KindOrganization:
+			seen[record.Organization.ID] = record
+		case KindProject:
+			seen[record.Project.ID] = record
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Dump() error = %v", err)
+	}
+
+	if _, ok := seen[orgID.String()]; !ok {
+		t.Error("organization missing from the dump")
+	}
+	live, ok := seen[liveID.String()]
+	if !ok || live.Project.ResourceID == nil || *live.Project.ResourceID != "101" || live.Project.OrgID != orgID.String() {
+		t.Errorf("live project dumped as %+v, want it with its resource and organization", live.Project)
+	}
+	if deleted, ok := seen[deletedID.String()]; !ok || deleted.Project.DeletedAt == nil {
+		t.Errorf("soft-deleted project dumped as %+v, want it with deleted_at", deleted.Project)
+	}
+	for i := 1; i < len(kinds); i++ {
+		if kinds[i-1] == KindProject && kinds[i] == KindOrganization {
+			t.Fatal("an organization was dumped after a project")
+		}
+	}
+
+	// The database now holds rows, so it cannot be restored into
+	err = store.Restore(ctx, func(func(Record, error) bool) {})
+	if !errors.Is(err, ErrDatabaseNotEmpty) {
+		t.Errorf("Restore() error = %v, want ErrDatabaseNotEmpty", err)
+	}
+}
@@ -0,0 +1,82 @@
+// Package backup dumps every organization and project to a single archive
+// and restores such an archive into an empty database, for disaster
+// recovery.
+//
+// An archive is gzip-compressed newline-delimited JSON: a header record,
+// then every organization, then every project, so that a project is only
+// restored once the organization owning it has been.
+package backup
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// FormatVersion is the archive format written by Backup. Restore refuses
+// archives of any other version.
+const FormatVersion = 1
+
+// Kinds of archive records.
+const (
+	KindHeader       = "header"
+	KindOrganization = "organization"
+	KindProject      = "project"
+)
+
+// Record is one line of an archive. Exactly one of its payloads is set,
+// as named by Kind.
+type Record struct {
+	Kind         string        `json:"kind"`
+	Header       *Header       `json:"header,omitempty"`
+	Organization *Organization `json:"organization,omitempty"`
+	Project      *Project      `json:"project,omitempty"`
+}
+
+// Header opens an archive.
+type Header struct {
+	Format    int       `json:"format"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Organization is an organizations row as archived. Its JSON columns are
+// kept verbatim; archives from before they existed restore them empty.
+type Organization struct {
+	ID                string          `json:"id"`
+	Name              string          `json:"name"`
+	UnixName          string          `json:"unix_name"`
+	MaxActiveProjects *int32          `json:"max_active_projects,omitempty"`
+	ProvisionPriority string          `json:"provision_priority,omitempty"`
+	DefaultLabels     json.RawMessage `json:"default_labels,omitempty"`
+	SyncedLabels      json.RawMessage `json:"synced_labels,omitempty"`
+	CreatedAt         time.Time       `json:"created_at"`
+	UpdatedAt         time.Time       `json:"updated_at"`
+}
+
+// Project is a projects row as archived, soft-deleted or not, along with
+// the record of the resource backing it. Its JSON columns are kept
+// verbatim.
+type Project struct {
+	ID               string          `json:"id"`
+	Name             string          `json:"name"`
+	UnixName         string          `json:"unix_name"`
+	Description      *string         `json:"description,omitempty"`
+	Active           bool            `json:"active"`
+	Status           string          `json:"status"`
+	OrgID            string          `json:"org_id,omitempty"`
+	Labels           json.RawMessage `json:"labels"`
+	ProvisionParams  json.RawMessage `json:"provision_params,omitempty"`
+	ResourceID       *string         `json:"resource_id,omitempty"`
+	Node             *string         `json:"node,omitempty"`
+	Network          json.RawMessage `json:"network,omitempty"`
+	ArchivedUnixName *string         `json:"archived_unix_name,omitempty"`
+	CreatedAt        time.Time       `json:"created_at"`
+	UpdatedAt        time.Time       `json:"updated_at"`
+	DeletedAt        *time.Time      `json:"deleted_at,omitempty"`
+	LastCheckedAt    *time.Time      `json:"last_checked_at,omitempty"`
+}
+
+// RestoreResult counts the rows a restore inserted.
+type RestoreResult struct {
+	Organizations int `json:"organizations"`
+	Projects      int `json:"projects"`
+}
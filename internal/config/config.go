@@ -5,6 +5,9 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Config holds application configuration.
@@ -12,14 +15,301 @@ import (
 type Config struct {
 	LogLevel string
 	Debug    bool
+	Server   ServerConfig
+	Database DatabaseConfig
+	Projects ProjectsConfig
+	Orgs     OrgsConfig
+	Plugins  PluginsConfig
+	Proxmox  ProxmoxConfig
+	Queue    QueueConfig
+
+	// Environment names the deployment (e.g. dev, staging, prod) in
+	// responses and logs.
+	Environment string
+
+	// TestingMode lets a create's X-Provision-Plugin header pick the
+	// plugin that provisions it, e.g. to route end-to-end tests to the
+	// fake plugin. Never enable it in production.
+	TestingMode bool
+
+	// ProvisioningEnabled lets creates call plugins. Without it Quokka is a
+	// pure metadata store for deployments that provision out of band: new
+	// projects are unmanaged and plugin-backed endpoints answer 501.
+	ProvisioningEnabled bool
+
+	// LogFormat is json or text.
+	LogFormat string
+	// LogOutput is stdout, stderr or a file path that is appended to.
+	LogOutput string
+	// LogRedactPattern matches log attribute keys whose values are masked.
+	LogRedactPattern string
+}
+
+// ServerConfig holds HTTP server settings.
+type ServerConfig struct {
+	// Addr is the TCP address the API listens on, e.g. ":8080".
+	Addr string
+	// TLSCertFile and TLSKeyFile enable HTTPS when both are set.
+	TLSCertFile string
+	TLSKeyFile  string
+	// ReadOnly starts the API rejecting all writes; it can be toggled at runtime.
+	ReadOnly bool
+	// HealthPath serves liveness; HealthPath+"/live" and HealthPath+"/ready"
+	// serve the liveness and readiness variants.
+	HealthPath string
+	// MaxInFlight caps concurrently served API requests. Zero derives the
+	// cap from the database pool; see MaxInFlightRequests.
+	MaxInFlight int
+	// FieldNaming is the convention JSON fields are written in for clients
+	// that do not ask for one: snake_case or camelCase.
+	FieldNaming string
+	// AdminToken, when set, is the bearer token that makes a caller an
+	// admin, allowed to act on any organization's projects.
+	AdminToken string
+	// RateLimit caps API requests per client IP in each RateLimitWindow.
+	// Zero disables rate limiting.
+	RateLimit int
+	// RateLimitWindow is the period over which RateLimit applies.
+	RateLimitWindow time.Duration
+	// ShutdownTimeout bounds how long a stopping server waits for running
+	// operations to finish before cancelling them. It must cover a
+	// provisioning call; see MinShutdownTimeout.
+	ShutdownTimeout time.Duration
+	// AuditStream streams every project mutation, and with Plugins.Audit
+	// every plugin operation, to admins at GET /api/v1/admin/audit/stream.
+	// Without it the route is not served.
+	AuditStream bool
+}
+
+// TLSEnabled reports whether the server should serve HTTPS.
+func (s ServerConfig) TLSEnabled() bool {
+	return s.TLSCertFile != "" || s.TLSKeyFile != ""
+}
+
+// inFlightPerConn is how many in-flight requests each pooled database
+// connection is expected to absorb when MaxInFlight is not set.
+const inFlightPerConn = 4
+
+// MaxInFlightRequests returns the effective API concurrency cap: MaxInFlight
+// if set, otherwise a multiple of the database pool size.
+// Pure function.
+func (c Config) MaxInFlightRequests() int {
+	if c.Server.MaxInFlight > 0 {
+		return c.Server.MaxInFlight
+	}
+	return int(c.Database.MaxConns) * inFlightPerConn
+}
+
+// DatabaseConfig holds PostgreSQL connection and query settings.
+type DatabaseConfig struct {
+	URL      string
+	MaxConns int32
+	MinConns int32
+	// QueryTimeout bounds each individual store query. Zero disables it.
+	QueryTimeout time.Duration
+	// HealthCheckPeriod is how often the pool checks idle connections,
+	// dropping broken ones before a query runs into them.
+	HealthCheckPeriod time.Duration
+	// SlowQueryThreshold logs every query that takes at least this long.
+	// Zero, the default, disables slow query logging.
+	SlowQueryThreshold time.Duration
+}
+
+// ProjectsConfig holds settings for the projects domain.
+type ProjectsConfig struct {
+	// IDVersion, when non-zero, rejects project IDs of any other UUID version.
+	IDVersion int
+	// DeferCreateEvents publishes project.created only once provisioning
+	// has succeeded or failed.
+	DeferCreateEvents bool
+	// EventOutbox writes project events to the events_outbox table in the
+	// same transaction as the change they describe; a background relay
+	// publishes them every EventRelayInterval, retrying failures, and
+	// deletes them once sent more than EventOutboxRetention ago.
+	EventOutbox          bool
+	EventRelayInterval   time.Duration
+	EventOutboxRetention time.Duration
+	// CreateDedupWindow collapses identical creates from the same client
+	// within this window onto one project. Zero disables it.
+	CreateDedupWindow time.Duration
+	// UnixNamePattern, UnixNameMinLength and UnixNameMaxLength define which
+	// unix names new projects may use.
+	UnixNamePattern   string
+	UnixNameMinLength int
+	UnixNameMaxLength int
+	// PurgeEnabled runs a background job hard-deleting projects that were
+	// soft-deleted more than DeletedRetention ago, every PurgeInterval.
+	PurgeEnabled     bool
+	DeletedRetention time.Duration
+	PurgeInterval    time.Duration
+	// ArchiveDeletedUnixNames renames projects as they are deleted so their
+	// unix_name can be reused before the purge.
+	ArchiveDeletedUnixNames bool
+	// UniqueNames rejects a display name already used by another project,
+	// compared case-insensitively.
+	UniqueNames bool
+	// ReservedUnixNames are unix names new projects may not take,
+	// compared case-insensitively.
+	ReservedUnixNames []string
+	// DefaultLabels are added to new projects that do not set those keys,
+	// and DefaultDescription is used for new projects without one.
+	DefaultLabels      map[string]string
+	DefaultDescription string
+	// TagLabels lists the label keys whose labels are propagated to the
+	// backend as resource tags; "*" propagates every label. Empty
+	// propagates none.
+	TagLabels []string
+	// EncryptionKeys are "<id>:<base64 AES key>" specs encrypting the
+	// provision resources named by EncryptedFields at rest. The first key
+	// encrypts; the others are kept to decrypt what they encrypted before
+	// being rotated out.
+	EncryptionKeys  []string
+	EncryptedFields []string
+	// StatusCacheTTL is how long a resource status lookup is reused.
+	// Zero only coalesces concurrent lookups.
+	StatusCacheTTL time.Duration
+	// AsyncWorkers caps how many asynchronous creates run at once; the
+	// rest wait their turn by priority. Zero runs every one immediately.
+	AsyncWorkers int
+	// AsyncQueueDepth caps how many asynchronous creates may wait for a
+	// worker; further ones are refused with 503. Zero lets any number wait.
+	AsyncQueueDepth int
+	// DefaultProvisionEstimate is how long provisioning is expected to take
+	// with a plugin and template that have not provisioned anything since
+	// startup; later estimates average recent runs. Zero gives none.
+	DefaultProvisionEstimate time.Duration
+}
+
+// OrgsConfig holds organization settings.
+type OrgsConfig struct {
+	// DefaultMaxActiveProjects caps active projects for organizations
+	// without a quota of their own. Zero means unlimited.
+	DefaultMaxActiveProjects int32
+	// ProvisionLimit caps how many projects each organization may create
+	// in each ProvisionWindow, to protect backend capacity. Zero disables it.
+	ProvisionLimit  int
+	ProvisionWindow time.Duration
+}
+
+// PluginsConfig holds settings shared by all plugins.
+type PluginsConfig struct {
+	// Audit records every plugin operation with its input, result and
+	// duration, adding the records to the audit stream if it is on.
+	Audit bool
+	// Allowed names the plugins new provisioning may use. Other registered
+	// plugins stay available for status and health checks. Empty allows all.
+	Allowed []string
+	// RetryAttempts is how many times provisioning is tried, including the
+	// first call.
+	RetryAttempts int
+	// RetryBackoff is the wait between provisioning attempts.
+	RetryBackoff time.Duration
+	// CallTimeout bounds each provisioning attempt. Zero disables it.
+	CallTimeout time.Duration
+	// RetryBudget bounds all attempts of one provisioning together, so
+	// retries stop once it is spent. Zero disables it.
+	RetryBudget time.Duration
+	// HealthTimeout bounds each plugin's health check; HealthTimeouts
+	// overrides it per plugin name.
+	HealthTimeout  time.Duration
+	HealthTimeouts map[string]time.Duration
+	// ProvisionConcurrency caps how many provisioning calls each named
+	// plugin runs at once, to match its backend's capacity. Plugins not
+	// named, or set to zero, are unlimited.
+	ProvisionConcurrency map[string]int
+}
+
+// ProxmoxConfig holds settings for the forge-ovh-cli backed Proxmox plugin.
+type ProxmoxConfig struct {
+	// CLIPath is the forge-ovh-cli executable; empty means look it up on PATH.
+	CLIPath string
+	// OutputFormat selects how CLI output is parsed: keyvalue, json or regex.
+	OutputFormat string
+	// OutputPattern is the regex used when OutputFormat is "regex".
+	OutputPattern string
+	// NamePrefix and NameSuffix qualify every VM name, e.g. "prod-".
+	NamePrefix string
+	NameSuffix string
+	// Nodes lists the cluster nodes projects may be pinned to; empty
+	// allows any. DefaultNode is used for projects that pin none; empty
+	// leaves placement to the CLI.
+	Nodes       []string
+	DefaultNode string
+	// Templates lists the templates that exist on the cluster; a project
+	// naming another is refused at create. Empty accepts any.
+	Templates []string
+	// SSHKeys are public keys installed on every provisioned resource,
+	// e.g. the ops team's.
+	SSHKeys []string
+}
+
+// QueueConfig holds settings for the queue plugin, which publishes
+// provisioning to a message queue for external workers to carry out.
+type QueueConfig struct {
+	// URL is the NATS server, nats://[user:password@]host[:port], or
+	// tls://... to require TLS. The server needs JetStream, with a stream
+	// storing the provision and deprovision subjects. Empty leaves the
+	// plugin unregistered.
+	URL string
+	// SubjectPrefix prefixes the subjects messages are published on, e.g.
+	// <prefix>.provision.
+	SubjectPrefix string
+	// StatusTimeout bounds the wait for a worker to answer a status request.
+	StatusTimeout time.Duration
 }
 
 // Default returns a Config with sensible defaults.
 // Pure function: no side effects.
 func Default() Config {
 	return Config{
-		LogLevel: "info",
-		Debug:    false,
+		LogLevel:         "info",
+		LogFormat:        "json",
+		LogOutput:        "stdout",
+		LogRedactPattern: `(?i)(password|passwd|secret|token|key)`,
+		Environment:      "unknown",
+		Debug:            false,
+
+		ProvisioningEnabled: true,
+		Server: ServerConfig{
+			Addr:            ":8080",
+			HealthPath:      "/api/v1/health",
+			FieldNaming:     "snake_case",
+			RateLimitWindow: time.Minute,
+			ShutdownTimeout: time.Minute,
+		},
+		Database: DatabaseConfig{
+			MaxConns:          10,
+			MinConns:          2,
+			QueryTimeout:      10 * time.Second,
+			HealthCheckPeriod: time.Minute,
+		},
+		Projects: ProjectsConfig{
+			EventRelayInterval:   time.Second,
+			EventOutboxRetention: 7 * 24 * time.Hour,
+			CreateDedupWindow:    2 * time.Second,
+			UnixNamePattern:      `^[a-z0-9-]+$`,
+			UnixNameMinLength:    3,
+			UnixNameMaxLength:    100,
+			DeletedRetention:     30 * 24 * time.Hour,
+			PurgeInterval:        time.Hour,
+			StatusCacheTTL:       5 * time.Second,
+			AsyncWorkers:         4,
+			AsyncQueueDepth:      100,
+		},
+		Orgs: OrgsConfig{
+			ProvisionWindow: time.Minute,
+		},
+		Plugins: PluginsConfig{
+			RetryAttempts: 1,
+			HealthTimeout: 5 * time.Second,
+		},
+		Proxmox: ProxmoxConfig{
+			OutputFormat: "keyvalue",
+		},
+		Queue: QueueConfig{
+			SubjectPrefix: "quokka.provision",
+			StatusTimeout: 5 * time.Second,
+		},
 	}
 }
 
@@ -35,11 +325,340 @@ func FromEnv() (Config, error) {
 		cfg.LogLevel = level
 	}
 
+	if format := os.Getenv("LOG_FORMAT"); format != "" {
+		if err := validateLogFormat(format); err != nil {
+			return Config{}, fmt.Errorf("invalid LOG_FORMAT: %w", err)
+		}
+		cfg.LogFormat = format
+	}
+	if output := os.Getenv("LOG_OUTPUT"); output != "" {
+		cfg.LogOutput = output
+	}
+	if pattern := os.Getenv("LOG_REDACT_PATTERN"); pattern != "" {
+		cfg.LogRedactPattern = pattern
+	}
+
 	cfg.Debug = os.Getenv("DEBUG") == "true"
+	cfg.TestingMode = os.Getenv("TESTING_MODE") == "true"
+	cfg.ProvisioningEnabled = os.Getenv("PROVISIONING_ENABLED") != "false"
+
+	if env := os.Getenv("ENVIRONMENT"); env != "" {
+		cfg.Environment = env
+	}
+
+	if addr := os.Getenv("LISTEN_ADDR"); addr != "" {
+		cfg.Server.Addr = addr
+	}
+	cfg.Server.TLSCertFile = os.Getenv("TLS_CERT_FILE")
+	cfg.Server.TLSKeyFile = os.Getenv("TLS_KEY_FILE")
+	cfg.Server.ReadOnly = os.Getenv("READ_ONLY") == "true"
+	if path := os.Getenv("HEALTH_PATH"); path != "" {
+		cfg.Server.HealthPath = path
+	}
+	if raw := os.Getenv("MAX_IN_FLIGHT_REQUESTS"); raw != "" {
+		n, err := parseInt32(raw)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid MAX_IN_FLIGHT_REQUESTS: %w", err)
+		}
+		cfg.Server.MaxInFlight = int(n)
+	}
+	if naming := os.Getenv("JSON_FIELD_NAMING"); naming != "" {
+		cfg.Server.FieldNaming = naming
+	}
+	cfg.Server.AdminToken = os.Getenv("ADMIN_TOKEN")
+	if raw := os.Getenv("RATE_LIMIT_REQUESTS"); raw != "" {
+		n, err := parseInt32(raw)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid RATE_LIMIT_REQUESTS: %w", err)
+		}
+		cfg.Server.RateLimit = int(n)
+	}
+	if raw := os.Getenv("RATE_LIMIT_WINDOW"); raw != "" {
+		d, err := parseDuration(raw)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid RATE_LIMIT_WINDOW: %w", err)
+		}
+		cfg.Server.RateLimitWindow = d
+	}
+	if raw := os.Getenv("SHUTDOWN_TIMEOUT"); raw != "" {
+		d, err := parseDuration(raw)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid SHUTDOWN_TIMEOUT: %w", err)
+		}
+		cfg.Server.ShutdownTimeout = d
+	}
+	cfg.Server.AuditStream = os.Getenv("AUDIT_STREAM") == "true"
+
+	cfg.Database.URL = os.Getenv("DATABASE_URL")
+	if raw := os.Getenv("DB_MAX_CONNS"); raw != "" {
+		n, err := parseInt32(raw)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid DB_MAX_CONNS: %w", err)
+		}
+		cfg.Database.MaxConns = n
+	}
+	if raw := os.Getenv("DB_MIN_CONNS"); raw != "" {
+		n, err := parseInt32(raw)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid DB_MIN_CONNS: %w", err)
+		}
+		cfg.Database.MinConns = n
+	}
+
+	if raw := os.Getenv("DB_QUERY_TIMEOUT"); raw != "" {
+		d, err := parseDuration(raw)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid DB_QUERY_TIMEOUT: %w", err)
+		}
+		cfg.Database.QueryTimeout = d
+	}
+	if raw := os.Getenv("DB_HEALTH_CHECK_PERIOD"); raw != "" {
+		d, err := parseDuration(raw)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid DB_HEALTH_CHECK_PERIOD: %w", err)
+		}
+		cfg.Database.HealthCheckPeriod = d
+	}
+	if raw := os.Getenv("DB_SLOW_QUERY_THRESHOLD"); raw != "" {
+		d, err := parseDuration(raw)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid DB_SLOW_QUERY_THRESHOLD: %w", err)
+		}
+		cfg.Database.SlowQueryThreshold = d
+	}
+
+	if raw := os.Getenv("PROJECT_ID_VERSION"); raw != "" {
+		n, err := parseInt32(raw)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid PROJECT_ID_VERSION: %w", err)
+		}
+		cfg.Projects.IDVersion = int(n)
+	}
+	cfg.Projects.DeferCreateEvents = os.Getenv("PROJECT_EVENTS_DEFER_CREATE") == "true"
+	cfg.Projects.EventOutbox = os.Getenv("PROJECT_EVENTS_OUTBOX") == "true"
+	if raw := os.Getenv("PROJECT_EVENTS_RELAY_INTERVAL"); raw != "" {
+		d, err := parseDuration(raw)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid PROJECT_EVENTS_RELAY_INTERVAL: %w", err)
+		}
+		cfg.Projects.EventRelayInterval = d
+	}
+	if raw := os.Getenv("PROJECT_EVENTS_OUTBOX_RETENTION"); raw != "" {
+		d, err := parseDuration(raw)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid PROJECT_EVENTS_OUTBOX_RETENTION: %w", err)
+		}
+		cfg.Projects.EventOutboxRetention = d
+	}
+	if raw := os.Getenv("PROJECT_CREATE_DEDUP_WINDOW"); raw != "" {
+		d, err := parseDuration(raw)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid PROJECT_CREATE_DEDUP_WINDOW: %w", err)
+		}
+		cfg.Projects.CreateDedupWindow = d
+	}
+	if raw := os.Getenv("PROJECT_UNIX_NAME_PATTERN"); raw != "" {
+		cfg.Projects.UnixNamePattern = raw
+	}
+	if raw := os.Getenv("PROJECT_UNIX_NAME_MIN_LENGTH"); raw != "" {
+		n, err := parseInt32(raw)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid PROJECT_UNIX_NAME_MIN_LENGTH: %w", err)
+		}
+		cfg.Projects.UnixNameMinLength = int(n)
+	}
+	if raw := os.Getenv("PROJECT_UNIX_NAME_MAX_LENGTH"); raw != "" {
+		n, err := parseInt32(raw)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid PROJECT_UNIX_NAME_MAX_LENGTH: %w", err)
+		}
+		cfg.Projects.UnixNameMaxLength = int(n)
+	}
+	cfg.Projects.PurgeEnabled = os.Getenv("PROJECT_PURGE_ENABLED") == "true"
+	cfg.Projects.ArchiveDeletedUnixNames = os.Getenv("PROJECT_ARCHIVE_DELETED_UNIX_NAMES") == "true"
+	cfg.Projects.UniqueNames = os.Getenv("PROJECT_UNIQUE_NAMES") == "true"
+	if raw := os.Getenv("PROJECT_DEFAULT_LABELS"); raw != "" {
+		labels, err := parseLabels(raw)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid PROJECT_DEFAULT_LABELS: %w", err)
+		}
+		cfg.Projects.DefaultLabels = labels
+	}
+	cfg.Projects.DefaultDescription = os.Getenv("PROJECT_DEFAULT_DESCRIPTION")
+	cfg.Projects.TagLabels = splitList(os.Getenv("PROJECT_TAG_LABELS"))
+	cfg.Projects.ReservedUnixNames = splitList(os.Getenv("PROJECT_RESERVED_UNIX_NAMES"))
+	cfg.Projects.EncryptionKeys = splitList(os.Getenv("PROJECT_ENCRYPTION_KEYS"))
+	cfg.Projects.EncryptedFields = splitList(os.Getenv("PROJECT_ENCRYPTED_FIELDS"))
+	if raw := os.Getenv("PROJECT_STATUS_CACHE_TTL"); raw != "" {
+		d, err := parseDuration(raw)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid PROJECT_STATUS_CACHE_TTL: %w", err)
+		}
+		cfg.Projects.StatusCacheTTL = d
+	}
+	if raw := os.Getenv("PROJECT_ASYNC_WORKERS"); raw != "" {
+		n, err := parseInt32(raw)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid PROJECT_ASYNC_WORKERS: %w", err)
+		}
+		cfg.Projects.AsyncWorkers = int(n)
+	}
+	if raw := os.Getenv("PROJECT_ASYNC_QUEUE_DEPTH"); raw != "" {
+		n, err := parseInt32(raw)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid PROJECT_ASYNC_QUEUE_DEPTH: %w", err)
+		}
+		cfg.Projects.AsyncQueueDepth = int(n)
+	}
+	if raw := os.Getenv("PROJECT_DEFAULT_PROVISION_ESTIMATE"); raw != "" {
+		d, err := parseDuration(raw)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid PROJECT_DEFAULT_PROVISION_ESTIMATE: %w", err)
+		}
+		cfg.Projects.DefaultProvisionEstimate = d
+	}
+	if raw := os.Getenv("PROJECT_DELETED_RETENTION"); raw != "" {
+		d, err := parseDuration(raw)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid PROJECT_DELETED_RETENTION: %w", err)
+		}
+		cfg.Projects.DeletedRetention = d
+	}
+	if raw := os.Getenv("PROJECT_PURGE_INTERVAL"); raw != "" {
+		d, err := parseDuration(raw)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid PROJECT_PURGE_INTERVAL: %w", err)
+		}
+		cfg.Projects.PurgeInterval = d
+	}
+
+	if raw := os.Getenv("ORG_DEFAULT_MAX_ACTIVE_PROJECTS"); raw != "" {
+		n, err := parseInt32(raw)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid ORG_DEFAULT_MAX_ACTIVE_PROJECTS: %w", err)
+		}
+		cfg.Orgs.DefaultMaxActiveProjects = n
+	}
+	if raw := os.Getenv("ORG_PROVISION_LIMIT"); raw != "" {
+		n, err := parseInt32(raw)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid ORG_PROVISION_LIMIT: %w", err)
+		}
+		cfg.Orgs.ProvisionLimit = int(n)
+	}
+	if raw := os.Getenv("ORG_PROVISION_WINDOW"); raw != "" {
+		d, err := parseDuration(raw)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid ORG_PROVISION_WINDOW: %w", err)
+		}
+		cfg.Orgs.ProvisionWindow = d
+	}
+
+	cfg.Plugins.Audit = os.Getenv("PLUGIN_AUDIT") == "true"
+	cfg.Plugins.Allowed = splitList(os.Getenv("PLUGIN_ALLOWLIST"))
+	if raw := os.Getenv("PLUGIN_RETRY_ATTEMPTS"); raw != "" {
+		n, err := parseInt32(raw)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid PLUGIN_RETRY_ATTEMPTS: %w", err)
+		}
+		cfg.Plugins.RetryAttempts = int(n)
+	}
+	if raw := os.Getenv("PLUGIN_RETRY_BACKOFF"); raw != "" {
+		d, err := parseDuration(raw)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid PLUGIN_RETRY_BACKOFF: %w", err)
+		}
+		cfg.Plugins.RetryBackoff = d
+	}
+	if raw := os.Getenv("PLUGIN_CALL_TIMEOUT"); raw != "" {
+		d, err := parseDuration(raw)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid PLUGIN_CALL_TIMEOUT: %w", err)
+		}
+		cfg.Plugins.CallTimeout = d
+	}
+	if raw := os.Getenv("PLUGIN_RETRY_BUDGET"); raw != "" {
+		d, err := parseDuration(raw)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid PLUGIN_RETRY_BUDGET: %w", err)
+		}
+		cfg.Plugins.RetryBudget = d
+	}
+	if raw := os.Getenv("PLUGIN_HEALTH_TIMEOUT"); raw != "" {
+		d, err := parseDuration(raw)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid PLUGIN_HEALTH_TIMEOUT: %w", err)
+		}
+		cfg.Plugins.HealthTimeout = d
+	}
+	if raw := os.Getenv("PLUGIN_HEALTH_TIMEOUTS"); raw != "" {
+		timeouts, err := parseDurations(raw)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid PLUGIN_HEALTH_TIMEOUTS: %w", err)
+		}
+		cfg.Plugins.HealthTimeouts = timeouts
+	}
+	if raw := os.Getenv("PLUGIN_PROVISION_CONCURRENCY"); raw != "" {
+		limits, err := parseCounts(raw)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid PLUGIN_PROVISION_CONCURRENCY: %w", err)
+		}
+		cfg.Plugins.ProvisionConcurrency = limits
+	}
+
+	cfg.Proxmox.CLIPath = os.Getenv("PROXMOX_CLI_PATH")
+	if format := os.Getenv("PROXMOX_OUTPUT_FORMAT"); format != "" {
+		if err := validateOutputFormat(format); err != nil {
+			return Config{}, fmt.Errorf("invalid PROXMOX_OUTPUT_FORMAT: %w", err)
+		}
+		cfg.Proxmox.OutputFormat = format
+	}
+	cfg.Proxmox.OutputPattern = os.Getenv("PROXMOX_OUTPUT_PATTERN")
+	cfg.Proxmox.NamePrefix = os.Getenv("PROXMOX_NAME_PREFIX")
+	cfg.Proxmox.NameSuffix = os.Getenv("PROXMOX_NAME_SUFFIX")
+	cfg.Proxmox.Nodes = splitList(os.Getenv("PROXMOX_NODES"))
+	cfg.Proxmox.DefaultNode = os.Getenv("PROXMOX_DEFAULT_NODE")
+	cfg.Proxmox.Templates = splitList(os.Getenv("PROXMOX_TEMPLATES"))
+	cfg.Proxmox.SSHKeys = splitLines(os.Getenv("PROXMOX_SSH_KEYS"))
+
+	cfg.Queue.URL = os.Getenv("QUEUE_URL")
+	if prefix := os.Getenv("QUEUE_SUBJECT_PREFIX"); prefix != "" {
+		cfg.Queue.SubjectPrefix = prefix
+	}
+	if raw := os.Getenv("QUEUE_STATUS_TIMEOUT"); raw != "" {
+		d, err := parseDuration(raw)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid QUEUE_STATUS_TIMEOUT: %w", err)
+		}
+		cfg.Queue.StatusTimeout = d
+	}
 
 	return cfg, nil
 }
 
+// Overrides are settings given on the command line, which take precedence
+// over the environment. Empty fields leave the setting as it is.
+type Overrides struct {
+	Addr        string
+	LogLevel    string
+	DatabaseURL string
+}
+
+// Apply returns c with the settings of o in place. Validate the result as
+// usual. Pure function.
+func (o Overrides) Apply(c Config) Config {
+	if o.Addr != "" {
+		c.Server.Addr = o.Addr
+	}
+	if o.LogLevel != "" {
+		c.LogLevel = o.LogLevel
+	}
+	if o.DatabaseURL != "" {
+		c.Database.URL = o.DatabaseURL
+	}
+	return c
+}
+
 // validateLogLevel checks whether the value is an accepted log level.
 // Pure function.
 func validateLogLevel(level string) error {
@@ -51,3 +670,130 @@ func validateLogLevel(level string) error {
 	}
 	return nil
 }
+
+// validateLogFormat checks whether the value is a supported log format.
+// Pure function.
+func validateLogFormat(format string) error {
+	switch format {
+	case "json", "text":
+		return nil
+	default:
+		return fmt.Errorf("%q is not valid; choose: json, text", format)
+	}
+}
+
+// validateOutputFormat checks whether the value is a known CLI output format.
+// Pure function.
+func validateOutputFormat(format string) error {
+	switch format {
+	case "keyvalue", "json", "regex":
+		return nil
+	default:
+		return fmt.Errorf("%q is not valid; choose: keyvalue, json, regex", format)
+	}
+}
+
+// splitList splits a comma-separated list, trimming entries and dropping
+// empty ones. Returns nil for an empty list.
+// Pure function.
+func splitList(raw string) []string {
+	var out []string
+	for _, part := range strings.Split(raw, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// splitLines splits raw into its lines, as in an authorized_keys file,
+// trimming them and dropping empty ones. Returns nil if there are none.
+// Pure function.
+func splitLines(raw string) []string {
+	var out []string
+	for line := range strings.Lines(raw) {
+		if line = strings.TrimSpace(line); line != "" {
+			out = append(out, line)
+		}
+	}
+	return out
+}
+
+// parseLabels parses a comma-separated list of key=value pairs.
+// Pure function.
+func parseLabels(raw string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, pair := range splitList(raw) {
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("%q is not a key=value pair", pair)
+		}
+		labels[key] = strings.TrimSpace(value)
+	}
+	return labels, nil
+}
+
+// parseDurations parses a comma-separated list of name=duration pairs,
+// e.g. "proxmox=15s,fake=1s".
+// Pure function.
+func parseDurations(raw string) (map[string]time.Duration, error) {
+	pairs, err := parseLabels(raw)
+	if err != nil {
+		return nil, err
+	}
+	durations := make(map[string]time.Duration, len(pairs))
+	for name, value := range pairs {
+		d, err := parseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		durations[name] = d
+	}
+	return durations, nil
+}
+
+// parseCounts parses a comma-separated list of name=count pairs, e.g.
+// "proxmox=5,fake=100". Counts must not be negative.
+// Pure function.
+func parseCounts(raw string) (map[string]int, error) {
+	pairs, err := parseLabels(raw)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int, len(pairs))
+	for name, value := range pairs {
+		n, err := parseInt32(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if n < 0 {
+			return nil, fmt.Errorf("%s: %q must not be negative", name, value)
+		}
+		counts[name] = int(n)
+	}
+	return counts, nil
+}
+
+// parseDuration parses a non-negative Go duration string (e.g. "5s").
+// Pure function.
+func parseDuration(raw string) (time.Duration, error) {
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a valid duration", raw)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("%q must not be negative", raw)
+	}
+	return d, nil
+}
+
+// parseInt32 parses a base-10 integer that fits in an int32.
+// Pure function.
+func parseInt32(raw string) (int32, error) {
+	n, err := strconv.ParseInt(raw, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a valid integer", raw)
+	}
+	return int32(n), nil
+}
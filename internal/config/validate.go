@@ -0,0 +1,269 @@
+package config
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// maxNameAffixLength leaves room for a 3-character project name within the
+// 63-character hostname limit on VM names.
+const maxNameAffixLength = 60
+
+// maxUnixNameLength is the width of the projects.unix_name column.
+const maxUnixNameLength = 100
+
+// MinShutdownTimeout is the shortest ShutdownTimeout accepted: how long a
+// provisioning call may take, projects.ProvisionTimeout, so that a drain
+// never cuts one short.
+const MinShutdownTimeout = 30 * time.Second
+
+var (
+	nameAffixRegex       = regexp.MustCompile(`^[a-z0-9-]*$`)
+	encryptionKeyIDRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	subjectPrefixRegex   = regexp.MustCompile(`^[A-Za-z0-9_-]+(\.[A-Za-z0-9_-]+)*$`)
+)
+
+// ValidationError lists every problem found while validating a Config.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration: " + strings.Join(e.Problems, "; ")
+}
+
+// Validate checks the whole Config and reports all problems at once.
+// Returns nil or a *ValidationError.
+func (c Config) Validate() error {
+	var problems []string
+	add := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if err := validateLogLevel(c.LogLevel); err != nil {
+		add("LOG_LEVEL: %v", err)
+	}
+	if err := validateLogFormat(c.LogFormat); err != nil {
+		add("LOG_FORMAT: %v", err)
+	}
+	if c.LogOutput == "" {
+		add("LOG_OUTPUT: must be stdout, stderr or a file path")
+	}
+	if _, err := regexp.Compile(c.LogRedactPattern); err != nil {
+		add("LOG_REDACT_PATTERN: does not compile: %v", err)
+	}
+
+	if c.Database.URL == "" {
+		add("DATABASE_URL: must be set")
+	} else if _, err := pgconn.ParseConfig(c.Database.URL); err != nil {
+		add("DATABASE_URL: cannot be parsed")
+	}
+	if c.Database.MaxConns <= 0 {
+		add("DB_MAX_CONNS: must be positive, got %d", c.Database.MaxConns)
+	}
+	if c.Database.MinConns < 0 {
+		add("DB_MIN_CONNS: must not be negative, got %d", c.Database.MinConns)
+	}
+	if c.Database.MaxConns > 0 && c.Database.MinConns > c.Database.MaxConns {
+		add("DB_MIN_CONNS: %d exceeds DB_MAX_CONNS %d", c.Database.MinConns, c.Database.MaxConns)
+	}
+	if c.Database.QueryTimeout < 0 {
+		add("DB_QUERY_TIMEOUT: must not be negative, got %s", c.Database.QueryTimeout)
+	}
+	if c.Database.HealthCheckPeriod <= 0 {
+		add("DB_HEALTH_CHECK_PERIOD: must be positive, got %s", c.Database.HealthCheckPeriod)
+	}
+	if c.Database.SlowQueryThreshold < 0 {
+		add("DB_SLOW_QUERY_THRESHOLD: must not be negative, got %s", c.Database.SlowQueryThreshold)
+	}
+
+	if c.Projects.IDVersion < 0 || c.Projects.IDVersion > 8 {
+		add("PROJECT_ID_VERSION: must be between 0 (any) and 8, got %d", c.Projects.IDVersion)
+	}
+	if _, err := regexp.Compile(c.Projects.UnixNamePattern); err != nil {
+		add("PROJECT_UNIX_NAME_PATTERN: does not compile: %v", err)
+	}
+	if c.Projects.UnixNameMinLength < 1 {
+		add("PROJECT_UNIX_NAME_MIN_LENGTH: must be at least 1, got %d", c.Projects.UnixNameMinLength)
+	}
+	if c.Projects.UnixNameMaxLength > maxUnixNameLength {
+		add("PROJECT_UNIX_NAME_MAX_LENGTH: must be at most %d, got %d", maxUnixNameLength, c.Projects.UnixNameMaxLength)
+	}
+	if c.Projects.UnixNameMinLength > c.Projects.UnixNameMaxLength {
+		add("PROJECT_UNIX_NAME_MIN_LENGTH: %d exceeds PROJECT_UNIX_NAME_MAX_LENGTH %d",
+			c.Projects.UnixNameMinLength, c.Projects.UnixNameMaxLength)
+	}
+	if len(c.Projects.EncryptedFields) > 0 && len(c.Projects.EncryptionKeys) == 0 {
+		add("PROJECT_ENCRYPTION_KEYS: must be set when PROJECT_ENCRYPTED_FIELDS is")
+	}
+	if _, err := ParseEncryptionKeys(c.Projects.EncryptionKeys); err != nil {
+		add("PROJECT_ENCRYPTION_KEYS: %v", err)
+	}
+	if c.Projects.StatusCacheTTL < 0 {
+		add("PROJECT_STATUS_CACHE_TTL: must not be negative, got %s", c.Projects.StatusCacheTTL)
+	}
+	if c.Projects.AsyncWorkers < 0 {
+		add("PROJECT_ASYNC_WORKERS: must not be negative, got %d", c.Projects.AsyncWorkers)
+	}
+	if c.Projects.AsyncQueueDepth < 0 {
+		add("PROJECT_ASYNC_QUEUE_DEPTH: must not be negative, got %d", c.Projects.AsyncQueueDepth)
+	}
+	if c.Projects.DefaultProvisionEstimate < 0 {
+		add("PROJECT_DEFAULT_PROVISION_ESTIMATE: must not be negative, got %s", c.Projects.DefaultProvisionEstimate)
+	}
+	if c.Projects.EventOutbox && c.Projects.EventRelayInterval <= 0 {
+		add("PROJECT_EVENTS_RELAY_INTERVAL: must be positive when the event outbox is enabled, got %s", c.Projects.EventRelayInterval)
+	}
+	if c.Projects.PurgeEnabled {
+		if c.Projects.DeletedRetention <= 0 {
+			add("PROJECT_DELETED_RETENTION: must be positive when purging is enabled, got %s", c.Projects.DeletedRetention)
+		}
+		if c.Projects.PurgeInterval <= 0 {
+			add("PROJECT_PURGE_INTERVAL: must be positive when purging is enabled, got %s", c.Projects.PurgeInterval)
+		}
+	}
+
+	if c.Orgs.DefaultMaxActiveProjects < 0 {
+		add("ORG_DEFAULT_MAX_ACTIVE_PROJECTS: must not be negative, got %d", c.Orgs.DefaultMaxActiveProjects)
+	}
+	if c.Orgs.ProvisionLimit < 0 {
+		add("ORG_PROVISION_LIMIT: must not be negative, got %d", c.Orgs.ProvisionLimit)
+	}
+	if c.Orgs.ProvisionLimit > 0 && c.Orgs.ProvisionWindow <= 0 {
+		add("ORG_PROVISION_WINDOW: must be positive when ORG_PROVISION_LIMIT is set, got %s", c.Orgs.ProvisionWindow)
+	}
+
+	if _, _, err := net.SplitHostPort(c.Server.Addr); err != nil {
+		add("LISTEN_ADDR: must be host:port or :port, got %q", c.Server.Addr)
+	}
+	if !strings.HasPrefix(c.Server.HealthPath, "/") || strings.HasSuffix(c.Server.HealthPath, "/") {
+		add("HEALTH_PATH: must start with / and not end with /, got %q", c.Server.HealthPath)
+	}
+	if c.Server.MaxInFlight < 0 {
+		add("MAX_IN_FLIGHT_REQUESTS: must not be negative, got %d", c.Server.MaxInFlight)
+	}
+	if c.Server.RateLimit < 0 {
+		add("RATE_LIMIT_REQUESTS: must not be negative, got %d", c.Server.RateLimit)
+	}
+	if c.Server.RateLimit > 0 && c.Server.RateLimitWindow <= 0 {
+		add("RATE_LIMIT_WINDOW: must be positive when RATE_LIMIT_REQUESTS is set, got %s", c.Server.RateLimitWindow)
+	}
+	if c.Server.ShutdownTimeout < MinShutdownTimeout {
+		add("SHUTDOWN_TIMEOUT: must be at least %s, how long provisioning may take, got %s", MinShutdownTimeout, c.Server.ShutdownTimeout)
+	}
+	if c.Server.FieldNaming != "snake_case" && c.Server.FieldNaming != "camelCase" {
+		add("JSON_FIELD_NAMING: must be snake_case or camelCase, got %q", c.Server.FieldNaming)
+	}
+	if c.Server.TLSEnabled() {
+		problems = append(problems, validateTLSFiles(c.Server)...)
+	}
+
+	if c.Plugins.RetryAttempts < 1 {
+		add("PLUGIN_RETRY_ATTEMPTS: must be at least 1, got %d", c.Plugins.RetryAttempts)
+	}
+	if c.Plugins.RetryBudget > 0 && c.Plugins.CallTimeout > c.Plugins.RetryBudget {
+		add("PLUGIN_CALL_TIMEOUT: %s exceeds PLUGIN_RETRY_BUDGET %s", c.Plugins.CallTimeout, c.Plugins.RetryBudget)
+	}
+
+	if err := validateOutputFormat(c.Proxmox.OutputFormat); err != nil {
+		add("PROXMOX_OUTPUT_FORMAT: %v", err)
+	}
+	if c.Proxmox.OutputPattern != "" {
+		if _, err := regexp.Compile(c.Proxmox.OutputPattern); err != nil {
+			add("PROXMOX_OUTPUT_PATTERN: does not compile: %v", err)
+		}
+	}
+
+	if c.Proxmox.DefaultNode != "" && len(c.Proxmox.Nodes) > 0 && !slices.Contains(c.Proxmox.Nodes, c.Proxmox.DefaultNode) {
+		add("PROXMOX_DEFAULT_NODE: %q is not listed in PROXMOX_NODES", c.Proxmox.DefaultNode)
+	}
+
+	if !nameAffixRegex.MatchString(c.Proxmox.NamePrefix) {
+		add("PROXMOX_NAME_PREFIX: may only contain lowercase letters, digits and hyphens, got %q", c.Proxmox.NamePrefix)
+	}
+	if !nameAffixRegex.MatchString(c.Proxmox.NameSuffix) {
+		add("PROXMOX_NAME_SUFFIX: may only contain lowercase letters, digits and hyphens, got %q", c.Proxmox.NameSuffix)
+	}
+	if n := len(c.Proxmox.NamePrefix) + len(c.Proxmox.NameSuffix); n > maxNameAffixLength {
+		add("PROXMOX_NAME_PREFIX: together with PROXMOX_NAME_SUFFIX is %d characters, the limit is %d", n, maxNameAffixLength)
+	}
+
+	if c.Queue.URL != "" {
+		if u, err := url.Parse(c.Queue.URL); err != nil || (u.Scheme != "nats" && u.Scheme != "tls") || u.Hostname() == "" {
+			add("QUEUE_URL: must be nats://host[:port] or tls://host[:port]")
+		}
+	}
+	if !subjectPrefixRegex.MatchString(c.Queue.SubjectPrefix) {
+		add("QUEUE_SUBJECT_PREFIX: must be dot-separated tokens of letters, digits, - and _, got %q", c.Queue.SubjectPrefix)
+	}
+	if c.Queue.StatusTimeout <= 0 {
+		add("QUEUE_STATUS_TIMEOUT: must be positive, got %s", c.Queue.StatusTimeout)
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// EncryptionKey is one PROJECT_ENCRYPTION_KEYS entry.
+type EncryptionKey struct {
+	ID  string
+	Key []byte
+}
+
+// ParseEncryptionKeys parses "<id>:<base64 key>" specs of AES keys, 16, 24
+// or 32 bytes long, with distinct ids, keeping their order. The key
+// material is never reported.
+// Pure function.
+func ParseEncryptionKeys(specs []string) ([]EncryptionKey, error) {
+	keys := make([]EncryptionKey, 0, len(specs))
+	seen := make(map[string]bool, len(specs))
+	for i, spec := range specs {
+		id, encoded, ok := strings.Cut(spec, ":")
+		if !ok || !encryptionKeyIDRegex.MatchString(id) {
+			return nil, fmt.Errorf("entry %d must be <id>:<base64 key>, the id made of letters, digits, - and _", i+1)
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("key %q is listed twice", id)
+		}
+		seen[id] = true
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("key %q is not valid base64", id)
+		}
+		if n := len(key); n != 16 && n != 24 && n != 32 {
+			return nil, fmt.Errorf("key %q is %d bytes, want 16, 24 or 32", id, n)
+		}
+		keys = append(keys, EncryptionKey{ID: id, Key: key})
+	}
+	return keys, nil
+}
+
+// validateTLSFiles requires both TLS files to be set and readable.
+func validateTLSFiles(s ServerConfig) []string {
+	var problems []string
+	files := []struct{ env, path string }{
+		{"TLS_CERT_FILE", s.TLSCertFile},
+		{"TLS_KEY_FILE", s.TLSKeyFile},
+	}
+	for _, f := range files {
+		if f.path == "" {
+			problems = append(problems, fmt.Sprintf("%s: must be set when TLS is enabled", f.env))
+			continue
+		}
+		if _, err := os.Stat(f.path); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", f.env, err))
+		}
+	}
+	return problems
+}
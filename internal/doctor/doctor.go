@@ -0,0 +1,174 @@
+// Package doctor implements environment preflight checks run before
+// starting the server.
+package doctor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Check is a single named preflight check.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// Result is the outcome of running a Check.
+type Result struct {
+	Name string
+	Err  error
+}
+
+// Passed reports whether the check succeeded.
+func (r Result) Passed() bool {
+	return r.Err == nil
+}
+
+// Run executes the checks in order and returns one result per check.
+// Every check runs even if an earlier one failed.
+func Run(ctx context.Context, checks []Check) []Result {
+	results := make([]Result, len(checks))
+	for i, c := range checks {
+		results[i] = Result{Name: c.Name, Err: c.Run(ctx)}
+	}
+	return results
+}
+
+// Failed returns how many results did not pass.
+// Pure function.
+func Failed(results []Result) int {
+	n := 0
+	for _, r := range results {
+		if !r.Passed() {
+			n++
+		}
+	}
+	return n
+}
+
+// Config reports the outcome of loading configuration.
+func Config(loadErr error) Check {
+	return Check{
+		Name: "configuration valid",
+		Run: func(context.Context) error {
+			return loadErr
+		},
+	}
+}
+
+// Executable checks that the named binary can be found on PATH.
+func Executable(name, path string) Check {
+	return Check{
+		Name: fmt.Sprintf("%s on PATH", name),
+		Run: func(context.Context) error {
+			if _, err := exec.LookPath(path); err != nil {
+				return fmt.Errorf("%s not found: %w", path, err)
+			}
+			return nil
+		},
+	}
+}
+
+// Database checks that the database at url accepts connections.
+func Database(url string) Check {
+	return Check{
+		Name: "database reachable",
+		Run: func(ctx context.Context) error {
+			return withConn(ctx, url, func(conn *pgx.Conn) error {
+				return conn.Ping(ctx)
+			})
+		},
+	}
+}
+
+// Migrations checks that golang-migrate has applied every migration in
+// files to the database at url, reading the version and dirty flag it
+// records in schema_migrations. A dirty version, left by a migration that
+// failed halfway, fails the check whatever its number.
+func Migrations(url string, files fs.FS) Check {
+	return Check{
+		Name: "database migrations up to date",
+		Run: func(ctx context.Context) error {
+			want, err := latestMigration(files)
+			if err != nil {
+				return err
+			}
+			return withConn(ctx, url, func(conn *pgx.Conn) error {
+				var exists bool
+				if err := conn.QueryRow(ctx, "SELECT to_regclass('schema_migrations') IS NOT NULL").Scan(&exists); err != nil {
+					return fmt.Errorf("look up table schema_migrations: %w", err)
+				}
+				if !exists {
+					return fmt.Errorf("no migrations applied, want version %d", want)
+				}
+
+				var version int64
+				var dirty bool
+				err := conn.QueryRow(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+				if errors.Is(err, pgx.ErrNoRows) {
+					return fmt.Errorf("no migrations applied, want version %d", want)
+				}
+				if err != nil {
+					return fmt.Errorf("read schema_migrations: %w", err)
+				}
+				if dirty {
+					return fmt.Errorf("migration %d is dirty: fix the schema and force the version", version)
+				}
+				if uint64(version) < want {
+					return fmt.Errorf("schema is at version %d, want %d", version, want)
+				}
+				return nil
+			})
+		},
+	}
+}
+
+// latestMigration returns the highest version among the up migrations in
+// files, named NNNNNN_description.up.sql.
+func latestMigration(files fs.FS) (uint64, error) {
+	names, err := fs.Glob(files, "*.up.sql")
+	if err != nil {
+		return 0, fmt.Errorf("list migrations: %w", err)
+	}
+	var latest uint64
+	for _, name := range names {
+		prefix, _, ok := strings.Cut(name, "_")
+		if !ok {
+			return 0, fmt.Errorf("migration %s has no version prefix", name)
+		}
+		version, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("migration %s has no version prefix", name)
+		}
+		latest = max(latest, version)
+	}
+	if latest == 0 {
+		return 0, errors.New("no migrations found")
+	}
+	return latest, nil
+}
+
+// withConn opens a short-lived connection to url for the duration of fn.
+func withConn(ctx context.Context, url string, fn func(*pgx.Conn) error) error {
+	if url == "" {
+		return errors.New("DATABASE_URL is not set")
+	}
+
+	conn, err := pgx.Connect(ctx, url)
+	if err != nil {
+		return fmt.Errorf("connect: %w", err)
+	}
+
+	fnErr := fn(conn)
+	if err := conn.Close(ctx); err != nil && fnErr == nil {
+		return fmt.Errorf("close connection: %w", err)
+	}
+	return fnErr
+}
@@ -0,0 +1,94 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/searge/quokka/migrations"
+)
+
+func TestRunReportsEveryCheck(t *testing.T) {
+	boom := errors.New("boom")
+	checks := []Check{
+		{Name: "first", Run: func(context.Context) error { return boom }},
+		{Name: "second", Run: func(context.Context) error { return nil }},
+	}
+
+	results := Run(context.Background(), checks)
+
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	if results[0].Name != "first" || !errors.Is(results[0].Err, boom) {
+		t.Errorf("unexpected first result: %+v", results[0])
+	}
+	if results[1].Name != "second" || !results[1].Passed() {
+		t.Errorf("unexpected second result: %+v", results[1])
+	}
+	if Failed(results) != 1 {
+		t.Errorf("Failed() = %d, want 1", Failed(results))
+	}
+}
+
+func TestConfigCheck(t *testing.T) {
+	if err := Config(nil).Run(context.Background()); err != nil {
+		t.Errorf("expected pass for valid config, got %v", err)
+	}
+
+	invalid := errors.New("invalid LOG_LEVEL")
+	if err := Config(invalid).Run(context.Background()); !errors.Is(err, invalid) {
+		t.Errorf("expected load error to be reported, got %v", err)
+	}
+}
+
+func TestExecutableCheck(t *testing.T) {
+	if err := Executable("shell", "sh").Run(context.Background()); err != nil {
+		t.Errorf("expected sh to be found, got %v", err)
+	}
+	if err := Executable("missing", "definitely-not-a-real-binary").Run(context.Background()); err == nil {
+		t.Error("expected an error for a missing binary")
+	}
+}
+
+func TestDatabaseChecksRequireURL(t *testing.T) {
+	for _, c := range []Check{Database(""), Migrations("", fstest.MapFS{"000001_init.up.sql": {}})} {
+		if err := c.Run(context.Background()); err == nil {
+			t.Errorf("%s: expected an error when DATABASE_URL is empty", c.Name)
+		}
+	}
+}
+
+func TestDatabaseCheckRejectsMalformedURL(t *testing.T) {
+	if err := Database("postgres://%zz").Run(context.Background()); err == nil {
+		t.Error("expected an error for a malformed DATABASE_URL")
+	}
+}
+
+func TestLatestMigration(t *testing.T) {
+	files := fstest.MapFS{
+		"000002_add_labels.up.sql":   {},
+		"000002_add_labels.down.sql": {},
+		"000010_add_outbox.up.sql":   {},
+		"000010_add_outbox.down.sql": {},
+		"000009_add_orgs.up.sql":     {},
+	}
+	got, err := latestMigration(files)
+	if err != nil || got != 10 {
+		t.Errorf("latestMigration() = %d, %v, want 10", got, err)
+	}
+
+	if _, err := latestMigration(fstest.MapFS{}); err == nil {
+		t.Error("expected an error when there are no migrations")
+	}
+	if _, err := latestMigration(fstest.MapFS{"init.up.sql": {}}); err == nil {
+		t.Error("expected an error for a migration without a version")
+	}
+}
+
+func TestEmbeddedMigrationsHaveAVersion(t *testing.T) {
+	if _, err := latestMigration(migrations.FS); err != nil {
+		t.Errorf("latestMigration(migrations.FS) error = %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+// Package integration wires the concrete plugin implementations into a
+// registry according to configuration.
+package integration
+
+import (
+	"fmt"
+
+	"github.com/searge/quokka/internal/config"
+	"github.com/searge/quokka/internal/integration/proxmox"
+	"github.com/searge/quokka/internal/integration/queue"
+	"github.com/searge/quokka/internal/plugin"
+)
+
+// NewRegistry builds a plugin registry containing every configured plugin:
+// proxmox always, and queue when cfg.Queue names a server.
+// Provisioning is retried per cfg.Plugins; a request that is not
+// idempotent is only retried once the plugin confirms the failed attempt
+// left no resource behind. When rec is non-nil every plugin is wrapped so its
+// operations are audited. Health checks and provisioning concurrency are
+// bounded per cfg.Plugins; options in extra are applied after those
+// derived from cfg.
+func NewRegistry(cfg config.Config, rec plugin.Recorder, extra ...plugin.RegistryOption) (*plugin.Registry, error) {
+	opts := []plugin.RegistryOption{plugin.WithHealthTimeout(cfg.Plugins.HealthTimeout)}
+	for name, d := range cfg.Plugins.HealthTimeouts {
+		opts = append(opts, plugin.WithPluginHealthTimeout(name, d))
+	}
+	for name, max := range cfg.Plugins.ProvisionConcurrency {
+		opts = append(opts, plugin.WithProvisionConcurrency(name, max))
+	}
+	registry := plugin.NewRegistry(append(opts, extra...)...)
+
+	for _, key := range cfg.Proxmox.SSHKeys {
+		if err := plugin.ValidateSSHPublicKey(key); err != nil {
+			return nil, fmt.Errorf("configure proxmox ssh keys: %w", err)
+		}
+	}
+	outputParser, err := plugin.NewOutputParser(cfg.Proxmox.OutputFormat, cfg.Proxmox.OutputPattern)
+	if err != nil {
+		return nil, fmt.Errorf("configure proxmox output parser: %w", err)
+	}
+	p := plugin.WithIdempotency(proxmox.New(cfg.Proxmox.CLIPath,
+		proxmox.WithOutputParser(outputParser),
+		proxmox.WithNameAffixes(cfg.Proxmox.NamePrefix, cfg.Proxmox.NameSuffix),
+		proxmox.WithDefaultNode(cfg.Proxmox.DefaultNode),
+		proxmox.WithDefaultSSHKeys(cfg.Proxmox.SSHKeys...),
+	))
+	retry := plugin.RetryPolicy{
+		Attempts:    cfg.Plugins.RetryAttempts,
+		CallTimeout: cfg.Plugins.CallTimeout,
+		Budget:      cfg.Plugins.RetryBudget,
+		Backoff:     cfg.Plugins.RetryBackoff,
+	}
+	p = plugin.WithRetry(p, retry)
+	if rec != nil {
+		p = plugin.WithAudit(p, rec)
+	}
+	if err := registry.Register(p); err != nil {
+		return nil, fmt.Errorf("register proxmox plugin: %w", err)
+	}
+
+	if cfg.Queue.URL != "" {
+		broker, err := queue.NewNATS(cfg.Queue.URL)
+		if err != nil {
+			return nil, fmt.Errorf("configure queue plugin: %w", err)
+		}
+		var q plugin.Plugin = queue.New(broker,
+			queue.WithSubjectPrefix(cfg.Queue.SubjectPrefix),
+			queue.WithStatusTimeout(cfg.Queue.StatusTimeout),
+		)
+		q = plugin.WithRetry(q, retry)
+		if rec != nil {
+			q = plugin.WithAudit(q, rec)
+		}
+		if err := registry.Register(q); err != nil {
+			return nil, fmt.Errorf("register queue plugin: %w", err)
+		}
+	}
+
+	return registry, nil
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package proxmox
+
+import "os/exec"
+
+// killProcessGroup is a no-op where process groups are unavailable; only
+// the CLI itself is killed on cancellation.
+func killProcessGroup(*exec.Cmd) {}
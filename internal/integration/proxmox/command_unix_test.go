@@ -0,0 +1,73 @@
+//go:build unix
+
+package proxmox
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/searge/quokka/internal/plugin"
+)
+
+// processGone reports whether pid no longer runs. Zombies count as gone:
+// they have exited and only await reaping by their new parent.
+func processGone(pid int) bool {
+	if err := syscall.Kill(pid, 0); errors.Is(err, syscall.ESRCH) {
+		return true
+	}
+	stat, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return false
+	}
+	fields := strings.Fields(string(stat))
+	return len(fields) > 2 && fields[2] == "Z"
+}
+
+func TestProvisionKillsCLISubprocessesOnCancel(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "helper.pid")
+	cli := writeFakeCLI(t, `sleep 30 &
+echo $! > "`+pidFile+`"
+wait`)
+	p := New(cli)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var pid int
+	go func() {
+		for {
+			raw, err := os.ReadFile(pidFile)
+			if err == nil && strings.HasSuffix(string(raw), "\n") {
+				pid, err = strconv.Atoi(strings.TrimSpace(string(raw)))
+				if err != nil {
+					t.Errorf("unreadable pid file: %v", err)
+				}
+				cancel()
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	if _, err := p.Provision(ctx, plugin.ProvisionRequest{UnixName: "alpha"}); err == nil {
+		t.Fatal("expected provision to fail after cancellation")
+	}
+	if pid == 0 {
+		t.Fatal("helper process never started")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !processGone(pid) {
+		if time.Now().After(deadline) {
+			t.Fatalf("helper process %d survived cancellation", pid)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,101 @@
+package proxmox
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+
+	"github.com/searge/quokka/internal/plugin"
+)
+
+// Exit codes forge-ovh-cli shares with sysexits(3).
+const (
+	exitUsage       = 64 // EX_USAGE
+	exitDataErr     = 65 // EX_DATAERR
+	exitNoInput     = 66 // EX_NOINPUT
+	exitUnavailable = 69 // EX_UNAVAILABLE
+	exitTempFail    = 75 // EX_TEMPFAIL
+	exitNoPerm      = 77 // EX_NOPERM
+)
+
+// outputClasses classify failures by what the CLI printed, for exit codes
+// that say nothing more specific. Checked in order.
+var outputClasses = []struct {
+	pattern *regexp.Regexp
+	class   plugin.ErrorClass
+}{
+	{regexp.MustCompile(`(?i)\b(unauthori[sz]ed|forbidden|permission denied|authentication failed|invalid (api )?token|401|403)\b`), plugin.ClassUnauthorized},
+	{regexp.MustCompile(`(?i)\b(not found|does not exist|no such (vm|resource|container)|404)\b`), plugin.ClassNotFound},
+	{regexp.MustCompile(`(?i)\b(timed? ?out|connection (refused|reset)|temporarily unavailable|try again|too many requests|locked|429|502|503|504)\b`), plugin.ClassTransient},
+	{regexp.MustCompile(`(?i)\b(invalid|unknown (flag|option|template)|bad request|400)\b|\busage:`), plugin.ClassInvalidInput},
+}
+
+// classifyExit maps a CLI exit code and its output to an error class.
+// Pure function.
+func classifyExit(code int, output string) plugin.ErrorClass {
+	switch code {
+	case exitUsage, exitDataErr:
+		return plugin.ClassInvalidInput
+	case exitNoInput:
+		return plugin.ClassNotFound
+	case exitUnavailable, exitTempFail:
+		return plugin.ClassTransient
+	case exitNoPerm:
+		return plugin.ClassUnauthorized
+	}
+	for _, oc := range outputClasses {
+		if oc.pattern.MatchString(output) {
+			return oc.class
+		}
+	}
+	return plugin.ClassUnknown
+}
+
+// partialOutputLimit bounds how much of the output of a CLI killed by
+// its context is kept in the error: the end, where its last progress
+// lines are.
+const partialOutputLimit = 4 << 10
+
+// outputTail returns the end of output, at most partialOutputLimit bytes,
+// cut at a line boundary and marked as such if longer. Pure function.
+func outputTail(output []byte) string {
+	output = bytes.TrimRight(output, "\n")
+	if len(output) <= partialOutputLimit {
+		return string(output)
+	}
+	tail := output[len(output)-partialOutputLimit:]
+	if i := bytes.IndexByte(tail, '\n'); i >= 0 {
+		tail = tail[i+1:]
+	}
+	return "[...] " + string(tail)
+}
+
+// cliError classifies a failed CLI run of op. A run cut short by its
+// context is transient and reported with the end of what the CLI printed
+// before it was killed, e.g. how far a clone got; one that exited
+// reporting an error is classified by its exit code and output.
+func cliError(ctx context.Context, op string, err error, output []byte) error {
+	if ctx.Err() != nil {
+		return &plugin.PluginError{
+			Plugin: "proxmox",
+			Op:     op,
+			Class:  plugin.ClassTransient,
+			Err:    fmt.Errorf("forge-ovh-cli: aborted: %w, output before abort: %s", ctx.Err(), outputTail(output)),
+		}
+	}
+
+	class := plugin.ClassUnknown
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		class = classifyExit(exitErr.ExitCode(), string(output))
+	}
+	return &plugin.PluginError{
+		Plugin: "proxmox",
+		Op:     op,
+		Class:  class,
+		Err:    fmt.Errorf("forge-ovh-cli: %w, output: %s", err, output),
+	}
+}
@@ -0,0 +1,123 @@
+package proxmox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/searge/quokka/internal/plugin"
+)
+
+func TestClassifyExit(t *testing.T) {
+	tests := []struct {
+		name   string
+		code   int
+		output string
+		want   plugin.ErrorClass
+	}{
+		{"usage exit code", 64, "", plugin.ClassInvalidInput},
+		{"no permission exit code", 77, "", plugin.ClassUnauthorized},
+		{"temporary failure exit code", 75, "", plugin.ClassTransient},
+		{"missing input exit code", 66, "", plugin.ClassNotFound},
+		{"unknown vm", 1, "Error: VM 104 does not exist", plugin.ClassNotFound},
+		{"api 404", 1, "API error 404: resource not found", plugin.ClassNotFound},
+		{"expired token", 1, "401 Unauthorized: invalid API token", plugin.ClassUnauthorized},
+		{"missing privilege", 1, "permission denied: VM.Allocate required", plugin.ClassUnauthorized},
+		{"cluster busy", 1, "can't lock file '/var/lock/qemu-server/lock-104.conf' - got timeout", plugin.ClassTransient},
+		{"node down", 1, "dial tcp 10.0.0.5:8006: connect: connection refused", plugin.ClassTransient},
+		{"gateway", 1, "proxy returned 503 Service Unavailable", plugin.ClassTransient},
+		{"bad template", 1, "unknown template 'debian-99'", plugin.ClassInvalidInput},
+		{"bad flag", 2, "Usage: forge-ovh-cli create --name <name>", plugin.ClassInvalidInput},
+		{"unrecognised", 1, "something went wrong", plugin.ClassUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyExit(tt.code, tt.output); got != tt.want {
+				t.Errorf("classifyExit(%d, %q) = %q, want %q", tt.code, tt.output, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatusClassifiesCLIFailure(t *testing.T) {
+	cli := writeFakeCLI(t, `echo "Error: VM 104 does not exist"; exit 2`)
+	p := New(cli)
+
+	_, err := p.Status(context.Background(), "104")
+	if got := plugin.Classify(err); got != plugin.ClassNotFound {
+		t.Fatalf("Classify(%v) = %q, want %q", err, got, plugin.ClassNotFound)
+	}
+}
+
+func TestProvisionClassifiesCancellationAsTransient(t *testing.T) {
+	cli := writeFakeCLI(t, `sleep 5`)
+	p := New(cli)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := p.Provision(ctx, plugin.ProvisionRequest{UnixName: "alpha"})
+	if got := plugin.Classify(err); got != plugin.ClassTransient {
+		t.Fatalf("Classify(%v) = %q, want %q", err, got, plugin.ClassTransient)
+	}
+}
+
+func TestProvisionTimeoutKeepsPartialOutput(t *testing.T) {
+	cli := writeFakeCLI(t, `echo "cloning template"
+echo "clone progress: 42%" >&2
+sleep 30`)
+	p := New(cli)
+
+	provisions := map[string]func(context.Context) error{
+		"Provision": func(ctx context.Context) error {
+			_, err := p.Provision(ctx, plugin.ProvisionRequest{UnixName: "alpha"})
+			return err
+		},
+		"ProvisionStream": func(ctx context.Context) error {
+			_, err := p.ProvisionStream(ctx, plugin.ProvisionRequest{UnixName: "alpha"}, nil)
+			return err
+		},
+	}
+	for name, provision := range provisions {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+
+			err := provision(ctx)
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("expected the timeout to be reported, got %v", err)
+			}
+			if got := plugin.Classify(err); got != plugin.ClassTransient {
+				t.Errorf("Classify(%v) = %q, want %q", err, got, plugin.ClassTransient)
+			}
+			for _, line := range []string{"cloning template", "clone progress: 42%"} {
+				if !strings.Contains(err.Error(), line) {
+					t.Errorf("error %q lacks the output line %q", err, line)
+				}
+			}
+		})
+	}
+}
+
+func TestOutputTailKeepsEndOnLineBoundary(t *testing.T) {
+	if got := outputTail([]byte("short\n")); got != "short" {
+		t.Errorf("outputTail() = %q, want short", got)
+	}
+
+	var output strings.Builder
+	for i := range 1000 {
+		fmt.Fprintf(&output, "clone progress: %d\n", i)
+	}
+	got := outputTail([]byte(output.String()))
+	if len(got) > partialOutputLimit+len("[...] ") {
+		t.Errorf("outputTail() kept %d bytes, want at most %d", len(got), partialOutputLimit)
+	}
+	if !strings.HasPrefix(got, "[...] clone progress: ") {
+		t.Errorf("outputTail() = %.40q..., want it to start on a whole line", got)
+	}
+	if !strings.HasSuffix(got, "clone progress: 999") {
+		t.Errorf("outputTail() lost the last line: ...%q", got[len(got)-30:])
+	}
+}
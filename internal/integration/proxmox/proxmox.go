@@ -1,26 +1,108 @@
 package proxmox
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
-	"strings"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
 
 	"github.com/searge/quokka/internal/plugin"
+	"golang.org/x/sync/singleflight"
 )
 
+// MaxNameLength is the longest resource name the cluster accepts: a single
+// DNS label, since VM names double as hostnames.
+const MaxNameLength = 63
+
+// ErrNameTooLong is returned when a resource name, affixes included, is
+// longer than MaxNameLength.
+var ErrNameTooLong = errors.New("resource name too long")
+
+// ErrInvalidName is returned when a resource name, affixes included, is
+// not a DNS label: lowercase letters, digits and "-", starting and ending
+// with a letter or digit.
+var ErrInvalidName = errors.New("resource name is not a DNS label")
+
+// dnsLabelPattern matches the names ErrInvalidName is not returned for.
+var dnsLabelPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
 // Plugin implements the plugin.Plugin interface for Proxmox via forge-ovh-cli.
 type Plugin struct {
-	cliPath string
+	cliPath    string
+	parser     plugin.OutputParser
+	namePrefix string
+	nameSuffix string
+	node       string
+	sshKeys    []string
+
+	versionGroup   singleflight.Group
+	versionMu      sync.Mutex
+	versionChecked bool
+	versionKnown   bool
+	version        Version
+}
+
+// Option configures optional Plugin behaviour.
+type Option func(*Plugin)
+
+// WithOutputParser sets the strategy used to parse provisioning output.
+func WithOutputParser(parser plugin.OutputParser) Option {
+	return func(p *Plugin) {
+		p.parser = parser
+	}
+}
+
+// WithNameAffixes qualifies every resource name as prefix+name+suffix, e.g.
+// to namespace VMs per environment with "prod-".
+func WithNameAffixes(prefix, suffix string) Option {
+	return func(p *Plugin) {
+		p.namePrefix = prefix
+		p.nameSuffix = suffix
+	}
+}
+
+// WithDefaultNode places resources whose request names no node on node.
+// Empty leaves placement to the CLI.
+func WithDefaultNode(node string) Option {
+	return func(p *Plugin) {
+		p.node = node
+	}
+}
+
+// WithDefaultSSHKeys installs keys on every resource, in addition to those
+// its request names.
+func WithDefaultSSHKeys(keys ...string) Option {
+	return func(p *Plugin) {
+		p.sshKeys = keys
+	}
 }
 
 // New creates a new Proxmox plugin instance.
-func New(cliPath string) *Plugin {
+// Output is parsed as "ID: <value>" lines unless another parser is given.
+func New(cliPath string, opts ...Option) *Plugin {
 	if cliPath == "" {
 		cliPath = "forge-ovh-cli"
 	}
-	return &Plugin{cliPath: cliPath}
+	p := &Plugin{cliPath: cliPath}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.parser == nil {
+		parser, err := plugin.NewRegexParser(plugin.DefaultKeyValuePattern)
+		if err != nil {
+			panic(fmt.Errorf("failed to compile default output pattern: %w", err))
+		}
+		p.parser = parser
+	}
+	return p
 }
 
 // Name returns the identifier for this plugin.
@@ -36,7 +118,7 @@ func (p *Plugin) Health(ctx context.Context) error {
 		return fmt.Errorf("forge-ovh-cli not found in path: %w", err)
 	}
 
-	cmd := exec.CommandContext(ctx, p.cliPath, "--help")
+	cmd := command(ctx, p.cliPath, "--help")
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("failed to execute forge-ovh-cli: %w", err)
 	}
@@ -45,78 +127,319 @@ func (p *Plugin) Health(ctx context.Context) error {
 
 // Provision invokes the CLI to create a new VM/container for the project.
 func (p *Plugin) Provision(ctx context.Context, req plugin.ProvisionRequest) (*plugin.ProvisionResult, error) {
-	// Assuming forge-ovh-cli usage: forge-ovh-cli create --name <project_name>
-	// The implementation here depends on the exact CLI expected format.
+	cmd, name, parser, err := p.provisionCommand(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, cliError(ctx, plugin.OpProvision, err, output)
+	}
+
+	return provisionResult(parser, output, name, p.nodeFor(req))
+}
+
+// ProvisionStream behaves like Provision but hands every line the CLI
+// prints, on stdout or stderr, to out as soon as it is printed. Cancelling
+// ctx kills the CLI.
+func (p *Plugin) ProvisionStream(ctx context.Context, req plugin.ProvisionRequest, out func(line string)) (*plugin.ProvisionResult, error) {
+	cmd, name, parser, err := p.provisionCommand(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("open forge-ovh-cli output: %w", err)
+	}
+	stderrPipe, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, fmt.Errorf("open forge-ovh-cli error output: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start forge-ovh-cli: %w", err)
+	}
+
+	// Both outputs are streamed, stderr carrying progress such as clone
+	// percentages, but only stdout is parsed for the result. Both are
+	// kept, interleaved as printed, to report a failure.
+	var mu sync.Mutex
+	var combined bytes.Buffer
+	lines := func(line string) {
+		mu.Lock()
+		defer mu.Unlock()
+		combined.WriteString(line)
+		combined.WriteByte('\n')
+		if out != nil {
+			out(line)
+		}
+	}
+	var output, stderr bytes.Buffer
+	var stdoutErr, stderrErr error
+	var scans sync.WaitGroup
+	scans.Go(func() { stdoutErr = scanLines(stdout, &output, lines) })
+	scans.Go(func() { stderrErr = scanLines(stderrPipe, &stderr, lines) })
+	scanned := make(chan struct{})
+	go func() {
+		scans.Wait()
+		close(scanned)
+	}()
+
+	// Wait for the CLI to close its outputs, or for cancellation, in which
+	// case the CLI process group has been killed and Wait releases the pipes.
+	select {
+	case <-scanned:
+	case <-ctx.Done():
+	}
+	waitErr := cmd.Wait()
+	<-scanned
+	scanErr := errors.Join(stdoutErr, stderrErr)
+
+	if waitErr != nil {
+		return nil, cliError(ctx, plugin.OpProvision, waitErr, combined.Bytes())
+	}
+	if scanErr != nil {
+		return nil, fmt.Errorf("read forge-ovh-cli output: %w", scanErr)
+	}
+
+	return provisionResult(parser, output.Bytes(), name, p.nodeFor(req))
+}
+
+// resourceName qualifies name with the configured prefix and suffix.
+// Returns ErrNameTooLong if the result exceeds MaxNameLength, and
+// ErrInvalidName if it is not a DNS label.
+func (p *Plugin) resourceName(name string) (string, error) {
+	qualified := p.namePrefix + name + p.nameSuffix
+	if len(qualified) > MaxNameLength {
+		return "", fmt.Errorf("%w: %q is %d characters, the limit is %d", ErrNameTooLong, qualified, len(qualified), MaxNameLength)
+	}
+	if !dnsLabelPattern.MatchString(qualified) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidName, qualified)
+	}
+	return qualified, nil
+}
+
+// ValidateRequest implements plugin.RequestValidator: the resource name
+// req's unix name makes, affixes included, must be a DNS label of at most
+// MaxNameLength characters, and its tags must follow Proxmox's format.
+func (p *Plugin) ValidateRequest(req plugin.ProvisionRequest) error {
+	if _, err := p.resourceName(plugin.ResourceName(req)); err != nil {
+		return &plugin.RequestError{Field: "unix_name", Err: err}
+	}
+	if _, err := Tags(req.Tags); err != nil {
+		return &plugin.RequestError{Field: "tags", Err: err}
+	}
+	return nil
+}
+
+// provisionCommand builds the CLI invocation that creates req's resource,
+// returning it with the qualified resource name it passes to the CLI and
+// the parser for what that invocation prints.
+func (p *Plugin) provisionCommand(ctx context.Context, req plugin.ProvisionRequest) (*exec.Cmd, string, plugin.OutputParser, error) {
+	name, err := p.resourceName(plugin.ResourceName(req))
+	if err != nil {
+		return nil, "", nil, err
+	}
 
-	args := []string{"create", "--name", req.ProjectName}
+	// Assuming forge-ovh-cli usage: forge-ovh-cli create --name <unix_name>
+	// The implementation here depends on the exact CLI expected format.
+	args := []string{"create", "--name", name}
 	if req.Template != "" {
 		args = append(args, "--template", req.Template)
 	}
+	if node := p.nodeFor(req); node != "" {
+		args = append(args, "--node", node)
+	}
+	keys, err := p.sshKeysFor(req)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	for _, key := range keys {
+		args = append(args, "--ssh-key", key)
+	}
+	tags, err := tagArgs(plugin.OpProvision, req.Tags)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	args = append(args, tags...)
+	outputArgs, parser, err := p.outputMode(ctx)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	args = append(args, outputArgs...)
 
-	cmd := exec.CommandContext(ctx, p.cliPath, args...)
+	cmd := command(ctx, p.cliPath, args...)
 
 	// Optional: pass down environment variables if CLI relies on them for auth
 	cmd.Env = os.Environ()
+	return cmd, name, parser, nil
+}
 
-	output, err := cmd.CombinedOutput()
-	outStr := string(output)
+// nodeFor is the node req's resource is placed on: the one it names, else
+// the default. Empty means the CLI chooses.
+func (p *Plugin) nodeFor(req plugin.ProvisionRequest) string {
+	if req.Node != "" {
+		return req.Node
+	}
+	return p.node
+}
+
+// sshKeysFor returns the keys to install on req's resource: the defaults,
+// then those it names, without duplicates. A malformed key fails the call
+// before the CLI runs, as invalid input.
+func (p *Plugin) sshKeysFor(req plugin.ProvisionRequest) ([]string, error) {
+	var keys []string
+	for _, key := range append(slices.Clone(p.sshKeys), req.SSHKeys...) {
+		if slices.Contains(keys, key) {
+			continue
+		}
+		if err := plugin.ValidateSSHPublicKey(key); err != nil {
+			return nil, &plugin.PluginError{Plugin: "proxmox", Op: plugin.OpProvision, Class: plugin.ClassInvalidInput, Err: err}
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// command builds a CLI invocation that, on cancellation, kills the CLI
+// together with any subprocess it spawned.
+func command(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	killProcessGroup(cmd)
+	// Bound how long Wait blocks on pipes held open by stray descendants.
+	cmd.WaitDelay = time.Second
+	return cmd
+}
+
+// provisionResult parses CLI output with parser into a result with default status,
+// metadata and, if the parser found none, network info filled in. name is
+// recorded as the "resource_name" metadata so later lookups use exactly
+// what the CLI was given, and node, if the CLI did not report one, as
+// "node".
+func provisionResult(parser plugin.OutputParser, output []byte, name, node string) (*plugin.ProvisionResult, error) {
+	result, err := parser.ParseProvision(output)
 	if err != nil {
-		return nil, fmt.Errorf("forge-ovh-cli provision failed: %w, output: %s", err, outStr)
+		return nil, err
 	}
 
-	// Pseudo-parsing to get a resource ID and status
-	// In reality we would parse the JSON output of forge-ovh-cli.
-	resourceID := p.parseResourceID(outStr)
-	if resourceID == "" {
-		return nil, fmt.Errorf("unable to parse resource id from cli output")
+	if result.Status == "" {
+		result.Status = "provisioned"
+	}
+	if result.Metadata == nil {
+		result.Metadata = make(map[string]string)
+	}
+	result.Metadata["cli_output"] = string(output)
+	result.Metadata["resource_name"] = name
+	if result.Network.Empty() {
+		result.Network = plugin.ParseNetworkInfo(string(output))
+	}
+	if _, ok := result.Metadata["node"]; !ok {
+		if node == "" {
+			node = "proxmox-01" // stub
+		}
+		result.Metadata["node"] = node
 	}
 
-	return &plugin.ProvisionResult{
-		ResourceID: resourceID,
-		Status:     "provisioned",
-		Metadata: map[string]string{
-			"cli_output": outStr,
-			"node":       "proxmox-01", // stub
-		},
-	}, nil
+	return result, nil
+}
+
+// scanLines copies r into buf line by line, calling out with each line.
+// On a read error the rest of r is discarded so the writer never blocks.
+func scanLines(r io.Reader, buf *bytes.Buffer, out func(line string)) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+		if out != nil {
+			out(line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		if _, drainErr := io.Copy(io.Discard, r); drainErr != nil {
+			return errors.Join(err, drainErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// FindResource looks up a resource by name, qualified like Provision does,
+// via the CLI. Output without a resource ID means nothing exists under that
+// name.
+func (p *Plugin) FindResource(ctx context.Context, name string) (*plugin.ProvisionResult, error) {
+	name, err := p.resourceName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	outputArgs, parser, err := p.outputMode(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cmd := command(ctx, p.cliPath, append([]string{"status", "--name", name}, outputArgs...)...)
+	cmd.Env = os.Environ()
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, cliError(ctx, plugin.OpStatus, err, output)
+	}
+
+	result, err := parser.ParseProvision(output)
+	if err != nil {
+		if errors.Is(err, plugin.ErrNoResourceID) {
+			return nil, plugin.ErrResourceNotFound
+		}
+		return nil, err
+	}
+	if result.Status == "" {
+		result.Status = "provisioned"
+	}
+	if result.Metadata == nil {
+		result.Metadata = make(map[string]string)
+	}
+	result.Metadata["resource_name"] = name
+	if result.Network.Empty() {
+		result.Network = plugin.ParseNetworkInfo(string(output))
+	}
+	return result, nil
 }
 
 // Status checks the status of an existing resource via the CLI.
 func (p *Plugin) Status(ctx context.Context, resourceID string) (*plugin.StatusResult, error) {
-	cmd := exec.CommandContext(ctx, p.cliPath, "status", "--id", resourceID)
+	cmd := command(ctx, p.cliPath, "status", "--id", resourceID)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get status: %w, output: %s", err, string(output))
+		return nil, cliError(ctx, plugin.OpStatus, err, output)
 	}
 
 	// Stub parsing
-	return &plugin.StatusResult{
+	result := &plugin.StatusResult{
 		Status: "running",
 		Metadata: map[string]string{
 			"raw_output": string(output),
 		},
-	}, nil
+	}
+	if progress, ok := plugin.ParseProgress(string(output)); ok {
+		result.Progress = &progress
+	}
+	result.Network = plugin.ParseNetworkInfo(string(output))
+	return result, nil
 }
 
-// Deprovision removes the resource.
+// Deprovision removes the resource. A resource the CLI reports as not
+// found is already gone, so deleting it again succeeds: teardown can be
+// retried safely.
 func (p *Plugin) Deprovision(ctx context.Context, resourceID string) error {
-	cmd := exec.CommandContext(ctx, p.cliPath, "delete", "--id", resourceID)
+	cmd := command(ctx, p.cliPath, "delete", "--id", resourceID)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return fmt.Errorf("failed to delete resource: %w, output: %s", err, string(output))
-	}
-	return nil
-}
-
-// parseResourceID is a helper to extract a resource ID from CLI output.
-func (p *Plugin) parseResourceID(output string) string {
-	// A naive extraction. If the CLI outputs JSON, this should be json.Unmarshal.
-	lines := strings.Split(output, "\n")
-	for _, l := range lines {
-		if strings.HasPrefix(strings.ToLower(l), "id:") {
-			return strings.TrimSpace(strings.SplitN(l, ":", 2)[1])
+		err = cliError(ctx, plugin.OpDeprovision, err, output)
+		if plugin.Classify(err) == plugin.ClassNotFound {
+			return nil
 		}
+		return err
 	}
-	// Fallback to generating a pseudo ID if not found for spike purpose
-	return ""
+	return nil
 }
@@ -1,21 +1,436 @@
 package proxmox
 
-import "testing"
+import (
+	"cmp"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/searge/quokka/internal/plugin"
+)
+
+// writeFakeCLI writes an executable shell script standing in for forge-ovh-cli.
+func writeFakeCLI(t *testing.T, script string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "forge-ovh-cli")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatalf("failed to write fake cli: %v", err)
+	}
+	return path
+}
 
 func TestParseResourceIDExtractsID(t *testing.T) {
 	p := New("forge-ovh-cli")
 
-	id := p.parseResourceID("ok\nID: 321\ndone")
-	if id != "321" {
-		t.Fatalf("expected resource id 321, got %q", id)
+	res, err := p.parser.ParseProvision([]byte("ok\nID: 321\ndone"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if res.ResourceID != "321" {
+		t.Fatalf("expected resource id 321, got %q", res.ResourceID)
 	}
 }
 
 func TestParseResourceIDReturnsEmptyWhenMissing(t *testing.T) {
 	p := New("forge-ovh-cli")
 
-	id := p.parseResourceID("no id here")
-	if id != "" {
-		t.Fatalf("expected empty id, got %q", id)
+	_, err := p.parser.ParseProvision([]byte("no id here"))
+	if err == nil {
+		t.Fatal("expected an error when the output has no id")
+	}
+}
+
+func TestProvisionUsesConfiguredParser(t *testing.T) {
+	cli := writeFakeCLI(t, `echo '{"resource_id":"vm-42","status":"creating"}'`)
+	p := New(cli, WithOutputParser(plugin.JSONParser{}))
+
+	res, err := p.Provision(context.Background(), plugin.ProvisionRequest{UnixName: "alpha"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if res.ResourceID != "vm-42" || res.Status != "creating" {
+		t.Fatalf("unexpected provision result: %+v", res)
+	}
+	if res.Metadata["cli_output"] == "" {
+		t.Fatal("expected raw cli output to be kept in metadata")
+	}
+}
+
+func TestProvisionAppliesNameAffixes(t *testing.T) {
+	received := filepath.Join(t.TempDir(), "name")
+	cli := writeFakeCLI(t, `echo "$3" > `+received+`
+echo "ID: 321"`)
+	p := New(cli, WithNameAffixes("prod-", "-vm"))
+
+	res, err := p.Provision(context.Background(), plugin.ProvisionRequest{UnixName: "alpha"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	raw, err := os.ReadFile(received)
+	if err != nil {
+		t.Fatalf("failed to read the name the cli received: %v", err)
+	}
+	got := strings.TrimSpace(string(raw))
+	if got != "prod-alpha-vm" {
+		t.Errorf("cli received --name %q, want prod-alpha-vm", got)
+	}
+	if res.Metadata["resource_name"] != got {
+		t.Errorf("stored resource_name %q does not match the cli's %q", res.Metadata["resource_name"], got)
+	}
+}
+
+func TestProvisionRejectsNamesOverHostnameLimit(t *testing.T) {
+	cli := writeFakeCLI(t, `echo "cli must not be invoked" >&2; exit 1`)
+	p := New(cli, WithNameAffixes("prod-", ""))
+
+	_, err := p.Provision(context.Background(), plugin.ProvisionRequest{UnixName: strings.Repeat("a", MaxNameLength-4)})
+	if !errors.Is(err, ErrNameTooLong) {
+		t.Fatalf("expected ErrNameTooLong, got %v", err)
+	}
+}
+
+func TestValidateRequestChecksResourceName(t *testing.T) {
+	p := New("forge-ovh-cli", WithNameAffixes("prod-", ""))
+	tests := []struct {
+		unixName string
+		wantErr  error
+	}{
+		{unixName: "alpha-1"},
+		{unixName: strings.Repeat("a", MaxNameLength-5)},
+		{unixName: strings.Repeat("a", MaxNameLength-4), wantErr: ErrNameTooLong},
+		{unixName: "Alpha", wantErr: ErrInvalidName},
+		{unixName: "alpha_1", wantErr: ErrInvalidName},
+		{unixName: "alpha-", wantErr: ErrInvalidName},
+	}
+	for _, tt := range tests {
+		err := p.ValidateRequest(plugin.ProvisionRequest{UnixName: tt.unixName})
+		if tt.wantErr == nil {
+			if err != nil {
+				t.Errorf("ValidateRequest(%q) error = %v", tt.unixName, err)
+			}
+			continue
+		}
+		var reqErr *plugin.RequestError
+		if !errors.Is(err, tt.wantErr) || !errors.As(err, &reqErr) || reqErr.Field != "unix_name" {
+			t.Errorf("ValidateRequest(%q) error = %v, want %v on unix_name", tt.unixName, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateRequestChecksTags(t *testing.T) {
+	p := New("forge-ovh-cli")
+
+	if err := p.ValidateRequest(plugin.ProvisionRequest{UnixName: "alpha", Tags: map[string]string{"team": "payments"}}); err != nil {
+		t.Errorf("ValidateRequest() error = %v", err)
+	}
+	for _, value := range []string{"web team", "a/b"} {
+		err := p.ValidateRequest(plugin.ProvisionRequest{UnixName: "alpha", Tags: map[string]string{"team": value}})
+		var reqErr *plugin.RequestError
+		if !errors.Is(err, ErrInvalidTag) || !errors.As(err, &reqErr) || reqErr.Field != "tags" {
+			t.Errorf("ValidateRequest() with tag value %q error = %v, want ErrInvalidTag on tags", value, err)
+		}
+	}
+}
+
+func TestFindResourceUsesQualifiedName(t *testing.T) {
+	cli := writeFakeCLI(t, `[ "$1 $2 $3" = "status --name prod-alpha" ] && echo "ID: 321"`)
+	p := New(cli, WithNameAffixes("prod-", ""))
+
+	res, err := p.FindResource(context.Background(), "alpha")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if res.ResourceID != "321" || res.Metadata["resource_name"] != "prod-alpha" {
+		t.Fatalf("unexpected lookup result: %+v", res)
+	}
+}
+
+func TestFindResourceReturnsExistingResource(t *testing.T) {
+	cli := writeFakeCLI(t, `[ "$1 $2 $3" = "status --name alpha" ] && echo "ID: 321"`)
+	p := New(cli)
+
+	res, err := p.FindResource(context.Background(), "alpha")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if res.ResourceID != "321" {
+		t.Fatalf("expected resource id 321, got %q", res.ResourceID)
+	}
+}
+
+func TestFindResourceReportsNotFoundWithoutID(t *testing.T) {
+	cli := writeFakeCLI(t, `echo "no resource named $3"`)
+	p := New(cli)
+
+	_, err := p.FindResource(context.Background(), "alpha")
+	if !errors.Is(err, plugin.ErrResourceNotFound) {
+		t.Fatalf("expected ErrResourceNotFound, got %v", err)
+	}
+}
+
+func TestProvisionStreamEmitsLinesAsProduced(t *testing.T) {
+	gate := filepath.Join(t.TempDir(), "gate")
+	// The CLI only finishes once the first line has been seen by the caller,
+	// which can only happen if output is streamed rather than buffered.
+	cli := writeFakeCLI(t, `echo "cloning template"
+while [ ! -f "`+gate+`" ]; do sleep 0.01; done
+echo "booting"
+echo "ID: 77"`)
+	p := New(cli)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var lines []string
+	res, err := p.ProvisionStream(ctx, plugin.ProvisionRequest{UnixName: "alpha"}, func(line string) {
+		lines = append(lines, line)
+		if len(lines) == 1 {
+			if err := os.WriteFile(gate, nil, 0o600); err != nil {
+				t.Errorf("failed to open gate: %v", err)
+			}
+		}
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if res.ResourceID != "77" {
+		t.Errorf("expected resource id 77, got %q", res.ResourceID)
+	}
+	want := []string{"cloning template", "booting", "ID: 77"}
+	if strings.Join(lines, "|") != strings.Join(want, "|") {
+		t.Errorf("lines = %q, want %q", lines, want)
+	}
+}
+
+func TestProvisionStreamKillsCLIOnCancel(t *testing.T) {
+	cli := writeFakeCLI(t, `echo "cloning template"
+exec sleep 30`)
+	p := New(cli)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	start := time.Now()
+	_, err := p.ProvisionStream(ctx, plugin.ProvisionRequest{UnixName: "alpha"}, func(string) {
+		cancel()
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("cancellation took %s; the CLI was not killed", elapsed)
+	}
+}
+
+func TestProvisionPassesNode(t *testing.T) {
+	cli := writeFakeCLI(t, `echo "ID: vm-1"; echo "args: $*"`)
+
+	tests := []struct {
+		name        string
+		defaultNode string
+		node        string
+		wantArgs    string
+	}{
+		{name: "requested node", defaultNode: "pve-01", node: "pve-02", wantArgs: "--node pve-02"},
+		{name: "default node", defaultNode: "pve-01", wantArgs: "--node pve-01"},
+		{name: "no node", wantArgs: "create --name alpha\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(cli, WithDefaultNode(tt.defaultNode))
+
+			res, err := p.Provision(context.Background(), plugin.ProvisionRequest{UnixName: "alpha", Node: tt.node})
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if !strings.Contains(res.Metadata["cli_output"], tt.wantArgs) {
+				t.Errorf("expected the CLI to be called with %q, output: %s", tt.wantArgs, res.Metadata["cli_output"])
+			}
+			if want := cmp.Or(tt.node, tt.defaultNode); want != "" && res.Metadata["node"] != want {
+				t.Errorf("expected node %q in metadata, got %q", want, res.Metadata["node"])
+			}
+		})
+	}
+}
+
+func TestProvisionPassesSSHKeys(t *testing.T) {
+	const (
+		opsKey  = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIAABAgMEBQYHCAkKCwwNDg8QERITFBUWFxgZGhscHR4f ops"
+		userKey = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8g user"
+	)
+	cli := writeFakeCLI(t, `echo "ID: vm-1"; for arg in "$@"; do echo "arg: $arg"; done`)
+	p := New(cli, WithDefaultSSHKeys(opsKey))
+
+	res, err := p.Provision(context.Background(), plugin.ProvisionRequest{
+		UnixName: "alpha",
+		SSHKeys:  []string{userKey, opsKey},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := "arg: --ssh-key\narg: " + opsKey + "\narg: --ssh-key\narg: " + userKey + "\n"
+	if !strings.Contains(res.Metadata["cli_output"], want) {
+		t.Errorf("expected the default then the requested key, once each, output: %s", res.Metadata["cli_output"])
+	}
+}
+
+func TestProvisionRejectsMalformedSSHKeyBeforeRunningCLI(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "ran")
+	cli := writeFakeCLI(t, `touch "`+marker+`"; echo "ID: vm-1"`)
+	p := New(cli)
+
+	_, err := p.Provision(context.Background(), plugin.ProvisionRequest{UnixName: "alpha", SSHKeys: []string{"ssh-ed25519 nope"}})
+	if !errors.Is(err, plugin.ErrInvalidSSHKey) {
+		t.Fatalf("expected ErrInvalidSSHKey, got %v", err)
+	}
+	if got := plugin.Classify(err); got != plugin.ClassInvalidInput {
+		t.Errorf("Classify() = %q, want %q so it is not retried", got, plugin.ClassInvalidInput)
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Error("the CLI ran despite the malformed key")
+	}
+}
+
+func TestStatusReportsProgress(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   *int
+	}{
+		{
+			name:   "cloning",
+			script: `echo "status: cloning"; echo "drive-scsi0: transferred 8.0 GiB of 32.0 GiB (25.00%) in 20s" >&2`,
+			want:   func() *int { n := 25; return &n }(),
+		},
+		{
+			name:   "not reported",
+			script: `echo "status: running"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(writeFakeCLI(t, tt.script))
+
+			res, err := p.Status(context.Background(), "104")
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			switch {
+			case tt.want == nil && res.Progress != nil:
+				t.Errorf("expected no progress, got %d", *res.Progress)
+			case tt.want != nil && (res.Progress == nil || *res.Progress != *tt.want):
+				t.Errorf("expected progress %d, got %v", *tt.want, res.Progress)
+			}
+		})
+	}
+}
+
+func TestProvisionReportsNetwork(t *testing.T) {
+	p := New(writeFakeCLI(t, `echo "ID: 104"; echo "IP: 10.0.0.5/24"; echo "Hostname: alpha.lan"`))
+
+	res, err := p.Provision(context.Background(), plugin.ProvisionRequest{UnixName: "alpha"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := &plugin.NetworkInfo{Hostname: "alpha.lan", IPAddresses: []string{"10.0.0.5"}}
+	if !res.Network.Equal(want) {
+		t.Fatalf("expected network %+v, got %+v", want, res.Network)
+	}
+}
+
+func TestProvisionKeepsNetworkFromJSONParser(t *testing.T) {
+	cli := writeFakeCLI(t, `echo '{"resource_id":"vm-42","network":{"ip_addresses":["10.0.0.9"]}}'`)
+	p := New(cli, WithOutputParser(plugin.JSONParser{}))
+
+	res, err := p.Provision(context.Background(), plugin.ProvisionRequest{UnixName: "alpha"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !res.Network.Equal(&plugin.NetworkInfo{IPAddresses: []string{"10.0.0.9"}}) {
+		t.Fatalf("expected the parsed network, got %+v", res.Network)
+	}
+}
+
+func TestStatusReportsNetwork(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   *plugin.NetworkInfo
+	}{
+		{
+			name:   "assigned",
+			script: `echo "status: running"; echo "ipv4: 10.0.0.5"; echo "ipv6: fd00::5"`,
+			want:   &plugin.NetworkInfo{IPAddresses: []string{"10.0.0.5", "fd00::5"}},
+		},
+		{
+			name:   "not yet assigned",
+			script: `echo "status: starting"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(writeFakeCLI(t, tt.script))
+
+			res, err := p.Status(context.Background(), "104")
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if tt.want == nil && res.Network != nil {
+				t.Errorf("expected no network, got %+v", res.Network)
+			}
+			if tt.want != nil && !res.Network.Equal(tt.want) {
+				t.Errorf("expected network %+v, got %+v", tt.want, res.Network)
+			}
+		})
+	}
+}
+
+func TestProvisionStreamPassesStderrLines(t *testing.T) {
+	cli := writeFakeCLI(t, `echo "progress: 50%" >&2; echo "ID: vm-1"`)
+	p := New(cli)
+
+	var lines []string
+	res, err := p.ProvisionStream(context.Background(), plugin.ProvisionRequest{UnixName: "alpha"}, func(line string) {
+		lines = append(lines, line)
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if res.ResourceID != "vm-1" {
+		t.Errorf("expected resource id vm-1, got %q", res.ResourceID)
+	}
+	if !slices.Contains(lines, "progress: 50%") {
+		t.Errorf("expected the stderr line to be streamed, got %q", lines)
+	}
+}
+
+func TestDeprovisionIsIdempotent(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		wantErr bool
+	}{
+		{name: "deleted", script: `echo "deleted VM 104"`},
+		{name: "already gone by output", script: `echo "Error: VM 104 does not exist" >&2; exit 2`},
+		{name: "already gone by exit code", script: `exit 66`},
+		{name: "permission denied", script: `echo "permission denied: VM.Allocate required" >&2; exit 1`, wantErr: true},
+		{name: "cluster busy", script: `echo "can't lock file - got timeout" >&2; exit 1`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(writeFakeCLI(t, tt.script))
+
+			err := p.Deprovision(context.Background(), "104")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Deprovision() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
 	}
 }
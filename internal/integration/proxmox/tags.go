@@ -0,0 +1,72 @@
+package proxmox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+
+	"github.com/searge/quokka/internal/plugin"
+)
+
+// ErrInvalidTag is returned for a tag Proxmox would refuse.
+var ErrInvalidTag = errors.New("invalid proxmox tag")
+
+// tagPattern is the format Proxmox accepts for VM tags: letters, digits
+// and "_", "-", "+" or ".", not starting with one of the last three.
+var tagPattern = regexp.MustCompile(`^(?i)[a-z0-9_][a-z0-9_+.-]*$`)
+
+// Tags renders tags as Proxmox VM tags, sorted: "key-value", or just "key"
+// when the value is empty. Returns ErrInvalidTag for any that does not
+// follow Proxmox's tag format, e.g. because it contains "/" or a space.
+// Pure function.
+func Tags(tags map[string]string) ([]string, error) {
+	rendered := make([]string, 0, len(tags))
+	for key, value := range tags {
+		tag := key
+		if value != "" {
+			tag += "-" + value
+		}
+		if !tagPattern.MatchString(tag) {
+			return nil, fmt.Errorf("%w: %q from label %q", ErrInvalidTag, tag, key)
+		}
+		rendered = append(rendered, tag)
+	}
+	slices.Sort(rendered)
+	return rendered, nil
+}
+
+// tagArgs returns a --tag flag for each of tags. Invalid tags fail op
+// before the CLI runs, as invalid input.
+func tagArgs(op string, tags map[string]string) ([]string, error) {
+	rendered, err := Tags(tags)
+	if err != nil {
+		return nil, &plugin.PluginError{Plugin: "proxmox", Op: op, Class: plugin.ClassInvalidInput, Err: err}
+	}
+	args := make([]string, 0, 2*len(rendered))
+	for _, tag := range rendered {
+		args = append(args, "--tag", tag)
+	}
+	return args, nil
+}
+
+// SetTags replaces the tags of a resource via the CLI. No tags clears them.
+func (p *Plugin) SetTags(ctx context.Context, resourceID string, tags map[string]string) error {
+	args, err := tagArgs(plugin.OpTag, tags)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		args = []string{"--clear"}
+	}
+
+	cmd := command(ctx, p.cliPath, append([]string{"tag", "--id", resourceID}, args...)...)
+	cmd.Env = os.Environ()
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return cliError(ctx, plugin.OpTag, err, output)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package proxmox
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/searge/quokka/internal/plugin"
+)
+
+func TestTags(t *testing.T) {
+	tests := []struct {
+		name    string
+		tags    map[string]string
+		want    []string
+		wantErr bool
+	}{
+		{name: "none", tags: nil, want: []string{}},
+		{name: "key and value", tags: map[string]string{"team": "payments", "env": "prod"}, want: []string{"env-prod", "team-payments"}},
+		{name: "empty value", tags: map[string]string{"billable": ""}, want: []string{"billable"}},
+		{name: "allowed punctuation", tags: map[string]string{"cost_center": "r+d.1"}, want: []string{"cost_center-r+d.1"}},
+		{name: "slash in key", tags: map[string]string{"example.com/team": "payments"}, wantErr: true},
+		{name: "space in value", tags: map[string]string{"team": "Payments Team"}, wantErr: true},
+		{name: "leading dot", tags: map[string]string{".hidden": ""}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Tags(tt.tags)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidTag) {
+					t.Fatalf("Tags() error = %v, want ErrInvalidTag", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Tags() error = %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Tags() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProvisionPassesLabelsAsTags(t *testing.T) {
+	cli := writeFakeCLI(t, `echo "ID: vm-1"; for arg in "$@"; do echo "arg: $arg"; done`)
+	p := New(cli)
+
+	res, err := p.Provision(context.Background(), plugin.ProvisionRequest{
+		UnixName: "alpha",
+		Tags:     map[string]string{"team": "payments", "env": "prod"},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := "arg: --tag\narg: env-prod\narg: --tag\narg: team-payments\n"
+	if !strings.Contains(res.Metadata["cli_output"], want) {
+		t.Errorf("expected a --tag flag per label, output: %s", res.Metadata["cli_output"])
+	}
+}
+
+func TestProvisionRejectsInvalidTagBeforeRunningCLI(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "ran")
+	cli := writeFakeCLI(t, `touch "`+marker+`"; echo "ID: vm-1"`)
+	p := New(cli)
+
+	_, err := p.Provision(context.Background(), plugin.ProvisionRequest{
+		UnixName: "alpha",
+		Tags:     map[string]string{"team": "Payments Team"},
+	})
+	if !errors.Is(err, ErrInvalidTag) {
+		t.Fatalf("expected ErrInvalidTag, got %v", err)
+	}
+	if got := plugin.Classify(err); got != plugin.ClassInvalidInput {
+		t.Errorf("Classify() = %q, want %q so it is not retried", got, plugin.ClassInvalidInput)
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Error("the CLI ran despite the invalid tag")
+	}
+}
+
+func TestSetTagsReplacesResourceTags(t *testing.T) {
+	received := filepath.Join(t.TempDir(), "args")
+	cli := writeFakeCLI(t, `echo "$*" > `+received)
+	p := New(cli)
+
+	tests := []struct {
+		name     string
+		tags     map[string]string
+		wantArgs string
+	}{
+		{name: "tags", tags: map[string]string{"team": "payments"}, wantArgs: "tag --id 104 --tag team-payments"},
+		{name: "no tags", wantArgs: "tag --id 104 --clear"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := p.SetTags(context.Background(), "104", tt.tags); err != nil {
+				t.Fatalf("SetTags() error = %v", err)
+			}
+			raw, err := os.ReadFile(received)
+			if err != nil {
+				t.Fatalf("failed to read the arguments the cli received: %v", err)
+			}
+			if got := strings.TrimSpace(string(raw)); got != tt.wantArgs {
+				t.Errorf("cli called with %q, want %q", got, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestSetTagsClassifiesCLIFailures(t *testing.T) {
+	p := New(writeFakeCLI(t, `echo "Error: VM 104 does not exist" >&2; exit 2`))
+
+	err := p.SetTags(context.Background(), "104", map[string]string{"team": "payments"})
+	if got := plugin.Classify(err); got != plugin.ClassNotFound {
+		t.Errorf("Classify() = %q, want %q", got, plugin.ClassNotFound)
+	}
+}
@@ -0,0 +1,130 @@
+package proxmox
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/searge/quokka/internal/plugin"
+)
+
+// Version is a forge-ovh-cli release.
+type Version struct {
+	Major, Minor, Patch int
+}
+
+// jsonOutputSince is the first forge-ovh-cli release with --output json.
+var jsonOutputSince = Version{Major: 2}
+
+// versionTimeout bounds how long the CLI may take to tell its version.
+// Callers stop waiting when their own context ends; the run goes on.
+const versionTimeout = 10 * time.Second
+
+var versionRegex = regexp.MustCompile(`(\d+)\.(\d+)(?:\.(\d+))?`)
+
+// ParseVersion reads the first x.y or x.y.z version in the output of
+// forge-ovh-cli --version, e.g. "forge-ovh-cli version v2.1.0".
+// Pure function.
+func ParseVersion(output string) (Version, error) {
+	m := versionRegex.FindStringSubmatch(output)
+	if m == nil {
+		return Version{}, fmt.Errorf("no version in %q", output)
+	}
+	var v Version
+	v.Major, _ = strconv.Atoi(m[1])
+	v.Minor, _ = strconv.Atoi(m[2])
+	if m[3] != "" {
+		v.Patch, _ = strconv.Atoi(m[3])
+	}
+	return v, nil
+}
+
+// AtLeast reports whether v is other or a later release.
+func (v Version) AtLeast(other Version) bool {
+	if v.Major != other.Major {
+		return v.Major > other.Major
+	}
+	if v.Minor != other.Minor {
+		return v.Minor > other.Minor
+	}
+	return v.Patch >= other.Patch
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// cliVersion returns the version of the CLI, running it with --version on
+// first use and caching the answer for the life of the plugin, so fleets
+// mixing CLI releases each get the flags their own supports. ok is false
+// if the CLI did not tell its version; that is cached too, unless it did
+// not answer within versionTimeout. Concurrent callers share one run,
+// which outlives them: a caller whose context ends stops waiting, with ok
+// false, but the answer is still cached for the next.
+func (p *Plugin) cliVersion(ctx context.Context) (v Version, ok bool) {
+	if v, ok, checked := p.cachedVersion(); checked {
+		return v, ok
+	}
+
+	callCtx := context.WithoutCancel(ctx)
+	ch := p.versionGroup.DoChan("version", func() (any, error) {
+		if _, _, checked := p.cachedVersion(); checked {
+			return nil, nil
+		}
+		callCtx, cancel := context.WithTimeout(callCtx, versionTimeout)
+		defer cancel()
+
+		var v Version
+		output, err := command(callCtx, p.cliPath, "--version").Output()
+		if err == nil {
+			v, err = ParseVersion(string(output))
+		}
+		if callCtx.Err() != nil {
+			return nil, nil
+		}
+		p.versionMu.Lock()
+		defer p.versionMu.Unlock()
+		p.versionChecked = true
+		p.version, p.versionKnown = v, err == nil
+		return nil, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return Version{}, false
+	case <-ch:
+		v, ok, _ = p.cachedVersion()
+		return v, ok
+	}
+}
+
+func (p *Plugin) cachedVersion() (v Version, ok, checked bool) {
+	p.versionMu.Lock()
+	defer p.versionMu.Unlock()
+	return p.version, p.versionKnown, p.versionChecked
+}
+
+// outputMode picks how the CLI is asked to print a resource and how that
+// output is parsed. With the JSON parser configured, a CLI known to
+// support it is passed --output json; one known to predate it is called
+// the older way, without the flag, and its default key-value output is
+// parsed instead. A CLI of unknown version is called as it always was.
+func (p *Plugin) outputMode(ctx context.Context) (args []string, parser plugin.OutputParser, err error) {
+	if _, ok := p.parser.(plugin.JSONParser); !ok {
+		return nil, p.parser, nil
+	}
+	v, ok := p.cliVersion(ctx)
+	switch {
+	case !ok:
+		return nil, p.parser, nil
+	case v.AtLeast(jsonOutputSince):
+		return []string{"--output", "json"}, p.parser, nil
+	}
+	legacy, err := plugin.NewOutputParser(plugin.OutputFormatKeyValue, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	return nil, legacy, nil
+}
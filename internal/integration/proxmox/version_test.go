@@ -0,0 +1,159 @@
+package proxmox
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/searge/quokka/internal/plugin"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		output string
+		want   Version
+	}{
+		{"forge-ovh-cli version v2.1.0\n", Version{2, 1, 0}},
+		{"1.4", Version{1, 4, 0}},
+		{"forge-ovh-cli 10.0.3 (linux/amd64)", Version{10, 0, 3}},
+	}
+	for _, tt := range tests {
+		got, err := ParseVersion(tt.output)
+		if err != nil {
+			t.Errorf("ParseVersion(%q): unexpected error %v", tt.output, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseVersion(%q) = %v, want %v", tt.output, got, tt.want)
+		}
+	}
+
+	if _, err := ParseVersion("unknown flag: --version"); err == nil {
+		t.Error("expected an error for output without a version")
+	}
+}
+
+func TestVersionAtLeast(t *testing.T) {
+	if !(Version{2, 0, 0}).AtLeast(jsonOutputSince) {
+		t.Error("2.0.0 should be at least 2.0.0")
+	}
+	if !(Version{2, 0, 1}).AtLeast(Version{1, 9, 9}) {
+		t.Error("2.0.1 should be at least 1.9.9")
+	}
+	if (Version{1, 9, 9}).AtLeast(jsonOutputSince) {
+		t.Error("1.9.9 should not be at least 2.0.0")
+	}
+}
+
+// writeVersionedCLI writes a fake CLI reporting version and recording the
+// arguments of every other call, one call per line, in the returned file.
+// With --output json it prints JSON, otherwise "ID:" lines.
+func writeVersionedCLI(t *testing.T, version string) (cli, calls string) {
+	t.Helper()
+
+	calls = filepath.Join(t.TempDir(), "calls")
+	cli = writeFakeCLI(t, `if [ "$1" = "--version" ]; then echo "forge-ovh-cli version `+version+`"; exit 0; fi
+echo "$*" >> "`+calls+`"
+case "$*" in
+*"--output json"*) echo '{"resource_id":"vm-42","status":"creating"}' ;;
+*) echo "ID: 321" ;;
+esac`)
+	return cli, calls
+}
+
+func readCalls(t *testing.T, path string) []string {
+	t.Helper()
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read recorded calls: %v", err)
+	}
+	return strings.Split(strings.TrimSpace(string(raw)), "\n")
+}
+
+func TestProvisionPassesJSONOutputToNewCLI(t *testing.T) {
+	cli, calls := writeVersionedCLI(t, "v2.1.0")
+	p := New(cli, WithOutputParser(plugin.JSONParser{}))
+
+	res, err := p.Provision(context.Background(), plugin.ProvisionRequest{UnixName: "alpha"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if res.ResourceID != "vm-42" {
+		t.Fatalf("expected resource id vm-42, got %q", res.ResourceID)
+	}
+	if got := readCalls(t, calls); !strings.HasSuffix(got[0], "--output json") {
+		t.Errorf("cli called with %q, want --output json", got[0])
+	}
+}
+
+func TestProvisionFallsBackForOldCLI(t *testing.T) {
+	cli, calls := writeVersionedCLI(t, "v1.8.2")
+	p := New(cli, WithOutputParser(plugin.JSONParser{}))
+
+	res, err := p.Provision(context.Background(), plugin.ProvisionRequest{UnixName: "alpha"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if res.ResourceID != "321" {
+		t.Fatalf("expected resource id 321, got %q", res.ResourceID)
+	}
+	if got := readCalls(t, calls); strings.Contains(got[0], "--output") {
+		t.Errorf("cli called with %q, want no --output", got[0])
+	}
+}
+
+func TestFindResourceFallsBackForOldCLI(t *testing.T) {
+	cli, calls := writeVersionedCLI(t, "1.2")
+	p := New(cli, WithOutputParser(plugin.JSONParser{}))
+
+	res, err := p.FindResource(context.Background(), "alpha")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if res.ResourceID != "321" {
+		t.Fatalf("expected resource id 321, got %q", res.ResourceID)
+	}
+	if got := readCalls(t, calls); got[0] != "status --name alpha" {
+		t.Errorf("cli called with %q, want status --name alpha", got[0])
+	}
+}
+
+func TestCLIVersionIsCached(t *testing.T) {
+	probes := filepath.Join(t.TempDir(), "probes")
+	cli := writeFakeCLI(t, `if [ "$1" = "--version" ]; then echo x >> "`+probes+`"; echo "v2.0.0"; exit 0; fi
+echo '{"resource_id":"vm-42"}'`)
+	p := New(cli, WithOutputParser(plugin.JSONParser{}))
+
+	for range 3 {
+		if _, err := p.Provision(context.Background(), plugin.ProvisionRequest{UnixName: "alpha"}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+	if got := readCalls(t, probes); len(got) != 1 {
+		t.Errorf("cli asked for its version %d times, want once", len(got))
+	}
+}
+
+func TestCLIVersionOutlivesCallerThatGaveUp(t *testing.T) {
+	probes := filepath.Join(t.TempDir(), "probes")
+	cli := writeFakeCLI(t, `if [ "$1" = "--version" ]; then sleep 0.3; echo x >> "`+probes+`"; echo "v2.0.0"; exit 0; fi`)
+	p := New(cli, WithOutputParser(plugin.JSONParser{}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, ok := p.cliVersion(ctx); ok {
+		t.Fatal("cliVersion() ok before the CLI answered, want the caller to give up")
+	}
+
+	v, ok := p.cliVersion(context.Background())
+	if !ok || v != (Version{Major: 2}) {
+		t.Fatalf("cliVersion() = %v, %v, want 2.0.0", v, ok)
+	}
+	if got := readCalls(t, probes); len(got) != 1 {
+		t.Errorf("cli asked for its version %d times, want the abandoned run reused", len(got))
+	}
+}
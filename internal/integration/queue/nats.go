@@ -0,0 +1,110 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// ErrAuthorization is returned when the queue rejects the credentials.
+var ErrAuthorization = errors.New("message queue rejected the credentials")
+
+// ErrNoStream is returned when no JetStream stream stored a published
+// message, so no worker would ever see it.
+var ErrNoStream = errors.New("no stream stored the message")
+
+// NATS is a Broker on a NATS server with JetStream. Messages are published
+// to JetStream and only count as accepted once a stream acknowledged
+// storing them; status requests use core request-reply. One connection is
+// shared by every call; it is opened on first use and reopened after it
+// closed, and reconnects on its own in between.
+type NATS struct {
+	url string
+
+	mu   sync.Mutex
+	conn *nats.Conn
+	js   jetstream.JetStream
+}
+
+// NewNATS creates a NATS broker for a nats://[user:password@]host[:port]
+// URL, or nats://token@host[:port] for token authentication. A tls://
+// URL requires TLS; a nats:// one uses it when the server requires it.
+func NewNATS(rawURL string) (*NATS, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "nats" && u.Scheme != "tls") || u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid NATS URL %q, want nats://host[:port] or tls://host[:port]", rawURL)
+	}
+	return &NATS{url: rawURL}, nil
+}
+
+// Publish stores data on subject in the JetStream stream bound to it,
+// returning once the stream acknowledged it. A subject no stream is bound
+// to fails with ErrNoStream.
+func (n *NATS) Publish(ctx context.Context, subject string, data []byte) error {
+	_, js, err := n.connection()
+	if err != nil {
+		return err
+	}
+	if _, err := js.Publish(ctx, subject, data); err != nil {
+		if errors.Is(err, jetstream.ErrNoStreamResponse) {
+			return fmt.Errorf("NATS: publish on %s: %w", subject, ErrNoStream)
+		}
+		return fmt.Errorf("NATS: publish on %s: %w", subject, err)
+	}
+	return nil
+}
+
+// Request sends data on subject and returns the first reply.
+func (n *NATS) Request(ctx context.Context, subject string, data []byte) ([]byte, error) {
+	conn, _, err := n.connection()
+	if err != nil {
+		return nil, err
+	}
+	msg, err := conn.RequestWithContext(ctx, subject, data)
+	if err != nil {
+		return nil, fmt.Errorf("NATS: request on %s: %w", subject, err)
+	}
+	return msg.Data, nil
+}
+
+// Ping checks the connection with a round trip to the server.
+func (n *NATS) Ping(ctx context.Context) error {
+	conn, _, err := n.connection()
+	if err != nil {
+		return err
+	}
+	if err := conn.FlushWithContext(ctx); err != nil {
+		return fmt.Errorf("NATS: %w", err)
+	}
+	return nil
+}
+
+// connection returns the shared connection, opening it if there is none
+// or the last one closed for good.
+func (n *NATS) connection() (*nats.Conn, jetstream.JetStream, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.conn != nil && !n.conn.IsClosed() {
+		return n.conn, n.js, nil
+	}
+
+	conn, err := nats.Connect(n.url, nats.Name("quokka"), nats.MaxReconnects(-1))
+	if err != nil {
+		if errors.Is(err, nats.ErrAuthorization) {
+			return nil, nil, fmt.Errorf("NATS: %w: %w", ErrAuthorization, err)
+		}
+		return nil, nil, fmt.Errorf("connect to NATS: %w", err)
+	}
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("NATS: %w", err)
+	}
+	n.conn, n.js = conn, js
+	return conn, js, nil
+}
@@ -0,0 +1,224 @@
+// Package queue implements a plugin that hands provisioning to external
+// workers through a message queue instead of running anything itself.
+//
+// Every call is a JSON Message published on <prefix>.<type>, type being
+// provision, deprovision or status. The plugin derives the resource ID of a
+// provision request from its project ID, and every later message about
+// that resource carries it, so workers correlate them by it. Provision and
+// deprovision are stored in a durable stream: the request is accepted once
+// the queue acknowledged storing it, and the resource reports pending until
+// a worker answers status requests otherwise. Status is a request the
+// worker replies to with a StatusReply.
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/searge/quokka/internal/plugin"
+)
+
+// Name is the name the plugin registers under.
+const Name = "queue"
+
+// Message types, also the last token of the subject they are published on.
+const (
+	TypeProvision   = "provision"
+	TypeDeprovision = "deprovision"
+	TypeStatus      = "status"
+)
+
+// StatusPending is the status of a resource whose provisioning was queued
+// but not yet reported on by a worker.
+const StatusPending = "pending"
+
+const (
+	// DefaultSubjectPrefix prefixes the subjects messages are published on.
+	DefaultSubjectPrefix = "quokka.provision"
+	// DefaultStatusTimeout bounds the wait for a worker to answer a status
+	// request.
+	DefaultStatusTimeout = 5 * time.Second
+)
+
+// resourceNamespace scopes the resource IDs derived from project IDs.
+var resourceNamespace = uuid.MustParse("8f6a3c2e-5b1d-4e7f-9a0c-2d4b6e8f1a3c")
+
+// Broker is the message queue the plugin publishes to.
+type Broker interface {
+	// Publish stores data on subject, returning once the queue
+	// acknowledged storing it durably.
+	Publish(ctx context.Context, subject string, data []byte) error
+	// Request sends data on subject and waits for the first reply.
+	Request(ctx context.Context, subject string, data []byte) ([]byte, error)
+	// Ping checks that the queue is reachable.
+	Ping(ctx context.Context) error
+}
+
+// Message is what the plugin publishes.
+type Message struct {
+	Type       string `json:"type"`
+	ResourceID string `json:"resource_id"`
+	// Request is the provisioning request; set on provision messages only.
+	Request *plugin.ProvisionRequest `json:"request,omitempty"`
+	SentAt  time.Time                `json:"sent_at"`
+}
+
+// StatusReply is what a worker answers a status request with: the
+// resource's status, or an error with the plugin.ErrorClass it falls in,
+// e.g. not_found for a resource it never heard of.
+type StatusReply struct {
+	plugin.StatusResult
+	Error      string            `json:"error,omitempty"`
+	ErrorClass plugin.ErrorClass `json:"error_class,omitempty"`
+}
+
+// Plugin publishes provisioning to a Broker.
+type Plugin struct {
+	broker        Broker
+	prefix        string
+	statusTimeout time.Duration
+	now           func() time.Time
+}
+
+// Option configures optional Plugin behaviour.
+type Option func(*Plugin)
+
+// WithSubjectPrefix publishes on <prefix>.<type> instead of
+// DefaultSubjectPrefix. An empty prefix keeps the default.
+func WithSubjectPrefix(prefix string) Option {
+	return func(p *Plugin) {
+		if prefix != "" {
+			p.prefix = prefix
+		}
+	}
+}
+
+// WithStatusTimeout bounds the wait for a status reply by d, unless the
+// caller's deadline is sooner. Zero keeps DefaultStatusTimeout.
+func WithStatusTimeout(d time.Duration) Option {
+	return func(p *Plugin) {
+		if d > 0 {
+			p.statusTimeout = d
+		}
+	}
+}
+
+// New creates a queue plugin publishing to broker.
+func New(broker Broker, opts ...Option) *Plugin {
+	p := &Plugin{
+		broker:        broker,
+		prefix:        DefaultSubjectPrefix,
+		statusTimeout: DefaultStatusTimeout,
+		now:           time.Now,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+func (p *Plugin) Name() string {
+	return Name
+}
+
+// Health checks that the queue is reachable. Whether any worker listens
+// cannot be told from here.
+func (p *Plugin) Health(ctx context.Context) error {
+	if err := p.broker.Ping(ctx); err != nil {
+		return fmt.Errorf("message queue unreachable: %w", err)
+	}
+	return nil
+}
+
+// Provision publishes req under the resource ID of its project and reports
+// the resource pending.
+func (p *Plugin) Provision(ctx context.Context, req plugin.ProvisionRequest) (*plugin.ProvisionResult, error) {
+	resourceID := resourceID(req)
+	if err := p.publish(ctx, plugin.OpProvision, Message{Type: TypeProvision, ResourceID: resourceID, Request: &req}); err != nil {
+		return nil, err
+	}
+	return &plugin.ProvisionResult{ResourceID: resourceID, Status: StatusPending}, nil
+}
+
+// Status asks the workers for the status of resourceID.
+func (p *Plugin) Status(ctx context.Context, resourceID string) (*plugin.StatusResult, error) {
+	data, err := p.encode(plugin.OpStatus, Message{Type: TypeStatus, ResourceID: resourceID})
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.statusTimeout)
+	defer cancel()
+
+	raw, err := p.broker.Request(ctx, p.subject(TypeStatus), data)
+	if err != nil {
+		return nil, p.fail(plugin.OpStatus, err)
+	}
+	var reply StatusReply
+	if err := json.Unmarshal(raw, &reply); err != nil {
+		return nil, &plugin.PluginError{Plugin: Name, Op: plugin.OpStatus, Class: plugin.ClassUnknown,
+			Err: fmt.Errorf("decode status reply: %w", err)}
+	}
+	if reply.Error != "" {
+		class := reply.ErrorClass
+		if class == "" {
+			class = plugin.ClassUnknown
+		}
+		return nil, &plugin.PluginError{Plugin: Name, Op: plugin.OpStatus, Class: class, Err: errors.New(reply.Error)}
+	}
+	return &reply.StatusResult, nil
+}
+
+// Deprovision publishes a request to destroy resourceID.
+func (p *Plugin) Deprovision(ctx context.Context, resourceID string) error {
+	return p.publish(ctx, plugin.OpDeprovision, Message{Type: TypeDeprovision, ResourceID: resourceID})
+}
+
+// resourceID returns the resource ID req is published under: a UUID
+// derived from its project ID, so that a publish retried after a transient
+// failure names the same resource rather than a second one. Pure function.
+func resourceID(req plugin.ProvisionRequest) string {
+	return uuid.NewSHA1(resourceNamespace, []byte(req.ProjectID)).String()
+}
+
+func (p *Plugin) subject(messageType string) string {
+	return p.prefix + "." + messageType
+}
+
+func (p *Plugin) publish(ctx context.Context, op string, msg Message) error {
+	data, err := p.encode(op, msg)
+	if err != nil {
+		return err
+	}
+	if err := p.broker.Publish(ctx, p.subject(msg.Type), data); err != nil {
+		return p.fail(op, err)
+	}
+	return nil
+}
+
+func (p *Plugin) encode(op string, msg Message) ([]byte, error) {
+	msg.SentAt = p.now().UTC()
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return nil, &plugin.PluginError{Plugin: Name, Op: op, Class: plugin.ClassInvalidInput, Err: err}
+	}
+	return data, nil
+}
+
+// fail classifies a broker error: rejected credentials are unauthorized,
+// a subject no stream stores is unknown, as retrying cannot fix the queue's
+// setup, and anything else, such as an unreachable queue or a status
+// request no worker answered in time, is transient.
+func (p *Plugin) fail(op string, err error) error {
+	class := plugin.ClassTransient
+	switch {
+	case errors.Is(err, ErrAuthorization):
+		class = plugin.ClassUnauthorized
+	case errors.Is(err, ErrNoStream):
+		class = plugin.ClassUnknown
+	}
+	return &plugin.PluginError{Plugin: Name, Op: op, Class: class, Err: err}
+}
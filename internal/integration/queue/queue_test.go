@@ -0,0 +1,219 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/searge/quokka/internal/plugin"
+)
+
+type published struct {
+	subject string
+	data    []byte
+}
+
+// fakeBroker records what is published and answers requests with reply.
+type fakeBroker struct {
+	published []published
+	reply     func(subject string, data []byte) ([]byte, error)
+	err       error
+}
+
+func (b *fakeBroker) Publish(_ context.Context, subject string, data []byte) error {
+	if b.err != nil {
+		return b.err
+	}
+	b.published = append(b.published, published{subject: subject, data: data})
+	return nil
+}
+
+func (b *fakeBroker) Request(_ context.Context, subject string, data []byte) ([]byte, error) {
+	b.published = append(b.published, published{subject: subject, data: data})
+	return b.reply(subject, data)
+}
+
+func (b *fakeBroker) Ping(context.Context) error {
+	return b.err
+}
+
+var sentAt = time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC)
+
+func newTestPlugin(broker Broker, opts ...Option) *Plugin {
+	p := New(broker, opts...)
+	p.now = func() time.Time { return sentAt }
+	return p
+}
+
+// decode unmarshals a published message into a generic map, so the test
+// asserts the wire shape rather than the Go type.
+func decode(t *testing.T, data []byte) map[string]any {
+	t.Helper()
+	var msg map[string]any
+	if err := json.Unmarshal(data, &msg); err != nil {
+		t.Fatalf("published %s, not JSON: %v", data, err)
+	}
+	return msg
+}
+
+func TestProvisionPublishesRequest(t *testing.T) {
+	broker := &fakeBroker{}
+	p := newTestPlugin(broker, WithSubjectPrefix("ops.provision"))
+
+	got, err := p.Provision(context.Background(), plugin.ProvisionRequest{
+		ProjectID:   "p-1",
+		ProjectName: "web",
+		Resources:   map[string]interface{}{"cores": 2},
+	})
+	if err != nil {
+		t.Fatalf("Provision() error = %v", err)
+	}
+	const wantID = "010f7061-078c-5443-b3a3-8dec303ce7c1"
+	if got.ResourceID != wantID || got.Status != StatusPending {
+		t.Errorf("Provision() = %+v, want %s pending", got, wantID)
+	}
+
+	if len(broker.published) != 1 {
+		t.Fatalf("published %d messages, want 1", len(broker.published))
+	}
+	if broker.published[0].subject != "ops.provision.provision" {
+		t.Errorf("subject = %q, want ops.provision.provision", broker.published[0].subject)
+	}
+	msg := decode(t, broker.published[0].data)
+	want := map[string]any{
+		"type":        "provision",
+		"resource_id": wantID,
+		"sent_at":     "2026-10-16T09:30:00Z",
+		"request": map[string]any{
+			"project_id":   "p-1",
+			"project_name": "web",
+			"resources":    map[string]any{"cores": float64(2)},
+		},
+	}
+	if !reflect.DeepEqual(msg, want) {
+		t.Errorf("published %v, want %v", msg, want)
+	}
+}
+
+func TestProvisionRetryKeepsResourceID(t *testing.T) {
+	broker := &fakeBroker{}
+	p := newTestPlugin(broker)
+	req := plugin.ProvisionRequest{ProjectID: "p-1", ProjectName: "web"}
+
+	// A retry repeats the call with the same request
+	first, err := p.Provision(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Provision() error = %v", err)
+	}
+	retried, err := p.Provision(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Provision() error = %v", err)
+	}
+	if retried.ResourceID != first.ResourceID {
+		t.Errorf("retry published resource %s, want %s again", retried.ResourceID, first.ResourceID)
+	}
+
+	other, err := p.Provision(context.Background(), plugin.ProvisionRequest{ProjectID: "p-2", ProjectName: "web"})
+	if err != nil {
+		t.Fatalf("Provision() error = %v", err)
+	}
+	if other.ResourceID == first.ResourceID {
+		t.Errorf("projects p-1 and p-2 share resource %s", first.ResourceID)
+	}
+}
+
+func TestDeprovisionPublishesResourceID(t *testing.T) {
+	broker := &fakeBroker{}
+	p := newTestPlugin(broker)
+
+	if err := p.Deprovision(context.Background(), "res-9"); err != nil {
+		t.Fatalf("Deprovision() error = %v", err)
+	}
+	if len(broker.published) != 1 || broker.published[0].subject != "quokka.provision.deprovision" {
+		t.Fatalf("published %+v, want one message on quokka.provision.deprovision", broker.published)
+	}
+	want := map[string]any{"type": "deprovision", "resource_id": "res-9", "sent_at": "2026-10-16T09:30:00Z"}
+	if msg := decode(t, broker.published[0].data); !reflect.DeepEqual(msg, want) {
+		t.Errorf("published %v, want %v", msg, want)
+	}
+}
+
+func TestStatusRequestsWorkerReply(t *testing.T) {
+	broker := &fakeBroker{reply: func(string, []byte) ([]byte, error) {
+		return []byte(`{"status":"running","metadata":{"ip":"10.0.0.5"}}`), nil
+	}}
+	p := newTestPlugin(broker)
+
+	got, err := p.Status(context.Background(), "res-1")
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if got.Status != "running" || got.Metadata["ip"] != "10.0.0.5" {
+		t.Errorf("Status() = %+v, want running with its metadata", got)
+	}
+	if broker.published[0].subject != "quokka.provision.status" {
+		t.Errorf("subject = %q, want quokka.provision.status", broker.published[0].subject)
+	}
+	want := map[string]any{"type": "status", "resource_id": "res-1", "sent_at": "2026-10-16T09:30:00Z"}
+	if msg := decode(t, broker.published[0].data); !reflect.DeepEqual(msg, want) {
+		t.Errorf("published %v, want %v", msg, want)
+	}
+}
+
+func TestErrorsAreClassified(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply string
+		err   error
+		want  plugin.ErrorClass
+	}{
+		{name: "worker reports not found", reply: `{"error":"no such resource","error_class":"not_found"}`, want: plugin.ClassNotFound},
+		{name: "worker reports an unclassified error", reply: `{"error":"disk full"}`, want: plugin.ClassUnknown},
+		{name: "malformed reply", reply: `not json`, want: plugin.ClassUnknown},
+		{name: "no reply in time", err: context.DeadlineExceeded, want: plugin.ClassTransient},
+		{name: "rejected credentials", err: ErrAuthorization, want: plugin.ClassUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			broker := &fakeBroker{reply: func(string, []byte) ([]byte, error) {
+				return []byte(tt.reply), tt.err
+			}}
+			_, err := newTestPlugin(broker).Status(context.Background(), "res-1")
+			var pe *plugin.PluginError
+			if !errors.As(err, &pe) {
+				t.Fatalf("Status() error = %v, want a PluginError", err)
+			}
+			if pe.Class != tt.want || pe.Plugin != Name || pe.Op != plugin.OpStatus {
+				t.Errorf("Status() error = %+v, want class %s", pe, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnstoredProvisionFails(t *testing.T) {
+	broker := &fakeBroker{err: fmt.Errorf("NATS: publish on quokka.provision.provision: %w", ErrNoStream)}
+
+	_, err := newTestPlugin(broker).Provision(context.Background(), plugin.ProvisionRequest{ProjectID: "p-1"})
+	var pe *plugin.PluginError
+	if !errors.As(err, &pe) || pe.Class != plugin.ClassUnknown || pe.Class.Retryable() {
+		t.Errorf("Provision() error = %v, want a PluginError that is not retried", err)
+	}
+}
+
+func TestUnreachableQueueIsTransient(t *testing.T) {
+	broker := &fakeBroker{err: errors.New("connection refused")}
+	p := newTestPlugin(broker)
+
+	_, err := p.Provision(context.Background(), plugin.ProvisionRequest{ProjectID: "p-1"})
+	var pe *plugin.PluginError
+	if !errors.As(err, &pe) || pe.Class != plugin.ClassTransient || !pe.Class.Retryable() {
+		t.Errorf("Provision() error = %v, want a retryable transient PluginError", err)
+	}
+	if err := p.Health(context.Background()); err == nil {
+		t.Error("Health() error = nil, want the queue reported unreachable")
+	}
+}
@@ -0,0 +1,48 @@
+package operations
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/searge/quokka/internal/platform"
+)
+
+type Handler struct {
+	service *Service
+	log     *slog.Logger
+}
+
+func NewHandler(service *Service, logger *slog.Logger) *Handler {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Handler{service: service, log: logger}
+}
+
+func (h *Handler) Routes() http.Handler {
+	r := chi.NewRouter()
+
+	r.Get("/{id}", h.GetByID)
+
+	return r
+}
+
+// GetByID reports an operation's progress; poll it until done is true.
+func (h *Handler) GetByID(w http.ResponseWriter, r *http.Request) {
+	op, err := h.service.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrOperationNotFound):
+			platform.RespondError(w, http.StatusNotFound, "OPERATION_NOT_FOUND", "operation not found")
+		case errors.Is(err, ErrInvalidOperationID):
+			platform.RespondError(w, http.StatusBadRequest, "INVALID_OPERATION_ID", "invalid operation id")
+		default:
+			platform.RespondServerError(w, h.log, err)
+		}
+		return
+	}
+
+	platform.RespondJSON(w, http.StatusOK, op)
+}
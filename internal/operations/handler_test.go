@@ -0,0 +1,53 @@
+package operations
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandlerGetByID(t *testing.T) {
+	store := newMemoryStore()
+	s := newService(store, nil)
+	op, err := s.Start(context.Background(), "project.create", func(context.Context) (string, error) {
+		return "projects/p-1", nil
+	})
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if err := s.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	h := NewHandler(s, nil)
+
+	tests := []struct {
+		name     string
+		id       string
+		wantCode int
+	}{
+		{name: "finished operation", id: op.ID, wantCode: http.StatusOK},
+		{name: "unknown operation", id: "op-404", wantCode: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			h.Routes().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/"+tt.id, nil))
+
+			if rr.Code != tt.wantCode {
+				t.Fatalf("expected %d, got %d", tt.wantCode, rr.Code)
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			var got Operation
+			if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+				t.Fatalf("failed to decode response body: %v", err)
+			}
+			if !got.Done || got.Resource != "projects/p-1" {
+				t.Errorf("unexpected operation: %+v", got)
+			}
+		})
+	}
+}
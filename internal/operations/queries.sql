@@ -0,0 +1,41 @@
+-- name: CreateOperation :one
+INSERT INTO operations (
+    id, kind, status, resource, created_at, updated_at, estimated_completion
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+)
+RETURNING id, kind, status, resource, error, created_at, updated_at, done_at, estimated_completion;
+
+-- name: FailAbandonedOperations :many
+-- Fails every unfinished operation no instance has touched since
+-- stale_before, i.e. whose instance stopped without finishing it.
+UPDATE operations
+SET
+    status = 'error',
+    error = sqlc.arg('error'),
+    updated_at = sqlc.arg('now'),
+    done_at = sqlc.arg('now')
+WHERE done_at IS NULL AND updated_at < sqlc.arg('stale_before')
+RETURNING id, kind, status, resource, error, created_at, updated_at, done_at, estimated_completion;
+
+-- name: GetOperation :one
+SELECT id, kind, status, resource, error, created_at, updated_at, done_at, estimated_completion
+FROM operations
+WHERE id = $1;
+
+-- name: TouchOperations :exec
+-- Marks unfinished operations as still held by a live instance.
+UPDATE operations
+SET updated_at = sqlc.arg('now')
+WHERE id = ANY(sqlc.arg('ids')::uuid[]) AND done_at IS NULL;
+
+-- name: UpdateOperation :one
+UPDATE operations
+SET
+    status = $2,
+    resource = $3,
+    error = $4,
+    updated_at = $5,
+    done_at = $6
+WHERE id = $1
+RETURNING id, kind, status, resource, error, created_at, updated_at, done_at, estimated_completion;
@@ -0,0 +1,209 @@
+package operations
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrInvalidPriority is returned for a priority name that is not one of
+// the known levels.
+var ErrInvalidPriority = errors.New("invalid priority")
+
+// Priority orders queued operations: higher priorities are started first,
+// and operations of equal priority in the order they were queued.
+type Priority int
+
+const (
+	PriorityLow    Priority = -1
+	PriorityNormal Priority = 0
+	PriorityHigh   Priority = 1
+	PriorityUrgent Priority = 2
+)
+
+// priorityNames maps each level to the name clients use for it.
+var priorityNames = map[Priority]string{
+	PriorityLow:    "low",
+	PriorityNormal: "normal",
+	PriorityHigh:   "high",
+	PriorityUrgent: "urgent",
+}
+
+// ParsePriority returns the level called name. An empty name is normal.
+func ParsePriority(name string) (Priority, error) {
+	if name == "" {
+		return PriorityNormal, nil
+	}
+	for p, n := range priorityNames {
+		if n == name {
+			return p, nil
+		}
+	}
+	return PriorityNormal, fmt.Errorf("%w: %q", ErrInvalidPriority, name)
+}
+
+func (p Priority) String() string {
+	if name, ok := priorityNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("priority(%d)", int(p))
+}
+
+// job is an operation waiting for a worker.
+type job struct {
+	priority Priority
+	seq      uint64
+	queuedAt time.Time
+	run      func()
+}
+
+// jobHeap implements heap.Interface, highest priority, then oldest, first.
+type jobHeap []*job
+
+func (h jobHeap) Len() int { return len(h) }
+
+func (h jobHeap) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h jobHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+// oldest returns when the longest-waiting job was queued, in Unix
+// nanoseconds, or zero if h is empty. Pure function.
+func (h jobHeap) oldest() int64 {
+	var oldest *job
+	for _, j := range h {
+		if oldest == nil || j.seq < oldest.seq {
+			oldest = j
+		}
+	}
+	if oldest == nil {
+		return 0
+	}
+	return oldest.queuedAt.UnixNano()
+}
+
+func (h *jobHeap) Push(x any) { *h = append(*h, x.(*job)) }
+
+func (h *jobHeap) Pop() any {
+	old := *h
+	n := len(old)
+	j := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return j
+}
+
+// QueueStats is a snapshot of the operation queue.
+type QueueStats struct {
+	// Depth counts operations accepted but waiting for a worker.
+	Depth int64 `json:"depth"`
+	// InFlight counts operations a worker is running.
+	InFlight int64 `json:"in_flight"`
+	// OldestQueuedSeconds is how long the longest-waiting operation has
+	// been queued, zero when none is.
+	OldestQueuedSeconds float64 `json:"oldest_queued_seconds"`
+}
+
+// queue runs jobs on at most workers goroutines, picking the next job by
+// priority whenever one is free; zero workers starts every job at once.
+// Workers exist only while there is work. With a maxDepth, at most that
+// many jobs wait for a worker, counting places claimed by reserve. Its
+// gauges are kept in atomics so they can be read without waiting on the
+// queue.
+type queue struct {
+	workers  int
+	maxDepth int
+	now      func() time.Time
+
+	mu       sync.Mutex
+	jobs     jobHeap
+	seq      uint64
+	active   int
+	reserved int
+
+	depth    atomic.Int64
+	inFlight atomic.Int64
+	// oldest is when the longest-waiting job was queued, in Unix
+	// nanoseconds, or zero when none is.
+	oldest atomic.Int64
+}
+
+func newQueue(workers int) *queue {
+	return &queue{workers: workers, now: time.Now}
+}
+
+// reserve claims a place for a job about to be pushed, reporting false if
+// the queue is already full.
+func (q *queue) reserve() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.maxDepth > 0 && q.jobs.Len()+q.reserved >= q.maxDepth {
+		return false
+	}
+	q.reserved++
+	return true
+}
+
+// unreserve gives back a place claimed by reserve for a job that will not
+// be pushed after all.
+func (q *queue) unreserve() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.reserved = max(q.reserved-1, 0)
+}
+
+// push queues run at priority, starting a worker if one is free. The job
+// takes the place claimed for it by reserve, if any.
+func (q *queue) push(priority Priority, run func()) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.reserved = max(q.reserved-1, 0)
+	q.seq++
+	now := q.now()
+	heap.Push(&q.jobs, &job{priority: priority, seq: q.seq, queuedAt: now, run: run})
+	q.depth.Add(1)
+	if q.jobs.Len() == 1 {
+		q.oldest.Store(now.UnixNano())
+	}
+	if q.workers == 0 || q.active < q.workers {
+		q.active++
+		go q.work()
+	}
+}
+
+// work runs queued jobs until there are none left.
+func (q *queue) work() {
+	for {
+		q.mu.Lock()
+		if q.jobs.Len() == 0 {
+			q.active--
+			q.mu.Unlock()
+			return
+		}
+		j := heap.Pop(&q.jobs).(*job)
+		q.depth.Add(-1)
+		q.oldest.Store(q.jobs.oldest())
+		q.inFlight.Add(1)
+		q.mu.Unlock()
+
+		j.run()
+		q.inFlight.Add(-1)
+	}
+}
+
+// stats returns the queue's gauges as of now.
+func (q *queue) stats() QueueStats {
+	stats := QueueStats{Depth: q.depth.Load(), InFlight: q.inFlight.Load()}
+	if oldest := q.oldest.Load(); oldest != 0 {
+		stats.OldestQueuedSeconds = max(q.now().Sub(time.Unix(0, oldest)).Seconds(), 0)
+	}
+	return stats
+}
@@ -0,0 +1,265 @@
+package operations
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParsePriority(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Priority
+		wantErr bool
+	}{
+		{name: "", want: PriorityNormal},
+		{name: "low", want: PriorityLow},
+		{name: "normal", want: PriorityNormal},
+		{name: "high", want: PriorityHigh},
+		{name: "urgent", want: PriorityUrgent},
+		{name: "URGENT", wantErr: true},
+		{name: "asap", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParsePriority(tt.name)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidPriority) {
+				t.Errorf("ParsePriority(%q) error = %v, want ErrInvalidPriority", tt.name, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParsePriority(%q) = %v, %v, want %v", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestServiceEnqueueStartsHigherPrioritiesFirst(t *testing.T) {
+	s := newService(newMemoryStore(), nil, WithWorkers(1))
+
+	// Occupy the only worker so everything after it queues up.
+	release := make(chan struct{})
+	if _, err := s.Start(context.Background(), "block", func(context.Context) (string, error) {
+		<-release
+		return "", nil
+	}); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	var mu sync.Mutex
+	var order []string
+	for _, q := range []struct {
+		name     string
+		priority Priority
+	}{
+		{"low", PriorityLow},
+		{"normal-1", PriorityNormal},
+		{"urgent", PriorityUrgent},
+		{"normal-2", PriorityNormal},
+		{"high", PriorityHigh},
+	} {
+		if _, err := s.Enqueue(context.Background(), q.name, q.priority, func(context.Context) (string, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, q.name)
+			return "", nil
+		}); err != nil {
+			t.Fatalf("Enqueue(%s) error = %v", q.name, err)
+		}
+	}
+	if got := s.Running(); got != 6 {
+		t.Errorf("Running() = %d with five queued behind one, want 6", got)
+	}
+
+	close(release)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Wait(ctx); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+
+	want := []string{"urgent", "high", "normal-1", "normal-2", "low"}
+	if !slices.Equal(order, want) {
+		t.Errorf("run order = %v, want %v", order, want)
+	}
+}
+
+func TestServiceWithWorkersBoundsConcurrency(t *testing.T) {
+	const workers = 3
+	s := newService(newMemoryStore(), nil, WithWorkers(workers))
+
+	var mu sync.Mutex
+	current, peak := 0, 0
+	for range 12 {
+		if _, err := s.Start(context.Background(), "work", func(context.Context) (string, error) {
+			mu.Lock()
+			current++
+			peak = max(peak, current)
+			mu.Unlock()
+
+			time.Sleep(5 * time.Millisecond)
+
+			mu.Lock()
+			current--
+			mu.Unlock()
+			return "", nil
+		}); err != nil {
+			t.Fatalf("Start() error = %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Wait(ctx); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if peak > workers {
+		t.Errorf("%d operations ran at once, want at most %d", peak, workers)
+	}
+}
+
+// fakeClock is a settable time source.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+}
+
+func TestServiceQueueStatsCountsWaitingOperations(t *testing.T) {
+	start := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	clock := &fakeClock{now: start}
+	s := newService(newMemoryStore(), nil, WithWorkers(1))
+	s.queue.now = clock.Now
+
+	started, release := make(chan struct{}), make(chan struct{})
+	if _, err := s.Start(context.Background(), "block", func(context.Context) (string, error) {
+		close(started)
+		<-release
+		return "", nil
+	}); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	<-started
+
+	clock.Set(start.Add(10 * time.Second))
+	for _, priority := range []Priority{PriorityLow, PriorityUrgent, PriorityNormal} {
+		if _, err := s.Enqueue(context.Background(), "work", priority, func(context.Context) (string, error) {
+			return "", nil
+		}); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+	}
+
+	clock.Set(start.Add(40 * time.Second))
+	want := QueueStats{Depth: 3, InFlight: 1, OldestQueuedSeconds: 30}
+	if got := s.QueueStats(); got != want {
+		t.Errorf("QueueStats() = %+v with three waiting, want %+v", got, want)
+	}
+	if got := s.QueueWait(time.Minute); got != 4*time.Minute {
+		t.Errorf("QueueWait(1m) = %v with four ahead of one worker, want 4m", got)
+	}
+
+	close(release)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Wait(ctx); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	// The last worker decrements in-flight just after the operation's
+	// WaitGroup is released, so allow it a moment.
+	deadline := time.Now().Add(5 * time.Second)
+	for s.QueueStats() != (QueueStats{}) && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := s.QueueStats(); got != (QueueStats{}) {
+		t.Errorf("QueueStats() = %+v once drained, want zeros", got)
+	}
+}
+
+func TestQueueStatsTracksOldestAcrossPriorities(t *testing.T) {
+	start := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	clock := &fakeClock{now: start}
+	q := newQueue(1)
+	q.now = clock.Now
+
+	// With no worker free, jobs stay queued; push takes the only slot.
+	q.active = 1
+	q.push(PriorityLow, func() {})
+	clock.Set(start.Add(5 * time.Second))
+	q.push(PriorityUrgent, func() {})
+
+	// Pop the urgent job as a worker would: the low one is still oldest.
+	q.mu.Lock()
+	heap.Pop(&q.jobs)
+	q.depth.Add(-1)
+	q.oldest.Store(q.jobs.oldest())
+	q.mu.Unlock()
+
+	clock.Set(start.Add(20 * time.Second))
+	if got := q.stats(); got.Depth != 1 || got.OldestQueuedSeconds != 20 {
+		t.Errorf("stats() = %+v, want the low job 20s old", got)
+	}
+}
+
+func TestServiceQueueWaitWithFreeWorkers(t *testing.T) {
+	for _, s := range []*Service{
+		newService(newMemoryStore(), nil),
+		newService(newMemoryStore(), nil, WithWorkers(2)),
+	} {
+		s.queue.inFlight.Store(1)
+		if got := s.QueueWait(time.Minute); got != 0 {
+			t.Errorf("QueueWait(1m) = %v with %d workers and one running, want none", got, s.queue.workers)
+		}
+	}
+}
+
+func TestServiceWithQueueDepthRejectsWhenFull(t *testing.T) {
+	store := newMemoryStore()
+	s := newService(store, nil, WithWorkers(1), WithQueueDepth(1))
+
+	started, release := make(chan struct{}), make(chan struct{})
+	if _, err := s.Start(context.Background(), "block", func(context.Context) (string, error) {
+		close(started)
+		<-release
+		return "", nil
+	}); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	<-started
+
+	work := func(context.Context) (string, error) { return "", nil }
+	if _, err := s.Start(context.Background(), "queued", work); err != nil {
+		t.Fatalf("Start() error = %v with room in the queue", err)
+	}
+	if _, err := s.Start(context.Background(), "overflow", work); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("expected ErrQueueFull, got %v", err)
+	}
+	if got := len(store.ops); got != 2 {
+		t.Errorf("recorded %d operations, want the rejected one left out", got)
+	}
+
+	close(release)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Wait(ctx); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if _, err := s.Start(context.Background(), "later", work); err != nil {
+		t.Errorf("Start() error = %v once the queue drained", err)
+	}
+}
@@ -0,0 +1,386 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrOperationNotFound  = errors.New("operation not found")
+	ErrInvalidOperationID = errors.New("invalid operation id format")
+	// ErrQueueFull is returned by Enqueue when as many operations as the
+	// queue depth allows are already waiting for a worker.
+	ErrQueueFull = errors.New("operation queue is full")
+	// ErrShuttingDown is returned by Enqueue once Shutdown was called, and
+	// is the cause of the contexts of operations it cancels.
+	ErrShuttingDown = errors.New("operations are shutting down")
+	// ErrAbandoned is recorded on operations whose instance stopped before
+	// finishing them.
+	ErrAbandoned = errors.New("operation abandoned: the instance running it stopped")
+)
+
+// defaultCancelGrace is how long Shutdown waits, by default, for the
+// operations it cancels to record how they ended.
+const defaultCancelGrace = 5 * time.Second
+
+// abandonedAfterBeats is how many heartbeats an unfinished operation must
+// miss before it is taken for abandoned.
+const abandonedAfterBeats = 3
+
+// Func is the work behind an operation. It returns the name of the resource
+// it produced, relative to the API root, which may be set even on error.
+type Func func(ctx context.Context) (resource string, err error)
+
+// Service starts operations in the background and reports on them.
+type Service struct {
+	store   operationStore
+	log     *slog.Logger
+	wg      sync.WaitGroup
+	running atomic.Int64
+	queue   *queue
+
+	cancelGrace time.Duration
+	onAbandoned func(ctx context.Context, op *Operation)
+
+	// held are the IDs of the operations accepted here and not finished,
+	// kept alive by Run.
+	heldMu sync.Mutex
+	held   map[string]struct{}
+
+	// mu orders accepting operations against Shutdown, so none is
+	// added to wg once Shutdown waits on it.
+	mu     sync.Mutex
+	closed bool
+	// stopping is cancelled when Shutdown gives up waiting, cancelling
+	// every operation still running or queued.
+	stopping context.Context
+	stop     context.CancelFunc
+}
+
+type operationStore interface {
+	Create(ctx context.Context, kind, resource string, estimatedCompletion time.Time) (*Operation, error)
+	GetByID(ctx context.Context, id string) (*Operation, error)
+	Update(ctx context.Context, id string, status Status, resource, errMsg string) (*Operation, error)
+	Touch(ctx context.Context, ids []string) error
+	FailAbandoned(ctx context.Context, staleBefore time.Time, errMsg string) ([]*Operation, error)
+}
+
+// ServiceOption configures optional Service behaviour.
+type ServiceOption func(*Service)
+
+// WithWorkers runs at most n operations at once. Further operations wait,
+// pending, and are started by priority as running ones finish. Zero, the
+// default, starts every operation immediately.
+func WithWorkers(n int) ServiceOption {
+	return func(s *Service) {
+		s.queue.workers = max(n, 0)
+	}
+}
+
+// WithQueueDepth lets at most n operations wait for a worker; Enqueue
+// returns ErrQueueFull instead of accepting more. Zero, the default, lets
+// any number wait. It only matters with WithWorkers.
+func WithQueueDepth(n int) ServiceOption {
+	return func(s *Service) {
+		s.queue.maxDepth = max(n, 0)
+	}
+}
+
+// WithCancelGrace makes Shutdown wait up to d for the operations it
+// cancels to record how they ended, instead of defaultCancelGrace.
+func WithCancelGrace(d time.Duration) ServiceOption {
+	return func(s *Service) {
+		s.cancelGrace = max(d, 0)
+	}
+}
+
+// WithAbandonedHandler calls fn for every operation Run fails as
+// abandoned, e.g. to settle the resource it was working on.
+func WithAbandonedHandler(fn func(ctx context.Context, op *Operation)) ServiceOption {
+	return func(s *Service) {
+		s.onAbandoned = fn
+	}
+}
+
+// EnqueueOption configures one operation started by Enqueue.
+type EnqueueOption func(*enqueueOptions)
+
+type enqueueOptions struct {
+	resource            string
+	estimatedCompletion time.Time
+}
+
+// WithResource records up front the resource the operation works on,
+// relative to the API root, e.g. so it can be settled if the operation is
+// abandoned.
+func WithResource(name string) EnqueueOption {
+	return func(o *enqueueOptions) {
+		o.resource = name
+	}
+}
+
+// WithEstimatedCompletion records that the operation is expected to finish
+// at t, for clients to show. It is not enforced.
+func WithEstimatedCompletion(t time.Time) EnqueueOption {
+	return func(o *enqueueOptions) {
+		o.estimatedCompletion = t
+	}
+}
+
+// NewService creates a new Service.
+func NewService(store *Store, logger *slog.Logger, opts ...ServiceOption) *Service {
+	return newService(store, logger, opts...)
+}
+
+func newService(store operationStore, logger *slog.Logger, opts ...ServiceOption) *Service {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	s := &Service{
+		store:       store,
+		log:         logger,
+		queue:       newQueue(0),
+		cancelGrace: defaultCancelGrace,
+		held:        make(map[string]struct{}),
+	}
+	s.stopping, s.stop = context.WithCancel(context.Background())
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Start is Enqueue at normal priority.
+func (s *Service) Start(ctx context.Context, kind string, fn Func) (*Operation, error) {
+	return s.Enqueue(ctx, kind, PriorityNormal, fn)
+}
+
+// Enqueue records a pending operation of the given kind and runs fn in the
+// background, once a worker is free if their number is limited; priority
+// decides which waiting operation goes next. fn keeps running after ctx is
+// cancelled, since the caller typically returns as soon as the operation
+// is accepted; only Shutdown cancels it. Returns ErrQueueFull if the queue
+// has no room left and ErrShuttingDown after Shutdown, recording nothing.
+func (s *Service) Enqueue(ctx context.Context, kind string, priority Priority, fn Func, opts ...EnqueueOption) (*Operation, error) {
+	var o enqueueOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if err := s.accept(); err != nil {
+		return nil, err
+	}
+	op, err := s.store.Create(ctx, kind, o.resource, o.estimatedCompletion)
+	if err != nil {
+		s.queue.unreserve()
+		s.done()
+		return nil, err
+	}
+	s.hold(op.ID)
+
+	ctx = context.WithoutCancel(ctx)
+	s.queue.push(priority, func() { s.run(ctx, op.ID, fn) })
+	return op, nil
+}
+
+// accept counts one more operation as running and claims its place in the
+// queue, unless the service is shutting down or the queue is full.
+func (s *Service) accept() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrShuttingDown
+	}
+	if !s.queue.reserve() {
+		return ErrQueueFull
+	}
+	s.wg.Add(1)
+	s.running.Add(1)
+	return nil
+}
+
+// done counts an accepted operation as finished.
+func (s *Service) done() {
+	s.running.Add(-1)
+	s.wg.Done()
+}
+
+// run drives an operation from running to done or error. fn's context is
+// cancelled with ErrShuttingDown if Shutdown gives up on it, but its
+// outcome is still recorded. Failures to record progress are logged; the
+// work itself is not retried.
+func (s *Service) run(ctx context.Context, id string, fn Func) {
+	defer s.done()
+	defer s.release(id)
+
+	runCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	if s.stopping.Err() != nil {
+		cancel(ErrShuttingDown)
+	}
+	defer context.AfterFunc(s.stopping, func() { cancel(ErrShuttingDown) })()
+
+	if _, err := s.store.Update(ctx, id, StatusRunning, "", ""); err != nil {
+		s.log.Warn("failed to mark operation running", "operation_id", id, "error", err)
+	}
+
+	resource, err := fn(runCtx)
+	status, errMsg := StatusDone, ""
+	if err != nil {
+		status, errMsg = StatusError, err.Error()
+	}
+
+	if _, err := s.store.Update(ctx, id, status, resource, errMsg); err != nil {
+		s.log.Error("failed to record operation outcome", "operation_id", id, "status", status, "error", err)
+	}
+}
+
+// hold counts operation id as held here until release.
+func (s *Service) hold(id string) {
+	s.heldMu.Lock()
+	defer s.heldMu.Unlock()
+	s.held[id] = struct{}{}
+}
+
+// release stops holding operation id.
+func (s *Service) release(id string) {
+	s.heldMu.Lock()
+	defer s.heldMu.Unlock()
+	delete(s.held, id)
+}
+
+// heldIDs returns the operations held here.
+func (s *Service) heldIDs() []string {
+	s.heldMu.Lock()
+	defer s.heldMu.Unlock()
+	ids := make([]string, 0, len(s.held))
+	for id := range s.held {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// beat keeps the operations held here alive, then fails those no instance
+// has kept alive for abandonedAfterBeats intervals, passing each to the
+// abandoned handler. It returns how many were failed.
+func (s *Service) beat(ctx context.Context, interval time.Duration) (int, error) {
+	if ids := s.heldIDs(); len(ids) > 0 {
+		if err := s.store.Touch(ctx, ids); err != nil {
+			return 0, err
+		}
+	}
+	abandoned, err := s.store.FailAbandoned(ctx, time.Now().Add(-abandonedAfterBeats*interval), ErrAbandoned.Error())
+	if err != nil {
+		return 0, err
+	}
+	for _, op := range abandoned {
+		s.log.Warn("failed abandoned operation", "operation_id", op.ID, "kind", op.Kind, "resource", op.Resource)
+		if s.onAbandoned != nil {
+			s.onAbandoned(ctx, op)
+		}
+	}
+	return len(abandoned), nil
+}
+
+// Run keeps the operations held here alive, and fails abandoned ones,
+// every interval until ctx is done. It starts at once, so operations left
+// unfinished by an instance that stopped, e.g. before a restart, are
+// failed as soon as they can be told apart from those still running
+// elsewhere. Every instance must use the same interval. Failures are
+// logged and retried on the next tick.
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.beat(ctx, interval); err != nil && ctx.Err() == nil {
+			s.log.Error("failed to keep operations alive", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Get returns the current state of an operation.
+func (s *Service) Get(ctx context.Context, id string) (*Operation, error) {
+	op, err := s.store.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrOperationNotFound
+		}
+		return nil, err
+	}
+	return op, nil
+}
+
+// Running returns the number of operations accepted but not yet finished,
+// including those still waiting for a worker.
+func (s *Service) Running() int {
+	return int(s.running.Load())
+}
+
+// QueueStats reports how far behind the operation workers are.
+func (s *Service) QueueStats() QueueStats {
+	return s.queue.stats()
+}
+
+// QueueWait returns how long an operation enqueued now is expected to wait
+// for a worker if each operation ahead of it, running or queued, takes
+// each: nothing while a worker is free, and each for every full round of
+// workers ahead of it otherwise. Without WithWorkers nothing waits.
+func (s *Service) QueueWait(each time.Duration) time.Duration {
+	if s.queue.workers == 0 {
+		return 0
+	}
+	stats := s.queue.stats()
+	rounds := (stats.Depth + stats.InFlight) / int64(s.queue.workers)
+	return time.Duration(rounds) * each
+}
+
+// Wait blocks until every started operation has finished or ctx is done,
+// returning ctx's error in the latter case.
+func (s *Service) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown stops accepting operations and waits for those accepted to
+// finish, including any still queued. If ctx is done first, it cancels
+// the contexts of the remaining ones with ErrShuttingDown, waits up to the
+// cancel grace for them to wind down and record how far they got, and
+// returns ctx's error.
+func (s *Service) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+
+	err := s.Wait(ctx)
+	if err == nil {
+		return nil
+	}
+	s.stop()
+
+	graceCtx, cancel := context.WithTimeout(context.Background(), s.cancelGrace)
+	defer cancel()
+	if s.Wait(graceCtx) != nil {
+		s.log.Warn("operations did not record their outcome in time", "running", s.Running())
+	}
+	return err
+}
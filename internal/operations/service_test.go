@@ -0,0 +1,362 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// memoryStore is an in-memory operationStore that records every status an
+// operation passes through.
+type memoryStore struct {
+	mu      sync.Mutex
+	ops     map[string]*Operation
+	history map[string][]Status
+	next    int
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{ops: map[string]*Operation{}, history: map[string][]Status{}}
+}
+
+func (m *memoryStore) Create(_ context.Context, kind, resource string, estimatedCompletion time.Time) (*Operation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.next++
+	now := time.Now()
+	op := &Operation{ID: fmt.Sprintf("op-%d", m.next), Kind: kind, Status: StatusPending, Resource: resource, CreatedAt: now, UpdatedAt: now}
+	if !estimatedCompletion.IsZero() {
+		op.EstimatedCompletion = &estimatedCompletion
+	}
+	m.ops[op.ID] = op
+	m.history[op.ID] = []Status{StatusPending}
+	copied := *op
+	return &copied, nil
+}
+
+func (m *memoryStore) GetByID(_ context.Context, id string) (*Operation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	op, ok := m.ops[id]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	copied := *op
+	return &copied, nil
+}
+
+func (m *memoryStore) Update(_ context.Context, id string, status Status, resource, errMsg string) (*Operation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	op, ok := m.ops[id]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	op.Status, op.Done, op.Resource, op.Error = status, isTerminal(status), resource, errMsg
+	op.UpdatedAt = time.Now()
+	m.history[id] = append(m.history[id], status)
+	copied := *op
+	return &copied, nil
+}
+
+func (m *memoryStore) Touch(_ context.Context, ids []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, id := range ids {
+		if op, ok := m.ops[id]; ok && !op.Done {
+			op.UpdatedAt = time.Now()
+		}
+	}
+	return nil
+}
+
+func (m *memoryStore) FailAbandoned(_ context.Context, staleBefore time.Time, errMsg string) ([]*Operation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var failed []*Operation
+	for id, op := range m.ops {
+		if op.Done || !op.UpdatedAt.Before(staleBefore) {
+			continue
+		}
+		op.Status, op.Done, op.Error, op.UpdatedAt = StatusError, true, errMsg, time.Now()
+		m.history[id] = append(m.history[id], StatusError)
+		copied := *op
+		failed = append(failed, &copied)
+	}
+	return failed, nil
+}
+
+func (m *memoryStore) statuses(id string) []Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Status(nil), m.history[id]...)
+}
+
+func TestServiceStartRunsOperationToDone(t *testing.T) {
+	store := newMemoryStore()
+	s := newService(store, nil)
+
+	release := make(chan struct{})
+	op, err := s.Start(context.Background(), "project.create", func(context.Context) (string, error) {
+		<-release
+		return "projects/p-1", nil
+	})
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if op.Status != StatusPending || op.Done {
+		t.Fatalf("expected a pending operation, got %+v", op)
+	}
+
+	close(release)
+	if err := s.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+
+	got, err := s.Get(context.Background(), op.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if !got.Done || got.Status != StatusDone || got.Resource != "projects/p-1" {
+		t.Errorf("unexpected finished operation: %+v", got)
+	}
+	want := []Status{StatusPending, StatusRunning, StatusDone}
+	if fmt.Sprint(store.statuses(op.ID)) != fmt.Sprint(want) {
+		t.Errorf("status history = %v, want %v", store.statuses(op.ID), want)
+	}
+}
+
+func TestServiceEnqueueRecordsEstimatedCompletion(t *testing.T) {
+	s := newService(newMemoryStore(), nil)
+	eta := time.Now().Add(2 * time.Minute)
+
+	op, err := s.Enqueue(context.Background(), "project.create", PriorityNormal, func(context.Context) (string, error) {
+		return "projects/p-1", nil
+	}, WithEstimatedCompletion(eta))
+	if err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	if op.EstimatedCompletion == nil || !op.EstimatedCompletion.Equal(eta) {
+		t.Errorf("EstimatedCompletion = %v, want %v", op.EstimatedCompletion, eta)
+	}
+	if err := s.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+
+	got, err := s.Get(context.Background(), op.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got.EstimatedCompletion == nil || !got.EstimatedCompletion.Equal(eta) {
+		t.Errorf("polled EstimatedCompletion = %v, want %v", got.EstimatedCompletion, eta)
+	}
+}
+
+func TestServiceStartRecordsFailure(t *testing.T) {
+	s := newService(newMemoryStore(), nil)
+
+	op, err := s.Start(context.Background(), "project.create", func(context.Context) (string, error) {
+		return "projects/p-1", errors.New("provisioning failed")
+	})
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if err := s.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+
+	got, err := s.Get(context.Background(), op.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if !got.Done || got.Status != StatusError || got.Error != "provisioning failed" || got.Resource != "projects/p-1" {
+		t.Errorf("unexpected failed operation: %+v", got)
+	}
+}
+
+func TestServiceStartOutlivesCallerContext(t *testing.T) {
+	s := newService(newMemoryStore(), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var workErr error
+	_, err := s.Start(ctx, "project.create", func(ctx context.Context) (string, error) {
+		cancel()
+		workErr = ctx.Err()
+		return "", nil
+	})
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if err := s.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if workErr != nil {
+		t.Errorf("operation context was cancelled with the request: %v", workErr)
+	}
+}
+
+func TestServiceGetReportsNotFound(t *testing.T) {
+	s := newService(newMemoryStore(), nil)
+
+	if _, err := s.Get(context.Background(), "op-404"); !errors.Is(err, ErrOperationNotFound) {
+		t.Fatalf("expected ErrOperationNotFound, got %v", err)
+	}
+}
+
+func TestServiceWaitHonoursContext(t *testing.T) {
+	s := newService(newMemoryStore(), nil)
+	release := make(chan struct{})
+	defer close(release)
+
+	if _, err := s.Start(context.Background(), "slow", func(context.Context) (string, error) {
+		<-release
+		return "", nil
+	}); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := s.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected Wait to give up with the context, got %v", err)
+	}
+	if got := s.Running(); got != 1 {
+		t.Errorf("Running() = %d, want the abandoned operation counted", got)
+	}
+}
+
+func TestServiceShutdownDrainsQueuedOperations(t *testing.T) {
+	store := newMemoryStore()
+	s := newService(store, nil, WithWorkers(1))
+
+	var ids []string
+	for range 3 {
+		op, err := s.Start(context.Background(), "work", func(context.Context) (string, error) {
+			time.Sleep(5 * time.Millisecond)
+			return "", nil
+		})
+		if err != nil {
+			t.Fatalf("Start() error = %v", err)
+		}
+		ids = append(ids, op.ID)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	for _, id := range ids {
+		if got := store.statuses(id); got[len(got)-1] != StatusDone {
+			t.Errorf("operation %s went through %v, want it run to done", id, got)
+		}
+	}
+	if _, err := s.Start(context.Background(), "late", func(context.Context) (string, error) {
+		return "", nil
+	}); !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("expected ErrShuttingDown after Shutdown, got %v", err)
+	}
+}
+
+func TestServiceShutdownCancelsOperationsOnTimeout(t *testing.T) {
+	store := newMemoryStore()
+	s := newService(store, nil)
+
+	cause := make(chan error, 1)
+	op, err := s.Start(context.Background(), "slow", func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		cause <- context.Cause(ctx)
+		return "", ctx.Err()
+	})
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := s.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected Shutdown to give up with the context, got %v", err)
+	}
+	if got := <-cause; !errors.Is(got, ErrShuttingDown) {
+		t.Errorf("operation cancelled with %v, want ErrShuttingDown", got)
+	}
+
+	// The outcome is still recorded.
+	if err := s.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if got, _ := s.Get(context.Background(), op.ID); got.Status != StatusError {
+		t.Errorf("status = %q, want error", got.Status)
+	}
+}
+
+func TestServiceShutdownWaitsForCancelledOperationsToRecordOutcome(t *testing.T) {
+	store := newMemoryStore()
+	s := newService(store, nil, WithCancelGrace(5*time.Second))
+
+	op, err := s.Start(context.Background(), "slow", func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		time.Sleep(20 * time.Millisecond) // winding down
+		return "", ctx.Err()
+	})
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := s.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected Shutdown to give up with the context, got %v", err)
+	}
+	if got := store.statuses(op.ID); got[len(got)-1] != StatusError {
+		t.Errorf("operation went through %v by the time Shutdown returned, want it recorded as error", got)
+	}
+	if got := s.Running(); got != 0 {
+		t.Errorf("Running() = %d after Shutdown, want 0", got)
+	}
+}
+
+func TestServiceBeatFailsAbandonedOperations(t *testing.T) {
+	store := newMemoryStore()
+	var abandoned []string
+	s := newService(store, nil, WithAbandonedHandler(func(_ context.Context, op *Operation) {
+		abandoned = append(abandoned, op.Resource)
+	}))
+
+	// Left pending by an instance that stopped.
+	orphan, err := store.Create(context.Background(), "project.create", "projects/p-1", time.Time{})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	release := make(chan struct{})
+	defer close(release)
+	held, err := s.Enqueue(context.Background(), "project.create", PriorityNormal, func(context.Context) (string, error) {
+		<-release
+		return "projects/p-2", nil
+	}, WithResource("projects/p-2"))
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	const interval = 10 * time.Millisecond
+	time.Sleep(abandonedAfterBeats*interval + interval)
+	n, err := s.beat(context.Background(), interval)
+	if err != nil {
+		t.Fatalf("beat() error = %v", err)
+	}
+	if n != 1 || len(abandoned) != 1 || abandoned[0] != "projects/p-1" {
+		t.Fatalf("failed %d, handled %v; want only the orphaned operation", n, abandoned)
+	}
+	got, _ := s.Get(context.Background(), orphan.ID)
+	if got.Status != StatusError || got.Error != ErrAbandoned.Error() {
+		t.Errorf("orphan = %+v, want it failed as abandoned", got)
+	}
+	if got, _ := s.Get(context.Background(), held.ID); got.Done {
+		t.Errorf("operation held here = %+v, want it left running", got)
+	}
+}
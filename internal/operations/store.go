@@ -0,0 +1,197 @@
+package operations
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/searge/quokka/internal/operations/db"
+	"github.com/searge/quokka/internal/platform"
+)
+
+// Store provides data access for operations via sqlc.
+type Store struct {
+	queries      *db.Queries
+	queryTimeout time.Duration
+}
+
+// StoreOption configures optional Store behaviour.
+type StoreOption func(*Store)
+
+// WithQueryTimeout bounds every store query by d. A zero duration leaves
+// queries bounded only by the caller's context.
+func WithQueryTimeout(d time.Duration) StoreOption {
+	return func(s *Store) {
+		s.queryTimeout = d
+	}
+}
+
+// NewStore initializes a new Store instance.
+func NewStore(pool *pgxpool.Pool, opts ...StoreOption) *Store {
+	s := &Store{queries: db.New(platform.NewPoolDB(pool))}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *Store) queryContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.queryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, s.queryTimeout)
+}
+
+// Create inserts a new pending operation of the given kind, working on
+// resource if known, expected to finish at estimatedCompletion; zero
+// leaves that unknown.
+func (s *Store) Create(ctx context.Context, kind, resource string, estimatedCompletion time.Time) (*Operation, error) {
+	now := pgtype.Timestamptz{Time: time.Now(), Valid: true}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	row, err := s.queries.CreateOperation(ctx, db.CreateOperationParams{
+		ID:                  pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		Kind:                kind,
+		Status:              string(StatusPending),
+		Resource:            resource,
+		CreatedAt:           now,
+		UpdatedAt:           now,
+		EstimatedCompletion: pgtype.Timestamptz{Time: estimatedCompletion, Valid: !estimatedCompletion.IsZero()},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mapToDomainOperation(row), nil
+}
+
+// GetByID retrieves an operation by its ID. Returns pgx.ErrNoRows if it
+// does not exist.
+func (s *Store) GetByID(ctx context.Context, id string) (*Operation, error) {
+	uid, err := parseOperationID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	row, err := s.queries.GetOperation(ctx, pgtype.UUID{Bytes: uid, Valid: true})
+	if err != nil {
+		return nil, err
+	}
+	return mapToDomainOperation(row), nil
+}
+
+// Update records the operation's status, the resource it produced and its
+// error message. Terminal statuses also stamp the completion time.
+func (s *Store) Update(ctx context.Context, id string, status Status, resource, errMsg string) (*Operation, error) {
+	uid, err := parseOperationID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	params := db.UpdateOperationParams{
+		ID:        pgtype.UUID{Bytes: uid, Valid: true},
+		Status:    string(status),
+		Resource:  resource,
+		Error:     errMsg,
+		UpdatedAt: pgtype.Timestamptz{Time: now, Valid: true},
+	}
+	if isTerminal(status) {
+		params.DoneAt = pgtype.Timestamptz{Time: now, Valid: true}
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	row, err := s.queries.UpdateOperation(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return mapToDomainOperation(row), nil
+}
+
+// Touch marks the unfinished operations among ids as still held by this
+// instance.
+func (s *Store) Touch(ctx context.Context, ids []string) error {
+	uids := make([]pgtype.UUID, 0, len(ids))
+	for _, id := range ids {
+		uid, err := parseOperationID(id)
+		if err != nil {
+			return err
+		}
+		uids = append(uids, pgtype.UUID{Bytes: uid, Valid: true})
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	return s.queries.TouchOperations(ctx, db.TouchOperationsParams{
+		Now: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		Ids: uids,
+	})
+}
+
+// FailAbandoned fails every unfinished operation left untouched since
+// staleBefore with errMsg, and returns them.
+func (s *Store) FailAbandoned(ctx context.Context, staleBefore time.Time, errMsg string) ([]*Operation, error) {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	rows, err := s.queries.FailAbandonedOperations(ctx, db.FailAbandonedOperationsParams{
+		Error:       errMsg,
+		Now:         pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		StaleBefore: pgtype.Timestamptz{Time: staleBefore, Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	ops := make([]*Operation, 0, len(rows))
+	for _, row := range rows {
+		ops = append(ops, mapToDomainOperation(row))
+	}
+	return ops, nil
+}
+
+// parseOperationID parses id, rejecting malformed values and the nil UUID.
+// Pure function.
+func parseOperationID(id string) (uuid.UUID, error) {
+	uid, err := uuid.Parse(id)
+	if err != nil || uid == uuid.Nil {
+		return uuid.Nil, ErrInvalidOperationID
+	}
+	return uid, nil
+}
+
+// isTerminal reports whether an operation in status has finished.
+// Pure function.
+func isTerminal(status Status) bool {
+	return status == StatusDone || status == StatusError
+}
+
+func mapToDomainOperation(row db.Operation) *Operation {
+	op := &Operation{
+		ID:        uuid.UUID(row.ID.Bytes).String(),
+		Kind:      row.Kind,
+		Status:    Status(row.Status),
+		Done:      isTerminal(Status(row.Status)),
+		Resource:  row.Resource,
+		Error:     row.Error,
+		CreatedAt: row.CreatedAt.Time,
+		UpdatedAt: row.UpdatedAt.Time,
+	}
+	if row.DoneAt.Valid {
+		doneAt := row.DoneAt.Time
+		op.DoneAt = &doneAt
+	}
+	if row.EstimatedCompletion.Valid {
+		eta := row.EstimatedCompletion.Time
+		op.EstimatedCompletion = &eta
+	}
+	return op
+}
@@ -0,0 +1,114 @@
+//go:build integration
+
+package operations
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestStoreOperationLifecycle(t *testing.T) {
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("DATABASE_URL is not set")
+	}
+	pool, err := pgxpool.New(context.Background(), dbURL)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	ctx := context.Background()
+	store := NewStore(pool)
+
+	eta := time.Now().Add(time.Minute).Truncate(time.Microsecond)
+	op, err := store.Create(ctx, "project.create", "projects/p-1", eta)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if op.Status != StatusPending || op.Done || op.DoneAt != nil {
+		t.Fatalf("unexpected new operation: %+v", op)
+	}
+	if op.EstimatedCompletion == nil || !op.EstimatedCompletion.Equal(eta) {
+		t.Errorf("EstimatedCompletion = %v, want %v", op.EstimatedCompletion, eta)
+	}
+
+	if _, err := store.Update(ctx, op.ID, StatusRunning, "", ""); err != nil {
+		t.Fatalf("Update(running) error = %v", err)
+	}
+	if _, err := store.Update(ctx, op.ID, StatusDone, "projects/p-1", ""); err != nil {
+		t.Fatalf("Update(done) error = %v", err)
+	}
+
+	got, err := store.GetByID(ctx, op.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if !got.Done || got.Resource != "projects/p-1" || got.DoneAt == nil {
+		t.Errorf("unexpected finished operation: %+v", got)
+	}
+
+	if _, err := store.GetByID(ctx, "2a4e6b16-8a62-4d57-a05b-9f59248dbdb2"); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("expected pgx.ErrNoRows for a missing operation, got %v", err)
+	}
+}
+
+func TestStoreFailAbandoned(t *testing.T) {
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("DATABASE_URL is not set")
+	}
+	pool, err := pgxpool.New(context.Background(), dbURL)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	ctx := context.Background()
+	store := NewStore(pool)
+
+	op, err := store.Create(ctx, "project.create", "projects/p-1", time.Time{})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := store.Touch(ctx, []string{op.ID}); err != nil {
+		t.Fatalf("Touch() error = %v", err)
+	}
+	touched, err := store.GetByID(ctx, op.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+
+	failed, err := store.FailAbandoned(ctx, touched.UpdatedAt, ErrAbandoned.Error())
+	if err != nil {
+		t.Fatalf("FailAbandoned() error = %v", err)
+	}
+	for _, f := range failed {
+		if f.ID == op.ID {
+			t.Fatalf("failed an operation touched at the cutoff: %+v", f)
+		}
+	}
+
+	failed, err = store.FailAbandoned(ctx, touched.UpdatedAt.Add(time.Microsecond), ErrAbandoned.Error())
+	if err != nil {
+		t.Fatalf("FailAbandoned() error = %v", err)
+	}
+	var found bool
+	for _, f := range failed {
+		if f.ID == op.ID {
+			found = true
+			if f.Status != StatusError || !f.Done || f.Resource != "projects/p-1" || f.Error != ErrAbandoned.Error() {
+				t.Errorf("unexpected abandoned operation: %+v", f)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("FailAbandoned() = %v, want it to include %s", failed, op.ID)
+	}
+}
@@ -0,0 +1,42 @@
+// Package operations models long-running work as pollable resources:
+// starting async work returns an operation that clients poll until it is
+// done, then follow to the resource it produced.
+package operations
+
+import "time"
+
+// Status is the state of an operation.
+type Status string
+
+const (
+	// StatusPending operations have been accepted but not started.
+	StatusPending Status = "pending"
+	// StatusRunning operations are in progress.
+	StatusRunning Status = "running"
+	// StatusDone operations finished successfully.
+	StatusDone Status = "done"
+	// StatusError operations finished with an error.
+	StatusError Status = "error"
+)
+
+// Operation is a unit of asynchronous work.
+type Operation struct {
+	ID     string `json:"id"`
+	Kind   string `json:"kind"`
+	Status Status `json:"status"`
+	// Done is true once Status is StatusDone or StatusError.
+	Done bool `json:"done"`
+	// Resource names what the operation works on or produced, relative to
+	// the API root (e.g. "projects/{id}"). It may be set before the
+	// operation is done, and even if it failed.
+	Resource  string    `json:"resource,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	// UpdatedAt keeps advancing while an instance holds the operation,
+	// even if it makes no visible progress.
+	UpdatedAt time.Time  `json:"updated_at"`
+	DoneAt    *time.Time `json:"done_at,omitempty"`
+	// EstimatedCompletion is when the operation is expected to finish, as
+	// estimated when it was accepted. Nil if there was nothing to go by.
+	EstimatedCompletion *time.Time `json:"estimated_completion,omitempty"`
+}
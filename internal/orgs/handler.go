@@ -0,0 +1,166 @@
+package orgs
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/searge/quokka/internal/platform"
+)
+
+type Handler struct {
+	service    *Service
+	log        *slog.Logger
+	adminToken string
+}
+
+// HandlerOption configures optional Handler behaviour.
+type HandlerOption func(*Handler)
+
+// WithAdminToken makes callers presenting token as a bearer token admins,
+// the only ones allowed to set quotas. An empty token makes no one an
+// admin.
+func WithAdminToken(token string) HandlerOption {
+	return func(h *Handler) {
+		h.adminToken = token
+	}
+}
+
+func NewHandler(service *Service, logger *slog.Logger, opts ...HandlerOption) *Handler {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	h := &Handler{service: service, log: logger}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+func (h *Handler) Routes() http.Handler {
+	r := chi.NewRouter()
+
+	r.Post("/", h.Create)
+	r.Get("/", h.List)
+	r.Get("/{id}", h.GetByID)
+	r.With(platform.RequireAdmin(h.adminToken)).Put("/{id}/quota", h.SetQuota)
+	r.Put("/{id}/labels", h.SetDefaultLabels)
+
+	return r
+}
+
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	var req CreateOrganizationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		platform.RespondError(w, http.StatusBadRequest, "INVALID_JSON", "invalid JSON")
+		return
+	}
+
+	org, err := h.service.Create(r.Context(), req)
+	if err != nil {
+		switch {
+		case errors.As(err, &validator.ValidationErrors{}):
+			platform.RespondValidationError(w, err)
+		case errors.Is(err, ErrOrganizationExists):
+			platform.RespondError(w, http.StatusConflict, "ORGANIZATION_EXISTS", err.Error())
+		default:
+			platform.RespondServerError(w, h.log, err)
+		}
+		return
+	}
+
+	platform.RespondJSON(w, http.StatusCreated, org)
+}
+
+// List serves GET /orgs?limit=&offset=: a page of organizations ordered
+// by name.
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	query := platform.QueryParams(r)
+	limit := query.Int32("limit", 0)
+	offset := query.Int32("offset", 0)
+	if err := query.Err(); err != nil {
+		platform.RespondQueryError(w, err)
+		return
+	}
+	if limit < 0 || offset < 0 {
+		platform.RespondError(w, http.StatusBadRequest, "INVALID_PAGINATION", "limit and offset must not be negative")
+		return
+	}
+
+	page, err := h.service.List(r.Context(), limit, offset)
+	if err != nil {
+		platform.RespondServerError(w, h.log, err)
+		return
+	}
+
+	platform.RespondJSON(w, http.StatusOK, page)
+}
+
+func (h *Handler) GetByID(w http.ResponseWriter, r *http.Request) {
+	org, err := h.service.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		h.respondLookupError(w, err)
+		return
+	}
+
+	platform.RespondJSON(w, http.StatusOK, org)
+}
+
+// SetQuota serves PUT /orgs/{id}/quota. The new quota applies to the next
+// project created; projects already active are never deactivated by it.
+func (h *Handler) SetQuota(w http.ResponseWriter, r *http.Request) {
+	var req SetQuotaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		platform.RespondError(w, http.StatusBadRequest, "INVALID_JSON", "invalid JSON")
+		return
+	}
+
+	org, err := h.service.SetQuota(r.Context(), chi.URLParam(r, "id"), req)
+	if err != nil {
+		if errors.As(err, &validator.ValidationErrors{}) {
+			platform.RespondValidationError(w, err)
+			return
+		}
+		h.respondLookupError(w, err)
+		return
+	}
+
+	platform.RespondJSON(w, http.StatusOK, org)
+}
+
+// SetDefaultLabels serves PUT /orgs/{id}/labels. Projects created from
+// then on inherit the new labels; existing ones are updated by POST
+// /projects/labels/sync.
+func (h *Handler) SetDefaultLabels(w http.ResponseWriter, r *http.Request) {
+	var req SetDefaultLabelsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		platform.RespondError(w, http.StatusBadRequest, "INVALID_JSON", "invalid JSON")
+		return
+	}
+
+	org, err := h.service.SetDefaultLabels(r.Context(), chi.URLParam(r, "id"), req)
+	if err != nil {
+		if errors.As(err, &validator.ValidationErrors{}) {
+			platform.RespondValidationError(w, err)
+			return
+		}
+		h.respondLookupError(w, err)
+		return
+	}
+
+	platform.RespondJSON(w, http.StatusOK, org)
+}
+
+func (h *Handler) respondLookupError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrOrganizationNotFound):
+		platform.RespondError(w, http.StatusNotFound, "ORGANIZATION_NOT_FOUND", "organization not found")
+	case errors.Is(err, ErrInvalidOrganizationID):
+		platform.RespondError(w, http.StatusBadRequest, "INVALID_ORGANIZATION_ID", "invalid organization id")
+	default:
+		platform.RespondServerError(w, h.log, err)
+	}
+}
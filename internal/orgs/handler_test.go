@@ -0,0 +1,114 @@
+package orgs
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandlerCreate(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+	}{
+		{name: "valid", body: `{"name":"Acme","unix_name":"acme","max_active_projects":3}`, wantCode: http.StatusCreated},
+		{name: "duplicate unix name", body: `{"name":"Other","unix_name":"taken"}`, wantCode: http.StatusConflict},
+		{name: "invalid unix name", body: `{"name":"Acme","unix_name":"Acme Corp"}`, wantCode: http.StatusUnprocessableEntity},
+		{name: "negative quota", body: `{"name":"Acme","unix_name":"acme","max_active_projects":-1}`, wantCode: http.StatusUnprocessableEntity},
+		{name: "invalid json", body: `{`, wantCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(newService(newMemoryStore(&Organization{ID: "org-taken", UnixName: "taken"}), nil), nil)
+
+			rr := httptest.NewRecorder()
+			h.Routes().ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body)))
+
+			if rr.Code != tt.wantCode {
+				t.Fatalf("expected %d, got %d: %s", tt.wantCode, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandlerSetQuota(t *testing.T) {
+	tests := []struct {
+		name      string
+		id        string
+		body      string
+		wantCode  int
+		wantQuota *int32
+	}{
+		{name: "set", id: "org-1", body: `{"max_active_projects":7}`, wantCode: http.StatusOK, wantQuota: quota(7)},
+		{name: "clear", id: "org-1", body: `{"max_active_projects":null}`, wantCode: http.StatusOK},
+		{name: "unknown organization", id: "org-404", body: `{"max_active_projects":7}`, wantCode: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(newService(newMemoryStore(&Organization{ID: "org-1", MaxActiveProjects: quota(2)}), nil), nil,
+				WithAdminToken("s3cret"))
+
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPut, "/"+tt.id+"/quota", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer s3cret")
+			h.Routes().ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantCode {
+				t.Fatalf("expected %d, got %d: %s", tt.wantCode, rr.Code, rr.Body.String())
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			var got Organization
+			if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+				t.Fatalf("failed to decode response body: %v", err)
+			}
+			if (got.MaxActiveProjects == nil) != (tt.wantQuota == nil) ||
+				(got.MaxActiveProjects != nil && *got.MaxActiveProjects != *tt.wantQuota) {
+				t.Errorf("max_active_projects = %v, want %v", got.MaxActiveProjects, tt.wantQuota)
+			}
+		})
+	}
+}
+
+func TestHandlerSetQuotaRequiresAdmin(t *testing.T) {
+	for name, auth := range map[string]string{"anonymous": "", "wrong token": "Bearer guess"} {
+		t.Run(name, func(t *testing.T) {
+			store := newMemoryStore(&Organization{ID: "org-1", MaxActiveProjects: quota(2)})
+			h := NewHandler(newService(store, nil), nil, WithAdminToken("s3cret"))
+
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPut, "/org-1/quota", strings.NewReader(`{"max_active_projects":100}`))
+			if auth != "" {
+				req.Header.Set("Authorization", auth)
+			}
+			h.Routes().ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusUnauthorized || !strings.Contains(rr.Body.String(), "ADMIN_REQUIRED") {
+				t.Fatalf("expected 401 ADMIN_REQUIRED, got %d: %s", rr.Code, rr.Body.String())
+			}
+			if org, _ := store.GetByID(context.Background(), "org-1"); *org.MaxActiveProjects != 2 {
+				t.Errorf("quota = %d, want it unchanged", *org.MaxActiveProjects)
+			}
+		})
+	}
+}
+
+func TestHandlerListEncodesNoOrganizationsAsEmptyPage(t *testing.T) {
+	h := NewHandler(newService(newMemoryStore(), nil), nil)
+
+	rr := httptest.NewRecorder()
+	h.Routes().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	const want = `{"data":[],"total":0,"limit":100,"offset":0,"has_next":false}`
+	if got := strings.TrimSpace(rr.Body.String()); got != want {
+		t.Errorf("body = %s, want %s", got, want)
+	}
+}
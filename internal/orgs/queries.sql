@@ -0,0 +1,44 @@
+-- name: CountOrganizations :one
+SELECT COUNT(*)
+FROM organizations;
+
+-- name: CreateOrganization :one
+INSERT INTO organizations (
+    id, name, unix_name, max_active_projects, created_at, updated_at, provision_priority, default_labels, synced_labels
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $8
+)
+RETURNING id, name, unix_name, max_active_projects, created_at, updated_at, provision_priority, default_labels, synced_labels;
+
+-- name: GetOrganization :one
+SELECT id, name, unix_name, max_active_projects, created_at, updated_at, provision_priority, default_labels, synced_labels
+FROM organizations
+WHERE id = $1;
+
+-- name: ListOrganizations :many
+SELECT id, name, unix_name, max_active_projects, created_at, updated_at, provision_priority, default_labels, synced_labels
+FROM organizations
+ORDER BY name
+LIMIT $1 OFFSET $2;
+
+-- name: SetOrganizationQuota :one
+UPDATE organizations
+SET
+    max_active_projects = $2,
+    updated_at = $3
+WHERE id = $1
+RETURNING id, name, unix_name, max_active_projects, created_at, updated_at, provision_priority, default_labels, synced_labels;
+
+-- name: SetOrganizationDefaultLabels :one
+UPDATE organizations
+SET
+    default_labels = $2,
+    updated_at = $3
+WHERE id = $1
+RETURNING id, name, unix_name, max_active_projects, created_at, updated_at, provision_priority, default_labels, synced_labels;
+
+-- name: MarkOrganizationLabelsSynced :execrows
+-- Records labels as synced to the organization's projects.
+UPDATE organizations
+SET synced_labels = $2
+WHERE id = $1;
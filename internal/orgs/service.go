@@ -0,0 +1,216 @@
+package orgs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5"
+	"github.com/searge/quokka/internal/platform"
+)
+
+var (
+	ErrOrganizationNotFound  = errors.New("organization not found")
+	ErrOrganizationExists    = errors.New("organization unix name already exists")
+	ErrInvalidOrganizationID = errors.New("invalid organization id format")
+
+	unixNameRegex = regexp.MustCompile(`^[a-z0-9-]+$`)
+	labelKeyRegex = regexp.MustCompile(`^[a-z0-9]([a-z0-9._/-]{0,61}[a-z0-9])?$`)
+)
+
+// MaxListOrganizations caps how many organizations one List call returns.
+const MaxListOrganizations = 100
+
+// Service houses the business logic for organizations.
+type Service struct {
+	store        orgStore
+	log          *slog.Logger
+	validate     *validator.Validate
+	defaultQuota int32
+}
+
+type orgStore interface {
+	Create(ctx context.Context, req CreateOrganizationRequest) (*Organization, error)
+	GetByID(ctx context.Context, id string) (*Organization, error)
+	List(ctx context.Context, limit, offset int32) ([]*Organization, error)
+	Count(ctx context.Context) (int64, error)
+	SetQuota(ctx context.Context, id string, maxActiveProjects *int32) (*Organization, error)
+	SetDefaultLabels(ctx context.Context, id string, labels map[string]string) (*Organization, error)
+	MarkLabelsSynced(ctx context.Context, id string, labels map[string]string) error
+}
+
+// ServiceOption configures optional Service behaviour.
+type ServiceOption func(*Service)
+
+// WithDefaultQuota caps active projects for organizations without a quota
+// of their own. Zero, the default, leaves them unlimited.
+func WithDefaultQuota(maxActiveProjects int32) ServiceOption {
+	return func(s *Service) {
+		s.defaultQuota = maxActiveProjects
+	}
+}
+
+// NewService creates a new Service.
+func NewService(store *Store, logger *slog.Logger, opts ...ServiceOption) *Service {
+	return newService(store, logger, opts...)
+}
+
+func newService(store orgStore, logger *slog.Logger, opts ...ServiceOption) *Service {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	s := &Service{store: store, log: logger, validate: newValidator()}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// newValidator returns a validator with the organization rules registered.
+// Panics if a rule cannot be registered.
+func newValidator() *validator.Validate {
+	validate := validator.New()
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			return field.Name
+		}
+		return name
+	})
+	err := validate.RegisterValidation("unix_name", func(fl validator.FieldLevel) bool {
+		return unixNameRegex.MatchString(fl.Field().String())
+	})
+	if err != nil {
+		panic(fmt.Errorf("failed to register unix_name validator: %w", err))
+	}
+	// Default labels end up on projects, so follow the project label rules
+	err = validate.RegisterValidation("label_key", func(fl validator.FieldLevel) bool {
+		return labelKeyRegex.MatchString(fl.Field().String())
+	})
+	if err != nil {
+		panic(fmt.Errorf("failed to register label_key validator: %w", err))
+	}
+	return validate
+}
+
+// Create validates and stores a new organization.
+func (s *Service) Create(ctx context.Context, req CreateOrganizationRequest) (*Organization, error) {
+	if err := s.validate.Struct(req); err != nil {
+		return nil, err
+	}
+	return s.store.Create(ctx, req)
+}
+
+// Get retrieves an organization by ID.
+func (s *Service) Get(ctx context.Context, id string) (*Organization, error) {
+	org, err := s.store.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrOrganizationNotFound
+		}
+		return nil, err
+	}
+	return org, nil
+}
+
+// List returns a page of organizations ordered by name. A limit of zero
+// or above MaxListOrganizations returns up to MaxListOrganizations.
+func (s *Service) List(ctx context.Context, limit, offset int32) (platform.Page[*Organization], error) {
+	if limit <= 0 || limit > MaxListOrganizations {
+		limit = MaxListOrganizations
+	}
+	offset = max(offset, 0)
+	orgs, err := s.store.List(ctx, limit, offset)
+	if err != nil {
+		return platform.Page[*Organization]{}, err
+	}
+	total, err := s.store.Count(ctx)
+	if err != nil {
+		return platform.Page[*Organization]{}, err
+	}
+	return platform.NewPage(orgs, total, limit, offset), nil
+}
+
+// SetQuota replaces an organization's active project quota.
+func (s *Service) SetQuota(ctx context.Context, id string, req SetQuotaRequest) (*Organization, error) {
+	if err := s.validate.Struct(req); err != nil {
+		return nil, err
+	}
+	org, err := s.store.SetQuota(ctx, id, req.MaxActiveProjects)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrOrganizationNotFound
+		}
+		return nil, err
+	}
+	return org, nil
+}
+
+// SetDefaultLabels replaces an organization's default labels. They apply
+// to projects created from then on; existing projects pick them up when
+// the organization's labels are next synced.
+func (s *Service) SetDefaultLabels(ctx context.Context, id string, req SetDefaultLabelsRequest) (*Organization, error) {
+	if err := s.validate.Struct(req); err != nil {
+		return nil, err
+	}
+	org, err := s.store.SetDefaultLabels(ctx, id, req.Labels)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrOrganizationNotFound
+		}
+		return nil, err
+	}
+	return org, nil
+}
+
+// DefaultLabels returns the labels the organization's projects inherit and
+// the ones last synced to its existing projects.
+func (s *Service) DefaultLabels(ctx context.Context, orgID string) (labels, synced map[string]string, err error) {
+	org, err := s.Get(ctx, orgID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return org.DefaultLabels, org.SyncedLabels, nil
+}
+
+// MarkLabelsSynced records labels as synced to the organization's projects.
+func (s *Service) MarkLabelsSynced(ctx context.Context, orgID string, labels map[string]string) error {
+	err := s.store.MarkLabelsSynced(ctx, orgID, labels)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrOrganizationNotFound
+	}
+	return err
+}
+
+// ProjectQuota reports how many active projects the organization may have.
+// limited is false when no quota applies. An organization's own quota,
+// including zero, takes precedence over the default.
+func (s *Service) ProjectQuota(ctx context.Context, orgID string) (limit int32, limited bool, err error) {
+	org, err := s.Get(ctx, orgID)
+	if err != nil {
+		return 0, false, err
+	}
+	if org.MaxActiveProjects != nil {
+		return *org.MaxActiveProjects, true, nil
+	}
+	if s.defaultQuota > 0 {
+		return s.defaultQuota, true, nil
+	}
+	return 0, false, nil
+}
+
+// ProvisionPriority returns the queue priority name for the organization's
+// async creates, empty when it has no policy.
+func (s *Service) ProvisionPriority(ctx context.Context, orgID string) (string, error) {
+	org, err := s.Get(ctx, orgID)
+	if err != nil {
+		return "", err
+	}
+	return org.ProvisionPriority, nil
+}
@@ -0,0 +1,208 @@
+package orgs
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"sync"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// memoryStore is an in-memory orgStore keyed by organization ID.
+type memoryStore struct {
+	mu   sync.Mutex
+	orgs map[string]*Organization
+}
+
+func newMemoryStore(orgs ...*Organization) *memoryStore {
+	m := &memoryStore{orgs: map[string]*Organization{}}
+	for _, org := range orgs {
+		m.orgs[org.ID] = org
+	}
+	return m
+}
+
+func (m *memoryStore) Create(_ context.Context, req CreateOrganizationRequest) (*Organization, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, org := range m.orgs {
+		if org.UnixName == req.UnixName {
+			return nil, ErrOrganizationExists
+		}
+	}
+	org := &Organization{
+		ID:                "org-" + req.UnixName,
+		Name:              req.Name,
+		UnixName:          req.UnixName,
+		MaxActiveProjects: req.MaxActiveProjects,
+		DefaultLabels:     req.DefaultLabels,
+		SyncedLabels:      req.DefaultLabels,
+	}
+	m.orgs[org.ID] = org
+	copied := *org
+	return &copied, nil
+}
+
+func (m *memoryStore) GetByID(_ context.Context, id string) (*Organization, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	org, ok := m.orgs[id]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	copied := *org
+	return &copied, nil
+}
+
+func (m *memoryStore) List(_ context.Context, _, _ int32) ([]*Organization, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var orgs []*Organization
+	for _, org := range m.orgs {
+		copied := *org
+		orgs = append(orgs, &copied)
+	}
+	return orgs, nil
+}
+
+func (m *memoryStore) Count(context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return int64(len(m.orgs)), nil
+}
+
+func (m *memoryStore) SetQuota(_ context.Context, id string, maxActiveProjects *int32) (*Organization, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	org, ok := m.orgs[id]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	org.MaxActiveProjects = maxActiveProjects
+	copied := *org
+	return &copied, nil
+}
+
+func (m *memoryStore) SetDefaultLabels(_ context.Context, id string, labels map[string]string) (*Organization, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	org, ok := m.orgs[id]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	org.DefaultLabels = labels
+	copied := *org
+	return &copied, nil
+}
+
+func (m *memoryStore) MarkLabelsSynced(_ context.Context, id string, labels map[string]string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	org, ok := m.orgs[id]
+	if !ok {
+		return pgx.ErrNoRows
+	}
+	org.SyncedLabels = labels
+	return nil
+}
+
+func quota(n int32) *int32 { return &n }
+
+func TestServiceProjectQuota(t *testing.T) {
+	store := newMemoryStore(
+		&Organization{ID: "org-own", MaxActiveProjects: quota(5)},
+		&Organization{ID: "org-zero", MaxActiveProjects: quota(0)},
+		&Organization{ID: "org-default"},
+	)
+
+	tests := []struct {
+		name         string
+		defaultQuota int32
+		orgID        string
+		wantLimit    int32
+		wantLimited  bool
+	}{
+		{name: "own quota", orgID: "org-own", wantLimit: 5, wantLimited: true},
+		{name: "own quota beats default", defaultQuota: 10, orgID: "org-own", wantLimit: 5, wantLimited: true},
+		{name: "own zero quota", defaultQuota: 10, orgID: "org-zero", wantLimit: 0, wantLimited: true},
+		{name: "default quota", defaultQuota: 10, orgID: "org-default", wantLimit: 10, wantLimited: true},
+		{name: "unlimited", orgID: "org-default", wantLimited: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newService(store, nil, WithDefaultQuota(tt.defaultQuota))
+
+			limit, limited, err := s.ProjectQuota(context.Background(), tt.orgID)
+			if err != nil {
+				t.Fatalf("ProjectQuota() error = %v", err)
+			}
+			if limit != tt.wantLimit || limited != tt.wantLimited {
+				t.Errorf("ProjectQuota() = (%d, %v), want (%d, %v)", limit, limited, tt.wantLimit, tt.wantLimited)
+			}
+		})
+	}
+}
+
+func TestServiceProjectQuotaUnknownOrganization(t *testing.T) {
+	s := newService(newMemoryStore(), nil)
+
+	if _, _, err := s.ProjectQuota(context.Background(), "org-404"); !errors.Is(err, ErrOrganizationNotFound) {
+		t.Fatalf("expected ErrOrganizationNotFound, got %v", err)
+	}
+}
+
+func TestServiceSetQuotaRejectsNegative(t *testing.T) {
+	s := newService(newMemoryStore(&Organization{ID: "org-1"}), nil)
+
+	if _, err := s.SetQuota(context.Background(), "org-1", SetQuotaRequest{MaxActiveProjects: quota(-1)}); err == nil {
+		t.Fatal("expected a validation error for a negative quota")
+	}
+}
+
+func TestServiceDefaultLabels(t *testing.T) {
+	s := newService(newMemoryStore(&Organization{ID: "org-1", SyncedLabels: map[string]string{"env": "dev"}}), nil)
+
+	if _, err := s.SetDefaultLabels(context.Background(), "org-1", SetDefaultLabelsRequest{
+		Labels: map[string]string{"env": "prod", "cost-center": "42"},
+	}); err != nil {
+		t.Fatalf("SetDefaultLabels() error = %v", err)
+	}
+	labels, synced, err := s.DefaultLabels(context.Background(), "org-1")
+	if err != nil {
+		t.Fatalf("DefaultLabels() error = %v", err)
+	}
+	if !maps.Equal(labels, map[string]string{"env": "prod", "cost-center": "42"}) {
+		t.Errorf("labels = %v, want the new defaults", labels)
+	}
+	if !maps.Equal(synced, map[string]string{"env": "dev"}) {
+		t.Errorf("synced = %v, want the defaults last synced", synced)
+	}
+
+	if err := s.MarkLabelsSynced(context.Background(), "org-1", labels); err != nil {
+		t.Fatalf("MarkLabelsSynced() error = %v", err)
+	}
+	if _, synced, _ := s.DefaultLabels(context.Background(), "org-1"); !maps.Equal(synced, labels) {
+		t.Errorf("synced after marking = %v, want %v", synced, labels)
+	}
+}
+
+func TestServiceSetDefaultLabelsRejectsInvalidKeys(t *testing.T) {
+	s := newService(newMemoryStore(&Organization{ID: "org-1"}), nil)
+
+	_, err := s.SetDefaultLabels(context.Background(), "org-1", SetDefaultLabelsRequest{
+		Labels: map[string]string{"Cost Center": "42"},
+	})
+	if err == nil {
+		t.Fatal("expected a validation error for an invalid label key")
+	}
+}
+
+func TestServiceMarkLabelsSyncedUnknownOrganization(t *testing.T) {
+	s := newService(newMemoryStore(), nil)
+
+	if err := s.MarkLabelsSynced(context.Background(), "org-404", nil); !errors.Is(err, ErrOrganizationNotFound) {
+		t.Fatalf("expected ErrOrganizationNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,278 @@
+package orgs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/searge/quokka/internal/orgs/db"
+	"github.com/searge/quokka/internal/platform"
+)
+
+// Store provides data access for organizations via sqlc.
+type Store struct {
+	queries      *db.Queries
+	queryTimeout time.Duration
+}
+
+// StoreOption configures optional Store behaviour.
+type StoreOption func(*Store)
+
+// WithQueryTimeout bounds every store query by d. A zero duration leaves
+// queries bounded only by the caller's context.
+func WithQueryTimeout(d time.Duration) StoreOption {
+	return func(s *Store) {
+		s.queryTimeout = d
+	}
+}
+
+// NewStore initializes a new Store instance.
+func NewStore(pool *pgxpool.Pool, opts ...StoreOption) *Store {
+	s := &Store{queries: db.New(platform.NewPoolDB(pool))}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *Store) queryContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.queryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, s.queryTimeout)
+}
+
+// Create inserts a new organization.
+func (s *Store) Create(ctx context.Context, req CreateOrganizationRequest) (*Organization, error) {
+	now := pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	labels, err := encodeLabels(req.DefaultLabels)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	row, err := s.queries.CreateOrganization(ctx, db.CreateOrganizationParams{
+		ID:                pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		Name:              req.Name,
+		UnixName:          req.UnixName,
+		MaxActiveProjects: toPgInt4(req.MaxActiveProjects),
+		ProvisionPriority: pgtype.Text{String: req.ProvisionPriority, Valid: req.ProvisionPriority != ""},
+		CreatedAt:         now,
+		UpdatedAt:         now,
+		DefaultLabels:     labels,
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrOrganizationExists
+		}
+		return nil, err
+	}
+	return mapToDomainOrganization(row)
+}
+
+// GetByID retrieves an organization by its ID. Returns pgx.ErrNoRows if it
+// does not exist.
+func (s *Store) GetByID(ctx context.Context, id string) (*Organization, error) {
+	uid, err := parseOrganizationID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	row, err := s.queries.GetOrganization(ctx, pgtype.UUID{Bytes: uid, Valid: true})
+	if err != nil {
+		return nil, err
+	}
+	return mapToDomainOrganization(row)
+}
+
+// List returns organizations ordered by name.
+func (s *Store) List(ctx context.Context, limit, offset int32) ([]*Organization, error) {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	rows, err := s.queries.ListOrganizations(ctx, db.ListOrganizationsParams{Limit: limit, Offset: offset})
+	if err != nil {
+		return nil, err
+	}
+
+	orgs := make([]*Organization, 0, len(rows))
+	for _, row := range rows {
+		org, err := mapToDomainOrganization(row)
+		if err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, org)
+	}
+	return orgs, nil
+}
+
+// Count returns how many organizations List pages through.
+func (s *Store) Count(ctx context.Context) (int64, error) {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	return s.queries.CountOrganizations(ctx)
+}
+
+// SetQuota replaces an organization's active project quota; nil clears it.
+// Returns pgx.ErrNoRows if the organization does not exist.
+func (s *Store) SetQuota(ctx context.Context, id string, maxActiveProjects *int32) (*Organization, error) {
+	uid, err := parseOrganizationID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	row, err := s.queries.SetOrganizationQuota(ctx, db.SetOrganizationQuotaParams{
+		ID:                pgtype.UUID{Bytes: uid, Valid: true},
+		MaxActiveProjects: toPgInt4(maxActiveProjects),
+		UpdatedAt:         pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mapToDomainOrganization(row)
+}
+
+// SetDefaultLabels replaces an organization's default labels. Returns
+// pgx.ErrNoRows if the organization does not exist.
+func (s *Store) SetDefaultLabels(ctx context.Context, id string, labels map[string]string) (*Organization, error) {
+	uid, err := parseOrganizationID(id)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := encodeLabels(labels)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	row, err := s.queries.SetOrganizationDefaultLabels(ctx, db.SetOrganizationDefaultLabelsParams{
+		ID:            pgtype.UUID{Bytes: uid, Valid: true},
+		DefaultLabels: raw,
+		UpdatedAt:     pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mapToDomainOrganization(row)
+}
+
+// MarkLabelsSynced records labels as the default labels last synced to the
+// organization's projects. Returns pgx.ErrNoRows if the organization does
+// not exist.
+func (s *Store) MarkLabelsSynced(ctx context.Context, id string, labels map[string]string) error {
+	uid, err := parseOrganizationID(id)
+	if err != nil {
+		return err
+	}
+	raw, err := encodeLabels(labels)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	n, err := s.queries.MarkOrganizationLabelsSynced(ctx, db.MarkOrganizationLabelsSyncedParams{
+		ID:           pgtype.UUID{Bytes: uid, Valid: true},
+		SyncedLabels: raw,
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// parseOrganizationID parses id, rejecting malformed values and the nil
+// UUID. Pure function.
+func parseOrganizationID(id string) (uuid.UUID, error) {
+	uid, err := uuid.Parse(id)
+	if err != nil || uid == uuid.Nil {
+		return uuid.Nil, ErrInvalidOrganizationID
+	}
+	return uid, nil
+}
+
+// toPgInt4 maps an optional quota to a nullable column. Pure function.
+func toPgInt4(v *int32) pgtype.Int4 {
+	if v == nil {
+		return pgtype.Int4{}
+	}
+	return pgtype.Int4{Int32: *v, Valid: true}
+}
+
+func mapToDomainOrganization(row db.Organization) (*Organization, error) {
+	defaults, err := decodeLabels(row.DefaultLabels)
+	if err != nil {
+		return nil, err
+	}
+	synced, err := decodeLabels(row.SyncedLabels)
+	if err != nil {
+		return nil, err
+	}
+	org := &Organization{
+		ID:                uuid.UUID(row.ID.Bytes).String(),
+		Name:              row.Name,
+		UnixName:          row.UnixName,
+		ProvisionPriority: row.ProvisionPriority.String,
+		DefaultLabels:     defaults,
+		SyncedLabels:      synced,
+		CreatedAt:         row.CreatedAt.Time,
+		UpdatedAt:         row.UpdatedAt.Time,
+	}
+	if row.MaxActiveProjects.Valid {
+		limit := row.MaxActiveProjects.Int32
+		org.MaxActiveProjects = &limit
+	}
+	return org, nil
+}
+
+// encodeLabels serializes labels as a JSON object; nil becomes {}. Pure
+// function.
+func encodeLabels(labels map[string]string) ([]byte, error) {
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	raw, err := json.Marshal(labels)
+	if err != nil {
+		return nil, fmt.Errorf("encode labels: %w", err)
+	}
+	return raw, nil
+}
+
+// decodeLabels is the inverse of encodeLabels, returning nil for an empty
+// object so organizations without default labels omit them. Pure function.
+func decodeLabels(raw []byte) (map[string]string, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var labels map[string]string
+	if err := json.Unmarshal(raw, &labels); err != nil {
+		return nil, fmt.Errorf("decode labels: %w", err)
+	}
+	if len(labels) == 0 {
+		return nil, nil
+	}
+	return labels, nil
+}
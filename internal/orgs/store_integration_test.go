@@ -0,0 +1,112 @@
+//go:build integration
+
+package orgs
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestStoreQuotaLifecycle(t *testing.T) {
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("DATABASE_URL is not set")
+	}
+	pool, err := pgxpool.New(context.Background(), dbURL)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	ctx := context.Background()
+	store := NewStore(pool)
+	unixName := "org-" + uuid.NewString()[:8]
+
+	org, err := store.Create(ctx, CreateOrganizationRequest{Name: "Acme", UnixName: unixName})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := pool.Exec(context.Background(), "DELETE FROM organizations WHERE id = $1", org.ID); err != nil {
+			t.Errorf("cleanup failed: %v", err)
+		}
+	})
+	if org.MaxActiveProjects != nil {
+		t.Fatalf("expected no quota on a new organization, got %d", *org.MaxActiveProjects)
+	}
+
+	if _, err := store.Create(ctx, CreateOrganizationRequest{Name: "Acme", UnixName: unixName}); !errors.Is(err, ErrOrganizationExists) {
+		t.Fatalf("expected ErrOrganizationExists, got %v", err)
+	}
+
+	limit := int32(3)
+	if _, err := store.SetQuota(ctx, org.ID, &limit); err != nil {
+		t.Fatalf("SetQuota() error = %v", err)
+	}
+	got, err := store.GetByID(ctx, org.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if got.MaxActiveProjects == nil || *got.MaxActiveProjects != limit {
+		t.Errorf("max_active_projects = %v, want %d", got.MaxActiveProjects, limit)
+	}
+}
+
+func TestStoreDefaultLabelsLifecycle(t *testing.T) {
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("DATABASE_URL is not set")
+	}
+	pool, err := pgxpool.New(context.Background(), dbURL)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	ctx := context.Background()
+	store := NewStore(pool)
+	initial := map[string]string{"env": "dev"}
+
+	org, err := store.Create(ctx, CreateOrganizationRequest{
+		Name:          "Acme",
+		UnixName:      "org-" + uuid.NewString()[:8],
+		DefaultLabels: initial,
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := pool.Exec(context.Background(), "DELETE FROM organizations WHERE id = $1", org.ID); err != nil {
+			t.Errorf("cleanup failed: %v", err)
+		}
+	})
+	if !maps.Equal(org.DefaultLabels, initial) || !maps.Equal(org.SyncedLabels, initial) {
+		t.Fatalf("a new organization's labels = %v, synced %v, want both %v", org.DefaultLabels, org.SyncedLabels, initial)
+	}
+
+	updated := map[string]string{"env": "prod", "cost-center": "42"}
+	if _, err := store.SetDefaultLabels(ctx, org.ID, updated); err != nil {
+		t.Fatalf("SetDefaultLabels() error = %v", err)
+	}
+	if err := store.MarkLabelsSynced(ctx, org.ID, updated); err != nil {
+		t.Fatalf("MarkLabelsSynced() error = %v", err)
+	}
+	got, err := store.GetByID(ctx, org.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if !maps.Equal(got.DefaultLabels, updated) || !maps.Equal(got.SyncedLabels, updated) {
+		t.Errorf("labels = %v, synced %v, want both %v", got.DefaultLabels, got.SyncedLabels, updated)
+	}
+
+	if err := store.MarkLabelsSynced(ctx, uuid.NewString(), updated); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("expected pgx.ErrNoRows for an unknown organization, got %v", err)
+	}
+}
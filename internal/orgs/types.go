@@ -0,0 +1,47 @@
+// Package orgs manages organizations, the owners of projects, and the
+// limits that apply to everything they own.
+package orgs
+
+import "time"
+
+// Organization groups projects under one owner.
+type Organization struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	UnixName string `json:"unix_name"`
+	// MaxActiveProjects caps the organization's active projects. Nil falls
+	// back to the service's default quota.
+	MaxActiveProjects *int32 `json:"max_active_projects,omitempty"`
+	// ProvisionPriority is the queue priority of the organization's async
+	// creates that do not ask for one. Empty means normal.
+	ProvisionPriority string `json:"provision_priority,omitempty"`
+	// DefaultLabels are merged into the labels of every project created in
+	// the organization; a project's own labels win on conflict.
+	DefaultLabels map[string]string `json:"default_labels,omitempty"`
+	// SyncedLabels are the default labels as they were last synced to the
+	// organization's existing projects.
+	SyncedLabels map[string]string `json:"-"`
+	CreatedAt    time.Time         `json:"created_at"`
+	UpdatedAt    time.Time         `json:"updated_at"`
+}
+
+// CreateOrganizationRequest is the payload for creating an organization.
+type CreateOrganizationRequest struct {
+	Name              string            `json:"name" validate:"required,max=255"`
+	UnixName          string            `json:"unix_name" validate:"required,min=3,max=100,unix_name"`
+	MaxActiveProjects *int32            `json:"max_active_projects,omitempty" validate:"omitempty,min=0"`
+	ProvisionPriority string            `json:"provision_priority,omitempty" validate:"omitempty,oneof=low normal high urgent"`
+	DefaultLabels     map[string]string `json:"default_labels,omitempty" validate:"omitempty,dive,keys,label_key,endkeys,max=63"`
+}
+
+// SetQuotaRequest replaces an organization's quota. A null
+// max_active_projects reverts it to the default.
+type SetQuotaRequest struct {
+	MaxActiveProjects *int32 `json:"max_active_projects" validate:"omitempty,min=0"`
+}
+
+// SetDefaultLabelsRequest replaces an organization's default labels. An
+// empty labels object clears them.
+type SetDefaultLabelsRequest struct {
+	Labels map[string]string `json:"labels" validate:"omitempty,dive,keys,label_key,endkeys,max=63"`
+}
@@ -0,0 +1,48 @@
+package platform
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// Actor is who a request acts for. The API does no authentication of its
+// own: the admin token is the only credential checked here.
+type Actor struct {
+	// OrgID is the organization the caller says it acts for, from
+	// X-Org-ID. Nothing vouches for it, so it must never grant a
+	// permission by itself.
+	OrgID string
+	// Admin callers presented the admin token and may act for any
+	// organization.
+	Admin bool
+}
+
+// RequestActor identifies the caller of r. It is an admin if it sends
+// "Authorization: Bearer <adminToken>"; an empty adminToken makes no one
+// an admin.
+func RequestActor(r *http.Request, adminToken string) Actor {
+	actor := Actor{OrgID: strings.TrimSpace(r.Header.Get("X-Org-ID"))}
+	if adminToken == "" {
+		return actor
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	actor.Admin = ok && subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) == 1
+	return actor
+}
+
+// RequireAdmin answers 401 ADMIN_REQUIRED to callers that are not admins,
+// as RequestActor tells them apart. An empty adminToken locks everyone
+// out.
+func RequireAdmin(adminToken string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !RequestActor(r, adminToken).Admin {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				RespondError(w, http.StatusUnauthorized, "ADMIN_REQUIRED", "this endpoint requires the admin token")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
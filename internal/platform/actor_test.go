@@ -0,0 +1,66 @@
+package platform
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestActor(t *testing.T) {
+	const orgID = "0d1f6a2e-7c3b-4e58-9a40-2b6c8e1f3d57"
+	tests := []struct {
+		name       string
+		adminToken string
+		headers    map[string]string
+		want       Actor
+	}{
+		{name: "anonymous", adminToken: "s3cret", want: Actor{}},
+		{name: "org member", adminToken: "s3cret", headers: map[string]string{"X-Org-ID": orgID}, want: Actor{OrgID: orgID}},
+		{name: "admin", adminToken: "s3cret", headers: map[string]string{"Authorization": "Bearer s3cret"}, want: Actor{Admin: true}},
+		{name: "wrong token", adminToken: "s3cret", headers: map[string]string{"Authorization": "Bearer guess"}, want: Actor{}},
+		{name: "not a bearer token", adminToken: "s3cret", headers: map[string]string{"Authorization": "s3cret"}, want: Actor{}},
+		{name: "no admin token configured", headers: map[string]string{"Authorization": "Bearer "}, want: Actor{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			if got := RequestActor(r, tt.adminToken); got != tt.want {
+				t.Errorf("RequestActor() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequireAdmin(t *testing.T) {
+	handler := RequireAdmin("s3cret")(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	tests := []struct {
+		name          string
+		authorization string
+		want          int
+	}{
+		{name: "admin", authorization: "Bearer s3cret", want: http.StatusNoContent},
+		{name: "wrong token", authorization: "Bearer guess", want: http.StatusUnauthorized},
+		{name: "anonymous", want: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, r)
+			if rr.Code != tt.want {
+				t.Errorf("status = %d, want %d", rr.Code, tt.want)
+			}
+			if tt.want == http.StatusUnauthorized && rr.Header().Get("WWW-Authenticate") != "Bearer" {
+				t.Errorf("WWW-Authenticate = %q, want Bearer", rr.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
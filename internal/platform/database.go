@@ -4,27 +4,28 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"os"
+	"log/slog"
+	"sync"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/searge/quokka/internal/config"
 )
 
-// NewDatabasePool initializes a new PostgreSQL connection pool
-func NewDatabasePool(ctx context.Context) (*pgxpool.Pool, error) {
-	dbURL := os.Getenv("DATABASE_URL")
-	if dbURL == "" {
-		return nil, errors.New("DATABASE_URL environment variable is required")
-	}
+// ErrPoolExhausted is returned when no database connection could be
+// acquired in time, typically because every pooled connection is busy.
+var ErrPoolExhausted = errors.New("database connection pool exhausted")
 
-	config, err := pgxpool.ParseConfig(dbURL)
+// NewDatabasePool initializes a new PostgreSQL connection pool. Slow
+// queries are logged to logger when cfg sets a threshold.
+func NewDatabasePool(ctx context.Context, cfg config.DatabaseConfig, logger *slog.Logger) (*pgxpool.Pool, error) {
+	poolConfig, err := newPoolConfig(cfg, logger)
 	if err != nil {
-		return nil, fmt.Errorf("unable to parse database url: %w", err)
+		return nil, err
 	}
 
-	config.MaxConns = 10
-	config.MinConns = 2
-
-	pool, err := pgxpool.NewWithConfig(ctx, config)
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
 		return nil, fmt.Errorf("unable to create connection pool: %w", err)
 	}
@@ -35,3 +36,126 @@ func NewDatabasePool(ctx context.Context) (*pgxpool.Pool, error) {
 
 	return pool, nil
 }
+
+// newPoolConfig builds the pgxpool configuration for cfg.
+func newPoolConfig(cfg config.DatabaseConfig, logger *slog.Logger) (*pgxpool.Config, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("DATABASE_URL environment variable is required")
+	}
+
+	poolConfig, err := pgxpool.ParseConfig(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse database url: %w", err)
+	}
+
+	poolConfig.MaxConns = cfg.MaxConns
+	poolConfig.MinConns = cfg.MinConns
+	if cfg.HealthCheckPeriod > 0 {
+		poolConfig.HealthCheckPeriod = cfg.HealthCheckPeriod
+	}
+	if cfg.SlowQueryThreshold > 0 {
+		poolConfig.ConnConfig.Tracer = NewSlowQueryTracer(cfg.SlowQueryThreshold, logger)
+	}
+	return poolConfig, nil
+}
+
+// ClassifyAcquireError tags an error from acquiring a pooled connection.
+// Running out of time while waiting for a connection is overload, not a
+// bug, and yields ErrPoolExhausted; a cancelled caller or any other error is
+// returned unchanged. Only errors from acquisition may be passed: a query
+// that times out after acquiring reports the same deadline.
+// Pure function.
+func ClassifyAcquireError(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %w", ErrPoolExhausted, err)
+	}
+	return err
+}
+
+// PoolDB runs queries on connections acquired from a pool, like the pool
+// itself, but reports acquisition failures through ClassifyAcquireError so
+// overload can be told apart from failing queries. It satisfies the DBTX
+// interface of the sqlc packages.
+type PoolDB struct {
+	pool *pgxpool.Pool
+}
+
+// NewPoolDB wraps pool.
+func NewPoolDB(pool *pgxpool.Pool) *PoolDB {
+	return &PoolDB{pool: pool}
+}
+
+func (d *PoolDB) acquire(ctx context.Context) (*pgxpool.Conn, error) {
+	conn, err := d.pool.Acquire(ctx)
+	if err != nil {
+		return nil, ClassifyAcquireError(err)
+	}
+	return conn, nil
+}
+
+// Exec acquires a connection, runs sql on it and releases it.
+func (d *PoolDB) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	conn, err := d.acquire(ctx)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	defer conn.Release()
+	return conn.Exec(ctx, sql, args...)
+}
+
+// Query acquires a connection and runs sql on it. The connection is
+// released when the rows are closed.
+func (d *PoolDB) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	conn, err := d.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := conn.Query(ctx, sql, args...)
+	if err != nil {
+		conn.Release()
+		return nil, err
+	}
+	return &releasingRows{Rows: rows, release: conn.Release}, nil
+}
+
+// QueryRow acquires a connection and runs sql on it. The connection is
+// released when the row is scanned.
+func (d *PoolDB) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	conn, err := d.acquire(ctx)
+	if err != nil {
+		return errRow{err: err}
+	}
+	return releasingRow{row: conn.QueryRow(ctx, sql, args...), release: conn.Release}
+}
+
+// releasingRows releases its connection once closed.
+type releasingRows struct {
+	pgx.Rows
+	once    sync.Once
+	release func()
+}
+
+func (r *releasingRows) Close() {
+	r.Rows.Close()
+	r.once.Do(r.release)
+}
+
+// releasingRow releases its connection once scanned.
+type releasingRow struct {
+	row     pgx.Row
+	release func()
+}
+
+func (r releasingRow) Scan(dest ...any) error {
+	defer r.release()
+	return r.row.Scan(dest...)
+}
+
+// errRow is a row that failed before its query ran.
+type errRow struct {
+	err error
+}
+
+func (r errRow) Scan(...any) error {
+	return r.err
+}
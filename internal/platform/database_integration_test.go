@@ -0,0 +1,94 @@
+//go:build integration
+
+package platform
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/searge/quokka/internal/config"
+)
+
+func TestPoolDBReportsExhaustion(t *testing.T) {
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("DATABASE_URL is not set")
+	}
+	cfg, err := pgxpool.ParseConfig(dbURL)
+	if err != nil {
+		t.Fatalf("failed to parse DATABASE_URL: %v", err)
+	}
+	cfg.MaxConns = 1
+	pool, err := pgxpool.NewWithConfig(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	db := NewPoolDB(pool)
+
+	held, err := pool.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("failed to acquire the only connection: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := db.Exec(ctx, "SELECT 1"); !errors.Is(err, ErrPoolExhausted) {
+		t.Fatalf("expected ErrPoolExhausted while the pool is drained, got %v", err)
+	}
+	held.Release()
+
+	var one int
+	if err := db.QueryRow(context.Background(), "SELECT 1").Scan(&one); err != nil {
+		t.Fatalf("QueryRow() error = %v", err)
+	}
+	if got := pool.Stat().AcquiredConns(); got != 0 {
+		t.Errorf("AcquiredConns() = %d after Scan, want the connection released", got)
+	}
+}
+
+func TestNewDatabasePoolLogsSlowQueries(t *testing.T) {
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("DATABASE_URL is not set")
+	}
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	pool, err := NewDatabasePool(context.Background(), config.DatabaseConfig{
+		URL:                dbURL,
+		MaxConns:           2,
+		SlowQueryThreshold: 50 * time.Millisecond,
+	}, logger)
+	if err != nil {
+		t.Fatalf("NewDatabasePool() error = %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	if _, err := pool.Exec(context.Background(), "-- name: SleepBriefly :exec\nSELECT pg_sleep(0.2)"); err != nil {
+		t.Fatalf("slow query failed: %v", err)
+	}
+
+	var found bool
+	for line := range strings.Lines(buf.String()) {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("failed to decode log record: %v", err)
+		}
+		if record["msg"] == "slow query" && record["operation"] == "SleepBriefly" {
+			found = true
+			if ms, _ := record["duration_ms"].(float64); ms < 200 {
+				t.Errorf("duration_ms = %v, want at least 200", record["duration_ms"])
+			}
+		}
+	}
+	if !found {
+		t.Errorf("no slow query entry for the sleeping query in:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,159 @@
+package platform
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/searge/quokka/internal/config"
+)
+
+// encryptedPrefix marks a value sealed by a Keyring, in the format
+// enc:v1:<key id>:<wrapped data key>:<ciphertext>.
+const encryptedPrefix = "enc:v1:"
+
+var (
+	ErrUnknownEncryptionKey = errors.New("value was encrypted with an unknown key")
+	ErrMalformedCiphertext  = errors.New("malformed encrypted value")
+)
+
+// Keyring encrypts values with envelope encryption: each value is sealed
+// with AES-GCM under a fresh random data key, itself sealed under the
+// active key encryption key and stored alongside it. Sealed values name
+// the key that wrapped their data key, so keys can be rotated by making a
+// new one active while keeping the old ones to open what they sealed.
+type Keyring struct {
+	active string
+	keys   map[string]cipher.AEAD
+}
+
+// ParseKeyring builds a Keyring from "<id>:<base64 key>" specs, as
+// checked by config.ParseEncryptionKeys. The first spec is the active key,
+// used to encrypt; the others only decrypt.
+func ParseKeyring(specs []string) (*Keyring, error) {
+	if len(specs) == 0 {
+		return nil, errors.New("no encryption key given")
+	}
+	keys, err := config.ParseEncryptionKeys(specs)
+	if err != nil {
+		return nil, fmt.Errorf("encryption keys: %w", err)
+	}
+	k := &Keyring{active: keys[0].ID, keys: make(map[string]cipher.AEAD, len(keys))}
+	for _, key := range keys {
+		aead, err := newGCM(key.Key)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %q: %w", key.ID, err)
+		}
+		k.keys[key.ID] = aead
+	}
+	return k, nil
+}
+
+// ActiveKeyID is the id of the key new values are encrypted with.
+func (k *Keyring) ActiveKeyID() string {
+	return k.active
+}
+
+// Encrypt seals plaintext under a fresh data key wrapped by the active key.
+func (k *Keyring) Encrypt(plaintext []byte) (string, error) {
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", fmt.Errorf("generate data key: %w", err)
+	}
+	data, err := newGCM(dataKey)
+	if err != nil {
+		return "", err
+	}
+	// Both layers are bound to the key id, so it cannot be swapped
+	aad := []byte(k.active)
+	wrapped, err := seal(k.keys[k.active], dataKey, aad)
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := seal(data, plaintext, aad)
+	if err != nil {
+		return "", err
+	}
+	return encryptedPrefix + k.active + ":" +
+		base64.RawURLEncoding.EncodeToString(wrapped) + ":" +
+		base64.RawURLEncoding.EncodeToString(ciphertext), nil
+}
+
+// Decrypt opens a value sealed by Encrypt under any key of the ring. It
+// returns ErrUnknownEncryptionKey if the key that sealed it is not in the
+// ring, and ErrMalformedCiphertext if it is not a sealed value or fails
+// authentication.
+func (k *Keyring) Decrypt(value string) ([]byte, error) {
+	rest, ok := strings.CutPrefix(value, encryptedPrefix)
+	if !ok {
+		return nil, ErrMalformedCiphertext
+	}
+	parts := strings.Split(rest, ":")
+	if len(parts) != 3 {
+		return nil, ErrMalformedCiphertext
+	}
+	id := parts[0]
+	kek, ok := k.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownEncryptionKey, id)
+	}
+	wrapped, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrMalformedCiphertext
+	}
+	ciphertext, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformedCiphertext
+	}
+
+	aad := []byte(id)
+	dataKey, err := open(kek, wrapped, aad)
+	if err != nil {
+		return nil, err
+	}
+	data, err := newGCM(dataKey)
+	if err != nil {
+		return nil, ErrMalformedCiphertext
+	}
+	return open(data, ciphertext, aad)
+}
+
+// IsEncrypted reports whether value looks like it was sealed by a Keyring.
+// Pure function.
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts plaintext under a random nonce, which it prepends.
+func seal(aead cipher.AEAD, plaintext, aad []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generate nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+// open is the inverse of seal.
+func open(aead cipher.AEAD, sealed, aad []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrMalformedCiphertext
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, aad)
+	if err != nil {
+		return nil, ErrMalformedCiphertext
+	}
+	return plaintext, nil
+}
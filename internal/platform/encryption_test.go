@@ -0,0 +1,133 @@
+package platform
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// testKeySpec returns a spec for a 32-byte key filled with b.
+func testKeySpec(id string, b byte) string {
+	return id + ":" + base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, 32))
+}
+
+func TestKeyringRoundTrip(t *testing.T) {
+	k, err := ParseKeyring([]string{testKeySpec("k1", 1)})
+	if err != nil {
+		t.Fatalf("ParseKeyring() error = %v", err)
+	}
+	plaintext := []byte("hunter2")
+
+	sealed, err := k.Encrypt(plaintext)
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if !IsEncrypted(sealed) || !strings.HasPrefix(sealed, "enc:v1:k1:") {
+		t.Errorf("Encrypt() = %q, want an enc:v1:k1: value", sealed)
+	}
+	if strings.Contains(sealed, string(plaintext)) {
+		t.Errorf("Encrypt() = %q leaks the plaintext", sealed)
+	}
+	if again, _ := k.Encrypt(plaintext); again == sealed {
+		t.Error("Encrypt() sealed the same plaintext twice to the same value")
+	}
+
+	got, err := k.Decrypt(sealed)
+	if err != nil {
+		t.Fatalf("Decrypt() error = %v", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("Decrypt() = %q, want %q", got, plaintext)
+	}
+}
+
+func TestKeyringRotation(t *testing.T) {
+	old, err := ParseKeyring([]string{testKeySpec("2025", 1)})
+	if err != nil {
+		t.Fatalf("ParseKeyring() error = %v", err)
+	}
+	sealedByOld, err := old.Encrypt([]byte("hunter2"))
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+
+	// The new key encrypts, the old one still decrypts
+	rotated, err := ParseKeyring([]string{testKeySpec("2026", 2), testKeySpec("2025", 1)})
+	if err != nil {
+		t.Fatalf("ParseKeyring() error = %v", err)
+	}
+	if rotated.ActiveKeyID() != "2026" {
+		t.Errorf("ActiveKeyID() = %q, want the first key", rotated.ActiveKeyID())
+	}
+	if got, err := rotated.Decrypt(sealedByOld); err != nil || string(got) != "hunter2" {
+		t.Errorf("Decrypt() = %q, %v; want the value sealed by the old key", got, err)
+	}
+	sealedByNew, err := rotated.Encrypt([]byte("hunter2"))
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if !strings.HasPrefix(sealedByNew, "enc:v1:2026:") {
+		t.Errorf("Encrypt() = %q, want it sealed by the new key", sealedByNew)
+	}
+
+	// Once the old key is dropped, what it sealed cannot be read
+	if _, err := old.Decrypt(sealedByNew); !errors.Is(err, ErrUnknownEncryptionKey) {
+		t.Errorf("Decrypt() error = %v, want ErrUnknownEncryptionKey", err)
+	}
+}
+
+func TestKeyringRejectsTamperedValues(t *testing.T) {
+	k, err := ParseKeyring([]string{testKeySpec("k1", 1), testKeySpec("k2", 2)})
+	if err != nil {
+		t.Fatalf("ParseKeyring() error = %v", err)
+	}
+	sealed, err := k.Encrypt([]byte("hunter2"))
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	last := sealed[len(sealed)-1]
+	flipped := byte('A')
+	if last == 'A' {
+		flipped = 'B'
+	}
+
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{name: "plaintext", value: "hunter2"},
+		{name: "missing parts", value: "enc:v1:k1:abc"},
+		{name: "flipped ciphertext", value: sealed[:len(sealed)-1] + string(flipped)},
+		{name: "swapped key id", value: strings.Replace(sealed, "enc:v1:k1:", "enc:v1:k2:", 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := k.Decrypt(tt.value); !errors.Is(err, ErrMalformedCiphertext) {
+				t.Errorf("Decrypt() error = %v, want ErrMalformedCiphertext", err)
+			}
+		})
+	}
+}
+
+func TestParseKeyringRejectsBadSpecs(t *testing.T) {
+	tests := []struct {
+		name  string
+		specs []string
+	}{
+		{name: "no keys"},
+		{name: "no id", specs: []string{base64.StdEncoding.EncodeToString(make([]byte, 32))}},
+		{name: "id with a colon", specs: []string{"a:b:" + base64.StdEncoding.EncodeToString(make([]byte, 32))}},
+		{name: "not base64", specs: []string{"k1:not base64"}},
+		{name: "wrong size", specs: []string{"k1:" + base64.StdEncoding.EncodeToString(make([]byte, 20))}},
+		{name: "duplicate id", specs: []string{testKeySpec("k1", 1), testKeySpec("k1", 2)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseKeyring(tt.specs); err == nil {
+				t.Error("ParseKeyring() error = nil, want the spec rejected")
+			}
+		})
+	}
+}
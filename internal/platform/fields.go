@@ -0,0 +1,96 @@
+package platform
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Fields reads a JSON:API-style sparse fieldset, e.g. ?fields=id,name, from
+// the named parameter. Every name must be a JSON field of model, a struct or
+// pointer to one; unknown names are reported through Err. Returns nil,
+// meaning every field, if the parameter is absent.
+func (q *Query) Fields(name string, model any) []string {
+	fields := q.StringSlice(name)
+	if fields == nil {
+		return nil
+	}
+
+	known := jsonFieldNames(reflect.TypeOf(model))
+	var unknown []string
+	for _, f := range fields {
+		if _, ok := known[f]; !ok {
+			unknown = append(unknown, f)
+		}
+	}
+	if len(unknown) > 0 {
+		q.invalid(name, "fields", fmt.Sprintf("unknown field(s): %s", strings.Join(unknown, ", ")))
+		return nil
+	}
+	return fields
+}
+
+// jsonFieldNames returns the names t's exported fields are encoded under,
+// including those promoted from embedded structs.
+// Pure function.
+func jsonFieldNames(t reflect.Type) map[string]struct{} {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	names := make(map[string]struct{})
+	if t.Kind() != reflect.Struct {
+		return names
+	}
+	for _, f := range reflect.VisibleFields(t) {
+		if !f.IsExported() || f.Anonymous {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = f.Name
+		}
+		names[name] = struct{}{}
+	}
+	return names
+}
+
+// SelectFields returns v's JSON encoding as an object restricted to fields.
+// Fields v omits from its encoding stay omitted. A nil fields keeps all.
+func SelectFields[T any](v T, fields []string) (map[string]any, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("encode for field selection: %w", err)
+	}
+	var all map[string]any
+	if err := json.Unmarshal(raw, &all); err != nil {
+		return nil, fmt.Errorf("decode for field selection: %w", err)
+	}
+	if fields == nil {
+		return all, nil
+	}
+
+	out := make(map[string]any, len(fields))
+	for _, f := range fields {
+		if value, ok := all[f]; ok {
+			out[f] = value
+		}
+	}
+	return out, nil
+}
+
+// SelectFieldsEach applies SelectFields to every element of items.
+func SelectFieldsEach[T any](items []T, fields []string) ([]map[string]any, error) {
+	out := make([]map[string]any, len(items))
+	for i, item := range items {
+		m, err := SelectFields(item, fields)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = m
+	}
+	return out, nil
+}
@@ -0,0 +1,74 @@
+package platform
+
+import (
+	"reflect"
+	"testing"
+)
+
+type fieldsModel struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Note     string `json:"note,omitempty"`
+	Secret   string `json:"-"`
+	Untagged int
+}
+
+func TestQueryFields(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    []string
+		wantErr bool
+	}{
+		{query: "", want: nil},
+		{query: "fields=id,name", want: []string{"id", "name"}},
+		{query: "fields=id,note,Untagged", want: []string{"id", "note", "Untagged"}},
+		{query: "fields=id,secret", want: nil, wantErr: true},
+		{query: "fields=id,-", want: nil, wantErr: true},
+	}
+	for _, tt := range tests {
+		q := newQuery(tt.query)
+		if got := q.Fields("fields", &fieldsModel{}); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: Fields() = %v, want %v", tt.query, got, tt.want)
+		}
+		if (q.Err() != nil) != tt.wantErr {
+			t.Errorf("%q: Err() = %v, wantErr %v", tt.query, q.Err(), tt.wantErr)
+		}
+	}
+}
+
+func TestSelectFields(t *testing.T) {
+	v := fieldsModel{ID: "p-1", Name: "Alpha", Secret: "s3cret", Untagged: 7}
+
+	tests := []struct {
+		name   string
+		fields []string
+		want   map[string]any
+	}{
+		{
+			name:   "subset",
+			fields: []string{"id", "name"},
+			want:   map[string]any{"id": "p-1", "name": "Alpha"},
+		},
+		{
+			name:   "all fields",
+			fields: nil,
+			want:   map[string]any{"id": "p-1", "name": "Alpha", "Untagged": float64(7)},
+		},
+		{
+			name:   "omitted field stays omitted",
+			fields: []string{"id", "note"},
+			want:   map[string]any{"id": "p-1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SelectFields(v, tt.fields)
+			if err != nil {
+				t.Fatalf("SelectFields() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SelectFields() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
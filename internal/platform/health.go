@@ -0,0 +1,174 @@
+package platform
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Health status values reported by the health handlers.
+const (
+	HealthOK          = "ok"
+	HealthUnavailable = "unavailable"
+)
+
+// HealthCheck reports whether a dependency is usable.
+type HealthCheck func(ctx context.Context) error
+
+// HealthCheckGroup runs several checks itself, e.g. every plugin's at once,
+// and reports each by name, latency included.
+type HealthCheckGroup func(ctx context.Context) map[string]HealthCheckResult
+
+// HealthOptions configures the liveness and readiness handlers.
+type HealthOptions struct {
+	Version   string
+	StartedAt time.Time
+	// Checks are run by the readiness handler only, keyed by dependency name.
+	Checks map[string]HealthCheck
+	// ReportGroups are run by the readiness handler alongside Checks when
+	// asked for ?verbose=true, their results merged in. Unlike Checks they
+	// never make the instance unready, for backends whose outage must not
+	// take read traffic down with it.
+	ReportGroups []HealthCheckGroup
+	// CheckTimeout bounds each readiness check. Zero means 2s.
+	CheckTimeout time.Duration
+}
+
+// HealthReport is the health response body. Only Status is set unless the
+// caller asked for ?verbose=true.
+type HealthReport struct {
+	Status        string                       `json:"status"`
+	Version       string                       `json:"version,omitempty"`
+	UptimeSeconds int64                        `json:"uptime_seconds,omitempty"`
+	Checks        map[string]HealthCheckResult `json:"checks,omitempty"`
+}
+
+// HealthCheckResult is the outcome of one readiness check. LatencyMS is
+// how long the check took to answer, so a dependency slowing down shows
+// before it fails.
+type HealthCheckResult struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// NewHealthCheckResult reports a check that returned err after latency.
+func NewHealthCheckResult(err error, latency time.Duration) HealthCheckResult {
+	res := HealthCheckResult{Status: HealthOK, LatencyMS: latencyMS(latency)}
+	if err != nil {
+		res.Status = HealthUnavailable
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// latencyMS converts d to milliseconds with microsecond precision, never
+// negative. Pure function.
+func latencyMS(d time.Duration) float64 {
+	return float64(max(d, 0).Microseconds()) / 1000
+}
+
+// LivenessHandler reports that the process is up. It never touches any
+// dependency, so orchestrators can restart only truly stuck processes.
+func LivenessHandler(opts HealthOptions) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := HealthReport{Status: HealthOK}
+		if verbose(r) {
+			opts.describe(&report)
+		}
+		RespondJSON(w, http.StatusOK, report)
+	}
+}
+
+// ReadinessHandler runs every configured check and answers 503 if any fail,
+// so traffic is only routed to instances whose dependencies are reachable.
+// Report groups are only run for the verbose payload and do not count.
+func ReadinessHandler(opts HealthOptions) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		results, reported := opts.runChecks(r.Context(), verbose(r))
+
+		report := HealthReport{Status: HealthOK}
+		for _, res := range results {
+			if res.Status != HealthOK {
+				report.Status = HealthUnavailable
+			}
+		}
+		if verbose(r) {
+			opts.describe(&report)
+			maps.Copy(results, reported)
+			report.Checks = results
+		}
+
+		status := http.StatusOK
+		if report.Status != HealthOK {
+			status = http.StatusServiceUnavailable
+		}
+		RespondJSON(w, status, report)
+	}
+}
+
+func (o HealthOptions) describe(report *HealthReport) {
+	report.Version = o.Version
+	if !o.StartedAt.IsZero() {
+		report.UptimeSeconds = int64(time.Since(o.StartedAt).Seconds())
+	}
+}
+
+// runChecks runs all checks and, if withReports, report groups
+// concurrently, each under its own timeout, timing every check. The
+// results of checks and report groups are returned apart.
+func (o HealthOptions) runChecks(ctx context.Context, withReports bool) (results, reported map[string]HealthCheckResult) {
+	timeout := o.CheckTimeout
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	results = make(map[string]HealthCheckResult, len(o.Checks))
+	reported = make(map[string]HealthCheckResult)
+	for name, check := range o.Checks {
+		wg.Add(1)
+		go func(name string, check HealthCheck) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := check(checkCtx)
+			res := NewHealthCheckResult(err, time.Since(start))
+			mu.Lock()
+			results[name] = res
+			mu.Unlock()
+		}(name, check)
+	}
+	for _, group := range o.ReportGroups {
+		if !withReports {
+			break
+		}
+		wg.Add(1)
+		go func(group HealthCheckGroup) {
+			defer wg.Done()
+			groupCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			found := group(groupCtx)
+			mu.Lock()
+			maps.Copy(reported, found)
+			mu.Unlock()
+		}(group)
+	}
+	wg.Wait()
+	return results, reported
+}
+
+// verbose reports whether the request asked for the extended payload.
+func verbose(r *http.Request) bool {
+	v, err := strconv.ParseBool(r.URL.Query().Get("verbose"))
+	return err == nil && v
+}
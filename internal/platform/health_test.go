@@ -0,0 +1,209 @@
+package platform
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func decodeHealth(t *testing.T, rr *httptest.ResponseRecorder) map[string]any {
+	t.Helper()
+	var body map[string]any
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode response body: %v", err)
+	}
+	return body
+}
+
+func TestLivenessHandlerMinimalPayload(t *testing.T) {
+	h := LivenessHandler(HealthOptions{Version: "1.2.3", StartedAt: time.Now()})
+
+	rr := httptest.NewRecorder()
+	h(rr, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	body := decodeHealth(t, rr)
+	if len(body) != 1 || body["status"] != HealthOK {
+		t.Errorf("expected only {\"status\":\"ok\"}, got %v", body)
+	}
+}
+
+func TestLivenessHandlerVerbosePayload(t *testing.T) {
+	h := LivenessHandler(HealthOptions{
+		Version:   "1.2.3",
+		StartedAt: time.Now().Add(-time.Minute),
+		Checks: map[string]HealthCheck{
+			"database": func(context.Context) error {
+				t.Error("liveness must not run dependency checks")
+				return nil
+			},
+		},
+	})
+
+	rr := httptest.NewRecorder()
+	h(rr, httptest.NewRequest(http.MethodGet, "/health?verbose=true", nil))
+
+	body := decodeHealth(t, rr)
+	if body["version"] != "1.2.3" {
+		t.Errorf("version = %v, want 1.2.3", body["version"])
+	}
+	if uptime, _ := body["uptime_seconds"].(float64); uptime < 60 {
+		t.Errorf("uptime_seconds = %v, want >= 60", body["uptime_seconds"])
+	}
+	if _, ok := body["checks"]; ok {
+		t.Error("liveness should not report checks")
+	}
+}
+
+func TestReadinessHandlerMinimalPayloadOnFailure(t *testing.T) {
+	h := ReadinessHandler(HealthOptions{
+		Checks: map[string]HealthCheck{
+			"database": func(context.Context) error { return errors.New("connection refused") },
+		},
+	})
+
+	rr := httptest.NewRecorder()
+	h(rr, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", rr.Code)
+	}
+	body := decodeHealth(t, rr)
+	if len(body) != 1 || body["status"] != HealthUnavailable {
+		t.Errorf("expected only {\"status\":\"unavailable\"}, got %v", body)
+	}
+}
+
+func TestReadinessHandlerVerboseReportsEachCheck(t *testing.T) {
+	h := ReadinessHandler(HealthOptions{
+		Version: "1.2.3",
+		Checks: map[string]HealthCheck{
+			"database": func(context.Context) error { return nil },
+			"cache":    func(context.Context) error { return errors.New("timeout") },
+		},
+	})
+
+	rr := httptest.NewRecorder()
+	h(rr, httptest.NewRequest(http.MethodGet, "/health/ready?verbose=1", nil))
+
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", rr.Code)
+	}
+
+	var body HealthReport
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode response body: %v", err)
+	}
+	if body.Version != "1.2.3" {
+		t.Errorf("version = %q, want 1.2.3", body.Version)
+	}
+	if body.Checks["database"].Status != HealthOK {
+		t.Errorf("database = %+v, want ok", body.Checks["database"])
+	}
+	if got := body.Checks["cache"]; got.Status != HealthUnavailable || got.Error != "timeout" {
+		t.Errorf("cache = %+v, want unavailable with error", got)
+	}
+}
+
+func TestReadinessHandlerReportsLatencyOfEachCheck(t *testing.T) {
+	h := ReadinessHandler(HealthOptions{
+		Checks: map[string]HealthCheck{
+			"database": func(context.Context) error {
+				time.Sleep(5 * time.Millisecond)
+				return nil
+			},
+			"cache": func(context.Context) error { return errors.New("timeout") },
+		},
+		ReportGroups: []HealthCheckGroup{
+			func(context.Context) map[string]HealthCheckResult {
+				return map[string]HealthCheckResult{
+					"plugin:proxmox": NewHealthCheckResult(nil, 12*time.Millisecond),
+				}
+			},
+		},
+	})
+
+	rr := httptest.NewRecorder()
+	h(rr, httptest.NewRequest(http.MethodGet, "/health/ready?verbose=true", nil))
+
+	checks, _ := decodeHealth(t, rr)["checks"].(map[string]any)
+	for _, name := range []string{"database", "cache", "plugin:proxmox"} {
+		check, _ := checks[name].(map[string]any)
+		latency, ok := check["latency_ms"].(float64)
+		if !ok || latency < 0 {
+			t.Errorf("%s latency_ms = %v, want a non-negative number", name, check["latency_ms"])
+		}
+	}
+	if latency := checks["database"].(map[string]any)["latency_ms"].(float64); latency < 5 {
+		t.Errorf("database latency_ms = %v, want at least 5", latency)
+	}
+	if latency := checks["plugin:proxmox"].(map[string]any)["latency_ms"].(float64); latency != 12 {
+		t.Errorf("plugin:proxmox latency_ms = %v, want 12", latency)
+	}
+}
+
+func TestReadinessHandlerIgnoresReportGroupsForStatus(t *testing.T) {
+	ran := 0
+	h := ReadinessHandler(HealthOptions{
+		Checks: map[string]HealthCheck{
+			"database": func(context.Context) error { return nil },
+		},
+		ReportGroups: []HealthCheckGroup{
+			func(context.Context) map[string]HealthCheckResult {
+				ran++
+				return map[string]HealthCheckResult{
+					"plugin:proxmox": NewHealthCheckResult(errors.New("cluster down"), time.Millisecond),
+				}
+			},
+		},
+	})
+
+	rr := httptest.NewRecorder()
+	h(rr, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	if ran != 0 {
+		t.Errorf("report groups ran %d times for a plain probe, want none", ran)
+	}
+
+	rr = httptest.NewRecorder()
+	h(rr, httptest.NewRequest(http.MethodGet, "/health/ready?verbose=true", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("verbose: expected 200, got %d", rr.Code)
+	}
+	body := decodeHealth(t, rr)
+	if body["status"] != HealthOK {
+		t.Errorf("status = %v, want ok", body["status"])
+	}
+	checks, _ := body["checks"].(map[string]any)
+	if plugin, _ := checks["plugin:proxmox"].(map[string]any); plugin["status"] != HealthUnavailable {
+		t.Errorf("plugin:proxmox = %v, want it reported unavailable", checks["plugin:proxmox"])
+	}
+}
+
+func TestNewHealthCheckResult(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		latency time.Duration
+		want    HealthCheckResult
+	}{
+		{name: "healthy", latency: 1500 * time.Microsecond, want: HealthCheckResult{Status: HealthOK, LatencyMS: 1.5}},
+		{name: "failed", err: errors.New("refused"), latency: 3 * time.Millisecond, want: HealthCheckResult{Status: HealthUnavailable, LatencyMS: 3, Error: "refused"}},
+		{name: "negative latency", latency: -time.Second, want: HealthCheckResult{Status: HealthOK}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewHealthCheckResult(tt.err, tt.latency); got != tt.want {
+				t.Errorf("NewHealthCheckResult() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package platform
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ConcurrencyLimit bounds the number of requests served at once to max.
+// Requests arriving while all slots are taken are answered immediately with
+// 503 SERVER_BUSY and a Retry-After of retryAfter instead of queueing, so a
+// burst cannot pile up behind the database pool. A max of zero or less
+// disables the limit.
+func ConcurrencyLimit(max int, retryAfter time.Duration) func(http.Handler) http.Handler {
+	if max <= 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	slots := make(chan struct{}, max)
+	retrySeconds := strconv.Itoa(int(math.Ceil(retryAfter.Seconds())))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+				next.ServeHTTP(w, r)
+			default:
+				w.Header().Set("Retry-After", retrySeconds)
+				RespondError(w, http.StatusServiceUnavailable, "SERVER_BUSY", "too many requests in flight, retry later")
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package platform
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConcurrencyLimitRejectsExcessRequests(t *testing.T) {
+	const limit, flood = 3, 10
+
+	release := make(chan struct{})
+	entered := make(chan struct{}, flood)
+	handler := ConcurrencyLimit(limit, 2*time.Second)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		entered <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	// Occupy every slot.
+	var wg sync.WaitGroup
+	held := make([]*httptest.ResponseRecorder, limit)
+	for i := range held {
+		held[i] = httptest.NewRecorder()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			handler.ServeHTTP(held[i], httptest.NewRequest(http.MethodGet, "/", nil))
+		}()
+	}
+	for range limit {
+		<-entered
+	}
+
+	// Everything beyond the limit must be rejected straight away.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range flood - limit {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+			if rr.Code != http.StatusServiceUnavailable {
+				t.Errorf("expected 503 while full, got %d", rr.Code)
+			}
+			if got := rr.Header().Get("Retry-After"); got != "2" {
+				t.Errorf("Retry-After = %q, want 2", got)
+			}
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("excess requests hung instead of being rejected")
+	}
+
+	close(release)
+	wg.Wait()
+	for _, rr := range held {
+		if rr.Code != http.StatusNoContent {
+			t.Errorf("admitted request got %d, want 204", rr.Code)
+		}
+	}
+
+	// Slots are released once requests finish.
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("expected a freed slot to admit the next request, got %d", rr.Code)
+	}
+}
+
+func TestConcurrencyLimitDisabled(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	rr := httptest.NewRecorder()
+	ConcurrencyLimit(0, time.Second)(next).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("expected a disabled limit to pass requests through, got %d", rr.Code)
+	}
+}
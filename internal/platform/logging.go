@@ -0,0 +1,98 @@
+package platform
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+
+	"github.com/searge/quokka/internal/config"
+)
+
+// NewLogger builds the application logger from cfg: level, format (json or
+// text), destination (stdout, stderr or an appended file), secret redaction
+// and the environment attribute. Close the returned io.Closer on shutdown;
+// it is a no-op for the standard streams.
+func NewLogger(cfg config.Config) (*slog.Logger, io.Closer, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.LogLevel)); err != nil {
+		return nil, nil, fmt.Errorf("invalid log level: %w", err)
+	}
+	redact, err := regexp.Compile(cfg.LogRedactPattern)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid log redaction pattern: %w", err)
+	}
+
+	w, closer, err := logOutput(cfg.LogOutput)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	opts := &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: RedactAttr(redact),
+	}
+	handler, err := logHandler(cfg.LogFormat, w, opts)
+	if err != nil {
+		if closeErr := closer.Close(); closeErr != nil {
+			return nil, nil, fmt.Errorf("%w (closing log output: %v)", err, closeErr)
+		}
+		return nil, nil, err
+	}
+
+	return WithEnvironment(slog.New(handler), cfg.Environment), closer, nil
+}
+
+// logOutput opens the log destination.
+func logOutput(dest string) (io.Writer, io.Closer, error) {
+	switch dest {
+	case "", "stdout":
+		return os.Stdout, nopCloser{}, nil
+	case "stderr":
+		return os.Stderr, nopCloser{}, nil
+	default:
+		f, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+		if err != nil {
+			return nil, nil, fmt.Errorf("open log file: %w", err)
+		}
+		return f, f, nil
+	}
+}
+
+// logHandler selects the slog handler for format.
+func logHandler(format string, w io.Writer, opts *slog.HandlerOptions) (slog.Handler, error) {
+	switch format {
+	case "", "json":
+		return slog.NewJSONHandler(w, opts), nil
+	case "text":
+		return slog.NewTextHandler(w, opts), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+}
+
+// nopCloser is the io.Closer of a stream the logger does not own.
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
+
+// RedactedValue replaces the value of sensitive log attributes.
+const RedactedValue = "***"
+
+// RedactAttr returns a slog.HandlerOptions.ReplaceAttr function that masks
+// the value of every attribute whose key matches pattern, at any group depth.
+func RedactAttr(pattern *regexp.Regexp) func(groups []string, a slog.Attr) slog.Attr {
+	return func(_ []string, a slog.Attr) slog.Attr {
+		if a.Value.Kind() != slog.KindGroup && pattern.MatchString(a.Key) {
+			return slog.String(a.Key, RedactedValue)
+		}
+		return a
+	}
+}
+
+// WithEnvironment tags every record written through logger with the
+// deployment environment.
+func WithEnvironment(logger *slog.Logger, env string) *slog.Logger {
+	return logger.With("environment", env)
+}
@@ -0,0 +1,198 @@
+package platform
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/searge/quokka/internal/config"
+)
+
+func TestRedactAttrMasksSensitiveKeys(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: RedactAttr(regexp.MustCompile(`(?i)(password|token|secret|key)`)),
+	}))
+
+	logger.Info("provisioning",
+		"project_id", "p-1",
+		"root_password", "hunter2",
+		slog.Group("auth", "API_TOKEN", "abc123", "user", "quokka"),
+	)
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("failed to decode log record: %v", err)
+	}
+	if record["root_password"] != RedactedValue {
+		t.Errorf("root_password = %v, want %q", record["root_password"], RedactedValue)
+	}
+	if record["project_id"] != "p-1" {
+		t.Errorf("project_id = %v, want p-1", record["project_id"])
+	}
+	if record["msg"] != "provisioning" {
+		t.Errorf("msg = %v, built-in keys must not be redacted", record["msg"])
+	}
+
+	auth, ok := record["auth"].(map[string]any)
+	if !ok {
+		t.Fatalf("expected auth group, got %T", record["auth"])
+	}
+	if auth["API_TOKEN"] != RedactedValue {
+		t.Errorf("auth.API_TOKEN = %v, want %q", auth["API_TOKEN"], RedactedValue)
+	}
+	if auth["user"] != "quokka" {
+		t.Errorf("auth.user = %v, want quokka", auth["user"])
+	}
+}
+
+func TestWithEnvironmentTagsEveryRecord(t *testing.T) {
+	var buf bytes.Buffer
+	logger := WithEnvironment(slog.New(slog.NewJSONHandler(&buf, nil)), "prod")
+
+	logger.Info("first")
+	logger.Warn("second", "project_id", "p-1")
+
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var record map[string]any
+		if err := dec.Decode(&record); err != nil {
+			t.Fatalf("failed to decode log record: %v", err)
+		}
+		if record["environment"] != "prod" {
+			t.Errorf("record %v: environment = %v, want prod", record["msg"], record["environment"])
+		}
+	}
+}
+
+func TestNewLoggerWritesSelectedFormatToFile(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{format: "json", want: `"msg":"hello"`},
+		{format: "text", want: `msg=hello`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "quokka.log")
+			cfg := config.Default()
+			cfg.LogFormat = tt.format
+			cfg.LogOutput = path
+			cfg.Environment = "test"
+
+			logger, closer, err := NewLogger(cfg)
+			if err != nil {
+				t.Fatalf("NewLogger() error = %v", err)
+			}
+			logger.Info("hello", "api_token", "abc")
+			if err := closer.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			raw, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("failed to read log file: %v", err)
+			}
+			out := string(raw)
+			for _, want := range []string{tt.want, "environment", RedactedValue} {
+				if !strings.Contains(out, want) {
+					t.Errorf("log output %q does not contain %q", out, want)
+				}
+			}
+			if strings.Contains(out, "abc") {
+				t.Errorf("log output %q leaks a redacted value", out)
+			}
+		})
+	}
+}
+
+func TestNewLoggerAppendsToExistingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quokka.log")
+	if err := os.WriteFile(path, []byte("previous run\n"), 0o600); err != nil {
+		t.Fatalf("failed to seed log file: %v", err)
+	}
+	cfg := config.Default()
+	cfg.LogOutput = path
+
+	logger, closer, err := NewLogger(cfg)
+	if err != nil {
+		t.Fatalf("NewLogger() error = %v", err)
+	}
+	logger.Info("next run")
+	if err := closer.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read log file: %v", err)
+	}
+	if !strings.HasPrefix(string(raw), "previous run\n") || !strings.Contains(string(raw), "next run") {
+		t.Errorf("expected the file to be appended to, got %q", raw)
+	}
+}
+
+func TestNewLoggerHonoursLevel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quokka.log")
+	cfg := config.Default()
+	cfg.LogLevel = "warn"
+	cfg.LogOutput = path
+
+	logger, closer, err := NewLogger(cfg)
+	if err != nil {
+		t.Fatalf("NewLogger() error = %v", err)
+	}
+	logger.Info("dropped")
+	logger.Warn("kept")
+	if err := closer.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read log file: %v", err)
+	}
+	if strings.Contains(string(raw), "dropped") || !strings.Contains(string(raw), "kept") {
+		t.Errorf("expected only warn records, got %q", raw)
+	}
+}
+
+func TestLogOutputSelectsStandardStreams(t *testing.T) {
+	tests := []struct {
+		dest string
+		want *os.File
+	}{
+		{dest: "stdout", want: os.Stdout},
+		{dest: "", want: os.Stdout},
+		{dest: "stderr", want: os.Stderr},
+	}
+	for _, tt := range tests {
+		w, closer, err := logOutput(tt.dest)
+		if err != nil {
+			t.Fatalf("logOutput(%q) error = %v", tt.dest, err)
+		}
+		if w != tt.want {
+			t.Errorf("logOutput(%q) = %v, want %v", tt.dest, w, tt.want.Name())
+		}
+		if err := closer.Close(); err != nil {
+			t.Errorf("closing %q must be a no-op, got %v", tt.dest, err)
+		}
+	}
+}
+
+func TestNewLoggerRejectsUnknownFormat(t *testing.T) {
+	cfg := config.Default()
+	cfg.LogFormat = "logfmt"
+	cfg.LogOutput = filepath.Join(t.TempDir(), "quokka.log")
+
+	if _, _, err := NewLogger(cfg); err == nil {
+		t.Fatal("expected an error for an unknown log format")
+	}
+}
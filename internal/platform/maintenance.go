@@ -0,0 +1,119 @@
+package platform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrMaintenanceRunning is returned by a MaintenanceLock that is held elsewhere.
+var ErrMaintenanceRunning = errors.New("maintenance is already running")
+
+// MaintenanceTask is one named housekeeping step.
+type MaintenanceTask struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// MaintenanceLock keeps maintenance runs from overlapping, possibly across
+// instances. TryLock returns ErrMaintenanceRunning rather than waiting.
+type MaintenanceLock interface {
+	TryLock(ctx context.Context) (unlock func(), err error)
+}
+
+// MaintenanceReport summarizes one maintenance run.
+type MaintenanceReport struct {
+	StartedAt  time.Time               `json:"started_at"`
+	DurationMS int64                   `json:"duration_ms"`
+	Tasks      []MaintenanceTaskReport `json:"tasks"`
+}
+
+// MaintenanceTaskReport is the outcome of a single task.
+type MaintenanceTaskReport struct {
+	Name       string `json:"name"`
+	DurationMS int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// MaintenanceHandler runs every task in order under lock and reports how
+// long each took. A run already in progress yields 409 MAINTENANCE_RUNNING.
+// A failing task is reported, and logged to logger, and does not stop the
+// ones after it.
+func MaintenanceHandler(lock MaintenanceLock, tasks []MaintenanceTask, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		unlock, err := lock.TryLock(r.Context())
+		if err != nil {
+			if errors.Is(err, ErrMaintenanceRunning) {
+				RespondError(w, http.StatusConflict, "MAINTENANCE_RUNNING", err.Error())
+				return
+			}
+			RespondServerError(w, logger, fmt.Errorf("take maintenance lock: %w", err))
+			return
+		}
+		defer unlock()
+
+		report := MaintenanceReport{StartedAt: time.Now().UTC(), Tasks: make([]MaintenanceTaskReport, 0, len(tasks))}
+		for _, task := range tasks {
+			start := time.Now()
+			taskReport := MaintenanceTaskReport{Name: task.Name}
+			if err := task.Run(r.Context()); err != nil {
+				logger.Error("maintenance task failed", "task", task.Name, "error", err)
+				taskReport.Error = err.Error()
+			}
+			taskReport.DurationMS = time.Since(start).Milliseconds()
+			report.Tasks = append(report.Tasks, taskReport)
+		}
+		report.DurationMS = time.Since(report.StartedAt).Milliseconds()
+
+		RespondJSON(w, http.StatusOK, report)
+	}
+}
+
+// AdvisoryLock is a MaintenanceLock backed by a PostgreSQL session advisory
+// lock, so only one instance sharing the database runs maintenance at a time.
+type AdvisoryLock struct {
+	pool *pgxpool.Pool
+	key  int64
+}
+
+// NewAdvisoryLock returns a lock on the advisory lock key.
+func NewAdvisoryLock(pool *pgxpool.Pool, key int64) *AdvisoryLock {
+	return &AdvisoryLock{pool: pool, key: key}
+}
+
+// TryLock implements MaintenanceLock. The lock is held on a dedicated
+// connection until unlock is called.
+func (l *AdvisoryLock) TryLock(ctx context.Context) (func(), error) {
+	conn, err := l.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var locked bool
+	if err := conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", l.key).Scan(&locked); err != nil {
+		conn.Release()
+		return nil, err
+	}
+	if !locked {
+		conn.Release()
+		return nil, ErrMaintenanceRunning
+	}
+
+	return func() {
+		ctx := context.WithoutCancel(ctx)
+		if _, err := conn.Exec(ctx, "SELECT pg_advisory_unlock($1)", l.key); err != nil {
+			// A session lock lives as long as its connection: close it
+			// rather than hand a locked session back to the pool.
+			slog.Default().Warn("failed to release maintenance lock", "error", err)
+			if err := conn.Conn().Close(ctx); err != nil {
+				slog.Default().Warn("failed to close maintenance connection", "error", err)
+			}
+		}
+		conn.Release()
+	}, nil
+}
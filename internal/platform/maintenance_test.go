@@ -0,0 +1,108 @@
+package platform
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// localLock is an in-process MaintenanceLock.
+type localLock struct {
+	mu sync.Mutex
+}
+
+func (l *localLock) TryLock(context.Context) (func(), error) {
+	if !l.mu.TryLock() {
+		return nil, ErrMaintenanceRunning
+	}
+	return l.mu.Unlock, nil
+}
+
+func TestMaintenanceHandlerRejectsConcurrentRuns(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := MaintenanceHandler(&localLock{}, []MaintenanceTask{{
+		Name: "slow",
+		Run: func(context.Context) error {
+			close(started)
+			<-release
+			return nil
+		},
+	}}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	first := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		handler(first, httptest.NewRequest(http.MethodPost, "/admin/maintenance", nil))
+		close(done)
+	}()
+	<-started
+
+	second := httptest.NewRecorder()
+	handler(second, httptest.NewRequest(http.MethodPost, "/admin/maintenance", nil))
+	if second.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for a concurrent run, got %d: %s", second.Code, second.Body.String())
+	}
+	var body APIError
+	if err := json.Unmarshal(second.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode response body: %v", err)
+	}
+	if body.Error.Code != "MAINTENANCE_RUNNING" {
+		t.Errorf("error code = %q, want MAINTENANCE_RUNNING", body.Error.Code)
+	}
+
+	close(release)
+	<-done
+	if first.Code != http.StatusOK {
+		t.Fatalf("expected the first run to succeed, got %d", first.Code)
+	}
+}
+
+func TestMaintenanceHandlerReportsEveryTask(t *testing.T) {
+	var ran []string
+	var logs bytes.Buffer
+	handler := MaintenanceHandler(&localLock{}, []MaintenanceTask{
+		{Name: "vacuum", Run: func(context.Context) error {
+			ran = append(ran, "vacuum")
+			return errors.New("disk full")
+		}},
+		{Name: "refresh_counts", Run: func(context.Context) error {
+			ran = append(ran, "refresh_counts")
+			return nil
+		}},
+	}, slog.New(slog.NewJSONHandler(&logs, nil)))
+
+	rr := httptest.NewRecorder()
+	handler(rr, httptest.NewRequest(http.MethodPost, "/admin/maintenance", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var report MaintenanceReport
+	if err := json.Unmarshal(rr.Body.Bytes(), &report); err != nil {
+		t.Fatalf("failed to decode report: %v", err)
+	}
+	if len(ran) != 2 {
+		t.Fatalf("expected a failed task not to stop the rest, ran %v", ran)
+	}
+	if len(report.Tasks) != 2 || report.Tasks[0].Name != "vacuum" || report.Tasks[1].Name != "refresh_counts" {
+		t.Fatalf("unexpected tasks in report: %+v", report.Tasks)
+	}
+	if report.Tasks[0].Error != "disk full" || report.Tasks[1].Error != "" {
+		t.Errorf("unexpected task errors: %+v", report.Tasks)
+	}
+	if report.StartedAt.IsZero() {
+		t.Error("expected started_at to be set")
+	}
+	if !strings.Contains(logs.String(), `"task":"vacuum"`) {
+		t.Errorf("expected the failed task logged to the handler's logger, got %q", logs.String())
+	}
+}
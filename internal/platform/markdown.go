@@ -0,0 +1,23 @@
+package platform
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+)
+
+// markdownPolicy allows the formatting produced by markdown and strips
+// anything executable (scripts, event handlers, javascript: URLs).
+var markdownPolicy = bluemonday.UGCPolicy()
+
+// RenderMarkdown converts markdown source into sanitized HTML that is safe
+// to embed in a page. The source itself is never modified.
+func RenderMarkdown(src string) (string, error) {
+	var buf bytes.Buffer
+	if err := goldmark.Convert([]byte(src), &buf); err != nil {
+		return "", fmt.Errorf("render markdown: %w", err)
+	}
+	return markdownPolicy.Sanitize(buf.String()), nil
+}
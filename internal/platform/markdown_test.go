@@ -0,0 +1,43 @@
+package platform
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderMarkdownConvertsBasicMarkdown(t *testing.T) {
+	out, err := RenderMarkdown("# Billing\n\nRuns **nightly** jobs. See [docs](https://example.com).")
+	if err != nil {
+		t.Fatalf("RenderMarkdown() error = %v", err)
+	}
+
+	for _, want := range []string{"<h1>Billing</h1>", "<strong>nightly</strong>", `href="https://example.com"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output %q should contain %q", out, want)
+		}
+	}
+}
+
+func TestRenderMarkdownStripsScripts(t *testing.T) {
+	tests := map[string]string{
+		"script tag":       "hello <script>alert('x')</script>",
+		"event handler":    `<img src="x.png" onerror="alert('x')">`,
+		"javascript link":  "[click](javascript:alert('x'))",
+		"inline html link": `<a href="javascript:alert('x')">click</a>`,
+	}
+
+	for name, src := range tests {
+		t.Run(name, func(t *testing.T) {
+			out, err := RenderMarkdown(src)
+			if err != nil {
+				t.Fatalf("RenderMarkdown() error = %v", err)
+			}
+			lower := strings.ToLower(out)
+			for _, banned := range []string{"<script", "onerror", "javascript:"} {
+				if strings.Contains(lower, banned) {
+					t.Errorf("output %q should not contain %q", out, banned)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package platform
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Gauge is a metric whose value is read each time it is scraped.
+type Gauge struct {
+	Name  string
+	Help  string
+	Value func() float64
+}
+
+// MetricsHandler serves gauges in the Prometheus text exposition format,
+// in the order given.
+func MetricsHandler(gauges ...Gauge) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		var b strings.Builder
+		for _, g := range gauges {
+			fmt.Fprintf(&b, "# HELP %s %s\n", g.Name, g.Help)
+			fmt.Fprintf(&b, "# TYPE %s gauge\n", g.Name)
+			fmt.Fprintf(&b, "%s %s\n", g.Name, strconv.FormatFloat(g.Value(), 'g', -1, 64))
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(b.String()))
+	}
+}
@@ -0,0 +1,36 @@
+package platform
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetricsHandlerWritesGauges(t *testing.T) {
+	depth := 2.0
+	h := MetricsHandler(
+		Gauge{Name: "quokka_queue_depth", Help: "Queued jobs.", Value: func() float64 { return depth }},
+		Gauge{Name: "quokka_oldest_seconds", Help: "Age of the oldest job.", Value: func() float64 { return 1.5 }},
+	)
+
+	depth = 7
+	rr := httptest.NewRecorder()
+	h(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	if got := rr.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q, want the Prometheus text format", got)
+	}
+	want := "# HELP quokka_queue_depth Queued jobs.\n" +
+		"# TYPE quokka_queue_depth gauge\n" +
+		"quokka_queue_depth 7\n" +
+		"# HELP quokka_oldest_seconds Age of the oldest job.\n" +
+		"# TYPE quokka_oldest_seconds gauge\n" +
+		"quokka_oldest_seconds 1.5\n"
+	if rr.Body.String() != want {
+		t.Errorf("body =\n%s\nwant\n%s", rr.Body.String(), want)
+	}
+}
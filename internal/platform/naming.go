@@ -0,0 +1,264 @@
+package platform
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode"
+)
+
+// FieldNaming is the convention JSON field names are written in.
+type FieldNaming string
+
+const (
+	// SnakeCase is the convention the API's types are declared in.
+	SnakeCase FieldNaming = "snake_case"
+	// CamelCase renames unix_name to unixName, created_at to createdAt.
+	CamelCase FieldNaming = "camelCase"
+)
+
+// MaxRenamedBody caps the request bodies JSONFieldNaming reads to rename
+// their fields.
+const MaxRenamedBody = 1 << 20
+
+// JSONFieldNaming renames the top-level fields of JSON responses to the
+// convention a client asks for with an Accept profile, e.g.
+// "application/json; profile=camelCase", falling back to def. Top-level
+// camelCase fields of application/json request bodies are renamed to
+// snake_case whatever the convention, so handlers accept both; such a body
+// larger than maxBody is refused with 413. Other request bodies, and
+// responses that are not JSON, such as event streams, pass through
+// untouched. The body is read before the handler runs, so mount this
+// behind the rate and in-flight limits.
+func JSONFieldNaming(def FieldNaming, maxBody int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept")
+			if r.Body != nil && r.Body != http.NoBody && isJSONRequest(r) {
+				body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBody))
+				if err != nil {
+					if errors.As(err, new(*http.MaxBytesError)) {
+						RespondError(w, http.StatusRequestEntityTooLarge, "BODY_TOO_LARGE", fmt.Sprintf("request body exceeds %d bytes", maxBody))
+						return
+					}
+					RespondError(w, http.StatusBadRequest, "INVALID_BODY", "failed to read request body")
+					return
+				}
+				if renamed, ok := renameTopLevelFields(body, snakeCase); ok {
+					body = renamed
+				}
+				r.Body = io.NopCloser(bytes.NewReader(body))
+				r.ContentLength = int64(len(body))
+			}
+
+			if requestedFieldNaming(r, def) != CamelCase {
+				next.ServeHTTP(w, r)
+				return
+			}
+			cw := &camelCaseWriter{ResponseWriter: w}
+			next.ServeHTTP(cw, r)
+			cw.finish()
+		})
+	}
+}
+
+// isJSONRequest reports whether r's body is declared application/json.
+func isJSONRequest(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "application/json"
+}
+
+// requestedFieldNaming returns the convention named by the first profile
+// parameter of r's Accept header, or def.
+func requestedFieldNaming(r *http.Request, def FieldNaming) FieldNaming {
+	for _, accept := range r.Header.Values("Accept") {
+		for part := range strings.SplitSeq(accept, ",") {
+			_, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+			if err != nil {
+				continue
+			}
+			switch FieldNaming(params["profile"]) {
+			case CamelCase:
+				return CamelCase
+			case SnakeCase:
+				return SnakeCase
+			}
+		}
+	}
+	return def
+}
+
+// camelCaseWriter holds back a JSON response until the handler is done, so
+// its fields can be renamed; anything else is written straight through.
+type camelCaseWriter struct {
+	http.ResponseWriter
+	status    int
+	decided   bool
+	buffering bool
+	buf       bytes.Buffer
+}
+
+func (cw *camelCaseWriter) WriteHeader(status int) {
+	if cw.decided {
+		return
+	}
+	cw.decided = true
+	cw.status = status
+	mediaType, _, _ := mime.ParseMediaType(cw.Header().Get("Content-Type"))
+	cw.buffering = mediaType == "application/json"
+	if !cw.buffering {
+		cw.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (cw *camelCaseWriter) Write(p []byte) (int, error) {
+	if !cw.decided {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.buffering {
+		return cw.buf.Write(p)
+	}
+	return cw.ResponseWriter.Write(p)
+}
+
+// Flush implements http.Flusher for responses written straight through.
+func (cw *camelCaseWriter) Flush() {
+	if cw.buffering {
+		return
+	}
+	_ = http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (cw *camelCaseWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// finish writes out a held-back response with its fields renamed.
+func (cw *camelCaseWriter) finish() {
+	if !cw.buffering {
+		return
+	}
+	body := cw.buf.Bytes()
+	if renamed, ok := renameTopLevelFields(body, camelCase); ok {
+		body = renamed
+	}
+	cw.Header().Del("Content-Length")
+	cw.ResponseWriter.WriteHeader(cw.status)
+	_, _ = cw.ResponseWriter.Write(body)
+}
+
+// renameTopLevelFields renames the fields of a JSON object, or of each
+// object in a JSON array, keeping their order and values. ok is false if
+// data is neither. Pure function.
+func renameTopLevelFields(data []byte, rename func(string) string) ([]byte, bool) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, false
+	}
+	switch trimmed[0] {
+	case '{':
+		out, err := renameObjectFields(trimmed, rename)
+		if err != nil {
+			return nil, false
+		}
+		return append(out, '\n'), true
+	case '[':
+		var items []json.RawMessage
+		if err := json.Unmarshal(trimmed, &items); err != nil {
+			return nil, false
+		}
+		for i, item := range items {
+			if out, err := renameObjectFields(item, rename); err == nil {
+				items[i] = out
+			}
+		}
+		out, err := json.Marshal(items)
+		if err != nil {
+			return nil, false
+		}
+		return append(out, '\n'), true
+	}
+	return nil, false
+}
+
+var errNotObject = errors.New("not a JSON object")
+
+// renameObjectFields renames the fields of the JSON object in data.
+// Pure function.
+func renameObjectFields(data []byte, rename func(string) string) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, errNotObject
+	}
+
+	var out bytes.Buffer
+	out.WriteByte('{')
+	for first := true; dec.More(); first = false {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		key, err := json.Marshal(rename(tok.(string)))
+		if err != nil {
+			return nil, err
+		}
+		if !first {
+			out.WriteByte(',')
+		}
+		out.Write(key)
+		out.WriteByte(':')
+		out.Write(value)
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	out.WriteByte('}')
+	return out.Bytes(), nil
+}
+
+// camelCase converts a snake_case name: unix_name becomes unixName.
+// Pure function.
+func camelCase(name string) string {
+	parts := strings.Split(name, "_")
+	var b strings.Builder
+	b.WriteString(parts[0])
+	for _, part := range parts[1:] {
+		if part == "" {
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]))
+		b.WriteString(part[1:])
+	}
+	return b.String()
+}
+
+// snakeCase converts a camelCase name, treating a run of capitals as one
+// word: unixName becomes unix_name and orgID org_id. Names without
+// capitals are returned as-is. Pure function.
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			prevLower := i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]))
+			endOfRun := i > 0 && unicode.IsUpper(runes[i-1]) && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || endOfRun {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
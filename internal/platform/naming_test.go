@@ -0,0 +1,195 @@
+package platform
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// echoProject decodes a project and writes it back, the way handlers do.
+func echoProject(w http.ResponseWriter, r *http.Request) {
+	var p struct {
+		UnixName string            `json:"unix_name"`
+		OrgID    string            `json:"org_id"`
+		Labels   map[string]string `json:"labels"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		RespondError(w, http.StatusBadRequest, "INVALID_BODY", err.Error())
+		return
+	}
+	RespondJSON(w, http.StatusOK, p)
+}
+
+func TestJSONFieldNamingRoundTrips(t *testing.T) {
+	tests := []struct {
+		name   string
+		def    FieldNaming
+		accept string
+		body   string
+		want   string
+	}{
+		{
+			name: "snake_case by default",
+			def:  SnakeCase,
+			body: `{"unix_name":"alpha","org_id":"o-1","labels":{"team_name":"ops"}}`,
+			want: `{"unix_name":"alpha","org_id":"o-1","labels":{"team_name":"ops"}}`,
+		},
+		{
+			name:   "camelCase by profile",
+			def:    SnakeCase,
+			accept: `application/json; profile=camelCase`,
+			body:   `{"unixName":"alpha","orgID":"o-1","labels":{"team_name":"ops"}}`,
+			want:   `{"unixName":"alpha","orgId":"o-1","labels":{"team_name":"ops"}}`,
+		},
+		{
+			name: "camelCase by config",
+			def:  CamelCase,
+			body: `{"unixName":"alpha","org_id":"o-1","labels":null}`,
+			want: `{"unixName":"alpha","orgId":"o-1","labels":null}`,
+		},
+		{
+			name:   "snake_case profile overrides config",
+			def:    CamelCase,
+			accept: `text/html, application/json;profile="snake_case"`,
+			body:   `{"unixName":"alpha","orgId":"o-1","labels":null}`,
+			want:   `{"unix_name":"alpha","org_id":"o-1","labels":null}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := JSONFieldNaming(tt.def, MaxRenamedBody)(http.HandlerFunc(echoProject))
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/projects", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+			}
+			if got := strings.TrimSpace(rr.Body.String()); got != tt.want {
+				t.Errorf("body = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONFieldNamingRequestBodies(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantCode    int
+		wantBody    string
+	}{
+		{
+			name:        "json is renamed",
+			contentType: "application/json; charset=utf-8",
+			body:        `{"unixName":"alpha"}`,
+			wantCode:    http.StatusOK,
+			wantBody:    `{"unix_name":"alpha"}`,
+		},
+		{
+			name:        "other media types pass through",
+			contentType: "application/gzip",
+			body:        `{"unixName":"alpha"}`,
+			wantCode:    http.StatusOK,
+			wantBody:    `{"unixName":"alpha"}`,
+		},
+		{
+			name:        "oversized json is refused",
+			contentType: "application/json",
+			body:        `{"name":"` + strings.Repeat("x", 64) + `"}`,
+			wantCode:    http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:        "oversized other bodies are left to the handler",
+			contentType: "text/plain",
+			body:        strings.Repeat("x", 100),
+			wantCode:    http.StatusOK,
+			wantBody:    strings.Repeat("x", 100),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			h := JSONFieldNaming(SnakeCase, 64)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				got = strings.TrimSpace(string(body))
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/projects", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantCode {
+				t.Fatalf("expected %d, got %d: %s", tt.wantCode, rr.Code, rr.Body.String())
+			}
+			if tt.wantCode == http.StatusOK && got != tt.wantBody {
+				t.Errorf("handler read %s, want %s", got, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestJSONFieldNamingRenamesEachListItem(t *testing.T) {
+	h := JSONFieldNaming(CamelCase, MaxRenamedBody)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		RespondJSON(w, http.StatusCreated, []map[string]string{{"created_at": "now"}, {"unix_name": "a"}})
+	}))
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/projects", nil))
+
+	if rr.Code != http.StatusCreated {
+		t.Errorf("expected 201, got %d", rr.Code)
+	}
+	if got, want := strings.TrimSpace(rr.Body.String()), `[{"createdAt":"now"},{"unixName":"a"}]`; got != want {
+		t.Errorf("body = %s, want %s", got, want)
+	}
+}
+
+func TestJSONFieldNamingPassesEventStreamsThrough(t *testing.T) {
+	h := JSONFieldNaming(CamelCase, MaxRenamedBody)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		sw := NewEventStreamWriter(w)
+		_ = sw.Send("progress", map[string]string{"unix_name": "alpha"})
+	}))
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/projects/p-1/watch", nil))
+
+	if !strings.Contains(rr.Body.String(), `"unix_name":"alpha"`) {
+		t.Errorf("event stream was rewritten: %s", rr.Body.String())
+	}
+	if !rr.Flushed {
+		t.Error("event stream was not flushed through")
+	}
+}
+
+func TestFieldNameConversions(t *testing.T) {
+	tests := []struct {
+		snake, camel string
+	}{
+		{"id", "id"},
+		{"unix_name", "unixName"},
+		{"max_active_projects", "maxActiveProjects"},
+		{"org_id", "orgId"},
+	}
+	for _, tt := range tests {
+		if got := camelCase(tt.snake); got != tt.camel {
+			t.Errorf("camelCase(%q) = %q, want %q", tt.snake, got, tt.camel)
+		}
+		if got := snakeCase(tt.camel); got != tt.snake {
+			t.Errorf("snakeCase(%q) = %q, want %q", tt.camel, got, tt.snake)
+		}
+	}
+	if got := snakeCase("resourceID"); got != "resource_id" {
+		t.Errorf("snakeCase(resourceID) = %q, want resource_id", got)
+	}
+}
@@ -0,0 +1,91 @@
+package platform
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+// ErrInvalidPagination is returned when a request body's limit or offset
+// is not a non-negative 32-bit integer.
+var ErrInvalidPagination = errors.New("invalid pagination")
+
+// Pagination is the limit/offset window of a request body. Values are
+// decoded as json.Number and range-checked, so an out-of-range value fails
+// instead of losing precision in a float64 or wrapping to a negative int32.
+type Pagination struct {
+	Limit  int32 `json:"limit"`
+	Offset int32 `json:"offset"`
+}
+
+// Page is the response body of every list endpoint: one window of a
+// result set, where it starts, and how large the whole set is.
+type Page[T any] struct {
+	Data    []T   `json:"data"`
+	Total   int64 `json:"total"`
+	Limit   int32 `json:"limit"`
+	Offset  int32 `json:"offset"`
+	HasNext bool  `json:"has_next"`
+}
+
+// NewPage returns the page of total items holding data, read from offset
+// with limit. HasNext reports whether items remain past data. A nil data
+// encodes as [] rather than null. Pure function.
+func NewPage[T any](data []T, total int64, limit, offset int32) Page[T] {
+	return Page[T]{
+		Data:    NonNil(data),
+		Total:   total,
+		Limit:   limit,
+		Offset:  offset,
+		HasNext: int64(offset)+int64(len(data)) < total,
+	}
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Absent fields decode to zero.
+func (p *Pagination) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Limit  json.Number `json:"limit"`
+		Offset json.Number `json:"offset"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	limit, err := paginationInt32("limit", raw.Limit)
+	if err != nil {
+		return err
+	}
+	offset, err := paginationInt32("offset", raw.Offset)
+	if err != nil {
+		return err
+	}
+	p.Limit, p.Offset = limit, offset
+	return nil
+}
+
+// paginationInt32 parses n as an integer in [0, MaxInt32]; empty is zero.
+// Pure function.
+func paginationInt32(field string, n json.Number) (int32, error) {
+	if n == "" {
+		return 0, nil
+	}
+	v, err := strconv.ParseInt(n.String(), 10, 32)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("%w: %s must be an integer between 0 and %d, got %s",
+			ErrInvalidPagination, field, math.MaxInt32, n)
+	}
+	return int32(v), nil
+}
+
+// RespondDecodeError reports a request body that failed to decode: 400
+// INVALID_PAGINATION for a bad pagination window, 400 INVALID_JSON otherwise.
+func RespondDecodeError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrInvalidPagination) {
+		RespondError(w, http.StatusBadRequest, "INVALID_PAGINATION", err.Error())
+		return
+	}
+	RespondError(w, http.StatusBadRequest, "INVALID_JSON", "invalid JSON")
+}
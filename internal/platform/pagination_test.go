@@ -0,0 +1,111 @@
+package platform
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPaginationUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		body    string
+		want    Pagination
+		wantErr bool
+	}{
+		{body: `{}`, want: Pagination{}},
+		{body: `{"limit":25,"offset":50}`, want: Pagination{Limit: 25, Offset: 50}},
+		{body: `{"limit":2147483647}`, want: Pagination{Limit: 2147483647}},
+		{body: `{"limit":2147483648}`, wantErr: true},
+		{body: `{"offset":9007199254740993}`, wantErr: true},
+		{body: `{"limit":1e3}`, wantErr: true},
+		{body: `{"limit":2.5}`, wantErr: true},
+		{body: `{"offset":-1}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		var got Pagination
+		err := json.Unmarshal([]byte(tt.body), &got)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidPagination) {
+				t.Errorf("%s: expected ErrInvalidPagination, got %v", tt.body, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: got %+v, %v; want %+v", tt.body, got, err, tt.want)
+		}
+	}
+}
+
+func TestRespondDecodeErrorReportsOverflowAs400(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Query string     `json:"query"`
+			Page  Pagination `json:"page"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			RespondDecodeError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+
+	rr := httptest.NewRecorder()
+	handler(rr, httptest.NewRequest(http.MethodPost, "/search",
+		strings.NewReader(`{"query":"alpha","page":{"limit":4294967296}}`)))
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+	var body APIError
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode response body: %v", err)
+	}
+	if body.Error.Code != "INVALID_PAGINATION" {
+		t.Errorf("error code = %q, want INVALID_PAGINATION", body.Error.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	handler(rr, httptest.NewRequest(http.MethodPost, "/search", strings.NewReader(`{"query":`)))
+	if !strings.Contains(rr.Body.String(), "INVALID_JSON") {
+		t.Errorf("expected malformed JSON to stay INVALID_JSON, got %s", rr.Body.String())
+	}
+}
+
+func TestNewPageHasNext(t *testing.T) {
+	tests := []struct {
+		name          string
+		items         int
+		total         int64
+		limit, offset int32
+		want          bool
+	}{
+		{name: "first of several", items: 10, total: 25, limit: 10, offset: 0, want: true},
+		{name: "middle", items: 10, total: 25, limit: 10, offset: 10, want: true},
+		{name: "last", items: 5, total: 25, limit: 10, offset: 20, want: false},
+		{name: "exactly full", items: 10, total: 10, limit: 10, offset: 0, want: false},
+		{name: "past the end", items: 0, total: 25, limit: 10, offset: 30, want: false},
+		{name: "empty", items: 0, total: 0, limit: 10, offset: 0, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := NewPage(make([]int, tt.items), tt.total, tt.limit, tt.offset)
+			if page.HasNext != tt.want {
+				t.Errorf("HasNext = %v, want %v", page.HasNext, tt.want)
+			}
+		})
+	}
+}
+
+func TestPageJSONShape(t *testing.T) {
+	raw, err := json.Marshal(NewPage[string](nil, 0, 20, 0))
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	const want = `{"data":[],"total":0,"limit":20,"offset":0,"has_next":false}`
+	if string(raw) != want {
+		t.Errorf("page encodes as %s, want %s", raw, want)
+	}
+}
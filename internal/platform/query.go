@@ -0,0 +1,151 @@
+package platform
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Query reads typed values from a request's query string. Malformed values
+// are collected instead of returned one by one; check Err once every value
+// has been read.
+type Query struct {
+	values url.Values
+	fields []FieldError
+}
+
+// QueryError lists every malformed query parameter of a request.
+type QueryError struct {
+	Fields []FieldError
+}
+
+func (e *QueryError) Error() string {
+	problems := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		problems[i] = f.Field + " " + f.Message
+	}
+	return "invalid query parameters: " + strings.Join(problems, "; ")
+}
+
+// QueryParams returns a Query over r's query string.
+func QueryParams(r *http.Request) *Query {
+	return &Query{values: r.URL.Query()}
+}
+
+// Int32 returns the named parameter as an int32, or def if it is absent.
+func (q *Query) Int32(name string, def int32) int32 {
+	raw := q.values.Get(name)
+	if raw == "" {
+		return def
+	}
+	n, err := strconv.ParseInt(raw, 10, 32)
+	if err != nil {
+		q.invalid(name, "int32", "must be a 32-bit integer")
+		return def
+	}
+	return int32(n)
+}
+
+// Int64 returns the named parameter as an int64, or def if it is absent.
+func (q *Query) Int64(name string, def int64) int64 {
+	raw := q.values.Get(name)
+	if raw == "" {
+		return def
+	}
+	n, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		q.invalid(name, "int64", "must be a 64-bit integer")
+		return def
+	}
+	return n
+}
+
+// Bool returns the named parameter as a bool, or def if it is absent.
+// Accepts the values understood by strconv.ParseBool.
+func (q *Query) Bool(name string, def bool) bool {
+	raw := q.values.Get(name)
+	if raw == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(raw)
+	if err != nil {
+		q.invalid(name, "bool", "must be true or false")
+		return def
+	}
+	return b
+}
+
+// Time returns the named parameter parsed as RFC 3339, or def if it is
+// absent.
+func (q *Query) Time(name string, def time.Time) time.Time {
+	raw := q.values.Get(name)
+	if raw == "" {
+		return def
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		q.invalid(name, "time", "must be an RFC 3339 timestamp")
+		return def
+	}
+	return t
+}
+
+// String returns the named parameter, trimmed, or def if it is absent or
+// blank.
+func (q *Query) String(name, def string) string {
+	raw := strings.TrimSpace(q.values.Get(name))
+	if raw == "" {
+		return def
+	}
+	return raw
+}
+
+// StringSlice returns the comma-separated values of the named parameter,
+// trimmed and without empty entries. Repeated parameters are combined.
+// Returns nil if the parameter is absent.
+func (q *Query) StringSlice(name string) []string {
+	var out []string
+	for _, raw := range q.values[name] {
+		for _, part := range strings.Split(raw, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				out = append(out, part)
+			}
+		}
+	}
+	return out
+}
+
+// Err reports every malformed parameter read so far as a *QueryError, or
+// nil if there were none.
+func (q *Query) Err() error {
+	if len(q.fields) == 0 {
+		return nil
+	}
+	return &QueryError{Fields: q.fields}
+}
+
+func (q *Query) invalid(name, rule, message string) {
+	q.fields = append(q.fields, FieldError{Field: name, Rule: rule, Message: message})
+}
+
+// RespondQueryError writes a 400 INVALID_QUERY_PARAM listing every malformed
+// parameter of a Query.Err error. Other errors are reported with their
+// message only.
+func RespondQueryError(w http.ResponseWriter, err error) {
+	var qerr *QueryError
+	if !errors.As(err, &qerr) {
+		RespondError(w, http.StatusBadRequest, "INVALID_QUERY_PARAM", err.Error())
+		return
+	}
+	RespondJSON(w, http.StatusBadRequest, APIError{
+		Error: ErrorDetail{
+			Code:    "INVALID_QUERY_PARAM",
+			Message: fmt.Sprintf("%d query parameter(s) are invalid", len(qerr.Fields)),
+			Fields:  qerr.Fields,
+		},
+	})
+}
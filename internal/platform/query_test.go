@@ -0,0 +1,177 @@
+package platform
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newQuery(rawQuery string) *Query {
+	return QueryParams(httptest.NewRequest(http.MethodGet, "/?"+rawQuery, nil))
+}
+
+func TestQueryInt32(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    int32
+		wantErr bool
+	}{
+		{query: "", want: 25},
+		{query: "limit=10", want: 10},
+		{query: "limit=-3", want: -3},
+		{query: "limit=ten", want: 25, wantErr: true},
+		{query: "limit=3000000000", want: 25, wantErr: true},
+	}
+	for _, tt := range tests {
+		q := newQuery(tt.query)
+		if got := q.Int32("limit", 25); got != tt.want {
+			t.Errorf("%q: Int32() = %d, want %d", tt.query, got, tt.want)
+		}
+		if (q.Err() != nil) != tt.wantErr {
+			t.Errorf("%q: Err() = %v, wantErr %v", tt.query, q.Err(), tt.wantErr)
+		}
+	}
+}
+
+func TestQueryInt64(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    int64
+		wantErr bool
+	}{
+		{query: "", want: 7},
+		{query: "since=3000000000", want: 3000000000},
+		{query: "since=-1", want: -1},
+		{query: "since=soon", want: 7, wantErr: true},
+	}
+	for _, tt := range tests {
+		q := newQuery(tt.query)
+		if got := q.Int64("since", 7); got != tt.want {
+			t.Errorf("%q: Int64() = %d, want %d", tt.query, got, tt.want)
+		}
+		if (q.Err() != nil) != tt.wantErr {
+			t.Errorf("%q: Err() = %v, wantErr %v", tt.query, q.Err(), tt.wantErr)
+		}
+	}
+}
+
+func TestQueryBool(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    bool
+		wantErr bool
+	}{
+		{query: "", want: false},
+		{query: "stream=true", want: true},
+		{query: "stream=1", want: true},
+		{query: "stream=false", want: false},
+		{query: "stream=maybe", want: false, wantErr: true},
+	}
+	for _, tt := range tests {
+		q := newQuery(tt.query)
+		if got := q.Bool("stream", false); got != tt.want {
+			t.Errorf("%q: Bool() = %v, want %v", tt.query, got, tt.want)
+		}
+		if (q.Err() != nil) != tt.wantErr {
+			t.Errorf("%q: Err() = %v, wantErr %v", tt.query, q.Err(), tt.wantErr)
+		}
+	}
+}
+
+func TestQueryTime(t *testing.T) {
+	def := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		query   string
+		want    time.Time
+		wantErr bool
+	}{
+		{query: "", want: def},
+		{query: "since=2026-03-04T05:06:07Z", want: time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)},
+		{query: "since=yesterday", want: def, wantErr: true},
+	}
+	for _, tt := range tests {
+		q := newQuery(tt.query)
+		if got := q.Time("since", def); !got.Equal(tt.want) {
+			t.Errorf("%q: Time() = %v, want %v", tt.query, got, tt.want)
+		}
+		if (q.Err() != nil) != tt.wantErr {
+			t.Errorf("%q: Err() = %v, wantErr %v", tt.query, q.Err(), tt.wantErr)
+		}
+	}
+}
+
+func TestQueryString(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{query: "", want: "any"},
+		{query: "node=%20%20", want: "any"},
+		{query: "node=pve-01", want: "pve-01"},
+		{query: "node=%20pve-01%20", want: "pve-01"},
+	}
+	for _, tt := range tests {
+		if got := newQuery(tt.query).String("node", "any"); got != tt.want {
+			t.Errorf("%q: String() = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestQueryStringSlice(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{query: "", want: nil},
+		{query: "status=failed", want: []string{"failed"}},
+		{query: "status=failed,%20provisioning,", want: []string{"failed", "provisioning"}},
+		{query: "status=failed&status=pending", want: []string{"failed", "pending"}},
+	}
+	for _, tt := range tests {
+		if got := newQuery(tt.query).StringSlice("status"); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: StringSlice() = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestQueryAccumulatesEveryError(t *testing.T) {
+	q := newQuery("limit=x&offset=y&stream=z")
+	q.Int32("limit", 0)
+	q.Int32("offset", 0)
+	q.Bool("stream", false)
+
+	var qerr *QueryError
+	if !errors.As(q.Err(), &qerr) {
+		t.Fatalf("expected a *QueryError, got %v", q.Err())
+	}
+	var got []string
+	for _, f := range qerr.Fields {
+		got = append(got, f.Field)
+	}
+	if want := []string{"limit", "offset", "stream"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("fields = %v, want %v", got, want)
+	}
+}
+
+func TestRespondQueryErrorListsFields(t *testing.T) {
+	q := newQuery("limit=x")
+	q.Int32("limit", 0)
+
+	rr := httptest.NewRecorder()
+	RespondQueryError(rr, q.Err())
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+	var body APIError
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode response body: %v", err)
+	}
+	if body.Error.Code != "INVALID_QUERY_PARAM" || len(body.Error.Fields) != 1 || body.Error.Fields[0].Field != "limit" {
+		t.Errorf("unexpected error body: %+v", body.Error)
+	}
+}
@@ -0,0 +1,88 @@
+package platform
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// SlowQueryTracer is a pgx.QueryTracer logging every query that takes at
+// least its threshold, with its SQL, sqlc operation name and duration.
+// Query arguments are never logged, as they may hold secrets.
+type SlowQueryTracer struct {
+	threshold time.Duration
+	log       *slog.Logger
+	now       func() time.Time
+}
+
+// NewSlowQueryTracer returns a tracer logging queries slower than
+// threshold to logger, or slog.Default() when it is nil.
+func NewSlowQueryTracer(threshold time.Duration, logger *slog.Logger) *SlowQueryTracer {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &SlowQueryTracer{threshold: threshold, log: logger, now: time.Now}
+}
+
+type slowQueryKey struct{}
+
+// tracedQuery is what TraceQueryStart hands to TraceQueryEnd.
+type tracedQuery struct {
+	sql     string
+	started time.Time
+}
+
+// TraceQueryStart implements pgx.QueryTracer.
+func (t *SlowQueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, slowQueryKey{}, tracedQuery{sql: data.SQL, started: t.now()})
+}
+
+// TraceQueryEnd implements pgx.QueryTracer.
+func (t *SlowQueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	q, ok := ctx.Value(slowQueryKey{}).(tracedQuery)
+	if !ok {
+		return
+	}
+	elapsed := t.now().Sub(q.started)
+	if elapsed < t.threshold {
+		return
+	}
+
+	attrs := []any{
+		"operation", queryOperation(q.sql),
+		"sql", compactSQL(q.sql),
+		"duration_ms", elapsed.Milliseconds(),
+	}
+	if data.Err != nil {
+		attrs = append(attrs, "error", data.Err.Error())
+	}
+	t.log.WarnContext(ctx, "slow query", attrs...)
+}
+
+// sqlcName matches the comment sqlc starts every generated query with.
+var sqlcName = regexp.MustCompile(`^\s*-- name: (\w+)`)
+
+// queryOperation returns the sqlc name of sql, or "" for hand-written SQL.
+// Pure function.
+func queryOperation(sql string) string {
+	if m := sqlcName.FindStringSubmatch(sql); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// compactSQL drops sql's comment lines and collapses its whitespace, so it
+// fits on one log line. Pure function.
+func compactSQL(sql string) string {
+	var kept []string
+	for line := range strings.Lines(sql) {
+		if !strings.HasPrefix(strings.TrimSpace(line), "--") {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(strings.Fields(strings.Join(kept, " ")), " ")
+}
@@ -0,0 +1,152 @@
+package platform
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimiter allows each client a fixed number of requests per window,
+// counted from the client's first request in it. Clients are told their
+// budget on every response, so they can slow down before they are refused.
+type RateLimiter struct {
+	limit  int
+	window time.Duration
+	now    func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*rateBucket
+	nextSweep time.Time
+}
+
+type rateBucket struct {
+	used  int
+	reset time.Time
+}
+
+// RateLimitState is a client's budget in its current window.
+type RateLimitState struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// NewRateLimiter returns a limiter allowing limit requests per window to
+// each client. A limit of zero or less disables it.
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		limit:   limit,
+		window:  window,
+		now:     time.Now,
+		buckets: make(map[string]*rateBucket),
+	}
+}
+
+// Enabled reports whether l limits anything.
+func (l *RateLimiter) Enabled() bool {
+	return l != nil && l.limit > 0
+}
+
+// Take spends one request from key's budget. ok is false, and nothing is
+// spent, if the budget is already exhausted.
+func (l *RateLimiter) Take(key string) (state RateLimitState, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.bucket(key)
+	if b.used >= l.limit {
+		return l.state(b), false
+	}
+	b.used++
+	return l.state(b), true
+}
+
+// Refund gives back one request spent from key's budget, for a request
+// that turned out not to count. Nothing is given back once the window it
+// was spent in ended.
+func (l *RateLimiter) Refund(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if b, ok := l.buckets[key]; ok && l.now().Before(b.reset) && b.used > 0 {
+		b.used--
+	}
+}
+
+// State returns key's budget without spending any of it.
+func (l *RateLimiter) State(key string) RateLimitState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.state(l.bucket(key))
+}
+
+// bucket returns key's bucket for the current window, starting a new one
+// if it has none or its window ended. Expired buckets of other clients are
+// dropped at most once per window. Callers hold l.mu.
+func (l *RateLimiter) bucket(key string) *rateBucket {
+	now := l.now()
+	if !now.Before(l.nextSweep) {
+		for k, b := range l.buckets {
+			if !now.Before(b.reset) {
+				delete(l.buckets, k)
+			}
+		}
+		l.nextSweep = now.Add(l.window)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok || !now.Before(b.reset) {
+		b = &rateBucket{reset: now.Add(l.window)}
+		l.buckets[key] = b
+	}
+	return b
+}
+
+func (l *RateLimiter) state(b *rateBucket) RateLimitState {
+	return RateLimitState{Limit: l.limit, Remaining: l.limit - b.used, Reset: b.reset}
+}
+
+// Middleware limits requests per client IP address. Every response carries
+// X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset, the Unix
+// time in seconds at which the budget refills; requests over the budget
+// are answered with 429 RATE_LIMITED and a Retry-After. A disabled limiter
+// passes requests through untouched.
+func (l *RateLimiter) Middleware(next http.Handler) http.Handler {
+	if !l.Enabled() {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		state, ok := l.Take(clientKey(r))
+
+		h := w.Header()
+		h.Set("X-RateLimit-Limit", strconv.Itoa(state.Limit))
+		h.Set("X-RateLimit-Remaining", strconv.Itoa(state.Remaining))
+		h.Set("X-RateLimit-Reset", strconv.FormatInt(state.Reset.Unix(), 10))
+		if !ok {
+			SetRetryAfter(w, state.Reset.Sub(l.now()))
+			RespondError(w, http.StatusTooManyRequests, "RATE_LIMITED", "rate limit exceeded, retry later")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// SetRetryAfter tells the client to retry after wait, in whole seconds
+// rounded up and at least one.
+func SetRetryAfter(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(max(1, int(math.Ceil(wait.Seconds())))))
+}
+
+// clientKey identifies the client that sent r by IP address, without the
+// port, which changes between connections. Pure function.
+func clientKey(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
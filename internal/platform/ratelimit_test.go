@@ -0,0 +1,126 @@
+package platform
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// newTestRateLimiter returns a limiter whose clock only moves when told to.
+func newTestRateLimiter(limit int, window time.Duration) (*RateLimiter, *time.Time) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	l := NewRateLimiter(limit, window)
+	l.now = func() time.Time { return now }
+	return l, &now
+}
+
+func rateLimitedRequest(handler http.Handler, remoteAddr string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = remoteAddr
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestRateLimiterHeadersDecrementAcrossRequests(t *testing.T) {
+	l, now := newTestRateLimiter(3, time.Minute)
+	handler := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	reset := strconv.FormatInt(now.Add(time.Minute).Unix(), 10)
+
+	for i, want := range []string{"2", "1", "0"} {
+		rr := rateLimitedRequest(handler, "192.0.2.1:5000"+strconv.Itoa(i))
+		if rr.Code != http.StatusNoContent {
+			t.Fatalf("request %d: expected 204, got %d", i+1, rr.Code)
+		}
+		if got := rr.Header().Get("X-RateLimit-Limit"); got != "3" {
+			t.Errorf("request %d: X-RateLimit-Limit = %q, want 3", i+1, got)
+		}
+		if got := rr.Header().Get("X-RateLimit-Remaining"); got != want {
+			t.Errorf("request %d: X-RateLimit-Remaining = %q, want %s", i+1, got, want)
+		}
+		if got := rr.Header().Get("X-RateLimit-Reset"); got != reset {
+			t.Errorf("request %d: X-RateLimit-Reset = %q, want %s", i+1, got, reset)
+		}
+	}
+
+	*now = now.Add(20 * time.Second)
+	rr := rateLimitedRequest(handler, "192.0.2.1:50010")
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 over the limit, got %d", rr.Code)
+	}
+	if got := rr.Header().Get("X-RateLimit-Remaining"); got != "0" {
+		t.Errorf("X-RateLimit-Remaining = %q over the limit, want 0", got)
+	}
+	if got := rr.Header().Get("Retry-After"); got != "40" {
+		t.Errorf("Retry-After = %q, want 40", got)
+	}
+
+	*now = now.Add(40 * time.Second)
+	rr = rateLimitedRequest(handler, "192.0.2.1:50011")
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("expected the budget to refill after the window, got %d", rr.Code)
+	}
+	if got := rr.Header().Get("X-RateLimit-Remaining"); got != "2" {
+		t.Errorf("X-RateLimit-Remaining = %q in a new window, want 2", got)
+	}
+}
+
+func TestRateLimiterBudgetsClientsSeparately(t *testing.T) {
+	l, _ := newTestRateLimiter(1, time.Minute)
+	handler := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	if rr := rateLimitedRequest(handler, "192.0.2.1:1234"); rr.Code != http.StatusNoContent {
+		t.Fatalf("first client: expected 204, got %d", rr.Code)
+	}
+	if rr := rateLimitedRequest(handler, "192.0.2.1:1234"); rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("first client again: expected 429, got %d", rr.Code)
+	}
+	if rr := rateLimitedRequest(handler, "198.51.100.7:1234"); rr.Code != http.StatusNoContent {
+		t.Fatalf("second client: expected 204, got %d", rr.Code)
+	}
+	if got := l.State("192.0.2.1").Remaining; got != 0 {
+		t.Errorf("first client has %d remaining, want 0", got)
+	}
+}
+
+func TestRateLimiterRefund(t *testing.T) {
+	l, now := newTestRateLimiter(1, time.Minute)
+
+	if _, ok := l.Take("client"); !ok {
+		t.Fatal("first take refused")
+	}
+	l.Refund("client")
+	if _, ok := l.Take("client"); !ok {
+		t.Fatal("take after a refund refused")
+	}
+
+	// A refund after the window ended does not carry into the next one.
+	*now = now.Add(time.Minute)
+	l.Refund("client")
+	if _, ok := l.Take("client"); !ok {
+		t.Fatal("take in a new window refused")
+	}
+	if _, ok := l.Take("client"); ok {
+		t.Error("take past the limit allowed")
+	}
+}
+
+func TestRateLimiterDisabled(t *testing.T) {
+	handler := NewRateLimiter(0, time.Minute).Middleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	rr := rateLimitedRequest(handler, "192.0.2.1:1234")
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", rr.Code)
+	}
+	if got := rr.Header().Get("X-RateLimit-Limit"); got != "" {
+		t.Errorf("a disabled limiter set X-RateLimit-Limit = %q", got)
+	}
+}
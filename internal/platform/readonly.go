@@ -0,0 +1,87 @@
+package platform
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+)
+
+// ReadOnlyHeader is set to "true" on responses served while read-only mode
+// is enabled, so clients can warn their users before a write is rejected.
+const ReadOnlyHeader = "X-Read-Only"
+
+// ReadOnly is a goroutine-safe switch that, when enabled, rejects every
+// mutating request while reads keep being served.
+type ReadOnly struct {
+	enabled atomic.Bool
+}
+
+// readOnlyState is the body of the admin read-only endpoint.
+type readOnlyState struct {
+	Enabled *bool `json:"enabled"`
+}
+
+// NewReadOnly creates a switch in the given initial state.
+func NewReadOnly(enabled bool) *ReadOnly {
+	ro := &ReadOnly{}
+	ro.enabled.Store(enabled)
+	return ro
+}
+
+// Enabled reports whether writes are currently blocked.
+func (ro *ReadOnly) Enabled() bool {
+	return ro.enabled.Load()
+}
+
+// Set turns read-only mode on or off.
+func (ro *ReadOnly) Set(enabled bool) {
+	ro.enabled.Store(enabled)
+}
+
+// Middleware answers 503 READ_ONLY to POST, PUT, PATCH and DELETE requests
+// while read-only mode is enabled, and marks the reads it still serves with
+// ReadOnlyHeader.
+func (ro *ReadOnly) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ro.Enabled() {
+			w.Header().Set(ReadOnlyHeader, "true")
+			if isMutating(r.Method) {
+				RespondError(w, http.StatusServiceUnavailable, "READ_ONLY", "the API is in read-only mode")
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// StatusHandler reports the current state as {"enabled": bool}.
+func (ro *ReadOnly) StatusHandler(w http.ResponseWriter, _ *http.Request) {
+	enabled := ro.Enabled()
+	RespondJSON(w, http.StatusOK, readOnlyState{Enabled: &enabled})
+}
+
+// ToggleHandler sets the state from a {"enabled": bool} body.
+func (ro *ReadOnly) ToggleHandler(w http.ResponseWriter, r *http.Request) {
+	var req readOnlyState
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondError(w, http.StatusBadRequest, "INVALID_JSON", "invalid JSON")
+		return
+	}
+	if req.Enabled == nil {
+		RespondError(w, http.StatusUnprocessableEntity, "VALIDATION_FAILED", "enabled is required")
+		return
+	}
+	ro.Set(*req.Enabled)
+	RespondJSON(w, http.StatusOK, req)
+}
+
+// isMutating reports whether method changes server state.
+// Pure function.
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
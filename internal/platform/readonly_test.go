@@ -0,0 +1,85 @@
+package platform
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func readOnlyServer(ro *ReadOnly) http.Handler {
+	return ro.Middleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+func TestReadOnlyAllowsReadsAndRejectsWrites(t *testing.T) {
+	h := readOnlyServer(NewReadOnly(true))
+
+	get := httptest.NewRecorder()
+	h.ServeHTTP(get, httptest.NewRequest(http.MethodGet, "/projects", nil))
+	if get.Code != http.StatusOK {
+		t.Errorf("GET: expected 200, got %d", get.Code)
+	}
+	if get.Header().Get(ReadOnlyHeader) != "true" {
+		t.Errorf("GET: expected %s: true, got %q", ReadOnlyHeader, get.Header().Get(ReadOnlyHeader))
+	}
+
+	post := httptest.NewRecorder()
+	h.ServeHTTP(post, httptest.NewRequest(http.MethodPost, "/projects", nil))
+	if post.Code != http.StatusServiceUnavailable {
+		t.Fatalf("POST: expected 503, got %d", post.Code)
+	}
+	if !strings.Contains(post.Body.String(), `"READ_ONLY"`) {
+		t.Errorf("POST: expected READ_ONLY code, got %s", post.Body.String())
+	}
+}
+
+func TestReadOnlyAllowsWritesWhenDisabled(t *testing.T) {
+	h := readOnlyServer(NewReadOnly(false))
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/projects/1", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", rr.Code)
+	}
+	if rr.Header().Get(ReadOnlyHeader) != "" {
+		t.Errorf("expected no %s header, got %q", ReadOnlyHeader, rr.Header().Get(ReadOnlyHeader))
+	}
+}
+
+func TestReadOnlyToggleHandler(t *testing.T) {
+	ro := NewReadOnly(false)
+
+	rr := httptest.NewRecorder()
+	ro.ToggleHandler(rr, httptest.NewRequest(http.MethodPut, "/admin/read-only", strings.NewReader(`{"enabled":true}`)))
+	if rr.Code != http.StatusOK || !ro.Enabled() {
+		t.Fatalf("expected read-only to be enabled, got %d enabled=%v", rr.Code, ro.Enabled())
+	}
+
+	rr = httptest.NewRecorder()
+	ro.ToggleHandler(rr, httptest.NewRequest(http.MethodPut, "/admin/read-only", strings.NewReader(`{}`)))
+	if rr.Code != http.StatusUnprocessableEntity || !ro.Enabled() {
+		t.Errorf("expected 422 and unchanged state, got %d enabled=%v", rr.Code, ro.Enabled())
+	}
+}
+
+func TestReadOnlyToggleIsGoroutineSafe(t *testing.T) {
+	ro := NewReadOnly(false)
+	h := readOnlyServer(ro)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func(on bool) {
+			defer wg.Done()
+			ro.Set(on)
+		}(i%2 == 0)
+		go func() {
+			defer wg.Done()
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/projects", nil))
+		}()
+	}
+	wg.Wait()
+}
@@ -1,29 +1,70 @@
 package platform
 
 import (
-	"log"
+	"log/slog"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
 
-// NewRouter initializes and returns a chi.Mux router with common middleware
-func NewRouter() *chi.Mux {
+// NewRouter initializes and returns a chi.Mux router with common middleware.
+// Requests are logged to logger, or slog.Default() when it is nil.
+func NewRouter(logger *slog.Logger) *chi.Mux {
 	r := chi.NewRouter()
 
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
-	r.Use(middleware.Logger)
+	r.Use(RequestLogger(logger))
 	r.Use(middleware.Recoverer)
 
 	return r
 }
 
-func HealthCheckHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	if _, err := w.Write([]byte(`{"status":"ok"}`)); err != nil {
-		log.Printf("failed to write healthcheck response: %v", err)
+// RequestLogger logs one record per request with the concrete path and the
+// chi route pattern that matched it, so /projects/abc and /projects/def
+// aggregate under route=/projects/{id}. The pattern is only complete once
+// routing has finished, so it is read after the handler returns.
+func RequestLogger(logger *slog.Logger) func(http.Handler) http.Handler {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			start := time.Now()
+
+			next.ServeHTTP(ww, r)
+
+			route := ""
+			if rctx := chi.RouteContext(r.Context()); rctx != nil {
+				route = rctx.RoutePattern()
+			}
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			logger.LogAttrs(r.Context(), slog.LevelInfo, "http request",
+				slog.String("request_id", middleware.GetReqID(r.Context())),
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.String("route", route),
+				slog.Int("status", status),
+				slog.Int("bytes", ww.BytesWritten()),
+				slog.Duration("duration", time.Since(start)),
+			)
+		})
+	}
+}
+
+// EnvironmentHeader sets X-Environment on every response so callers can tell
+// which deployment answered.
+func EnvironmentHeader(env string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Environment", env)
+			next.ServeHTTP(w, r)
+		})
 	}
 }
@@ -0,0 +1,58 @@
+package platform
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestEnvironmentHeaderIsSetOnResponses(t *testing.T) {
+	r := NewRouter(slog.New(slog.DiscardHandler))
+	r.Use(EnvironmentHeader("staging"))
+	r.Get("/ping", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	for _, path := range []string{"/ping", "/missing"} {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		if got := rr.Header().Get("X-Environment"); got != "staging" {
+			t.Errorf("%s: X-Environment = %q, want staging", path, got)
+		}
+	}
+}
+
+func TestRequestLoggerRecordsRoutePattern(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewRouter(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	projects := chi.NewRouter()
+	projects.Get("/{id}", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	r.Mount("/projects", projects)
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/projects/abc123", nil))
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("failed to decode log record %q: %v", buf.String(), err)
+	}
+	want := map[string]any{
+		"method": http.MethodGet,
+		"path":   "/projects/abc123",
+		"route":  "/projects/{id}",
+		"status": float64(http.StatusNoContent),
+	}
+	for key, value := range want {
+		if record[key] != value {
+			t.Errorf("%s = %v, want %v", key, record[key], value)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package platform
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// ConnCounter counts the open connections of an http.Server. Install
+// ConnState as the server's ConnState hook.
+type ConnCounter struct {
+	open atomic.Int64
+}
+
+// ConnState implements the http.Server ConnState hook.
+func (c *ConnCounter) ConnState(_ net.Conn, state http.ConnState) {
+	switch state {
+	case http.StateNew:
+		c.open.Add(1)
+	case http.StateClosed, http.StateHijacked:
+		c.open.Add(-1)
+	}
+}
+
+// Open returns the number of connections currently open.
+func (c *ConnCounter) Open() int {
+	return int(c.open.Load())
+}
+
+// ShutdownReport describes what a graceful shutdown drained and what it
+// had to leave behind.
+type ShutdownReport struct {
+	ConnectionsDrained   int
+	ConnectionsAbandoned int
+	JobsCompleted        int
+	JobsAbandoned        int
+	Duration             time.Duration
+}
+
+// Attrs returns the report as log attributes.
+// Pure function.
+func (r ShutdownReport) Attrs() []slog.Attr {
+	return []slog.Attr{
+		slog.Int("connections_drained", r.ConnectionsDrained),
+		slog.Int("connections_abandoned", r.ConnectionsAbandoned),
+		slog.Int("jobs_completed", r.JobsCompleted),
+		slog.Int("jobs_abandoned", r.JobsAbandoned),
+		slog.Duration("duration", r.Duration),
+	}
+}
+
+// Log writes the report to logger at info level.
+func (r ShutdownReport) Log(ctx context.Context, logger *slog.Logger) {
+	logger.LogAttrs(ctx, slog.LevelInfo, "shutdown report", r.Attrs()...)
+}
@@ -0,0 +1,57 @@
+package platform
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestShutdownReportLogsCounts(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	ShutdownReport{
+		ConnectionsDrained:   7,
+		ConnectionsAbandoned: 1,
+		JobsCompleted:        3,
+		JobsAbandoned:        2,
+		Duration:             1500 * time.Millisecond,
+	}.Log(context.Background(), logger)
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("failed to decode log record: %v", err)
+	}
+	want := map[string]any{
+		"level":                 "INFO",
+		"msg":                   "shutdown report",
+		"connections_drained":   float64(7),
+		"connections_abandoned": float64(1),
+		"jobs_completed":        float64(3),
+		"jobs_abandoned":        float64(2),
+		"duration":              float64(1500 * time.Millisecond),
+	}
+	for key, value := range want {
+		if record[key] != value {
+			t.Errorf("%s = %v, want %v", key, record[key], value)
+		}
+	}
+}
+
+func TestConnCounterTracksOpenConnections(t *testing.T) {
+	var c ConnCounter
+	for _, state := range []http.ConnState{
+		http.StateNew, http.StateNew, http.StateNew,
+		http.StateActive, http.StateIdle,
+		http.StateClosed, http.StateHijacked,
+	} {
+		c.ConnState(nil, state)
+	}
+	if got := c.Open(); got != 1 {
+		t.Errorf("Open() = %d, want 1", got)
+	}
+}
@@ -0,0 +1,68 @@
+package platform
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// EventStreamWriter writes a response as server-sent events, flushing every
+// event to the client as soon as it is sent.
+type EventStreamWriter struct {
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	started bool
+}
+
+// NewEventStreamWriter creates an EventStreamWriter. Nothing is written
+// until the first event, so the caller may still respond normally.
+func NewEventStreamWriter(w http.ResponseWriter) *EventStreamWriter {
+	return &EventStreamWriter{w: w, rc: http.NewResponseController(w)}
+}
+
+// Send writes v as the JSON data of one event named event and flushes it.
+func (s *EventStreamWriter) Send(event string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encode stream event: %w", err)
+	}
+	return s.write(fmt.Sprintf("event: %s\ndata: %s\n\n", event, data))
+}
+
+// Comment writes a comment line, which clients ignore. Sent periodically,
+// it keeps idle proxies from closing the connection.
+func (s *EventStreamWriter) Comment(text string) error {
+	return s.write(": " + text + "\n\n")
+}
+
+func (s *EventStreamWriter) write(frame string) error {
+	if !s.started {
+		if err := s.start(); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprint(s.w, frame); err != nil {
+		return fmt.Errorf("write stream event: %w", err)
+	}
+	if err := s.rc.Flush(); err != nil {
+		return fmt.Errorf("flush stream event: %w", err)
+	}
+	return nil
+}
+
+// start lifts the server's write timeout, since a stream outlives any
+// single response, and writes the stream's headers. Writers that cannot
+// change deadlines, such as test recorders, are streamed to regardless.
+func (s *EventStreamWriter) start() error {
+	if err := s.rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return fmt.Errorf("lift stream write deadline: %w", err)
+	}
+	s.w.Header().Set("Content-Type", "text/event-stream")
+	s.w.Header().Set("Cache-Control", "no-cache")
+	s.w.Header().Set("X-Accel-Buffering", "no")
+	s.w.WriteHeader(http.StatusOK)
+	s.started = true
+	return nil
+}
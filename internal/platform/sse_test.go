@@ -0,0 +1,29 @@
+package platform
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEventStreamWriter(t *testing.T) {
+	rr := httptest.NewRecorder()
+	sw := NewEventStreamWriter(rr)
+
+	if err := sw.Send("project.status", map[string]string{"status": "pending"}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if err := sw.Comment("keepalive"); err != nil {
+		t.Fatalf("Comment() error = %v", err)
+	}
+
+	if got := rr.Header().Get("Content-Type"); got != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want text/event-stream", got)
+	}
+	want := "event: project.status\ndata: {\"status\":\"pending\"}\n\n: keepalive\n\n"
+	if rr.Body.String() != want {
+		t.Errorf("body = %q, want %q", rr.Body.String(), want)
+	}
+	if !rr.Flushed {
+		t.Error("expected events to be flushed")
+	}
+}
@@ -0,0 +1,24 @@
+package platform
+
+import (
+	"net/http"
+	"time"
+)
+
+// StatsSource returns a JSON-serializable snapshot of a component's counters.
+type StatsSource func() any
+
+// StatsHandler serves process uptime together with a snapshot from each
+// source, keyed by source name. Counters are cumulative since start.
+func StatsHandler(startedAt time.Time, sources map[string]StatsSource) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		payload := map[string]any{
+			"started_at":     startedAt.UTC(),
+			"uptime_seconds": int64(time.Since(startedAt).Seconds()),
+		}
+		for name, source := range sources {
+			payload[name] = source()
+		}
+		RespondJSON(w, http.StatusOK, payload)
+	}
+}
@@ -0,0 +1,37 @@
+package platform
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestStatsHandlerReportsUptimeAndSources(t *testing.T) {
+	startedAt := time.Now().Add(-90 * time.Second)
+	h := StatsHandler(startedAt, map[string]StatsSource{
+		"projects": func() any { return map[string]int{"created": 3} },
+	})
+
+	rr := httptest.NewRecorder()
+	h(rr, httptest.NewRequest(http.MethodGet, "/api/v1/stats", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+
+	var body struct {
+		UptimeSeconds int64          `json:"uptime_seconds"`
+		Projects      map[string]int `json:"projects"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode response body: %v", err)
+	}
+	if body.UptimeSeconds < 90 {
+		t.Errorf("uptime_seconds = %d, want >= 90", body.UptimeSeconds)
+	}
+	if body.Projects["created"] != 3 {
+		t.Errorf("projects.created = %d, want 3", body.Projects["created"])
+	}
+}
@@ -0,0 +1,44 @@
+package platform
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// StreamWriter writes a response as newline-delimited JSON, flushing every
+// event to the client as soon as it is sent.
+type StreamWriter struct {
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	status  int
+	started bool
+}
+
+// NewStreamWriter creates a StreamWriter that answers with status once the
+// first event is sent. Until then the caller may still respond normally.
+func NewStreamWriter(w http.ResponseWriter, status int) *StreamWriter {
+	return &StreamWriter{w: w, rc: http.NewResponseController(w), status: status}
+}
+
+// Started reports whether the status line has been written.
+func (s *StreamWriter) Started() bool {
+	return s.started
+}
+
+// Send writes v as one JSON line and flushes it.
+func (s *StreamWriter) Send(v any) error {
+	if !s.started {
+		s.w.Header().Set("Content-Type", "application/x-ndjson")
+		s.w.Header().Set("Cache-Control", "no-cache")
+		s.w.WriteHeader(s.status)
+		s.started = true
+	}
+	if err := json.NewEncoder(s.w).Encode(v); err != nil {
+		return fmt.Errorf("write stream event: %w", err)
+	}
+	if err := s.rc.Flush(); err != nil {
+		return fmt.Errorf("flush stream event: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package platform
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	// Embed the tz database so zone lookups work in minimal runtime images.
+	_ "time/tzdata"
+)
+
+// ErrInvalidTimezone is returned when a requested display timezone is unknown.
+var ErrInvalidTimezone = errors.New("invalid timezone")
+
+// RequestLocation resolves the display timezone requested by the client.
+// The `tz` query parameter takes precedence over the `X-Timezone` header.
+// Defaults to UTC when neither is present.
+func RequestLocation(r *http.Request) (*time.Location, error) {
+	name := r.URL.Query().Get("tz")
+	if name == "" {
+		name = r.Header.Get("X-Timezone")
+	}
+	if name == "" {
+		return time.UTC, nil
+	}
+	// "Local" would expose the server's own zone, which is not a client choice.
+	if name == "Local" {
+		return nil, ErrInvalidTimezone
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, ErrInvalidTimezone
+	}
+	return loc, nil
+}
@@ -2,8 +2,12 @@ package platform
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+
+	"github.com/go-playground/validator/v10"
 )
 
 // APIError represents the standard JSON error response format.
@@ -15,7 +19,38 @@ type APIError struct {
 type ErrorDetail struct {
 	Code    string `json:"code"`
 	Message string `json:"message"`
-	// Details could be added here later for validation specifics if needed.
+	// Fields lists every offending field of a failed validation.
+	Fields []FieldError `json:"fields,omitempty"`
+}
+
+// FieldError describes why a single request field failed validation.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+	// Suggestion is a valid value close to the rejected one, for clients
+	// to offer, e.g. "did you mean alpha-project?".
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// SuggestionError is a rejected request along with valid alternatives for
+// some of its fields, by field name.
+type SuggestionError struct {
+	Err         error
+	Suggestions map[string]string
+}
+
+func (e *SuggestionError) Error() string { return e.Err.Error() }
+
+func (e *SuggestionError) Unwrap() error { return e.Err }
+
+// Suggestions returns the suggested values carried by err, if any.
+func Suggestions(err error) map[string]string {
+	var serr *SuggestionError
+	if errors.As(err, &serr) {
+		return serr.Suggestions
+	}
+	return nil
 }
 
 // RespondJSON writes a structured JSON payload to the response.
@@ -28,6 +63,21 @@ func RespondJSON(w http.ResponseWriter, status int, payload interface{}) {
 	}
 }
 
+// RespondList writes items as a JSON array, [] rather than null when there
+// are none.
+func RespondList[T any](w http.ResponseWriter, status int, items []T) {
+	RespondJSON(w, status, NonNil(items))
+}
+
+// NonNil returns items, or an empty slice if it is nil, so that it encodes
+// as [] rather than null. Pure function.
+func NonNil[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
+
 // RespondError writes a standardized APIError JSON payload.
 func RespondError(w http.ResponseWriter, status int, code string, message string) {
 	errResp := APIError{
@@ -39,9 +89,85 @@ func RespondError(w http.ResponseWriter, status int, code string, message string
 	RespondJSON(w, status, errResp)
 }
 
-// RespondValidationError formats go-playground/validator errors
+// RespondFieldError writes the standard error body for a request rejected
+// over a single field, described by field.
+func RespondFieldError(w http.ResponseWriter, status int, code, message string, field FieldError) {
+	RespondJSON(w, status, APIError{
+		Error: ErrorDetail{Code: code, Message: message, Fields: []FieldError{field}},
+	})
+}
+
+// RespondValidationError answers 422 for a well-formed request that fails
+// validation, reporting every failed field of a go-playground/validator
+// error at once, with the values suggested by a wrapping SuggestionError.
+// Other errors are reported with their message only. Malformed bodies and
+// parameters are answered 400 by their handlers instead.
 func RespondValidationError(w http.ResponseWriter, err error) {
-	// A simple approach: grab the first error for the message, or format them all.
-	// For this spike, returning a 400 with a generic validation failed message and the error string.
-	RespondError(w, http.StatusBadRequest, "VALIDATION_FAILED", err.Error())
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		RespondError(w, http.StatusUnprocessableEntity, "VALIDATION_FAILED", err.Error())
+		return
+	}
+
+	fields := ValidationFields(verrs)
+	suggestions := Suggestions(err)
+	for i := range fields {
+		fields[i].Suggestion = suggestions[fields[i].Field]
+	}
+	RespondJSON(w, http.StatusUnprocessableEntity, APIError{
+		Error: ErrorDetail{
+			Code:    "VALIDATION_FAILED",
+			Message: fmt.Sprintf("%d field(s) failed validation", len(fields)),
+			Fields:  fields,
+		},
+	})
+}
+
+// ValidationFields describes each failed field of verrs.
+// Pure function.
+func ValidationFields(verrs validator.ValidationErrors) []FieldError {
+	fields := make([]FieldError, len(verrs))
+	for i, fe := range verrs {
+		fields[i] = FieldError{
+			Field:   fe.Field(),
+			Rule:    fe.ActualTag(),
+			Message: fieldMessage(fe),
+		}
+	}
+	return fields
+}
+
+// fieldMessage renders a human-readable reason for a failed rule. Aliases
+// are reported by the rule they expand to.
+// Pure function.
+func fieldMessage(fe validator.FieldError) string {
+	switch fe.ActualTag() {
+	case "required":
+		return "is required"
+	case "min":
+		return fmt.Sprintf("must be at least %s characters", fe.Param())
+	case "max":
+		return fmt.Sprintf("must be at most %s characters", fe.Param())
+	case "unix_name":
+		return "does not follow the unix name format"
+	case "ssh_public_key":
+		return "is not a valid ssh public key"
+	default:
+		return fmt.Sprintf("failed the %s rule", fe.Tag())
+	}
+}
+
+// RespondServerError answers a request that failed on the server's side.
+// Database overload (ErrPoolExhausted) is a 503 SERVICE_UNAVAILABLE with a
+// Retry-After, so it can be alerted on apart from genuine internal errors,
+// which are logged and answered with a 500.
+func RespondServerError(w http.ResponseWriter, logger *slog.Logger, err error) {
+	if errors.Is(err, ErrPoolExhausted) {
+		logger.Warn("database overloaded", "error", err)
+		w.Header().Set("Retry-After", "1")
+		RespondError(w, http.StatusServiceUnavailable, "SERVICE_UNAVAILABLE", "service temporarily overloaded, retry later")
+		return
+	}
+	logger.Error("internal err", "error", err)
+	RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "internal server error")
 }
@@ -0,0 +1,61 @@
+package platform
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+)
+
+func TestRespondListEncodesNilAsEmptyArray(t *testing.T) {
+	rr := httptest.NewRecorder()
+	RespondList[string](rr, http.StatusOK, nil)
+
+	if got := strings.TrimSpace(rr.Body.String()); got != "[]" {
+		t.Errorf("body = %s, want []", got)
+	}
+	if got := rr.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+}
+
+func TestNonNil(t *testing.T) {
+	if got := NonNil[int](nil); got == nil || len(got) != 0 {
+		t.Errorf("NonNil(nil) = %#v, want an empty slice", got)
+	}
+	items := []int{1, 2}
+	if got := NonNil(items); &got[0] != &items[0] {
+		t.Error("NonNil must return a non-nil slice unchanged")
+	}
+}
+
+func TestRespondValidationErrorAddsSuggestions(t *testing.T) {
+	var req struct {
+		Name     string `validate:"required"`
+		UnixName string `validate:"lowercase"`
+	}
+	req.UnixName = "Alpha"
+	err := validator.New().Struct(req)
+	err = &SuggestionError{Err: err, Suggestions: map[string]string{"UnixName": "alpha"}}
+
+	rr := httptest.NewRecorder()
+	RespondValidationError(rr, err)
+
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d", rr.Code)
+	}
+	var body APIError
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode response body: %v", err)
+	}
+	got := map[string]string{}
+	for _, f := range body.Error.Fields {
+		got[f.Field] = f.Suggestion
+	}
+	if len(got) != 2 || got["UnixName"] != "alpha" || got["Name"] != "" {
+		t.Errorf("suggestions by field = %v, want only UnixName: alpha", got)
+	}
+}
@@ -0,0 +1,216 @@
+package plugin
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"slices"
+	"time"
+)
+
+// Operation names recorded by the audit decorator.
+const (
+	OpProvision   = "provision"
+	OpStatus      = "status"
+	OpDeprovision = "deprovision"
+	OpTag         = "tag"
+)
+
+// redacted replaces the value of any sensitive key in audit records.
+const redacted = "***"
+
+// sensitiveKey matches map keys whose values must never be recorded.
+var sensitiveKey = regexp.MustCompile(`(?i)(password|passwd|secret|token|key|credential)`)
+
+// Operation is the record of a single plugin invocation.
+type Operation struct {
+	Plugin    string
+	Name      string
+	Input     any
+	Output    any
+	Err       error
+	StartedAt time.Time
+	Duration  time.Duration
+}
+
+// Recorder stores plugin operation records.
+type Recorder interface {
+	Record(ctx context.Context, op Operation)
+}
+
+// MultiRecorder passes every operation record to each of its recorders in
+// turn.
+type MultiRecorder []Recorder
+
+// Record implements Recorder.
+func (m MultiRecorder) Record(ctx context.Context, op Operation) {
+	for _, r := range m {
+		r.Record(ctx, op)
+	}
+}
+
+// LogRecorder writes operation records as structured log entries.
+type LogRecorder struct {
+	log *slog.Logger
+}
+
+// NewLogRecorder creates a Recorder that logs to logger.
+func NewLogRecorder(logger *slog.Logger) *LogRecorder {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &LogRecorder{log: logger}
+}
+
+// Record implements Recorder.
+func (r *LogRecorder) Record(ctx context.Context, op Operation) {
+	attrs := []any{
+		"plugin", op.Plugin,
+		"operation", op.Name,
+		"input", op.Input,
+		"duration_ms", op.Duration.Milliseconds(),
+	}
+	if op.Err != nil {
+		r.log.WarnContext(ctx, "plugin operation failed", append(attrs, "error", op.Err.Error())...)
+		return
+	}
+	r.log.InfoContext(ctx, "plugin operation", append(attrs, "output", op.Output)...)
+}
+
+// auditedPlugin records every backend interaction of the wrapped plugin.
+type auditedPlugin struct {
+	Plugin
+	rec Recorder
+	now func() time.Time
+}
+
+// WithAudit wraps p so that Provision, Status, Deprovision and SetTags
+// calls are passed to rec with their (redacted) input, output or error, and duration.
+func WithAudit(p Plugin, rec Recorder) Plugin {
+	return &auditedPlugin{Plugin: p, rec: rec, now: time.Now}
+}
+
+func (a *auditedPlugin) record(ctx context.Context, name string, started time.Time, input, output any, err error) {
+	a.rec.Record(ctx, Operation{
+		Plugin:    a.Name(),
+		Name:      name,
+		Input:     input,
+		Output:    output,
+		Err:       err,
+		StartedAt: started,
+		Duration:  a.now().Sub(started),
+	})
+}
+
+// Unwrap returns the plugin a wraps.
+func (a *auditedPlugin) Unwrap() Plugin {
+	return a.Plugin
+}
+
+// Provision implements Plugin.
+func (a *auditedPlugin) Provision(ctx context.Context, req ProvisionRequest) (*ProvisionResult, error) {
+	return a.provision(ctx, req, func() (*ProvisionResult, error) {
+		return a.Plugin.Provision(ctx, req)
+	})
+}
+
+// ProvisionStream implements StreamingProvisioner.
+func (a *auditedPlugin) ProvisionStream(ctx context.Context, req ProvisionRequest, out func(line string)) (*ProvisionResult, error) {
+	return a.provision(ctx, req, func() (*ProvisionResult, error) {
+		return ProvisionStream(ctx, a.Plugin, req, out)
+	})
+}
+
+func (a *auditedPlugin) provision(ctx context.Context, req ProvisionRequest, run func() (*ProvisionResult, error)) (*ProvisionResult, error) {
+	started := a.now()
+	res, err := run()
+
+	var output any
+	if res != nil {
+		output = redactResult(*res)
+	}
+	a.record(ctx, OpProvision, started, redactRequest(req), output, err)
+	return res, err
+}
+
+// Status implements Plugin.
+func (a *auditedPlugin) Status(ctx context.Context, resourceID string) (*StatusResult, error) {
+	started := a.now()
+	res, err := a.Plugin.Status(ctx, resourceID)
+
+	var output any
+	if res != nil {
+		output = StatusResult{Status: res.Status, Metadata: redactStrings(res.Metadata)}
+	}
+	a.record(ctx, OpStatus, started, resourceID, output, err)
+	return res, err
+}
+
+// Deprovision implements Plugin.
+func (a *auditedPlugin) Deprovision(ctx context.Context, resourceID string) error {
+	started := a.now()
+	err := a.Plugin.Deprovision(ctx, resourceID)
+	a.record(ctx, OpDeprovision, started, resourceID, nil, err)
+	return err
+}
+
+// SetTags implements ResourceTagger, through the plugin a wraps. Nothing
+// is recorded if no plugin in the chain can tag resources.
+func (a *auditedPlugin) SetTags(ctx context.Context, resourceID string, tags map[string]string) error {
+	if tagger(a.Plugin) == nil {
+		return errUnsupportedTags
+	}
+	started := a.now()
+	err := SetTags(ctx, a.Plugin, resourceID, tags)
+	input := map[string]any{"resource_id": resourceID, "tags": redactStrings(tags)}
+	a.record(ctx, OpTag, started, input, nil, err)
+	return err
+}
+
+// redactRequest returns a copy of req with sensitive resources masked.
+// Pure function.
+func redactRequest(req ProvisionRequest) ProvisionRequest {
+	req.Resources = RedactResources(req.Resources)
+	return req
+}
+
+// RedactResources returns a copy of resources with the values of
+// sensitive keys, and of any key listed in extra, masked as in audit
+// records.
+// Pure function.
+func RedactResources(resources map[string]interface{}, extra ...string) map[string]interface{} {
+	if resources == nil {
+		return nil
+	}
+	out := make(map[string]interface{}, len(resources))
+	for k, v := range resources {
+		if sensitiveKey.MatchString(k) || slices.Contains(extra, k) {
+			v = redacted
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// redactResult returns a copy of res with sensitive metadata masked.
+// Pure function.
+func redactResult(res ProvisionResult) ProvisionResult {
+	res.Metadata = redactStrings(res.Metadata)
+	return res
+}
+
+// redactStrings returns a copy of m with sensitive values masked.
+// Pure function.
+func redactStrings(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		if sensitiveKey.MatchString(k) {
+			v = redacted
+		}
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,116 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+type memoryRecorder struct {
+	mu  sync.Mutex
+	ops []Operation
+}
+
+func (m *memoryRecorder) Record(_ context.Context, op Operation) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ops = append(m.ops, op)
+}
+
+type failingProvisionPlugin struct {
+	fakePlugin
+	err error
+}
+
+func (f failingProvisionPlugin) Provision(context.Context, ProvisionRequest) (*ProvisionResult, error) {
+	return nil, f.err
+}
+
+func TestWithAuditRecordsFailedProvision(t *testing.T) {
+	cliErr := errors.New("forge-ovh-cli provision failed: exit status 2")
+	rec := &memoryRecorder{}
+	p := WithAudit(failingProvisionPlugin{fakePlugin: fakePlugin{name: "proxmox"}, err: cliErr}, rec)
+
+	req := ProvisionRequest{
+		ProjectID:   "p-1",
+		ProjectName: "alpha",
+		Resources:   map[string]interface{}{"cores": 2, "root_password": "hunter2"},
+	}
+	_, err := p.Provision(context.Background(), req)
+	if !errors.Is(err, cliErr) {
+		t.Fatalf("expected the plugin error to pass through, got %v", err)
+	}
+
+	if len(rec.ops) != 1 {
+		t.Fatalf("expected 1 recorded operation, got %d", len(rec.ops))
+	}
+	op := rec.ops[0]
+	if op.Plugin != "proxmox" || op.Name != OpProvision {
+		t.Errorf("unexpected operation identity: %s/%s", op.Plugin, op.Name)
+	}
+	if !errors.Is(op.Err, cliErr) {
+		t.Errorf("expected recorded error %v, got %v", cliErr, op.Err)
+	}
+	if op.Output != nil {
+		t.Errorf("expected no output for a failed provision, got %v", op.Output)
+	}
+
+	input, ok := op.Input.(ProvisionRequest)
+	if !ok {
+		t.Fatalf("expected ProvisionRequest input, got %T", op.Input)
+	}
+	if input.Resources["root_password"] != redacted {
+		t.Errorf("expected root_password to be redacted, got %v", input.Resources["root_password"])
+	}
+	if input.Resources["cores"] != 2 {
+		t.Errorf("expected non-sensitive resources to be kept, got %v", input.Resources["cores"])
+	}
+	if req.Resources["root_password"] != "hunter2" {
+		t.Error("redaction must not mutate the caller's request")
+	}
+}
+
+func TestWithAuditRecordsStatusAndDeprovision(t *testing.T) {
+	rec := &memoryRecorder{}
+	p := WithAudit(fakePlugin{name: "proxmox"}, rec)
+
+	if _, err := p.Status(context.Background(), "vm-1"); err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if err := p.Deprovision(context.Background(), "vm-1"); err != nil {
+		t.Fatalf("Deprovision() error = %v", err)
+	}
+
+	if len(rec.ops) != 2 {
+		t.Fatalf("expected 2 recorded operations, got %d", len(rec.ops))
+	}
+	if rec.ops[0].Name != OpStatus || rec.ops[0].Input != "vm-1" {
+		t.Errorf("unexpected status record: %+v", rec.ops[0])
+	}
+	if rec.ops[1].Name != OpDeprovision || rec.ops[1].Err != nil {
+		t.Errorf("unexpected deprovision record: %+v", rec.ops[1])
+	}
+}
+
+func TestWithAuditPassesThroughNameAndHealth(t *testing.T) {
+	p := WithAudit(fakePlugin{name: "proxmox"}, &memoryRecorder{})
+
+	if p.Name() != "proxmox" {
+		t.Errorf("Name() = %q, want proxmox", p.Name())
+	}
+	if err := p.Health(context.Background()); err != nil {
+		t.Errorf("Health() error = %v", err)
+	}
+}
+
+func TestMultiRecorderRecordsToEach(t *testing.T) {
+	first, second := &memoryRecorder{}, &memoryRecorder{}
+	MultiRecorder{first, second}.Record(context.Background(), Operation{Plugin: "proxmox", Name: OpDeprovision})
+
+	for i, rec := range []*memoryRecorder{first, second} {
+		if len(rec.ops) != 1 || rec.ops[0].Name != OpDeprovision {
+			t.Errorf("recorder %d got %+v, want the deprovision", i, rec.ops)
+		}
+	}
+}
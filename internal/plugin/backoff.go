@@ -0,0 +1,51 @@
+package plugin
+
+import "time"
+
+// Backoff paces polling of a resource's status: often while it changes,
+// less and less often while it stays the same. Each poll's result is fed
+// to Next, which returns the wait before the next one. The zero value is
+// not usable; set Min and Max.
+type Backoff struct {
+	// Min is the wait after a change of status, and before the first poll.
+	Min time.Duration
+	// Max caps the wait while the status stays the same.
+	Max time.Duration
+	// Factor multiplies the wait each time the status is unchanged. Values
+	// below 1 use 2.
+	Factor float64
+
+	current time.Duration
+	last    string
+	seen    bool
+}
+
+// Next returns the wait before polling again, given the status just
+// polled: Min if it differs from the previous one, the previous wait times
+// Factor, up to Max, if not.
+func (b *Backoff) Next(status string) time.Duration {
+	if !b.seen || status != b.last {
+		b.seen = true
+		b.last = status
+		b.current = b.Min
+		return b.current
+	}
+	factor := b.Factor
+	if factor < 1 {
+		factor = 2
+	}
+	next := time.Duration(float64(b.current) * factor)
+	if next > b.Max || next < b.current {
+		next = b.Max
+	}
+	b.current = next
+	return b.current
+}
+
+// Reset forgets the statuses seen, so the next wait is Min, e.g. right
+// after a resource was provisioned again.
+func (b *Backoff) Reset() {
+	b.current = 0
+	b.last = ""
+	b.seen = false
+}
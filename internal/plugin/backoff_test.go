@@ -0,0 +1,42 @@
+package plugin
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffGrowsWhileStatusIsStable(t *testing.T) {
+	b := &Backoff{Min: time.Second, Max: 10 * time.Second}
+
+	var got []time.Duration
+	for range 6 {
+		got = append(got, b.Next("running"))
+	}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("waits = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestBackoffResetsOnChange(t *testing.T) {
+	b := &Backoff{Min: time.Second, Max: time.Minute, Factor: 3}
+
+	b.Next("pending")
+	b.Next("pending")
+	if got := b.Next("pending"); got != 9*time.Second {
+		t.Fatalf("Next() = %s while stable, want 9s", got)
+	}
+	if got := b.Next("running"); got != time.Second {
+		t.Errorf("Next() = %s after a change, want Min", got)
+	}
+	if got := b.Next("running"); got != 3*time.Second {
+		t.Errorf("Next() = %s once stable again, want 3s", got)
+	}
+
+	b.Reset()
+	if got := b.Next("running"); got != time.Second {
+		t.Errorf("Next() = %s after Reset, want Min", got)
+	}
+}
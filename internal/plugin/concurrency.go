@@ -0,0 +1,57 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+)
+
+// limitedPlugin caps how many provisioning calls run at once.
+type limitedPlugin struct {
+	Plugin
+	slots chan struct{}
+}
+
+// WithConcurrencyLimit wraps p so at most max Provision and ProvisionStream
+// calls run at once; the rest wait for a slot until their context ends.
+// Status and Deprovision are not limited. A max below one returns p as-is.
+func WithConcurrencyLimit(p Plugin, max int) Plugin {
+	if max < 1 {
+		return p
+	}
+	return &limitedPlugin{Plugin: p, slots: make(chan struct{}, max)}
+}
+
+// Unwrap returns the plugin l wraps.
+func (l *limitedPlugin) Unwrap() Plugin {
+	return l.Plugin
+}
+
+// Provision implements Plugin.
+func (l *limitedPlugin) Provision(ctx context.Context, req ProvisionRequest) (*ProvisionResult, error) {
+	release, err := l.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return l.Plugin.Provision(ctx, req)
+}
+
+// ProvisionStream implements StreamingProvisioner.
+func (l *limitedPlugin) ProvisionStream(ctx context.Context, req ProvisionRequest, out func(line string)) (*ProvisionResult, error) {
+	release, err := l.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return ProvisionStream(ctx, l.Plugin, req, out)
+}
+
+// acquire waits for a free slot, returning the function that frees it.
+func (l *limitedPlugin) acquire(ctx context.Context) (release func(), err error) {
+	select {
+	case l.slots <- struct{}{}:
+		return func() { <-l.slots }, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("wait for a %s provisioning slot: %w", l.Name(), ctx.Err())
+	}
+}
@@ -0,0 +1,130 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gatedPlugin blocks every Provision until release is closed, tracking how
+// many calls run at once.
+type gatedPlugin struct {
+	fakePlugin
+	release chan struct{}
+
+	mu      sync.Mutex
+	running int
+	peak    int
+}
+
+func (g *gatedPlugin) Provision(ctx context.Context, req ProvisionRequest) (*ProvisionResult, error) {
+	g.mu.Lock()
+	g.running++
+	g.peak = max(g.peak, g.running)
+	g.mu.Unlock()
+	defer func() {
+		g.mu.Lock()
+		g.running--
+		g.mu.Unlock()
+	}()
+
+	<-g.release
+	return g.fakePlugin.Provision(ctx, req)
+}
+
+func (g *gatedPlugin) counts() (running, peak int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.running, g.peak
+}
+
+// waitRunning waits until exactly want calls of g are running.
+func waitRunning(t *testing.T, g *gatedPlugin, want int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if running, _ := g.counts(); running == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			running, _ := g.counts()
+			t.Fatalf("%s: %d calls running, want %d", g.name, running, want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRegistryLimitsProvisionConcurrencyPerPlugin(t *testing.T) {
+	release := make(chan struct{})
+	proxmox := &gatedPlugin{fakePlugin: fakePlugin{name: "proxmox"}, release: release}
+	fake := &gatedPlugin{fakePlugin: fakePlugin{name: "fake"}, release: release}
+	r := NewRegistry(
+		WithProvisionConcurrency("proxmox", 2),
+		WithProvisionConcurrency("fake", 5),
+	)
+	for _, p := range []Plugin{proxmox, fake} {
+		if err := r.Register(p); err != nil {
+			t.Fatalf("Register() error = %v", err)
+		}
+	}
+
+	var calls sync.WaitGroup
+	for _, name := range []string{"proxmox", "fake"} {
+		p, err := r.Get(name)
+		if err != nil {
+			t.Fatalf("Get(%q) error = %v", name, err)
+		}
+		for range 8 {
+			calls.Go(func() {
+				if _, err := p.Provision(context.Background(), ProvisionRequest{ProjectName: "alpha"}); err != nil {
+					t.Errorf("%s: Provision() error = %v", name, err)
+				}
+			})
+		}
+	}
+
+	// Both plugins fill their own slots at the same time, neither taking
+	// from the other's
+	waitRunning(t, proxmox, 2)
+	waitRunning(t, fake, 5)
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	calls.Wait()
+
+	if _, peak := proxmox.counts(); peak != 2 {
+		t.Errorf("proxmox ran %d calls at once, want 2", peak)
+	}
+	if _, peak := fake.counts(); peak != 5 {
+		t.Errorf("fake ran %d calls at once, want 5", peak)
+	}
+}
+
+func TestConcurrencyLimitGivesUpWhenContextEnds(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	gated := &gatedPlugin{fakePlugin: fakePlugin{name: "proxmox"}, release: release}
+	p := WithConcurrencyLimit(gated, 1)
+
+	go func() {
+		_, _ = p.Provision(context.Background(), ProvisionRequest{})
+	}()
+	waitRunning(t, gated, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := p.Provision(ctx, ProvisionRequest{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the wait to end with the context, got %v", err)
+	}
+	if _, peak := gated.counts(); peak != 1 {
+		t.Errorf("ran %d calls at once, want 1", peak)
+	}
+}
+
+func TestConcurrencyLimitBelowOneIsUnlimited(t *testing.T) {
+	got := WithConcurrencyLimit(fakePlugin{name: "proxmox"}, 0)
+	if _, ok := got.(fakePlugin); !ok {
+		t.Errorf("WithConcurrencyLimit(p, 0) = %T, want p itself", got)
+	}
+}
@@ -0,0 +1,60 @@
+package plugin
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrorClass says what kind of failure a plugin call ran into, so callers
+// can choose a response and whether to retry without parsing messages.
+type ErrorClass string
+
+const (
+	// ClassUnknown is any failure the plugin could not classify.
+	ClassUnknown ErrorClass = "unknown"
+	// ClassNotFound means the resource the call named does not exist.
+	ClassNotFound ErrorClass = "not_found"
+	// ClassUnauthorized means the plugin's credentials were rejected.
+	ClassUnauthorized ErrorClass = "unauthorized"
+	// ClassTransient means the backend was unavailable or timed out; the
+	// same call may succeed later.
+	ClassTransient ErrorClass = "transient"
+	// ClassInvalidInput means the backend rejected the request itself;
+	// repeating it unchanged will fail again.
+	ClassInvalidInput ErrorClass = "invalid_input"
+)
+
+// Retryable reports whether a call that failed with c is worth repeating.
+// Only transient failures are: an unclassified one, such as a call killed
+// by its context, may have got far enough that repeating it does harm.
+func (c ErrorClass) Retryable() bool {
+	return c == ClassTransient
+}
+
+// PluginError is a classified failure of one plugin call.
+type PluginError struct {
+	// Plugin is the name of the plugin that failed.
+	Plugin string
+	// Op is the call that failed, one of the Op* audit operations.
+	Op    string
+	Class ErrorClass
+	Err   error
+}
+
+func (e *PluginError) Error() string {
+	return fmt.Sprintf("%s %s failed (%s): %v", e.Plugin, e.Op, e.Class, e.Err)
+}
+
+func (e *PluginError) Unwrap() error {
+	return e.Err
+}
+
+// Classify returns the class of the first PluginError in err's chain, or
+// ClassUnknown if there is none.
+func Classify(err error) ErrorClass {
+	var perr *PluginError
+	if errors.As(err, &perr) {
+		return perr.Class
+	}
+	return ClassUnknown
+}
@@ -0,0 +1,37 @@
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestClassifyFindsWrappedPluginError(t *testing.T) {
+	cause := errors.New("exit status 77")
+	err := fmt.Errorf("provisioning failed: %w", &PluginError{Plugin: "proxmox", Op: OpProvision, Class: ClassUnauthorized, Err: cause})
+
+	if got := Classify(err); got != ClassUnauthorized {
+		t.Errorf("Classify() = %q, want %q", got, ClassUnauthorized)
+	}
+	if !errors.Is(err, cause) {
+		t.Error("expected the cause to stay reachable through the PluginError")
+	}
+	if got := Classify(errors.New("plain")); got != ClassUnknown {
+		t.Errorf("Classify(plain error) = %q, want %q", got, ClassUnknown)
+	}
+}
+
+func TestErrorClassRetryable(t *testing.T) {
+	tests := map[ErrorClass]bool{
+		ClassUnknown:      false,
+		ClassTransient:    true,
+		ClassNotFound:     false,
+		ClassUnauthorized: false,
+		ClassInvalidInput: false,
+	}
+	for class, want := range tests {
+		if got := class.Retryable(); got != want {
+			t.Errorf("%s.Retryable() = %v, want %v", class, got, want)
+		}
+	}
+}
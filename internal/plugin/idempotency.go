@@ -0,0 +1,104 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrResourceNotFound is returned by ResourceFinder when no resource exists
+// under the requested name.
+var ErrResourceNotFound = errors.New("resource not found")
+
+// ResourceFinder is implemented by plugins that can look up an existing
+// resource by the name it was provisioned under.
+type ResourceFinder interface {
+	// FindResource returns the resource named name, or ErrResourceNotFound.
+	FindResource(ctx context.Context, name string) (*ProvisionResult, error)
+}
+
+// FindResource looks up the resource named name through p, or through the
+// plugin p decorates, whichever implements ResourceFinder first. It returns
+// ErrResourceNotFound when no resource exists and errors.ErrUnsupported
+// when no plugin in the chain can look resources up.
+func FindResource(ctx context.Context, p Plugin, name string) (*ProvisionResult, error) {
+	for p != nil {
+		if finder, ok := p.(ResourceFinder); ok {
+			return finder.FindResource(ctx, name)
+		}
+		w, ok := p.(interface{ Unwrap() Plugin })
+		if !ok {
+			break
+		}
+		p = w.Unwrap()
+	}
+	return nil, fmt.Errorf("find resource: %w", errors.ErrUnsupported)
+}
+
+// ResourceName is the deterministic name a resource for req is created
+// under, and therefore the name it can be found by again: the project's
+// unix name, or its ID if the request has none. It is never the display
+// name, which several projects may share.
+// Pure function.
+func ResourceName(req ProvisionRequest) string {
+	if req.UnixName != "" {
+		return req.UnixName
+	}
+	return req.ProjectID
+}
+
+// idempotentPlugin reuses existing resources for idempotent requests.
+type idempotentPlugin struct {
+	Plugin
+	finder ResourceFinder
+}
+
+// WithIdempotency wraps p so that requests with Idempotent set first look
+// up a resource named ResourceName(req) and return it instead of creating a
+// duplicate. Plugins that do not implement ResourceFinder are returned as-is.
+func WithIdempotency(p Plugin) Plugin {
+	finder, ok := p.(ResourceFinder)
+	if !ok {
+		return p
+	}
+	return &idempotentPlugin{Plugin: p, finder: finder}
+}
+
+// FindResource implements ResourceFinder.
+func (i *idempotentPlugin) FindResource(ctx context.Context, name string) (*ProvisionResult, error) {
+	return i.finder.FindResource(ctx, name)
+}
+
+// Provision implements Plugin.
+func (i *idempotentPlugin) Provision(ctx context.Context, req ProvisionRequest) (*ProvisionResult, error) {
+	return i.provision(ctx, req, func() (*ProvisionResult, error) {
+		return i.Plugin.Provision(ctx, req)
+	})
+}
+
+// ProvisionStream implements StreamingProvisioner.
+func (i *idempotentPlugin) ProvisionStream(ctx context.Context, req ProvisionRequest, out func(line string)) (*ProvisionResult, error) {
+	return i.provision(ctx, req, func() (*ProvisionResult, error) {
+		return ProvisionStream(ctx, i.Plugin, req, out)
+	})
+}
+
+func (i *idempotentPlugin) provision(ctx context.Context, req ProvisionRequest, create func() (*ProvisionResult, error)) (*ProvisionResult, error) {
+	if !req.Idempotent {
+		return create()
+	}
+
+	existing, err := i.finder.FindResource(ctx, ResourceName(req))
+	switch {
+	case err == nil:
+		if existing.Metadata == nil {
+			existing.Metadata = make(map[string]string)
+		}
+		existing.Metadata["reused"] = "true"
+		return existing, nil
+	case errors.Is(err, ErrResourceNotFound):
+		return create()
+	default:
+		return nil, fmt.Errorf("look up existing resource: %w", err)
+	}
+}
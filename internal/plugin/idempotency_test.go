@@ -0,0 +1,158 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// finderPlugin simulates a backend that may already hold a resource.
+type finderPlugin struct {
+	fakePlugin
+	existing   map[string]*ProvisionResult
+	findErr    error
+	provisions int
+	lookups    int
+}
+
+func (f *finderPlugin) FindResource(_ context.Context, name string) (*ProvisionResult, error) {
+	f.lookups++
+	if f.findErr != nil {
+		return nil, f.findErr
+	}
+	if res, ok := f.existing[name]; ok {
+		return res, nil
+	}
+	return nil, ErrResourceNotFound
+}
+
+func (f *finderPlugin) Provision(_ context.Context, req ProvisionRequest) (*ProvisionResult, error) {
+	f.provisions++
+	return &ProvisionResult{ResourceID: "vm-new", Status: "provisioned"}, nil
+}
+
+func TestWithIdempotencyReturnsExistingResource(t *testing.T) {
+	fake := &finderPlugin{
+		fakePlugin: fakePlugin{name: "proxmox"},
+		existing: map[string]*ProvisionResult{
+			"alpha": {ResourceID: "vm-1", Status: "running"},
+		},
+	}
+	p := WithIdempotency(fake)
+
+	res, err := p.Provision(context.Background(), ProvisionRequest{UnixName: "alpha", Idempotent: true})
+	if err != nil {
+		t.Fatalf("Provision() error = %v", err)
+	}
+	if res.ResourceID != "vm-1" || res.Metadata["reused"] != "true" {
+		t.Errorf("expected the existing resource to be reused, got %+v", res)
+	}
+	if fake.provisions != 0 {
+		t.Errorf("expected no new resource to be created, got %d provisions", fake.provisions)
+	}
+}
+
+func TestWithIdempotencyCreatesWhenNothingExists(t *testing.T) {
+	fake := &finderPlugin{fakePlugin: fakePlugin{name: "proxmox"}}
+	p := WithIdempotency(fake)
+
+	res, err := p.Provision(context.Background(), ProvisionRequest{UnixName: "beta", Idempotent: true})
+	if err != nil {
+		t.Fatalf("Provision() error = %v", err)
+	}
+	if res.ResourceID != "vm-new" || fake.provisions != 1 {
+		t.Errorf("expected a new resource, got %+v after %d provisions", res, fake.provisions)
+	}
+}
+
+func TestWithIdempotencySkipsLookupUnlessRequested(t *testing.T) {
+	fake := &finderPlugin{
+		fakePlugin: fakePlugin{name: "proxmox"},
+		existing:   map[string]*ProvisionResult{"alpha": {ResourceID: "vm-1"}},
+	}
+	p := WithIdempotency(fake)
+
+	if _, err := p.Provision(context.Background(), ProvisionRequest{UnixName: "alpha"}); err != nil {
+		t.Fatalf("Provision() error = %v", err)
+	}
+	if fake.lookups != 0 || fake.provisions != 1 {
+		t.Errorf("expected a plain create, got %d lookups and %d provisions", fake.lookups, fake.provisions)
+	}
+}
+
+func TestWithIdempotencyPropagatesLookupFailure(t *testing.T) {
+	lookupErr := errors.New("cli unreachable")
+	fake := &finderPlugin{fakePlugin: fakePlugin{name: "proxmox"}, findErr: lookupErr}
+	p := WithIdempotency(fake)
+
+	_, err := p.Provision(context.Background(), ProvisionRequest{UnixName: "alpha", Idempotent: true})
+	if !errors.Is(err, lookupErr) {
+		t.Fatalf("expected lookup error, got %v", err)
+	}
+	if fake.provisions != 0 {
+		t.Error("must not create a resource when the lookup failed")
+	}
+}
+
+func TestWithIdempotencyLeavesNonFindersUnwrapped(t *testing.T) {
+	if got, ok := WithIdempotency(fakePlugin{name: "proxmox"}).(fakePlugin); !ok {
+		t.Errorf("expected plugin without ResourceFinder to be returned as-is, got %T", got)
+	}
+}
+
+func TestFindResourceSeesThroughDecorators(t *testing.T) {
+	fake := &finderPlugin{
+		fakePlugin: fakePlugin{name: "proxmox"},
+		existing:   map[string]*ProvisionResult{"alpha": {ResourceID: "vm-1"}},
+	}
+	p := WithLogging(WithAudit(WithRetry(fake, RetryPolicy{Attempts: 3}), &memoryRecorder{}), nil)
+
+	res, err := FindResource(context.Background(), p, "alpha")
+	if err != nil {
+		t.Fatalf("FindResource() error = %v", err)
+	}
+	if res.ResourceID != "vm-1" {
+		t.Errorf("FindResource() = %+v, want vm-1", res)
+	}
+
+	_, err = FindResource(context.Background(), WithLogging(fakePlugin{name: "gitlab"}, nil), "alpha")
+	if !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("FindResource() on a non-finder error = %v, want errors.ErrUnsupported", err)
+	}
+}
+
+func TestResourceNameIsUniquePerProject(t *testing.T) {
+	tests := []struct {
+		name string
+		req  ProvisionRequest
+		want string
+	}{
+		{name: "unix name", req: ProvisionRequest{ProjectID: "p-1", ProjectName: "Web", UnixName: "web-eu"}, want: "web-eu"},
+		{name: "no unix name", req: ProvisionRequest{ProjectID: "p-1", ProjectName: "Web"}, want: "p-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ResourceName(tt.req); got != tt.want {
+				t.Errorf("ResourceName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithIdempotencyIgnoresResourcesOfProjectsSharingDisplayName(t *testing.T) {
+	fake := &finderPlugin{
+		fakePlugin: fakePlugin{name: "proxmox"},
+		existing: map[string]*ProvisionResult{
+			"web-eu": {ResourceID: "vm-1", Status: "running"},
+		},
+	}
+	p := WithIdempotency(fake)
+
+	res, err := p.Provision(context.Background(), ProvisionRequest{ProjectName: "Web", UnixName: "web-us", Idempotent: true})
+	if err != nil {
+		t.Fatalf("Provision() error = %v", err)
+	}
+	if res.ResourceID != "vm-new" || fake.provisions != 1 {
+		t.Errorf("expected a new resource, not another project's, got %+v", res)
+	}
+}
@@ -0,0 +1,111 @@
+package plugin
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// LoggingPlugin logs every call to the plugin it wraps: a debug entry when
+// the call starts, then its duration and outcome when it ends. Results and
+// errors are passed through unchanged.
+type LoggingPlugin struct {
+	Plugin
+	log *slog.Logger
+	now func() time.Time
+}
+
+// WithLogging wraps p in a LoggingPlugin writing to logger. Wrapping a
+// LoggingPlugin again returns it as-is.
+func WithLogging(p Plugin, logger *slog.Logger) Plugin {
+	if _, ok := p.(*LoggingPlugin); ok {
+		return p
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &LoggingPlugin{Plugin: p, log: logger, now: time.Now}
+}
+
+// call runs fn as method, logging around it. Successful health checks are
+// logged at debug level, as probes run them continuously.
+func (l *LoggingPlugin) call(ctx context.Context, method string, fn func() error) {
+	attrs := []any{"plugin", l.Name(), "method", method}
+	l.log.DebugContext(ctx, "plugin call started", attrs...)
+
+	started := l.now()
+	err := fn()
+	attrs = append(attrs, "duration_ms", l.now().Sub(started).Milliseconds())
+
+	switch {
+	case err != nil:
+		l.log.WarnContext(ctx, "plugin call failed", append(attrs, "error", err.Error())...)
+	case method == "health":
+		l.log.DebugContext(ctx, "plugin call finished", attrs...)
+	default:
+		l.log.InfoContext(ctx, "plugin call finished", attrs...)
+	}
+}
+
+// Health implements Plugin.
+func (l *LoggingPlugin) Health(ctx context.Context) (err error) {
+	l.call(ctx, "health", func() error {
+		err = l.Plugin.Health(ctx)
+		return err
+	})
+	return err
+}
+
+// Unwrap returns the plugin l wraps.
+func (l *LoggingPlugin) Unwrap() Plugin {
+	return l.Plugin
+}
+
+// Provision implements Plugin.
+func (l *LoggingPlugin) Provision(ctx context.Context, req ProvisionRequest) (res *ProvisionResult, err error) {
+	l.call(ctx, OpProvision, func() error {
+		res, err = l.Plugin.Provision(ctx, req)
+		return err
+	})
+	return res, err
+}
+
+// ProvisionStream implements StreamingProvisioner.
+func (l *LoggingPlugin) ProvisionStream(ctx context.Context, req ProvisionRequest, out func(line string)) (res *ProvisionResult, err error) {
+	l.call(ctx, OpProvision, func() error {
+		res, err = ProvisionStream(ctx, l.Plugin, req, out)
+		return err
+	})
+	return res, err
+}
+
+// Status implements Plugin.
+func (l *LoggingPlugin) Status(ctx context.Context, resourceID string) (res *StatusResult, err error) {
+	l.call(ctx, OpStatus, func() error {
+		res, err = l.Plugin.Status(ctx, resourceID)
+		return err
+	})
+	return res, err
+}
+
+// SetTags implements ResourceTagger, through the plugin l wraps. Nothing
+// is logged if no plugin in the chain can tag resources.
+func (l *LoggingPlugin) SetTags(ctx context.Context, resourceID string, tags map[string]string) (err error) {
+	if tagger(l.Plugin) == nil {
+		return errUnsupportedTags
+	}
+	l.call(ctx, OpTag, func() error {
+		err = SetTags(ctx, l.Plugin, resourceID, tags)
+		return err
+	})
+	return err
+}
+
+// Deprovision implements Plugin.
+func (l *LoggingPlugin) Deprovision(ctx context.Context, resourceID string) (err error) {
+	l.call(ctx, OpDeprovision, func() error {
+		err = l.Plugin.Deprovision(ctx, resourceID)
+		return err
+	})
+	return err
+}
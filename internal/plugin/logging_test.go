@@ -0,0 +1,112 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// newLogCapture returns a debug-level logger and a function decoding what
+// it has written so far.
+func newLogCapture(t *testing.T) (*slog.Logger, func() []map[string]any) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	return logger, func() []map[string]any {
+		t.Helper()
+		var records []map[string]any
+		for line := range strings.Lines(buf.String()) {
+			var record map[string]any
+			if err := json.Unmarshal([]byte(line), &record); err != nil {
+				t.Fatalf("failed to decode log record: %v", err)
+			}
+			records = append(records, record)
+		}
+		return records
+	}
+}
+
+func TestWithLoggingLogsAroundCalls(t *testing.T) {
+	logger, records := newLogCapture(t)
+	p := WithLogging(fakePlugin{name: "proxmox"}, logger)
+
+	res, err := p.Provision(context.Background(), ProvisionRequest{ProjectName: "alpha"})
+	if err != nil {
+		t.Fatalf("Provision() error = %v", err)
+	}
+	if res.ResourceID != "res-1" || res.Status != "ok" {
+		t.Errorf("Provision() = %+v, want the wrapped plugin's result", res)
+	}
+
+	got := records()
+	if len(got) != 2 {
+		t.Fatalf("expected 2 log records, got %d: %v", len(got), got)
+	}
+	if got[0]["msg"] != "plugin call started" || got[0]["level"] != "DEBUG" {
+		t.Errorf("first record = %v, want a debug start entry", got[0])
+	}
+	end := got[1]
+	if end["msg"] != "plugin call finished" || end["level"] != "INFO" {
+		t.Errorf("second record = %v, want an info end entry", end)
+	}
+	if end["plugin"] != "proxmox" || end["method"] != OpProvision {
+		t.Errorf("end record names %v %v, want proxmox provision", end["plugin"], end["method"])
+	}
+	if _, ok := end["duration_ms"]; !ok {
+		t.Error("end record has no duration_ms")
+	}
+}
+
+func TestWithLoggingPassesErrorsThrough(t *testing.T) {
+	logger, records := newLogCapture(t)
+	cliErr := errors.New("forge-ovh-cli: exit status 2")
+	p := WithLogging(failingProvisionPlugin{fakePlugin: fakePlugin{name: "proxmox"}, err: cliErr}, logger)
+
+	res, err := p.Provision(context.Background(), ProvisionRequest{})
+	if res != nil || err != cliErr {
+		t.Fatalf("Provision() = %v, %v, want nil and the plugin's error unchanged", res, err)
+	}
+
+	got := records()
+	end := got[len(got)-1]
+	if end["msg"] != "plugin call failed" || end["level"] != "WARN" || end["error"] != cliErr.Error() {
+		t.Errorf("end record = %v, want a warning carrying the error", end)
+	}
+}
+
+func TestWithLoggingKeepsStreaming(t *testing.T) {
+	logger, _ := newLogCapture(t)
+	p := WithLogging(fakePlugin{name: "proxmox"}, logger)
+
+	if _, ok := p.(StreamingProvisioner); !ok {
+		t.Fatal("a logged plugin must still offer ProvisionStream")
+	}
+	if WithLogging(p, logger) != p {
+		t.Error("wrapping a logged plugin again must return it as-is")
+	}
+}
+
+func TestRegistryWithCallLoggingWrapsPlugins(t *testing.T) {
+	logger, records := newLogCapture(t)
+	r := NewRegistry(WithCallLogging(logger))
+	if err := r.Register(fakePlugin{name: "proxmox"}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	p, err := r.Get("proxmox")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if _, ok := p.(*LoggingPlugin); !ok {
+		t.Fatalf("registered plugin is %T, want *LoggingPlugin", p)
+	}
+	if err := p.Deprovision(context.Background(), "res-1"); err != nil {
+		t.Fatalf("Deprovision() error = %v", err)
+	}
+	if got := records(); len(got) != 2 || got[1]["method"] != OpDeprovision {
+		t.Errorf("records = %v, want the deprovision call logged", got)
+	}
+}
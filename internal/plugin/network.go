@@ -0,0 +1,74 @@
+package plugin
+
+import (
+	"net/netip"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// NetworkInfo is how to reach a provisioned resource. Backends often
+// assign addresses after the resource is created, so it may only appear
+// in a later StatusResult.
+type NetworkInfo struct {
+	Hostname    string   `json:"hostname,omitempty"`
+	IPAddresses []string `json:"ip_addresses,omitempty"`
+}
+
+// Empty reports whether n carries no address at all. A nil n is empty.
+func (n *NetworkInfo) Empty() bool {
+	return n == nil || (n.Hostname == "" && len(n.IPAddresses) == 0)
+}
+
+// Equal reports whether n and other describe the same addresses.
+func (n *NetworkInfo) Equal(other *NetworkInfo) bool {
+	if n.Empty() || other.Empty() {
+		return n.Empty() == other.Empty()
+	}
+	return n.Hostname == other.Hostname && slices.Equal(n.IPAddresses, other.IPAddresses)
+}
+
+var (
+	// ipLinePattern matches lines such as "IP: 10.0.0.5", "ipv6: fd00::5"
+	// or "ip-addresses = 10.0.0.5, 10.0.1.5/24", case-insensitively.
+	ipLinePattern = regexp.MustCompile(`(?mi)^[ \t]*(?:ip|ipv4|ipv6|ip[ _-]?address(?:es)?)[ \t]*[:=][ \t]*(.+?)[ \t]*$`)
+	// hostnameLinePattern matches lines such as "Hostname: vm-1.lan".
+	hostnameLinePattern = regexp.MustCompile(`(?mi)^[ \t]*(?:hostname|fqdn)[ \t]*[:=][ \t]*(\S+)[ \t]*$`)
+)
+
+// ParseNetworkInfo collects the addresses reported in CLI output, each
+// once, in the order they appear; a CIDR suffix is dropped and anything
+// that is not an IP address is skipped. The last hostname reported wins.
+// Returns nil if output reports neither.
+// Pure function.
+func ParseNetworkInfo(output string) *NetworkInfo {
+	info := &NetworkInfo{}
+	for _, m := range ipLinePattern.FindAllStringSubmatch(output, -1) {
+		for field := range strings.FieldsFuncSeq(m[1], func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+			addr, ok := parseAddr(field)
+			if ok && !slices.Contains(info.IPAddresses, addr) {
+				info.IPAddresses = append(info.IPAddresses, addr)
+			}
+		}
+	}
+	if matches := hostnameLinePattern.FindAllStringSubmatch(output, -1); len(matches) > 0 {
+		info.Hostname = matches[len(matches)-1][1]
+	}
+	if info.Empty() {
+		return nil
+	}
+	return info
+}
+
+// parseAddr returns the canonical form of an IP address, with or without a
+// CIDR suffix. Pure function.
+func parseAddr(s string) (string, bool) {
+	if prefix, err := netip.ParsePrefix(s); err == nil {
+		return prefix.Addr().String(), true
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return "", false
+	}
+	return addr.String(), true
+}
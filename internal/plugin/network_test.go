@@ -0,0 +1,73 @@
+package plugin
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseNetworkInfo(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   *NetworkInfo
+	}{
+		{
+			name:   "ip with prefix and hostname",
+			output: "ID: 104\nIP: 10.0.0.5/24\nHostname: alpha.lan\n",
+			want:   &NetworkInfo{Hostname: "alpha.lan", IPAddresses: []string{"10.0.0.5"}},
+		},
+		{
+			name:   "ipv4 and ipv6 lines",
+			output: "ipv4: 10.0.0.5\nipv6 = FD00:0:0:0::5/64\n",
+			want:   &NetworkInfo{IPAddresses: []string{"10.0.0.5", "fd00::5"}},
+		},
+		{
+			name:   "address list with duplicates",
+			output: "ip-addresses: 10.0.0.5, 10.0.1.5 10.0.0.5\nip_address: 10.0.1.5\n",
+			want:   &NetworkInfo{IPAddresses: []string{"10.0.0.5", "10.0.1.5"}},
+		},
+		{
+			name:   "invalid addresses skipped",
+			output: "IP: pending\nIP: 10.0.0.300, 10.0.0.7\n",
+			want:   &NetworkInfo{IPAddresses: []string{"10.0.0.7"}},
+		},
+		{
+			name:   "hostname only",
+			output: "fqdn: alpha.example.com",
+			want:   &NetworkInfo{Hostname: "alpha.example.com"},
+		},
+		{
+			name:   "nothing reported",
+			output: "ID: 104\nstatus: running\ndescription: ip: not in a key position",
+		},
+		{
+			name:   "no valid address",
+			output: "IP: dhcp",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseNetworkInfo(tt.output); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseNetworkInfo() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNetworkInfoEqual(t *testing.T) {
+	var none *NetworkInfo
+	a := &NetworkInfo{Hostname: "alpha.lan", IPAddresses: []string{"10.0.0.5"}}
+
+	if !none.Equal(&NetworkInfo{}) {
+		t.Error("nil and empty network info should be equal")
+	}
+	if a.Equal(none) || none.Equal(a) {
+		t.Error("network info should not equal nil")
+	}
+	if !a.Equal(&NetworkInfo{Hostname: "alpha.lan", IPAddresses: []string{"10.0.0.5"}}) {
+		t.Error("identical network info should be equal")
+	}
+	if a.Equal(&NetworkInfo{Hostname: "alpha.lan", IPAddresses: []string{"10.0.0.6"}}) {
+		t.Error("network info with different addresses should not be equal")
+	}
+}
@@ -0,0 +1,115 @@
+package plugin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// Supported output formats for CLI-backed plugins.
+const (
+	OutputFormatKeyValue = "keyvalue"
+	OutputFormatJSON     = "json"
+	OutputFormatRegex    = "regex"
+)
+
+// DefaultKeyValuePattern matches an "ID: <value>" line, case-insensitively.
+const DefaultKeyValuePattern = `(?mi)^id:[ \t]*(?P<id>\S+)`
+
+// ErrNoResourceID is returned when CLI output does not contain a resource ID.
+var ErrNoResourceID = errors.New("unable to parse resource id from cli output")
+
+// OutputParser turns raw CLI output into a provisioning result.
+type OutputParser interface {
+	ParseProvision(output []byte) (*ProvisionResult, error)
+}
+
+// NewOutputParser builds the parser for the given format. The pattern is
+// only used by the regex format and falls back to DefaultKeyValuePattern.
+func NewOutputParser(format, pattern string) (OutputParser, error) {
+	switch format {
+	case "", OutputFormatKeyValue:
+		return NewRegexParser(DefaultKeyValuePattern)
+	case OutputFormatJSON:
+		return JSONParser{}, nil
+	case OutputFormatRegex:
+		if pattern == "" {
+			pattern = DefaultKeyValuePattern
+		}
+		return NewRegexParser(pattern)
+	default:
+		return nil, fmt.Errorf("unknown output format %q; choose: keyvalue, json, regex", format)
+	}
+}
+
+// JSONParser parses CLI output emitted as a single JSON object.
+type JSONParser struct{}
+
+type jsonProvisionOutput struct {
+	ID         string            `json:"id"`
+	ResourceID string            `json:"resource_id"`
+	Status     string            `json:"status"`
+	Metadata   map[string]string `json:"metadata"`
+	Network    *NetworkInfo      `json:"network"`
+}
+
+// ParseProvision implements OutputParser.
+func (JSONParser) ParseProvision(output []byte) (*ProvisionResult, error) {
+	var out jsonProvisionOutput
+	if err := json.Unmarshal(output, &out); err != nil {
+		return nil, fmt.Errorf("invalid json cli output: %w", err)
+	}
+
+	id := out.ResourceID
+	if id == "" {
+		id = out.ID
+	}
+	if id == "" {
+		return nil, ErrNoResourceID
+	}
+
+	return &ProvisionResult{
+		ResourceID: id,
+		Status:     out.Status,
+		Metadata:   out.Metadata,
+		Network:    out.Network,
+	}, nil
+}
+
+// RegexParser extracts fields from CLI output using named capture groups.
+// The "id" group is required; an optional "status" group is used if present.
+type RegexParser struct {
+	re *regexp.Regexp
+}
+
+// NewRegexParser compiles pattern and checks it declares an "id" group.
+func NewRegexParser(pattern string) (*RegexParser, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid output pattern: %w", err)
+	}
+	if re.SubexpIndex("id") < 0 {
+		return nil, fmt.Errorf("output pattern %q must declare an (?P<id>...) group", pattern)
+	}
+	return &RegexParser{re: re}, nil
+}
+
+// ParseProvision implements OutputParser.
+func (p *RegexParser) ParseProvision(output []byte) (*ProvisionResult, error) {
+	m := p.re.FindSubmatch(output)
+	if m == nil {
+		return nil, ErrNoResourceID
+	}
+
+	id := string(m[p.re.SubexpIndex("id")])
+	if id == "" {
+		return nil, ErrNoResourceID
+	}
+
+	result := &ProvisionResult{ResourceID: id}
+	if i := p.re.SubexpIndex("status"); i >= 0 {
+		result.Status = string(m[i])
+	}
+	return result, nil
+}
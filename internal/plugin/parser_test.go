@@ -0,0 +1,131 @@
+package plugin
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewOutputParser(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		pattern string
+		output  string
+		wantID  string
+		wantErr bool
+	}{
+		{
+			name:   "default is key:value",
+			output: "ok\nID: 321\ndone",
+			wantID: "321",
+		},
+		{
+			name:   "key:value is case-insensitive",
+			format: OutputFormatKeyValue,
+			output: "id:abc-1",
+			wantID: "abc-1",
+		},
+		{
+			name:   "json with resource_id",
+			format: OutputFormatJSON,
+			output: `{"resource_id":"vm-7","status":"creating"}`,
+			wantID: "vm-7",
+		},
+		{
+			name:   "json with id",
+			format: OutputFormatJSON,
+			output: `{"id":"vm-8"}`,
+			wantID: "vm-8",
+		},
+		{
+			name:    "plain id via regex",
+			format:  OutputFormatRegex,
+			pattern: `^\s*(?P<id>\d+)\s*$`,
+			output:  " 9001\n",
+			wantID:  "9001",
+		},
+		{
+			name:    "unknown format",
+			format:  "yaml",
+			wantErr: true,
+		},
+		{
+			name:    "regex without id group",
+			format:  OutputFormatRegex,
+			pattern: `vm-(\d+)`,
+			wantErr: true,
+		},
+		{
+			name:    "regex that does not compile",
+			format:  OutputFormatRegex,
+			pattern: `(?P<id>`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewOutputParser(tt.format, tt.pattern)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewOutputParser() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			res, err := p.ParseProvision([]byte(tt.output))
+			if err != nil {
+				t.Fatalf("ParseProvision() error = %v", err)
+			}
+			if res.ResourceID != tt.wantID {
+				t.Errorf("ResourceID = %q, want %q", res.ResourceID, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestRegexParserReadsStatusGroup(t *testing.T) {
+	p, err := NewRegexParser(`vmid=(?P<id>\d+) state=(?P<status>\w+)`)
+	if err != nil {
+		t.Fatalf("NewRegexParser() error = %v", err)
+	}
+
+	res, err := p.ParseProvision([]byte("clone done: vmid=104 state=running"))
+	if err != nil {
+		t.Fatalf("ParseProvision() error = %v", err)
+	}
+	if res.ResourceID != "104" || res.Status != "running" {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+}
+
+func TestParsersReturnErrNoResourceID(t *testing.T) {
+	kv, err := NewOutputParser(OutputFormatKeyValue, "")
+	if err != nil {
+		t.Fatalf("NewOutputParser() error = %v", err)
+	}
+
+	parsers := map[string]struct {
+		parser OutputParser
+		output string
+	}{
+		"keyvalue": {kv, "no id here"},
+		"json":     {JSONParser{}, `{"status":"creating"}`},
+	}
+
+	for name, tc := range parsers {
+		t.Run(name, func(t *testing.T) {
+			_, err := tc.parser.ParseProvision([]byte(tc.output))
+			if !errors.Is(err, ErrNoResourceID) {
+				t.Fatalf("expected ErrNoResourceID, got %v", err)
+			}
+		})
+	}
+}
+
+func TestJSONParserRejectsMalformedOutput(t *testing.T) {
+	_, err := JSONParser{}.ParseProvision([]byte("ID: 12"))
+	if err == nil {
+		t.Fatal("expected an error for non-json output")
+	}
+}
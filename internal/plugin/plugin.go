@@ -22,10 +22,25 @@ type Plugin interface {
 
 // ProvisionRequest contains parameters for creating new external resources.
 type ProvisionRequest struct {
-	ProjectID   string                 `json:"project_id"`
-	ProjectName string                 `json:"project_name"`
-	Template    string                 `json:"template,omitempty"`
-	Resources   map[string]interface{} `json:"resources,omitempty"`
+	ProjectID   string `json:"project_id"`
+	ProjectName string `json:"project_name"`
+	// UnixName is the project's unique machine name, which its resource
+	// is named after. See ResourceName.
+	UnixName  string                 `json:"unix_name,omitempty"`
+	Template  string                 `json:"template,omitempty"`
+	Resources map[string]interface{} `json:"resources,omitempty"`
+	// Node places the resource on a specific cluster node. Empty leaves
+	// the choice to the plugin.
+	Node string `json:"node,omitempty"`
+	// Idempotent reuses an existing resource of the same name, if the
+	// plugin can look one up, instead of creating another.
+	Idempotent bool `json:"idempotent,omitempty"`
+	// SSHKeys are public keys, in authorized_keys format, to install on
+	// the resource. See ValidateSSHPublicKey.
+	SSHKeys []string `json:"ssh_keys,omitempty"`
+	// Tags label the resource in the backend, e.g. for billing, key to
+	// value. Plugins whose backend has no tags ignore them.
+	Tags map[string]string `json:"tags,omitempty"`
 }
 
 // ProvisionResult is the result of a successful provisioning attempt.
@@ -33,10 +48,17 @@ type ProvisionResult struct {
 	ResourceID string            `json:"resource_id"`
 	Metadata   map[string]string `json:"metadata,omitempty"`
 	Status     string            `json:"status"`
+	// Network is how to reach the resource, if the backend reported it.
+	Network *NetworkInfo `json:"network,omitempty"`
 }
 
 // StatusResult contains the current state of an external resource.
 type StatusResult struct {
 	Status   string            `json:"status"`
 	Metadata map[string]string `json:"metadata,omitempty"`
+	// Progress is how far along, from 0 to 100, a long-running operation
+	// on the resource is. Nil when the backend does not report it.
+	Progress *int `json:"progress,omitempty"`
+	// Network is how to reach the resource, once the backend reports it.
+	Network *NetworkInfo `json:"network,omitempty"`
 }
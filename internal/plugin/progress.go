@@ -0,0 +1,27 @@
+package plugin
+
+import (
+	"regexp"
+	"strconv"
+)
+
+// progressPattern matches a completion percentage reported as
+// "progress: 42%" or, as Proxmox clones do, "transferred 1.2 GiB of
+// 32.0 GiB (3.75%)".
+var progressPattern = regexp.MustCompile(`(?i)(?:progress[:=]?[ \t]*|transferred\b[^\n]*\()(\d{1,3})(?:\.\d+)?[ \t]*%`)
+
+// ParseProgress returns the last completion percentage reported in output,
+// truncated to a whole number and capped at 100. It reports false if there
+// is none.
+// Pure function.
+func ParseProgress(output string) (int, bool) {
+	matches := progressPattern.FindAllStringSubmatch(output, -1)
+	if len(matches) == 0 {
+		return 0, false
+	}
+	percent, err := strconv.Atoi(matches[len(matches)-1][1])
+	if err != nil {
+		return 0, false
+	}
+	return min(percent, 100), true
+}
@@ -0,0 +1,49 @@
+package plugin
+
+import "testing"
+
+func TestParseProgress(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   int
+		wantOK bool
+	}{
+		{
+			name:   "progress line",
+			output: "status: cloning\nprogress: 42%\n",
+			want:   42,
+			wantOK: true,
+		},
+		{
+			name: "proxmox clone keeps the latest",
+			output: "create full clone of drive scsi0 (local-lvm:base-9000-disk-0)\n" +
+				"drive-scsi0: transferred 1.2 GiB of 32.0 GiB (3.75%) in 5s\n" +
+				"drive-scsi0: transferred 20.5 GiB of 32.0 GiB (64.06%) in 1m 2s\n",
+			want:   64,
+			wantOK: true,
+		},
+		{
+			name:   "capped at 100",
+			output: "Progress=250 %",
+			want:   100,
+			wantOK: true,
+		},
+		{
+			name:   "no progress reported",
+			output: "status: running\nuptime: 3600\n",
+		},
+		{
+			name:   "unrelated percentage",
+			output: "cpu: 12%\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseProgress(tt.output)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("ParseProgress() = %d, %v, want %d, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -1,9 +1,13 @@
 package plugin
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"sort"
 	"sync"
+	"time"
 )
 
 var (
@@ -15,13 +19,63 @@ var (
 type Registry struct {
 	mu      sync.RWMutex
 	plugins map[string]Plugin
+
+	healthTimeout  time.Duration
+	healthTimeouts map[string]time.Duration
+	callLog        *slog.Logger
+	concurrency    map[string]int
+}
+
+// RegistryOption configures optional Registry behaviour.
+type RegistryOption func(*Registry)
+
+// WithHealthTimeout bounds each plugin's health check in HealthAll, unless
+// the plugin has its own timeout. Zero leaves checks bounded only by the
+// caller's context.
+func WithHealthTimeout(d time.Duration) RegistryOption {
+	return func(r *Registry) {
+		r.healthTimeout = d
+	}
+}
+
+// WithPluginHealthTimeout bounds the named plugin's health check in
+// HealthAll by d instead of the default, e.g. for a plugin whose check
+// shells out to a slow CLI.
+func WithPluginHealthTimeout(name string, d time.Duration) RegistryOption {
+	return func(r *Registry) {
+		r.healthTimeouts[name] = d
+	}
+}
+
+// WithProvisionConcurrency caps how many provisioning calls the named
+// plugin, once registered, runs at once, e.g. to match what its backend
+// can take. Each plugin is limited independently; zero leaves it
+// unlimited. See WithConcurrencyLimit.
+func WithProvisionConcurrency(name string, max int) RegistryOption {
+	return func(r *Registry) {
+		r.concurrency[name] = max
+	}
+}
+
+// WithCallLogging wraps every plugin registered from then on with
+// WithLogging, so all plugins log their calls the same way to logger.
+func WithCallLogging(logger *slog.Logger) RegistryOption {
+	return func(r *Registry) {
+		r.callLog = logger
+	}
 }
 
 // NewRegistry creates a new empty plugin registry.
-func NewRegistry() *Registry {
-	return &Registry{
-		plugins: make(map[string]Plugin),
+func NewRegistry(opts ...RegistryOption) *Registry {
+	r := &Registry{
+		plugins:        make(map[string]Plugin),
+		healthTimeouts: make(map[string]time.Duration),
+		concurrency:    make(map[string]int),
 	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
 }
 
 // Register adds a plugin to the registry. Returns an error if a plugin with
@@ -35,6 +89,10 @@ func (r *Registry) Register(p Plugin) error {
 		return fmt.Errorf("plugin %q already registered", name)
 	}
 
+	if r.callLog != nil {
+		p = WithLogging(p, r.callLog)
+	}
+	p = WithConcurrencyLimit(p, r.concurrency[name])
 	r.plugins[name] = p
 	return nil
 }
@@ -63,3 +121,68 @@ func (r *Registry) List() []Plugin {
 	}
 	return list
 }
+
+// HealthResult is the outcome of a single plugin health check.
+type HealthResult struct {
+	Name string
+	Err  error
+	// Latency is how long the check took, up to its timeout.
+	Latency time.Duration
+}
+
+// Healthy reports whether the plugin passed its health check.
+func (h HealthResult) Healthy() bool {
+	return h.Err == nil
+}
+
+// HealthAll runs every registered plugin's health check concurrently and
+// returns the results, with their latencies, sorted by plugin name. Each
+// check is bounded by its plugin's health timeout; one that overruns it
+// reports context.DeadlineExceeded without holding up the others, even if
+// the plugin ignores its context.
+func (r *Registry) HealthAll(ctx context.Context) []HealthResult {
+	plugins := r.List()
+
+	results := make([]HealthResult, len(plugins))
+	var wg sync.WaitGroup
+	for i, p := range plugins {
+		wg.Add(1)
+		go func(i int, p Plugin) {
+			defer wg.Done()
+			start := time.Now()
+			err := r.health(ctx, p)
+			results[i] = HealthResult{Name: p.Name(), Err: err, Latency: time.Since(start)}
+		}(i, p)
+	}
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Name < results[j].Name
+	})
+	return results
+}
+
+// health runs p's health check within its timeout.
+func (r *Registry) health(ctx context.Context, p Plugin) error {
+	timeout, ok := r.healthTimeouts[p.Name()]
+	if !ok {
+		timeout = r.healthTimeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// Buffered so a check that outlives its timeout can still finish.
+	done := make(chan error, 1)
+	go func() {
+		done <- p.Health(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("health check abandoned: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,129 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type fakePlugin struct {
+	name     string
+	healthFn func(context.Context) error
+}
+
+func (f fakePlugin) Name() string { return f.name }
+
+func (f fakePlugin) Health(ctx context.Context) error {
+	if f.healthFn == nil {
+		return nil
+	}
+	return f.healthFn(ctx)
+}
+
+func (f fakePlugin) Provision(context.Context, ProvisionRequest) (*ProvisionResult, error) {
+	return &ProvisionResult{ResourceID: "res-1", Status: "ok"}, nil
+}
+
+func (f fakePlugin) Status(context.Context, string) (*StatusResult, error) {
+	return &StatusResult{Status: "running"}, nil
+}
+
+func (f fakePlugin) Deprovision(context.Context, string) error { return nil }
+
+func TestRegistryHealthAllReportsEveryPluginSortedByName(t *testing.T) {
+	r := NewRegistry()
+	broken := errors.New("cli not found")
+	for _, p := range []Plugin{
+		fakePlugin{name: "proxmox", healthFn: func(context.Context) error { return broken }},
+		fakePlugin{name: "gitlab"},
+	} {
+		if err := r.Register(p); err != nil {
+			t.Fatalf("Register() error = %v", err)
+		}
+	}
+
+	results := r.HealthAll(context.Background())
+
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	if results[0].Name != "gitlab" || !results[0].Healthy() {
+		t.Errorf("unexpected first result: %+v", results[0])
+	}
+	if results[1].Name != "proxmox" || !errors.Is(results[1].Err, broken) {
+		t.Errorf("unexpected second result: %+v", results[1])
+	}
+}
+
+func TestRegistryHealthAllMeasuresLatency(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Register(fakePlugin{name: "proxmox", healthFn: func(context.Context) error {
+		time.Sleep(20 * time.Millisecond)
+		return nil
+	}}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	results := r.HealthAll(context.Background())
+
+	if len(results) != 1 || results[0].Latency < 20*time.Millisecond {
+		t.Errorf("expected a latency of at least 20ms, got %+v", results)
+	}
+}
+
+func TestRegistryHealthAllAppliesTimeoutsPerPlugin(t *testing.T) {
+	// slow takes 200ms and honours its context; stuck ignores it entirely.
+	slow := func(ctx context.Context) error {
+		select {
+		case <-time.After(200 * time.Millisecond):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	release := make(chan struct{})
+	defer close(release)
+	stuck := func(context.Context) error {
+		<-release
+		return nil
+	}
+
+	r := NewRegistry(
+		WithHealthTimeout(50*time.Millisecond),
+		WithPluginHealthTimeout("proxmox", time.Second),
+		WithPluginHealthTimeout("stuck", 50*time.Millisecond),
+	)
+	for _, p := range []Plugin{
+		fakePlugin{name: "fake"},
+		fakePlugin{name: "proxmox", healthFn: slow},
+		fakePlugin{name: "gitlab", healthFn: slow},
+		fakePlugin{name: "stuck", healthFn: stuck},
+	} {
+		if err := r.Register(p); err != nil {
+			t.Fatalf("Register() error = %v", err)
+		}
+	}
+
+	start := time.Now()
+	results := r.HealthAll(context.Background())
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("HealthAll took %s, want it bounded by the slowest timeout", elapsed)
+	}
+
+	want := map[string]bool{"fake": true, "gitlab": false, "proxmox": true, "stuck": false}
+	for _, res := range results {
+		if res.Healthy() != want[res.Name] {
+			t.Errorf("%s healthy = %v, want %v (err: %v)", res.Name, res.Healthy(), want[res.Name], res.Err)
+		}
+		if !res.Healthy() && !errors.Is(res.Err, context.DeadlineExceeded) {
+			t.Errorf("%s error = %v, want context.DeadlineExceeded", res.Name, res.Err)
+		}
+	}
+}
+
+func TestRegistryHealthAllWithNoPlugins(t *testing.T) {
+	if results := NewRegistry().HealthAll(context.Background()); len(results) != 0 {
+		t.Fatalf("expected no results, got %d", len(results))
+	}
+}
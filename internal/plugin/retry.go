@@ -0,0 +1,159 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrRetryBudgetExhausted is returned when the total time allowed for an
+// operation runs out before it succeeds, whether or not attempts remain.
+// It also wraps context.DeadlineExceeded.
+var ErrRetryBudgetExhausted = errors.New("retry budget exhausted")
+
+// RetryPolicy controls how WithRetry repeats failed provisioning calls.
+type RetryPolicy struct {
+	// Attempts is the maximum number of calls, including the first.
+	Attempts int
+	// CallTimeout bounds each attempt. Zero leaves attempts unbounded.
+	CallTimeout time.Duration
+	// Budget bounds all attempts and the waits between them together,
+	// measured from the first call. Zero leaves the total unbounded.
+	Budget time.Duration
+	// Backoff is the wait between attempts.
+	Backoff time.Duration
+}
+
+// retryPlugin repeats failed provisioning within a shared time budget.
+type retryPlugin struct {
+	Plugin
+	policy RetryPolicy
+}
+
+// WithRetry wraps p so failed Provision calls are repeated up to
+// policy.Attempts times, as long as each failure is classified transient
+// (see ErrorClass.Retryable). A failed attempt may still have created the
+// resource, so a request without Idempotent set is only repeated once
+// FindResource confirms nothing exists under ResourceName(req); if the
+// resource does exist it is returned instead, and if it cannot be looked
+// up the call is not repeated. Every attempt, and every wait between attempts,
+// draws from one deadline of policy.Budget, so retries stop as soon as the
+// budget is spent. A policy that neither retries nor times out returns p
+// as-is.
+func WithRetry(p Plugin, policy RetryPolicy) Plugin {
+	if policy.Attempts <= 1 && policy.CallTimeout == 0 && policy.Budget == 0 {
+		return p
+	}
+	if policy.Attempts < 1 {
+		policy.Attempts = 1
+	}
+	return &retryPlugin{Plugin: p, policy: policy}
+}
+
+// Unwrap returns the plugin r wraps.
+func (r *retryPlugin) Unwrap() Plugin {
+	return r.Plugin
+}
+
+// Provision implements Plugin.
+func (r *retryPlugin) Provision(ctx context.Context, req ProvisionRequest) (*ProvisionResult, error) {
+	return r.retry(ctx, req, func(ctx context.Context) (*ProvisionResult, error) {
+		return r.Plugin.Provision(ctx, req)
+	})
+}
+
+// ProvisionStream implements StreamingProvisioner. Output from every attempt
+// is passed to out.
+func (r *retryPlugin) ProvisionStream(ctx context.Context, req ProvisionRequest, out func(line string)) (*ProvisionResult, error) {
+	return r.retry(ctx, req, func(ctx context.Context) (*ProvisionResult, error) {
+		return ProvisionStream(ctx, r.Plugin, req, out)
+	})
+}
+
+// SetTags implements ResourceTagger, through the plugin r wraps. Setting
+// tags is idempotent, so it is repeated like a Provision with Idempotent
+// set, within the same limits. Nothing is attempted if no plugin in the
+// chain can tag resources.
+func (r *retryPlugin) SetTags(ctx context.Context, resourceID string, tags map[string]string) error {
+	if tagger(r.Plugin) == nil {
+		return errUnsupportedTags
+	}
+	_, err := r.retry(ctx, ProvisionRequest{Idempotent: true}, func(ctx context.Context) (*ProvisionResult, error) {
+		return nil, SetTags(ctx, r.Plugin, resourceID, tags)
+	})
+	return err
+}
+
+func (r *retryPlugin) retry(ctx context.Context, req ProvisionRequest, call func(ctx context.Context) (*ProvisionResult, error)) (*ProvisionResult, error) {
+	budgetCtx := ctx
+	if r.policy.Budget > 0 {
+		var cancel context.CancelFunc
+		budgetCtx, cancel = context.WithTimeout(ctx, r.policy.Budget)
+		defer cancel()
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= r.policy.Attempts; attempt++ {
+		result, err := r.attempt(budgetCtx, call)
+		if err == nil {
+			return result, nil
+		}
+		lastErr = err
+
+		// The caller gave up: report that rather than the budget.
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		if budgetCtx.Err() != nil {
+			return nil, exhausted(attempt, lastErr)
+		}
+		// Repeating a call that may have got anywhere, or that the
+		// backend rejected outright, cannot help.
+		if !Classify(err).Retryable() {
+			return nil, err
+		}
+		if attempt == r.policy.Attempts {
+			break
+		}
+
+		timer := time.NewTimer(r.policy.Backoff)
+		select {
+		case <-timer.C:
+		case <-budgetCtx.Done():
+			timer.Stop()
+			if ctx.Err() != nil {
+				return nil, lastErr
+			}
+			return nil, exhausted(attempt, lastErr)
+		}
+
+		if !req.Idempotent {
+			existing, err := FindResource(budgetCtx, r.Plugin, ResourceName(req))
+			switch {
+			case err == nil:
+				return existing, nil
+			case !errors.Is(err, ErrResourceNotFound):
+				return nil, lastErr
+			}
+		}
+	}
+	return nil, fmt.Errorf("after %d attempts: %w", r.policy.Attempts, lastErr)
+}
+
+// attempt makes one call bounded by CallTimeout, within the budget.
+func (r *retryPlugin) attempt(ctx context.Context, call func(ctx context.Context) (*ProvisionResult, error)) (*ProvisionResult, error) {
+	if r.policy.CallTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.policy.CallTimeout)
+		defer cancel()
+	}
+	return call(ctx)
+}
+
+// exhausted reports a budget that ran out after attempts calls.
+// Pure function.
+func exhausted(attempts int, lastErr error) error {
+	return fmt.Errorf("%w after %d attempts: %w (last error: %v)",
+		ErrRetryBudgetExhausted, attempts, context.DeadlineExceeded, lastErr)
+}
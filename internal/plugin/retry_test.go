@@ -0,0 +1,200 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// flakyPlugin fails its first failures calls, with err if set. Each call
+// waits for delay or until its context ends, whichever comes first.
+type flakyPlugin struct {
+	fakePlugin
+	failures int
+	delay    time.Duration
+	calls    int
+	err      error
+}
+
+func (f *flakyPlugin) Provision(ctx context.Context, _ ProvisionRequest) (*ProvisionResult, error) {
+	f.calls++
+	timer := time.NewTimer(f.delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if f.calls <= f.failures {
+		if f.err != nil {
+			return nil, f.err
+		}
+		return nil, &PluginError{Plugin: "proxmox", Op: OpProvision, Class: ClassTransient, Err: errors.New("connection reset")}
+	}
+	return &ProvisionResult{ResourceID: "vm-1", Status: "provisioned"}, nil
+}
+
+func TestWithRetryRepeatsUntilSuccess(t *testing.T) {
+	fake := &flakyPlugin{fakePlugin: fakePlugin{name: "proxmox"}, failures: 2}
+	p := WithRetry(fake, RetryPolicy{Attempts: 3})
+
+	res, err := p.Provision(context.Background(), ProvisionRequest{UnixName: "alpha", Idempotent: true})
+	if err != nil {
+		t.Fatalf("Provision() error = %v", err)
+	}
+	if res.ResourceID != "vm-1" || fake.calls != 3 {
+		t.Errorf("expected success on the third call, got %+v after %d calls", res, fake.calls)
+	}
+}
+
+func TestWithRetryGivesUpAfterAttempts(t *testing.T) {
+	fake := &flakyPlugin{fakePlugin: fakePlugin{name: "proxmox"}, failures: 5}
+	p := WithRetry(fake, RetryPolicy{Attempts: 2})
+
+	if _, err := p.Provision(context.Background(), ProvisionRequest{Idempotent: true}); err == nil {
+		t.Fatal("expected an error once attempts run out")
+	}
+	if fake.calls != 2 {
+		t.Errorf("expected 2 calls, got %d", fake.calls)
+	}
+}
+
+func TestWithRetrySkipsRetryForRejectedRequests(t *testing.T) {
+	rejected := &PluginError{Plugin: "proxmox", Op: OpProvision, Class: ClassInvalidInput, Err: errors.New("unknown template")}
+	fake := &flakyPlugin{fakePlugin: fakePlugin{name: "proxmox"}, failures: 5, err: rejected}
+	p := WithRetry(fake, RetryPolicy{Attempts: 3})
+
+	_, err := p.Provision(context.Background(), ProvisionRequest{})
+	if Classify(err) != ClassInvalidInput {
+		t.Fatalf("expected the invalid input error, got %v", err)
+	}
+	if fake.calls != 1 {
+		t.Errorf("expected 1 call, got %d", fake.calls)
+	}
+}
+
+func TestWithRetryStopsWhenBudgetExpiresMidRetry(t *testing.T) {
+	// Each attempt fails after 40ms; the 100ms budget runs out during the
+	// third attempt, long before all ten are used.
+	fake := &flakyPlugin{fakePlugin: fakePlugin{name: "proxmox"}, failures: 10, delay: 40 * time.Millisecond}
+	p := WithRetry(fake, RetryPolicy{
+		Attempts:    10,
+		CallTimeout: time.Second,
+		Budget:      100 * time.Millisecond,
+		Backoff:     5 * time.Millisecond,
+	})
+
+	start := time.Now()
+	_, err := p.Provision(context.Background(), ProvisionRequest{Idempotent: true})
+	elapsed := time.Since(start)
+
+	if !errors.Is(err, ErrRetryBudgetExhausted) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a budget deadline error, got %v", err)
+	}
+	if elapsed > 300*time.Millisecond {
+		t.Errorf("retry loop outlived its budget, took %v", elapsed)
+	}
+	if fake.calls >= 10 {
+		t.Errorf("expected the budget to cut attempts short, got %d calls", fake.calls)
+	}
+}
+
+func TestWithRetryBoundsEachAttempt(t *testing.T) {
+	fake := &flakyPlugin{fakePlugin: fakePlugin{name: "proxmox"}, delay: time.Second}
+	p := WithRetry(fake, RetryPolicy{Attempts: 2, CallTimeout: 20 * time.Millisecond})
+
+	_, err := p.Provision(context.Background(), ProvisionRequest{Idempotent: true})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the call to time out, got %v", err)
+	}
+	// The plugin did not classify the timeout, so it may have got anywhere.
+	if fake.calls != 1 {
+		t.Errorf("expected an unclassified timeout not to be retried, got %d calls", fake.calls)
+	}
+}
+
+func TestWithRetrySkipsRetryForUnclassifiedFailures(t *testing.T) {
+	fake := &flakyPlugin{fakePlugin: fakePlugin{name: "proxmox"}, failures: 5, err: errors.New("exit status 1")}
+	p := WithRetry(fake, RetryPolicy{Attempts: 3})
+
+	if _, err := p.Provision(context.Background(), ProvisionRequest{Idempotent: true}); err == nil {
+		t.Fatal("expected the unclassified failure to be returned")
+	}
+	if fake.calls != 1 {
+		t.Errorf("expected 1 call, got %d", fake.calls)
+	}
+}
+
+// halfDonePlugin creates its resource on the first call and then fails,
+// as a CLI that loses its connection after the VM exists would.
+type halfDonePlugin struct {
+	finderPlugin
+}
+
+func (h *halfDonePlugin) Provision(_ context.Context, req ProvisionRequest) (*ProvisionResult, error) {
+	h.provisions++
+	if h.existing == nil {
+		h.existing = make(map[string]*ProvisionResult)
+	}
+	h.existing[ResourceName(req)] = &ProvisionResult{ResourceID: "vm-1", Status: "provisioned"}
+	return nil, &PluginError{Plugin: "proxmox", Op: OpProvision, Class: ClassTransient, Err: errors.New("connection reset")}
+}
+
+func TestWithRetryReturnsResourceCreatedByFailedAttempt(t *testing.T) {
+	fake := &halfDonePlugin{finderPlugin{fakePlugin: fakePlugin{name: "proxmox"}}}
+	p := WithRetry(fake, RetryPolicy{Attempts: 3})
+
+	res, err := p.Provision(context.Background(), ProvisionRequest{UnixName: "alpha"})
+	if err != nil {
+		t.Fatalf("Provision() error = %v", err)
+	}
+	if res.ResourceID != "vm-1" {
+		t.Errorf("expected the resource the failed attempt created, got %+v", res)
+	}
+	if fake.provisions != 1 {
+		t.Errorf("expected no second create, got %d provisions", fake.provisions)
+	}
+}
+
+func TestWithRetryRepeatsCreateWhenNothingWasLeftBehind(t *testing.T) {
+	fake := &flakyFinder{flakyPlugin: flakyPlugin{fakePlugin: fakePlugin{name: "proxmox"}, failures: 1}}
+	p := WithRetry(fake, RetryPolicy{Attempts: 2})
+
+	if _, err := p.Provision(context.Background(), ProvisionRequest{UnixName: "alpha"}); err != nil {
+		t.Fatalf("Provision() error = %v", err)
+	}
+	if fake.calls != 2 || fake.lookups != 1 {
+		t.Errorf("expected one lookup before the second call, got %d calls and %d lookups", fake.calls, fake.lookups)
+	}
+}
+
+func TestWithRetryDoesNotRepeatCreateItCannotCheck(t *testing.T) {
+	fake := &flakyPlugin{fakePlugin: fakePlugin{name: "proxmox"}, failures: 1}
+	p := WithRetry(fake, RetryPolicy{Attempts: 3})
+
+	if _, err := p.Provision(context.Background(), ProvisionRequest{UnixName: "alpha"}); err == nil {
+		t.Fatal("expected the first failure to be returned")
+	}
+	if fake.calls != 1 {
+		t.Errorf("expected a create that cannot be checked to run once, got %d calls", fake.calls)
+	}
+}
+
+// flakyFinder is a flakyPlugin whose failures leave nothing behind.
+type flakyFinder struct {
+	flakyPlugin
+	lookups int
+}
+
+func (f *flakyFinder) FindResource(context.Context, string) (*ProvisionResult, error) {
+	f.lookups++
+	return nil, ErrResourceNotFound
+}
+
+func TestWithRetryLeavesPluginUnwrappedWithoutPolicy(t *testing.T) {
+	fake := &flakyPlugin{fakePlugin: fakePlugin{name: "proxmox"}}
+	if _, wrapped := WithRetry(fake, RetryPolicy{Attempts: 1}).(*retryPlugin); wrapped {
+		t.Error("expected a single-attempt policy without timeouts to return the plugin as-is")
+	}
+}
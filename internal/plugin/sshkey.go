@@ -0,0 +1,56 @@
+package plugin
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidSSHKey is returned for a string that is not a single public key
+// in authorized_keys format.
+var ErrInvalidSSHKey = errors.New("invalid ssh public key")
+
+// sshKeyTypes are the key algorithms that may be injected into resources.
+var sshKeyTypes = map[string]struct{}{
+	"ssh-ed25519":                        {},
+	"ssh-rsa":                            {},
+	"ecdsa-sha2-nistp256":                {},
+	"ecdsa-sha2-nistp384":                {},
+	"ecdsa-sha2-nistp521":                {},
+	"sk-ssh-ed25519@openssh.com":         {},
+	"sk-ecdsa-sha2-nistp256@openssh.com": {},
+}
+
+// ValidateSSHPublicKey checks that key is one authorized_keys line without
+// options: a known key type, the base64 key blob, which must itself name
+// that type, and an optional comment. Pure function.
+func ValidateSSHPublicKey(key string) error {
+	if strings.ContainsAny(key, "\r\n") {
+		return fmt.Errorf("%w: must be a single line", ErrInvalidSSHKey)
+	}
+	fields := strings.Fields(key)
+	if len(fields) < 2 {
+		return fmt.Errorf("%w: want \"<type> <base64 key> [comment]\"", ErrInvalidSSHKey)
+	}
+
+	keyType := fields[0]
+	if _, ok := sshKeyTypes[keyType]; !ok {
+		return fmt.Errorf("%w: unsupported key type %q", ErrInvalidSSHKey, keyType)
+	}
+	blob, err := base64.StdEncoding.DecodeString(fields[1])
+	if err != nil {
+		return fmt.Errorf("%w: key is not valid base64", ErrInvalidSSHKey)
+	}
+	// The blob starts with its own type as an SSH string: a big-endian
+	// uint32 length, then the name.
+	if len(blob) < 4 {
+		return fmt.Errorf("%w: key is truncated", ErrInvalidSSHKey)
+	}
+	n := binary.BigEndian.Uint32(blob)
+	if uint64(len(blob)-4) <= uint64(n) || string(blob[4:4+n]) != keyType {
+		return fmt.Errorf("%w: key does not match its type %q", ErrInvalidSSHKey, keyType)
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package plugin
+
+import (
+	"errors"
+	"testing"
+)
+
+const testSSHKey = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIAABAgMEBQYHCAkKCwwNDg8QERITFBUWFxgZGhscHR4f ops@example"
+
+func TestValidateSSHPublicKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		wantErr bool
+	}{
+		{name: "ed25519 with comment", key: testSSHKey},
+		{name: "without comment", key: "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIAABAgMEBQYHCAkKCwwNDg8QERITFBUWFxgZGhscHR4f"},
+		{name: "empty", key: "", wantErr: true},
+		{name: "type only", key: "ssh-ed25519", wantErr: true},
+		{name: "unknown type", key: "ssh-dss AAAAB3NzaC1kc3MAAACBAP", wantErr: true},
+		{name: "not base64", key: "ssh-ed25519 not*base64", wantErr: true},
+		{name: "mismatched type", key: "ssh-rsa AAAAC3NzaC1lZDI1NTE5AAAAIAABAgMEBQYHCAkKCwwNDg8QERITFBUWFxgZGhscHR4f", wantErr: true},
+		{name: "truncated blob", key: "ssh-ed25519 AAAA", wantErr: true},
+		{name: "two lines", key: testSSHKey + "\n" + testSSHKey, wantErr: true},
+		{name: "with options", key: `command="rm -rf /" ` + testSSHKey, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSSHPublicKey(tt.key)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidSSHKey) {
+					t.Fatalf("ValidateSSHPublicKey() error = %v, want ErrInvalidSSHKey", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ValidateSSHPublicKey() error = %v", err)
+			}
+		})
+	}
+}
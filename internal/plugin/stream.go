@@ -0,0 +1,20 @@
+package plugin
+
+import "context"
+
+// StreamingProvisioner is implemented by plugins that can report provisioning
+// output line by line while it is being produced.
+type StreamingProvisioner interface {
+	// ProvisionStream behaves like Provision and calls out with every line
+	// of output, in order, before returning. out may be nil.
+	ProvisionStream(ctx context.Context, req ProvisionRequest, out func(line string)) (*ProvisionResult, error)
+}
+
+// ProvisionStream provisions through p, streaming output to out when p
+// supports it. Other plugins are provisioned normally and emit no lines.
+func ProvisionStream(ctx context.Context, p Plugin, req ProvisionRequest, out func(line string)) (*ProvisionResult, error) {
+	if sp, ok := p.(StreamingProvisioner); ok && out != nil {
+		return sp.ProvisionStream(ctx, req, out)
+	}
+	return p.Provision(ctx, req)
+}
@@ -0,0 +1,48 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ResourceTagger is implemented by plugins that can change the tags of a
+// resource after it was provisioned.
+type ResourceTagger interface {
+	// SetTags replaces the tags of resourceID with tags, key to value.
+	// Tags the resource had that are not in tags are removed.
+	SetTags(ctx context.Context, resourceID string, tags map[string]string) error
+}
+
+// SetTags replaces the tags of resourceID through p, or through the plugin
+// p decorates, whichever implements ResourceTagger first. Decorators that
+// retry, time out, audit or log calls implement it themselves, so the call
+// goes through them. It returns errors.ErrUnsupported when no plugin in
+// the chain can tag resources.
+func SetTags(ctx context.Context, p Plugin, resourceID string, tags map[string]string) error {
+	t := tagger(p)
+	if t == nil {
+		return errUnsupportedTags
+	}
+	return t.SetTags(ctx, resourceID, tags)
+}
+
+// errUnsupportedTags is returned by SetTags for a plugin chain that cannot
+// tag resources.
+var errUnsupportedTags = fmt.Errorf("set tags: %w", errors.ErrUnsupported)
+
+// tagger returns p, or the plugin p decorates, whichever implements
+// ResourceTagger first, or nil if none does.
+func tagger(p Plugin) ResourceTagger {
+	for p != nil {
+		if t, ok := p.(ResourceTagger); ok {
+			return t
+		}
+		w, ok := p.(interface{ Unwrap() Plugin })
+		if !ok {
+			break
+		}
+		p = w.Unwrap()
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"testing"
+)
+
+// taggerPlugin records the tags it was asked to set, failing its first
+// failures calls as transient.
+type taggerPlugin struct {
+	fakePlugin
+	failures   int
+	calls      int
+	resourceID string
+	tags       map[string]string
+}
+
+func (f *taggerPlugin) SetTags(_ context.Context, resourceID string, tags map[string]string) error {
+	f.calls++
+	if f.calls <= f.failures {
+		return &PluginError{Plugin: "proxmox", Op: OpTag, Class: ClassTransient, Err: errors.New("cluster busy")}
+	}
+	f.resourceID = resourceID
+	f.tags = tags
+	return nil
+}
+
+func TestSetTagsSeesThroughDecorators(t *testing.T) {
+	fake := &taggerPlugin{fakePlugin: fakePlugin{name: "proxmox"}}
+	p := WithLogging(WithAudit(WithRetry(fake, RetryPolicy{Attempts: 3}), &memoryRecorder{}), nil)
+
+	tags := map[string]string{"team": "payments"}
+	if err := SetTags(context.Background(), p, "vm-1", tags); err != nil {
+		t.Fatalf("SetTags() error = %v", err)
+	}
+	if fake.resourceID != "vm-1" || !maps.Equal(fake.tags, tags) {
+		t.Errorf("tagged %q with %v, want vm-1 with %v", fake.resourceID, fake.tags, tags)
+	}
+
+	err := SetTags(context.Background(), WithLogging(fakePlugin{name: "gitlab"}, nil), "vm-1", tags)
+	if !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("SetTags() on a non-tagger error = %v, want errors.ErrUnsupported", err)
+	}
+}
+
+func TestSetTagsGoesThroughDecorators(t *testing.T) {
+	fake := &taggerPlugin{fakePlugin: fakePlugin{name: "proxmox"}, failures: 1}
+	rec := &memoryRecorder{}
+	p := WithAudit(WithRetry(fake, RetryPolicy{Attempts: 3}), rec)
+
+	if err := SetTags(context.Background(), p, "vm-1", map[string]string{"team": "payments"}); err != nil {
+		t.Fatalf("SetTags() error = %v", err)
+	}
+	if fake.calls != 2 {
+		t.Errorf("tagger called %d times, want the transient failure retried once", fake.calls)
+	}
+	if len(rec.ops) != 1 || rec.ops[0].Name != OpTag || rec.ops[0].Err != nil {
+		t.Errorf("audit recorded %+v, want one successful tag operation", rec.ops)
+	}
+
+	rec.ops = nil
+	err := SetTags(context.Background(), WithAudit(fakePlugin{name: "gitlab"}, rec), "vm-1", nil)
+	if !errors.Is(err, errors.ErrUnsupported) || len(rec.ops) != 0 {
+		t.Errorf("SetTags() on a non-tagger error = %v with %d records, want errors.ErrUnsupported and none", err, len(rec.ops))
+	}
+}
@@ -0,0 +1,44 @@
+package plugin
+
+// RequestError reports the field of a provisioning request a plugin would
+// reject.
+type RequestError struct {
+	// Field is the ProvisionRequest field at fault, by its JSON name,
+	// e.g. unix_name.
+	Field string
+	Err   error
+}
+
+func (e *RequestError) Error() string {
+	return e.Field + ": " + e.Err.Error()
+}
+
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+// RequestValidator is implemented by plugins that can tell, without I/O,
+// that they would reject a provisioning request, so it can be refused
+// before anything is stored.
+type RequestValidator interface {
+	// ValidateRequest returns a *RequestError for a request the plugin
+	// cannot provision.
+	ValidateRequest(req ProvisionRequest) error
+}
+
+// ValidateRequest checks req through p, or through the plugin p decorates,
+// whichever implements RequestValidator first. Plugins implementing none
+// accept every request.
+func ValidateRequest(p Plugin, req ProvisionRequest) error {
+	for p != nil {
+		if v, ok := p.(RequestValidator); ok {
+			return v.ValidateRequest(req)
+		}
+		w, ok := p.(interface{ Unwrap() Plugin })
+		if !ok {
+			break
+		}
+		p = w.Unwrap()
+	}
+	return nil
+}
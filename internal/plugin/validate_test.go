@@ -0,0 +1,33 @@
+package plugin
+
+import (
+	"errors"
+	"testing"
+)
+
+// validatingPlugin rejects requests without a unix name.
+type validatingPlugin struct {
+	fakePlugin
+}
+
+func (validatingPlugin) ValidateRequest(req ProvisionRequest) error {
+	if req.UnixName == "" {
+		return &RequestError{Field: "unix_name", Err: errors.New("required")}
+	}
+	return nil
+}
+
+func TestValidateRequestSeesThroughDecorators(t *testing.T) {
+	p := WithLogging(WithAudit(WithRetry(validatingPlugin{fakePlugin{name: "proxmox"}}, RetryPolicy{Attempts: 3}), &memoryRecorder{}), nil)
+
+	var reqErr *RequestError
+	if err := ValidateRequest(p, ProvisionRequest{ProjectID: "p-1"}); !errors.As(err, &reqErr) || reqErr.Field != "unix_name" {
+		t.Errorf("ValidateRequest() error = %v, want a RequestError on unix_name", err)
+	}
+	if err := ValidateRequest(p, ProvisionRequest{ProjectID: "p-1", UnixName: "alpha"}); err != nil {
+		t.Errorf("ValidateRequest() error = %v, want the request accepted", err)
+	}
+	if err := ValidateRequest(WithLogging(fakePlugin{name: "gitlab"}, nil), ProvisionRequest{}); err != nil {
+		t.Errorf("ValidateRequest() on a plugin without checks error = %v, want nil", err)
+	}
+}
@@ -0,0 +1,61 @@
+package projects
+
+import (
+	"context"
+	"sync"
+)
+
+// subscriberBuffer is how many events a subscriber may fall behind by
+// before it starts missing them.
+const subscriberBuffer = 16
+
+// Broadcaster is an EventPublisher that fans each event out to everyone
+// watching its project, e.g. event stream clients. Delivery never blocks
+// the publisher: a subscriber that falls behind misses events.
+type Broadcaster struct {
+	mu   sync.Mutex
+	subs map[string]map[chan Event]struct{}
+}
+
+// NewBroadcaster returns a Broadcaster with no subscribers.
+func NewBroadcaster() *Broadcaster {
+	return &Broadcaster{subs: map[string]map[chan Event]struct{}{}}
+}
+
+// Subscribe returns a channel receiving events about the project until
+// unsubscribe is called.
+func (b *Broadcaster) Subscribe(projectID string) (events <-chan Event, unsubscribe func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	b.mu.Lock()
+	if b.subs[projectID] == nil {
+		b.subs[projectID] = map[chan Event]struct{}{}
+	}
+	b.subs[projectID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			delete(b.subs[projectID], ch)
+			if len(b.subs[projectID]) == 0 {
+				delete(b.subs, projectID)
+			}
+		})
+	}
+}
+
+// Publish implements EventPublisher.
+func (b *Broadcaster) Publish(_ context.Context, event Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs[event.ProjectID] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+	return nil
+}
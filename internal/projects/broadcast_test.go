@@ -0,0 +1,179 @@
+package projects
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/searge/quokka/internal/platform"
+)
+
+func TestBroadcasterDeliversToProjectSubscribers(t *testing.T) {
+	b := NewBroadcaster()
+	watched, unsubscribe := b.Subscribe("p-1")
+	other, unsubscribeOther := b.Subscribe("p-2")
+	defer unsubscribeOther()
+
+	if err := b.Publish(context.Background(), Event{Type: EventProvisioned, ProjectID: "p-1"}); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	select {
+	case ev := <-watched:
+		if ev.Type != EventProvisioned {
+			t.Errorf("received %s, want %s", ev.Type, EventProvisioned)
+		}
+	default:
+		t.Fatal("expected the subscriber to receive the event")
+	}
+	select {
+	case ev := <-other:
+		t.Errorf("subscriber of another project received %+v", ev)
+	default:
+	}
+
+	unsubscribe()
+	unsubscribe()
+	if err := b.Publish(context.Background(), Event{Type: EventProvisioned, ProjectID: "p-1"}); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	select {
+	case ev := <-watched:
+		t.Errorf("unsubscribed channel received %+v", ev)
+	default:
+	}
+}
+
+func TestBroadcasterNeverBlocksOnSlowSubscribers(t *testing.T) {
+	b := NewBroadcaster()
+	_, unsubscribe := b.Subscribe("p-1")
+	defer unsubscribe()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range subscriberBuffer * 2 {
+			if err := b.Publish(context.Background(), Event{ProjectID: "p-1"}); err != nil {
+				t.Errorf("Publish() error = %v", err)
+			}
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Publish blocked on a subscriber that is not reading")
+	}
+}
+
+const eventsProjectID = "5e8b2c4d-1f7a-4e69-a3d0-7c2b9e4f1a86"
+
+func TestHandlerEventsStreamsSnapshotThenEvents(t *testing.T) {
+	project := &Project{ID: eventsProjectID, UnixName: "demo", Status: StatusProvisioning}
+	svc := newService(
+		mockStore{
+			getByID: func(context.Context, string) (*Project, error) {
+				copied := *project
+				return &copied, nil
+			},
+		},
+		mockRegistry{},
+		nil,
+	)
+	events := NewBroadcaster()
+	srv := httptest.NewServer(NewHandler(svc, nil, WithEventStream(events)).Routes())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/" + eventsProjectID + "/events")
+	if err != nil {
+		t.Fatalf("GET events: %v", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			t.Errorf("failed to close body: %v", err)
+		}
+	}()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q, want text/event-stream", ct)
+	}
+
+	lines := bufio.NewScanner(resp.Body)
+	next := func() string {
+		t.Helper()
+		if !lines.Scan() {
+			t.Fatalf("stream ended early: %v", lines.Err())
+		}
+		return lines.Text()
+	}
+
+	if got := next(); got != "event: "+EventStatus {
+		t.Fatalf("first line = %q, want the status event", got)
+	}
+	if got := next(); !strings.Contains(got, `"status":"provisioning"`) {
+		t.Errorf("snapshot data = %q, want the current status", got)
+	}
+	next()
+
+	if err := events.Publish(context.Background(), Event{Type: EventProvisioned, ProjectID: eventsProjectID}); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if got := next(); got != "event: "+EventProvisioned {
+		t.Errorf("next event = %q, want %s", got, EventProvisioned)
+	}
+}
+
+func TestHandlerEventsTakesNoPlaceInRequestLimit(t *testing.T) {
+	svc := newService(
+		mockStore{
+			getByID: func(_ context.Context, id string) (*Project, error) {
+				return &Project{ID: id, Status: StatusProvisioned}, nil
+			},
+		},
+		mockRegistry{},
+		nil,
+	)
+	h := NewHandler(svc, nil,
+		WithEventStream(NewBroadcaster()),
+		WithRequestLimit(platform.ConcurrencyLimit(1, time.Second)),
+	)
+	srv := httptest.NewServer(h.Routes())
+	defer srv.Close()
+
+	stream, err := http.Get(srv.URL + "/" + eventsProjectID + "/events")
+	if err != nil {
+		t.Fatalf("GET events: %v", err)
+	}
+	defer func() {
+		if err := stream.Body.Close(); err != nil {
+			t.Errorf("failed to close body: %v", err)
+		}
+	}()
+	if stream.StatusCode != http.StatusOK {
+		t.Fatalf("events: expected 200, got %d", stream.StatusCode)
+	}
+
+	resp, err := http.Get(srv.URL + "/" + eventsProjectID)
+	if err != nil {
+		t.Fatalf("GET project: %v", err)
+	}
+	if err := resp.Body.Close(); err != nil {
+		t.Errorf("failed to close body: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 beside an open stream, got %d", resp.StatusCode)
+	}
+}
+
+func TestHandlerEventsUnavailableWithoutStream(t *testing.T) {
+	h := NewHandler(newService(mockStore{}, mockRegistry{}, nil), nil)
+
+	rr := httptest.NewRecorder()
+	h.Routes().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/"+eventsProjectID+"/events", nil))
+
+	if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), "EVENTS_UNAVAILABLE") {
+		t.Fatalf("expected 400 EVENTS_UNAVAILABLE, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
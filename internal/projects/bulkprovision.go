@@ -0,0 +1,265 @@
+package projects
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/searge/quokka/internal/operations"
+	"github.com/searge/quokka/internal/plugin"
+)
+
+// OperationReprovision is the kind of operation each project of an applied
+// bulk provisioning change is reprovisioned by.
+const OperationReprovision = "project.reprovision"
+
+// MaxBulkProvision caps how many projects one bulk provisioning change may
+// select, so a loose selector cannot reprovision the fleet by accident.
+const MaxBulkProvision = 500
+
+var (
+	ErrEmptyProvisionChange = errors.New("provision change must set at least one parameter")
+	ErrTooManyProjects      = errors.New("selector matches too many projects")
+	ErrNeedsReplacement     = errors.New("selected projects have resources the change would need to replace")
+)
+
+// ProvisionParamsChange is a partial ProvisionParams: what it sets replaces
+// a project's own parameters, and the rest are kept. Resources are merged
+// key by key, a null value removing the key; SSHKeys, when set, replace
+// the project's keys, an empty list removing them all.
+type ProvisionParamsChange struct {
+	Plugin     *string                `json:"plugin,omitempty" validate:"omitempty,min=1"`
+	Template   *string                `json:"template,omitempty"`
+	Resources  map[string]interface{} `json:"resources,omitempty"`
+	Node       *string                `json:"node,omitempty"`
+	Idempotent *bool                  `json:"idempotent,omitempty"`
+	SSHKeys    []string               `json:"ssh_keys,omitempty" validate:"omitempty,max=32,dive,ssh_public_key"`
+}
+
+// IsEmpty reports whether the change would leave every project as it is.
+func (c ProvisionParamsChange) IsEmpty() bool {
+	return c.Plugin == nil && c.Template == nil && len(c.Resources) == 0 &&
+		c.Node == nil && c.Idempotent == nil && c.SSHKeys == nil
+}
+
+// apply returns a copy of params with the change made to it.
+// Pure function.
+func (c ProvisionParamsChange) apply(params *ProvisionParams) *ProvisionParams {
+	out := *withProvisionDefaults(params)
+	if c.Plugin != nil {
+		out.Plugin = *c.Plugin
+	}
+	if c.Template != nil {
+		out.Template = *c.Template
+	}
+	if len(c.Resources) > 0 {
+		out.Resources = maps.Clone(out.Resources)
+		if out.Resources == nil {
+			out.Resources = make(map[string]interface{}, len(c.Resources))
+		}
+		for k, v := range c.Resources {
+			if v == nil {
+				delete(out.Resources, k)
+				continue
+			}
+			out.Resources[k] = v
+		}
+		if len(out.Resources) == 0 {
+			out.Resources = nil
+		}
+	}
+	if c.Node != nil {
+		out.Node = *c.Node
+	}
+	if c.Idempotent != nil {
+		out.Idempotent = *c.Idempotent
+	}
+	if c.SSHKeys != nil {
+		out.SSHKeys = nil
+		if len(c.SSHKeys) > 0 {
+			out.SSHKeys = slices.Clone(c.SSHKeys)
+		}
+	}
+	return &out
+}
+
+// BulkProvisionRequest changes the provisioning parameters of every
+// selected project and reprovisions those it changes.
+type BulkProvisionRequest struct {
+	Selector        LabelSelector         `json:"selector"`
+	ProvisionParams ProvisionParamsChange `json:"provision_params"`
+}
+
+// ProvisionPlan is what a bulk provisioning change would do.
+type ProvisionPlan struct {
+	// Reprovision lists the projects whose parameters change, in the
+	// order their reprovisioning is queued.
+	Reprovision []PlannedReprovision `json:"reprovision"`
+	// Skipped lists the projects whose parameters would change but that
+	// are still being provisioned, which are left alone.
+	Skipped []PlannedReprovision `json:"skipped"`
+	// Replace lists the projects whose parameters would change but that
+	// already have a resource. Provisioning again would either keep the
+	// old resource or leave it behind next to a new one, so a plan that
+	// lists any cannot be applied.
+	Replace []PlannedReprovision `json:"replace"`
+	// Unchanged counts the selected projects already matching the change.
+	Unchanged int `json:"unchanged"`
+}
+
+// PlannedReprovision is one project a bulk provisioning change affects,
+// with its parameters before and after, sensitive resources masked.
+type PlannedReprovision struct {
+	ProjectID string           `json:"project_id"`
+	Name      string           `json:"name"`
+	Status    ProvisionStatus  `json:"status"`
+	From      *ProvisionParams `json:"from"`
+	To        *ProvisionParams `json:"to"`
+}
+
+// PlanBulkProvision works out which projects req would reprovision,
+// without changing anything. The selector and the change must not be
+// empty, the selector may match at most MaxBulkProvision projects, and
+// the plugin and node the change sets must be allowed and, for the
+// plugin, registered. Returns ErrProvisioningDisabled if provisioning is
+// off.
+func (s *Service) PlanBulkProvision(ctx context.Context, req BulkProvisionRequest) (*ProvisionPlan, error) {
+	if err := s.checkProvisioning(); err != nil {
+		return nil, err
+	}
+	if err := s.validate.Struct(req); err != nil {
+		return nil, err
+	}
+	if req.Selector.IsEmpty() {
+		return nil, ErrEmptySelector
+	}
+	change := req.ProvisionParams
+	if change.IsEmpty() {
+		return nil, ErrEmptyProvisionChange
+	}
+	if change.Plugin != nil {
+		if err := s.checkPluginAllowed(*change.Plugin); err != nil {
+			return nil, err
+		}
+		if _, err := s.registry.Get(*change.Plugin); err != nil {
+			return nil, err
+		}
+	}
+	if change.Node != nil {
+		if err := s.checkNodeAllowed(*change.Node); err != nil {
+			return nil, err
+		}
+	}
+	if change.Template != nil {
+		if err := s.checkTemplateExists(*change.Template); err != nil {
+			return nil, err
+		}
+	}
+
+	selected, err := s.store.ListSelected(ctx, req.Selector, MaxBulkProvision+1)
+	if err != nil {
+		return nil, err
+	}
+	if len(selected) > MaxBulkProvision {
+		return nil, fmt.Errorf("%w: more than %d", ErrTooManyProjects, MaxBulkProvision)
+	}
+
+	plan := &ProvisionPlan{Reprovision: []PlannedReprovision{}, Skipped: []PlannedReprovision{}, Replace: []PlannedReprovision{}}
+	for _, project := range selected {
+		from := withProvisionDefaults(project.ProvisionParams)
+		to := change.apply(from)
+		if reflect.DeepEqual(from, to) {
+			plan.Unchanged++
+			continue
+		}
+		planned := PlannedReprovision{
+			ProjectID: project.ID,
+			Name:      project.Name,
+			Status:    project.Status,
+			From:      s.redactParams(from),
+			To:        s.redactParams(to),
+		}
+		if !project.Status.Settled() {
+			plan.Skipped = append(plan.Skipped, planned)
+			continue
+		}
+		if project.ResourceID != "" {
+			plan.Replace = append(plan.Replace, planned)
+			continue
+		}
+		plan.Reprovision = append(plan.Reprovision, planned)
+	}
+	return plan, nil
+}
+
+// redactParams returns a copy of params with sensitive resources masked,
+// for showing.
+func (s *Service) redactParams(params *ProvisionParams) *ProvisionParams {
+	if params == nil {
+		return nil
+	}
+	redacted := *params
+	redacted.Resources = plugin.RedactResources(params.Resources, s.redacted...)
+	return &redacted
+}
+
+// BulkProvisionResult is what applying a bulk provisioning change queued.
+type BulkProvisionResult struct {
+	// Operations reprovision one planned project each, in plan order.
+	Operations []*operations.Operation `json:"operations"`
+	// Deferred lists the planned projects left as they are because the
+	// operation queue had no room for them. Sending the same change again
+	// picks them up, as the projects already changed are then unchanged.
+	Deferred []string `json:"deferred"`
+}
+
+// ReprovisionWithChange makes change to project id as it is by now and
+// reprovisions it, for one project of a bulk provisioning change. The new
+// parameters are stored in the same transaction that marks the project
+// pending, so a project is never left with parameters it was not
+// provisioned with and a status saying otherwise. Projects deleted, still
+// provisioning, already changed or given a resource since the plan are
+// skipped. Plugin failures are wrapped in ErrProvisionFailed.
+func (s *Service) ReprovisionWithChange(ctx context.Context, id string, change ProvisionParamsChange) error {
+	project, err := s.Get(ctx, id)
+	if errors.Is(err, ErrProjectNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	from := withProvisionDefaults(project.ProvisionParams)
+	to := change.apply(from)
+	if !project.Status.Settled() || project.ResourceID != "" || reflect.DeepEqual(from, to) {
+		return nil
+	}
+
+	var claimed bool
+	err = s.store.Atomically(ctx, func(tx projectStore) error {
+		var err error
+		// Fails the claim if the project started provisioning since
+		claimed, err = tx.TransitionStatus(ctx, id, project.Status, StatusPending)
+		if err != nil || !claimed {
+			return err
+		}
+		return tx.SetProvisionParams(ctx, id, to)
+	})
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && !claimed) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	project.ProvisionParams = to
+	project.Status = StatusPending
+
+	_, err = s.provision(ctx, project, nil, false)
+	if errors.Is(err, ErrProvisionCancelled) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,511 @@
+package projects
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/searge/quokka/internal/operations"
+	"github.com/searge/quokka/internal/plugin"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+func TestProvisionParamsChangeApply(t *testing.T) {
+	current := &ProvisionParams{
+		Plugin:    "proxmox",
+		Template:  "debian-12",
+		Resources: map[string]interface{}{"cores": 2.0, "memory": 2048.0},
+		SSHKeys:   []string{"ssh-ed25519 AAAA old"},
+	}
+
+	tests := []struct {
+		name   string
+		change ProvisionParamsChange
+		want   *ProvisionParams
+	}{
+		{
+			name:   "template",
+			change: ProvisionParamsChange{Template: ptr("debian-13")},
+			want: &ProvisionParams{
+				Plugin:    "proxmox",
+				Template:  "debian-13",
+				Resources: map[string]interface{}{"cores": 2.0, "memory": 2048.0},
+				SSHKeys:   []string{"ssh-ed25519 AAAA old"},
+			},
+		},
+		{
+			name:   "resources merged, null removes",
+			change: ProvisionParamsChange{Resources: map[string]interface{}{"cores": 4.0, "memory": nil, "disk": 20.0}},
+			want: &ProvisionParams{
+				Plugin:    "proxmox",
+				Template:  "debian-12",
+				Resources: map[string]interface{}{"cores": 4.0, "disk": 20.0},
+				SSHKeys:   []string{"ssh-ed25519 AAAA old"},
+			},
+		},
+		{
+			name:   "empty ssh keys clear them",
+			change: ProvisionParamsChange{SSHKeys: []string{}, Idempotent: ptr(true)},
+			want: &ProvisionParams{
+				Plugin:     "proxmox",
+				Template:   "debian-12",
+				Resources:  map[string]interface{}{"cores": 2.0, "memory": 2048.0},
+				Idempotent: true,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.change.apply(current); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("apply() = %+v, want %+v", got, tt.want)
+			}
+			if current.Template != "debian-12" || len(current.Resources) != 2 || len(current.SSHKeys) != 1 {
+				t.Errorf("apply() changed its input to %+v", current)
+			}
+		})
+	}
+}
+
+// fleet is a mockStore over a handful of projects, recording the
+// provisioning parameters stored for each. Status transitions apply to the
+// projects themselves.
+func fleet(projects ...*Project) (mockStore, map[string]*ProvisionParams) {
+	byID := make(map[string]*Project, len(projects))
+	for _, p := range projects {
+		byID[p.ID] = p
+	}
+	stored := map[string]*ProvisionParams{}
+	return mockStore{
+		selectFn: func(context.Context, LabelSelector, int32) ([]*Project, error) {
+			return projects, nil
+		},
+		getByID: func(_ context.Context, id string) (*Project, error) {
+			p, ok := byID[id]
+			if !ok {
+				return nil, ErrProjectNotFound
+			}
+			copied := *p
+			return &copied, nil
+		},
+		paramsFn: func(_ context.Context, id string, params *ProvisionParams) error {
+			stored[id] = params
+			return nil
+		},
+		transFn: func(_ context.Context, id string, from, to ProvisionStatus) (bool, error) {
+			p, ok := byID[id]
+			if !ok {
+				return false, pgx.ErrNoRows
+			}
+			if p.Status != from {
+				return false, nil
+			}
+			p.Status = to
+			return true, nil
+		},
+		statusFn: func(context.Context, string, ProvisionStatus) error { return nil },
+	}, stored
+}
+
+func templateOnly(template string) *ProvisionParams {
+	return &ProvisionParams{Plugin: "proxmox", Template: template}
+}
+
+func TestServicePlanBulkProvision(t *testing.T) {
+	store, stored := fleet(
+		&Project{ID: "p-1", Name: "Alpha", Status: StatusProvisioned, ProvisionParams: templateOnly("debian-12")},
+		&Project{ID: "p-2", Name: "Bravo", Status: StatusProvisioned, ProvisionParams: templateOnly("debian-13")},
+		&Project{ID: "p-3", Name: "Charlie", Status: StatusProvisioning, ProvisionParams: templateOnly("debian-12")},
+		&Project{ID: "p-4", Name: "Delta", Status: StatusFailed},
+		&Project{ID: "p-5", Name: "Echo", Status: StatusProvisioned, ResourceID: "vm-5", ProvisionParams: templateOnly("debian-12")},
+	)
+	s := newService(store, mockRegistry{}, nil)
+
+	plan, err := s.PlanBulkProvision(context.Background(), BulkProvisionRequest{
+		Selector:        LabelSelector{MatchLabels: map[string]string{"team": "web"}},
+		ProvisionParams: ProvisionParamsChange{Template: ptr("debian-13")},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	want := &ProvisionPlan{
+		Reprovision: []PlannedReprovision{
+			{ProjectID: "p-1", Name: "Alpha", Status: StatusProvisioned, From: templateOnly("debian-12"), To: templateOnly("debian-13")},
+			{ProjectID: "p-4", Name: "Delta", Status: StatusFailed, From: templateOnly(""), To: templateOnly("debian-13")},
+		},
+		Skipped: []PlannedReprovision{
+			{ProjectID: "p-3", Name: "Charlie", Status: StatusProvisioning, From: templateOnly("debian-12"), To: templateOnly("debian-13")},
+		},
+		Replace: []PlannedReprovision{
+			{ProjectID: "p-5", Name: "Echo", Status: StatusProvisioned, From: templateOnly("debian-12"), To: templateOnly("debian-13")},
+		},
+		Unchanged: 1,
+	}
+	if !reflect.DeepEqual(plan, want) {
+		t.Errorf("plan = %+v, want %+v", plan, want)
+	}
+	if len(stored) != 0 {
+		t.Errorf("planning stored parameters for %d projects, want none", len(stored))
+	}
+}
+
+func TestServicePlanBulkProvisionMasksSensitiveResources(t *testing.T) {
+	params := &ProvisionParams{
+		Plugin:    "proxmox",
+		Template:  "debian-12",
+		Resources: map[string]interface{}{"root_password": "hunter2", "license": "ABC-123", "cores": 2.0},
+	}
+	store, _ := fleet(&Project{ID: "p-1", Name: "Alpha", Status: StatusProvisioned, ProvisionParams: params})
+	s := newService(store, mockRegistry{}, nil, WithRedactedResources("license"))
+
+	plan, err := s.PlanBulkProvision(context.Background(), BulkProvisionRequest{
+		Selector:        LabelSelector{MatchLabels: map[string]string{"team": "web"}},
+		ProvisionParams: ProvisionParamsChange{Template: ptr("debian-13")},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(plan.Reprovision) != 1 {
+		t.Fatalf("plan = %+v, want p-1 reprovisioned", plan)
+	}
+	want := map[string]interface{}{"root_password": "***", "license": "***", "cores": 2.0}
+	for name, shown := range map[string]*ProvisionParams{"from": plan.Reprovision[0].From, "to": plan.Reprovision[0].To} {
+		if !reflect.DeepEqual(shown.Resources, want) {
+			t.Errorf("%s resources = %v, want %v", name, shown.Resources, want)
+		}
+	}
+	if params.Resources["root_password"] != "hunter2" {
+		t.Errorf("planning changed the project's resources to %v", params.Resources)
+	}
+}
+
+func TestServicePlanBulkProvisionRejectsRequest(t *testing.T) {
+	tooMany := make([]*Project, MaxBulkProvision+1)
+	for i := range tooMany {
+		tooMany[i] = &Project{Status: StatusProvisioned}
+	}
+	selector := LabelSelector{Template: "debian-12"}
+
+	tests := []struct {
+		name    string
+		req     BulkProvisionRequest
+		opts    []ServiceOption
+		wantErr error
+	}{
+		{
+			name:    "empty selector",
+			req:     BulkProvisionRequest{ProvisionParams: ProvisionParamsChange{Template: ptr("debian-13")}},
+			wantErr: ErrEmptySelector,
+		},
+		{
+			name:    "empty change",
+			req:     BulkProvisionRequest{Selector: selector},
+			wantErr: ErrEmptyProvisionChange,
+		},
+		{
+			name:    "plugin not allowed",
+			req:     BulkProvisionRequest{Selector: selector, ProvisionParams: ProvisionParamsChange{Plugin: ptr("fake")}},
+			opts:    []ServiceOption{WithAllowedPlugins("proxmox")},
+			wantErr: ErrPluginNotAllowed,
+		},
+		{
+			name:    "unknown plugin",
+			req:     BulkProvisionRequest{Selector: selector, ProvisionParams: ProvisionParamsChange{Plugin: ptr("nope")}},
+			wantErr: plugin.ErrPluginNotFound,
+		},
+		{
+			name:    "unknown template",
+			req:     BulkProvisionRequest{Selector: selector, ProvisionParams: ProvisionParamsChange{Template: ptr("ubuntu-18.04")}},
+			opts:    []ServiceOption{WithTemplates("debian-13")},
+			wantErr: ErrUnknownTemplate,
+		},
+		{
+			name:    "too many projects",
+			req:     BulkProvisionRequest{Selector: selector, ProvisionParams: ProvisionParamsChange{Template: ptr("debian-13")}},
+			wantErr: ErrTooManyProjects,
+		},
+		{
+			name:    "provisioning disabled",
+			req:     BulkProvisionRequest{Selector: selector, ProvisionParams: ProvisionParamsChange{Template: ptr("debian-13")}},
+			opts:    []ServiceOption{WithProvisioning(false)},
+			wantErr: ErrProvisioningDisabled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newService(
+				mockStore{
+					selectFn: func(context.Context, LabelSelector, int32) ([]*Project, error) {
+						return tooMany, nil
+					},
+				},
+				mockRegistry{
+					getFn: func(name string) (plugin.Plugin, error) {
+						return nil, plugin.ErrPluginNotFound
+					},
+				},
+				nil,
+				tt.opts...,
+			)
+
+			if _, err := s.PlanBulkProvision(context.Background(), tt.req); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestServiceReprovisionWithChange(t *testing.T) {
+	store, stored := fleet(
+		&Project{ID: "p-1", Name: "Alpha", Status: StatusProvisioned, ProvisionParams: templateOnly("debian-12")},
+		&Project{ID: "p-2", Name: "Bravo", Status: StatusFailed, ProvisionParams: templateOnly("debian-12")},
+	)
+	var provisioned []string
+	s := newService(
+		store,
+		mockRegistry{
+			getFn: func(string) (plugin.Plugin, error) {
+				return mockPlugin{
+					provisionFn: func(_ context.Context, req plugin.ProvisionRequest) (*plugin.ProvisionResult, error) {
+						if req.Template != "debian-13" {
+							t.Errorf("project %s provisioned with template %q, want debian-13", req.ProjectID, req.Template)
+						}
+						provisioned = append(provisioned, req.ProjectID)
+						if req.ProjectID == "p-2" {
+							return nil, errors.New("cli failed")
+						}
+						return &plugin.ProvisionResult{ResourceID: "vm-" + req.ProjectID}, nil
+					},
+				}, nil
+			},
+		},
+		nil,
+	)
+	change := ProvisionParamsChange{Template: ptr("debian-13")}
+
+	if err := s.ReprovisionWithChange(context.Background(), "p-1", change); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := s.ReprovisionWithChange(context.Background(), "p-2", change); !errors.Is(err, ErrProvisionFailed) {
+		t.Fatalf("expected ErrProvisionFailed, got %v", err)
+	}
+	if want := []string{"p-1", "p-2"}; !reflect.DeepEqual(provisioned, want) {
+		t.Errorf("provisioned %v, want %v", provisioned, want)
+	}
+	for _, id := range []string{"p-1", "p-2"} {
+		if got := stored[id]; !reflect.DeepEqual(got, templateOnly("debian-13")) {
+			t.Errorf("stored parameters for %s = %+v, want template debian-13", id, got)
+		}
+	}
+}
+
+func TestServiceReprovisionWithChangeSkipsProjectsChangedSincePlan(t *testing.T) {
+	store, stored := fleet(
+		&Project{ID: "p-1", Name: "Alpha", Status: StatusProvisioning, ProvisionParams: templateOnly("debian-12")},
+		&Project{ID: "p-2", Name: "Bravo", Status: StatusProvisioned, ProvisionParams: templateOnly("debian-13")},
+	)
+	s := newService(store, panickyRegistry(t), nil)
+
+	for _, id := range []string{"p-1", "p-2", "p-gone"} {
+		if err := s.ReprovisionWithChange(context.Background(), id, ProvisionParamsChange{Template: ptr("debian-13")}); err != nil {
+			t.Fatalf("%s: expected no error, got %v", id, err)
+		}
+	}
+	if len(stored) != 0 {
+		t.Errorf("stored parameters %v, want none", stored)
+	}
+}
+
+func TestServiceReprovisionWithChangeLeavesProjectsWithResources(t *testing.T) {
+	idempotent := templateOnly("debian-12")
+	idempotent.Idempotent = true
+	store, stored := fleet(
+		// Would find its old VM by name and report it as reprovisioned
+		&Project{ID: "p-1", Name: "Alpha", Status: StatusProvisioned, ResourceID: "vm-1", ProvisionParams: idempotent},
+		// Would get a second VM, leaking the first
+		&Project{ID: "p-2", Name: "Bravo", Status: StatusFailed, ResourceID: "vm-2", ProvisionParams: templateOnly("debian-12")},
+	)
+	s := newService(store, panickyRegistry(t), nil)
+
+	for _, id := range []string{"p-1", "p-2"} {
+		if err := s.ReprovisionWithChange(context.Background(), id, ProvisionParamsChange{Template: ptr("debian-13")}); err != nil {
+			t.Fatalf("%s: expected no error, got %v", id, err)
+		}
+	}
+	if len(stored) != 0 {
+		t.Errorf("stored parameters %v, want none", stored)
+	}
+}
+
+func TestServiceReprovisionWithChangeLosesRaceWithoutChanging(t *testing.T) {
+	store, stored := fleet(&Project{ID: "p-1", Name: "Alpha", Status: StatusProvisioned, ProvisionParams: templateOnly("debian-12")})
+	// Someone else starts provisioning between the read and the claim
+	store.transFn = func(context.Context, string, ProvisionStatus, ProvisionStatus) (bool, error) {
+		return false, nil
+	}
+	s := newService(store, panickyRegistry(t), nil)
+
+	if err := s.ReprovisionWithChange(context.Background(), "p-1", ProvisionParamsChange{Template: ptr("debian-13")}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(stored) != 0 {
+		t.Errorf("stored parameters %v, want none without the status claim", stored)
+	}
+}
+
+// boundedOperations runs every operation it accepts at once, turning any
+// away past capacity with operations.ErrQueueFull.
+type boundedOperations struct {
+	capacity int
+	ops      []*operations.Operation
+	errs     []error
+}
+
+func (b *boundedOperations) Enqueue(ctx context.Context, kind string, priority operations.Priority, fn operations.Func, _ ...operations.EnqueueOption) (*operations.Operation, error) {
+	if len(b.ops) == b.capacity {
+		return nil, operations.ErrQueueFull
+	}
+	if priority != operations.PriorityLow {
+		return nil, fmt.Errorf("queued at %s, want low priority", priority)
+	}
+	op := &operations.Operation{ID: fmt.Sprintf("op-%d", len(b.ops)+1), Kind: kind, Status: operations.StatusPending}
+	b.ops = append(b.ops, op)
+	_, err := fn(ctx)
+	b.errs = append(b.errs, err)
+	return op, nil
+}
+
+func (b *boundedOperations) QueueWait(time.Duration) time.Duration {
+	return 0
+}
+
+func TestHandlerBulkProvision(t *testing.T) {
+	const body = `{"selector":{"template":"debian-12"},"provision_params":{"template":"debian-13"}}`
+	newHandler := func(opts ...HandlerOption) (*Handler, map[string]*ProvisionParams) {
+		store, stored := fleet(
+			&Project{ID: "p-1", Name: "Alpha", Status: StatusProvisioned, ProvisionParams: templateOnly("debian-12")},
+			&Project{ID: "p-2", Name: "Bravo", Status: StatusProvisioned, ProvisionParams: templateOnly("debian-12")},
+			&Project{ID: "p-3", Name: "Charlie", Status: StatusFailed, ProvisionParams: templateOnly("debian-12")},
+		)
+		svc := newService(store, mockRegistry{
+			getFn: func(string) (plugin.Plugin, error) {
+				return mockPlugin{}, nil
+			},
+		}, nil)
+		return NewHandler(svc, nil, opts...), stored
+	}
+
+	t.Run("dry run", func(t *testing.T) {
+		ops := &syncOperations{}
+		h, stored := newHandler(WithOperations(ops))
+
+		rr := httptest.NewRecorder()
+		h.Routes().ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/provision-params?dry_run=true", strings.NewReader(body)))
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var plan ProvisionPlan
+		if err := json.Unmarshal(rr.Body.Bytes(), &plan); err != nil {
+			t.Fatalf("failed to decode response body: %v", err)
+		}
+		if len(plan.Reprovision) != 3 || plan.Reprovision[0].ProjectID != "p-1" {
+			t.Errorf("plan = %+v, want every project reprovisioned", plan)
+		}
+		if ops.kind != "" || len(stored) != 0 {
+			t.Error("a dry run must not change anything")
+		}
+	})
+
+	t.Run("apply", func(t *testing.T) {
+		ops := &boundedOperations{capacity: 2}
+		h, stored := newHandler(WithOperations(ops))
+
+		rr := httptest.NewRecorder()
+		h.BulkProvision(rr, httptest.NewRequest(http.MethodPost, "/api/v1/projects/provision-params", strings.NewReader(body)))
+
+		if rr.Code != http.StatusAccepted {
+			t.Fatalf("expected 202, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var result BulkProvisionResult
+		if err := json.Unmarshal(rr.Body.Bytes(), &result); err != nil {
+			t.Fatalf("failed to decode response body: %v", err)
+		}
+		if len(result.Operations) != 2 || result.Operations[0].Kind != OperationReprovision || result.Operations[1].ID != "op-2" {
+			t.Errorf("operations = %+v, want one %s per project that fit", result.Operations, OperationReprovision)
+		}
+		if !reflect.DeepEqual(result.Deferred, []string{"p-3"}) {
+			t.Errorf("deferred = %v, want [p-3]", result.Deferred)
+		}
+		for i, err := range ops.errs {
+			if err != nil {
+				t.Errorf("operation %d error = %v", i+1, err)
+			}
+		}
+		for _, id := range []string{"p-1", "p-2"} {
+			if got := stored[id]; !reflect.DeepEqual(got, templateOnly("debian-13")) {
+				t.Errorf("stored parameters for %s = %+v, want template debian-13", id, got)
+			}
+		}
+		if _, ok := stored["p-3"]; ok {
+			t.Error("the deferred project must be left as it is")
+		}
+	})
+
+	t.Run("apply with a full queue", func(t *testing.T) {
+		h, stored := newHandler(WithOperations(&boundedOperations{}))
+
+		rr := httptest.NewRecorder()
+		h.Routes().ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/provision-params", strings.NewReader(body)))
+
+		if rr.Code != http.StatusServiceUnavailable || rr.Header().Get("Retry-After") == "" {
+			t.Fatalf("expected 503 with Retry-After, got %d: %s", rr.Code, rr.Body.String())
+		}
+		if len(stored) != 0 {
+			t.Errorf("stored parameters %v, want none", stored)
+		}
+	})
+
+	t.Run("apply to projects needing replacement", func(t *testing.T) {
+		store, stored := fleet(
+			&Project{ID: "p-1", Name: "Alpha", Status: StatusFailed, ProvisionParams: templateOnly("debian-12")},
+			&Project{ID: "p-2", Name: "Bravo", Status: StatusProvisioned, ResourceID: "vm-2", ProvisionParams: templateOnly("debian-12")},
+		)
+		ops := &boundedOperations{capacity: 2}
+		h := NewHandler(newService(store, mockRegistry{
+			getFn: func(string) (plugin.Plugin, error) {
+				return mockPlugin{}, nil
+			},
+		}, nil), nil, WithOperations(ops))
+
+		rr := httptest.NewRecorder()
+		h.Routes().ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/provision-params", strings.NewReader(body)))
+
+		if rr.Code != http.StatusConflict || !strings.Contains(rr.Body.String(), "NEEDS_REPLACEMENT") {
+			t.Fatalf("expected 409 NEEDS_REPLACEMENT, got %d: %s", rr.Code, rr.Body.String())
+		}
+		if len(ops.ops) != 0 || len(stored) != 0 {
+			t.Error("a refused change must not queue or store anything")
+		}
+	})
+
+	t.Run("apply without operations", func(t *testing.T) {
+		h, _ := newHandler()
+
+		rr := httptest.NewRecorder()
+		h.Routes().ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/provision-params", strings.NewReader(body)))
+
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400, got %d: %s", rr.Code, rr.Body.String())
+		}
+	})
+}
@@ -0,0 +1,211 @@
+package projects
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/searge/quokka/internal/operations"
+	"github.com/searge/quokka/internal/plugin"
+)
+
+var (
+	ErrNotProvisioning       = errors.New("project is not provisioning")
+	ErrProvisioningElsewhere = errors.New("project is being provisioned by another instance")
+	ErrProvisionCancelled    = errors.New("provisioning cancelled")
+	ErrCleanupFailed         = errors.New("removing partial resource failed")
+)
+
+// staleProvisioningAfter is how long a project must have been left
+// provisioning, unchanged, before it is taken for one no instance is still
+// provisioning: a call times out after ProvisionTimeout, and the rest
+// leaves it time to record how it ended.
+const staleProvisioningAfter = 2 * ProvisionTimeout
+
+// provisionJobs tracks the provisioning calls running in this process, so
+// they can be cancelled by project.
+type provisionJobs struct {
+	mu   sync.Mutex
+	jobs map[string]*provisionJob
+}
+
+type provisionJob struct {
+	cancel context.CancelCauseFunc
+	done   chan struct{}
+}
+
+func newProvisionJobs() *provisionJobs {
+	return &provisionJobs{jobs: make(map[string]*provisionJob)}
+}
+
+// start tracks a provisioning call for projectID, returning the context it
+// must run under and a function to call once it has settled.
+func (j *provisionJobs) start(ctx context.Context, projectID string) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	job := &provisionJob{cancel: cancel, done: make(chan struct{})}
+
+	j.mu.Lock()
+	j.jobs[projectID] = job
+	j.mu.Unlock()
+
+	return ctx, func() {
+		j.mu.Lock()
+		if j.jobs[projectID] == job {
+			delete(j.jobs, projectID)
+		}
+		j.mu.Unlock()
+		cancel(nil)
+		close(job.done)
+	}
+}
+
+// get returns the call running for projectID, or nil.
+func (j *provisionJobs) get(projectID string) *provisionJob {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.jobs[projectID]
+}
+
+// CancelProvision stops the provisioning running for project id, killing
+// the plugin's work, and waits for the project to settle as cancelled.
+// A project provisioning with no call for it in this process is marked
+// cancelled directly once it has been left unchanged long enough that no
+// instance can still be provisioning it, e.g. after a restart; before
+// that ErrProvisioningElsewhere is returned. With deprovision set, a
+// resource the cancelled call left behind is removed, which needs
+// provisioning enabled. Returns ErrNotProvisioning if the project is not
+// provisioning.
+func (s *Service) CancelProvision(ctx context.Context, id string, deprovision bool) (*Project, error) {
+	if deprovision {
+		if err := s.checkProvisioning(); err != nil {
+			return nil, err
+		}
+	}
+	project, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if project.Status != StatusProvisioning {
+		return nil, ErrNotProvisioning
+	}
+
+	if job := s.jobs.get(id); job != nil {
+		job.cancel(ErrProvisionCancelled)
+		select {
+		case <-job.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	} else if err := s.cancelStale(ctx, project); err != nil {
+		return nil, err
+	}
+
+	// The call may have settled on its own before it could be cancelled.
+	project, err = s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if deprovision && project.Status == StatusCancelled {
+		if err := s.removePartialResource(ctx, project); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrCleanupFailed, err)
+		}
+	}
+	return project, nil
+}
+
+// cancelStale marks project, provisioning with no call running for it
+// here, as cancelled, if the database shows it has been left so for
+// staleProvisioningAfter.
+func (s *Service) cancelStale(ctx context.Context, project *Project) error {
+	changedBefore := time.Now().Add(-staleProvisioningAfter)
+	err := s.commit(ctx, "", func(store projectStore) ([]Event, error) {
+		ok, err := store.TransitionStaleStatus(ctx, project.ID, StatusProvisioning, StatusCancelled, changedBefore)
+		if err != nil {
+			return nil, err
+		}
+		if !ok && project.UpdatedAt.After(changedBefore) {
+			return nil, ErrProvisioningElsewhere
+		}
+		if !ok {
+			return nil, ErrNotProvisioning
+		}
+		project.Status = StatusCancelled
+		return outcomeEvents(project, ErrProvisionCancelled, false), nil
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrProjectNotFound
+	}
+	return err
+}
+
+// FailAbandoned marks the project an abandoned create operation was
+// working on failed, if it is still waiting to be provisioned, or has been
+// left provisioning for staleProvisioningAfter, so that it can be
+// reprovisioned. Projects provisioning more recently are left to
+// CancelProvision. Other operations are ignored.
+func (s *Service) FailAbandoned(ctx context.Context, op *operations.Operation) error {
+	id, ok := strings.CutPrefix(op.Resource, "projects/")
+	if op.Kind != OperationCreate || !ok {
+		return nil
+	}
+	project, err := s.Get(ctx, id)
+	if errors.Is(err, ErrProjectNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var changedBefore time.Time
+	switch project.Status {
+	case StatusPending:
+		changedBefore = time.Now()
+	case StatusProvisioning:
+		changedBefore = time.Now().Add(-staleProvisioningAfter)
+	default:
+		return nil
+	}
+	outcome := fmt.Errorf("%w: %w", ErrProvisionFailed, operations.ErrAbandoned)
+	return s.commit(ctx, "", func(store projectStore) ([]Event, error) {
+		ok, err := store.TransitionStaleStatus(ctx, project.ID, project.Status, StatusFailed, changedBefore)
+		if err != nil || !ok {
+			return nil, err
+		}
+		project.Status = StatusFailed
+		return outcomeEvents(project, outcome, s.deferCreateEvents), nil
+	})
+}
+
+// removePartialResource deprovisions the resource a cancelled call created
+// for project, if its plugin can find one. Projects provisioned before are
+// left alone: the resource found may be the one they already had.
+func (s *Service) removePartialResource(ctx context.Context, project *Project) error {
+	if project.ResourceID != "" {
+		s.log.Info("not removing resource of a previously provisioned project",
+			"project_id", project.ID, "resource_id", project.ResourceID)
+		return nil
+	}
+
+	params := withProvisionDefaults(project.ProvisionParams)
+	p, err := s.registry.Get(params.Plugin)
+	if err != nil {
+		return err
+	}
+
+	name := plugin.ResourceName(plugin.ProvisionRequest{ProjectID: project.ID, UnixName: project.UnixName})
+	res, err := plugin.FindResource(ctx, p, name)
+	switch {
+	case errors.Is(err, plugin.ErrResourceNotFound):
+		return nil
+	case errors.Is(err, errors.ErrUnsupported):
+		s.log.Warn("plugin cannot look up partial resources", "project_id", project.ID, "plugin", params.Plugin)
+		return nil
+	case err != nil:
+		return err
+	}
+	return p.Deprovision(ctx, res.ResourceID)
+}
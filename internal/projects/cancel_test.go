@@ -0,0 +1,220 @@
+package projects
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/searge/quokka/internal/operations"
+	"github.com/searge/quokka/internal/plugin"
+)
+
+const cancelProjectID = "5e2d8c71-0a4b-4f36-9d1e-7b3a6c8f0e52"
+
+// statusTracker is a one-project store that remembers its status, last
+// changed at updatedAt.
+type statusTracker struct {
+	mu        sync.Mutex
+	status    ProvisionStatus
+	updatedAt time.Time
+}
+
+func (st *statusTracker) get() ProvisionStatus {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.status
+}
+
+func (st *statusTracker) store() mockStore {
+	return mockStore{
+		getByID: func(_ context.Context, id string) (*Project, error) {
+			st.mu.Lock()
+			defer st.mu.Unlock()
+			return &Project{ID: id, Name: "Alpha", UnixName: "alpha", Status: st.status, UpdatedAt: st.updatedAt}, nil
+		},
+		statusFn: func(_ context.Context, _ string, status ProvisionStatus) error {
+			st.mu.Lock()
+			defer st.mu.Unlock()
+			st.status = status
+			return nil
+		},
+		transFn: func(_ context.Context, _ string, from, to ProvisionStatus) (bool, error) {
+			st.mu.Lock()
+			defer st.mu.Unlock()
+			if st.status != from {
+				return false, nil
+			}
+			st.status = to
+			return true, nil
+		},
+		staleFn: func(_ context.Context, _ string, from, to ProvisionStatus, changedBefore time.Time) (bool, error) {
+			st.mu.Lock()
+			defer st.mu.Unlock()
+			if st.status != from || !st.updatedAt.Before(changedBefore) {
+				return false, nil
+			}
+			st.status = to
+			return true, nil
+		},
+	}
+}
+
+// findingPlugin can look up the resources it provisioned by name.
+type findingPlugin struct {
+	mockPlugin
+	found         string
+	deprovisioned *[]string
+}
+
+func (f findingPlugin) FindResource(_ context.Context, name string) (*plugin.ProvisionResult, error) {
+	// Resources are named after the project's unix name, never its
+	// display name, which another project may share.
+	if f.found == "" || name != "alpha" {
+		return nil, plugin.ErrResourceNotFound
+	}
+	return &plugin.ProvisionResult{ResourceID: f.found}, nil
+}
+
+func (f findingPlugin) Deprovision(_ context.Context, resourceID string) error {
+	*f.deprovisioned = append(*f.deprovisioned, resourceID)
+	return nil
+}
+
+func TestCancelProvisionStopsRunningCall(t *testing.T) {
+	tracker := &statusTracker{status: StatusPending}
+	started := make(chan struct{})
+	pluginErr := make(chan error, 1)
+	var deprovisioned []string
+	events := &recordingPublisher{}
+	s := newService(
+		tracker.store(),
+		mockRegistry{getFn: func(string) (plugin.Plugin, error) {
+			return findingPlugin{
+				mockPlugin: mockPlugin{provisionFn: func(ctx context.Context, _ plugin.ProvisionRequest) (*plugin.ProvisionResult, error) {
+					close(started)
+					// Stands in for the CLI, whose process group dies with ctx.
+					<-ctx.Done()
+					pluginErr <- ctx.Err()
+					return nil, ctx.Err()
+				}},
+				found:         "vm-partial",
+				deprovisioned: &deprovisioned,
+			}, nil
+		}},
+		nil,
+		WithEventPublisher(events),
+	)
+
+	provisionErr := make(chan error, 1)
+	go func() {
+		_, err := s.Reprovision(context.Background(), cancelProjectID)
+		provisionErr <- err
+	}()
+	<-started
+
+	project, err := s.CancelProvision(context.Background(), cancelProjectID, true)
+	if err != nil {
+		t.Fatalf("CancelProvision() error = %v", err)
+	}
+	if err := <-pluginErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("plugin context ended with %v, want context.Canceled", err)
+	}
+	if err := <-provisionErr; !errors.Is(err, ErrProvisionCancelled) {
+		t.Errorf("Reprovision() error = %v, want ErrProvisionCancelled", err)
+	}
+	if project.Status != StatusCancelled || tracker.get() != StatusCancelled {
+		t.Errorf("status = %s, stored %s, want cancelled", project.Status, tracker.get())
+	}
+	if !slices.Equal(deprovisioned, []string{"vm-partial"}) {
+		t.Errorf("deprovisioned %v, want the partial resource", deprovisioned)
+	}
+	if got := events.types(); !slices.Equal(got, []string{EventProvisionCancelled}) {
+		t.Errorf("events = %v, want %s", got, EventProvisionCancelled)
+	}
+}
+
+func TestCancelProvisionSettlesStaleProject(t *testing.T) {
+	tracker := &statusTracker{status: StatusProvisioning, updatedAt: time.Now().Add(-time.Hour)}
+	s := newService(tracker.store(), mockRegistry{}, nil)
+
+	project, err := s.CancelProvision(context.Background(), cancelProjectID, false)
+	if err != nil {
+		t.Fatalf("CancelProvision() error = %v", err)
+	}
+	if project.Status != StatusCancelled {
+		t.Errorf("status = %s, want cancelled", project.Status)
+	}
+}
+
+func TestCancelProvisionLeavesProjectAnotherInstanceMayBeProvisioning(t *testing.T) {
+	tracker := &statusTracker{status: StatusProvisioning, updatedAt: time.Now().Add(-5 * time.Second)}
+	h := NewHandler(newService(tracker.store(), mockRegistry{}, nil), nil)
+
+	rr := httptest.NewRecorder()
+	h.Cancel(rr, newGetRequestWithID(cancelProjectID))
+
+	if rr.Code != http.StatusConflict || !strings.Contains(rr.Body.String(), "PROVISIONING_ELSEWHERE") {
+		t.Fatalf("expected 409 PROVISIONING_ELSEWHERE, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if tracker.get() != StatusProvisioning {
+		t.Errorf("status changed to %s", tracker.get())
+	}
+}
+
+func TestCancelProvisionRequiresProvisioning(t *testing.T) {
+	for _, status := range []ProvisionStatus{StatusPending, StatusProvisioned, StatusFailed, StatusCancelled} {
+		t.Run(string(status), func(t *testing.T) {
+			tracker := &statusTracker{status: status}
+			h := NewHandler(newService(tracker.store(), mockRegistry{}, nil), nil)
+
+			rr := httptest.NewRecorder()
+			h.Cancel(rr, newGetRequestWithID(cancelProjectID))
+			if rr.Code != http.StatusConflict {
+				t.Fatalf("expected 409, got %d: %s", rr.Code, rr.Body.String())
+			}
+			if tracker.get() != status {
+				t.Errorf("status changed to %s", tracker.get())
+			}
+		})
+	}
+}
+
+func TestServiceFailAbandoned(t *testing.T) {
+	tests := []struct {
+		name       string
+		kind       string
+		status     ProvisionStatus
+		updatedAgo time.Duration
+		want       ProvisionStatus
+	}{
+		{name: "pending", kind: OperationCreate, status: StatusPending, updatedAgo: time.Second, want: StatusFailed},
+		{name: "stale provisioning", kind: OperationCreate, status: StatusProvisioning, updatedAgo: time.Hour, want: StatusFailed},
+		{name: "recent provisioning", kind: OperationCreate, status: StatusProvisioning, updatedAgo: time.Second, want: StatusProvisioning},
+		{name: "already provisioned", kind: OperationCreate, status: StatusProvisioned, updatedAgo: time.Hour, want: StatusProvisioned},
+		{name: "other kind", kind: "project.other", status: StatusPending, updatedAgo: time.Hour, want: StatusPending},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := &statusTracker{status: tt.status, updatedAt: time.Now().Add(-tt.updatedAgo)}
+			events := &recordingPublisher{}
+			s := newService(tracker.store(), mockRegistry{}, nil, WithEventPublisher(events))
+
+			op := &operations.Operation{ID: "op-1", Kind: tt.kind, Resource: "projects/" + cancelProjectID}
+			if err := s.FailAbandoned(context.Background(), op); err != nil {
+				t.Fatalf("FailAbandoned() error = %v", err)
+			}
+			if got := tracker.get(); got != tt.want {
+				t.Errorf("status = %s, want %s", got, tt.want)
+			}
+			if failed := tt.want == StatusFailed; failed != slices.Contains(events.types(), EventProvisionFailed) {
+				t.Errorf("events = %v, want %s only if the project failed", events.types(), EventProvisionFailed)
+			}
+		})
+	}
+}
@@ -8,12 +8,58 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type EventsOutbox struct {
+	ID            int64              `json:"id"`
+	EventType     string             `json:"event_type"`
+	ProjectID     pgtype.UUID        `json:"project_id"`
+	Payload       []byte             `json:"payload"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+	Attempts      int32              `json:"attempts"`
+	NextAttemptAt pgtype.Timestamptz `json:"next_attempt_at"`
+	LastError     pgtype.Text        `json:"last_error"`
+	SentAt        pgtype.Timestamptz `json:"sent_at"`
+}
+
+type Operation struct {
+	ID                  pgtype.UUID        `json:"id"`
+	Kind                string             `json:"kind"`
+	Status              string             `json:"status"`
+	Resource            string             `json:"resource"`
+	Error               string             `json:"error"`
+	CreatedAt           pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt           pgtype.Timestamptz `json:"updated_at"`
+	DoneAt              pgtype.Timestamptz `json:"done_at"`
+	EstimatedCompletion pgtype.Timestamptz `json:"estimated_completion"`
+}
+
+type Organization struct {
+	ID                pgtype.UUID        `json:"id"`
+	Name              string             `json:"name"`
+	UnixName          string             `json:"unix_name"`
+	MaxActiveProjects pgtype.Int4        `json:"max_active_projects"`
+	CreatedAt         pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt         pgtype.Timestamptz `json:"updated_at"`
+	ProvisionPriority pgtype.Text        `json:"provision_priority"`
+	DefaultLabels     []byte             `json:"default_labels"`
+	SyncedLabels      []byte             `json:"synced_labels"`
+}
+
 type Project struct {
-	ID          pgtype.UUID        `json:"id"`
-	Name        string             `json:"name"`
-	UnixName    string             `json:"unix_name"`
-	Description pgtype.Text        `json:"description"`
-	Active      bool               `json:"active"`
-	CreatedAt   pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
+	ID               pgtype.UUID        `json:"id"`
+	Name             string             `json:"name"`
+	UnixName         string             `json:"unix_name"`
+	Description      pgtype.Text        `json:"description"`
+	Active           bool               `json:"active"`
+	CreatedAt        pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt        pgtype.Timestamptz `json:"updated_at"`
+	ProvisionParams  []byte             `json:"provision_params"`
+	Labels           []byte             `json:"labels"`
+	Status           string             `json:"status"`
+	DeletedAt        pgtype.Timestamptz `json:"deleted_at"`
+	OrgID            pgtype.UUID        `json:"org_id"`
+	ResourceID       pgtype.Text        `json:"resource_id"`
+	ArchivedUnixName pgtype.Text        `json:"archived_unix_name"`
+	Network          []byte             `json:"network"`
+	LastCheckedAt    pgtype.Timestamptz `json:"last_checked_at"`
+	Node             pgtype.Text        `json:"node"`
 }
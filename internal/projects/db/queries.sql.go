@@ -11,6 +11,27 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const checkProjectExistsByName = `-- name: CheckProjectExistsByName :one
+SELECT EXISTS(
+    SELECT 1 FROM projects
+    WHERE lower(name) = lower($1)
+      AND id IS DISTINCT FROM $2
+      AND deleted_at IS NULL
+)
+`
+
+type CheckProjectExistsByNameParams struct {
+	Name     string      `json:"name"`
+	ExceptID pgtype.UUID `json:"except_id"`
+}
+
+func (q *Queries) CheckProjectExistsByName(ctx context.Context, arg CheckProjectExistsByNameParams) (bool, error) {
+	row := q.db.QueryRow(ctx, checkProjectExistsByName, arg.Name, arg.ExceptID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
 const checkProjectExistsByUnixName = `-- name: CheckProjectExistsByUnixName :one
 SELECT EXISTS(
     SELECT 1 FROM projects WHERE unix_name = $1
@@ -24,23 +45,165 @@ func (q *Queries) CheckProjectExistsByUnixName(ctx context.Context, unixName str
 	return exists, err
 }
 
+const claimOutboxEvents = `-- name: ClaimOutboxEvents :many
+UPDATE events_outbox
+SET next_attempt_at = $1
+WHERE id IN (
+    SELECT id FROM events_outbox
+    WHERE sent_at IS NULL AND next_attempt_at <= $2
+    ORDER BY id
+    LIMIT $3
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, event_type, project_id, payload, created_at, attempts, next_attempt_at, last_error, sent_at
+`
+
+type ClaimOutboxEventsParams struct {
+	LeaseUntil pgtype.Timestamptz `json:"lease_until"`
+	Now        pgtype.Timestamptz `json:"now"`
+	MaxEvents  int32              `json:"max_events"`
+}
+
+// Leases up to max_events due events until lease_until, skipping rows
+// another relay is claiming; an event not marked by then is claimed again.
+func (q *Queries) ClaimOutboxEvents(ctx context.Context, arg ClaimOutboxEventsParams) ([]EventsOutbox, error) {
+	rows, err := q.db.Query(ctx, claimOutboxEvents, arg.LeaseUntil, arg.Now, arg.MaxEvents)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []EventsOutbox
+	for rows.Next() {
+		var i EventsOutbox
+		if err := rows.Scan(
+			&i.ID,
+			&i.EventType,
+			&i.ProjectID,
+			&i.Payload,
+			&i.CreatedAt,
+			&i.Attempts,
+			&i.NextAttemptAt,
+			&i.LastError,
+			&i.SentAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const countActiveProjectsByOrg = `-- name: CountActiveProjectsByOrg :one
+SELECT COUNT(*)
+FROM projects
+WHERE org_id = $1 AND active AND deleted_at IS NULL
+`
+
+func (q *Queries) CountActiveProjectsByOrg(ctx context.Context, orgID pgtype.UUID) (int64, error) {
+	row := q.db.QueryRow(ctx, countActiveProjectsByOrg, orgID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countProjects = `-- name: CountProjects :one
+SELECT COUNT(*)
+FROM projects
+WHERE deleted_at IS NULL
+  AND (cardinality($1::text[]) = 0 OR status = ANY($1::text[]))
+  AND ($2::text = '' OR node = $2::text)
+`
+
+type CountProjectsParams struct {
+	Statuses []string `json:"statuses"`
+	Node     string   `json:"node"`
+}
+
+func (q *Queries) CountProjects(ctx context.Context, arg CountProjectsParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countProjects, arg.Statuses, arg.Node)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countProjectsByStatus = `-- name: CountProjectsByStatus :many
+SELECT status, COUNT(*) AS count
+FROM projects
+WHERE deleted_at IS NULL
+GROUP BY status
+`
+
+type CountProjectsByStatusRow struct {
+	Status string `json:"status"`
+	Count  int64  `json:"count"`
+}
+
+func (q *Queries) CountProjectsByStatus(ctx context.Context) ([]CountProjectsByStatusRow, error) {
+	rows, err := q.db.Query(ctx, countProjectsByStatus)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CountProjectsByStatusRow
+	for rows.Next() {
+		var i CountProjectsByStatusRow
+		if err := rows.Scan(&i.Status, &i.Count); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const countRelatedProjects = `-- name: CountRelatedProjects :one
+SELECT COUNT(*)
+FROM projects source
+JOIN projects p
+    ON p.id <> source.id
+   AND p.labels ?| ARRAY(SELECT jsonb_object_keys(source.labels))
+WHERE source.id = $1 AND source.deleted_at IS NULL
+  AND p.active AND p.deleted_at IS NULL
+  AND EXISTS (
+      SELECT 1
+      FROM jsonb_each(p.labels) AS label
+      WHERE source.labels @> jsonb_build_object(label.key, label.value)
+  )
+`
+
+// Counts the projects ListRelatedProjects pages through.
+func (q *Queries) CountRelatedProjects(ctx context.Context, id pgtype.UUID) (int64, error) {
+	row := q.db.QueryRow(ctx, countRelatedProjects, id)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createProject = `-- name: CreateProject :one
 INSERT INTO projects (
-    id, name, unix_name, description, active, created_at, updated_at
+    id, name, unix_name, description, active, created_at, updated_at, provision_params, labels, org_id
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $7
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
 )
-RETURNING id, name, unix_name, description, active, created_at, updated_at
+RETURNING id, name, unix_name, description, active, created_at, updated_at, provision_params, labels, status, deleted_at, org_id, resource_id, archived_unix_name, network, last_checked_at, node
 `
 
 type CreateProjectParams struct {
-	ID          pgtype.UUID        `json:"id"`
-	Name        string             `json:"name"`
-	UnixName    string             `json:"unix_name"`
-	Description pgtype.Text        `json:"description"`
-	Active      bool               `json:"active"`
-	CreatedAt   pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
+	ID              pgtype.UUID        `json:"id"`
+	Name            string             `json:"name"`
+	UnixName        string             `json:"unix_name"`
+	Description     pgtype.Text        `json:"description"`
+	Active          bool               `json:"active"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
+	ProvisionParams []byte             `json:"provision_params"`
+	Labels          []byte             `json:"labels"`
+	OrgID           pgtype.UUID        `json:"org_id"`
 }
 
 func (q *Queries) CreateProject(ctx context.Context, arg CreateProjectParams) (Project, error) {
@@ -52,6 +215,9 @@ func (q *Queries) CreateProject(ctx context.Context, arg CreateProjectParams) (P
 		arg.Active,
 		arg.CreatedAt,
 		arg.UpdatedAt,
+		arg.ProvisionParams,
+		arg.Labels,
+		arg.OrgID,
 	)
 	var i Project
 	err := row.Scan(
@@ -62,27 +228,86 @@ func (q *Queries) CreateProject(ctx context.Context, arg CreateProjectParams) (P
 		&i.Active,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ProvisionParams,
+		&i.Labels,
+		&i.Status,
+		&i.DeletedAt,
+		&i.OrgID,
+		&i.ResourceID,
+		&i.ArchivedUnixName,
+		&i.Network,
+		&i.LastCheckedAt,
+		&i.Node,
 	)
 	return i, err
 }
 
 const deleteProject = `-- name: DeleteProject :execrows
-DELETE FROM projects
-WHERE id = $1
+UPDATE projects
+SET
+    deleted_at = $1,
+    updated_at = $1,
+    archived_unix_name = CASE WHEN $2::text = '' THEN archived_unix_name ELSE unix_name END,
+    unix_name = CASE WHEN $2::text = '' THEN unix_name
+        ELSE left(unix_name, 100 - length($2::text)) || $2::text END
+WHERE id = $3 AND deleted_at IS NULL
 `
 
-func (q *Queries) DeleteProject(ctx context.Context, id pgtype.UUID) (int64, error) {
-	result, err := q.db.Exec(ctx, deleteProject, id)
+type DeleteProjectParams struct {
+	DeletedAt      pgtype.Timestamptz `json:"deleted_at"`
+	UnixNameSuffix string             `json:"unix_name_suffix"`
+	ID             pgtype.UUID        `json:"id"`
+}
+
+// A non-empty unix_name_suffix renames the project to free its unix_name,
+// keeping the original in archived_unix_name.
+func (q *Queries) DeleteProject(ctx context.Context, arg DeleteProjectParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteProject, arg.DeletedAt, arg.UnixNameSuffix, arg.ID)
 	if err != nil {
 		return 0, err
 	}
 	return result.RowsAffected(), nil
 }
 
+const deleteSentOutboxEvents = `-- name: DeleteSentOutboxEvents :execrows
+DELETE FROM events_outbox
+WHERE sent_at < $1
+`
+
+func (q *Queries) DeleteSentOutboxEvents(ctx context.Context, sentAt pgtype.Timestamptz) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteSentOutboxEvents, sentAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const enqueueOutboxEvent = `-- name: EnqueueOutboxEvent :exec
+INSERT INTO events_outbox (event_type, project_id, payload, created_at, next_attempt_at)
+VALUES ($1, $2, $3, $4, $4)
+`
+
+type EnqueueOutboxEventParams struct {
+	EventType string             `json:"event_type"`
+	ProjectID pgtype.UUID        `json:"project_id"`
+	Payload   []byte             `json:"payload"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+func (q *Queries) EnqueueOutboxEvent(ctx context.Context, arg EnqueueOutboxEventParams) error {
+	_, err := q.db.Exec(ctx, enqueueOutboxEvent,
+		arg.EventType,
+		arg.ProjectID,
+		arg.Payload,
+		arg.CreatedAt,
+	)
+	return err
+}
+
 const getProject = `-- name: GetProject :one
-SELECT id, name, unix_name, description, active, created_at, updated_at
+SELECT id, name, unix_name, description, active, created_at, updated_at, provision_params, labels, status, deleted_at, org_id, resource_id, archived_unix_name, network, last_checked_at, node
 FROM projects
-WHERE id = $1
+WHERE id = $1 AND deleted_at IS NULL
 `
 
 func (q *Queries) GetProject(ctx context.Context, id pgtype.UUID) (Project, error) {
@@ -96,14 +321,24 @@ func (q *Queries) GetProject(ctx context.Context, id pgtype.UUID) (Project, erro
 		&i.Active,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ProvisionParams,
+		&i.Labels,
+		&i.Status,
+		&i.DeletedAt,
+		&i.OrgID,
+		&i.ResourceID,
+		&i.ArchivedUnixName,
+		&i.Network,
+		&i.LastCheckedAt,
+		&i.Node,
 	)
 	return i, err
 }
 
 const getProjectByUnixName = `-- name: GetProjectByUnixName :one
-SELECT id, name, unix_name, description, active, created_at, updated_at
+SELECT id, name, unix_name, description, active, created_at, updated_at, provision_params, labels, status, deleted_at, org_id, resource_id, archived_unix_name, network, last_checked_at, node
 FROM projects
-WHERE unix_name = $1
+WHERE unix_name = $1 AND deleted_at IS NULL
 `
 
 func (q *Queries) GetProjectByUnixName(ctx context.Context, unixName string) (Project, error) {
@@ -117,24 +352,205 @@ func (q *Queries) GetProjectByUnixName(ctx context.Context, unixName string) (Pr
 		&i.Active,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ProvisionParams,
+		&i.Labels,
+		&i.Status,
+		&i.DeletedAt,
+		&i.OrgID,
+		&i.ResourceID,
+		&i.ArchivedUnixName,
+		&i.Network,
+		&i.LastCheckedAt,
+		&i.Node,
 	)
 	return i, err
 }
 
+const getProjectsByIDs = `-- name: GetProjectsByIDs :many
+SELECT id, name, unix_name, description, active, created_at, updated_at, provision_params, labels, status, deleted_at, org_id, resource_id, archived_unix_name, network, last_checked_at, node
+FROM projects
+WHERE id = ANY($1::uuid[]) AND deleted_at IS NULL
+`
+
+func (q *Queries) GetProjectsByIDs(ctx context.Context, ids []pgtype.UUID) ([]Project, error) {
+	rows, err := q.db.Query(ctx, getProjectsByIDs, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Project
+	for rows.Next() {
+		var i Project
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.UnixName,
+			&i.Description,
+			&i.Active,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ProvisionParams,
+			&i.Labels,
+			&i.Status,
+			&i.DeletedAt,
+			&i.OrgID,
+			&i.ResourceID,
+			&i.ArchivedUnixName,
+			&i.Network,
+			&i.LastCheckedAt,
+			&i.Node,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listProjects = `-- name: ListProjects :many
-SELECT id, name, unix_name, description, active, created_at, updated_at
+SELECT id, name, unix_name, description, active, created_at, updated_at, provision_params, labels, status, deleted_at, org_id, resource_id, archived_unix_name, network, last_checked_at, node
 FROM projects
+WHERE deleted_at IS NULL
+  AND (cardinality($1::text[]) = 0 OR status = ANY($1::text[]))
+  AND ($2::text = '' OR node = $2::text)
 ORDER BY created_at DESC
-LIMIT $1 OFFSET $2
+LIMIT $3 OFFSET $4
 `
 
 type ListProjectsParams struct {
-	Limit  int32 `json:"limit"`
-	Offset int32 `json:"offset"`
+	Statuses []string `json:"statuses"`
+	Node     string   `json:"node"`
+	Limit    int32    `json:"limit"`
+	Offset   int32    `json:"offset"`
 }
 
 func (q *Queries) ListProjects(ctx context.Context, arg ListProjectsParams) ([]Project, error) {
-	rows, err := q.db.Query(ctx, listProjects, arg.Limit, arg.Offset)
+	rows, err := q.db.Query(ctx, listProjects,
+		arg.Statuses,
+		arg.Node,
+		arg.Limit,
+		arg.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Project
+	for rows.Next() {
+		var i Project
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.UnixName,
+			&i.Description,
+			&i.Active,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ProvisionParams,
+			&i.Labels,
+			&i.Status,
+			&i.DeletedAt,
+			&i.OrgID,
+			&i.ResourceID,
+			&i.ArchivedUnixName,
+			&i.Network,
+			&i.LastCheckedAt,
+			&i.Node,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listProjectsBySelector = `-- name: ListProjectsBySelector :many
+SELECT id, name, unix_name, description, active, created_at, updated_at, provision_params, labels, status, deleted_at, org_id, resource_id, archived_unix_name, network, last_checked_at, node
+FROM projects
+WHERE (cardinality($1::uuid[]) = 0 OR id = ANY($1::uuid[]))
+  AND labels @> $2::jsonb
+  AND ($3::text = '' OR provision_params->>'template' = $3::text)
+  AND deleted_at IS NULL
+ORDER BY created_at, id
+LIMIT $4
+`
+
+type ListProjectsBySelectorParams struct {
+	Ids         []pgtype.UUID `json:"ids"`
+	MatchLabels []byte        `json:"match_labels"`
+	Template    string        `json:"template"`
+	Limit       int32         `json:"limit"`
+}
+
+// Live projects matching a bulk selector, as UpdateProjectLabels matches
+// them, oldest first.
+func (q *Queries) ListProjectsBySelector(ctx context.Context, arg ListProjectsBySelectorParams) ([]Project, error) {
+	rows, err := q.db.Query(ctx, listProjectsBySelector,
+		arg.Ids,
+		arg.MatchLabels,
+		arg.Template,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Project
+	for rows.Next() {
+		var i Project
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.UnixName,
+			&i.Description,
+			&i.Active,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ProvisionParams,
+			&i.Labels,
+			&i.Status,
+			&i.DeletedAt,
+			&i.OrgID,
+			&i.ResourceID,
+			&i.ArchivedUnixName,
+			&i.Network,
+			&i.LastCheckedAt,
+			&i.Node,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listProjectsForReconcile = `-- name: ListProjectsForReconcile :many
+SELECT id, name, unix_name, description, active, created_at, updated_at, provision_params, labels, status, deleted_at, org_id, resource_id, archived_unix_name, network, last_checked_at, node
+FROM projects
+WHERE deleted_at IS NULL
+  AND (last_checked_at IS NULL OR last_checked_at < $1)
+ORDER BY last_checked_at NULLS FIRST, id
+LIMIT $2
+`
+
+type ListProjectsForReconcileParams struct {
+	OlderThan pgtype.Timestamptz `json:"older_than"`
+	Limit     int32              `json:"limit"`
+}
+
+// Live projects last checked before older_than, or never, least recently
+// checked first.
+func (q *Queries) ListProjectsForReconcile(ctx context.Context, arg ListProjectsForReconcileParams) ([]Project, error) {
+	rows, err := q.db.Query(ctx, listProjectsForReconcile, arg.OlderThan, arg.Limit)
 	if err != nil {
 		return nil, err
 	}
@@ -150,6 +566,103 @@ func (q *Queries) ListProjects(ctx context.Context, arg ListProjectsParams) ([]P
 			&i.Active,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.ProvisionParams,
+			&i.Labels,
+			&i.Status,
+			&i.DeletedAt,
+			&i.OrgID,
+			&i.ResourceID,
+			&i.ArchivedUnixName,
+			&i.Network,
+			&i.LastCheckedAt,
+			&i.Node,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRelatedProjects = `-- name: ListRelatedProjects :many
+SELECT p.id, p.name, p.unix_name, p.description, p.active, p.created_at, p.updated_at, p.provision_params, p.labels, p.status, p.deleted_at, p.org_id, p.resource_id, p.archived_unix_name, p.network, p.last_checked_at, p.node,
+    shared.count AS shared_labels
+FROM projects source
+JOIN projects p
+    ON p.id <> source.id
+   AND p.labels ?| ARRAY(SELECT jsonb_object_keys(source.labels))
+CROSS JOIN LATERAL (
+    SELECT COUNT(*) AS count
+    FROM jsonb_each(p.labels) AS label
+    WHERE source.labels @> jsonb_build_object(label.key, label.value)
+) shared
+WHERE source.id = $1 AND source.deleted_at IS NULL
+  AND p.active AND p.deleted_at IS NULL
+  AND shared.count > 0
+ORDER BY shared.count DESC, p.created_at DESC, p.id
+LIMIT $2 OFFSET $3
+`
+
+type ListRelatedProjectsParams struct {
+	ID     pgtype.UUID `json:"id"`
+	Limit  int32       `json:"limit"`
+	Offset int32       `json:"offset"`
+}
+
+type ListRelatedProjectsRow struct {
+	ID               pgtype.UUID        `json:"id"`
+	Name             string             `json:"name"`
+	UnixName         string             `json:"unix_name"`
+	Description      pgtype.Text        `json:"description"`
+	Active           bool               `json:"active"`
+	CreatedAt        pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt        pgtype.Timestamptz `json:"updated_at"`
+	ProvisionParams  []byte             `json:"provision_params"`
+	Labels           []byte             `json:"labels"`
+	Status           string             `json:"status"`
+	DeletedAt        pgtype.Timestamptz `json:"deleted_at"`
+	OrgID            pgtype.UUID        `json:"org_id"`
+	ResourceID       pgtype.Text        `json:"resource_id"`
+	ArchivedUnixName pgtype.Text        `json:"archived_unix_name"`
+	Network          []byte             `json:"network"`
+	LastCheckedAt    pgtype.Timestamptz `json:"last_checked_at"`
+	Node             pgtype.Text        `json:"node"`
+	SharedLabels     int64              `json:"shared_labels"`
+}
+
+// Other active projects sharing at least one label, key and value, with the
+// given project, ranked by how many they share.
+func (q *Queries) ListRelatedProjects(ctx context.Context, arg ListRelatedProjectsParams) ([]ListRelatedProjectsRow, error) {
+	rows, err := q.db.Query(ctx, listRelatedProjects, arg.ID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListRelatedProjectsRow
+	for rows.Next() {
+		var i ListRelatedProjectsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.UnixName,
+			&i.Description,
+			&i.Active,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ProvisionParams,
+			&i.Labels,
+			&i.Status,
+			&i.DeletedAt,
+			&i.OrgID,
+			&i.ResourceID,
+			&i.ArchivedUnixName,
+			&i.Network,
+			&i.LastCheckedAt,
+			&i.Node,
+			&i.SharedLabels,
 		); err != nil {
 			return nil, err
 		}
@@ -161,6 +674,381 @@ func (q *Queries) ListProjects(ctx context.Context, arg ListProjectsParams) ([]P
 	return items, nil
 }
 
+const lockOrganization = `-- name: LockOrganization :exec
+SELECT 1 FROM organizations WHERE id = $1 FOR UPDATE
+`
+
+// Held until the transaction ends, so quota checks on one organization run
+// one at a time.
+func (q *Queries) LockOrganization(ctx context.Context, id pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, lockOrganization, id)
+	return err
+}
+
+const lockProjectName = `-- name: LockProjectName :exec
+SELECT pg_advisory_xact_lock(hashtext('projects.name'), hashtext(lower($1::text)))
+`
+
+// The two-key form keeps name locks apart from single-key advisory locks.
+func (q *Queries) LockProjectName(ctx context.Context, name string) error {
+	_, err := q.db.Exec(ctx, lockProjectName, name)
+	return err
+}
+
+const markOutboxEventFailed = `-- name: MarkOutboxEventFailed :exec
+UPDATE events_outbox
+SET
+    attempts = attempts + 1,
+    next_attempt_at = $2,
+    last_error = $3
+WHERE id = $1
+`
+
+type MarkOutboxEventFailedParams struct {
+	ID            int64              `json:"id"`
+	NextAttemptAt pgtype.Timestamptz `json:"next_attempt_at"`
+	LastError     pgtype.Text        `json:"last_error"`
+}
+
+func (q *Queries) MarkOutboxEventFailed(ctx context.Context, arg MarkOutboxEventFailedParams) error {
+	_, err := q.db.Exec(ctx, markOutboxEventFailed, arg.ID, arg.NextAttemptAt, arg.LastError)
+	return err
+}
+
+const markOutboxEventSent = `-- name: MarkOutboxEventSent :exec
+UPDATE events_outbox
+SET sent_at = $2
+WHERE id = $1
+`
+
+type MarkOutboxEventSentParams struct {
+	ID     int64              `json:"id"`
+	SentAt pgtype.Timestamptz `json:"sent_at"`
+}
+
+func (q *Queries) MarkOutboxEventSent(ctx context.Context, arg MarkOutboxEventSentParams) error {
+	_, err := q.db.Exec(ctx, markOutboxEventSent, arg.ID, arg.SentAt)
+	return err
+}
+
+const markProjectChecked = `-- name: MarkProjectChecked :execrows
+UPDATE projects
+SET last_checked_at = $2
+WHERE id = $1 AND deleted_at IS NULL
+`
+
+type MarkProjectCheckedParams struct {
+	ID            pgtype.UUID        `json:"id"`
+	LastCheckedAt pgtype.Timestamptz `json:"last_checked_at"`
+}
+
+func (q *Queries) MarkProjectChecked(ctx context.Context, arg MarkProjectCheckedParams) (int64, error) {
+	result, err := q.db.Exec(ctx, markProjectChecked, arg.ID, arg.LastCheckedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const ping = `-- name: Ping :one
+SELECT 1
+`
+
+func (q *Queries) Ping(ctx context.Context) (int32, error) {
+	row := q.db.QueryRow(ctx, ping)
+	var column_1 int32
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
+const purgeDeletedProjects = `-- name: PurgeDeletedProjects :many
+DELETE FROM projects
+WHERE deleted_at IS NOT NULL AND deleted_at < $1
+RETURNING id, COALESCE(archived_unix_name, unix_name) AS unix_name, deleted_at
+`
+
+type PurgeDeletedProjectsRow struct {
+	ID        pgtype.UUID        `json:"id"`
+	UnixName  string             `json:"unix_name"`
+	DeletedAt pgtype.Timestamptz `json:"deleted_at"`
+}
+
+func (q *Queries) PurgeDeletedProjects(ctx context.Context, deletedAt pgtype.Timestamptz) ([]PurgeDeletedProjectsRow, error) {
+	rows, err := q.db.Query(ctx, purgeDeletedProjects, deletedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PurgeDeletedProjectsRow
+	for rows.Next() {
+		var i PurgeDeletedProjectsRow
+		if err := rows.Scan(&i.ID, &i.UnixName, &i.DeletedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setProjectNetwork = `-- name: SetProjectNetwork :execrows
+UPDATE projects
+SET
+    network = $2,
+    updated_at = $3
+WHERE id = $1 AND deleted_at IS NULL
+`
+
+type SetProjectNetworkParams struct {
+	ID        pgtype.UUID        `json:"id"`
+	Network   []byte             `json:"network"`
+	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+}
+
+func (q *Queries) SetProjectNetwork(ctx context.Context, arg SetProjectNetworkParams) (int64, error) {
+	result, err := q.db.Exec(ctx, setProjectNetwork, arg.ID, arg.Network, arg.UpdatedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const setProjectProvisionParams = `-- name: SetProjectProvisionParams :execrows
+UPDATE projects
+SET
+    provision_params = $2,
+    updated_at = $3
+WHERE id = $1 AND deleted_at IS NULL
+`
+
+type SetProjectProvisionParamsParams struct {
+	ID              pgtype.UUID        `json:"id"`
+	ProvisionParams []byte             `json:"provision_params"`
+	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
+}
+
+func (q *Queries) SetProjectProvisionParams(ctx context.Context, arg SetProjectProvisionParamsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, setProjectProvisionParams, arg.ID, arg.ProvisionParams, arg.UpdatedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const setProjectResource = `-- name: SetProjectResource :execrows
+UPDATE projects
+SET
+    resource_id = $2,
+    node = $3,
+    updated_at = $4
+WHERE id = $1 AND deleted_at IS NULL
+`
+
+type SetProjectResourceParams struct {
+	ID         pgtype.UUID        `json:"id"`
+	ResourceID pgtype.Text        `json:"resource_id"`
+	Node       pgtype.Text        `json:"node"`
+	UpdatedAt  pgtype.Timestamptz `json:"updated_at"`
+}
+
+func (q *Queries) SetProjectResource(ctx context.Context, arg SetProjectResourceParams) (int64, error) {
+	result, err := q.db.Exec(ctx, setProjectResource,
+		arg.ID,
+		arg.ResourceID,
+		arg.Node,
+		arg.UpdatedAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const syncOrgProjectLabels = `-- name: SyncOrgProjectLabels :many
+UPDATE projects AS p
+SET
+    labels = synced.labels,
+    updated_at = $1
+FROM (
+    SELECT q.id, $2::jsonb || COALESCE((
+        SELECT jsonb_object_agg(label.key, label.value)
+        FROM jsonb_each(q.labels) AS label
+        WHERE NOT $3::jsonb @> jsonb_build_object(label.key, label.value)
+    ), '{}'::jsonb) AS labels
+    FROM projects AS q
+    WHERE q.org_id = $4 AND q.deleted_at IS NULL
+) AS synced
+WHERE p.id = synced.id AND p.labels IS DISTINCT FROM synced.labels
+RETURNING p.id
+`
+
+type SyncOrgProjectLabelsParams struct {
+	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+	Current   []byte             `json:"current"`
+	Previous  []byte             `json:"previous"`
+	OrgID     pgtype.UUID        `json:"org_id"`
+}
+
+// Rebases the labels of an organization's live projects from the previous
+// default labels onto the current ones. A label still holding its previous
+// default is inherited, so it takes the current default or goes with it;
+// any other label is the project's own and wins over the defaults.
+func (q *Queries) SyncOrgProjectLabels(ctx context.Context, arg SyncOrgProjectLabelsParams) ([]pgtype.UUID, error) {
+	rows, err := q.db.Query(ctx, syncOrgProjectLabels,
+		arg.UpdatedAt,
+		arg.Current,
+		arg.Previous,
+		arg.OrgID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []pgtype.UUID
+	for rows.Next() {
+		var id pgtype.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const transferProject = `-- name: TransferProject :one
+UPDATE projects
+SET
+    org_id = $1,
+    updated_at = $2
+WHERE id = $3 AND org_id IS NOT DISTINCT FROM $4 AND deleted_at IS NULL
+RETURNING id, name, unix_name, description, active, created_at, updated_at, provision_params, labels, status, deleted_at, org_id, resource_id, archived_unix_name, network, last_checked_at, node
+`
+
+type TransferProjectParams struct {
+	ToOrgID   pgtype.UUID        `json:"to_org_id"`
+	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+	ID        pgtype.UUID        `json:"id"`
+	FromOrgID pgtype.UUID        `json:"from_org_id"`
+}
+
+// Moves a project to another organization only if it still belongs to
+// from_org_id, so a concurrent transfer is never silently overwritten.
+func (q *Queries) TransferProject(ctx context.Context, arg TransferProjectParams) (Project, error) {
+	row := q.db.QueryRow(ctx, transferProject,
+		arg.ToOrgID,
+		arg.UpdatedAt,
+		arg.ID,
+		arg.FromOrgID,
+	)
+	var i Project
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.UnixName,
+		&i.Description,
+		&i.Active,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ProvisionParams,
+		&i.Labels,
+		&i.Status,
+		&i.DeletedAt,
+		&i.OrgID,
+		&i.ResourceID,
+		&i.ArchivedUnixName,
+		&i.Network,
+		&i.LastCheckedAt,
+		&i.Node,
+	)
+	return i, err
+}
+
+const transitionProjectStatus = `-- name: TransitionProjectStatus :one
+WITH updated AS (
+    UPDATE projects
+    SET
+        status = $1,
+        updated_at = $2
+    WHERE id = $3 AND status = $4 AND deleted_at IS NULL
+    RETURNING id
+)
+SELECT
+    EXISTS(SELECT 1 FROM updated) AS transitioned,
+    EXISTS(SELECT 1 FROM projects WHERE id = $3 AND deleted_at IS NULL) AS found
+`
+
+type TransitionProjectStatusParams struct {
+	To        string             `json:"to"`
+	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+	ID        pgtype.UUID        `json:"id"`
+	From      string             `json:"from"`
+}
+
+type TransitionProjectStatusRow struct {
+	Transitioned bool `json:"transitioned"`
+	Found        bool `json:"found"`
+}
+
+func (q *Queries) TransitionProjectStatus(ctx context.Context, arg TransitionProjectStatusParams) (TransitionProjectStatusRow, error) {
+	row := q.db.QueryRow(ctx, transitionProjectStatus,
+		arg.To,
+		arg.UpdatedAt,
+		arg.ID,
+		arg.From,
+	)
+	var i TransitionProjectStatusRow
+	err := row.Scan(&i.Transitioned, &i.Found)
+	return i, err
+}
+
+const transitionStaleProjectStatus = `-- name: TransitionStaleProjectStatus :one
+WITH updated AS (
+    UPDATE projects
+    SET
+        status = $1,
+        updated_at = $2
+    WHERE id = $3 AND status = $4 AND updated_at < $5 AND deleted_at IS NULL
+    RETURNING id
+)
+SELECT
+    EXISTS(SELECT 1 FROM updated) AS transitioned,
+    EXISTS(SELECT 1 FROM projects WHERE id = $3 AND deleted_at IS NULL) AS found
+`
+
+type TransitionStaleProjectStatusParams struct {
+	To            string             `json:"to"`
+	UpdatedAt     pgtype.Timestamptz `json:"updated_at"`
+	ID            pgtype.UUID        `json:"id"`
+	From          string             `json:"from"`
+	ChangedBefore pgtype.Timestamptz `json:"changed_before"`
+}
+
+type TransitionStaleProjectStatusRow struct {
+	Transitioned bool `json:"transitioned"`
+	Found        bool `json:"found"`
+}
+
+// Like TransitionProjectStatus, but only for a project left unchanged
+// since changed_before, e.g. one whose provisioning no instance can still
+// be running.
+func (q *Queries) TransitionStaleProjectStatus(ctx context.Context, arg TransitionStaleProjectStatusParams) (TransitionStaleProjectStatusRow, error) {
+	row := q.db.QueryRow(ctx, transitionStaleProjectStatus,
+		arg.To,
+		arg.UpdatedAt,
+		arg.ID,
+		arg.From,
+		arg.ChangedBefore,
+	)
+	var i TransitionStaleProjectStatusRow
+	err := row.Scan(&i.Transitioned, &i.Found)
+	return i, err
+}
+
 const updateProject = `-- name: UpdateProject :one
 UPDATE projects
 SET
@@ -168,8 +1056,8 @@ SET
     description = COALESCE($4, description),
     active = COALESCE($5, active),
     updated_at = $3
-WHERE id = $1
-RETURNING id, name, unix_name, description, active, created_at, updated_at
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING id, name, unix_name, description, active, created_at, updated_at, provision_params, labels, status, deleted_at, org_id, resource_id, archived_unix_name, network, last_checked_at, node
 `
 
 type UpdateProjectParams struct {
@@ -197,6 +1085,86 @@ func (q *Queries) UpdateProject(ctx context.Context, arg UpdateProjectParams) (P
 		&i.Active,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ProvisionParams,
+		&i.Labels,
+		&i.Status,
+		&i.DeletedAt,
+		&i.OrgID,
+		&i.ResourceID,
+		&i.ArchivedUnixName,
+		&i.Network,
+		&i.LastCheckedAt,
+		&i.Node,
 	)
 	return i, err
 }
+
+const updateProjectLabels = `-- name: UpdateProjectLabels :many
+UPDATE projects
+SET
+    labels = (labels || $1::jsonb) - $2::text[],
+    updated_at = $3
+WHERE (cardinality($4::uuid[]) = 0 OR id = ANY($4::uuid[]))
+  AND labels @> $5::jsonb
+  AND ($6::text = '' OR provision_params->>'template' = $6::text)
+  AND deleted_at IS NULL
+RETURNING id
+`
+
+type UpdateProjectLabelsParams struct {
+	Add         []byte             `json:"add"`
+	Remove      []string           `json:"remove"`
+	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
+	Ids         []pgtype.UUID      `json:"ids"`
+	MatchLabels []byte             `json:"match_labels"`
+	Template    string             `json:"template"`
+}
+
+func (q *Queries) UpdateProjectLabels(ctx context.Context, arg UpdateProjectLabelsParams) ([]pgtype.UUID, error) {
+	rows, err := q.db.Query(ctx, updateProjectLabels,
+		arg.Add,
+		arg.Remove,
+		arg.UpdatedAt,
+		arg.Ids,
+		arg.MatchLabels,
+		arg.Template,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []pgtype.UUID
+	for rows.Next() {
+		var id pgtype.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateProjectStatus = `-- name: UpdateProjectStatus :execrows
+UPDATE projects
+SET
+    status = $2,
+    updated_at = $3
+WHERE id = $1 AND deleted_at IS NULL
+`
+
+type UpdateProjectStatusParams struct {
+	ID        pgtype.UUID        `json:"id"`
+	Status    string             `json:"status"`
+	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+}
+
+func (q *Queries) UpdateProjectStatus(ctx context.Context, arg UpdateProjectStatusParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateProjectStatus, arg.ID, arg.Status, arg.UpdatedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
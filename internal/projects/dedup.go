@@ -0,0 +1,76 @@
+package projects
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// createDedup collapses identical creates that arrive within a short window,
+// such as a double-clicked submit, onto a single project. It is a cheap
+// in-memory safety net, not a durable idempotency guarantee.
+type createDedup struct {
+	window time.Duration
+	now    func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*dedupEntry
+}
+
+// dedupEntry is one create, in flight until done is closed.
+type dedupEntry struct {
+	done    chan struct{}
+	project *Project
+	err     error
+	expires time.Time
+}
+
+func newCreateDedup(window time.Duration) *createDedup {
+	return &createDedup{
+		window:  window,
+		now:     time.Now,
+		entries: make(map[string]*dedupEntry),
+	}
+}
+
+// dedupKey identifies a create by what it creates and who asked for it.
+// Pure function.
+func dedupKey(unixName, actor string) string {
+	sum := sha256.Sum256([]byte(unixName + "\x00" + actor))
+	return hex.EncodeToString(sum[:])
+}
+
+// do runs create unless an identical create is in flight or succeeded less
+// than window ago, in which case its result is returned and shared is true.
+// Failed creates are not remembered, so a retry runs again.
+func (d *createDedup) do(key string, create func() (*Project, error)) (project *Project, shared bool, err error) {
+	d.mu.Lock()
+	now := d.now()
+	for k, e := range d.entries {
+		if !e.expires.IsZero() && now.After(e.expires) {
+			delete(d.entries, k)
+		}
+	}
+	if e, ok := d.entries[key]; ok {
+		d.mu.Unlock()
+		<-e.done
+		return e.project, true, e.err
+	}
+	e := &dedupEntry{done: make(chan struct{})}
+	d.entries[key] = e
+	d.mu.Unlock()
+
+	e.project, e.err = create()
+
+	d.mu.Lock()
+	if e.err != nil {
+		delete(d.entries, key)
+	} else {
+		e.expires = d.now().Add(d.window)
+	}
+	d.mu.Unlock()
+	close(e.done)
+
+	return e.project, false, e.err
+}
@@ -0,0 +1,112 @@
+package projects
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/searge/quokka/internal/plugin"
+)
+
+func TestHandlerCreateDeduplicatesIdenticalCreates(t *testing.T) {
+	var creates atomic.Int32
+	svc := newService(
+		mockStore{
+			createFn: func(_ context.Context, req CreateProjectRequest) (*Project, error) {
+				n := creates.Add(1)
+				time.Sleep(10 * time.Millisecond)
+				return &Project{ID: fmt.Sprintf("p-%d", n), Name: req.Name, UnixName: req.UnixName}, nil
+			},
+		},
+		mockRegistry{
+			getFn: func(string) (plugin.Plugin, error) {
+				return nil, plugin.ErrPluginNotFound
+			},
+		},
+		nil,
+	)
+	h := NewHandler(svc, nil, WithCreateDedup(time.Minute))
+
+	recorders := make([]*httptest.ResponseRecorder, 2)
+	var wg sync.WaitGroup
+	for i := range recorders {
+		recorders[i] = httptest.NewRecorder()
+		wg.Go(func() {
+			req := httptest.NewRequest(http.MethodPost, "/projects",
+				strings.NewReader(`{"name":"Alpha","unix_name":"alpha"}`))
+			h.Create(recorders[i], req)
+		})
+	}
+	wg.Wait()
+
+	if got := creates.Load(); got != 1 {
+		t.Fatalf("expected one project to be created, got %d", got)
+	}
+	deduplicated := 0
+	for _, rr := range recorders {
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+		}
+		if !strings.Contains(rr.Body.String(), `"id":"p-1"`) {
+			t.Errorf("expected both responses to carry project p-1, got %s", rr.Body.String())
+		}
+		if rr.Header().Get("X-Deduplicated") == "true" {
+			deduplicated++
+		}
+	}
+	if deduplicated != 1 {
+		t.Errorf("expected exactly one deduplicated response, got %d", deduplicated)
+	}
+}
+
+func TestCreateDedupExpiresAfterWindow(t *testing.T) {
+	d := newCreateDedup(time.Second)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	d.now = func() time.Time { return now }
+
+	calls := 0
+	create := func() (*Project, error) {
+		calls++
+		return &Project{ID: "p-1"}, nil
+	}
+	key := dedupKey("alpha", "10.0.0.1")
+
+	if _, shared, err := d.do(key, create); err != nil || shared {
+		t.Fatalf("first create: shared=%v err=%v", shared, err)
+	}
+	if _, shared, _ := d.do(key, create); !shared {
+		t.Error("expected a create within the window to be shared")
+	}
+	if _, shared, _ := d.do(dedupKey("alpha", "10.0.0.2"), create); shared {
+		t.Error("expected a create from another client not to be shared")
+	}
+
+	now = now.Add(2 * time.Second)
+	if _, shared, _ := d.do(key, create); shared {
+		t.Error("expected a create after the window to run again")
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 creates, got %d", calls)
+	}
+}
+
+func TestCreateDedupDoesNotRememberFailures(t *testing.T) {
+	d := newCreateDedup(time.Minute)
+	key := dedupKey("alpha", "")
+
+	_, _, err := d.do(key, func() (*Project, error) { return nil, ErrProjectExists })
+	if !errors.Is(err, ErrProjectExists) {
+		t.Fatalf("expected ErrProjectExists, got %v", err)
+	}
+	project, shared, err := d.do(key, func() (*Project, error) { return &Project{ID: "p-1"}, nil })
+	if err != nil || shared || project.ID != "p-1" {
+		t.Errorf("expected a retry after failure to run: project=%v shared=%v err=%v", project, shared, err)
+	}
+}
@@ -0,0 +1,52 @@
+package projects
+
+import "maps"
+
+// Defaulter fills in fields a create request left unset with a deployment's
+// default. It must not overwrite anything the client set.
+type Defaulter func(req *CreateProjectRequest)
+
+// WithDefaulters runs defaulters, in order, on every create request before
+// it is validated. Without any, requests are validated as sent.
+func WithDefaulters(defaulters ...Defaulter) ServiceOption {
+	return func(s *Service) {
+		s.defaulters = append(s.defaulters, defaulters...)
+	}
+}
+
+// DefaultLabels adds each of labels whose key the request does not set.
+func DefaultLabels(labels map[string]string) Defaulter {
+	return func(req *CreateProjectRequest) {
+		for k, v := range labels {
+			if _, ok := req.Labels[k]; ok {
+				continue
+			}
+			if req.Labels == nil {
+				req.Labels = make(map[string]string, len(labels))
+			}
+			req.Labels[k] = v
+		}
+	}
+}
+
+// DefaultDescription sets the description of requests that have none.
+func DefaultDescription(description string) Defaulter {
+	return func(req *CreateProjectRequest) {
+		if req.Description == "" {
+			req.Description = description
+		}
+	}
+}
+
+// applyDefaults returns req with every defaulter applied. The caller's
+// labels map is copied first, never written to.
+func (s *Service) applyDefaults(req CreateProjectRequest) CreateProjectRequest {
+	if len(s.defaulters) == 0 {
+		return req
+	}
+	req.Labels = maps.Clone(req.Labels)
+	for _, d := range s.defaulters {
+		d(&req)
+	}
+	return req
+}
@@ -0,0 +1,86 @@
+package projects
+
+import (
+	"context"
+	"maps"
+	"testing"
+)
+
+func TestApplyDefaultsFillsOnlyUnsetFields(t *testing.T) {
+	s := newService(mockStore{}, mockRegistry{}, nil, WithDefaulters(
+		DefaultLabels(map[string]string{"env": "prod", "team": "platform"}),
+		DefaultDescription("Managed by Quokka"),
+	))
+
+	tests := []struct {
+		name            string
+		req             CreateProjectRequest
+		wantLabels      map[string]string
+		wantDescription string
+	}{
+		{
+			name:            "nothing set",
+			req:             CreateProjectRequest{},
+			wantLabels:      map[string]string{"env": "prod", "team": "platform"},
+			wantDescription: "Managed by Quokka",
+		},
+		{
+			name:            "client values win",
+			req:             CreateProjectRequest{Description: "Billing", Labels: map[string]string{"env": "dev", "app": "api"}},
+			wantLabels:      map[string]string{"env": "dev", "team": "platform", "app": "api"},
+			wantDescription: "Billing",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sent := maps.Clone(tt.req.Labels)
+
+			got := s.applyDefaults(tt.req)
+			if !maps.Equal(got.Labels, tt.wantLabels) {
+				t.Errorf("labels = %v, want %v", got.Labels, tt.wantLabels)
+			}
+			if got.Description != tt.wantDescription {
+				t.Errorf("description = %q, want %q", got.Description, tt.wantDescription)
+			}
+			if !maps.Equal(tt.req.Labels, sent) {
+				t.Errorf("caller's labels changed to %v", tt.req.Labels)
+			}
+		})
+	}
+}
+
+func TestServiceCreateAppliesDefaultsBeforeValidation(t *testing.T) {
+	var stored CreateProjectRequest
+	s := newService(
+		mockStore{
+			createFn: func(_ context.Context, req CreateProjectRequest) (*Project, error) {
+				stored = req
+				return &Project{ID: "p-1", Name: req.Name, Labels: req.Labels}, nil
+			},
+		},
+		mockRegistry{},
+		nil,
+		WithDefaulters(func(req *CreateProjectRequest) {
+			if req.UnixName == "" {
+				req.UnixName = "generated"
+			}
+		}, DefaultLabels(map[string]string{"env": "prod"})),
+	)
+
+	if _, err := s.Create(context.Background(), CreateProjectRequest{Name: "Alpha"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if stored.UnixName != "generated" || stored.Labels["env"] != "prod" {
+		t.Errorf("stored request = %+v, want defaults applied", stored)
+	}
+}
+
+func TestApplyDefaultsIsNoOpWithoutDefaulters(t *testing.T) {
+	s := newService(mockStore{}, mockRegistry{}, nil)
+
+	req := CreateProjectRequest{Name: "Alpha"}
+	got := s.applyDefaults(req)
+	if got.Labels != nil || got.Description != "" {
+		t.Errorf("applyDefaults() = %+v, want the request unchanged", got)
+	}
+}
@@ -0,0 +1,212 @@
+package projects
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// ErrInvalidDescriptionTemplate is returned for a description template that
+// does not parse, or uses a variable that is not available.
+var ErrInvalidDescriptionTemplate = errors.New("invalid description template")
+
+// maxDescriptionVars caps the caller-supplied variables of one template.
+const maxDescriptionVars = 32
+
+// maxRenderedDescription caps the size of a rendered description.
+const maxRenderedDescription = 64 << 10
+
+// descriptionFuncs are the template functions a description may call. The
+// rest are left out because they can do unbounded work before anything is
+// written: printf takes its padding from the template, call runs
+// arbitrary functions.
+var descriptionFuncs = map[string]bool{
+	"and": true, "or": true, "not": true, "len": true, "index": true, "slice": true,
+	"print": true, "html": true, "js": true, "urlquery": true,
+	"eq": true, "ne": true, "lt": true, "le": true, "gt": true, "ge": true,
+}
+
+// descriptionVarRegex matches names usable as {{.Name}} in a template.
+var descriptionVarRegex = regexp.MustCompile(`^[A-Z][A-Za-z0-9]{0,62}$`)
+
+// renderDescription returns the description to store for req. Unless
+// req.DescriptionTemplate is set, that is the description as given.
+// Otherwise the description is a text/template that may use .Name,
+// .UnixName, .Plugin, .Labels and the request's description_vars; any other
+// variable is an error. Templates are limited to what renders in time
+// linear in the request (see checkDescriptionNode), and the output to
+// maxRenderedDescription. Pure function.
+func renderDescription(req CreateProjectRequest) (string, error) {
+	if !req.DescriptionTemplate {
+		return req.Description, nil
+	}
+
+	data, err := descriptionData(req)
+	if err != nil {
+		return "", err
+	}
+	tmpl, err := template.New("description").Option("missingkey=error").Parse(req.Description)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalidDescriptionTemplate, templateErrorDetail(err))
+	}
+	if len(tmpl.Templates()) > 1 {
+		return "", fmt.Errorf("%w: define and block are not allowed", ErrInvalidDescriptionTemplate)
+	}
+	if err := checkDescriptionNode(tmpl.Tree, tmpl.Root, false); err != nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalidDescriptionTemplate, err)
+	}
+
+	out := &cappedBuilder{max: maxRenderedDescription}
+	if err := tmpl.Execute(out, data); err != nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalidDescriptionTemplate, templateErrorDetail(err))
+	}
+	return out.String(), nil
+}
+
+// checkDescriptionNode rejects the parts of a template whose running time
+// the request does not bound: ranges over anything but a data field (such
+// as {{range 1000000000}}), nested ranges, template calls and functions
+// outside descriptionFuncs. inRange reports whether node is inside a
+// range. Pure function.
+func checkDescriptionNode(tree *parse.Tree, node parse.Node, inRange bool) error {
+	switch n := node.(type) {
+	case nil:
+		return nil
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			if err := checkDescriptionNode(tree, child, inRange); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *parse.TextNode, *parse.CommentNode, *parse.BreakNode, *parse.ContinueNode:
+		return nil
+	case *parse.ActionNode:
+		return checkDescriptionPipe(tree, n.Pipe)
+	case *parse.IfNode:
+		return checkDescriptionBranch(tree, &n.BranchNode, inRange)
+	case *parse.WithNode:
+		return checkDescriptionBranch(tree, &n.BranchNode, inRange)
+	case *parse.RangeNode:
+		if inRange {
+			return fmt.Errorf("%s: nested range is not allowed", nodeLocation(tree, n))
+		}
+		if len(n.Pipe.Cmds) != 1 || len(n.Pipe.Cmds[0].Args) != 1 {
+			return fmt.Errorf("%s: range must be over a field", nodeLocation(tree, n))
+		}
+		if _, ok := n.Pipe.Cmds[0].Args[0].(*parse.FieldNode); !ok {
+			return fmt.Errorf("%s: range must be over a field", nodeLocation(tree, n))
+		}
+		if err := checkDescriptionNode(tree, n.List, true); err != nil {
+			return err
+		}
+		return checkDescriptionNode(tree, n.ElseList, inRange)
+	case *parse.TemplateNode:
+		return fmt.Errorf("%s: template calls are not allowed", nodeLocation(tree, n))
+	default:
+		return fmt.Errorf("%s: %s is not allowed", nodeLocation(tree, node), node)
+	}
+}
+
+// checkDescriptionBranch checks the pipeline and both lists of an if or
+// with. Pure function.
+func checkDescriptionBranch(tree *parse.Tree, n *parse.BranchNode, inRange bool) error {
+	if err := checkDescriptionPipe(tree, n.Pipe); err != nil {
+		return err
+	}
+	if err := checkDescriptionNode(tree, n.List, inRange); err != nil {
+		return err
+	}
+	return checkDescriptionNode(tree, n.ElseList, inRange)
+}
+
+// checkDescriptionPipe rejects functions outside descriptionFuncs anywhere
+// in pipe, including parenthesized pipelines. Pure function.
+func checkDescriptionPipe(tree *parse.Tree, pipe *parse.PipeNode) error {
+	if pipe == nil {
+		return nil
+	}
+	for _, cmd := range pipe.Cmds {
+		for _, arg := range cmd.Args {
+			switch a := arg.(type) {
+			case *parse.IdentifierNode:
+				if !descriptionFuncs[a.Ident] {
+					return fmt.Errorf("%s: function %q is not allowed", nodeLocation(tree, a), a.Ident)
+				}
+			case *parse.PipeNode:
+				if err := checkDescriptionPipe(tree, a); err != nil {
+					return err
+				}
+			case *parse.ChainNode:
+				if p, ok := a.Node.(*parse.PipeNode); ok {
+					if err := checkDescriptionPipe(tree, p); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// nodeLocation returns where node is in tree, as "description:line:col".
+// Pure function.
+func nodeLocation(tree *parse.Tree, node parse.Node) string {
+	location, _ := tree.ErrorContext(node)
+	return location
+}
+
+// cappedBuilder is a strings.Builder that refuses to grow past max bytes.
+type cappedBuilder struct {
+	strings.Builder
+	max int
+}
+
+func (b *cappedBuilder) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.max {
+		return 0, fmt.Errorf("rendered description exceeds %d bytes", b.max)
+	}
+	return b.Builder.Write(p)
+}
+
+// descriptionData is the whitelisted variable set of a description
+// template: the project's own fields plus the caller's variables, which may
+// not shadow them. Pure function.
+func descriptionData(req CreateProjectRequest) (map[string]any, error) {
+	labels := req.Labels
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	data := map[string]any{
+		"Name":     req.Name,
+		"UnixName": req.UnixName,
+		"Plugin":   withProvisionDefaults(req.ProvisionParams).Plugin,
+		"Labels":   labels,
+	}
+
+	if len(req.DescriptionVars) > maxDescriptionVars {
+		return nil, fmt.Errorf("%w: at most %d description_vars are allowed", ErrInvalidDescriptionTemplate, maxDescriptionVars)
+	}
+	for name, value := range req.DescriptionVars {
+		if !descriptionVarRegex.MatchString(name) {
+			return nil, fmt.Errorf("%w: variable %q must start with an uppercase letter and contain only letters and digits", ErrInvalidDescriptionTemplate, name)
+		}
+		if _, taken := data[name]; taken {
+			return nil, fmt.Errorf("%w: variable %q is reserved", ErrInvalidDescriptionTemplate, name)
+		}
+		data[name] = value
+	}
+	return data, nil
+}
+
+// templateErrorDetail strips the "template: description:" prefix text/template
+// puts on its errors, leaving the position and cause. Pure function.
+func templateErrorDetail(err error) string {
+	return strings.TrimPrefix(err.Error(), "template: ")
+}
@@ -0,0 +1,190 @@
+package projects
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRenderDescription(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     CreateProjectRequest
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "plain description is stored as given",
+			req:  CreateProjectRequest{Description: "{{.UnixName}} stays literal"},
+			want: "{{.UnixName}} stays literal",
+		},
+		{
+			name: "valid template",
+			req: CreateProjectRequest{
+				Name:                "Billing",
+				UnixName:            "billing",
+				Labels:              map[string]string{"env": "prod"},
+				Description:         "{{.UnixName}} for {{.Team}} ({{.Labels.env}}, {{.Plugin}})",
+				DescriptionTemplate: true,
+				DescriptionVars:     map[string]string{"Team": "payments"},
+			},
+			want: "billing for payments (prod, proxmox)",
+		},
+		{
+			name: "unknown variable",
+			req: CreateProjectRequest{
+				UnixName:            "billing",
+				Description:         "{{.UnixName}} for {{.Team}}",
+				DescriptionTemplate: true,
+			},
+			wantErr: true,
+		},
+		{
+			name: "malformed template",
+			req: CreateProjectRequest{
+				Description:         "{{.UnixName",
+				DescriptionTemplate: true,
+			},
+			wantErr: true,
+		},
+		{
+			name: "variable shadowing a project field",
+			req: CreateProjectRequest{
+				Description:         "{{.Name}}",
+				DescriptionTemplate: true,
+				DescriptionVars:     map[string]string{"Name": "spoofed"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "variable that cannot be referenced",
+			req: CreateProjectRequest{
+				Description:         "x",
+				DescriptionTemplate: true,
+				DescriptionVars:     map[string]string{"team-name": "payments"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "oversized output",
+			req: CreateProjectRequest{
+				Description:         strings.Repeat("x", maxRenderedDescription+1),
+				DescriptionTemplate: true,
+			},
+			wantErr: true,
+		},
+		{
+			name: "range over labels",
+			req: CreateProjectRequest{
+				Labels:              map[string]string{"env": "prod", "team": "payments"},
+				Description:         `{{range $k, $v := .Labels}}{{$k}}={{$v}};{{end}}{{if eq (len .Labels) 2}} ok{{end}}`,
+				DescriptionTemplate: true,
+			},
+			want: "env=prod;team=payments; ok",
+		},
+		{
+			name: "range over a number",
+			req: CreateProjectRequest{
+				Description:         "{{range 1000000000}}{{end}}",
+				DescriptionTemplate: true,
+			},
+			wantErr: true,
+		},
+		{
+			name: "range over a variable",
+			req: CreateProjectRequest{
+				Description:         "{{$n := 1000000000}}{{range $n}}{{end}}",
+				DescriptionTemplate: true,
+			},
+			wantErr: true,
+		},
+		{
+			name: "nested range",
+			req: CreateProjectRequest{
+				Labels:              map[string]string{"env": "prod"},
+				Description:         "{{range .Labels}}{{range $.Labels}}{{end}}{{end}}",
+				DescriptionTemplate: true,
+			},
+			wantErr: true,
+		},
+		{
+			name: "recursive template",
+			req: CreateProjectRequest{
+				Description:         `{{define "a"}}{{template "a" .}}{{template "a" .}}{{end}}{{template "a" .}}`,
+				DescriptionTemplate: true,
+			},
+			wantErr: true,
+		},
+		{
+			name: "padding from the template",
+			req: CreateProjectRequest{
+				Description:         `{{(printf "%*d" 1000000000 1) | len}}`,
+				DescriptionTemplate: true,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := renderDescription(tt.req)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidDescriptionTemplate) {
+					t.Fatalf("expected ErrInvalidDescriptionTemplate, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("renderDescription() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("renderDescription() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServiceCreateStoresRenderedDescription(t *testing.T) {
+	var stored string
+	s := newService(
+		mockStore{
+			createFn: func(_ context.Context, req CreateProjectRequest) (*Project, error) {
+				stored = req.Description
+				return &Project{ID: "p-1", Description: req.Description}, nil
+			},
+		},
+		mockRegistry{},
+		nil,
+	)
+
+	_, err := s.Create(context.Background(), CreateProjectRequest{
+		Name:                "Valid Name",
+		UnixName:            "valid-name",
+		Description:         "{{.UnixName}} for {{.Team}}",
+		DescriptionTemplate: true,
+		DescriptionVars:     map[string]string{"Team": "ops"},
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if stored != "valid-name for ops" {
+		t.Errorf("stored description = %q, want the rendered template", stored)
+	}
+}
+
+func TestHandlerCreateRejectsInvalidDescriptionTemplate(t *testing.T) {
+	h := NewHandler(newService(mockStore{}, mockRegistry{}, nil), nil)
+
+	body := `{"name":"Valid Name","unix_name":"valid-name","description":"{{.Team}}","description_template":true}`
+	rr := httptest.NewRecorder()
+	h.Routes().ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d", rr.Code)
+	}
+	if !strings.Contains(rr.Body.String(), "INVALID_DESCRIPTION_TEMPLATE") {
+		t.Errorf("expected INVALID_DESCRIPTION_TEMPLATE, got %s", rr.Body.String())
+	}
+}
@@ -0,0 +1,96 @@
+package projects
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+
+	"github.com/searge/quokka/internal/platform"
+	"github.com/searge/quokka/internal/projects/db"
+)
+
+// WithFieldEncryption encrypts the provision resources named by fields,
+// e.g. a generated root_password, before they are written, and decrypts
+// them as they are read, so the database only ever holds their ciphertext.
+// Other resources and project fields stay plaintext. Values stored before
+// a field was designated are read back as they are until next written.
+func WithFieldEncryption(keyring *platform.Keyring, fields ...string) StoreOption {
+	return func(s *Store) {
+		s.keyring = keyring
+		s.encryptedFields = fields
+	}
+}
+
+// encodeProvisionParams serializes params for the provision_params column,
+// encrypting its designated resources.
+func (s *Store) encodeProvisionParams(params *ProvisionParams) ([]byte, error) {
+	sealed, err := s.sealResources(params)
+	if err != nil {
+		return nil, err
+	}
+	return encodeProvisionParams(sealed)
+}
+
+// toDomainProject maps row to a Project, decrypting its designated
+// provision resources.
+func (s *Store) toDomainProject(row db.Project) (*Project, error) {
+	project, err := mapToDomainProject(row)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.openResources(project.ProvisionParams); err != nil {
+		return nil, fmt.Errorf("project %s: %w", project.ID, err)
+	}
+	return project, nil
+}
+
+// sealResources returns a copy of params whose designated resources are
+// encrypted, leaving params itself untouched for the caller.
+func (s *Store) sealResources(params *ProvisionParams) (*ProvisionParams, error) {
+	if s.keyring == nil || params == nil || len(params.Resources) == 0 {
+		return params, nil
+	}
+	sealed := *params
+	sealed.Resources = maps.Clone(params.Resources)
+	for _, field := range s.encryptedFields {
+		value, ok := sealed.Resources[field]
+		if !ok {
+			continue
+		}
+		// Any JSON value is encrypted, and restored with its type
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("encrypt resource %q: %w", field, err)
+		}
+		ciphertext, err := s.keyring.Encrypt(raw)
+		if err != nil {
+			return nil, fmt.Errorf("encrypt resource %q: %w", field, err)
+		}
+		sealed.Resources[field] = ciphertext
+	}
+	return &sealed, nil
+}
+
+// openResources decrypts the designated resources of params in place.
+// Those that are not ciphertext are left as they are.
+func (s *Store) openResources(params *ProvisionParams) error {
+	if s.keyring == nil || params == nil {
+		return nil
+	}
+	for _, field := range s.encryptedFields {
+		ciphertext, ok := params.Resources[field].(string)
+		if !ok || !platform.IsEncrypted(ciphertext) {
+			continue
+		}
+		raw, err := s.keyring.Decrypt(ciphertext)
+		if err != nil {
+			return fmt.Errorf("decrypt resource %q: %w", field, err)
+		}
+		var value any
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return fmt.Errorf("decrypt resource %q: %w", field, err)
+		}
+		params.Resources[field] = value
+	}
+	return nil
+}
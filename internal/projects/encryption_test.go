@@ -0,0 +1,128 @@
+package projects
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/searge/quokka/internal/platform"
+	"github.com/searge/quokka/internal/projects/db"
+)
+
+// testKeyring parses specs, the first being the active key.
+func testKeyring(t *testing.T, specs ...string) *platform.Keyring {
+	t.Helper()
+	k, err := platform.ParseKeyring(specs)
+	if err != nil {
+		t.Fatalf("ParseKeyring() error = %v", err)
+	}
+	return k
+}
+
+func testKeySpec(id string, b byte) string {
+	return id + ":" + base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, 32))
+}
+
+func secretParams() *ProvisionParams {
+	return &ProvisionParams{
+		Plugin: "proxmox",
+		Resources: map[string]interface{}{
+			"cores":         float64(4),
+			"root_password": "hunter2",
+			"api_tokens":    []interface{}{"t1", "t2"},
+		},
+	}
+}
+
+func TestStoreEncryptsDesignatedResources(t *testing.T) {
+	s := &Store{}
+	WithFieldEncryption(testKeyring(t, testKeySpec("k1", 1)), "root_password", "api_tokens")(s)
+	params := secretParams()
+
+	raw, err := s.encodeProvisionParams(params)
+	if err != nil {
+		t.Fatalf("encodeProvisionParams() error = %v", err)
+	}
+	// Quoted, as a secret stored in plaintext would be: ciphertext is
+	// base64 and may contain a short secret by chance, but never a quote
+	for _, secret := range []string{`"hunter2"`, `"t1"`, `"t2"`} {
+		if bytes.Contains(raw, []byte(secret)) {
+			t.Errorf("stored value %s holds %s in plaintext", raw, secret)
+		}
+	}
+	stored, err := decodeProvisionParams(raw)
+	if err != nil {
+		t.Fatalf("decodeProvisionParams() error = %v", err)
+	}
+	for _, field := range []string{"root_password", "api_tokens"} {
+		if v, _ := stored.Resources[field].(string); !strings.HasPrefix(v, "enc:v1:k1:") {
+			t.Errorf("stored %s = %v, want ciphertext", field, stored.Resources[field])
+		}
+	}
+	if stored.Resources["cores"] != float64(4) || stored.Plugin != "proxmox" {
+		t.Errorf("stored %+v, want the other fields in plaintext", stored)
+	}
+	if params.Resources["root_password"] != "hunter2" {
+		t.Error("encoding encrypted the caller's params in place")
+	}
+
+	got, err := s.toDomainProject(db.Project{
+		ID:              pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		ProvisionParams: raw,
+	})
+	if err != nil {
+		t.Fatalf("toDomainProject() error = %v", err)
+	}
+	if !reflect.DeepEqual(got.ProvisionParams, secretParams()) {
+		t.Errorf("read back %+v, want %+v", got.ProvisionParams, secretParams())
+	}
+}
+
+func TestStoreReadsValuesEncryptedWithRotatedKeys(t *testing.T) {
+	old := &Store{}
+	WithFieldEncryption(testKeyring(t, testKeySpec("2025", 1)), "root_password")(old)
+	raw, err := old.encodeProvisionParams(secretParams())
+	if err != nil {
+		t.Fatalf("encodeProvisionParams() error = %v", err)
+	}
+
+	rotated := &Store{}
+	WithFieldEncryption(testKeyring(t, testKeySpec("2026", 2), testKeySpec("2025", 1)), "root_password")(rotated)
+	got, err := rotated.toDomainProject(db.Project{ID: pgtype.UUID{Bytes: uuid.New(), Valid: true}, ProvisionParams: raw})
+	if err != nil {
+		t.Fatalf("toDomainProject() error = %v", err)
+	}
+	if got.ProvisionParams.Resources["root_password"] != "hunter2" {
+		t.Errorf("root_password = %v, want it decrypted with the retired key", got.ProvisionParams.Resources["root_password"])
+	}
+
+	// Without the retired key, the value cannot be read
+	dropped := &Store{}
+	WithFieldEncryption(testKeyring(t, testKeySpec("2026", 2)), "root_password")(dropped)
+	_, err = dropped.toDomainProject(db.Project{ID: pgtype.UUID{Bytes: uuid.New(), Valid: true}, ProvisionParams: raw})
+	if !errors.Is(err, platform.ErrUnknownEncryptionKey) {
+		t.Errorf("toDomainProject() error = %v, want ErrUnknownEncryptionKey", err)
+	}
+}
+
+func TestStoreReadsPlaintextStoredBeforeEncryption(t *testing.T) {
+	raw, err := encodeProvisionParams(secretParams())
+	if err != nil {
+		t.Fatalf("encodeProvisionParams() error = %v", err)
+	}
+	s := &Store{}
+	WithFieldEncryption(testKeyring(t, testKeySpec("k1", 1)), "root_password")(s)
+
+	got, err := s.toDomainProject(db.Project{ID: pgtype.UUID{Bytes: uuid.New(), Valid: true}, ProvisionParams: raw})
+	if err != nil {
+		t.Fatalf("toDomainProject() error = %v", err)
+	}
+	if got.ProvisionParams.Resources["root_password"] != "hunter2" {
+		t.Errorf("root_password = %v, want the plaintext left as it was", got.ProvisionParams.Resources["root_password"])
+	}
+}
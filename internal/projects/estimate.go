@@ -0,0 +1,86 @@
+package projects
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// estimateWindow is how many recent provisioning durations an estimate
+// averages over, per plugin and template.
+const estimateWindow = 20
+
+// provisionEstimates keeps how long recent successful provisioning took,
+// per plugin and template, to predict how long the next one will take.
+// Durations live only as long as the process.
+type provisionEstimates struct {
+	fallback time.Duration
+
+	mu      sync.Mutex
+	samples map[string][]time.Duration
+}
+
+func newProvisionEstimates(fallback time.Duration) *provisionEstimates {
+	return &provisionEstimates{fallback: fallback, samples: map[string][]time.Duration{}}
+}
+
+func estimateKey(pluginName, template string) string {
+	return pluginName + "/" + template
+}
+
+// record adds a successful provisioning duration, dropping the oldest once
+// the window is full.
+func (e *provisionEstimates) record(pluginName, template string, d time.Duration) {
+	key := estimateKey(pluginName, template)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	samples := e.samples[key]
+	if len(samples) == estimateWindow {
+		samples = append(samples[:0], samples[1:]...)
+	}
+	e.samples[key] = append(samples, d)
+}
+
+// estimate returns the average of the recorded durations for pluginName
+// and template, or the fallback if there are none. ok is false if there
+// is neither.
+func (e *provisionEstimates) estimate(pluginName, template string) (d time.Duration, ok bool) {
+	e.mu.Lock()
+	samples := e.samples[estimateKey(pluginName, template)]
+	var total time.Duration
+	for _, sample := range samples {
+		total += sample
+	}
+	e.mu.Unlock()
+
+	if len(samples) == 0 {
+		return e.fallback, e.fallback > 0
+	}
+	return total / time.Duration(len(samples)), true
+}
+
+// WithDefaultProvisionEstimate sets how long provisioning is expected to
+// take with a plugin and template that have not provisioned anything yet.
+// Zero, the default, gives no estimate for them.
+func WithDefaultProvisionEstimate(d time.Duration) ServiceOption {
+	return func(s *Service) {
+		s.estimates.fallback = d
+	}
+}
+
+// EstimateProvisioning returns how long provisioning the project req
+// describes is expected to take: the average of the last successful
+// provisioning runs with the same plugin and template, or the configured
+// default if there were none. ok is false if there is no estimate, or
+// nothing to estimate because provisioning is off.
+func (s *Service) EstimateProvisioning(ctx context.Context, req CreateProjectRequest) (d time.Duration, ok bool) {
+	if s.provisioningDisabled {
+		return 0, false
+	}
+	params := withProvisionDefaults(req.ProvisionParams)
+	if override := pluginOverride(ctx); override != "" {
+		params.Plugin = override
+	}
+	return s.estimates.estimate(params.Plugin, params.Template)
+}
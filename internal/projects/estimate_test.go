@@ -0,0 +1,125 @@
+package projects
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/searge/quokka/internal/plugin"
+)
+
+func TestProvisionEstimatesAverageRecentDurations(t *testing.T) {
+	e := newProvisionEstimates(time.Minute)
+	for _, d := range []time.Duration{10 * time.Second, 20 * time.Second, 30 * time.Second} {
+		e.record("proxmox", "ubuntu", d)
+	}
+	e.record("proxmox", "debian", time.Hour)
+
+	if d, ok := e.estimate("proxmox", "ubuntu"); !ok || d != 20*time.Second {
+		t.Errorf("estimate = %v, %v, want 20s, true", d, ok)
+	}
+	if d, ok := e.estimate("ovh", "ubuntu"); !ok || d != time.Minute {
+		t.Errorf("estimate without history = %v, %v, want the 1m default", d, ok)
+	}
+}
+
+func TestProvisionEstimatesKeepOnlyTheWindow(t *testing.T) {
+	e := newProvisionEstimates(0)
+	e.record("proxmox", "ubuntu", time.Hour)
+	for range estimateWindow {
+		e.record("proxmox", "ubuntu", 5*time.Second)
+	}
+
+	if d, _ := e.estimate("proxmox", "ubuntu"); d != 5*time.Second {
+		t.Errorf("estimate = %v, want 5s once the outlier left the window", d)
+	}
+}
+
+func TestProvisionEstimatesWithoutHistoryOrDefault(t *testing.T) {
+	if d, ok := newProvisionEstimates(0).estimate("proxmox", "ubuntu"); ok {
+		t.Errorf("expected no estimate, got %v", d)
+	}
+}
+
+func TestServiceEstimateProvisioningLearnsFromCreates(t *testing.T) {
+	svc := newService(
+		mockStore{
+			createFn: func(_ context.Context, req CreateProjectRequest) (*Project, error) {
+				return &Project{ID: "p-1", Name: req.Name, Status: StatusPending, ProvisionParams: req.ProvisionParams}, nil
+			},
+		},
+		mockRegistry{
+			getFn: func(string) (plugin.Plugin, error) {
+				return mockPlugin{
+					provisionFn: func(context.Context, plugin.ProvisionRequest) (*plugin.ProvisionResult, error) {
+						time.Sleep(20 * time.Millisecond)
+						return &plugin.ProvisionResult{ResourceID: "r-1"}, nil
+					},
+				}, nil
+			},
+		},
+		nil,
+		WithDefaultProvisionEstimate(time.Hour),
+	)
+	req := CreateProjectRequest{Name: "Alpha", UnixName: "alpha"}
+
+	if d, ok := svc.EstimateProvisioning(context.Background(), req); !ok || d != time.Hour {
+		t.Fatalf("estimate before any create = %v, %v, want the 1h default", d, ok)
+	}
+	if _, err := svc.Create(context.Background(), req); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	d, ok := svc.EstimateProvisioning(context.Background(), req)
+	if !ok || d < 20*time.Millisecond || d > time.Second {
+		t.Errorf("estimate after create = %v, %v, want about 20ms", d, ok)
+	}
+}
+
+func TestHandlerCreateAsyncPassesEstimatedCompletion(t *testing.T) {
+	tests := []struct {
+		name     string
+		estimate time.Duration
+		wantOpts int
+	}{
+		// The resource is always passed, the estimate only if there is one.
+		{name: "estimated", estimate: time.Minute, wantOpts: 2},
+		{name: "no estimate", wantOpts: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newService(
+				mockStore{
+					createFn: func(context.Context, CreateProjectRequest) (*Project, error) {
+						return &Project{ID: "p-1", Name: "Alpha", Status: StatusPending}, nil
+					},
+				},
+				mockRegistry{
+					getFn: func(string) (plugin.Plugin, error) {
+						return mockPlugin{}, nil
+					},
+				},
+				nil,
+				WithDefaultProvisionEstimate(tt.estimate),
+			)
+			ops := &syncOperations{}
+			h := NewHandler(svc, nil, WithOperations(ops))
+
+			rr := httptest.NewRecorder()
+			h.Create(rr, httptest.NewRequest(http.MethodPost, "/api/v1/projects?async=true",
+				strings.NewReader(`{"name":"Alpha","unix_name":"alpha"}`)))
+
+			if rr.Code != http.StatusAccepted {
+				t.Fatalf("expected 202, got %d: %s", rr.Code, rr.Body.String())
+			}
+			if len(ops.opts) != tt.wantOpts {
+				t.Errorf("enqueued with %d options, want %d", len(ops.opts), tt.wantOpts)
+			}
+			if ops.queueWaitFor != tt.estimate {
+				t.Errorf("asked the queue wait for operations of %v, want %v", ops.queueWaitFor, tt.estimate)
+			}
+		})
+	}
+}
@@ -0,0 +1,284 @@
+package projects
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/searge/quokka/internal/plugin"
+)
+
+// Event types published by the Service.
+const (
+	// EventCreated follows a project being stored. In deferred mode it is
+	// held back until provisioning has settled.
+	EventCreated = "project.created"
+	// EventProvisioned follows a successful (re)provisioning.
+	EventProvisioned = "project.provisioned"
+	// EventProvisionFailed follows a failed (re)provisioning.
+	EventProvisionFailed = "project.provision_failed"
+	// EventProvisionCancelled follows a (re)provisioning stopped by
+	// CancelProvision.
+	EventProvisionCancelled = "project.provision_cancelled"
+	// EventProgress reports how far along a running provisioning is, as
+	// the plugin's output says.
+	EventProgress = "project.progress"
+	// EventTransferred follows a project moving to another organization.
+	EventTransferred = "project.transferred"
+	// EventUpdated follows a change to a project's own fields.
+	EventUpdated = "project.updated"
+	// EventLabelsChanged follows a bulk or organization label change
+	// reaching a project. It carries no project snapshot.
+	EventLabelsChanged = "project.labels_changed"
+	// EventDeleted follows a project being deleted. It carries no project
+	// snapshot.
+	EventDeleted = "project.deleted"
+	// EventStatus carries a project's current state. It opens every event
+	// stream and is never published.
+	EventStatus = "project.status"
+)
+
+// Event is a domain event about a single project.
+type Event struct {
+	Type      string `json:"type"`
+	ProjectID string `json:"project_id"`
+	// Project is the project as the event left it, nil for events that
+	// carry no snapshot.
+	Project    *Project  `json:"project"`
+	Error      string    `json:"error,omitempty"`
+	OccurredAt time.Time `json:"occurred_at"`
+	// Progress is the completion percentage of a project.progress event.
+	Progress *int `json:"progress,omitempty"`
+	// PreviousOrgID is the organization a project.transferred event's
+	// project moved from, empty if it had none.
+	PreviousOrgID string `json:"previous_org_id,omitempty"`
+}
+
+// EventPublisher delivers domain events to interested consumers.
+type EventPublisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// MultiPublisher publishes every event to each of its publishers in turn.
+type MultiPublisher []EventPublisher
+
+// Publish implements EventPublisher. Every publisher is tried; their
+// failures are joined.
+func (m MultiPublisher) Publish(ctx context.Context, event Event) error {
+	var errs []error
+	for _, p := range m {
+		if err := p.Publish(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// LogPublisher publishes events as log records.
+type LogPublisher struct {
+	log *slog.Logger
+}
+
+// NewLogPublisher returns a publisher that logs every event to logger.
+func NewLogPublisher(logger *slog.Logger) *LogPublisher {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &LogPublisher{log: logger}
+}
+
+// Publish implements EventPublisher.
+func (p *LogPublisher) Publish(ctx context.Context, event Event) error {
+	attrs := []slog.Attr{
+		slog.String("type", event.Type),
+		slog.String("project_id", event.ProjectID),
+		slog.Time("occurred_at", event.OccurredAt),
+	}
+	if event.Project != nil {
+		attrs = append(attrs, slog.String("status", string(event.Project.Status)))
+	}
+	if event.Error != "" {
+		attrs = append(attrs, slog.String("error", event.Error))
+	}
+	if event.Progress != nil {
+		attrs = append(attrs, slog.Int("progress", *event.Progress))
+	}
+	if event.Type == EventTransferred {
+		attrs = append(attrs, slog.String("previous_org_id", event.PreviousOrgID))
+		if event.Project != nil {
+			attrs = append(attrs, slog.String("org_id", event.Project.OrgID))
+		}
+	}
+	p.log.LogAttrs(ctx, slog.LevelInfo, "project event", attrs...)
+	return nil
+}
+
+// WithEventPublisher publishes the service's domain events to p.
+func WithEventPublisher(p EventPublisher) ServiceOption {
+	return func(s *Service) {
+		s.events = p
+	}
+}
+
+// WithDeferredCreateEvents holds EventCreated back until provisioning has
+// reached a terminal state, so consumers never see a project without its
+// infrastructure outcome. The outcome event always follows it.
+func WithDeferredCreateEvents(deferred bool) ServiceOption {
+	return func(s *Service) {
+		s.deferCreateEvents = deferred
+	}
+}
+
+// WithEventOutbox writes the service's domain events to the store's
+// outbox, in the same transaction as the change each one describes, for a
+// Relay to publish, instead of publishing them once the change is made.
+// An event is then never lost to a crash or a failing publisher.
+// Progress events describe no stored change and are still published
+// directly.
+func WithEventOutbox(enabled bool) ServiceOption {
+	return func(s *Service) {
+		s.outbox = enabled
+	}
+}
+
+// newEvent returns an event about a snapshot of project, which the service
+// keeps updating.
+func newEvent(eventType string, project *Project, cause error) Event {
+	snapshot := *project
+	event := Event{
+		Type:       eventType,
+		ProjectID:  project.ID,
+		Project:    &snapshot,
+		OccurredAt: time.Now(),
+	}
+	if cause != nil {
+		event.Error = cause.Error()
+	}
+	return event
+}
+
+// idEvent returns an event about project id that carries no snapshot.
+func idEvent(eventType, id string) Event {
+	return Event{Type: eventType, ProjectID: id, OccurredAt: time.Now()}
+}
+
+// labelEvents returns project.labels_changed for each of ids.
+func labelEvents(ids []string) []Event {
+	events := make([]Event, len(ids))
+	for i, id := range ids {
+		events[i] = idEvent(EventLabelsChanged, id)
+	}
+	return events
+}
+
+// transferEvent returns project.transferred for project, which belonged to
+// previousOrgID.
+func transferEvent(project *Project, previousOrgID string) Event {
+	event := newEvent(EventTransferred, project, nil)
+	event.PreviousOrgID = previousOrgID
+	return event
+}
+
+// commit applies change to the store and delivers the events it returns:
+// through the outbox in the same transaction, holding a lock on lockName
+// if it is set, or published once change succeeded.
+func (s *Service) commit(ctx context.Context, lockName string, change func(projectStore) ([]Event, error)) error {
+	return s.commitIn(ctx, lockName, s.outbox, change)
+}
+
+// commitIn is commit, running change in a transaction if lockName is set
+// or atomic is.
+func (s *Service) commitIn(ctx context.Context, lockName string, atomic bool, change func(projectStore) ([]Event, error)) error {
+	var events []Event
+	apply := func(store projectStore) error {
+		var err error
+		events, err = change(store)
+		if err != nil || !s.outbox || len(events) == 0 {
+			return err
+		}
+		return store.EnqueueEvents(ctx, events...)
+	}
+
+	var err error
+	switch {
+	case lockName != "":
+		err = s.store.WithNameLock(ctx, lockName, apply)
+	case atomic:
+		err = s.store.Atomically(ctx, apply)
+	default:
+		err = apply(s.store)
+	}
+	if err != nil {
+		return err
+	}
+	if !s.outbox {
+		s.publishEvents(ctx, events...)
+	}
+	return nil
+}
+
+// publishEvents publishes events directly, logging rather than returning
+// delivery failures: the changes they describe have already happened.
+func (s *Service) publishEvents(ctx context.Context, events ...Event) {
+	if s.events == nil {
+		return
+	}
+	for _, event := range events {
+		s.send(ctx, event)
+	}
+}
+
+// send publishes event, logging a failure to do so.
+func (s *Service) send(ctx context.Context, event Event) {
+	if err := s.events.Publish(ctx, event); err != nil {
+		s.log.Warn("failed to publish project event", "type", event.Type, "project_id", event.ProjectID, "error", err)
+	}
+}
+
+// trackProgress returns out extended to publish a project.progress event
+// whenever a provisioning output line reports a new completion percentage.
+// Without an event publisher out is returned as-is.
+func (s *Service) trackProgress(ctx context.Context, project *Project, out func(line string)) func(line string) {
+	if s.events == nil {
+		return out
+	}
+	last := -1
+	return func(line string) {
+		if out != nil {
+			out(line)
+		}
+		progress, ok := plugin.ParseProgress(line)
+		if !ok || progress == last {
+			return
+		}
+		last = progress
+		s.send(ctx, Event{
+			Type:       EventProgress,
+			ProjectID:  project.ID,
+			Progress:   &progress,
+			OccurredAt: time.Now(),
+		})
+	}
+}
+
+// outcomeEvents returns the events reporting how a provisioning attempt
+// on project ended, preceded by project.created if created is set.
+// Projects whose plugin is not registered, or created with provisioning
+// disabled, were never provisioned and produce no outcome event.
+func outcomeEvents(project *Project, err error, created bool) []Event {
+	var events []Event
+	if created {
+		events = append(events, newEvent(EventCreated, project, nil))
+	}
+	switch {
+	case err == nil:
+		events = append(events, newEvent(EventProvisioned, project, nil))
+	case errors.Is(err, plugin.ErrPluginNotFound), errors.Is(err, ErrProvisioningDisabled):
+	case errors.Is(err, ErrProvisionCancelled):
+		events = append(events, newEvent(EventProvisionCancelled, project, nil))
+	default:
+		events = append(events, newEvent(EventProvisionFailed, project, err))
+	}
+	return events
+}
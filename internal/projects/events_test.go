@@ -0,0 +1,246 @@
+package projects
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/searge/quokka/internal/plugin"
+)
+
+// recordingPublisher keeps every published event in order.
+type recordingPublisher struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (r *recordingPublisher) Publish(_ context.Context, event Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *recordingPublisher) types() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	types := make([]string, len(r.events))
+	for i, e := range r.events {
+		types[i] = e.Type
+	}
+	return types
+}
+
+func TestServiceCreateEventOrdering(t *testing.T) {
+	tests := []struct {
+		name     string
+		deferred bool
+		provErr  error
+		want     []string
+		// wantCreatedStatus is the project status carried by project.created.
+		wantCreatedStatus ProvisionStatus
+	}{
+		{
+			name:              "immediate success",
+			want:              []string{EventCreated, EventProvisioned},
+			wantCreatedStatus: StatusPending,
+		},
+		{
+			name:              "immediate failure",
+			provErr:           errors.New("cli failed"),
+			want:              []string{EventCreated, EventProvisionFailed},
+			wantCreatedStatus: StatusPending,
+		},
+		{
+			name:              "deferred success",
+			deferred:          true,
+			want:              []string{EventCreated, EventProvisioned},
+			wantCreatedStatus: StatusProvisioned,
+		},
+		{
+			name:              "deferred failure",
+			deferred:          true,
+			provErr:           errors.New("cli failed"),
+			want:              []string{EventCreated, EventProvisionFailed},
+			wantCreatedStatus: StatusFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := &recordingPublisher{}
+			var provisionedAfter []string
+			s := newService(
+				mockStore{
+					createFn: func(context.Context, CreateProjectRequest) (*Project, error) {
+						return &Project{ID: "p-1", Name: "Alpha", Status: StatusPending}, nil
+					},
+				},
+				mockRegistry{
+					getFn: func(string) (plugin.Plugin, error) {
+						return mockPlugin{
+							provisionFn: func(context.Context, plugin.ProvisionRequest) (*plugin.ProvisionResult, error) {
+								provisionedAfter = events.types()
+								if tt.provErr != nil {
+									return nil, tt.provErr
+								}
+								return &plugin.ProvisionResult{ResourceID: "r-1"}, nil
+							},
+						}, nil
+					},
+				},
+				nil,
+				WithEventPublisher(events),
+				WithDeferredCreateEvents(tt.deferred),
+			)
+
+			if _, err := s.Create(context.Background(), CreateProjectRequest{Name: "Alpha", UnixName: "alpha"}); err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+
+			if got := events.types(); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("events = %v, want %v", got, tt.want)
+			}
+			if created := events.events[0]; created.Project.Status != tt.wantCreatedStatus {
+				t.Errorf("project.created carried status %q, want %q", created.Project.Status, tt.wantCreatedStatus)
+			}
+			if tt.deferred && len(provisionedAfter) != 0 {
+				t.Errorf("deferred mode published %v before provisioning settled", provisionedAfter)
+			}
+			if !tt.deferred && !reflect.DeepEqual(provisionedAfter, []string{EventCreated}) {
+				t.Errorf("immediate mode published %v before provisioning, want [%s]", provisionedAfter, EventCreated)
+			}
+			if tt.provErr != nil && events.events[1].Error == "" {
+				t.Error("expected the failure event to carry the error")
+			}
+		})
+	}
+}
+
+func TestServiceCreateWithoutPluginPublishesOnlyCreated(t *testing.T) {
+	events := &recordingPublisher{}
+	s := newService(
+		mockStore{
+			createFn: func(context.Context, CreateProjectRequest) (*Project, error) {
+				return &Project{ID: "p-1", Name: "Alpha"}, nil
+			},
+		},
+		mockRegistry{
+			getFn: func(string) (plugin.Plugin, error) {
+				return nil, plugin.ErrPluginNotFound
+			},
+		},
+		nil,
+		WithEventPublisher(events),
+		WithDeferredCreateEvents(true),
+	)
+
+	if _, err := s.Create(context.Background(), CreateProjectRequest{Name: "Alpha", UnixName: "alpha"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if got := events.types(); !reflect.DeepEqual(got, []string{EventCreated}) {
+		t.Errorf("events = %v, want [%s]", got, EventCreated)
+	}
+}
+
+func TestServiceProvisionPublishesProgress(t *testing.T) {
+	events := &recordingPublisher{}
+	s := newService(
+		mockStore{},
+		mockRegistry{
+			getFn: func(string) (plugin.Plugin, error) {
+				return streamingPlugin{lines: []string{
+					"cloning template",
+					"progress: 10%",
+					"progress: 10%",
+					"drive-scsi0: transferred 20.5 GiB of 32.0 GiB (64.06%) in 1m 2s",
+					"done",
+				}}, nil
+			},
+		},
+		nil,
+		WithEventPublisher(events),
+	)
+
+	var streamed []string
+	project := &Project{ID: "p-1", Name: "Alpha"}
+	if _, err := s.provision(context.Background(), project, func(line string) { streamed = append(streamed, line) }, false); err != nil {
+		t.Fatalf("provision() error = %v", err)
+	}
+
+	var progress []int
+	for _, e := range events.events {
+		if e.Type == EventProgress {
+			progress = append(progress, *e.Progress)
+		}
+	}
+	if !reflect.DeepEqual(progress, []int{10, 64}) {
+		t.Errorf("progress events = %v, want [10 64]", progress)
+	}
+	if len(streamed) != 5 {
+		t.Errorf("expected every line passed on to the caller, got %q", streamed)
+	}
+}
+
+func TestServiceMutationsPublishEvents(t *testing.T) {
+	store := mockStore{
+		updateFn: func(_ context.Context, id string, _ UpdateProjectRequest) (*Project, error) {
+			return &Project{ID: id, Name: "Renamed"}, nil
+		},
+		labelsFn: func(context.Context, LabelSelector, map[string]string, []string) ([]string, error) {
+			return []string{"p-2", "p-3"}, nil
+		},
+	}
+	tests := []struct {
+		name   string
+		mutate func(*Service) error
+		want   []Event
+	}{
+		{
+			name: "update",
+			mutate: func(s *Service) error {
+				_, err := s.Update(context.Background(), "p-1", UpdateProjectRequest{Name: ptr("Renamed")}, false)
+				return err
+			},
+			want: []Event{{Type: EventUpdated, ProjectID: "p-1"}},
+		},
+		{
+			name: "bulk label",
+			mutate: func(s *Service) error {
+				_, err := s.BulkLabel(context.Background(), BulkLabelRequest{
+					Selector: LabelSelector{MatchLabels: map[string]string{"env": "prod"}},
+					Add:      map[string]string{"tier": "gold"},
+				})
+				return err
+			},
+			want: []Event{{Type: EventLabelsChanged, ProjectID: "p-2"}, {Type: EventLabelsChanged, ProjectID: "p-3"}},
+		},
+		{
+			name:   "delete",
+			mutate: func(s *Service) error { return s.Delete(context.Background(), "p-1") },
+			want:   []Event{{Type: EventDeleted, ProjectID: "p-1"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := &recordingPublisher{}
+			s := newService(store, mockRegistry{}, nil, WithEventPublisher(events))
+
+			if err := tt.mutate(s); err != nil {
+				t.Fatalf("mutation error = %v", err)
+			}
+			if len(events.events) != len(tt.want) {
+				t.Fatalf("events = %v, want %d", events.types(), len(tt.want))
+			}
+			for i, want := range tt.want {
+				got := events.events[i]
+				if got.Type != want.Type || got.ProjectID != want.ProjectID {
+					t.Errorf("event %d = %s for %s, want %s for %s", i, got.Type, got.ProjectID, want.Type, want.ProjectID)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package projects
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/searge/quokka/internal/platform"
+)
+
+// ListFilter selects and windows the projects List returns. The zero value
+// lists every project, newest first, up to MaxListProjects.
+type ListFilter struct {
+	// Statuses, if non-empty, keeps only projects in one of them.
+	Statuses []ProvisionStatus
+	// Node, if non-empty, keeps only projects whose resource the plugin
+	// reported placing on it, wherever they asked to be pinned.
+	Node string
+	// Limit and Offset window the filtered projects. A Limit of zero
+	// means the maximum.
+	Limit  int32
+	Offset int32
+}
+
+// ParseListFilter reads a ListFilter from r's query string: status (comma
+// separated, may repeat), node, limit and offset. Malformed values are
+// reported together as a *platform.QueryError, and a negative limit or
+// offset as platform.ErrInvalidPagination. Whether statuses and node exist
+// is left to Service.List.
+func ParseListFilter(r *http.Request) (ListFilter, error) {
+	query := platform.QueryParams(r)
+	var filter ListFilter
+	for _, raw := range query.StringSlice("status") {
+		filter.Statuses = append(filter.Statuses, ProvisionStatus(raw))
+	}
+	filter.Node = query.String("node", "")
+	filter.Limit = query.Int32("limit", 0)
+	filter.Offset = query.Int32("offset", 0)
+	if err := query.Err(); err != nil {
+		return ListFilter{}, err
+	}
+	if filter.Limit < 0 || filter.Offset < 0 {
+		return ListFilter{}, fmt.Errorf("%w: limit and offset must not be negative", platform.ErrInvalidPagination)
+	}
+	return filter, nil
+}
@@ -0,0 +1,57 @@
+package projects
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/searge/quokka/internal/platform"
+)
+
+func TestParseListFilter(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  ListFilter
+	}{
+		{name: "empty", query: "", want: ListFilter{}},
+		{name: "statuses", query: "?status=failed,%20provisioning&status=provisioned",
+			want: ListFilter{Statuses: []ProvisionStatus{StatusFailed, StatusProvisioning, StatusProvisioned}}},
+		{name: "all", query: "?status=failed&node=pve-03&limit=20&offset=40",
+			want: ListFilter{Statuses: []ProvisionStatus{StatusFailed}, Node: "pve-03", Limit: 20, Offset: 40}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseListFilter(httptest.NewRequest(http.MethodGet, "/projects"+tt.query, nil))
+			if err != nil {
+				t.Fatalf("ParseListFilter() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseListFilter() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseListFilterReportsEveryMalformedValue(t *testing.T) {
+	_, err := ParseListFilter(httptest.NewRequest(http.MethodGet, "/projects?limit=ten&offset=x", nil))
+
+	var qerr *platform.QueryError
+	if !errors.As(err, &qerr) {
+		t.Fatalf("expected a *platform.QueryError, got %v", err)
+	}
+	if len(qerr.Fields) != 2 {
+		t.Errorf("expected 2 invalid fields, got %+v", qerr.Fields)
+	}
+}
+
+func TestParseListFilterRejectsNegativePagination(t *testing.T) {
+	for _, query := range []string{"?limit=-1", "?offset=-5"} {
+		_, err := ParseListFilter(httptest.NewRequest(http.MethodGet, "/projects"+query, nil))
+		if !errors.Is(err, platform.ErrInvalidPagination) {
+			t.Errorf("%s: expected ErrInvalidPagination, got %v", query, err)
+		}
+	}
+}
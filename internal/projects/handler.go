@@ -1,84 +1,479 @@
 package projects
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
+	"github.com/searge/quokka/internal/operations"
 	"github.com/searge/quokka/internal/platform"
+	"github.com/searge/quokka/internal/plugin"
 )
 
 type Handler struct {
-	service *Service
-	log     *slog.Logger
+	service    *Service
+	log        *slog.Logger
+	operations operationStarter
+	dedup      *createDedup
+	events     *Broadcaster
+	adminToken string
+	limit      func(http.Handler) http.Handler
+
+	testingMode bool
+}
+
+// operationStarter runs work in the background as a pollable operation.
+type operationStarter interface {
+	Enqueue(ctx context.Context, kind string, priority operations.Priority, fn operations.Func, opts ...operations.EnqueueOption) (*operations.Operation, error)
+	QueueWait(each time.Duration) time.Duration
+}
+
+// HandlerOption configures optional Handler behaviour.
+type HandlerOption func(*Handler)
+
+// WithOperations makes create asynchronous by default, provisioning each
+// new project in an operation started through ops; ?async=false still
+// provisions within the request.
+func WithOperations(ops operationStarter) HandlerOption {
+	return func(h *Handler) {
+		h.operations = ops
+	}
 }
 
-func NewHandler(service *Service, logger *slog.Logger) *Handler {
+// WithCreateDedup makes identical creates (same unix_name from the same
+// client) within window return the first project instead of creating again.
+// A zero window disables deduplication.
+func WithCreateDedup(window time.Duration) HandlerOption {
+	return func(h *Handler) {
+		if window > 0 {
+			h.dedup = newCreateDedup(window)
+		}
+	}
+}
+
+// WithEventStream enables GET /projects/{id}/events, streaming the events
+// published through b.
+func WithEventStream(b *Broadcaster) HandlerOption {
+	return func(h *Handler) {
+		h.events = b
+	}
+}
+
+// WithRequestLimit wraps every route but the event stream in limit, e.g.
+// platform.ConcurrencyLimit. The stream stays open for as long as its
+// client watches, so it would hold its place in the limit all that time.
+func WithRequestLimit(limit func(http.Handler) http.Handler) HandlerOption {
+	return func(h *Handler) {
+		h.limit = limit
+	}
+}
+
+// WithAdminToken makes callers presenting token as a bearer token admins,
+// the only ones allowed to transfer projects. An empty token makes no one
+// an admin.
+func WithAdminToken(token string) HandlerOption {
+	return func(h *Handler) {
+		h.adminToken = token
+	}
+}
+
+// WithTestingMode makes creates honor PluginOverrideHeader, provisioning
+// them with the plugin it names, which must be allowed. Without it the
+// header is ignored.
+func WithTestingMode(enabled bool) HandlerOption {
+	return func(h *Handler) {
+		h.testingMode = enabled
+	}
+}
+
+func NewHandler(service *Service, logger *slog.Logger, opts ...HandlerOption) *Handler {
 	if logger == nil {
 		logger = slog.Default()
 	}
-	return &Handler{service: service, log: logger}
+	h := &Handler{service: service, log: logger}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
 func (h *Handler) Routes() http.Handler {
 	r := chi.NewRouter()
 
-	r.Post("/", h.Create)
-	r.Get("/", h.List)
-	r.Get("/{id}", h.GetByID)
-	r.Put("/{id}", h.Update)
-	r.Delete("/{id}", h.Delete)
+	r.With(requireProjectID).Get("/{id}/events", h.Events)
+	r.Group(func(r chi.Router) {
+		if h.limit != nil {
+			r.Use(h.limit)
+		}
+		r.Post("/", h.Create)
+		r.Get("/", h.List)
+		r.Post("/labels", h.BulkLabel)
+		r.Post("/labels/sync", h.SyncOrgLabels)
+		r.Post("/provision-params", h.BulkProvision)
+		r.Post("/validate", h.Validate)
+		r.Group(func(r chi.Router) {
+			r.Use(requireProjectID)
+			r.Get("/{id}", h.GetByID)
+			r.Put("/{id}", h.Update)
+			r.Delete("/{id}", h.Delete)
+			r.Post("/{id}/reprovision", h.Reprovision)
+			r.Post("/{id}/cancel", h.Cancel)
+			r.With(platform.RequireAdmin(h.adminToken)).Post("/{id}/transfer", h.Transfer)
+			r.Get("/{id}/related", h.Related)
+			r.Get("/{id}/status", h.Status)
+			r.Post("/{id}/tags/sync", h.SyncTags)
+		})
+	})
 
 	return r
 }
 
+// requireProjectID answers 400 INVALID_PROJECT_ID for any {id} that can
+// never name a project, before the request reaches the service, so every
+// endpoint rejects one alike. The store still enforces WithIDVersion.
+func requireProjectID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := parseProjectID(chi.URLParam(r, "id"), 0); err != nil {
+			platform.RespondError(w, http.StatusBadRequest, "INVALID_PROJECT_ID", "invalid project id")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	loc, ok := h.location(w, r)
+	if !ok {
+		return
+	}
+
+	query := platform.QueryParams(r)
+	stream := query.Bool("stream", false)
+	// Creates are queued whenever operations are enabled, unless the client
+	// asks to wait for provisioning with ?async=false or ?stream=true.
+	async := query.Bool("async", h.operations != nil && !stream)
+	if err := query.Err(); err != nil {
+		platform.RespondQueryError(w, err)
+		return
+	}
+
 	var req CreateProjectRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		platform.RespondError(w, http.StatusBadRequest, "INVALID_JSON", "invalid JSON")
 		return
 	}
 
-	project, err := h.service.Create(r.Context(), req)
-	if err != nil {
-		switch {
-		case errors.As(err, &validator.ValidationErrors{}):
-			platform.RespondValidationError(w, err)
-		case errors.Is(err, ErrProjectExists):
-			platform.RespondError(w, http.StatusConflict, "PROJECT_EXISTS", err.Error())
-		case errors.Is(err, ErrInvalidUnixName):
-			platform.RespondError(w, http.StatusBadRequest, "INVALID_UNIX_NAME", err.Error())
-		default:
-			h.log.Error("internal err", "error", err)
-			platform.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "internal server error")
+	if h.testingMode {
+		if override := r.Header.Get(PluginOverrideHeader); override != "" {
+			if err := h.service.checkPluginAllowed(override); err != nil {
+				h.respondCreateError(w, err)
+				return
+			}
+			r = r.WithContext(WithPluginOverride(r.Context(), override))
 		}
+	}
+
+	if async {
+		h.createAsync(w, r, req)
+		return
+	}
+	if stream {
+		h.createStream(w, r, loc, req)
+		return
+	}
+
+	project, shared, err := h.create(r, req)
+	if err != nil {
+		h.respondCreateError(w, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", resourcePath(r.URL.Path, project.ID))
+	if shared {
+		w.Header().Set("X-Deduplicated", "true")
+	}
 	w.WriteHeader(http.StatusCreated)
-	if err := json.NewEncoder(w).Encode(project); err != nil {
+	if err := json.NewEncoder(w).Encode(project.In(loc)); err != nil {
 		h.log.Error("failed to encode response", "error", err)
 	}
 }
 
+// create runs a create, collapsed onto an identical recent one when
+// deduplication is enabled. shared reports whether the result was reused.
+func (h *Handler) create(r *http.Request, req CreateProjectRequest) (project *Project, shared bool, err error) {
+	if h.dedup == nil {
+		project, err = h.service.Create(r.Context(), req)
+		return project, false, err
+	}
+	return h.dedup.do(dedupKey(req.UnixName, clientAddr(r)), func() (*Project, error) {
+		return h.service.Create(r.Context(), req)
+	})
+}
+
+// createStream serves POST /projects?stream=true: provisioning output is
+// streamed as "log" events, followed by a final "done" event with the project.
+func (h *Handler) createStream(w http.ResponseWriter, r *http.Request, loc *time.Location, req CreateProjectRequest) {
+	sw := platform.NewStreamWriter(w, http.StatusCreated)
+	project, err := h.service.CreateStream(r.Context(), req, h.streamLines(sw))
+	if err != nil {
+		if !sw.Started() {
+			h.respondCreateError(w, err)
+			return
+		}
+		h.log.Error("internal err", "error", err)
+		h.sendEvent(sw, provisionEvent{Event: "error", Error: "internal server error"})
+		return
+	}
+	h.sendEvent(sw, provisionEvent{Event: "done", Project: project.In(loc)})
+}
+
+// createAsync serves POST /projects when operations are enabled: the
+// project is created up front, pending, and provisioned by an operation
+// queued at the request's priority. The response is 202 with the
+// operation, whose URL is in the Location header, with the project's URL
+// in Content-Location, and its estimated completion if the service can
+// estimate how long provisioning will take, counted from when the
+// operations already ahead of it in the queue are expected to be done. If
+// the operation cannot be queued, the project is marked failed and the
+// response is 503.
+func (h *Handler) createAsync(w http.ResponseWriter, r *http.Request, req CreateProjectRequest) {
+	if h.operations == nil {
+		platform.RespondError(w, http.StatusBadRequest, "ASYNC_UNAVAILABLE", "asynchronous creation is not enabled")
+		return
+	}
+	if err := h.service.ValidateCreate(req); err != nil {
+		h.respondCreateError(w, err)
+		return
+	}
+	priority, err := h.service.ProvisionPriority(r.Context(), req)
+	if err != nil {
+		h.respondCreateError(w, err)
+		return
+	}
+
+	var opts []operations.EnqueueOption
+	if d, ok := h.service.EstimateProvisioning(r.Context(), req); ok {
+		opts = append(opts, operations.WithEstimatedCompletion(time.Now().Add(h.operations.QueueWait(d)+d)))
+	}
+
+	project, err := h.service.CreatePending(r.Context(), req)
+	if err != nil {
+		h.respondCreateError(w, err)
+		return
+	}
+	resource := "projects/" + project.ID
+	opts = append(opts, operations.WithResource(resource))
+	op, err := h.operations.Enqueue(r.Context(), OperationCreate, priority, func(ctx context.Context) (string, error) {
+		if err := h.service.ProvisionPending(ctx, project); errors.Is(err, ErrProvisionFailed) {
+			return resource, err
+		}
+		return resource, nil
+	}, opts...)
+	if err != nil {
+		h.service.AbandonPending(context.WithoutCancel(r.Context()), project, err)
+		switch {
+		case errors.Is(err, operations.ErrQueueFull), errors.Is(err, operations.ErrShuttingDown):
+			w.Header().Set("Retry-After", "5")
+			platform.RespondError(w, http.StatusServiceUnavailable, "QUEUE_UNAVAILABLE",
+				fmt.Sprintf("project %s was created but could not be queued for provisioning (%v); reprovision it later", project.ID, err))
+		default:
+			platform.RespondServerError(w, h.log, err)
+		}
+		return
+	}
+
+	w.Header().Set("Location", apiBase(r.URL.Path)+"/operations/"+op.ID)
+	w.Header().Set("Content-Location", resourcePath(r.URL.Path, project.ID))
+	platform.RespondJSON(w, http.StatusAccepted, op)
+}
+
+func (h *Handler) respondCreateError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.As(err, &validator.ValidationErrors{}):
+		platform.RespondValidationError(w, err)
+	case errors.Is(err, ErrProjectExists):
+		platform.RespondError(w, http.StatusConflict, "PROJECT_EXISTS", err.Error())
+	case errors.Is(err, ErrNameExists):
+		platform.RespondError(w, http.StatusConflict, "NAME_EXISTS", err.Error())
+	case errors.Is(err, ErrInvalidUnixName):
+		platform.RespondFieldError(w, http.StatusUnprocessableEntity, "INVALID_UNIX_NAME", err.Error(), platform.FieldError{
+			Field:      "unix_name",
+			Rule:       "unix_name",
+			Message:    "does not follow the unix name format",
+			Suggestion: platform.Suggestions(err)["unix_name"],
+		})
+	case errors.Is(err, ErrReservedUnixName):
+		platform.RespondFieldError(w, http.StatusUnprocessableEntity, "RESERVED_UNIX_NAME", err.Error(), platform.FieldError{
+			Field:   "unix_name",
+			Rule:    "reserved",
+			Message: "is reserved",
+		})
+	case errors.Is(err, ErrInvalidDescriptionTemplate):
+		platform.RespondError(w, http.StatusUnprocessableEntity, "INVALID_DESCRIPTION_TEMPLATE", err.Error())
+	case errors.Is(err, ErrPluginNotAllowed):
+		platform.RespondError(w, http.StatusForbidden, "PLUGIN_NOT_ALLOWED", err.Error())
+	case errors.Is(err, ErrNodeNotAllowed):
+		platform.RespondError(w, http.StatusUnprocessableEntity, "INVALID_NODE", err.Error())
+	case errors.Is(err, ErrUnknownTemplate):
+		platform.RespondError(w, http.StatusUnprocessableEntity, "UNKNOWN_TEMPLATE", err.Error())
+	case errors.Is(err, ErrPluginRejected):
+		platform.RespondError(w, http.StatusUnprocessableEntity, "PLUGIN_REJECTED", err.Error())
+	case errors.Is(err, ErrQuotaExceeded):
+		platform.RespondError(w, http.StatusTooManyRequests, "QUOTA_EXCEEDED", err.Error())
+	case errors.Is(err, ErrProvisionThrottled):
+		var throttled *ThrottleError
+		if errors.As(err, &throttled) {
+			platform.SetRetryAfter(w, throttled.RetryAfter)
+		}
+		platform.RespondError(w, http.StatusTooManyRequests, "PROVISION_THROTTLED", err.Error())
+	case errors.Is(err, ErrUnknownOrganization):
+		platform.RespondError(w, http.StatusUnprocessableEntity, "UNKNOWN_ORGANIZATION", err.Error())
+	default:
+		platform.RespondServerError(w, h.log, err)
+	}
+}
+
+// Validate serves POST /projects/validate: the create payload is checked as
+// Create would check it, and every problem is reported at once, but nothing
+// is created. A payload that fails checks is still a 200; the report's
+// valid field carries the verdict.
+func (h *Handler) Validate(w http.ResponseWriter, r *http.Request) {
+	var req CreateProjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		platform.RespondError(w, http.StatusBadRequest, "INVALID_JSON", "invalid JSON")
+		return
+	}
+
+	report, err := h.service.CheckCreate(r.Context(), req)
+	if err != nil {
+		platform.RespondServerError(w, h.log, err)
+		return
+	}
+
+	platform.RespondJSON(w, http.StatusOK, report)
+}
+
+// List serves GET /projects?status=&limit=&offset=: a page of projects,
+// newest first.
 func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
-	projects, err := h.service.List(r.Context(), 100, 0)
+	loc, ok := h.location(w, r)
+	if !ok {
+		return
+	}
+
+	filter, err := ParseListFilter(r)
 	if err != nil {
-		platform.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "internal server error")
+		if errors.Is(err, platform.ErrInvalidPagination) {
+			platform.RespondError(w, http.StatusBadRequest, "INVALID_PAGINATION", err.Error())
+			return
+		}
+		platform.RespondQueryError(w, err)
+		return
+	}
+	query := platform.QueryParams(r)
+	ids := query.StringSlice("ids")
+	fields := query.Fields("fields", Project{})
+	if err := query.Err(); err != nil {
+		platform.RespondQueryError(w, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(projects); err != nil {
-		h.log.Error("failed to encode response", "error", err)
+	if len(ids) > 0 {
+		h.listByIDs(w, r, loc, ids, fields)
+		return
 	}
+
+	page, err := h.service.List(r.Context(), filter)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidStatus):
+			platform.RespondError(w, http.StatusBadRequest, "INVALID_STATUS", err.Error())
+		case errors.Is(err, ErrNodeNotAllowed):
+			platform.RespondError(w, http.StatusUnprocessableEntity, "INVALID_NODE", err.Error())
+		default:
+			platform.RespondServerError(w, h.log, err)
+		}
+		return
+	}
+
+	for i, p := range page.Data {
+		page.Data[i] = p.In(loc)
+	}
+
+	if fields != nil {
+		shaped, err := platform.SelectFieldsEach(page.Data, fields)
+		if err != nil {
+			platform.RespondServerError(w, h.log, err)
+			return
+		}
+		platform.RespondJSON(w, http.StatusOK, platform.NewPage(shaped, page.Total, page.Limit, page.Offset))
+		return
+	}
+	platform.RespondJSON(w, http.StatusOK, page)
+}
+
+// listByIDs serves GET /projects?ids=a,b,c.
+func (h *Handler) listByIDs(w http.ResponseWriter, r *http.Request, loc *time.Location, ids []string, fields []string) {
+	result, err := h.service.GetMany(r.Context(), ids)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidProjectID):
+			platform.RespondError(w, http.StatusBadRequest, "INVALID_PROJECT_ID", err.Error())
+		case errors.Is(err, ErrTooManyIDs):
+			platform.RespondError(w, http.StatusBadRequest, "TOO_MANY_IDS", fmt.Sprintf("at most %d ids may be requested", MaxBatchIDs))
+		default:
+			platform.RespondServerError(w, h.log, err)
+		}
+		return
+	}
+
+	for i, p := range result.Projects {
+		result.Projects[i] = p.In(loc)
+	}
+
+	if fields != nil {
+		shaped, err := platform.SelectFieldsEach(result.Projects, fields)
+		if err != nil {
+			platform.RespondServerError(w, h.log, err)
+			return
+		}
+		platform.RespondJSON(w, http.StatusOK, map[string]any{
+			"projects":  shaped,
+			"not_found": result.NotFound,
+		})
+		return
+	}
+	platform.RespondJSON(w, http.StatusOK, result)
 }
 
 func (h *Handler) GetByID(w http.ResponseWriter, r *http.Request) {
+	loc, ok := h.location(w, r)
+	if !ok {
+		return
+	}
+
+	render := r.URL.Query().Get("render")
+	if render != "" && render != "html" {
+		platform.RespondError(w, http.StatusBadRequest, "INVALID_RENDER", "render must be html")
+		return
+	}
+
+	query := platform.QueryParams(r)
+	fields := query.Fields("fields", Project{})
+	if err := query.Err(); err != nil {
+		platform.RespondQueryError(w, err)
+		return
+	}
+
 	id := chi.URLParam(r, "id")
 	project, err := h.service.Get(r.Context(), id)
 	if err != nil {
@@ -88,43 +483,118 @@ func (h *Handler) GetByID(w http.ResponseWriter, r *http.Request) {
 		case errors.Is(err, ErrInvalidProjectID):
 			platform.RespondError(w, http.StatusBadRequest, "INVALID_PROJECT_ID", "invalid project id")
 		default:
-			h.log.Error("internal err", "error", err)
+			platform.RespondServerError(w, h.log, err)
+		}
+		return
+	}
+
+	resp := project.In(loc)
+	if render == "html" {
+		html, err := platform.RenderMarkdown(resp.Description)
+		if err != nil {
+			h.log.Error("failed to render description", "project_id", resp.ID, "error", err)
 			platform.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "internal server error")
+			return
+		}
+		resp.DescriptionHTML = html
+	}
+
+	if fields != nil {
+		shaped, err := platform.SelectFields(resp, fields)
+		if err != nil {
+			platform.RespondServerError(w, h.log, err)
+			return
 		}
+		platform.RespondJSON(w, http.StatusOK, shaped)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(project); err != nil {
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
 		h.log.Error("failed to encode response", "error", err)
 	}
 }
 
+// Related serves GET /projects/{id}/related?limit=&offset=: a page of the
+// other active projects sharing labels with this one.
+func (h *Handler) Related(w http.ResponseWriter, r *http.Request) {
+	loc, ok := h.location(w, r)
+	if !ok {
+		return
+	}
+
+	query := platform.QueryParams(r)
+	limit := query.Int32("limit", 0)
+	offset := query.Int32("offset", 0)
+	if err := query.Err(); err != nil {
+		platform.RespondQueryError(w, err)
+		return
+	}
+	if limit < 0 || offset < 0 {
+		platform.RespondError(w, http.StatusBadRequest, "INVALID_PAGINATION", "limit and offset must not be negative")
+		return
+	}
+
+	page, err := h.service.Related(r.Context(), chi.URLParam(r, "id"), limit, offset)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrProjectNotFound):
+			platform.RespondError(w, http.StatusNotFound, "PROJECT_NOT_FOUND", "project not found")
+		case errors.Is(err, ErrInvalidProjectID):
+			platform.RespondError(w, http.StatusBadRequest, "INVALID_PROJECT_ID", "invalid project id")
+		default:
+			platform.RespondServerError(w, h.log, err)
+		}
+		return
+	}
+
+	for _, rp := range page.Data {
+		rp.Project = rp.Project.In(loc)
+	}
+	platform.RespondJSON(w, http.StatusOK, page)
+}
+
 func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	loc, ok := h.location(w, r)
+	if !ok {
+		return
+	}
+
 	id := chi.URLParam(r, "id")
 
+	query := platform.QueryParams(r)
+	force := query.Bool("force", false)
+	if err := query.Err(); err != nil {
+		platform.RespondQueryError(w, err)
+		return
+	}
+
 	var req UpdateProjectRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		platform.RespondError(w, http.StatusBadRequest, "INVALID_JSON", "invalid JSON")
 		return
 	}
 
-	project, err := h.service.Update(r.Context(), id, req)
+	project, err := h.service.Update(r.Context(), id, req, force)
 	if err != nil {
 		switch {
 		case errors.Is(err, ErrProjectNotFound):
 			platform.RespondError(w, http.StatusNotFound, "PROJECT_NOT_FOUND", "project not found")
 		case errors.Is(err, ErrInvalidProjectID):
 			platform.RespondError(w, http.StatusBadRequest, "INVALID_PROJECT_ID", "invalid project id")
+		case errors.Is(err, ErrHasLiveResources):
+			platform.RespondError(w, http.StatusConflict, "HAS_ACTIVE_RESOURCES",
+				"project still has active resources; retry with ?force=true to deactivate anyway")
+		case errors.Is(err, ErrNameExists):
+			platform.RespondError(w, http.StatusConflict, "NAME_EXISTS", err.Error())
 		default:
-			h.log.Error("internal err", "error", err)
-			platform.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "internal server error")
+			platform.RespondServerError(w, h.log, err)
 		}
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(project); err != nil {
+	if err := json.NewEncoder(w).Encode(project.In(loc)); err != nil {
 		h.log.Error("failed to encode response", "error", err)
 	}
 }
@@ -139,11 +609,508 @@ func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
 		case errors.Is(err, ErrInvalidProjectID):
 			platform.RespondError(w, http.StatusBadRequest, "INVALID_PROJECT_ID", "invalid project id")
 		default:
-			h.log.Error("internal err", "error", err)
-			platform.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "internal server error")
+			platform.RespondServerError(w, h.log, err)
 		}
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// BulkLabel serves POST /projects/labels.
+func (h *Handler) BulkLabel(w http.ResponseWriter, r *http.Request) {
+	var req BulkLabelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		platform.RespondError(w, http.StatusBadRequest, "INVALID_JSON", "invalid JSON")
+		return
+	}
+
+	result, err := h.service.BulkLabel(r.Context(), req)
+	if err != nil {
+		switch {
+		case errors.As(err, &validator.ValidationErrors{}):
+			platform.RespondValidationError(w, err)
+		case errors.Is(err, ErrEmptySelector):
+			platform.RespondError(w, http.StatusUnprocessableEntity, "EMPTY_SELECTOR", err.Error())
+		case errors.Is(err, ErrInvalidLabelOp):
+			platform.RespondError(w, http.StatusUnprocessableEntity, "INVALID_LABEL_CHANGE", err.Error())
+		case errors.Is(err, ErrInvalidProjectID):
+			platform.RespondError(w, http.StatusBadRequest, "INVALID_PROJECT_ID", err.Error())
+		default:
+			platform.RespondServerError(w, h.log, err)
+		}
+		return
+	}
+
+	platform.RespondJSON(w, http.StatusOK, result)
+}
+
+// SyncOrgLabels serves POST /projects/labels/sync: it brings the labels of
+// an organization's projects in line with its current default labels.
+func (h *Handler) SyncOrgLabels(w http.ResponseWriter, r *http.Request) {
+	var req SyncOrgLabelsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		platform.RespondError(w, http.StatusBadRequest, "INVALID_JSON", "invalid JSON")
+		return
+	}
+
+	result, err := h.service.SyncOrgLabels(r.Context(), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrOrgLabelsDisabled):
+			platform.RespondError(w, http.StatusBadRequest, "ORG_LABELS_UNAVAILABLE", "organization labels are not enabled")
+		case errors.As(err, &validator.ValidationErrors{}):
+			platform.RespondValidationError(w, err)
+		case errors.Is(err, ErrUnknownOrganization):
+			platform.RespondError(w, http.StatusUnprocessableEntity, "UNKNOWN_ORGANIZATION", err.Error())
+		default:
+			platform.RespondServerError(w, h.log, err)
+		}
+		return
+	}
+
+	platform.RespondJSON(w, http.StatusOK, result)
+}
+
+// BulkProvision serves POST /projects/provision-params: it changes the
+// provisioning parameters of the selected projects and reprovisions those
+// that change. With ?dry_run=true the plan is returned and nothing is
+// changed. A plan with projects whose resources would need replacing is
+// refused with 409. Otherwise each planned project is changed and
+// reprovisioned by its own operation, queued at low priority so it does
+// not hold up creates; the response is 202 with a BulkProvisionResult.
+// Projects the queue has no room for are deferred, or the response is 503
+// if none fit.
+func (h *Handler) BulkProvision(w http.ResponseWriter, r *http.Request) {
+	query := platform.QueryParams(r)
+	dryRun := query.Bool("dry_run", false)
+	if err := query.Err(); err != nil {
+		platform.RespondQueryError(w, err)
+		return
+	}
+
+	var req BulkProvisionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		platform.RespondError(w, http.StatusBadRequest, "INVALID_JSON", "invalid JSON")
+		return
+	}
+	if !dryRun && h.operations == nil {
+		platform.RespondError(w, http.StatusBadRequest, "ASYNC_UNAVAILABLE", "asynchronous operations are not enabled")
+		return
+	}
+
+	plan, err := h.service.PlanBulkProvision(r.Context(), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrProvisioningDisabled):
+			platform.RespondError(w, http.StatusNotImplemented, "PROVISIONING_DISABLED", err.Error())
+		case errors.As(err, &validator.ValidationErrors{}):
+			platform.RespondValidationError(w, err)
+		case errors.Is(err, ErrEmptySelector):
+			platform.RespondError(w, http.StatusUnprocessableEntity, "EMPTY_SELECTOR", err.Error())
+		case errors.Is(err, ErrEmptyProvisionChange):
+			platform.RespondError(w, http.StatusUnprocessableEntity, "EMPTY_PROVISION_CHANGE", err.Error())
+		case errors.Is(err, ErrTooManyProjects):
+			platform.RespondError(w, http.StatusUnprocessableEntity, "TOO_MANY_PROJECTS", err.Error())
+		case errors.Is(err, ErrInvalidProjectID):
+			platform.RespondError(w, http.StatusBadRequest, "INVALID_PROJECT_ID", err.Error())
+		case errors.Is(err, plugin.ErrPluginNotFound):
+			platform.RespondError(w, http.StatusUnprocessableEntity, "PLUGIN_NOT_FOUND", err.Error())
+		case errors.Is(err, ErrPluginNotAllowed):
+			platform.RespondError(w, http.StatusForbidden, "PLUGIN_NOT_ALLOWED", err.Error())
+		case errors.Is(err, ErrNodeNotAllowed):
+			platform.RespondError(w, http.StatusUnprocessableEntity, "INVALID_NODE", err.Error())
+		case errors.Is(err, ErrUnknownTemplate):
+			platform.RespondError(w, http.StatusUnprocessableEntity, "UNKNOWN_TEMPLATE", err.Error())
+		default:
+			platform.RespondServerError(w, h.log, err)
+		}
+		return
+	}
+	if dryRun {
+		platform.RespondJSON(w, http.StatusOK, plan)
+		return
+	}
+	if len(plan.Replace) > 0 {
+		platform.RespondError(w, http.StatusConflict, "NEEDS_REPLACEMENT",
+			fmt.Sprintf("%v: %d project(s); narrow the selector to projects without resources", ErrNeedsReplacement, len(plan.Replace)))
+		return
+	}
+
+	result := BulkProvisionResult{Operations: []*operations.Operation{}, Deferred: []string{}}
+	for i, planned := range plan.Reprovision {
+		id := planned.ProjectID
+		resource := "projects/" + id
+		op, err := h.operations.Enqueue(r.Context(), OperationReprovision, operations.PriorityLow, func(ctx context.Context) (string, error) {
+			return resource, h.service.ReprovisionWithChange(ctx, id, req.ProvisionParams)
+		})
+		if errors.Is(err, operations.ErrQueueFull) || errors.Is(err, operations.ErrShuttingDown) {
+			for _, rest := range plan.Reprovision[i:] {
+				result.Deferred = append(result.Deferred, rest.ProjectID)
+			}
+			if len(result.Operations) == 0 {
+				w.Header().Set("Retry-After", "5")
+				platform.RespondError(w, http.StatusServiceUnavailable, "QUEUE_UNAVAILABLE", err.Error())
+				return
+			}
+			break
+		}
+		if err != nil {
+			platform.RespondServerError(w, h.log, err)
+			return
+		}
+		result.Operations = append(result.Operations, op)
+	}
+
+	platform.RespondJSON(w, http.StatusAccepted, result)
+}
+
+// Status reports the current state of the resource backing a project, as
+// its plugin sees it.
+func (h *Handler) Status(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	result, err := h.service.ResourceStatus(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrProvisioningDisabled):
+			platform.RespondError(w, http.StatusNotImplemented, "PROVISIONING_DISABLED", err.Error())
+		case errors.Is(err, ErrProjectNotFound):
+			platform.RespondError(w, http.StatusNotFound, "PROJECT_NOT_FOUND", "project not found")
+		case errors.Is(err, ErrInvalidProjectID):
+			platform.RespondError(w, http.StatusBadRequest, "INVALID_PROJECT_ID", "invalid project id")
+		case errors.Is(err, ErrNotProvisioned):
+			platform.RespondError(w, http.StatusConflict, "NOT_PROVISIONED", err.Error())
+		case errors.Is(err, plugin.ErrPluginNotFound):
+			platform.RespondError(w, http.StatusUnprocessableEntity, "PLUGIN_NOT_FOUND", err.Error())
+		case errors.Is(err, ErrStatusFailed):
+			h.log.Warn("resource status lookup failed", "project_id", id, "error", err)
+			respondPluginError(w, err, "STATUS_FAILED", "resource status lookup failed")
+		default:
+			platform.RespondServerError(w, h.log, err)
+		}
+		return
+	}
+
+	platform.RespondJSON(w, http.StatusOK, result)
+}
+
+// SyncTags serves POST /projects/{id}/tags/sync: it retags the project's
+// resource with its current labels.
+func (h *Handler) SyncTags(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	result, err := h.service.SyncTags(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrProvisioningDisabled):
+			platform.RespondError(w, http.StatusNotImplemented, "PROVISIONING_DISABLED", err.Error())
+		case errors.Is(err, ErrTagsDisabled):
+			platform.RespondError(w, http.StatusBadRequest, "TAGS_UNAVAILABLE", "resource tagging is not enabled")
+		case errors.Is(err, ErrProjectNotFound):
+			platform.RespondError(w, http.StatusNotFound, "PROJECT_NOT_FOUND", "project not found")
+		case errors.Is(err, ErrInvalidProjectID):
+			platform.RespondError(w, http.StatusBadRequest, "INVALID_PROJECT_ID", "invalid project id")
+		case errors.Is(err, ErrNotProvisioned):
+			platform.RespondError(w, http.StatusConflict, "NOT_PROVISIONED", err.Error())
+		case errors.Is(err, plugin.ErrPluginNotFound):
+			platform.RespondError(w, http.StatusUnprocessableEntity, "PLUGIN_NOT_FOUND", err.Error())
+		case errors.Is(err, errors.ErrUnsupported):
+			platform.RespondError(w, http.StatusUnprocessableEntity, "TAGS_UNSUPPORTED", "the project's plugin cannot tag resources")
+		case errors.Is(err, ErrTagSyncFailed):
+			h.log.Warn("tag sync failed", "project_id", id, "error", err)
+			respondPluginError(w, err, "TAG_SYNC_FAILED", "tag sync failed")
+		default:
+			platform.RespondServerError(w, h.log, err)
+		}
+		return
+	}
+
+	platform.RespondJSON(w, http.StatusOK, result)
+}
+
+// Reprovision replays the project's stored provisioning request.
+func (h *Handler) Reprovision(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	query := platform.QueryParams(r)
+	stream := query.Bool("stream", false)
+	if err := query.Err(); err != nil {
+		platform.RespondQueryError(w, err)
+		return
+	}
+
+	if stream {
+		h.reprovisionStream(w, r, id)
+		return
+	}
+
+	result, err := h.service.Reprovision(r.Context(), id)
+	if err != nil {
+		h.respondReprovisionError(w, id, err)
+		return
+	}
+
+	platform.RespondJSON(w, http.StatusOK, result)
+}
+
+// reprovisionStream serves POST /projects/{id}/reprovision?stream=true.
+func (h *Handler) reprovisionStream(w http.ResponseWriter, r *http.Request, id string) {
+	sw := platform.NewStreamWriter(w, http.StatusOK)
+	result, err := h.service.ReprovisionStream(r.Context(), id, h.streamLines(sw))
+	if err != nil {
+		if !sw.Started() {
+			h.respondReprovisionError(w, id, err)
+			return
+		}
+		h.log.Warn("reprovisioning failed", "project_id", id, "error", err)
+		h.sendEvent(sw, provisionEvent{Event: "error", Error: "provisioning failed"})
+		return
+	}
+	h.sendEvent(sw, provisionEvent{Event: "done", Result: result})
+}
+
+func (h *Handler) respondReprovisionError(w http.ResponseWriter, id string, err error) {
+	switch {
+	case errors.Is(err, ErrProvisioningDisabled):
+		platform.RespondError(w, http.StatusNotImplemented, "PROVISIONING_DISABLED", err.Error())
+	case errors.Is(err, ErrProjectNotFound):
+		platform.RespondError(w, http.StatusNotFound, "PROJECT_NOT_FOUND", "project not found")
+	case errors.Is(err, ErrInvalidProjectID):
+		platform.RespondError(w, http.StatusBadRequest, "INVALID_PROJECT_ID", "invalid project id")
+	case errors.Is(err, plugin.ErrPluginNotFound):
+		platform.RespondError(w, http.StatusUnprocessableEntity, "PLUGIN_NOT_FOUND", err.Error())
+	case errors.Is(err, ErrPluginNotAllowed):
+		platform.RespondError(w, http.StatusForbidden, "PLUGIN_NOT_ALLOWED", err.Error())
+	case errors.Is(err, ErrNodeNotAllowed):
+		platform.RespondError(w, http.StatusUnprocessableEntity, "INVALID_NODE", err.Error())
+	case errors.Is(err, ErrProvisionCancelled):
+		platform.RespondError(w, http.StatusConflict, "PROVISION_CANCELLED", "provisioning was cancelled")
+	case errors.Is(err, ErrProvisionFailed):
+		h.log.Warn("reprovisioning failed", "project_id", id, "error", err)
+		respondPluginError(w, err, "PROVISION_FAILED", "provisioning failed")
+	default:
+		platform.RespondServerError(w, h.log, err)
+	}
+}
+
+// Cancel serves POST /projects/{id}/cancel?deprovision=: it stops the
+// project's running provisioning and returns the project, now cancelled.
+// With deprovision=true a resource it left behind is removed too.
+func (h *Handler) Cancel(w http.ResponseWriter, r *http.Request) {
+	loc, ok := h.location(w, r)
+	if !ok {
+		return
+	}
+
+	query := platform.QueryParams(r)
+	deprovision := query.Bool("deprovision", false)
+	if err := query.Err(); err != nil {
+		platform.RespondQueryError(w, err)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	project, err := h.service.CancelProvision(r.Context(), id, deprovision)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrProvisioningDisabled):
+			platform.RespondError(w, http.StatusNotImplemented, "PROVISIONING_DISABLED", err.Error())
+		case errors.Is(err, ErrProjectNotFound):
+			platform.RespondError(w, http.StatusNotFound, "PROJECT_NOT_FOUND", "project not found")
+		case errors.Is(err, ErrInvalidProjectID):
+			platform.RespondError(w, http.StatusBadRequest, "INVALID_PROJECT_ID", "invalid project id")
+		case errors.Is(err, ErrNotProvisioning):
+			platform.RespondError(w, http.StatusConflict, "NOT_PROVISIONING", err.Error())
+		case errors.Is(err, ErrProvisioningElsewhere):
+			platform.RespondError(w, http.StatusConflict, "PROVISIONING_ELSEWHERE", err.Error())
+		case errors.Is(err, ErrCleanupFailed):
+			h.log.Warn("removing partial resource failed", "project_id", id, "error", err)
+			respondPluginError(w, err, "CLEANUP_FAILED", "provisioning cancelled, but removing its partial resource failed")
+		default:
+			platform.RespondServerError(w, h.log, err)
+		}
+		return
+	}
+
+	platform.RespondJSON(w, http.StatusOK, project.In(loc))
+}
+
+// Transfer serves POST /projects/{id}/transfer: the project moves to the
+// organization in the body. Routes only let admins through.
+func (h *Handler) Transfer(w http.ResponseWriter, r *http.Request) {
+	loc, ok := h.location(w, r)
+	if !ok {
+		return
+	}
+
+	var req TransferProjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		platform.RespondError(w, http.StatusBadRequest, "INVALID_JSON", "invalid JSON")
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	project, err := h.service.Transfer(r.Context(), id, req, platform.RequestActor(r, h.adminToken))
+	if err != nil {
+		switch {
+		case errors.As(err, &validator.ValidationErrors{}):
+			platform.RespondValidationError(w, err)
+		case errors.Is(err, ErrProjectNotFound):
+			platform.RespondError(w, http.StatusNotFound, "PROJECT_NOT_FOUND", "project not found")
+		case errors.Is(err, ErrInvalidProjectID):
+			platform.RespondError(w, http.StatusBadRequest, "INVALID_PROJECT_ID", "invalid project id")
+		case errors.Is(err, ErrTransferForbidden):
+			platform.RespondError(w, http.StatusForbidden, "TRANSFER_FORBIDDEN", err.Error())
+		case errors.Is(err, ErrUnknownOrganization):
+			platform.RespondError(w, http.StatusUnprocessableEntity, "UNKNOWN_ORGANIZATION", err.Error())
+		case errors.Is(err, ErrQuotaExceeded):
+			platform.RespondError(w, http.StatusTooManyRequests, "QUOTA_EXCEEDED", err.Error())
+		case errors.Is(err, ErrOwnerChanged):
+			platform.RespondError(w, http.StatusConflict, "OWNER_CHANGED", err.Error())
+		default:
+			platform.RespondServerError(w, h.log, err)
+		}
+		return
+	}
+
+	platform.RespondJSON(w, http.StatusOK, project.In(loc))
+}
+
+// respondPluginError answers a failed plugin call by how the plugin
+// classified it: a rejected request is the client's to fix, a missing
+// resource is a 404, an unavailable backend is worth retrying, and
+// anything else, including rejected plugin credentials, is a bad gateway.
+func respondPluginError(w http.ResponseWriter, err error, code, message string) {
+	switch plugin.Classify(err) {
+	case plugin.ClassInvalidInput:
+		platform.RespondError(w, http.StatusUnprocessableEntity, code, message+": request rejected by plugin")
+	case plugin.ClassNotFound:
+		platform.RespondError(w, http.StatusNotFound, "RESOURCE_NOT_FOUND", "resource not found")
+	case plugin.ClassTransient:
+		w.Header().Set("Retry-After", "5")
+		platform.RespondError(w, http.StatusServiceUnavailable, code, message+": plugin temporarily unavailable")
+	default:
+		platform.RespondError(w, http.StatusBadGateway, code, message)
+	}
+}
+
+// provisionEvent is one line of a streamed provisioning response.
+type provisionEvent struct {
+	Event   string                  `json:"event"`
+	Line    string                  `json:"line,omitempty"`
+	Error   string                  `json:"error,omitempty"`
+	Project *Project                `json:"project,omitempty"`
+	Result  *plugin.ProvisionResult `json:"result,omitempty"`
+}
+
+// streamLines forwards each provisioning output line as a "log" event.
+func (h *Handler) streamLines(sw *platform.StreamWriter) func(line string) {
+	return func(line string) {
+		h.sendEvent(sw, provisionEvent{Event: "log", Line: line})
+	}
+}
+
+// sendEvent writes ev, logging failures: the client may have gone away.
+func (h *Handler) sendEvent(sw *platform.StreamWriter, ev provisionEvent) {
+	if err := sw.Send(ev); err != nil {
+		h.log.Warn("failed to stream provisioning event", "event", ev.Event, "error", err)
+	}
+}
+
+// eventStreamKeepalive is how often an idle event stream sends a comment.
+const eventStreamKeepalive = 15 * time.Second
+
+// Events serves GET /projects/{id}/events as server-sent events: a
+// project.status event with the project as it is now, then every event
+// published about it until the client disconnects.
+func (h *Handler) Events(w http.ResponseWriter, r *http.Request) {
+	if h.events == nil {
+		platform.RespondError(w, http.StatusBadRequest, "EVENTS_UNAVAILABLE", "event streaming is not enabled")
+		return
+	}
+
+	project, err := h.service.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrProjectNotFound):
+			platform.RespondError(w, http.StatusNotFound, "PROJECT_NOT_FOUND", "project not found")
+		case errors.Is(err, ErrInvalidProjectID):
+			platform.RespondError(w, http.StatusBadRequest, "INVALID_PROJECT_ID", "invalid project id")
+		default:
+			platform.RespondServerError(w, h.log, err)
+		}
+		return
+	}
+
+	// Subscribe under the canonical ID, then re-read so no event can fall
+	// between the snapshot and the stream
+	events, unsubscribe := h.events.Subscribe(project.ID)
+	defer unsubscribe()
+	project, err = h.service.Get(r.Context(), project.ID)
+	if err != nil {
+		platform.RespondServerError(w, h.log, err)
+		return
+	}
+
+	sw := platform.NewEventStreamWriter(w)
+	snapshot := Event{Type: EventStatus, ProjectID: project.ID, Project: project, OccurredAt: time.Now()}
+	if err := sw.Send(snapshot.Type, snapshot); err != nil {
+		h.log.Warn("event stream closed", "project_id", project.ID, "error", err)
+		return
+	}
+
+	keepalive := time.NewTicker(eventStreamKeepalive)
+	defer keepalive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev := <-events:
+			err = sw.Send(ev.Type, ev)
+		case <-keepalive.C:
+			err = sw.Comment("keepalive")
+		}
+		if err != nil {
+			h.log.Warn("event stream closed", "project_id", project.ID, "error", err)
+			return
+		}
+	}
+}
+
+// location resolves the requested display timezone, writing a 400 response
+// and returning false when it is invalid.
+func (h *Handler) location(w http.ResponseWriter, r *http.Request) (*time.Location, bool) {
+	loc, err := platform.RequestLocation(r)
+	if err != nil {
+		platform.RespondError(w, http.StatusBadRequest, "INVALID_TIMEZONE", "invalid timezone")
+		return nil, false
+	}
+	return loc, true
+}
+
+// resourcePath returns the canonical path of the member id of the collection
+// at collectionPath, e.g. "/api/v1/projects/{id}" for "/api/v1/projects".
+// Pure function.
+func resourcePath(collectionPath, id string) string {
+	return strings.TrimSuffix(collectionPath, "/") + "/" + id
+}
+
+// apiBase returns the API root a collection path is served under, e.g.
+// "/api/v1" for "/api/v1/projects".
+// Pure function.
+func apiBase(collectionPath string) string {
+	collectionPath = strings.TrimSuffix(collectionPath, "/")
+	if i := strings.LastIndex(collectionPath, "/"); i >= 0 {
+		return collectionPath[:i]
+	}
+	return ""
+}
+
+// clientAddr identifies the caller by address, without the port. It stands
+// in for an authenticated actor.
+func clientAddr(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -4,12 +4,18 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/jackc/pgx/v5"
+	"github.com/searge/quokka/internal/operations"
+	"github.com/searge/quokka/internal/platform"
 	"github.com/searge/quokka/internal/plugin"
 )
 
@@ -100,3 +106,1322 @@ func TestHandlerGetByIDReturns500ForInternalError(t *testing.T) {
 		t.Fatalf("expected 500, got %d", rr.Code)
 	}
 }
+
+func newTimezoneTestHandler(createdAt time.Time) *Handler {
+	svc := newService(
+		mockStore{
+			getByID: func(_ context.Context, id string) (*Project, error) {
+				return &Project{ID: id, Name: "Alpha", CreatedAt: createdAt, UpdatedAt: createdAt}, nil
+			},
+		},
+		mockRegistry{},
+		nil,
+	)
+	return NewHandler(svc, nil)
+}
+
+func TestHandlerGetByIDConvertsTimestampsToRequestedTimezone(t *testing.T) {
+	createdAt := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	h := newTimezoneTestHandler(createdAt)
+
+	req := newGetRequestWithID("2a4e6b16-8a62-4d57-a05b-9f59248dbdb2")
+	req.URL.RawQuery = "tz=America/New_York"
+
+	rr := httptest.NewRecorder()
+	h.GetByID(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+
+	var body map[string]any
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode response body: %v", err)
+	}
+	if body["created_at"] != "2024-01-15T07:00:00-05:00" {
+		t.Fatalf("expected created_at in America/New_York, got %v", body["created_at"])
+	}
+}
+
+func TestHandlerGetByIDAcceptsTimezoneHeader(t *testing.T) {
+	createdAt := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+	h := newTimezoneTestHandler(createdAt)
+
+	req := newGetRequestWithID("2a4e6b16-8a62-4d57-a05b-9f59248dbdb2")
+	req.Header.Set("X-Timezone", "Europe/Kyiv")
+
+	rr := httptest.NewRecorder()
+	h.GetByID(rr, req)
+
+	var body map[string]any
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode response body: %v", err)
+	}
+	if body["created_at"] != "2024-07-01T15:00:00+03:00" {
+		t.Fatalf("expected created_at in Europe/Kyiv, got %v", body["created_at"])
+	}
+}
+
+func TestHandlerGetByIDReturns400ForInvalidTimezone(t *testing.T) {
+	h := newTimezoneTestHandler(time.Now())
+
+	req := newGetRequestWithID("2a4e6b16-8a62-4d57-a05b-9f59248dbdb2")
+	req.URL.RawQuery = "tz=Mars/Olympus_Mons"
+
+	rr := httptest.NewRecorder()
+	h.GetByID(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+
+	var body map[string]map[string]string
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode response body: %v", err)
+	}
+	if body["error"]["code"] != "INVALID_TIMEZONE" {
+		t.Fatalf("expected code INVALID_TIMEZONE, got %q", body["error"]["code"])
+	}
+}
+
+func TestHandlerGetByIDDefaultsToUTC(t *testing.T) {
+	kyiv, err := time.LoadLocation("Europe/Kyiv")
+	if err != nil {
+		t.Fatalf("failed to load location: %v", err)
+	}
+	createdAt := time.Date(2024, 1, 15, 14, 0, 0, 0, kyiv)
+	h := newTimezoneTestHandler(createdAt)
+
+	rr := httptest.NewRecorder()
+	h.GetByID(rr, newGetRequestWithID("2a4e6b16-8a62-4d57-a05b-9f59248dbdb2"))
+
+	var body map[string]any
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode response body: %v", err)
+	}
+	if body["created_at"] != "2024-01-15T12:00:00Z" {
+		t.Fatalf("expected created_at in UTC, got %v", body["created_at"])
+	}
+}
+
+func TestHandlerGetByIDRendersSanitizedDescriptionHTML(t *testing.T) {
+	svc := newService(
+		mockStore{
+			getByID: func(_ context.Context, id string) (*Project, error) {
+				return &Project{ID: id, Description: "**Billing** <script>alert(1)</script>"}, nil
+			},
+		},
+		mockRegistry{},
+		nil,
+	)
+	h := NewHandler(svc, nil)
+
+	req := newGetRequestWithID("2a4e6b16-8a62-4d57-a05b-9f59248dbdb2")
+	req.URL.RawQuery = "render=html"
+
+	rr := httptest.NewRecorder()
+	h.GetByID(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+
+	var body Project
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode response body: %v", err)
+	}
+	if !strings.Contains(body.DescriptionHTML, "<strong>Billing</strong>") {
+		t.Errorf("expected rendered markdown, got %q", body.DescriptionHTML)
+	}
+	if strings.Contains(body.DescriptionHTML, "<script") {
+		t.Errorf("expected script tag to be stripped, got %q", body.DescriptionHTML)
+	}
+	if body.Description != "**Billing** <script>alert(1)</script>" {
+		t.Errorf("raw description should be returned unchanged, got %q", body.Description)
+	}
+}
+
+func TestHandlerGetByIDOmitsDescriptionHTMLByDefault(t *testing.T) {
+	svc := newService(
+		mockStore{
+			getByID: func(_ context.Context, id string) (*Project, error) {
+				return &Project{ID: id, Description: "**Billing**"}, nil
+			},
+		},
+		mockRegistry{},
+		nil,
+	)
+	h := NewHandler(svc, nil)
+
+	rr := httptest.NewRecorder()
+	h.GetByID(rr, newGetRequestWithID("2a4e6b16-8a62-4d57-a05b-9f59248dbdb2"))
+
+	if strings.Contains(rr.Body.String(), "description_html") {
+		t.Fatalf("expected no description_html field, got %s", rr.Body.String())
+	}
+}
+
+func TestHandlerGetByIDReturns400ForUnknownRender(t *testing.T) {
+	h := NewHandler(newService(mockStore{}, mockRegistry{}, nil), nil)
+
+	req := newGetRequestWithID("2a4e6b16-8a62-4d57-a05b-9f59248dbdb2")
+	req.URL.RawQuery = "render=pdf"
+
+	rr := httptest.NewRecorder()
+	h.GetByID(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+}
+
+func TestHandlerListByIDsReturnsProjectsAndNotFound(t *testing.T) {
+	svc := newService(
+		mockStore{
+			getByIDs: func(context.Context, []string) ([]*Project, error) {
+				return []*Project{{ID: "2a4e6b16-8a62-4d57-a05b-9f59248dbdb2", Name: "Alpha"}}, nil
+			},
+		},
+		mockRegistry{},
+		nil,
+	)
+	h := NewHandler(svc, nil)
+
+	req := httptest.NewRequest(http.MethodGet,
+		"/projects?ids=2a4e6b16-8a62-4d57-a05b-9f59248dbdb2,%206f1c1f0e-5a55-4b8f-9b43-0d0f1f3cbd7e", nil)
+	rr := httptest.NewRecorder()
+	h.List(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+
+	var body BatchGetResult
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode response body: %v", err)
+	}
+	if len(body.Projects) != 1 || body.Projects[0].Name != "Alpha" {
+		t.Errorf("unexpected projects: %+v", body.Projects)
+	}
+	if len(body.NotFound) != 1 || body.NotFound[0] != "6f1c1f0e-5a55-4b8f-9b43-0d0f1f3cbd7e" {
+		t.Errorf("unexpected not_found: %v", body.NotFound)
+	}
+}
+
+func TestHandlerListByIDsReturns400ForInvalidID(t *testing.T) {
+	svc := newService(
+		mockStore{
+			getByIDs: func(context.Context, []string) ([]*Project, error) {
+				return nil, fmt.Errorf("%w: %q", ErrInvalidProjectID, "bad-id")
+			},
+		},
+		mockRegistry{},
+		nil,
+	)
+	h := NewHandler(svc, nil)
+
+	rr := httptest.NewRecorder()
+	h.List(rr, httptest.NewRequest(http.MethodGet, "/projects?ids=2a4e6b16-8a62-4d57-a05b-9f59248dbdb2,bad-id", nil))
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+	if !strings.Contains(rr.Body.String(), "bad-id") {
+		t.Errorf("expected the offending id in the error, got %s", rr.Body.String())
+	}
+}
+
+func TestHandlerCreateReportsAllInvalidFields(t *testing.T) {
+	svc := newService(mockStore{}, mockRegistry{}, nil)
+	h := NewHandler(svc, nil)
+
+	body := strings.NewReader(`{"name":"ab","unix_name":"Bad_Name"}`)
+	rr := httptest.NewRecorder()
+	h.Create(rr, httptest.NewRequest(http.MethodPost, "/projects", body))
+
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d", rr.Code)
+	}
+
+	var resp platform.APIError
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response body: %v", err)
+	}
+	if resp.Error.Code != "VALIDATION_FAILED" {
+		t.Fatalf("expected code VALIDATION_FAILED, got %q", resp.Error.Code)
+	}
+
+	got := make(map[string]string, len(resp.Error.Fields))
+	for _, f := range resp.Error.Fields {
+		got[f.Field] = f.Rule
+	}
+	want := map[string]string{"name": "min", "unix_name": "unix_name"}
+	if len(got) != len(want) {
+		t.Fatalf("expected fields %v, got %+v", want, resp.Error.Fields)
+	}
+	for field, rule := range want {
+		if got[field] != rule {
+			t.Errorf("field %s: rule = %q, want %q", field, got[field], rule)
+		}
+	}
+}
+
+func TestHandlerCreateKeepsInvalidUnixNameCode(t *testing.T) {
+	svc := newService(mockStore{}, mockRegistry{}, nil)
+	h := NewHandler(svc, nil)
+
+	body := strings.NewReader(`{"name":"Alpha","unix_name":"Bad_Name"}`)
+	rr := httptest.NewRecorder()
+	h.Create(rr, httptest.NewRequest(http.MethodPost, "/projects", body))
+
+	var resp platform.APIError
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response body: %v", err)
+	}
+	if rr.Code != http.StatusUnprocessableEntity || resp.Error.Code != "INVALID_UNIX_NAME" {
+		t.Fatalf("expected 422 INVALID_UNIX_NAME, got %d %q", rr.Code, resp.Error.Code)
+	}
+}
+
+func TestHandlerCreateSeparatesMalformedFromInvalidRequests(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+		wantErr  string
+	}{
+		{name: "malformed json", body: `{"name":"Alpha",`, wantCode: http.StatusBadRequest, wantErr: "INVALID_JSON"},
+		{name: "wrong json type", body: `{"name":42}`, wantCode: http.StatusBadRequest, wantErr: "INVALID_JSON"},
+		{name: "fails validation", body: `{"name":"Alpha"}`, wantCode: http.StatusUnprocessableEntity, wantErr: "VALIDATION_FAILED"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(newService(mockStore{}, mockRegistry{}, nil), nil)
+
+			rr := httptest.NewRecorder()
+			h.Create(rr, httptest.NewRequest(http.MethodPost, "/projects", strings.NewReader(tt.body)))
+
+			var resp platform.APIError
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response body: %v", err)
+			}
+			if rr.Code != tt.wantCode || resp.Error.Code != tt.wantErr {
+				t.Fatalf("expected %d %s, got %d %q", tt.wantCode, tt.wantErr, rr.Code, resp.Error.Code)
+			}
+		})
+	}
+}
+
+// streamingPlugin emits fixed output lines through ProvisionStream.
+type streamingPlugin struct {
+	mockPlugin
+	lines []string
+}
+
+func (p streamingPlugin) ProvisionStream(_ context.Context, _ plugin.ProvisionRequest, out func(string)) (*plugin.ProvisionResult, error) {
+	for _, line := range p.lines {
+		out(line)
+	}
+	return &plugin.ProvisionResult{ResourceID: "vm-7", Status: "provisioned"}, nil
+}
+
+func TestHandlerCreateStreamsProvisioningOutput(t *testing.T) {
+	svc := newService(
+		mockStore{
+			createFn: func(_ context.Context, req CreateProjectRequest) (*Project, error) {
+				return &Project{ID: "p-1", Name: req.Name, UnixName: req.UnixName}, nil
+			},
+		},
+		mockRegistry{
+			getFn: func(string) (plugin.Plugin, error) {
+				return streamingPlugin{lines: []string{"cloning template", "ID: vm-7"}}, nil
+			},
+		},
+		nil,
+	)
+	h := NewHandler(svc, nil)
+
+	body := strings.NewReader(`{"name":"Alpha","unix_name":"alpha"}`)
+	rr := httptest.NewRecorder()
+	h.Create(rr, httptest.NewRequest(http.MethodPost, "/projects?stream=true", body))
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", rr.Code)
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("Content-Type = %q, want application/x-ndjson", ct)
+	}
+
+	var events []provisionEvent
+	dec := json.NewDecoder(rr.Body)
+	for dec.More() {
+		var ev provisionEvent
+		if err := dec.Decode(&ev); err != nil {
+			t.Fatalf("failed to decode event: %v", err)
+		}
+		events = append(events, ev)
+	}
+
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events, got %d: %+v", len(events), events)
+	}
+	if events[0].Event != "log" || events[0].Line != "cloning template" {
+		t.Errorf("first event = %+v, want log of the first line", events[0])
+	}
+	if events[1].Line != "ID: vm-7" {
+		t.Errorf("second event = %+v, want log of the second line", events[1])
+	}
+	if events[2].Event != "done" || events[2].Project == nil || events[2].Project.ID != "p-1" {
+		t.Errorf("last event = %+v, want done with the project", events[2])
+	}
+}
+
+func TestHandlerCreateStreamReportsErrorsBeforeStreaming(t *testing.T) {
+	svc := newService(mockStore{}, mockRegistry{}, nil)
+	h := NewHandler(svc, nil)
+
+	body := strings.NewReader(`{"name":"Alpha","unix_name":"Bad_Name"}`)
+	rr := httptest.NewRecorder()
+	h.Create(rr, httptest.NewRequest(http.MethodPost, "/projects?stream=true", body))
+
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d", rr.Code)
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want a regular JSON error", ct)
+	}
+}
+
+func TestHandlerListFiltersByStatus(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []ProvisionStatus
+	}{
+		{name: "no filter", query: "", want: nil},
+		{name: "single status", query: "?status=failed", want: []ProvisionStatus{StatusFailed}},
+		{name: "multiple statuses", query: "?status=failed,%20provisioning", want: []ProvisionStatus{StatusFailed, StatusProvisioning}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []ProvisionStatus
+			svc := newService(
+				mockStore{
+					listFn: func(_ context.Context, filter ListFilter) ([]*Project, error) {
+						got = filter.Statuses
+						return []*Project{{ID: "2a4e6b16-8a62-4d57-a05b-9f59248dbdb2", Status: StatusFailed}}, nil
+					},
+				},
+				mockRegistry{},
+				nil,
+			)
+			h := NewHandler(svc, nil)
+
+			rr := httptest.NewRecorder()
+			h.List(rr, httptest.NewRequest(http.MethodGet, "/projects"+tt.query, nil))
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("store filtered by %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandlerListFiltersByNode(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		wantCode     int
+		wantNode     string
+		wantStatuses []ProvisionStatus
+	}{
+		{name: "no filter", query: "", wantCode: http.StatusOK},
+		{name: "known node", query: "?node=pve-03", wantCode: http.StatusOK, wantNode: "pve-03"},
+		{name: "with status", query: "?node=pve-03&status=provisioned", wantCode: http.StatusOK,
+			wantNode: "pve-03", wantStatuses: []ProvisionStatus{StatusProvisioned}},
+		{name: "unknown node", query: "?node=pve-99", wantCode: http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var listed, counted []string
+			var statuses []ProvisionStatus
+			svc := newService(
+				mockStore{
+					listFn: func(_ context.Context, filter ListFilter) ([]*Project, error) {
+						listed = append(listed, filter.Node)
+						statuses = filter.Statuses
+						return nil, nil
+					},
+					totalFn: func(_ context.Context, filter ListFilter) (int64, error) {
+						counted = append(counted, filter.Node)
+						return 0, nil
+					},
+				},
+				mockRegistry{},
+				nil,
+				WithAllowedNodes("pve-01", "pve-03"),
+			)
+			h := NewHandler(svc, nil)
+
+			rr := httptest.NewRecorder()
+			h.List(rr, httptest.NewRequest(http.MethodGet, "/projects"+tt.query, nil))
+
+			if rr.Code != tt.wantCode {
+				t.Fatalf("expected %d, got %d: %s", tt.wantCode, rr.Code, rr.Body.String())
+			}
+			if tt.wantCode != http.StatusOK {
+				if !strings.Contains(rr.Body.String(), "INVALID_NODE") {
+					t.Errorf("expected INVALID_NODE, got %s", rr.Body.String())
+				}
+				if len(listed) != 0 {
+					t.Errorf("store was queried for unknown node %v", listed)
+				}
+				return
+			}
+			if len(listed) != 1 || listed[0] != tt.wantNode || len(counted) != 1 || counted[0] != tt.wantNode {
+				t.Errorf("store listed node %v and counted node %v, want %q", listed, counted, tt.wantNode)
+			}
+			if fmt.Sprint(statuses) != fmt.Sprint(tt.wantStatuses) {
+				t.Errorf("store filtered by %v, want %v", statuses, tt.wantStatuses)
+			}
+		})
+	}
+}
+
+func TestHandlerListEncodesNoProjectsAsEmptyPage(t *testing.T) {
+	const projectID = "2a4e6b16-8a62-4d57-a05b-9f59248dbdb2"
+	svc := newService(
+		mockStore{
+			getByID: func(_ context.Context, id string) (*Project, error) {
+				return &Project{ID: id, Labels: map[string]string{"team": "ops"}}, nil
+			},
+			relFn: func(context.Context, string, int32, int32) ([]*RelatedProject, error) {
+				return nil, nil
+			},
+		},
+		mockRegistry{},
+		nil,
+	)
+	h := NewHandler(svc, nil)
+
+	for _, target := range []string{"/", "/?fields=id,name", "/" + projectID + "/related"} {
+		t.Run(target, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			h.Routes().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, target, nil))
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+			}
+			const want = `{"data":[],"total":0,"limit":100,"offset":0,"has_next":false}`
+			if got := strings.TrimSpace(rr.Body.String()); got != want {
+				t.Errorf("body = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestHandlerListReturnsPage(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		total       int64
+		wantLimit   int32
+		wantOffset  int32
+		wantHasNext bool
+	}{
+		{name: "first page", query: "?limit=2", total: 5, wantLimit: 2, wantOffset: 0, wantHasNext: true},
+		{name: "last page", query: "?limit=2&offset=3", total: 5, wantLimit: 2, wantOffset: 3, wantHasNext: false},
+		{name: "default limit", total: 2, wantLimit: MaxListProjects, wantHasNext: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newService(
+				mockStore{
+					listFn: func(_ context.Context, filter ListFilter) ([]*Project, error) {
+						var page []*Project
+						for i := int64(filter.Offset); i < tt.total && len(page) < int(filter.Limit); i++ {
+							page = append(page, &Project{ID: fmt.Sprintf("p-%d", i), Name: "Alpha"})
+						}
+						return page, nil
+					},
+					totalFn: func(context.Context, ListFilter) (int64, error) {
+						return tt.total, nil
+					},
+				},
+				mockRegistry{},
+				nil,
+			)
+			h := NewHandler(svc, nil)
+
+			rr := httptest.NewRecorder()
+			h.List(rr, httptest.NewRequest(http.MethodGet, "/projects"+tt.query, nil))
+			if rr.Code != http.StatusOK {
+				t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+			}
+			var page platform.Page[*Project]
+			if err := json.Unmarshal(rr.Body.Bytes(), &page); err != nil {
+				t.Fatalf("failed to decode response body: %v", err)
+			}
+			if page.Total != tt.total || page.Limit != tt.wantLimit || page.Offset != tt.wantOffset || page.HasNext != tt.wantHasNext {
+				t.Errorf("page = total %d limit %d offset %d has_next %v, want %d %d %d %v",
+					page.Total, page.Limit, page.Offset, page.HasNext, tt.total, tt.wantLimit, tt.wantOffset, tt.wantHasNext)
+			}
+			if len(page.Data) == 0 || page.Data[0].Name != "Alpha" {
+				t.Errorf("unexpected projects: %v", page.Data)
+			}
+		})
+	}
+}
+
+func TestHandlerListReturns400ForNegativePagination(t *testing.T) {
+	h := NewHandler(newService(mockStore{}, mockRegistry{}, nil), nil)
+
+	rr := httptest.NewRecorder()
+	h.List(rr, httptest.NewRequest(http.MethodGet, "/projects?offset=-1", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if !strings.Contains(rr.Body.String(), "INVALID_PAGINATION") {
+		t.Errorf("expected INVALID_PAGINATION, got %s", rr.Body.String())
+	}
+}
+
+func TestHandlerListReturns400ForUnknownStatus(t *testing.T) {
+	svc := newService(
+		mockStore{
+			listFn: func(context.Context, ListFilter) ([]*Project, error) {
+				t.Fatal("store must not be queried with an unknown status")
+				return nil, nil
+			},
+		},
+		mockRegistry{},
+		nil,
+	)
+	h := NewHandler(svc, nil)
+
+	rr := httptest.NewRecorder()
+	h.List(rr, httptest.NewRequest(http.MethodGet, "/projects?status=failed,exploded", nil))
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+	var body map[string]map[string]string
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode response body: %v", err)
+	}
+	if body["error"]["code"] != "INVALID_STATUS" || !strings.Contains(body["error"]["message"], "exploded") {
+		t.Errorf("unexpected error body: %v", body)
+	}
+}
+
+func TestHandlerReprovisionReturns400ForMalformedStreamFlag(t *testing.T) {
+	h := NewHandler(newService(mockStore{}, mockRegistry{}, nil), nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/projects/p-1/reprovision?stream=maybe", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "p-1")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	rr := httptest.NewRecorder()
+	h.Reprovision(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+	if !strings.Contains(rr.Body.String(), "INVALID_QUERY_PARAM") {
+		t.Errorf("expected INVALID_QUERY_PARAM, got %s", rr.Body.String())
+	}
+}
+
+func TestHandlerReprovisionReturns422ForDisallowedNode(t *testing.T) {
+	store := mockStore{
+		getByID: func(context.Context, string) (*Project, error) {
+			return &Project{ID: "p-1", Name: "Alpha", ProvisionParams: &ProvisionParams{Node: "pve-09"}}, nil
+		},
+	}
+	h := NewHandler(newService(store, noProvisionRegistry(t), nil, WithAllowedNodes("pve-01")), nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/projects/p-1/reprovision", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "p-1")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	rr := httptest.NewRecorder()
+	h.Reprovision(rr, req)
+
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if !strings.Contains(rr.Body.String(), "INVALID_NODE") {
+		t.Errorf("expected INVALID_NODE, got %s", rr.Body.String())
+	}
+}
+
+// syncOperations runs operations to completion inside Enqueue and keeps the
+// outcome, standing in for the operations service. Its queue is always
+// empty; queueWaitFor keeps what QueueWait was last asked about.
+type syncOperations struct {
+	kind         string
+	priority     operations.Priority
+	opts         []operations.EnqueueOption
+	resource     string
+	err          error
+	queueWaitFor time.Duration
+}
+
+func (s *syncOperations) Enqueue(ctx context.Context, kind string, priority operations.Priority, fn operations.Func, opts ...operations.EnqueueOption) (*operations.Operation, error) {
+	s.kind = kind
+	s.priority = priority
+	s.opts = opts
+	s.resource, s.err = fn(ctx)
+	return &operations.Operation{ID: "op-1", Kind: kind, Status: operations.StatusPending}, nil
+}
+
+func (s *syncOperations) QueueWait(each time.Duration) time.Duration {
+	s.queueWaitFor = each
+	return 0
+}
+
+func TestHandlerCreateAsyncRunsAsOperation(t *testing.T) {
+	tests := []struct {
+		name    string
+		provErr error
+		wantErr error
+	}{
+		{name: "provisioned"},
+		{name: "provisioning failed", provErr: errors.New("cli failed"), wantErr: ErrProvisionFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newService(
+				mockStore{
+					createFn: func(context.Context, CreateProjectRequest) (*Project, error) {
+						return &Project{ID: "p-1", Name: "Alpha", Status: StatusPending}, nil
+					},
+				},
+				mockRegistry{
+					getFn: func(string) (plugin.Plugin, error) {
+						return mockPlugin{
+							provisionFn: func(context.Context, plugin.ProvisionRequest) (*plugin.ProvisionResult, error) {
+								if tt.provErr != nil {
+									return nil, tt.provErr
+								}
+								return &plugin.ProvisionResult{ResourceID: "r-1"}, nil
+							},
+						}, nil
+					},
+				},
+				nil,
+			)
+			ops := &syncOperations{}
+			h := NewHandler(svc, nil, WithOperations(ops))
+
+			rr := httptest.NewRecorder()
+			h.Create(rr, httptest.NewRequest(http.MethodPost, "/api/v1/projects?async=true",
+				strings.NewReader(`{"name":"Alpha","unix_name":"alpha"}`)))
+
+			if rr.Code != http.StatusAccepted {
+				t.Fatalf("expected 202, got %d: %s", rr.Code, rr.Body.String())
+			}
+			if got := rr.Header().Get("Location"); got != "/api/v1/operations/op-1" {
+				t.Errorf("Location = %q, want /api/v1/operations/op-1", got)
+			}
+			var op operations.Operation
+			if err := json.Unmarshal(rr.Body.Bytes(), &op); err != nil {
+				t.Fatalf("failed to decode response body: %v", err)
+			}
+			if op.ID != "op-1" || op.Kind != OperationCreate {
+				t.Errorf("unexpected operation in response: %+v", op)
+			}
+
+			if ops.resource != "projects/p-1" {
+				t.Errorf("operation produced %q, want projects/p-1", ops.resource)
+			}
+			if !errors.Is(ops.err, tt.wantErr) || (tt.wantErr == nil && ops.err != nil) {
+				t.Errorf("operation error = %v, want %v", ops.err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHandlerCreateAsyncRejectsInvalidRequestUpFront(t *testing.T) {
+	ops := &syncOperations{}
+	h := NewHandler(newService(mockStore{}, mockRegistry{}, nil), nil, WithOperations(ops))
+
+	rr := httptest.NewRecorder()
+	h.Create(rr, httptest.NewRequest(http.MethodPost, "/api/v1/projects?async=true",
+		strings.NewReader(`{"name":"A","unix_name":"bad_name"}`)))
+
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d", rr.Code)
+	}
+	if ops.kind != "" {
+		t.Error("an invalid request must not start an operation")
+	}
+}
+
+// queuedOperations accepts operations without running them, or rejects
+// them with err.
+type queuedOperations struct {
+	fn  operations.Func
+	err error
+}
+
+func (q *queuedOperations) Enqueue(_ context.Context, kind string, _ operations.Priority, fn operations.Func, _ ...operations.EnqueueOption) (*operations.Operation, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+	q.fn = fn
+	return &operations.Operation{ID: "op-1", Kind: kind, Status: operations.StatusPending}, nil
+}
+
+func (q *queuedOperations) QueueWait(time.Duration) time.Duration {
+	return 0
+}
+
+func TestHandlerCreateAsyncStoresProjectBeforeProvisioning(t *testing.T) {
+	var statuses []ProvisionStatus
+	svc := newService(
+		mockStore{
+			createFn: func(context.Context, CreateProjectRequest) (*Project, error) {
+				return &Project{ID: "p-1", Name: "Alpha", Status: StatusPending}, nil
+			},
+			statusFn: func(_ context.Context, _ string, status ProvisionStatus) error {
+				statuses = append(statuses, status)
+				return nil
+			},
+		},
+		mockRegistry{
+			getFn: func(string) (plugin.Plugin, error) {
+				return mockPlugin{}, nil
+			},
+		},
+		nil,
+	)
+	ops := &queuedOperations{}
+	h := NewHandler(svc, nil, WithOperations(ops))
+
+	rr := httptest.NewRecorder()
+	h.Create(rr, httptest.NewRequest(http.MethodPost, "/api/v1/projects",
+		strings.NewReader(`{"name":"Alpha","unix_name":"alpha"}`)))
+
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if got := rr.Header().Get("Content-Location"); got != "/api/v1/projects/p-1" {
+		t.Errorf("Content-Location = %q, want /api/v1/projects/p-1", got)
+	}
+	if len(statuses) != 0 {
+		t.Fatalf("statuses = %v before the operation ran, want the project left pending", statuses)
+	}
+
+	resource, err := ops.fn(context.Background())
+	if err != nil || resource != "projects/p-1" {
+		t.Fatalf("operation = %q, %v, want projects/p-1", resource, err)
+	}
+	if !slices.Equal(statuses, []ProvisionStatus{StatusProvisioning, StatusProvisioned}) {
+		t.Errorf("statuses = %v, want provisioning then provisioned", statuses)
+	}
+}
+
+func TestHandlerCreateProvisionsInRequestWhenAsyncIsOff(t *testing.T) {
+	svc := newService(
+		mockStore{
+			createFn: func(context.Context, CreateProjectRequest) (*Project, error) {
+				return &Project{ID: "p-1", Name: "Alpha", Status: StatusPending}, nil
+			},
+		},
+		mockRegistry{
+			getFn: func(string) (plugin.Plugin, error) {
+				return mockPlugin{}, nil
+			},
+		},
+		nil,
+	)
+	ops := &queuedOperations{}
+	h := NewHandler(svc, nil, WithOperations(ops))
+
+	rr := httptest.NewRecorder()
+	h.Create(rr, httptest.NewRequest(http.MethodPost, "/api/v1/projects?async=false",
+		strings.NewReader(`{"name":"Alpha","unix_name":"alpha"}`)))
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if ops.fn != nil {
+		t.Error("a create with async=false must not start an operation")
+	}
+}
+
+func TestHandlerCreateAsyncFailsProjectItCannotQueue(t *testing.T) {
+	var stored ProvisionStatus
+	svc := newService(
+		mockStore{
+			createFn: func(context.Context, CreateProjectRequest) (*Project, error) {
+				return &Project{ID: "p-1", Name: "Alpha", Status: StatusPending}, nil
+			},
+			statusFn: func(_ context.Context, _ string, status ProvisionStatus) error {
+				stored = status
+				return nil
+			},
+		},
+		mockRegistry{},
+		nil,
+	)
+	h := NewHandler(svc, nil, WithOperations(&queuedOperations{err: operations.ErrQueueFull}))
+
+	rr := httptest.NewRecorder()
+	h.Create(rr, httptest.NewRequest(http.MethodPost, "/api/v1/projects?async=true",
+		strings.NewReader(`{"name":"Alpha","unix_name":"alpha"}`)))
+
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if !strings.Contains(rr.Body.String(), "QUEUE_UNAVAILABLE") || rr.Header().Get("Retry-After") == "" {
+		t.Errorf("expected QUEUE_UNAVAILABLE with a Retry-After, got %s", rr.Body.String())
+	}
+	if stored != StatusFailed {
+		t.Errorf("stored status %q, want the unqueued project failed", stored)
+	}
+}
+
+func TestHandlerCreateReturns403ForDisallowedPlugin(t *testing.T) {
+	svc := newService(mockStore{}, mockRegistry{}, nil, WithAllowedPlugins("proxmox"))
+	h := NewHandler(svc, nil)
+
+	rr := httptest.NewRecorder()
+	h.Create(rr, httptest.NewRequest(http.MethodPost, "/projects",
+		strings.NewReader(`{"name":"Alpha","unix_name":"alpha","provision_params":{"plugin":"fake"}}`)))
+
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var body platform.APIError
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode response body: %v", err)
+	}
+	if body.Error.Code != "PLUGIN_NOT_ALLOWED" {
+		t.Errorf("error code = %q, want PLUGIN_NOT_ALLOWED", body.Error.Code)
+	}
+}
+
+func TestHandlerCreateReturns422ForDisallowedNode(t *testing.T) {
+	svc := newService(mockStore{}, mockRegistry{}, nil, WithAllowedNodes("pve-01", "pve-02"))
+	h := NewHandler(svc, nil)
+
+	rr := httptest.NewRecorder()
+	h.Create(rr, httptest.NewRequest(http.MethodPost, "/projects",
+		strings.NewReader(`{"name":"Alpha","unix_name":"alpha","provision_params":{"node":"pve-09"}}`)))
+
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var body platform.APIError
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode response body: %v", err)
+	}
+	if body.Error.Code != "INVALID_NODE" {
+		t.Errorf("error code = %q, want INVALID_NODE", body.Error.Code)
+	}
+}
+
+func TestHandlerCreateReturns422ForReservedNameOrUnknownTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		wantCode string
+	}{
+		{name: "reserved unix name", body: `{"name":"Alpha","unix_name":"localhost"}`, wantCode: "RESERVED_UNIX_NAME"},
+		{name: "unknown template", body: `{"name":"Alpha","unix_name":"alpha","provision_params":{"template":"ubuntu-18.04"}}`, wantCode: "UNKNOWN_TEMPLATE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			created := false
+			svc := newService(mockStore{
+				createFn: func(context.Context, CreateProjectRequest) (*Project, error) {
+					created = true
+					return &Project{ID: "p-1"}, nil
+				},
+			}, mockRegistry{}, nil, WithReservedUnixNames("localhost"), WithTemplates("debian-12"))
+			h := NewHandler(svc, nil)
+
+			rr := httptest.NewRecorder()
+			h.Create(rr, httptest.NewRequest(http.MethodPost, "/projects", strings.NewReader(tt.body)))
+
+			if rr.Code != http.StatusUnprocessableEntity {
+				t.Fatalf("expected 422, got %d: %s", rr.Code, rr.Body.String())
+			}
+			var body platform.APIError
+			if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+				t.Fatalf("failed to decode response body: %v", err)
+			}
+			if body.Error.Code != tt.wantCode {
+				t.Errorf("error code = %q, want %s", body.Error.Code, tt.wantCode)
+			}
+			if created {
+				t.Error("a refused create must not be stored")
+			}
+		})
+	}
+}
+
+func TestHandlerCreateReturns422ForRequestThePluginRefuses(t *testing.T) {
+	created := false
+	svc := newService(mockStore{
+		createFn: func(context.Context, CreateProjectRequest) (*Project, error) {
+			created = true
+			return &Project{ID: "p-1"}, nil
+		},
+	}, mockRegistry{
+		getFn: func(string) (plugin.Plugin, error) { return checkingPlugin{}, nil },
+	}, nil)
+	h := NewHandler(svc, nil)
+
+	rr := httptest.NewRecorder()
+	h.Create(rr, httptest.NewRequest(http.MethodPost, "/projects",
+		strings.NewReader(`{"name":"Alpha","unix_name":"alpha-project"}`)))
+
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var body platform.APIError
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode response body: %v", err)
+	}
+	if body.Error.Code != "PLUGIN_REJECTED" {
+		t.Errorf("error code = %q, want PLUGIN_REJECTED", body.Error.Code)
+	}
+	if created {
+		t.Error("a request the plugin refuses must not be stored")
+	}
+}
+
+func TestHandlerCreateReturns422ForMalformedSSHKey(t *testing.T) {
+	created := false
+	svc := newService(mockStore{
+		createFn: func(context.Context, CreateProjectRequest) (*Project, error) {
+			created = true
+			return &Project{ID: "p-1"}, nil
+		},
+	}, mockRegistry{}, nil)
+	h := NewHandler(svc, nil)
+
+	rr := httptest.NewRecorder()
+	h.Create(rr, httptest.NewRequest(http.MethodPost, "/projects",
+		strings.NewReader(`{"name":"Alpha","unix_name":"alpha","provision_params":{"ssh_keys":["ssh-ed25519 not-a-key"]}}`)))
+
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if !strings.Contains(rr.Body.String(), "ssh public key") {
+		t.Errorf("expected the error to name the ssh key, got %s", rr.Body.String())
+	}
+	if created {
+		t.Error("a project was created despite the malformed key")
+	}
+}
+
+func TestHandlerCreateSetsLocationHeader(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/api/v1/projects", want: "/api/v1/projects/p-1"},
+		{path: "/api/v1/projects/", want: "/api/v1/projects/p-1"},
+		{path: "/quokka/api/v1/projects", want: "/quokka/api/v1/projects/p-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			svc := newService(
+				mockStore{
+					createFn: func(_ context.Context, req CreateProjectRequest) (*Project, error) {
+						return &Project{ID: "p-1", Name: req.Name, UnixName: req.UnixName}, nil
+					},
+				},
+				mockRegistry{
+					getFn: func(string) (plugin.Plugin, error) {
+						return nil, plugin.ErrPluginNotFound
+					},
+				},
+				nil,
+			)
+			h := NewHandler(svc, nil)
+
+			rr := httptest.NewRecorder()
+			h.Create(rr, httptest.NewRequest(http.MethodPost, tt.path,
+				strings.NewReader(`{"name":"Alpha","unix_name":"alpha"}`)))
+
+			if rr.Code != http.StatusCreated {
+				t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+			}
+			if got := rr.Header().Get("Location"); got != tt.want {
+				t.Errorf("Location = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandlerUpdateReturns409WhenDeactivatingLiveResources(t *testing.T) {
+	svc := newService(
+		mockStore{
+			getByID: func(context.Context, string) (*Project, error) {
+				return &Project{ID: "p-1", Active: true, Status: StatusProvisioned}, nil
+			},
+			updateFn: func(_ context.Context, id string, req UpdateProjectRequest) (*Project, error) {
+				return &Project{ID: id, Active: *req.Active, Status: StatusProvisioned}, nil
+			},
+		},
+		mockRegistry{},
+		nil,
+	)
+	h := NewHandler(svc, nil)
+
+	newRequest := func(target string) *http.Request {
+		req := httptest.NewRequest(http.MethodPatch, target, strings.NewReader(`{"active":false}`))
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", "p-1")
+		return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	}
+
+	rr := httptest.NewRecorder()
+	h.Update(rr, newRequest("/projects/p-1"))
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if !strings.Contains(rr.Body.String(), "HAS_ACTIVE_RESOURCES") {
+		t.Errorf("expected HAS_ACTIVE_RESOURCES, got %s", rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	h.Update(rr, newRequest("/projects/p-1?force=true"))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected forced update to succeed, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestHandlerSparseFieldsets(t *testing.T) {
+	project := &Project{ID: "2a4e6b16-8a62-4d57-a05b-9f59248dbdb2", Name: "Alpha", UnixName: "alpha"}
+	svc := newService(
+		mockStore{
+			getByID: func(context.Context, string) (*Project, error) {
+				return project, nil
+			},
+			listFn: func(context.Context, ListFilter) ([]*Project, error) {
+				return []*Project{project}, nil
+			},
+		},
+		mockRegistry{},
+		nil,
+	)
+	h := NewHandler(svc, nil)
+
+	t.Run("list subset", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		h.List(rr, httptest.NewRequest(http.MethodGet, "/projects?fields=id,name", nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var page platform.Page[map[string]any]
+		if err := json.Unmarshal(rr.Body.Bytes(), &page); err != nil {
+			t.Fatalf("failed to decode response body: %v", err)
+		}
+		body := page.Data
+		want := map[string]any{"id": project.ID, "name": "Alpha"}
+		if len(body) != 1 || len(body[0]) != len(want) || body[0]["id"] != want["id"] || body[0]["name"] != want["name"] {
+			t.Errorf("unexpected projects: %v", body)
+		}
+	})
+
+	t.Run("get all fields", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		h.GetByID(rr, newGetRequestWithID(project.ID))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var body map[string]any
+		if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+			t.Fatalf("failed to decode response body: %v", err)
+		}
+		for _, field := range []string{"id", "name", "unix_name", "active", "status", "created_at"} {
+			if _, ok := body[field]; !ok {
+				t.Errorf("expected field %q in full response: %v", field, body)
+			}
+		}
+	})
+
+	t.Run("get unknown field", func(t *testing.T) {
+		req := newGetRequestWithID(project.ID)
+		req.URL.RawQuery = "fields=id,owner"
+		rr := httptest.NewRecorder()
+		h.GetByID(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400, got %d: %s", rr.Code, rr.Body.String())
+		}
+		if !strings.Contains(rr.Body.String(), "owner") {
+			t.Errorf("expected the unknown field to be named: %s", rr.Body.String())
+		}
+	})
+}
+
+func TestHandlerGetByIDReturns503WhenPoolExhausted(t *testing.T) {
+	svc := newService(
+		mockStore{
+			getByID: func(context.Context, string) (*Project, error) {
+				return nil, platform.ClassifyAcquireError(context.DeadlineExceeded)
+			},
+		},
+		mockRegistry{},
+		nil,
+	)
+	h := NewHandler(svc, nil)
+
+	rr := httptest.NewRecorder()
+	h.GetByID(rr, newGetRequestWithID("p-1"))
+
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", rr.Code)
+	}
+	if rr.Header().Get("Retry-After") == "" {
+		t.Error("expected a Retry-After header")
+	}
+	if !strings.Contains(rr.Body.String(), "SERVICE_UNAVAILABLE") {
+		t.Errorf("expected SERVICE_UNAVAILABLE, got %s", rr.Body.String())
+	}
+}
+
+func TestHandlerCreateReturns409ForTakenName(t *testing.T) {
+	svc := newService(
+		mockStore{
+			nameFn: func(context.Context, string, string) (bool, error) {
+				return true, nil
+			},
+		},
+		mockRegistry{},
+		nil,
+		WithUniqueNames(true),
+	)
+	h := NewHandler(svc, nil)
+
+	body := `{"name":"Billing","unix_name":"billing-2"}`
+	rr := httptest.NewRecorder()
+	h.Routes().ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d", rr.Code)
+	}
+	if !strings.Contains(rr.Body.String(), "NAME_EXISTS") {
+		t.Errorf("expected NAME_EXISTS, got %s", rr.Body.String())
+	}
+}
+
+func TestHandlerRelated(t *testing.T) {
+	const id = "2b7f0c1e-93d4-4c55-a1b8-6e0f7d2c9a41"
+	tests := []struct {
+		name     string
+		query    string
+		getErr   error
+		wantCode int
+	}{
+		{name: "page", query: "?limit=5&offset=5", wantCode: http.StatusOK},
+		{name: "negative offset", query: "?offset=-1", wantCode: http.StatusBadRequest},
+		{name: "malformed limit", query: "?limit=ten", wantCode: http.StatusBadRequest},
+		{name: "unknown project", getErr: pgx.ErrNoRows, wantCode: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotLimit, gotOffset int32
+			svc := newService(
+				mockStore{
+					getByID: func(context.Context, string) (*Project, error) {
+						if tt.getErr != nil {
+							return nil, tt.getErr
+						}
+						return &Project{ID: id, Labels: map[string]string{"team": "web"}}, nil
+					},
+					relFn: func(_ context.Context, _ string, limit, offset int32) ([]*RelatedProject, error) {
+						gotLimit, gotOffset = limit, offset
+						return []*RelatedProject{{Project: &Project{ID: "other"}, SharedLabels: 1}}, nil
+					},
+				},
+				mockRegistry{},
+				nil,
+			)
+			h := NewHandler(svc, nil)
+
+			req := newGetRequestWithID(id)
+			req.URL.RawQuery = strings.TrimPrefix(tt.query, "?")
+			rr := httptest.NewRecorder()
+			h.Related(rr, req)
+
+			if rr.Code != tt.wantCode {
+				t.Fatalf("expected %d, got %d: %s", tt.wantCode, rr.Code, rr.Body.String())
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			if gotLimit != 5 || gotOffset != 5 {
+				t.Errorf("page = limit %d offset %d, want 5 and 5", gotLimit, gotOffset)
+			}
+			var page platform.Page[map[string]any]
+			if err := json.Unmarshal(rr.Body.Bytes(), &page); err != nil {
+				t.Fatalf("failed to decode response body: %v", err)
+			}
+			body := page.Data
+			if len(body) != 1 || body[0]["id"] != "other" || body[0]["shared_labels"] != float64(1) {
+				t.Errorf("unexpected body %v", body)
+			}
+			if page.Limit != 5 || page.Offset != 5 {
+				t.Errorf("page echoes limit %d offset %d, want 5 and 5", page.Limit, page.Offset)
+			}
+		})
+	}
+}
+
+func TestHandlerRejectsMalformedProjectIDOnEveryEndpoint(t *testing.T) {
+	// The store would accept any ID: only the handler guard can reject it.
+	store := mockStore{
+		getByID: func(_ context.Context, id string) (*Project, error) {
+			return &Project{ID: id, Labels: map[string]string{"team": "web"}}, nil
+		},
+	}
+	h := NewHandler(newService(store, mockRegistry{}, nil), nil, WithEventStream(NewBroadcaster()))
+
+	routes := []struct{ method, path, body string }{
+		{method: http.MethodGet, path: "/%s"},
+		{method: http.MethodPut, path: "/%s", body: `{"name":"renamed"}`},
+		{method: http.MethodDelete, path: "/%s"},
+		{method: http.MethodPost, path: "/%s/reprovision"},
+		{method: http.MethodPost, path: "/%s/cancel"},
+		{method: http.MethodPost, path: "/%s/transfer", body: `{"org_id":"6f2d9b81-4e7a-4c3d-a1b5-8c0e2f4a7d96"}`},
+		{method: http.MethodGet, path: "/%s/events"},
+		{method: http.MethodGet, path: "/%s/related"},
+		{method: http.MethodGet, path: "/%s/status"},
+	}
+	for _, id := range []string{"not-a-uuid", "00000000-0000-0000-0000-000000000000"} {
+		for _, route := range routes {
+			target := fmt.Sprintf(route.path, id)
+			t.Run(route.method+" "+target, func(t *testing.T) {
+				rr := httptest.NewRecorder()
+				h.Routes().ServeHTTP(rr, httptest.NewRequest(route.method, target, strings.NewReader(route.body)))
+
+				if rr.Code != http.StatusBadRequest {
+					t.Fatalf("expected 400, got %d: %s", rr.Code, rr.Body.String())
+				}
+				var body platform.APIError
+				if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+					t.Fatalf("failed to decode response body: %v", err)
+				}
+				if body.Error.Code != "INVALID_PROJECT_ID" {
+					t.Errorf("error code = %q, want INVALID_PROJECT_ID", body.Error.Code)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package projects
+
+import (
+	"context"
+	"errors"
+	"maps"
+
+	"github.com/searge/quokka/internal/orgs"
+)
+
+// ErrOrgLabelsDisabled is returned by SyncOrgLabels when the service has
+// no source of organization labels.
+var ErrOrgLabelsDisabled = errors.New("organization labels are not enabled")
+
+// orgLabelSource reports the default labels of an organization and keeps
+// track of which of them its existing projects were last synced to.
+type orgLabelSource interface {
+	DefaultLabels(ctx context.Context, orgID string) (labels, synced map[string]string, err error)
+	MarkLabelsSynced(ctx context.Context, orgID string, labels map[string]string) error
+}
+
+// WithOrgLabels makes projects inherit their organization's default labels
+// from src on create. A label the client sets wins over the organization's;
+// the organization's win over the deployment's defaulters.
+func WithOrgLabels(src orgLabelSource) ServiceOption {
+	return func(s *Service) {
+		s.orgLabels = src
+	}
+}
+
+// SyncOrgLabelsRequest names the organization whose projects to sync.
+type SyncOrgLabelsRequest struct {
+	OrgID string `json:"org_id" validate:"required,uuid"`
+}
+
+// inheritOrgLabels returns labels with the default labels of organization
+// orgID added under every key the client did not send in sent. labels is
+// copied first, never written to.
+func (s *Service) inheritOrgLabels(ctx context.Context, orgID string, labels, sent map[string]string) (map[string]string, error) {
+	if s.orgLabels == nil || orgID == "" {
+		return labels, nil
+	}
+	defaults, _, err := s.orgLabels.DefaultLabels(ctx, orgID)
+	if err != nil {
+		return nil, orgLookupError(err)
+	}
+	if len(defaults) == 0 {
+		return labels, nil
+	}
+
+	labels = maps.Clone(labels)
+	if labels == nil {
+		labels = make(map[string]string, len(defaults))
+	}
+	for k, v := range defaults {
+		if _, ok := sent[k]; !ok {
+			labels[k] = v
+		}
+	}
+	return labels, nil
+}
+
+// SyncOrgLabels brings the labels of an organization's live projects in
+// line with its current default labels, after they changed. A project label
+// still holding the default it was last synced to counts as inherited: it
+// takes the new default, or is dropped if the default was. Any other label
+// is the project's own and is kept, winning over the defaults. Reports how
+// many projects changed, publishing project.labels_changed for each.
+func (s *Service) SyncOrgLabels(ctx context.Context, req SyncOrgLabelsRequest) (*BulkLabelResult, error) {
+	if s.orgLabels == nil {
+		return nil, ErrOrgLabelsDisabled
+	}
+	if err := s.validate.Struct(req); err != nil {
+		return nil, err
+	}
+	labels, synced, err := s.orgLabels.DefaultLabels(ctx, req.OrgID)
+	if err != nil {
+		return nil, orgLookupError(err)
+	}
+
+	var changed []string
+	err = s.commit(ctx, "", func(store projectStore) ([]Event, error) {
+		var err error
+		changed, err = store.SyncOrgLabels(ctx, req.OrgID, synced, labels)
+		return labelEvents(changed), err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.orgLabels.MarkLabelsSynced(ctx, req.OrgID, labels); err != nil {
+		return nil, orgLookupError(err)
+	}
+	return &BulkLabelResult{Affected: int64(len(changed))}, nil
+}
+
+// orgLookupError maps an organization that does not exist to
+// ErrUnknownOrganization.
+func orgLookupError(err error) error {
+	if errors.Is(err, orgs.ErrOrganizationNotFound) || errors.Is(err, orgs.ErrInvalidOrganizationID) {
+		return ErrUnknownOrganization
+	}
+	return err
+}
@@ -0,0 +1,208 @@
+package projects
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/searge/quokka/internal/orgs"
+	"github.com/searge/quokka/internal/plugin"
+)
+
+// memoryOrgLabels holds the default labels of one organization, testOrgID.
+type memoryOrgLabels struct {
+	labels map[string]string
+	synced map[string]string
+}
+
+func (m *memoryOrgLabels) DefaultLabels(_ context.Context, orgID string) (map[string]string, map[string]string, error) {
+	if orgID != testOrgID {
+		return nil, nil, orgs.ErrOrganizationNotFound
+	}
+	return m.labels, m.synced, nil
+}
+
+func (m *memoryOrgLabels) MarkLabelsSynced(_ context.Context, orgID string, labels map[string]string) error {
+	if orgID != testOrgID {
+		return orgs.ErrOrganizationNotFound
+	}
+	m.synced = labels
+	return nil
+}
+
+func TestServiceCreateInheritsOrgLabels(t *testing.T) {
+	src := &memoryOrgLabels{labels: map[string]string{"cost-center": "42", "env": "prod", "team": "billing"}}
+
+	tests := []struct {
+		name       string
+		orgID      string
+		labels     map[string]string
+		wantLabels map[string]string
+	}{
+		{
+			name:       "inherited",
+			orgID:      testOrgID,
+			wantLabels: map[string]string{"cost-center": "42", "env": "prod", "team": "billing", "tier": "web"},
+		},
+		{
+			name:       "project labels win",
+			orgID:      testOrgID,
+			labels:     map[string]string{"env": "dev", "app": "api"},
+			wantLabels: map[string]string{"cost-center": "42", "env": "dev", "team": "billing", "tier": "web", "app": "api"},
+		},
+		{
+			name:       "no organization",
+			wantLabels: map[string]string{"team": "platform", "tier": "web"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stored CreateProjectRequest
+			s := newService(
+				mockStore{
+					createFn: func(_ context.Context, req CreateProjectRequest) (*Project, error) {
+						stored = req
+						return &Project{ID: "p-1", Name: req.Name, Labels: req.Labels}, nil
+					},
+				},
+				mockRegistry{
+					getFn: func(string) (plugin.Plugin, error) {
+						return mockPlugin{}, nil
+					},
+				},
+				nil,
+				WithOrgLabels(src),
+				// The organization's labels win over the deployment's
+				WithDefaulters(DefaultLabels(map[string]string{"team": "platform", "tier": "web"})),
+			)
+			sent := maps.Clone(tt.labels)
+
+			_, err := s.Create(context.Background(), CreateProjectRequest{
+				Name: "Alpha", UnixName: "alpha", OrgID: tt.orgID, Labels: tt.labels,
+			})
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if !maps.Equal(stored.Labels, tt.wantLabels) {
+				t.Errorf("stored labels = %v, want %v", stored.Labels, tt.wantLabels)
+			}
+			if !maps.Equal(tt.labels, sent) {
+				t.Errorf("caller's labels changed to %v", tt.labels)
+			}
+		})
+	}
+}
+
+func TestServiceCreateRejectsUnknownOrgForLabels(t *testing.T) {
+	s := newService(mockStore{}, mockRegistry{}, nil, WithOrgLabels(&memoryOrgLabels{}))
+
+	_, err := s.Create(context.Background(), CreateProjectRequest{
+		Name: "Alpha", UnixName: "alpha", OrgID: "9c3b2a10-0000-4000-8000-000000000001",
+	})
+	if !errors.Is(err, ErrUnknownOrganization) {
+		t.Fatalf("expected ErrUnknownOrganization, got %v", err)
+	}
+}
+
+func TestServiceSyncOrgLabels(t *testing.T) {
+	src := &memoryOrgLabels{
+		labels: map[string]string{"env": "prod", "cost-center": "42"},
+		synced: map[string]string{"env": "dev", "owner": "ops"},
+	}
+	var gotOrg string
+	var gotPrevious, gotCurrent map[string]string
+	s := newService(
+		mockStore{
+			syncFn: func(_ context.Context, orgID string, previous, current map[string]string) ([]string, error) {
+				gotOrg, gotPrevious, gotCurrent = orgID, previous, current
+				return []string{"p-1", "p-2", "p-3"}, nil
+			},
+		},
+		mockRegistry{},
+		nil,
+		WithOrgLabels(src),
+	)
+
+	result, err := s.SyncOrgLabels(context.Background(), SyncOrgLabelsRequest{OrgID: testOrgID})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result.Affected != 3 {
+		t.Errorf("affected = %d, want 3", result.Affected)
+	}
+	if gotOrg != testOrgID || !maps.Equal(gotPrevious, map[string]string{"env": "dev", "owner": "ops"}) ||
+		!maps.Equal(gotCurrent, map[string]string{"env": "prod", "cost-center": "42"}) {
+		t.Errorf("store synced %s from %v to %v", gotOrg, gotPrevious, gotCurrent)
+	}
+	if !maps.Equal(src.synced, src.labels) {
+		t.Errorf("synced labels = %v, want the current defaults %v", src.synced, src.labels)
+	}
+}
+
+func TestServiceSyncOrgLabelsLeavesSyncedOnFailure(t *testing.T) {
+	src := &memoryOrgLabels{
+		labels: map[string]string{"env": "prod"},
+		synced: map[string]string{"env": "dev"},
+	}
+	s := newService(
+		mockStore{
+			syncFn: func(context.Context, string, map[string]string, map[string]string) ([]string, error) {
+				return nil, errors.New("connection reset")
+			},
+		},
+		mockRegistry{},
+		nil,
+		WithOrgLabels(src),
+	)
+
+	if _, err := s.SyncOrgLabels(context.Background(), SyncOrgLabelsRequest{OrgID: testOrgID}); err == nil {
+		t.Fatal("expected the store error")
+	}
+	if !maps.Equal(src.synced, map[string]string{"env": "dev"}) {
+		t.Errorf("synced labels = %v, want them unchanged so the sync can be retried", src.synced)
+	}
+}
+
+func TestHandlerSyncOrgLabels(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      orgLabelSource
+		body     string
+		wantCode int
+	}{
+		{name: "synced", src: &memoryOrgLabels{}, body: `{"org_id":"` + testOrgID + `"}`, wantCode: http.StatusOK},
+		{name: "unknown organization", src: &memoryOrgLabels{}, body: `{"org_id":"9c3b2a10-0000-4000-8000-000000000001"}`, wantCode: http.StatusUnprocessableEntity},
+		{name: "missing organization", src: &memoryOrgLabels{}, body: `{}`, wantCode: http.StatusUnprocessableEntity},
+		{name: "disabled", body: `{"org_id":"` + testOrgID + `"}`, wantCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []ServiceOption
+			if tt.src != nil {
+				opts = append(opts, WithOrgLabels(tt.src))
+			}
+			svc := newService(
+				mockStore{
+					syncFn: func(context.Context, string, map[string]string, map[string]string) ([]string, error) {
+						return []string{"p-1"}, nil
+					},
+				},
+				mockRegistry{},
+				nil,
+				opts...,
+			)
+			h := NewHandler(svc, nil)
+
+			rr := httptest.NewRecorder()
+			h.Routes().ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/labels/sync", strings.NewReader(tt.body)))
+
+			if rr.Code != tt.wantCode {
+				t.Fatalf("expected %d, got %d: %s", tt.wantCode, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
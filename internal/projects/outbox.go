@@ -0,0 +1,150 @@
+package projects
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// OutboxEvent is an event waiting in the outbox to be published.
+type OutboxEvent struct {
+	ID    int64
+	Event Event
+	// Attempts counts the failed attempts to publish it so far.
+	Attempts int
+}
+
+const (
+	// relayBatchSize caps how many events one RelayOnce call publishes.
+	relayBatchSize = 100
+	// relayLease is how long a claimed event is left to one relay before
+	// another may claim it again.
+	relayLease = 30 * time.Second
+	// maxRelayRetryDelay caps the backoff between attempts at an event.
+	maxRelayRetryDelay = 5 * time.Minute
+	// relayPruneInterval is how often Run deletes sent events past their
+	// retention.
+	relayPruneInterval = time.Hour
+)
+
+// Relay publishes the events the Service wrote to the outbox, oldest due
+// first, marking each one sent once its publisher accepted it. Events
+// whose publishing fails are retried with exponential backoff, so they may
+// go out after events written later. An event may be published more than
+// once, but never lost: one claimed by a relay that died before marking it
+// is claimed again once its lease runs out.
+type Relay struct {
+	store     relayStore
+	publisher EventPublisher
+	log       *slog.Logger
+	now       func() time.Time
+	retention time.Duration
+}
+
+type relayStore interface {
+	ClaimEvents(ctx context.Context, now, leaseUntil time.Time, limit int32) ([]OutboxEvent, error)
+	MarkEventSent(ctx context.Context, id int64, sentAt time.Time) error
+	MarkEventFailed(ctx context.Context, id int64, retryAt time.Time, cause error) error
+	DeleteSentEvents(ctx context.Context, before time.Time) (int64, error)
+}
+
+// RelayOption configures optional Relay behaviour.
+type RelayOption func(*Relay)
+
+// WithSentRetention makes Run delete events sent more than retention ago.
+// Zero, the default, keeps sent events forever.
+func WithSentRetention(retention time.Duration) RelayOption {
+	return func(r *Relay) {
+		r.retention = retention
+	}
+}
+
+// NewRelay creates a Relay delivering the outbox of store to publisher.
+func NewRelay(store *Store, publisher EventPublisher, logger *slog.Logger, opts ...RelayOption) *Relay {
+	return newRelay(store, publisher, logger, opts...)
+}
+
+func newRelay(store relayStore, publisher EventPublisher, logger *slog.Logger, opts ...RelayOption) *Relay {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	r := &Relay{store: store, publisher: publisher, log: logger, now: time.Now}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// RelayOnce publishes the events currently due, up to a batch of them, and
+// returns how many were delivered. A failure to publish one event is
+// recorded for retry rather than returned.
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	now := r.now()
+	claimed, err := r.store.ClaimEvents(ctx, now, now.Add(relayLease), relayBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, pending := range claimed {
+		if err := r.publisher.Publish(ctx, pending.Event); err != nil {
+			retryAt := r.now().Add(relayRetryDelay(pending.Attempts))
+			r.log.Warn("failed to publish project event",
+				"type", pending.Event.Type, "project_id", pending.Event.ProjectID,
+				"attempts", pending.Attempts+1, "retry_at", retryAt, "error", err)
+			if err := r.store.MarkEventFailed(ctx, pending.ID, retryAt, err); err != nil {
+				return delivered, err
+			}
+			continue
+		}
+		if err := r.store.MarkEventSent(ctx, pending.ID, r.now()); err != nil {
+			return delivered, err
+		}
+		delivered++
+	}
+	return delivered, nil
+}
+
+// PruneOnce deletes the events sent more than the retention ago and
+// returns how many it deleted. Without a retention it deletes nothing.
+func (r *Relay) PruneOnce(ctx context.Context) (int64, error) {
+	if r.retention <= 0 {
+		return 0, nil
+	}
+	return r.store.DeleteSentEvents(ctx, r.now().Add(-r.retention))
+}
+
+// Run relays every interval until ctx is done, pruning sent events every
+// relayPruneInterval. Failed runs are logged and retried on the next tick.
+func (r *Relay) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var pruned time.Time
+	for {
+		if _, err := r.RelayOnce(ctx); err != nil && ctx.Err() == nil {
+			r.log.Error("failed to relay project events", "error", err)
+		}
+		if now := r.now(); now.Sub(pruned) >= relayPruneInterval {
+			pruned = now
+			if _, err := r.PruneOnce(ctx); err != nil && ctx.Err() == nil {
+				r.log.Error("failed to prune sent project events", "error", err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// relayRetryDelay is how long to wait before publishing an event again
+// once it failed, attempts being how many attempts had failed before: one
+// second, doubling each time up to maxRelayRetryDelay. Pure function.
+func relayRetryDelay(attempts int) time.Duration {
+	if attempts >= 9 {
+		return maxRelayRetryDelay
+	}
+	return min(time.Second<<attempts, maxRelayRetryDelay)
+}
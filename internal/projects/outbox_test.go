@@ -0,0 +1,350 @@
+package projects
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/searge/quokka/internal/platform"
+	"github.com/searge/quokka/internal/plugin"
+)
+
+// outboxRow is an event in a memoryOutbox with its delivery state.
+type outboxRow struct {
+	OutboxEvent
+	due     time.Time
+	sent    bool
+	sentAt  time.Time
+	deleted bool
+	lastErr string
+}
+
+// memoryOutbox keeps outbox events in memory, leasing them like the
+// events_outbox queries do.
+type memoryOutbox struct {
+	mu   sync.Mutex
+	rows []*outboxRow
+}
+
+func (m *memoryOutbox) add(due time.Time, events ...Event) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, event := range events {
+		m.rows = append(m.rows, &outboxRow{
+			OutboxEvent: OutboxEvent{ID: int64(len(m.rows) + 1), Event: event},
+			due:         due,
+		})
+	}
+}
+
+func (m *memoryOutbox) ClaimEvents(_ context.Context, now, leaseUntil time.Time, limit int32) ([]OutboxEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var claimed []OutboxEvent
+	for _, row := range m.rows {
+		if len(claimed) == int(limit) {
+			break
+		}
+		if row.sent || row.deleted || row.due.After(now) {
+			continue
+		}
+		row.due = leaseUntil
+		claimed = append(claimed, row.OutboxEvent)
+	}
+	return claimed, nil
+}
+
+func (m *memoryOutbox) MarkEventSent(_ context.Context, id int64, sentAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rows[id-1].sent = true
+	m.rows[id-1].sentAt = sentAt
+	return nil
+}
+
+func (m *memoryOutbox) DeleteSentEvents(_ context.Context, before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	for _, row := range m.rows {
+		if row.sent && !row.deleted && row.sentAt.Before(before) {
+			row.deleted = true
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *memoryOutbox) MarkEventFailed(_ context.Context, id int64, retryAt time.Time, cause error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	row := m.rows[id-1]
+	row.Attempts++
+	row.due = retryAt
+	row.lastErr = cause.Error()
+	return nil
+}
+
+func (m *memoryOutbox) pending() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	for _, row := range m.rows {
+		if !row.sent {
+			n++
+		}
+	}
+	return n
+}
+
+// flakyPublisher fails the first failures calls, then records events.
+type flakyPublisher struct {
+	recordingPublisher
+	failures int
+}
+
+func (f *flakyPublisher) Publish(ctx context.Context, event Event) error {
+	if f.failures > 0 {
+		f.failures--
+		return errors.New("broker unavailable")
+	}
+	return f.recordingPublisher.Publish(ctx, event)
+}
+
+func TestRelayDeliversEventsInOrder(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := &memoryOutbox{}
+	store.add(now,
+		Event{Type: EventCreated, ProjectID: "p-1"},
+		Event{Type: EventProvisioned, ProjectID: "p-1"},
+	)
+	events := &recordingPublisher{}
+	r := newRelay(store, events, nil)
+	r.now = func() time.Time { return now }
+
+	n, err := r.RelayOnce(context.Background())
+	if err != nil {
+		t.Fatalf("RelayOnce() error = %v", err)
+	}
+	if want := []string{EventCreated, EventProvisioned}; n != 2 || !slices.Equal(events.types(), want) {
+		t.Fatalf("delivered %d events %v, want %v", n, events.types(), want)
+	}
+	if store.pending() != 0 {
+		t.Errorf("%d events left unsent", store.pending())
+	}
+
+	// Sent events are never delivered again.
+	if n, err := r.RelayOnce(context.Background()); err != nil || n != 0 {
+		t.Errorf("second RelayOnce() = %d, %v; want 0, nil", n, err)
+	}
+}
+
+func TestRelayRetriesFailedEventsWithBackoff(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := &memoryOutbox{}
+	store.add(now, Event{Type: EventProvisioned, ProjectID: "p-1"})
+	events := &flakyPublisher{failures: 2}
+	r := newRelay(store, events, nil)
+	r.now = func() time.Time { return now }
+
+	for attempt, wait := range []time.Duration{time.Second, 2 * time.Second} {
+		n, err := r.RelayOnce(context.Background())
+		if err != nil || n != 0 {
+			t.Fatalf("attempt %d: RelayOnce() = %d, %v; want 0, nil", attempt+1, n, err)
+		}
+		row := store.rows[0]
+		if row.Attempts != attempt+1 || !row.due.Equal(now.Add(wait)) || row.lastErr != "broker unavailable" {
+			t.Fatalf("attempt %d: row = %+v, want attempt recorded and retry after %s", attempt+1, row, wait)
+		}
+
+		// Nothing is retried before it is due.
+		if n, _ := r.RelayOnce(context.Background()); n != 0 {
+			t.Fatalf("attempt %d: event retried before its backoff elapsed", attempt+1)
+		}
+		now = now.Add(wait)
+	}
+
+	n, err := r.RelayOnce(context.Background())
+	if err != nil || n != 1 {
+		t.Fatalf("RelayOnce() = %d, %v; want 1, nil", n, err)
+	}
+	if len(events.events) != 1 || store.pending() != 0 {
+		t.Errorf("published %v with %d pending, want the event delivered once", events.types(), store.pending())
+	}
+}
+
+func TestRelayRedeliversEventsOfACrashedRelay(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := &memoryOutbox{}
+	store.add(now, Event{Type: EventTransferred, ProjectID: "p-1"})
+
+	// A relay claims the event, then dies before marking it.
+	if _, err := store.ClaimEvents(context.Background(), now, now.Add(relayLease), relayBatchSize); err != nil {
+		t.Fatalf("ClaimEvents() error = %v", err)
+	}
+
+	events := &recordingPublisher{}
+	r := newRelay(store, events, nil)
+	r.now = func() time.Time { return now.Add(relayLease / 2) }
+	if n, _ := r.RelayOnce(context.Background()); n != 0 {
+		t.Fatal("event delivered while another relay held its lease")
+	}
+
+	r.now = func() time.Time { return now.Add(relayLease) }
+	n, err := r.RelayOnce(context.Background())
+	if err != nil || n != 1 {
+		t.Fatalf("RelayOnce() = %d, %v; want 1, nil", n, err)
+	}
+	if got := events.types(); !slices.Equal(got, []string{EventTransferred}) {
+		t.Errorf("published %v, want the orphaned event", got)
+	}
+}
+
+func TestRelayPrunesSentEventsPastRetention(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := &memoryOutbox{}
+	store.add(now, Event{Type: EventCreated, ProjectID: "p-1"})
+	r := newRelay(store, &recordingPublisher{}, nil, WithSentRetention(24*time.Hour))
+	r.now = func() time.Time { return now }
+	if _, err := r.RelayOnce(context.Background()); err != nil {
+		t.Fatalf("RelayOnce() error = %v", err)
+	}
+	store.add(now, Event{Type: EventProvisioned, ProjectID: "p-1"})
+
+	r.now = func() time.Time { return now.Add(24 * time.Hour) }
+	if n, err := r.PruneOnce(context.Background()); err != nil || n != 0 {
+		t.Fatalf("PruneOnce() at retention = %d, %v; want 0, nil", n, err)
+	}
+	r.now = func() time.Time { return now.Add(25 * time.Hour) }
+	if n, err := r.PruneOnce(context.Background()); err != nil || n != 1 {
+		t.Fatalf("PruneOnce() past retention = %d, %v; want 1, nil", n, err)
+	}
+	if !store.rows[0].deleted || store.rows[1].deleted {
+		t.Errorf("rows = %+v, %+v; want only the sent event deleted", store.rows[0], store.rows[1])
+	}
+}
+
+func TestRelayWithoutRetentionKeepsSentEvents(t *testing.T) {
+	store := &memoryOutbox{}
+	store.add(time.Now(), Event{Type: EventCreated, ProjectID: "p-1"})
+	r := newRelay(store, &recordingPublisher{}, nil)
+	if _, err := r.RelayOnce(context.Background()); err != nil {
+		t.Fatalf("RelayOnce() error = %v", err)
+	}
+
+	r.now = func() time.Time { return time.Now().Add(365 * 24 * time.Hour) }
+	if n, err := r.PruneOnce(context.Background()); err != nil || n != 0 {
+		t.Errorf("PruneOnce() = %d, %v; want 0, nil", n, err)
+	}
+}
+
+func TestRelayRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 0, want: time.Second},
+		{attempts: 1, want: 2 * time.Second},
+		{attempts: 4, want: 16 * time.Second},
+		{attempts: 8, want: 256 * time.Second},
+		{attempts: 9, want: maxRelayRetryDelay},
+		{attempts: 100, want: maxRelayRetryDelay},
+	}
+	for _, tt := range tests {
+		if got := relayRetryDelay(tt.attempts); got != tt.want {
+			t.Errorf("relayRetryDelay(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestRelayRunStopsWithContext(t *testing.T) {
+	r := newRelay(&memoryOutbox{}, &recordingPublisher{}, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		r.Run(ctx, time.Millisecond)
+		close(done)
+	}()
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after the context was cancelled")
+	}
+}
+
+func TestServiceWritesEventsToOutbox(t *testing.T) {
+	now := time.Now()
+	outbox := &memoryOutbox{}
+	var statuses []ProvisionStatus
+	store := mockStore{
+		createFn: func(_ context.Context, req CreateProjectRequest) (*Project, error) {
+			return &Project{ID: "p-1", Name: req.Name, UnixName: req.UnixName, ProvisionParams: req.ProvisionParams}, nil
+		},
+		statusFn: func(_ context.Context, _ string, status ProvisionStatus) error {
+			statuses = append(statuses, status)
+			return nil
+		},
+		enqFn: func(_ context.Context, events []Event) error {
+			outbox.add(now, events...)
+			return nil
+		},
+	}
+	direct := &recordingPublisher{}
+	registry := mockRegistry{getFn: func(string) (plugin.Plugin, error) {
+		return mockPlugin{provisionFn: func(context.Context, plugin.ProvisionRequest) (*plugin.ProvisionResult, error) {
+			return &plugin.ProvisionResult{}, nil
+		}}, nil
+	}}
+	s := newService(store, registry, nil, WithEventPublisher(direct), WithEventOutbox(true))
+
+	if _, err := s.Create(context.Background(), CreateProjectRequest{Name: "Alpha", UnixName: "alpha"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if got := direct.types(); len(got) != 0 {
+		t.Fatalf("published %v directly, want every event left to the relay", got)
+	}
+	if want := []ProvisionStatus{StatusProvisioning, StatusProvisioned}; !slices.Equal(statuses, want) {
+		t.Errorf("statuses = %v, want %v", statuses, want)
+	}
+
+	// Had the process died here, the relay would still find both events.
+	relayed := &recordingPublisher{}
+	if _, err := newRelay(outbox, relayed, nil).RelayOnce(context.Background()); err != nil {
+		t.Fatalf("RelayOnce() error = %v", err)
+	}
+	if want := []string{EventCreated, EventProvisioned}; !slices.Equal(relayed.types(), want) {
+		t.Errorf("relayed %v, want %v", relayed.types(), want)
+	}
+	if last := relayed.events[len(relayed.events)-1]; last.Project == nil || last.Project.Status != StatusProvisioned {
+		t.Errorf("project.provisioned carries %+v, want the provisioned project", last.Project)
+	}
+}
+
+func TestServiceOutboxDropsEventsOfFailedChanges(t *testing.T) {
+	var enqueued []Event
+	store := transferStore(new([]string))
+	store.xferFn = func(context.Context, string, string, string) (*Project, error) {
+		return nil, ErrUnknownOrganization
+	}
+	store.enqFn = func(_ context.Context, events []Event) error {
+		enqueued = append(enqueued, events...)
+		return nil
+	}
+	s := newService(store, mockRegistry{}, nil, WithEventOutbox(true))
+
+	_, err := s.Transfer(context.Background(), transferProjectID,
+		TransferProjectRequest{OrgID: transferToOrg}, platform.Actor{Admin: true})
+	if !errors.Is(err, ErrUnknownOrganization) {
+		t.Fatalf("Transfer() error = %v, want ErrUnknownOrganization", err)
+	}
+	if len(enqueued) != 0 {
+		t.Errorf("enqueued %d events for a transfer that failed", len(enqueued))
+	}
+}
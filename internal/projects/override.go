@@ -0,0 +1,26 @@
+package projects
+
+import "context"
+
+// PluginOverrideHeader is the request header that, in testing mode, names
+// the plugin to provision a create with instead of the project's own.
+const PluginOverrideHeader = "X-Provision-Plugin"
+
+type pluginOverrideKey struct{}
+
+// WithPluginOverride returns a copy of ctx under which provisioning uses
+// the named plugin instead of the one the project names. The project keeps
+// its own plugin, so a later reprovision uses it again. The override must
+// still be on the plugin allowlist. An empty name overrides nothing.
+func WithPluginOverride(ctx context.Context, name string) context.Context {
+	if name == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, pluginOverrideKey{}, name)
+}
+
+// pluginOverride returns the plugin WithPluginOverride set on ctx, if any.
+func pluginOverride(ctx context.Context) string {
+	name, _ := ctx.Value(pluginOverrideKey{}).(string)
+	return name
+}
@@ -0,0 +1,139 @@
+package projects
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/searge/quokka/internal/plugin"
+)
+
+func TestHandlerCreateHonorsPluginOverrideOnlyInTestingMode(t *testing.T) {
+	tests := []struct {
+		name        string
+		testingMode bool
+		header      string
+		wantPlugin  string
+	}{
+		{name: "testing mode", testingMode: true, header: "fake", wantPlugin: "fake"},
+		{name: "testing mode without header", testingMode: true, wantPlugin: "proxmox"},
+		{name: "production", header: "fake", wantPlugin: "proxmox"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stored *ProvisionParams
+			var used []string
+			svc := newService(
+				mockStore{
+					createFn: func(_ context.Context, req CreateProjectRequest) (*Project, error) {
+						stored = req.ProvisionParams
+						return &Project{ID: "p-1", Name: req.Name, UnixName: req.UnixName, ProvisionParams: req.ProvisionParams}, nil
+					},
+				},
+				mockRegistry{
+					getFn: func(name string) (plugin.Plugin, error) {
+						return mockPlugin{
+							provisionFn: func(context.Context, plugin.ProvisionRequest) (*plugin.ProvisionResult, error) {
+								used = append(used, name)
+								return &plugin.ProvisionResult{ResourceID: "res-1", Status: "ok"}, nil
+							},
+						}, nil
+					},
+				},
+				nil,
+				WithAllowedPlugins("proxmox", "fake"),
+			)
+			h := NewHandler(svc, nil, WithTestingMode(tt.testingMode))
+
+			req := httptest.NewRequest(http.MethodPost, "/projects",
+				strings.NewReader(`{"name":"Alpha","unix_name":"alpha","provision_params":{"plugin":"proxmox"}}`))
+			if tt.header != "" {
+				req.Header.Set(PluginOverrideHeader, tt.header)
+			}
+			rr := httptest.NewRecorder()
+			h.Create(rr, req)
+
+			if rr.Code != http.StatusCreated {
+				t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+			}
+			if len(used) != 1 || used[0] != tt.wantPlugin {
+				t.Errorf("provisioned with %v, want %s", used, tt.wantPlugin)
+			}
+			if stored == nil || stored.Plugin != "proxmox" {
+				t.Errorf("stored provision params %+v, want the project's own plugin", stored)
+			}
+			if !strings.Contains(rr.Body.String(), `"status":"provisioned"`) {
+				t.Errorf("expected the project to be provisioned, got %s", rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandlerCreateRejectsDisallowedPluginOverride(t *testing.T) {
+	created := false
+	svc := newService(
+		mockStore{
+			createFn: func(context.Context, CreateProjectRequest) (*Project, error) {
+				created = true
+				return &Project{ID: "p-1"}, nil
+			},
+		},
+		mockRegistry{},
+		nil,
+		WithAllowedPlugins("proxmox"),
+	)
+	h := NewHandler(svc, nil, WithTestingMode(true))
+
+	req := httptest.NewRequest(http.MethodPost, "/projects",
+		strings.NewReader(`{"name":"Alpha","unix_name":"alpha","provision_params":{"plugin":"proxmox"}}`))
+	req.Header.Set(PluginOverrideHeader, "fake")
+	rr := httptest.NewRecorder()
+	h.Create(rr, req)
+
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if created {
+		t.Error("a project was created for a disallowed plugin override")
+	}
+}
+
+func TestServiceProvisionChecksPluginOverrideAgainstAllowlist(t *testing.T) {
+	var used []string
+	svc := newService(
+		mockStore{
+			getByID: func(context.Context, string) (*Project, error) {
+				return &Project{ID: "p-1", Status: StatusProvisioned, ProvisionParams: &ProvisionParams{Plugin: "proxmox"}}, nil
+			},
+		},
+		mockRegistry{
+			getFn: func(name string) (plugin.Plugin, error) {
+				used = append(used, name)
+				return mockPlugin{}, nil
+			},
+		},
+		nil,
+		WithAllowedPlugins("proxmox"),
+	)
+
+	_, err := svc.Reprovision(WithPluginOverride(context.Background(), "fake"), "p-1")
+	if !errors.Is(err, ErrPluginNotAllowed) {
+		t.Fatalf("Reprovision() error = %v, want ErrPluginNotAllowed", err)
+	}
+	if len(used) != 0 {
+		t.Errorf("provisioned with %v, want no plugin called", used)
+	}
+}
+
+func TestWithPluginOverrideIgnoresEmptyName(t *testing.T) {
+	ctx := context.Background()
+	if got := WithPluginOverride(ctx, ""); got != ctx {
+		t.Error("an empty override should leave ctx as it is")
+	}
+	if got := pluginOverride(WithPluginOverride(ctx, "fake")); got != "fake" {
+		t.Errorf("pluginOverride() = %q, want fake", got)
+	}
+}
@@ -0,0 +1,39 @@
+package projects
+
+import (
+	"context"
+	"errors"
+
+	"github.com/searge/quokka/internal/operations"
+	"github.com/searge/quokka/internal/orgs"
+)
+
+// prioritySource reports the queue priority policy of an organization.
+type prioritySource interface {
+	ProvisionPriority(ctx context.Context, orgID string) (string, error)
+}
+
+// WithOrgPriorities queues async creates that do not ask for a priority at
+// the priority their organization's policy in p sets.
+func WithOrgPriorities(p prioritySource) ServiceOption {
+	return func(s *Service) {
+		s.priorities = p
+	}
+}
+
+// ProvisionPriority returns the queue priority of an async create: the
+// request's own, else its organization's policy, else normal.
+func (s *Service) ProvisionPriority(ctx context.Context, req CreateProjectRequest) (operations.Priority, error) {
+	name := req.Priority
+	if name == "" && s.priorities != nil && req.OrgID != "" {
+		policy, err := s.priorities.ProvisionPriority(ctx, req.OrgID)
+		if err != nil {
+			if errors.Is(err, orgs.ErrOrganizationNotFound) || errors.Is(err, orgs.ErrInvalidOrganizationID) {
+				return operations.PriorityNormal, ErrUnknownOrganization
+			}
+			return operations.PriorityNormal, err
+		}
+		name = policy
+	}
+	return operations.ParsePriority(name)
+}
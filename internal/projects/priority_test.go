@@ -0,0 +1,98 @@
+package projects
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/searge/quokka/internal/operations"
+	"github.com/searge/quokka/internal/orgs"
+)
+
+// orgPolicies maps organization IDs to their priority policy.
+type orgPolicies map[string]string
+
+func (p orgPolicies) ProvisionPriority(_ context.Context, orgID string) (string, error) {
+	policy, ok := p[orgID]
+	if !ok {
+		return "", orgs.ErrOrganizationNotFound
+	}
+	return policy, nil
+}
+
+func TestServiceProvisionPriority(t *testing.T) {
+	const otherOrgID = "9c0e5a43-7f21-4d8b-b6a2-3e1f0d9c8b75"
+	policies := orgPolicies{testOrgID: "high", otherOrgID: ""}
+
+	tests := []struct {
+		name    string
+		req     CreateProjectRequest
+		want    operations.Priority
+		wantErr error
+	}{
+		{name: "default", want: operations.PriorityNormal},
+		{name: "request", req: CreateProjectRequest{Priority: "urgent"}, want: operations.PriorityUrgent},
+		{name: "request overrides organization", req: CreateProjectRequest{Priority: "low", OrgID: testOrgID}, want: operations.PriorityLow},
+		{name: "organization policy", req: CreateProjectRequest{OrgID: testOrgID}, want: operations.PriorityHigh},
+		{name: "organization without policy", req: CreateProjectRequest{OrgID: otherOrgID}, want: operations.PriorityNormal},
+		{name: "unknown organization", req: CreateProjectRequest{OrgID: "00000000-0000-4000-8000-000000000001"}, wantErr: ErrUnknownOrganization},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newService(mockStore{}, mockRegistry{}, nil, WithOrgPriorities(policies))
+
+			got, err := s.ProvisionPriority(context.Background(), tt.req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ProvisionPriority() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("ProvisionPriority() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandlerCreateAsyncQueuesAtPriority(t *testing.T) {
+	ops := &syncOperations{}
+	svc := newService(
+		mockStore{
+			createFn: func(context.Context, CreateProjectRequest) (*Project, error) {
+				return &Project{ID: "p-1", Name: "Alpha", Status: StatusPending}, nil
+			},
+		},
+		mockRegistry{},
+		nil,
+		WithOrgPriorities(orgPolicies{testOrgID: "low"}),
+	)
+	h := NewHandler(svc, nil, WithOperations(ops))
+
+	rr := httptest.NewRecorder()
+	h.Create(rr, httptest.NewRequest(http.MethodPost, "/api/v1/projects?async=true",
+		strings.NewReader(`{"name":"Alpha","unix_name":"alpha","org_id":"`+testOrgID+`"}`)))
+
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if ops.priority != operations.PriorityLow {
+		t.Errorf("queued at %s, want the organization's low priority", ops.priority)
+	}
+}
+
+func TestHandlerCreateAsyncRejectsUnknownPriority(t *testing.T) {
+	ops := &syncOperations{}
+	h := NewHandler(newService(mockStore{}, mockRegistry{}, nil), nil, WithOperations(ops))
+
+	rr := httptest.NewRecorder()
+	h.Create(rr, httptest.NewRequest(http.MethodPost, "/api/v1/projects?async=true",
+		strings.NewReader(`{"name":"Alpha","unix_name":"alpha","priority":"asap"}`)))
+
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if ops.kind != "" {
+		t.Error("an invalid priority must not start an operation")
+	}
+}
@@ -0,0 +1,26 @@
+package projects
+
+import "errors"
+
+// ErrProvisioningDisabled is returned by operations that need a plugin
+// when the service was built without provisioning.
+var ErrProvisioningDisabled = errors.New("provisioning is disabled")
+
+// WithProvisioning turns provisioning on or off; it is on by default.
+// Without it the service only keeps project metadata for deployments that
+// provision out of band: creates store the project as unmanaged without
+// calling a plugin, and whatever else would call one returns
+// ErrProvisioningDisabled.
+func WithProvisioning(enabled bool) ServiceOption {
+	return func(s *Service) {
+		s.provisioningDisabled = !enabled
+	}
+}
+
+// checkProvisioning returns ErrProvisioningDisabled if provisioning is off.
+func (s *Service) checkProvisioning() error {
+	if s.provisioningDisabled {
+		return ErrProvisioningDisabled
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package projects
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/searge/quokka/internal/plugin"
+)
+
+// panickyRegistry fails the test if anything asks it for a plugin.
+func panickyRegistry(t *testing.T) mockRegistry {
+	return mockRegistry{
+		getFn: func(name string) (plugin.Plugin, error) {
+			t.Errorf("plugin %q requested with provisioning disabled", name)
+			return mockPlugin{}, nil
+		},
+	}
+}
+
+func TestServiceCreateWithProvisioningDisabled(t *testing.T) {
+	for _, deferred := range []bool{false, true} {
+		var stored ProvisionStatus
+		events := &recordingPublisher{}
+		s := newService(
+			mockStore{
+				createFn: func(_ context.Context, req CreateProjectRequest) (*Project, error) {
+					return &Project{ID: "p-1", Name: req.Name, Status: StatusPending}, nil
+				},
+				statusFn: func(_ context.Context, _ string, status ProvisionStatus) error {
+					stored = status
+					return nil
+				},
+			},
+			panickyRegistry(t),
+			nil,
+			WithProvisioning(false),
+			WithEventPublisher(events),
+			WithDeferredCreateEvents(deferred),
+		)
+
+		project, err := s.Create(context.Background(), CreateProjectRequest{Name: "Alpha", UnixName: "alpha"})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if project.Status != StatusUnmanaged || stored != StatusUnmanaged {
+			t.Errorf("status = %q, stored %q, want unmanaged", project.Status, stored)
+		}
+		if got := events.types(); !slices.Equal(got, []string{EventCreated}) {
+			t.Errorf("deferred=%v: events = %v, want only %s", deferred, got, EventCreated)
+		}
+	}
+}
+
+func TestServiceEstimateProvisioningWithProvisioningDisabled(t *testing.T) {
+	s := newService(mockStore{}, mockRegistry{}, nil, WithProvisioning(false), WithDefaultProvisionEstimate(time.Minute))
+
+	if d, ok := s.EstimateProvisioning(context.Background(), CreateProjectRequest{}); ok {
+		t.Errorf("expected no estimate, got %v", d)
+	}
+}
+
+func TestHandlerPluginEndpointsWithProvisioningDisabled(t *testing.T) {
+	const id = "7c9e6679-7425-40de-944b-e07fc1f90ae7"
+	tests := []struct {
+		name   string
+		method string
+		path   string
+	}{
+		{name: "status", method: http.MethodGet, path: "/" + id + "/status"},
+		{name: "reprovision", method: http.MethodPost, path: "/" + id + "/reprovision"},
+		{name: "reprovision stream", method: http.MethodPost, path: "/" + id + "/reprovision?stream=true"},
+		{name: "cancel and deprovision", method: http.MethodPost, path: "/" + id + "/cancel?deprovision=true"},
+		{name: "tag sync", method: http.MethodPost, path: "/" + id + "/tags/sync"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := mockStore{
+				getByID: func(context.Context, string) (*Project, error) {
+					return &Project{ID: id, Status: StatusProvisioning, ResourceID: "vm-1"}, nil
+				},
+			}
+			svc := newService(store, panickyRegistry(t), nil, WithProvisioning(false), WithTagLabels(AllTagLabels))
+			h := NewHandler(svc, nil)
+
+			rr := httptest.NewRecorder()
+			h.Routes().ServeHTTP(rr, httptest.NewRequest(tt.method, tt.path, nil))
+
+			if rr.Code != http.StatusNotImplemented {
+				t.Fatalf("expected 501, got %d: %s", rr.Code, rr.Body.String())
+			}
+			if !strings.Contains(rr.Body.String(), "PROVISIONING_DISABLED") {
+				t.Errorf("body = %s, want PROVISIONING_DISABLED", rr.Body.String())
+			}
+		})
+	}
+}
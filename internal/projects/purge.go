@@ -0,0 +1,64 @@
+package projects
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Purger hard-deletes projects once they have been soft-deleted for longer
+// than the retention period, keeping the projects table lean.
+type Purger struct {
+	store     purgeStore
+	retention time.Duration
+	log       *slog.Logger
+	now       func() time.Time
+}
+
+type purgeStore interface {
+	PurgeDeleted(ctx context.Context, before time.Time) ([]PurgedProject, error)
+}
+
+// NewPurger creates a Purger removing projects deleted more than retention ago.
+func NewPurger(store *Store, retention time.Duration, logger *slog.Logger) *Purger {
+	return newPurger(store, retention, logger)
+}
+
+func newPurger(store purgeStore, retention time.Duration, logger *slog.Logger) *Purger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Purger{store: store, retention: retention, log: logger, now: time.Now}
+}
+
+// PurgeOnce removes every project past retention, logging each one, and
+// returns how many were removed.
+func (p *Purger) PurgeOnce(ctx context.Context) (int, error) {
+	purged, err := p.store.PurgeDeleted(ctx, p.now().Add(-p.retention))
+	if err != nil {
+		return 0, err
+	}
+	for _, project := range purged {
+		p.log.Info("purged deleted project",
+			"project_id", project.ID, "unix_name", project.UnixName, "deleted_at", project.DeletedAt)
+	}
+	return len(purged), nil
+}
+
+// Run purges every interval until ctx is done. Failed runs are logged and
+// retried on the next tick.
+func (p *Purger) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := p.PurgeOnce(ctx); err != nil && ctx.Err() == nil {
+			p.log.Error("failed to purge deleted projects", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
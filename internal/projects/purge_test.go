@@ -0,0 +1,76 @@
+package projects
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryPurgeStore keeps soft-deleted projects in memory.
+type memoryPurgeStore struct {
+	mu      sync.Mutex
+	deleted []PurgedProject
+}
+
+func (m *memoryPurgeStore) PurgeDeleted(_ context.Context, before time.Time) ([]PurgedProject, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var purged, kept []PurgedProject
+	for _, p := range m.deleted {
+		if p.DeletedAt.Before(before) {
+			purged = append(purged, p)
+		} else {
+			kept = append(kept, p)
+		}
+	}
+	m.deleted = kept
+	return purged, nil
+}
+
+func TestPurgerRemovesProjectsPastRetention(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := &memoryPurgeStore{deleted: []PurgedProject{
+		{ID: "p-old", UnixName: "old", DeletedAt: now.Add(-48 * time.Hour)},
+		{ID: "p-recent", UnixName: "recent", DeletedAt: now.Add(-23 * time.Hour)},
+	}}
+	p := newPurger(store, 24*time.Hour, nil)
+	p.now = func() time.Time { return now }
+
+	n, err := p.PurgeOnce(context.Background())
+	if err != nil {
+		t.Fatalf("PurgeOnce() error = %v", err)
+	}
+	if n != 1 || len(store.deleted) != 1 || store.deleted[0].ID != "p-recent" {
+		t.Fatalf("expected only p-old purged, got n=%d remaining=%v", n, store.deleted)
+	}
+
+	// Two hours later the recent deletion has aged past retention too.
+	now = now.Add(2 * time.Hour)
+	n, err = p.PurgeOnce(context.Background())
+	if err != nil {
+		t.Fatalf("PurgeOnce() error = %v", err)
+	}
+	if n != 1 || len(store.deleted) != 0 {
+		t.Fatalf("expected p-recent purged once past retention, got n=%d remaining=%v", n, store.deleted)
+	}
+}
+
+func TestPurgerRunStopsWithContext(t *testing.T) {
+	p := newPurger(&memoryPurgeStore{}, time.Hour, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		p.Run(ctx, time.Millisecond)
+		close(done)
+	}()
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after the context was cancelled")
+	}
+}
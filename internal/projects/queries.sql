@@ -1,31 +1,122 @@
 -- name: GetProject :one
-SELECT id, name, unix_name, description, active, created_at, updated_at
+SELECT id, name, unix_name, description, active, created_at, updated_at, provision_params, labels, status, deleted_at, org_id, resource_id, archived_unix_name, network, last_checked_at, node
 FROM projects
-WHERE id = $1;
+WHERE id = $1 AND deleted_at IS NULL;
 
 -- name: GetProjectByUnixName :one
-SELECT id, name, unix_name, description, active, created_at, updated_at
+SELECT id, name, unix_name, description, active, created_at, updated_at, provision_params, labels, status, deleted_at, org_id, resource_id, archived_unix_name, network, last_checked_at, node
+FROM projects
+WHERE unix_name = $1 AND deleted_at IS NULL;
+
+-- name: GetProjectsByIDs :many
+SELECT id, name, unix_name, description, active, created_at, updated_at, provision_params, labels, status, deleted_at, org_id, resource_id, archived_unix_name, network, last_checked_at, node
 FROM projects
-WHERE unix_name = $1;
+WHERE id = ANY(sqlc.arg('ids')::uuid[]) AND deleted_at IS NULL;
+
+-- name: CheckProjectExistsByName :one
+SELECT EXISTS(
+    SELECT 1 FROM projects
+    WHERE lower(name) = lower(sqlc.arg('name'))
+      AND id IS DISTINCT FROM sqlc.narg('except_id')
+      AND deleted_at IS NULL
+);
 
 -- name: CheckProjectExistsByUnixName :one
 SELECT EXISTS(
     SELECT 1 FROM projects WHERE unix_name = $1
 );
 
+-- name: CountActiveProjectsByOrg :one
+SELECT COUNT(*)
+FROM projects
+WHERE org_id = $1 AND active AND deleted_at IS NULL;
+
+-- name: CountProjects :one
+SELECT COUNT(*)
+FROM projects
+WHERE deleted_at IS NULL
+  AND (cardinality(sqlc.arg('statuses')::text[]) = 0 OR status = ANY(sqlc.arg('statuses')::text[]))
+  AND (sqlc.arg('node')::text = '' OR node = sqlc.arg('node')::text);
+
+-- name: CountProjectsByStatus :many
+SELECT status, COUNT(*) AS count
+FROM projects
+WHERE deleted_at IS NULL
+GROUP BY status;
+
+-- name: CountRelatedProjects :one
+-- Counts the projects ListRelatedProjects pages through.
+SELECT COUNT(*)
+FROM projects source
+JOIN projects p
+    ON p.id <> source.id
+   AND p.labels ?| ARRAY(SELECT jsonb_object_keys(source.labels))
+WHERE source.id = $1 AND source.deleted_at IS NULL
+  AND p.active AND p.deleted_at IS NULL
+  AND EXISTS (
+      SELECT 1
+      FROM jsonb_each(p.labels) AS label
+      WHERE source.labels @> jsonb_build_object(label.key, label.value)
+  );
+
 -- name: CreateProject :one
 INSERT INTO projects (
-    id, name, unix_name, description, active, created_at, updated_at
+    id, name, unix_name, description, active, created_at, updated_at, provision_params, labels, org_id
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $7
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
 )
-RETURNING id, name, unix_name, description, active, created_at, updated_at;
+RETURNING id, name, unix_name, description, active, created_at, updated_at, provision_params, labels, status, deleted_at, org_id, resource_id, archived_unix_name, network, last_checked_at, node;
 
 -- name: ListProjects :many
-SELECT id, name, unix_name, description, active, created_at, updated_at
+SELECT id, name, unix_name, description, active, created_at, updated_at, provision_params, labels, status, deleted_at, org_id, resource_id, archived_unix_name, network, last_checked_at, node
 FROM projects
+WHERE deleted_at IS NULL
+  AND (cardinality(sqlc.arg('statuses')::text[]) = 0 OR status = ANY(sqlc.arg('statuses')::text[]))
+  AND (sqlc.arg('node')::text = '' OR node = sqlc.arg('node')::text)
 ORDER BY created_at DESC
-LIMIT $1 OFFSET $2;
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: ListProjectsBySelector :many
+-- Live projects matching a bulk selector, as UpdateProjectLabels matches
+-- them, oldest first.
+SELECT id, name, unix_name, description, active, created_at, updated_at, provision_params, labels, status, deleted_at, org_id, resource_id, archived_unix_name, network, last_checked_at, node
+FROM projects
+WHERE (cardinality(sqlc.arg('ids')::uuid[]) = 0 OR id = ANY(sqlc.arg('ids')::uuid[]))
+  AND labels @> sqlc.arg('match_labels')::jsonb
+  AND (sqlc.arg('template')::text = '' OR provision_params->>'template' = sqlc.arg('template')::text)
+  AND deleted_at IS NULL
+ORDER BY created_at, id
+LIMIT sqlc.arg('limit');
+
+-- name: ListProjectsForReconcile :many
+-- Live projects last checked before older_than, or never, least recently
+-- checked first.
+SELECT id, name, unix_name, description, active, created_at, updated_at, provision_params, labels, status, deleted_at, org_id, resource_id, archived_unix_name, network, last_checked_at, node
+FROM projects
+WHERE deleted_at IS NULL
+  AND (last_checked_at IS NULL OR last_checked_at < sqlc.arg('older_than'))
+ORDER BY last_checked_at NULLS FIRST, id
+LIMIT sqlc.arg('limit');
+
+-- name: ListRelatedProjects :many
+-- Other active projects sharing at least one label, key and value, with the
+-- given project, ranked by how many they share.
+SELECT p.id, p.name, p.unix_name, p.description, p.active, p.created_at, p.updated_at, p.provision_params, p.labels, p.status, p.deleted_at, p.org_id, p.resource_id, p.archived_unix_name, p.network, p.last_checked_at, p.node,
+    shared.count AS shared_labels
+FROM projects source
+JOIN projects p
+    ON p.id <> source.id
+   AND p.labels ?| ARRAY(SELECT jsonb_object_keys(source.labels))
+CROSS JOIN LATERAL (
+    SELECT COUNT(*) AS count
+    FROM jsonb_each(p.labels) AS label
+    WHERE source.labels @> jsonb_build_object(label.key, label.value)
+) shared
+WHERE source.id = sqlc.arg('id') AND source.deleted_at IS NULL
+  AND p.active AND p.deleted_at IS NULL
+  AND shared.count > 0
+ORDER BY shared.count DESC, p.created_at DESC, p.id
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
 
 -- name: UpdateProject :one
 UPDATE projects
@@ -34,9 +125,174 @@ SET
     description = COALESCE(sqlc.narg('description'), description),
     active = COALESCE(sqlc.narg('active'), active),
     updated_at = $3
-WHERE id = $1
-RETURNING id, name, unix_name, description, active, created_at, updated_at;
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING id, name, unix_name, description, active, created_at, updated_at, provision_params, labels, status, deleted_at, org_id, resource_id, archived_unix_name, network, last_checked_at, node;
 
 -- name: DeleteProject :execrows
+-- A non-empty unix_name_suffix renames the project to free its unix_name,
+-- keeping the original in archived_unix_name.
+UPDATE projects
+SET
+    deleted_at = sqlc.arg('deleted_at'),
+    updated_at = sqlc.arg('deleted_at'),
+    archived_unix_name = CASE WHEN sqlc.arg('unix_name_suffix')::text = '' THEN archived_unix_name ELSE unix_name END,
+    unix_name = CASE WHEN sqlc.arg('unix_name_suffix')::text = '' THEN unix_name
+        ELSE left(unix_name, 100 - length(sqlc.arg('unix_name_suffix')::text)) || sqlc.arg('unix_name_suffix')::text END
+WHERE id = sqlc.arg('id') AND deleted_at IS NULL;
+
+-- name: PurgeDeletedProjects :many
 DELETE FROM projects
+WHERE deleted_at IS NOT NULL AND deleted_at < $1
+RETURNING id, COALESCE(archived_unix_name, unix_name) AS unix_name, deleted_at;
+
+-- name: MarkProjectChecked :execrows
+UPDATE projects
+SET last_checked_at = $2
+WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: SetProjectNetwork :execrows
+UPDATE projects
+SET
+    network = $2,
+    updated_at = $3
+WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: SetProjectProvisionParams :execrows
+UPDATE projects
+SET
+    provision_params = $2,
+    updated_at = $3
+WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: SetProjectResource :execrows
+UPDATE projects
+SET
+    resource_id = $2,
+    node = $3,
+    updated_at = $4
+WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: SyncOrgProjectLabels :many
+-- Rebases the labels of an organization's live projects from the previous
+-- default labels onto the current ones. A label still holding its previous
+-- default is inherited, so it takes the current default or goes with it;
+-- any other label is the project's own and wins over the defaults.
+UPDATE projects AS p
+SET
+    labels = synced.labels,
+    updated_at = sqlc.arg('updated_at')
+FROM (
+    SELECT q.id, sqlc.arg('current')::jsonb || COALESCE((
+        SELECT jsonb_object_agg(label.key, label.value)
+        FROM jsonb_each(q.labels) AS label
+        WHERE NOT sqlc.arg('previous')::jsonb @> jsonb_build_object(label.key, label.value)
+    ), '{}'::jsonb) AS labels
+    FROM projects AS q
+    WHERE q.org_id = sqlc.arg('org_id') AND q.deleted_at IS NULL
+) AS synced
+WHERE p.id = synced.id AND p.labels IS DISTINCT FROM synced.labels
+RETURNING p.id;
+
+-- name: TransferProject :one
+-- Moves a project to another organization only if it still belongs to
+-- from_org_id, so a concurrent transfer is never silently overwritten.
+UPDATE projects
+SET
+    org_id = sqlc.arg('to_org_id'),
+    updated_at = sqlc.arg('updated_at')
+WHERE id = sqlc.arg('id') AND org_id IS NOT DISTINCT FROM sqlc.narg('from_org_id') AND deleted_at IS NULL
+RETURNING id, name, unix_name, description, active, created_at, updated_at, provision_params, labels, status, deleted_at, org_id, resource_id, archived_unix_name, network, last_checked_at, node;
+
+-- name: TransitionProjectStatus :one
+WITH updated AS (
+    UPDATE projects
+    SET
+        status = sqlc.arg('to'),
+        updated_at = sqlc.arg('updated_at')
+    WHERE id = sqlc.arg('id') AND status = sqlc.arg('from') AND deleted_at IS NULL
+    RETURNING id
+)
+SELECT
+    EXISTS(SELECT 1 FROM updated) AS transitioned,
+    EXISTS(SELECT 1 FROM projects WHERE id = sqlc.arg('id') AND deleted_at IS NULL) AS found;
+
+-- name: TransitionStaleProjectStatus :one
+-- Like TransitionProjectStatus, but only for a project left unchanged
+-- since changed_before, e.g. one whose provisioning no instance can still
+-- be running.
+WITH updated AS (
+    UPDATE projects
+    SET
+        status = sqlc.arg('to'),
+        updated_at = sqlc.arg('updated_at')
+    WHERE id = sqlc.arg('id') AND status = sqlc.arg('from') AND updated_at < sqlc.arg('changed_before') AND deleted_at IS NULL
+    RETURNING id
+)
+SELECT
+    EXISTS(SELECT 1 FROM updated) AS transitioned,
+    EXISTS(SELECT 1 FROM projects WHERE id = sqlc.arg('id') AND deleted_at IS NULL) AS found;
+
+-- name: UpdateProjectLabels :many
+UPDATE projects
+SET
+    labels = (labels || sqlc.arg('add')::jsonb) - sqlc.arg('remove')::text[],
+    updated_at = sqlc.arg('updated_at')
+WHERE (cardinality(sqlc.arg('ids')::uuid[]) = 0 OR id = ANY(sqlc.arg('ids')::uuid[]))
+  AND labels @> sqlc.arg('match_labels')::jsonb
+  AND (sqlc.arg('template')::text = '' OR provision_params->>'template' = sqlc.arg('template')::text)
+  AND deleted_at IS NULL
+RETURNING id;
+
+-- name: UpdateProjectStatus :execrows
+UPDATE projects
+SET
+    status = $2,
+    updated_at = $3
+WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: EnqueueOutboxEvent :exec
+INSERT INTO events_outbox (event_type, project_id, payload, created_at, next_attempt_at)
+VALUES (sqlc.arg('event_type'), sqlc.arg('project_id'), sqlc.arg('payload'), sqlc.arg('created_at'), sqlc.arg('created_at'));
+
+-- name: ClaimOutboxEvents :many
+-- Leases up to max_events due events until lease_until, skipping rows
+-- another relay is claiming; an event not marked by then is claimed again.
+UPDATE events_outbox
+SET next_attempt_at = sqlc.arg('lease_until')
+WHERE id IN (
+    SELECT id FROM events_outbox
+    WHERE sent_at IS NULL AND next_attempt_at <= sqlc.arg('now')
+    ORDER BY id
+    LIMIT sqlc.arg('max_events')
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, event_type, project_id, payload, created_at, attempts, next_attempt_at, last_error, sent_at;
+
+-- name: MarkOutboxEventFailed :exec
+UPDATE events_outbox
+SET
+    attempts = attempts + 1,
+    next_attempt_at = $2,
+    last_error = $3
+WHERE id = $1;
+
+-- name: MarkOutboxEventSent :exec
+UPDATE events_outbox
+SET sent_at = $2
 WHERE id = $1;
+
+-- name: DeleteSentOutboxEvents :execrows
+DELETE FROM events_outbox
+WHERE sent_at < $1;
+
+-- name: LockProjectName :exec
+-- The two-key form keeps name locks apart from single-key advisory locks.
+SELECT pg_advisory_xact_lock(hashtext('projects.name'), hashtext(lower(sqlc.arg('name')::text)));
+
+-- name: LockOrganization :exec
+-- Held until the transaction ends, so quota checks on one organization run
+-- one at a time.
+SELECT 1 FROM organizations WHERE id = $1 FOR UPDATE;
+
+-- name: Ping :one
+SELECT 1;
@@ -0,0 +1,63 @@
+package projects
+
+import (
+	"context"
+	"fmt"
+)
+
+// quotaSource reports the active project quota of an organization.
+type quotaSource interface {
+	ProjectQuota(ctx context.Context, orgID string) (limit int32, limited bool, err error)
+}
+
+// WithQuotas enforces per-organization active project quotas from q on
+// create. Projects without an organization are never limited.
+func WithQuotas(q quotaSource) ServiceOption {
+	return func(s *Service) {
+		s.quotas = q
+	}
+}
+
+// checkQuota returns ErrQuotaExceeded if creating one more active project
+// would take the organization past its quota. It locks the organization's
+// row first, so through a store bound to a transaction that also inserts
+// the project, concurrent creates cannot overshoot the quota; see
+// commitQuota.
+func (s *Service) checkQuota(ctx context.Context, store projectStore, orgID string) error {
+	if !s.quotaApplies(orgID) {
+		return nil
+	}
+
+	limit, limited, err := s.quotas.ProjectQuota(ctx, orgID)
+	if err != nil {
+		return orgLookupError(err)
+	}
+	if !limited {
+		return nil
+	}
+
+	if err := store.LockOrganization(ctx, orgID); err != nil {
+		return err
+	}
+	active, err := store.CountActiveByOrg(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	if active >= int64(limit) {
+		return fmt.Errorf("%w: %d of %d active projects in use", ErrQuotaExceeded, active, limit)
+	}
+	return nil
+}
+
+// quotaApplies reports whether projects of orgID count against a quota.
+func (s *Service) quotaApplies(orgID string) bool {
+	return s.quotas != nil && orgID != ""
+}
+
+// commitQuota is commit for a change adding an active project to orgID:
+// when a quota applies, change runs in one transaction whatever the
+// outbox setting, so its quota check and its write cannot be interleaved
+// with another's.
+func (s *Service) commitQuota(ctx context.Context, lockName, orgID string, change func(projectStore) ([]Event, error)) error {
+	return s.commitIn(ctx, lockName, s.outbox || s.quotaApplies(orgID), change)
+}
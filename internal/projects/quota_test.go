@@ -0,0 +1,151 @@
+package projects
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/searge/quokka/internal/orgs"
+	"github.com/searge/quokka/internal/plugin"
+)
+
+const testOrgID = "4b1f8f52-3d5e-4c1a-9a57-0c8a1d2e3f40"
+
+// fixedQuotas reports the same quota for every known organization.
+type fixedQuotas struct {
+	limit   int32
+	limited bool
+	known   bool
+}
+
+func (q fixedQuotas) ProjectQuota(context.Context, string) (int32, bool, error) {
+	if !q.known {
+		return 0, false, orgs.ErrOrganizationNotFound
+	}
+	return q.limit, q.limited, nil
+}
+
+// newQuotaService returns a service whose organization already has active
+// projects, recording whether the store was asked to create another.
+func newQuotaService(active int64, quotas fixedQuotas, created *bool) *Service {
+	return newService(
+		mockStore{
+			activeFn: func(context.Context, string) (int64, error) {
+				return active, nil
+			},
+			createFn: func(_ context.Context, req CreateProjectRequest) (*Project, error) {
+				*created = true
+				return &Project{ID: "p-1", UnixName: req.UnixName, OrgID: req.OrgID}, nil
+			},
+		},
+		mockRegistry{
+			getFn: func(string) (plugin.Plugin, error) {
+				return nil, plugin.ErrPluginNotFound
+			},
+		},
+		nil,
+		WithQuotas(quotas),
+	)
+}
+
+func TestServiceCreateEnforcesQuota(t *testing.T) {
+	tests := []struct {
+		name        string
+		active      int64
+		quotas      fixedQuotas
+		wantErr     error
+		wantCreated bool
+	}{
+		{name: "below quota", active: 2, quotas: fixedQuotas{limit: 3, limited: true, known: true}, wantCreated: true},
+		{name: "at quota", active: 3, quotas: fixedQuotas{limit: 3, limited: true, known: true}, wantErr: ErrQuotaExceeded},
+		{name: "above quota", active: 4, quotas: fixedQuotas{limit: 3, limited: true, known: true}, wantErr: ErrQuotaExceeded},
+		{name: "zero quota", active: 0, quotas: fixedQuotas{limit: 0, limited: true, known: true}, wantErr: ErrQuotaExceeded},
+		{name: "unlimited", active: 1000, quotas: fixedQuotas{known: true}, wantCreated: true},
+		{name: "unknown organization", quotas: fixedQuotas{}, wantErr: ErrUnknownOrganization},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var created bool
+			s := newQuotaService(tt.active, tt.quotas, &created)
+
+			_, err := s.Create(context.Background(), CreateProjectRequest{
+				Name:     "Valid Name",
+				UnixName: "valid-name",
+				OrgID:    testOrgID,
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Create() error = %v, want %v", err, tt.wantErr)
+			}
+			if created != tt.wantCreated {
+				t.Errorf("store create called = %v, want %v", created, tt.wantCreated)
+			}
+		})
+	}
+}
+
+func TestServiceCreateChecksQuotaInItsTransaction(t *testing.T) {
+	// The quota is counted and the project inserted in one transaction,
+	// under a lock on the organization, so concurrent creates serialize
+	var steps []string
+	s := newService(
+		mockStore{
+			atomicFn: func() { steps = append(steps, "begin") },
+			orgLock: func(_ context.Context, orgID string) {
+				steps = append(steps, "lock "+orgID)
+			},
+			activeFn: func(context.Context, string) (int64, error) {
+				steps = append(steps, "count")
+				return 2, nil
+			},
+			createFn: func(_ context.Context, req CreateProjectRequest) (*Project, error) {
+				steps = append(steps, "insert")
+				return &Project{ID: "p-1", UnixName: req.UnixName, OrgID: req.OrgID}, nil
+			},
+		},
+		mockRegistry{getFn: func(string) (plugin.Plugin, error) { return nil, plugin.ErrPluginNotFound }},
+		nil,
+		WithQuotas(fixedQuotas{limit: 3, limited: true, known: true}),
+	)
+
+	if _, err := s.CreatePending(context.Background(), CreateProjectRequest{
+		Name: "Valid Name", UnixName: "valid-name", OrgID: testOrgID,
+	}); err != nil {
+		t.Fatalf("CreatePending() error = %v", err)
+	}
+	want := []string{"begin", "lock " + testOrgID, "count", "insert"}
+	if !slices.Equal(steps, want) {
+		t.Errorf("steps = %v, want %v", steps, want)
+	}
+}
+
+func TestServiceCreateWithoutOrganizationSkipsQuota(t *testing.T) {
+	var created bool
+	s := newQuotaService(0, fixedQuotas{limit: 0, limited: true, known: true}, &created)
+
+	if _, err := s.Create(context.Background(), CreateProjectRequest{Name: "Valid Name", UnixName: "valid-name"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if !created {
+		t.Error("expected a project without an organization to be created")
+	}
+}
+
+func TestHandlerCreateQuotaExceeded(t *testing.T) {
+	var created bool
+	h := NewHandler(newQuotaService(3, fixedQuotas{limit: 3, limited: true, known: true}, &created), nil)
+
+	body := `{"name":"Valid Name","unix_name":"valid-name","org_id":"` + testOrgID + `"}`
+	rr := httptest.NewRecorder()
+	h.Routes().ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if !strings.Contains(rr.Body.String(), "QUOTA_EXCEEDED") {
+		t.Errorf("expected QUOTA_EXCEEDED code, got %s", rr.Body.String())
+	}
+}
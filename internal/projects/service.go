@@ -5,99 +5,532 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"reflect"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/searge/quokka/internal/platform"
 	"github.com/searge/quokka/internal/plugin"
 )
 
 var (
 	ErrProjectNotFound  = errors.New("project not found")
 	ErrProjectExists    = errors.New("project unix name already exists")
+	ErrNameExists       = errors.New("project name already exists")
 	ErrInvalidUnixName  = errors.New("invalid unix name format")
 	ErrInvalidProjectID = errors.New("invalid project id format")
+	ErrTooManyIDs       = errors.New("too many project ids requested")
+	ErrProvisionFailed  = errors.New("provisioning failed")
+	ErrEmptySelector    = errors.New("selector must set ids, match_labels or template")
+	ErrInvalidLabelOp   = errors.New("invalid label change")
+	ErrInvalidStatus    = errors.New("invalid project status")
+	ErrPluginNotAllowed = errors.New("plugin not allowed for provisioning")
+	ErrHasLiveResources = errors.New("project has active resources")
+	ErrQuotaExceeded    = errors.New("organization project quota exceeded")
+	ErrNodeNotAllowed   = errors.New("node not allowed for provisioning")
+	ErrPluginRejected   = errors.New("plugin cannot provision the request")
+	ErrReservedUnixName = errors.New("unix name is reserved")
+	ErrUnknownTemplate  = errors.New("template does not exist")
 
-	unixNameRegex = regexp.MustCompile(`^[a-z0-9-]+$`)
+	ErrUnknownOrganization = errors.New("organization does not exist")
+
+	labelKeyRegex = regexp.MustCompile(`^[a-z0-9]([a-z0-9._/-]{0,61}[a-z0-9])?$`)
 )
 
+// MaxBatchIDs caps how many projects can be fetched in one GetMany call.
+const MaxBatchIDs = 100
+
+// MaxRelatedProjects caps how many projects one Related call returns.
+const MaxRelatedProjects = 100
+
+// MaxListProjects caps how many projects one List call returns.
+const MaxListProjects = 100
+
+// DefaultProvisionPlugin provisions projects that do not name a plugin.
+const DefaultProvisionPlugin = "proxmox"
+
+// OperationCreate is the kind of operation an asynchronous create runs as.
+const OperationCreate = "project.create"
+
+// ProvisionTimeout bounds one provisioning call, retries included.
+const ProvisionTimeout = 30 * time.Second
+
 // Service houses the central business logic for Projects.
 type Service struct {
 	store    projectStore
 	registry pluginRegistry
 	log      *slog.Logger
 	validate *validator.Validate
+	counters *counters
+
+	events            EventPublisher
+	deferCreateEvents bool
+	outbox            bool
+	allowedPlugins    map[string]struct{}
+	allowedNodes      map[string]struct{}
+	templates         map[string]struct{}
+	reservedUnixNames map[string]struct{}
+	tagLabels         []string
+	redacted          []string
+	unixNames         UnixNamePolicy
+	quotas            quotaSource
+	orgLabels         orgLabelSource
+	throttle          *platform.RateLimiter
+	priorities        prioritySource
+	uniqueNames       bool
+	statuses          *statusCache
+	defaulters        []Defaulter
+	jobs              *provisionJobs
+	estimates         *provisionEstimates
+
+	provisioningDisabled bool
 }
 
 type projectStore interface {
 	Create(ctx context.Context, req CreateProjectRequest) (*Project, error)
 	GetByID(ctx context.Context, id string) (*Project, error)
-	List(ctx context.Context, limit, offset int32) ([]*Project, error)
+	GetByIDs(ctx context.Context, ids []string) ([]*Project, error)
+	ExistsByUnixName(ctx context.Context, unixName string) (bool, error)
+	List(ctx context.Context, filter ListFilter) ([]*Project, error)
+	Count(ctx context.Context, filter ListFilter) (int64, error)
+	Related(ctx context.Context, id string, limit, offset int32) ([]*RelatedProject, error)
+	CountRelated(ctx context.Context, id string) (int64, error)
 	Update(ctx context.Context, id string, req UpdateProjectRequest) (*Project, error)
+	Transfer(ctx context.Context, id, fromOrgID, toOrgID string) (*Project, error)
+	SetStatus(ctx context.Context, id string, status ProvisionStatus) error
+	SetResource(ctx context.Context, id, resourceID, node string) error
+	SetProvisionParams(ctx context.Context, id string, params *ProvisionParams) error
+	SetNetwork(ctx context.Context, id string, network *plugin.NetworkInfo) error
+	TransitionStatus(ctx context.Context, id string, from, to ProvisionStatus) (bool, error)
+	TransitionStaleStatus(ctx context.Context, id string, from, to ProvisionStatus, changedBefore time.Time) (bool, error)
+	CountByStatus(ctx context.Context) (map[ProvisionStatus]int64, error)
+	CountActiveByOrg(ctx context.Context, orgID string) (int64, error)
+	LockOrganization(ctx context.Context, orgID string) error
+	UpdateLabels(ctx context.Context, sel LabelSelector, add map[string]string, remove []string) ([]string, error)
+	ListSelected(ctx context.Context, sel LabelSelector, limit int32) ([]*Project, error)
+	SyncOrgLabels(ctx context.Context, orgID string, previous, current map[string]string) ([]string, error)
 	Delete(ctx context.Context, id string) error
+	NameExists(ctx context.Context, name, exceptID string) (bool, error)
+	WithNameLock(ctx context.Context, name string, fn func(projectStore) error) error
+	Atomically(ctx context.Context, fn func(projectStore) error) error
+	EnqueueEvents(ctx context.Context, events ...Event) error
 }
 
 type pluginRegistry interface {
 	Get(name string) (plugin.Plugin, error)
 }
 
+// ServiceOption configures optional Service behaviour.
+type ServiceOption func(*Service)
+
+// WithAllowedPlugins restricts new provisioning, including reprovisioning,
+// to the named plugins. Other registered plugins remain usable for status
+// and health checks. No names allows every registered plugin.
+func WithAllowedPlugins(names ...string) ServiceOption {
+	return func(s *Service) {
+		s.allowedPlugins = nameSet(names)
+	}
+}
+
+// WithAllowedNodes restricts which cluster nodes a project may be pinned
+// to. No names allows any node.
+func WithAllowedNodes(names ...string) ServiceOption {
+	return func(s *Service) {
+		s.allowedNodes = nameSet(names)
+	}
+}
+
+// WithTemplates lists the templates that exist on the backend, so a
+// project naming any other is refused before it is stored rather than
+// failing to provision. No names accepts any template.
+func WithTemplates(names ...string) ServiceOption {
+	return func(s *Service) {
+		s.templates = nameSet(names)
+	}
+}
+
+// WithReservedUnixNames keeps new projects from taking the given unix
+// names, e.g. ones the backend or the hosts it creates use themselves.
+// Names are compared case-insensitively.
+func WithReservedUnixNames(names ...string) ServiceOption {
+	return func(s *Service) {
+		lowered := make([]string, len(names))
+		for i, name := range names {
+			lowered[i] = strings.ToLower(name)
+		}
+		s.reservedUnixNames = nameSet(lowered)
+	}
+}
+
+// nameSet returns names as a set, or nil if there are none.
+// Pure function.
+func nameSet(names []string) map[string]struct{} {
+	if len(names) == 0 {
+		return nil
+	}
+	set := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		set[name] = struct{}{}
+	}
+	return set
+}
+
+// WithRedactedResources masks the provision resources named by fields,
+// on top of those whose names look sensitive, wherever parameters are
+// shown rather than used, e.g. in bulk provisioning plans. Pass the fields
+// stored encrypted.
+func WithRedactedResources(fields ...string) ServiceOption {
+	return func(s *Service) {
+		s.redacted = fields
+	}
+}
+
+// WithUniqueNames makes display names unique among live projects,
+// compared case-insensitively, on create and rename.
+func WithUniqueNames(unique bool) ServiceOption {
+	return func(s *Service) {
+		s.uniqueNames = unique
+	}
+}
+
+// UnixNamePolicy is the naming rule project unix names must follow.
+type UnixNamePolicy struct {
+	Pattern   *regexp.Regexp
+	MinLength int
+	MaxLength int
+}
+
+// DefaultUnixNamePolicy allows 3 to 100 lowercase letters, digits and hyphens.
+func DefaultUnixNamePolicy() UnixNamePolicy {
+	return UnixNamePolicy{
+		Pattern:   regexp.MustCompile(`^[a-z0-9-]+$`),
+		MinLength: 3,
+		MaxLength: 100,
+	}
+}
+
+// WithUnixNamePolicy replaces the default unix name rule, e.g. to enforce a
+// deployment's naming convention.
+func WithUnixNamePolicy(policy UnixNamePolicy) ServiceOption {
+	return func(s *Service) {
+		s.unixNames = policy
+	}
+}
+
 // NewService creates a new Service.
-func NewService(store *Store, registry *plugin.Registry, logger *slog.Logger) *Service {
-	return newService(store, registry, logger)
+func NewService(store *Store, registry *plugin.Registry, logger *slog.Logger, opts ...ServiceOption) *Service {
+	return newService(store, registry, logger, opts...)
 }
 
-func newService(store projectStore, registry pluginRegistry, logger *slog.Logger) *Service {
+func newService(store projectStore, registry pluginRegistry, logger *slog.Logger, opts ...ServiceOption) *Service {
 	if logger == nil {
 		logger = slog.Default()
 	}
 
+	s := &Service{
+		store:     store,
+		registry:  registry,
+		log:       logger,
+		counters:  &counters{},
+		unixNames: DefaultUnixNamePolicy(),
+		statuses:  newStatusCache(0),
+		jobs:      newProvisionJobs(),
+		estimates: newProvisionEstimates(0),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.validate = newValidator(s.unixNames)
+	return s
+}
+
+// newValidator returns a validator with the project rules registered, unix
+// names following policy. Panics if a rule cannot be registered.
+func newValidator(policy UnixNamePolicy) *validator.Validate {
 	validate := validator.New()
+	validate.RegisterTagNameFunc(jsonFieldName)
+	validate.RegisterAlias("unix_name_length", fmt.Sprintf("min=%d,max=%d", policy.MinLength, policy.MaxLength))
 	err := validate.RegisterValidation("unix_name", func(fl validator.FieldLevel) bool {
-		return unixNameRegex.MatchString(fl.Field().String())
+		return policy.Pattern.MatchString(fl.Field().String())
 	})
 	if err != nil {
 		panic(fmt.Errorf("failed to register unix_name validator: %w", err))
 	}
-
-	return &Service{
-		store:    store,
-		registry: registry,
-		log:      logger,
-		validate: validate,
+	err = validate.RegisterValidation("label_key", func(fl validator.FieldLevel) bool {
+		return labelKeyRegex.MatchString(fl.Field().String())
+	})
+	if err != nil {
+		panic(fmt.Errorf("failed to register label_key validator: %w", err))
+	}
+	err = validate.RegisterValidation("ssh_public_key", func(fl validator.FieldLevel) bool {
+		return plugin.ValidateSSHPublicKey(fl.Field().String()) == nil
+	})
+	if err != nil {
+		panic(fmt.Errorf("failed to register ssh_public_key validator: %w", err))
 	}
+	return validate
 }
 
 // Create generates a new project entity and attempts resource provisioning via plugins.
+// With provisioning disabled, the project is stored as unmanaged instead.
 func (s *Service) Create(ctx context.Context, req CreateProjectRequest) (*Project, error) {
+	return s.CreateStream(ctx, req, nil)
+}
+
+// CreateStream is Create with the plugin's provisioning output passed to out
+// line by line as it is produced. out may be nil.
+func (s *Service) CreateStream(ctx context.Context, req CreateProjectRequest, out func(line string)) (*Project, error) {
+	project, err := s.CreatePending(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	// For the Spike, synchronously trigger provisioning via registry
+	if err := s.provisionPending(ctx, project, out); err != nil && !errors.Is(err, plugin.ErrPluginNotFound) {
+		// GO-004: We swallow the error from the client's perspective to avoid
+		// "500 Internal Error" when the DB creation actually succeeded.
+		// The failure is recorded in the project's status instead.
+		s.log.Warn("provisioning failed", "project_id", project.ID, "error", err)
+	}
+	return project, nil
+}
+
+// CreatePending is the first half of Create: it validates req and stores
+// the project, pending, without provisioning it, for callers that hand
+// provisioning to ProvisionPending in the background. With provisioning
+// disabled the project is stored as unmanaged, as by Create.
+func (s *Service) CreatePending(ctx context.Context, req CreateProjectRequest) (*Project, error) {
+	sent := req.Labels
+	req = s.applyDefaults(req)
 	if err := s.validateCreate(req); err != nil {
 		return nil, err
 	}
+	labels, err := s.inheritOrgLabels(ctx, req.OrgID, req.Labels, sent)
+	if err != nil {
+		return nil, err
+	}
+	req.Labels = labels
+
+	description, err := renderDescription(req)
+	if err != nil {
+		return nil, err
+	}
+	req.Description = description
+	req.ProvisionParams = withProvisionDefaults(req.ProvisionParams)
+
+	if err := s.checkThrottle(req.OrgID); err != nil {
+		return nil, err
+	}
 
 	// Persist to database
-	project, err := s.store.Create(ctx, req)
+	project, err := s.insert(ctx, req)
 	if err != nil {
+		s.refundThrottle(req.OrgID)
 		return nil, err
 	}
+	s.counters.created.Add(1)
+
+	if s.provisioningDisabled {
+		s.settle(ctx, project, StatusUnmanaged, ErrProvisioningDisabled, s.deferCreateEvents)
+	}
+	return project, nil
+}
+
+// ProvisionPending is the second half of Create: it provisions a project
+// stored by CreatePending, recording the outcome in its status as Create
+// does, and returns the provisioning error, if any. It does nothing with
+// provisioning disabled, as the project is already unmanaged.
+func (s *Service) ProvisionPending(ctx context.Context, project *Project) error {
+	return s.provisionPending(ctx, project, nil)
+}
+
+// AbandonPending marks a project stored by CreatePending failed without
+// provisioning it, when its provisioning could not be scheduled; it can
+// be reprovisioned later.
+func (s *Service) AbandonPending(ctx context.Context, project *Project, cause error) {
+	s.settle(ctx, project, StatusFailed, fmt.Errorf("%w: %w", ErrProvisionFailed, cause), s.deferCreateEvents)
+}
+
+// provisionPending provisions a newly created project, streaming the
+// plugin's output to out.
+func (s *Service) provisionPending(ctx context.Context, project *Project, out func(line string)) error {
+	if s.provisioningDisabled {
+		return nil
+	}
+	_, err := s.provision(ctx, project, out, s.deferCreateEvents)
+	if errors.Is(err, ErrProvisionCancelled) {
+		s.log.Info("provisioning cancelled", "project_id", project.ID)
+		return nil
+	}
+	return err
+}
 
-	// For the Spike, synchronously trigger the Proxmox plugin via registry
-	if proxmoxPlugin, err := s.registry.Get("proxmox"); err == nil {
-		provCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
-		defer cancel()
+// Reprovision replays the provisioning request stored on the project.
+// Projects created before requests were stored use the default plugin.
+// Plugin failures are wrapped in ErrProvisionFailed.
+// Returns ErrProvisioningDisabled if provisioning is off.
+func (s *Service) Reprovision(ctx context.Context, id string) (*plugin.ProvisionResult, error) {
+	return s.ReprovisionStream(ctx, id, nil)
+}
+
+// ReprovisionStream is Reprovision with the plugin's output passed to out
+// line by line as it is produced. out may be nil.
+func (s *Service) ReprovisionStream(ctx context.Context, id string, out func(line string)) (*plugin.ProvisionResult, error) {
+	if err := s.checkProvisioning(); err != nil {
+		return nil, err
+	}
+	project, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.provision(ctx, project, out, false)
+}
+
+// provision runs the project's provisioning request against its plugin,
+// streaming output to out if both are available. The project's status
+// follows the call: provisioning while it runs, then provisioned or failed,
+// or cancelled if CancelProvision stopped it. The outcome is reported
+// along with the final status, preceded by project.created if created is
+// set.
+func (s *Service) provision(ctx context.Context, project *Project, out func(line string), created bool) (*plugin.ProvisionResult, error) {
+	params := withProvisionDefaults(project.ProvisionParams)
+
+	err := s.checkPluginAllowed(params.Plugin)
+	if override := pluginOverride(ctx); override != "" {
+		s.log.Info("provisioning with overridden plugin",
+			"project_id", project.ID, "plugin", override, "project_plugin", params.Plugin)
+		params.Plugin = override
+		err = s.checkPluginAllowed(override)
+	}
+	if err == nil {
+		err = s.checkNodeAllowed(params.Node)
+	}
+	var p plugin.Plugin
+	if err == nil {
+		p, err = s.registry.Get(params.Plugin)
+	}
+	if err != nil {
+		s.settle(ctx, project, "", err, created)
+		return nil, err
+	}
 
-		if _, err := proxmoxPlugin.Provision(provCtx, plugin.ProvisionRequest{
-			ProjectID:   project.ID,
-			ProjectName: project.Name,
-		}); err != nil {
-			// GO-004: We swallow the error from the client's perspective to avoid
-			// "500 Internal Error" when the DB creation actually succeeded.
-			// Future work: Track ProvisionStatus on the Project entity.
-			// Currently, we just log the failure.
-			s.log.Warn("provisioning failed", "project_id", project.ID, "error", err)
+	// Tracked before the status changes, so a cancel that sees the
+	// project provisioning always finds the call.
+	provCtx, settled := s.jobs.start(ctx, project.ID)
+	defer settled()
+	s.setStatus(ctx, project, StatusProvisioning)
+
+	provCtx, cancel := context.WithTimeout(provCtx, ProvisionTimeout)
+	defer cancel()
+
+	s.counters.provisionsInFlight.Add(1)
+	started := time.Now()
+	result, err := plugin.ProvisionStream(provCtx, p, plugin.ProvisionRequest{
+		ProjectID:   project.ID,
+		ProjectName: project.Name,
+		UnixName:    project.UnixName,
+		Template:    params.Template,
+		Resources:   params.Resources,
+		Node:        params.Node,
+		Idempotent:  params.Idempotent,
+		SSHKeys:     params.SSHKeys,
+		Tags:        tagsFor(project.Labels, s.tagLabels),
+	}, s.trackProgress(ctx, project, out))
+	s.counters.provisionsInFlight.Add(-1)
+	// Record the outcome even if ctx was cancelled during the call, e.g.
+	// at shutdown, rather than leave the project provisioning.
+	ctx = context.WithoutCancel(ctx)
+
+	if err != nil && errors.Is(context.Cause(provCtx), ErrProvisionCancelled) {
+		err = fmt.Errorf("%w: %w", ErrProvisionCancelled, err)
+		s.settle(ctx, project, StatusCancelled, err, created)
+		return nil, err
+	}
+	if err != nil {
+		s.counters.provisionFailed.Add(1)
+		err = fmt.Errorf("%w: %w", ErrProvisionFailed, err)
+		s.settle(ctx, project, StatusFailed, err, created)
+		return nil, err
+	}
+	s.counters.provisionSucceeded.Add(1)
+	s.estimates.record(params.Plugin, params.Template, time.Since(started))
+	s.setResource(ctx, project, result.ResourceID, result.Metadata["node"])
+	s.setNetwork(ctx, project, result.Network)
+	s.settle(ctx, project, StatusProvisioned, nil, created)
+	return result, nil
+}
+
+// settle records status on project, unless it is empty because the
+// attempt never started, and reports outcome with the events from
+// outcomeEvents. With an outbox both are stored in one transaction;
+// otherwise the events are published even if the status could not be
+// stored. Failures are logged like in setStatus.
+func (s *Service) settle(ctx context.Context, project *Project, status ProvisionStatus, outcome error, created bool) {
+	if !s.outbox {
+		if status != "" {
+			s.setStatus(ctx, project, status)
 		}
+		s.publishEvents(ctx, outcomeEvents(project, outcome, created)...)
+		return
 	}
 
-	return project, nil
+	err := s.commit(ctx, "", func(store projectStore) ([]Event, error) {
+		if status != "" {
+			project.Status = status
+			if err := store.SetStatus(ctx, project.ID, status); err != nil {
+				return nil, err
+			}
+		}
+		return outcomeEvents(project, outcome, created), nil
+	})
+	if err != nil {
+		s.log.Warn("failed to record provisioning outcome",
+			"project_id", project.ID, "status", status, "error", err)
+	}
+}
+
+// setResource records the resource backing project and the node the
+// plugin placed it on, logging a failure to persist them like setStatus.
+func (s *Service) setResource(ctx context.Context, project *Project, resourceID, node string) {
+	if resourceID == "" && node == "" {
+		return
+	}
+	project.ResourceID, project.Node = resourceID, node
+	if err := s.store.SetResource(ctx, project.ID, resourceID, node); err != nil {
+		s.log.Warn("failed to record provisioned resource",
+			"project_id", project.ID, "resource_id", resourceID, "node", node, "error", err)
+	}
+}
+
+// setNetwork records how to reach the resource backing project, if the
+// plugin reported it, logging a failure to persist it like setStatus.
+func (s *Service) setNetwork(ctx context.Context, project *Project, network *plugin.NetworkInfo) {
+	if network.Empty() || network.Equal(project.Network) {
+		return
+	}
+	project.Network = network
+	if err := s.store.SetNetwork(ctx, project.ID, network); err != nil {
+		s.log.Warn("failed to record resource network",
+			"project_id", project.ID, "error", err)
+	}
+}
+
+// setStatus records status on project and in the store. A failure to
+// persist it is logged rather than failing the provisioning it describes.
+func (s *Service) setStatus(ctx context.Context, project *Project, status ProvisionStatus) {
+	project.Status = status
+	if err := s.store.SetStatus(ctx, project.ID, status); err != nil {
+		s.log.Warn("failed to record provisioning status",
+			"project_id", project.ID, "status", status, "error", err)
+	}
+}
+
+// Stats returns a snapshot of the service counters since start.
+func (s *Service) Stats() Stats {
+	return s.counters.snapshot()
 }
 
 func (s *Service) Get(ctx context.Context, id string) (*Project, error) {
@@ -114,15 +547,125 @@ func (s *Service) Get(ctx context.Context, id string) (*Project, error) {
 	return project, nil
 }
 
-func (s *Service) List(ctx context.Context, limit, offset int32) ([]*Project, error) {
-	if limit <= 0 {
-		limit = 100
+// GetMany fetches several projects at once, preserving the requested order
+// and reporting IDs that matched nothing. IDs are canonicalized first, so
+// spellings of one ID, such as in uppercase, are duplicates, and duplicate
+// IDs are collapsed; not_found lists the canonical forms.
+// Returns ErrTooManyIDs above MaxBatchIDs and ErrInvalidProjectID if any ID
+// is malformed.
+func (s *Service) GetMany(ctx context.Context, ids []string) (*BatchGetResult, error) {
+	unique := make([]string, 0, len(ids))
+	seen := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		// Stored IDs are canonical, so the ones asked for must be too
+		// before they are matched with them
+		if uid, err := uuid.Parse(id); err == nil {
+			id = uid.String()
+		}
+		if _, dup := seen[id]; dup {
+			continue
+		}
+		seen[id] = struct{}{}
+		unique = append(unique, id)
+	}
+	if len(unique) > MaxBatchIDs {
+		return nil, ErrTooManyIDs
 	}
-	return s.store.List(ctx, limit, offset)
+
+	found, err := s.store.GetByIDs(ctx, unique)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]*Project, len(found))
+	for _, p := range found {
+		byID[p.ID] = p
+	}
+
+	result := &BatchGetResult{
+		Projects: make([]*Project, 0, len(found)),
+		NotFound: []string{},
+	}
+	for _, id := range unique {
+		if p, ok := byID[id]; ok {
+			result.Projects = append(result.Projects, p)
+			continue
+		}
+		result.NotFound = append(result.NotFound, id)
+	}
+	return result, nil
 }
 
-func (s *Service) Update(ctx context.Context, id string, req UpdateProjectRequest) (*Project, error) {
-	project, err := s.store.Update(ctx, id, req)
+// List returns the page of projects filter selects. Unknown statuses yield
+// ErrInvalidStatus, and a node projects may not be pinned to yields
+// ErrNodeNotAllowed. A limit of zero or above MaxListProjects returns up
+// to MaxListProjects.
+func (s *Service) List(ctx context.Context, filter ListFilter) (platform.Page[*Project], error) {
+	for _, status := range filter.Statuses {
+		if !status.Valid() {
+			return platform.Page[*Project]{}, fmt.Errorf("%w: %q", ErrInvalidStatus, status)
+		}
+	}
+	if err := s.checkNodeAllowed(filter.Node); err != nil {
+		return platform.Page[*Project]{}, err
+	}
+	if filter.Limit <= 0 || filter.Limit > MaxListProjects {
+		filter.Limit = MaxListProjects
+	}
+	filter.Offset = max(filter.Offset, 0)
+	projects, err := s.store.List(ctx, filter)
+	if err != nil {
+		return platform.Page[*Project]{}, err
+	}
+	total, err := s.store.Count(ctx, filter)
+	if err != nil {
+		return platform.Page[*Project]{}, err
+	}
+	return platform.NewPage(projects, total, filter.Limit, filter.Offset), nil
+}
+
+// Related returns a page of the other active projects sharing at least one
+// label with project id, those sharing the most first. A limit of zero or
+// above MaxRelatedProjects returns up to MaxRelatedProjects.
+func (s *Service) Related(ctx context.Context, id string, limit, offset int32) (platform.Page[*RelatedProject], error) {
+	project, err := s.Get(ctx, id)
+	if err != nil {
+		return platform.Page[*RelatedProject]{}, err
+	}
+	if limit <= 0 || limit > MaxRelatedProjects {
+		limit = MaxRelatedProjects
+	}
+	offset = max(offset, 0)
+	if len(project.Labels) == 0 {
+		return platform.NewPage[*RelatedProject](nil, 0, limit, offset), nil
+	}
+	related, err := s.store.Related(ctx, id, limit, offset)
+	if err != nil {
+		return platform.Page[*RelatedProject]{}, err
+	}
+	total, err := s.store.CountRelated(ctx, id)
+	if err != nil {
+		return platform.Page[*RelatedProject]{}, err
+	}
+	return platform.NewPage(related, total, limit, offset), nil
+}
+
+// Update applies the set fields of req. Deactivating an active project that
+// still has live resources returns ErrHasLiveResources unless force is set,
+// since its infrastructure would keep running unseen. Publishes
+// project.updated.
+func (s *Service) Update(ctx context.Context, id string, req UpdateProjectRequest, force bool) (*Project, error) {
+	if req.Active != nil && !*req.Active && !force {
+		current, err := s.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if current.Active && current.Status.HasLiveResources() {
+			return nil, fmt.Errorf("%w: status is %s", ErrHasLiveResources, current.Status)
+		}
+	}
+
+	project, err := s.update(ctx, id, req)
 	if err != nil {
 		if errors.Is(err, ErrInvalidProjectID) {
 			return nil, err
@@ -135,8 +678,115 @@ func (s *Service) Update(ctx context.Context, id string, req UpdateProjectReques
 	return project, nil
 }
 
+// insert stores a new project, first making sure its name is free when
+// names must be unique, and reports project.created unless it is deferred.
+func (s *Service) insert(ctx context.Context, req CreateProjectRequest) (*Project, error) {
+	lockName := ""
+	if s.uniqueNames {
+		lockName = req.Name
+	}
+
+	var project *Project
+	err := s.commitQuota(ctx, lockName, req.OrgID, func(tx projectStore) ([]Event, error) {
+		if s.uniqueNames {
+			if err := checkNameFree(ctx, tx, req.Name, ""); err != nil {
+				return nil, err
+			}
+		}
+		if err := s.checkQuota(ctx, tx, req.OrgID); err != nil {
+			return nil, err
+		}
+		var err error
+		project, err = tx.Create(ctx, req)
+		if err != nil || s.deferCreateEvents {
+			return nil, err
+		}
+		return []Event{newEvent(EventCreated, project, nil)}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return project, nil
+}
+
+// update applies req to project id, first making sure a new name is free
+// when names must be unique.
+func (s *Service) update(ctx context.Context, id string, req UpdateProjectRequest) (*Project, error) {
+	lockName := ""
+	if s.uniqueNames && req.Name != nil {
+		lockName = *req.Name
+	}
+
+	var project *Project
+	err := s.commit(ctx, lockName, func(tx projectStore) ([]Event, error) {
+		if lockName != "" {
+			if err := checkNameFree(ctx, tx, lockName, id); err != nil {
+				return nil, err
+			}
+		}
+		var err error
+		project, err = tx.Update(ctx, id, req)
+		if err != nil {
+			return nil, err
+		}
+		return []Event{newEvent(EventUpdated, project, nil)}, nil
+	})
+	return project, err
+}
+
+// checkNameFree returns ErrNameExists if a project other than exceptID is
+// already called name.
+func checkNameFree(ctx context.Context, store projectStore, name, exceptID string) error {
+	taken, err := store.NameExists(ctx, name, exceptID)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return fmt.Errorf("%w: %q", ErrNameExists, name)
+	}
+	return nil
+}
+
+// BulkLabel adds and removes labels on every project matching the selector
+// in one statement, publishing project.labels_changed for each project it
+// changes. The selector must not be empty, and a key may not be both added
+// and removed.
+func (s *Service) BulkLabel(ctx context.Context, req BulkLabelRequest) (*BulkLabelResult, error) {
+	if err := s.validate.Struct(req); err != nil {
+		return nil, err
+	}
+	if req.Selector.IsEmpty() {
+		return nil, ErrEmptySelector
+	}
+	if len(req.Add) == 0 && len(req.Remove) == 0 {
+		return nil, fmt.Errorf("%w: nothing to add or remove", ErrInvalidLabelOp)
+	}
+	for _, key := range req.Remove {
+		if _, ok := req.Add[key]; ok {
+			return nil, fmt.Errorf("%w: %q is both added and removed", ErrInvalidLabelOp, key)
+		}
+	}
+
+	var changed []string
+	err := s.commit(ctx, "", func(store projectStore) ([]Event, error) {
+		var err error
+		changed, err = store.UpdateLabels(ctx, req.Selector, req.Add, req.Remove)
+		return labelEvents(changed), err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &BulkLabelResult{Affected: int64(len(changed))}, nil
+}
+
+// Delete soft-deletes a project and publishes project.deleted.
 func (s *Service) Delete(ctx context.Context, id string) error {
-	err := s.store.Delete(ctx, id)
+	err := s.commit(ctx, "", func(store projectStore) ([]Event, error) {
+		if err := store.Delete(ctx, id); err != nil {
+			return nil, err
+		}
+		return []Event{idEvent(EventDeleted, id)}, nil
+	})
 	if err != nil {
 		if errors.Is(err, ErrInvalidProjectID) {
 			return err
@@ -149,17 +799,154 @@ func (s *Service) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+// withProvisionDefaults returns a copy of params with the plugin defaulted.
+// Pure function.
+func withProvisionDefaults(params *ProvisionParams) *ProvisionParams {
+	out := ProvisionParams{Plugin: DefaultProvisionPlugin}
+	if params != nil {
+		out = *params
+		if out.Plugin == "" {
+			out.Plugin = DefaultProvisionPlugin
+		}
+	}
+	return &out
+}
+
+// ValidateCreate reports every invalid field of req at once. A request whose
+// only problem is the unix name format yields ErrInvalidUnixName instead.
+// Either way, a rejected unix name comes with a valid one to suggest, if
+// one can be derived; see platform.Suggestions.
+// A description template that does not render yields
+// ErrInvalidDescriptionTemplate, a reserved unix name ErrReservedUnixName,
+// and a template not known to exist ErrUnknownTemplate. A valid request
+// naming a plugin or node outside its allowlist yields ErrPluginNotAllowed
+// or ErrNodeNotAllowed, and one its plugin would refuse, e.g. for a unix name its backend cannot
+// name a resource after, ErrPluginRejected.
+// Defaults are applied first, as Create would.
+func (s *Service) ValidateCreate(req CreateProjectRequest) error {
+	return s.validateCreate(s.applyDefaults(req))
+}
+
 func (s *Service) validateCreate(req CreateProjectRequest) error {
-	if err := s.validate.Struct(req); err != nil {
-		var validationErrors validator.ValidationErrors
-		if errors.As(err, &validationErrors) {
-			for _, fieldErr := range validationErrors {
-				if fieldErr.Field() == "UnixName" && fieldErr.Tag() == "unix_name" {
-					return ErrInvalidUnixName
-				}
-			}
+	err := s.validate.Struct(req)
+	if err == nil {
+		if problems := s.createProblems(req); len(problems) > 0 {
+			return problems[0].err
 		}
+		return nil
+	}
+
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
 		return err
 	}
+	if len(validationErrors) == 1 {
+		fieldErr := validationErrors[0]
+		if fieldErr.StructField() == "UnixName" && fieldErr.Tag() == "unix_name" {
+			err = ErrInvalidUnixName
+		}
+	}
+	return s.withUnixNameSuggestion(err, validationErrors, req)
+}
+
+// checkPluginAllowed returns ErrPluginNotAllowed unless new provisioning may
+// use the named plugin.
+func (s *Service) checkPluginAllowed(name string) error {
+	if s.allowedPlugins == nil {
+		return nil
+	}
+	if _, ok := s.allowedPlugins[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrPluginNotAllowed, name)
+	}
+	return nil
+}
+
+// checkPluginRequest returns ErrPluginRejected if the plugin req would be
+// provisioned with refuses its request, as plugin.ValidateRequest tells.
+// An unknown plugin, or provisioning being disabled, is left for
+// provisioning to report.
+func (s *Service) checkPluginRequest(req CreateProjectRequest, params *ProvisionParams) error {
+	if s.provisioningDisabled || s.registry == nil {
+		return nil
+	}
+	p, err := s.registry.Get(params.Plugin)
+	if err != nil {
+		return nil
+	}
+	err = plugin.ValidateRequest(p, plugin.ProvisionRequest{
+		ProjectName: req.Name,
+		UnixName:    req.UnixName,
+		Template:    params.Template,
+		Resources:   params.Resources,
+		Node:        params.Node,
+		Idempotent:  params.Idempotent,
+		SSHKeys:     params.SSHKeys,
+		Tags:        tagsFor(req.Labels, s.tagLabels),
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrPluginRejected, err)
+	}
+	return nil
+}
+
+// rejectedField returns the create request field behind the provisioning
+// request field a plugin rejected in err. Pure function.
+func rejectedField(err error) string {
+	var reqErr *plugin.RequestError
+	if !errors.As(err, &reqErr) {
+		return "provision_params"
+	}
+	switch reqErr.Field {
+	case "project_name":
+		return "name"
+	case "unix_name":
+		return "unix_name"
+	case "tags":
+		return "labels"
+	default:
+		return "provision_params." + reqErr.Field
+	}
+}
+
+// checkUnixNameReserved returns ErrReservedUnixName if new projects may
+// not take the unix name.
+func (s *Service) checkUnixNameReserved(name string) error {
+	if _, ok := s.reservedUnixNames[strings.ToLower(name)]; ok {
+		return fmt.Errorf("%w: %s", ErrReservedUnixName, name)
+	}
+	return nil
+}
+
+// checkTemplateExists returns ErrUnknownTemplate unless the named template
+// is known to exist. Leaving the template to the plugin is always allowed.
+func (s *Service) checkTemplateExists(name string) error {
+	if name == "" || s.templates == nil {
+		return nil
+	}
+	if _, ok := s.templates[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownTemplate, name)
+	}
 	return nil
 }
+
+// checkNodeAllowed returns ErrNodeNotAllowed unless a project may be pinned
+// to the named node. Not pinning one is always allowed.
+func (s *Service) checkNodeAllowed(name string) error {
+	if name == "" || s.allowedNodes == nil {
+		return nil
+	}
+	if _, ok := s.allowedNodes[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrNodeNotAllowed, name)
+	}
+	return nil
+}
+
+// jsonFieldName reports validation errors under the field's JSON name.
+// Pure function.
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
@@ -3,17 +3,54 @@ package projects
 import (
 	"context"
 	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/go-playground/validator/v10"
 	"github.com/searge/quokka/internal/plugin"
 )
 
 type mockStore struct {
 	createFn func(context.Context, CreateProjectRequest) (*Project, error)
 	getByID  func(context.Context, string) (*Project, error)
-	listFn   func(context.Context, int32, int32) ([]*Project, error)
+	getByIDs func(context.Context, []string) ([]*Project, error)
+	listFn   func(context.Context, ListFilter) ([]*Project, error)
+	totalFn  func(context.Context, ListFilter) (int64, error)
+	relFn    func(context.Context, string, int32, int32) ([]*RelatedProject, error)
+	relTotal func(context.Context, string) (int64, error)
 	updateFn func(context.Context, string, UpdateProjectRequest) (*Project, error)
+	statusFn func(context.Context, string, ProvisionStatus) error
+	labelsFn func(context.Context, LabelSelector, map[string]string, []string) ([]string, error)
+	selectFn func(context.Context, LabelSelector, int32) ([]*Project, error)
+	paramsFn func(context.Context, string, *ProvisionParams) error
+	syncFn   func(context.Context, string, map[string]string, map[string]string) ([]string, error)
 	deleteFn func(context.Context, string) error
+	countFn  func(context.Context) (map[ProvisionStatus]int64, error)
+	activeFn func(context.Context, string) (int64, error)
+	nameFn   func(context.Context, string, string) (bool, error)
+	lockFn   func(context.Context, string)
+	orgLock  func(context.Context, string)
+	atomicFn func()
+	resFn    func(context.Context, string, string, string) error
+	netFn    func(context.Context, string, *plugin.NetworkInfo) error
+	transFn  func(context.Context, string, ProvisionStatus, ProvisionStatus) (bool, error)
+	staleFn  func(context.Context, string, ProvisionStatus, ProvisionStatus, time.Time) (bool, error)
+	unixFn   func(context.Context, string) (bool, error)
+	xferFn   func(context.Context, string, string, string) (*Project, error)
+	enqFn    func(context.Context, []Event) error
+}
+
+func (m mockStore) ExistsByUnixName(ctx context.Context, unixName string) (bool, error) {
+	if m.unixFn == nil {
+		return false, nil
+	}
+	return m.unixFn(ctx, unixName)
 }
 
 func (m mockStore) Create(ctx context.Context, req CreateProjectRequest) (*Project, error) {
@@ -30,11 +67,39 @@ func (m mockStore) GetByID(ctx context.Context, id string) (*Project, error) {
 	return m.getByID(ctx, id)
 }
 
-func (m mockStore) List(ctx context.Context, limit, offset int32) ([]*Project, error) {
+func (m mockStore) GetByIDs(ctx context.Context, ids []string) ([]*Project, error) {
+	if m.getByIDs == nil {
+		return nil, errors.New("getByIDs is not set")
+	}
+	return m.getByIDs(ctx, ids)
+}
+
+func (m mockStore) List(ctx context.Context, filter ListFilter) ([]*Project, error) {
 	if m.listFn == nil {
 		return nil, nil
 	}
-	return m.listFn(ctx, limit, offset)
+	return m.listFn(ctx, filter)
+}
+
+func (m mockStore) Related(ctx context.Context, id string, limit, offset int32) ([]*RelatedProject, error) {
+	if m.relFn == nil {
+		return nil, errors.New("relFn is not set")
+	}
+	return m.relFn(ctx, id, limit, offset)
+}
+
+func (m mockStore) Count(ctx context.Context, filter ListFilter) (int64, error) {
+	if m.totalFn == nil {
+		return 0, nil
+	}
+	return m.totalFn(ctx, filter)
+}
+
+func (m mockStore) CountRelated(ctx context.Context, id string) (int64, error) {
+	if m.relTotal == nil {
+		return 0, nil
+	}
+	return m.relTotal(ctx, id)
 }
 
 func (m mockStore) Update(ctx context.Context, id string, req UpdateProjectRequest) (*Project, error) {
@@ -44,6 +109,125 @@ func (m mockStore) Update(ctx context.Context, id string, req UpdateProjectReque
 	return m.updateFn(ctx, id, req)
 }
 
+func (m mockStore) Transfer(ctx context.Context, id, fromOrgID, toOrgID string) (*Project, error) {
+	if m.xferFn == nil {
+		return nil, errors.New("xferFn is not set")
+	}
+	return m.xferFn(ctx, id, fromOrgID, toOrgID)
+}
+
+func (m mockStore) SetResource(ctx context.Context, id, resourceID, node string) error {
+	if m.resFn == nil {
+		return nil
+	}
+	return m.resFn(ctx, id, resourceID, node)
+}
+
+func (m mockStore) SetNetwork(ctx context.Context, id string, network *plugin.NetworkInfo) error {
+	if m.netFn == nil {
+		return nil
+	}
+	return m.netFn(ctx, id, network)
+}
+
+func (m mockStore) SetStatus(ctx context.Context, id string, status ProvisionStatus) error {
+	if m.statusFn == nil {
+		return nil
+	}
+	return m.statusFn(ctx, id, status)
+}
+
+func (m mockStore) TransitionStatus(ctx context.Context, id string, from, to ProvisionStatus) (bool, error) {
+	if m.transFn == nil {
+		return false, errors.New("transFn is not set")
+	}
+	return m.transFn(ctx, id, from, to)
+}
+
+func (m mockStore) TransitionStaleStatus(ctx context.Context, id string, from, to ProvisionStatus, changedBefore time.Time) (bool, error) {
+	if m.staleFn == nil {
+		return false, errors.New("staleFn is not set")
+	}
+	return m.staleFn(ctx, id, from, to, changedBefore)
+}
+
+func (m mockStore) CountByStatus(ctx context.Context) (map[ProvisionStatus]int64, error) {
+	if m.countFn == nil {
+		return nil, errors.New("countFn is not set")
+	}
+	return m.countFn(ctx)
+}
+
+func (m mockStore) CountActiveByOrg(ctx context.Context, orgID string) (int64, error) {
+	if m.activeFn == nil {
+		return 0, errors.New("activeFn is not set")
+	}
+	return m.activeFn(ctx, orgID)
+}
+
+func (m mockStore) LockOrganization(ctx context.Context, orgID string) error {
+	if m.orgLock != nil {
+		m.orgLock(ctx, orgID)
+	}
+	return nil
+}
+
+func (m mockStore) NameExists(ctx context.Context, name, exceptID string) (bool, error) {
+	if m.nameFn == nil {
+		return false, errors.New("nameFn is not set")
+	}
+	return m.nameFn(ctx, name, exceptID)
+}
+
+func (m mockStore) WithNameLock(ctx context.Context, name string, fn func(projectStore) error) error {
+	if m.lockFn != nil {
+		m.lockFn(ctx, name)
+	}
+	return fn(m)
+}
+
+func (m mockStore) Atomically(_ context.Context, fn func(projectStore) error) error {
+	if m.atomicFn != nil {
+		m.atomicFn()
+	}
+	return fn(m)
+}
+
+func (m mockStore) EnqueueEvents(ctx context.Context, events ...Event) error {
+	if m.enqFn == nil {
+		return errors.New("enqFn is not set")
+	}
+	return m.enqFn(ctx, events)
+}
+
+func (m mockStore) UpdateLabels(ctx context.Context, sel LabelSelector, add map[string]string, remove []string) ([]string, error) {
+	if m.labelsFn == nil {
+		return nil, errors.New("labelsFn is not set")
+	}
+	return m.labelsFn(ctx, sel, add, remove)
+}
+
+func (m mockStore) ListSelected(ctx context.Context, sel LabelSelector, limit int32) ([]*Project, error) {
+	if m.selectFn == nil {
+		return nil, errors.New("selectFn is not set")
+	}
+	return m.selectFn(ctx, sel, limit)
+}
+
+func (m mockStore) SetProvisionParams(ctx context.Context, id string, params *ProvisionParams) error {
+	if m.paramsFn == nil {
+		return nil
+	}
+	return m.paramsFn(ctx, id, params)
+}
+
+func (m mockStore) SyncOrgLabels(ctx context.Context, orgID string, previous, current map[string]string) ([]string, error) {
+	if m.syncFn == nil {
+		return nil, errors.New("syncFn is not set")
+	}
+	return m.syncFn(ctx, orgID, previous, current)
+}
+
 func (m mockStore) Delete(ctx context.Context, id string) error {
 	if m.deleteFn == nil {
 		return nil
@@ -64,6 +248,7 @@ func (m mockRegistry) Get(name string) (plugin.Plugin, error) {
 
 type mockPlugin struct {
 	provisionFn func(context.Context, plugin.ProvisionRequest) (*plugin.ProvisionResult, error)
+	statusFn    func(context.Context, string) (*plugin.StatusResult, error)
 }
 
 func (m mockPlugin) Name() string { return "proxmox" }
@@ -78,8 +263,11 @@ func (m mockPlugin) Provision(ctx context.Context, req plugin.ProvisionRequest)
 	return m.provisionFn(ctx, req)
 }
 
-func (m mockPlugin) Status(context.Context, string) (*plugin.StatusResult, error) {
-	return &plugin.StatusResult{Status: "running"}, nil
+func (m mockPlugin) Status(ctx context.Context, resourceID string) (*plugin.StatusResult, error) {
+	if m.statusFn == nil {
+		return &plugin.StatusResult{Status: "running"}, nil
+	}
+	return m.statusFn(ctx, resourceID)
 }
 
 func (m mockPlugin) Deprovision(context.Context, string) error { return nil }
@@ -120,6 +308,31 @@ func TestServiceCreatePropagatesErrProjectExists(t *testing.T) {
 	}
 }
 
+func TestServiceCreateDoesNotProvisionOnConflict(t *testing.T) {
+	s := newService(
+		mockStore{
+			createFn: func(context.Context, CreateProjectRequest) (*Project, error) {
+				return nil, ErrProjectExists
+			},
+		},
+		mockRegistry{
+			getFn: func(string) (plugin.Plugin, error) {
+				return mockPlugin{
+					provisionFn: func(context.Context, plugin.ProvisionRequest) (*plugin.ProvisionResult, error) {
+						t.Fatal("a create that lost the unix_name race must not provision")
+						return nil, nil
+					},
+				}, nil
+			},
+		},
+		nil,
+	)
+
+	if _, err := s.Create(context.Background(), CreateProjectRequest{Name: "Alpha", UnixName: "alpha"}); !errors.Is(err, ErrProjectExists) {
+		t.Fatalf("expected ErrProjectExists, got %v", err)
+	}
+}
+
 func TestServiceCreateCallsProvisionWithProjectData(t *testing.T) {
 	var gotReq plugin.ProvisionRequest
 
@@ -127,8 +340,9 @@ func TestServiceCreateCallsProvisionWithProjectData(t *testing.T) {
 		mockStore{
 			createFn: func(context.Context, CreateProjectRequest) (*Project, error) {
 				return &Project{
-					ID:   "p-123",
-					Name: "Alpha",
+					ID:       "p-123",
+					Name:     "Alpha",
+					UnixName: "alpha",
 				}, nil
 			},
 		},
@@ -152,7 +366,7 @@ func TestServiceCreateCallsProvisionWithProjectData(t *testing.T) {
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
-	if gotReq.ProjectID != "p-123" || gotReq.ProjectName != "Alpha" {
+	if gotReq.ProjectID != "p-123" || gotReq.ProjectName != "Alpha" || gotReq.UnixName != "alpha" {
 		t.Fatalf("unexpected provision request: %+v", gotReq)
 	}
 }
@@ -173,3 +387,856 @@ func TestServiceGetPropagatesInvalidProjectID(t *testing.T) {
 		t.Fatalf("expected ErrInvalidProjectID, got %v", err)
 	}
 }
+
+func TestServiceStatsCountConcurrentCreates(t *testing.T) {
+	const creates = 50
+	failProvision := errors.New("cli failed")
+
+	s := newService(
+		mockStore{
+			createFn: func(_ context.Context, req CreateProjectRequest) (*Project, error) {
+				return &Project{ID: req.UnixName, Name: req.Name}, nil
+			},
+		},
+		mockRegistry{
+			getFn: func(string) (plugin.Plugin, error) {
+				return mockPlugin{
+					provisionFn: func(_ context.Context, req plugin.ProvisionRequest) (*plugin.ProvisionResult, error) {
+						// Fail every project whose ID ends in an odd digit.
+						if last := req.ProjectID[len(req.ProjectID)-1]; (last-'0')%2 == 1 {
+							return nil, failProvision
+						}
+						return &plugin.ProvisionResult{ResourceID: "r-" + req.ProjectID}, nil
+					},
+				}, nil
+			},
+		},
+		nil,
+	)
+
+	var wg sync.WaitGroup
+	for i := range creates {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := s.Create(context.Background(), CreateProjectRequest{
+				Name:     "Project",
+				UnixName: fmt.Sprintf("project-%d", i),
+			})
+			if err != nil {
+				t.Errorf("Create() error = %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	stats := s.Stats()
+	if stats.Created != creates {
+		t.Errorf("Created = %d, want %d", stats.Created, creates)
+	}
+	if stats.ProvisionSucceeded != creates/2 || stats.ProvisionFailed != creates/2 {
+		t.Errorf("succeeded/failed = %d/%d, want %d/%d", stats.ProvisionSucceeded, stats.ProvisionFailed, creates/2, creates/2)
+	}
+	if stats.ProvisionsInFlight != 0 {
+		t.Errorf("ProvisionsInFlight = %d, want 0", stats.ProvisionsInFlight)
+	}
+}
+
+func TestServiceGetManyReportsPartialMatches(t *testing.T) {
+	var gotIDs []string
+	s := newService(
+		mockStore{
+			getByIDs: func(_ context.Context, ids []string) ([]*Project, error) {
+				gotIDs = ids
+				// Rows come back in database order, not request order.
+				return []*Project{{ID: "c"}, {ID: "a"}}, nil
+			},
+		},
+		mockRegistry{},
+		nil,
+	)
+
+	res, err := s.GetMany(context.Background(), []string{"a", "b", "a", "c"})
+	if err != nil {
+		t.Fatalf("GetMany() error = %v", err)
+	}
+
+	if len(gotIDs) != 3 {
+		t.Errorf("expected duplicates to be collapsed before querying, got %v", gotIDs)
+	}
+	if len(res.Projects) != 2 || res.Projects[0].ID != "a" || res.Projects[1].ID != "c" {
+		t.Errorf("expected projects a, c in request order, got %+v", res.Projects)
+	}
+	if len(res.NotFound) != 1 || res.NotFound[0] != "b" {
+		t.Errorf("expected not_found [b], got %v", res.NotFound)
+	}
+}
+
+func TestServiceGetManyMatchesIDsInAnyCase(t *testing.T) {
+	const id = "2a4e6b16-8a62-4d57-a05b-9f59248dbdb2"
+	var gotIDs []string
+	s := newService(
+		mockStore{
+			getByIDs: func(_ context.Context, ids []string) ([]*Project, error) {
+				gotIDs = ids
+				return []*Project{{ID: id}}, nil
+			},
+		},
+		mockRegistry{},
+		nil,
+	)
+
+	res, err := s.GetMany(context.Background(), []string{strings.ToUpper(id), id})
+	if err != nil {
+		t.Fatalf("GetMany() error = %v", err)
+	}
+
+	if len(gotIDs) != 1 || gotIDs[0] != id {
+		t.Errorf("queried %v, want the canonical ID once", gotIDs)
+	}
+	if len(res.Projects) != 1 || res.Projects[0].ID != id {
+		t.Errorf("expected the project, got %+v", res.Projects)
+	}
+	if len(res.NotFound) != 0 {
+		t.Errorf("expected nothing not found, got %v", res.NotFound)
+	}
+}
+
+func TestServiceGetManyRejectsTooManyIDs(t *testing.T) {
+	s := newService(mockStore{}, mockRegistry{}, nil)
+
+	ids := make([]string, MaxBatchIDs+1)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("id-%d", i)
+	}
+
+	if _, err := s.GetMany(context.Background(), ids); !errors.Is(err, ErrTooManyIDs) {
+		t.Fatalf("expected ErrTooManyIDs, got %v", err)
+	}
+}
+
+func TestServiceGetManyPropagatesInvalidProjectID(t *testing.T) {
+	s := newService(
+		mockStore{
+			getByIDs: func(_ context.Context, ids []string) ([]*Project, error) {
+				for _, id := range ids {
+					if _, err := parseProjectID(id, 0); err != nil {
+						return nil, fmt.Errorf("%w: %q", err, id)
+					}
+				}
+				return nil, nil
+			},
+		},
+		mockRegistry{},
+		nil,
+	)
+
+	_, err := s.GetMany(context.Background(), []string{"2a4e6b16-8a62-4d57-a05b-9f59248dbdb2", "nope"})
+	if !errors.Is(err, ErrInvalidProjectID) {
+		t.Fatalf("expected ErrInvalidProjectID, got %v", err)
+	}
+}
+
+// storedProjectStore persists a single project the way Store does, including
+// the JSON round trip of its provisioning parameters.
+func storedProjectStore(t *testing.T) mockStore {
+	t.Helper()
+	var row []byte
+	return mockStore{
+		createFn: func(_ context.Context, req CreateProjectRequest) (*Project, error) {
+			raw, err := encodeProvisionParams(req.ProvisionParams)
+			if err != nil {
+				return nil, err
+			}
+			row = raw
+			params, err := decodeProvisionParams(row)
+			if err != nil {
+				return nil, err
+			}
+			return &Project{ID: "p-1", Name: req.Name, ProvisionParams: params}, nil
+		},
+		getByID: func(context.Context, string) (*Project, error) {
+			params, err := decodeProvisionParams(row)
+			if err != nil {
+				return nil, err
+			}
+			return &Project{ID: "p-1", Name: "Alpha", ProvisionParams: params}, nil
+		},
+	}
+}
+
+func TestServiceReprovisionReplaysStoredRequest(t *testing.T) {
+	var requests []plugin.ProvisionRequest
+	var plugins []string
+
+	s := newService(
+		storedProjectStore(t),
+		mockRegistry{
+			getFn: func(name string) (plugin.Plugin, error) {
+				plugins = append(plugins, name)
+				return mockPlugin{
+					provisionFn: func(_ context.Context, req plugin.ProvisionRequest) (*plugin.ProvisionResult, error) {
+						requests = append(requests, req)
+						return &plugin.ProvisionResult{ResourceID: "r-1", Status: "ok"}, nil
+					},
+				}, nil
+			},
+		},
+		nil,
+	)
+
+	project, err := s.Create(context.Background(), CreateProjectRequest{
+		Name:     "Alpha",
+		UnixName: "alpha",
+		ProvisionParams: &ProvisionParams{
+			Plugin:    "proxmox",
+			Template:  "debian-12",
+			Resources: map[string]interface{}{"cores": 4, "storage": "ssd"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if project.ProvisionParams == nil || project.ProvisionParams.Template != "debian-12" {
+		t.Fatalf("expected stored provision params on the project, got %+v", project.ProvisionParams)
+	}
+
+	if _, err := s.Reprovision(context.Background(), project.ID); err != nil {
+		t.Fatalf("Reprovision() error = %v", err)
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 provision calls, got %d", len(requests))
+	}
+	if !reflect.DeepEqual(requests[0], requests[1]) {
+		t.Errorf("reprovision request differs from original:\n got %+v\nwant %+v", requests[1], requests[0])
+	}
+	if requests[1].Template != "debian-12" || requests[1].Resources["storage"] != "ssd" {
+		t.Errorf("unexpected replayed request: %+v", requests[1])
+	}
+	if plugins[1] != "proxmox" {
+		t.Errorf("reprovision used plugin %q, want proxmox", plugins[1])
+	}
+}
+
+func TestServiceCreateStoresDefaultProvisionPlugin(t *testing.T) {
+	var stored *ProvisionParams
+
+	s := newService(
+		mockStore{
+			createFn: func(_ context.Context, req CreateProjectRequest) (*Project, error) {
+				stored = req.ProvisionParams
+				return &Project{ID: "p-1", Name: req.Name}, nil
+			},
+		},
+		mockRegistry{
+			getFn: func(string) (plugin.Plugin, error) {
+				return nil, plugin.ErrPluginNotFound
+			},
+		},
+		nil,
+	)
+
+	if _, err := s.Create(context.Background(), CreateProjectRequest{Name: "Alpha", UnixName: "alpha"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if stored == nil || stored.Plugin != DefaultProvisionPlugin {
+		t.Fatalf("expected stored plugin %q, got %+v", DefaultProvisionPlugin, stored)
+	}
+}
+
+func TestServiceReprovisionWrapsPluginFailure(t *testing.T) {
+	cliErr := errors.New("cli failed")
+
+	s := newService(
+		mockStore{
+			getByID: func(context.Context, string) (*Project, error) {
+				return &Project{ID: "p-1", Name: "Alpha"}, nil
+			},
+		},
+		mockRegistry{
+			getFn: func(string) (plugin.Plugin, error) {
+				return mockPlugin{
+					provisionFn: func(context.Context, plugin.ProvisionRequest) (*plugin.ProvisionResult, error) {
+						return nil, cliErr
+					},
+				}, nil
+			},
+		},
+		nil,
+	)
+
+	_, err := s.Reprovision(context.Background(), "p-1")
+	if !errors.Is(err, ErrProvisionFailed) || !errors.Is(err, cliErr) {
+		t.Fatalf("expected ErrProvisionFailed wrapping the plugin error, got %v", err)
+	}
+	if got := s.Stats().ProvisionFailed; got != 1 {
+		t.Errorf("ProvisionFailed = %d, want 1", got)
+	}
+}
+
+func TestServiceProvisionRecordsStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		provErr error
+		want    []ProvisionStatus
+	}{
+		{name: "success", want: []ProvisionStatus{StatusProvisioning, StatusProvisioned}},
+		{name: "failure", provErr: errors.New("cli failed"), want: []ProvisionStatus{StatusProvisioning, StatusFailed}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []ProvisionStatus
+			s := newService(
+				mockStore{
+					createFn: func(context.Context, CreateProjectRequest) (*Project, error) {
+						return &Project{ID: "p-1", Name: "Alpha", Status: StatusPending}, nil
+					},
+					statusFn: func(_ context.Context, id string, status ProvisionStatus) error {
+						if id != "p-1" {
+							t.Errorf("status set on %q, want p-1", id)
+						}
+						got = append(got, status)
+						return nil
+					},
+				},
+				mockRegistry{
+					getFn: func(string) (plugin.Plugin, error) {
+						return mockPlugin{
+							provisionFn: func(context.Context, plugin.ProvisionRequest) (*plugin.ProvisionResult, error) {
+								if tt.provErr != nil {
+									return nil, tt.provErr
+								}
+								return &plugin.ProvisionResult{ResourceID: "r-1"}, nil
+							},
+						}, nil
+					},
+				},
+				nil,
+			)
+
+			project, err := s.Create(context.Background(), CreateProjectRequest{Name: "Alpha", UnixName: "alpha"})
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("recorded statuses %v, want %v", got, tt.want)
+			}
+			if project.Status != tt.want[len(tt.want)-1] {
+				t.Errorf("returned status %q, want %q", project.Status, tt.want[len(tt.want)-1])
+			}
+		})
+	}
+}
+
+func TestServiceBulkLabelAddsAndRemovesOnSelectedProjects(t *testing.T) {
+	var (
+		gotSel    LabelSelector
+		gotAdd    map[string]string
+		gotRemove []string
+	)
+	s := newService(
+		mockStore{
+			labelsFn: func(_ context.Context, sel LabelSelector, add map[string]string, remove []string) ([]string, error) {
+				gotSel, gotAdd, gotRemove = sel, add, remove
+				return []string{"p-1", "p-2", "p-3"}, nil
+			},
+		},
+		mockRegistry{},
+		nil,
+	)
+
+	res, err := s.BulkLabel(context.Background(), BulkLabelRequest{
+		Selector: LabelSelector{Template: "debian-11", MatchLabels: map[string]string{"env": "prod"}},
+		Add:      map[string]string{"migration": "debian-12"},
+		Remove:   []string{"legacy"},
+	})
+	if err != nil {
+		t.Fatalf("BulkLabel() error = %v", err)
+	}
+	if res.Affected != 3 {
+		t.Errorf("Affected = %d, want 3", res.Affected)
+	}
+	if gotSel.Template != "debian-11" || gotSel.MatchLabels["env"] != "prod" {
+		t.Errorf("selector not passed through: %+v", gotSel)
+	}
+	if gotAdd["migration"] != "debian-12" || len(gotRemove) != 1 || gotRemove[0] != "legacy" {
+		t.Errorf("label changes not passed through: add=%v remove=%v", gotAdd, gotRemove)
+	}
+}
+
+func TestServiceBulkLabelRejectsInvalidRequests(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     BulkLabelRequest
+		wantErr error
+	}{
+		{
+			name:    "empty selector",
+			req:     BulkLabelRequest{Add: map[string]string{"team": "infra"}},
+			wantErr: ErrEmptySelector,
+		},
+		{
+			name:    "no changes",
+			req:     BulkLabelRequest{Selector: LabelSelector{Template: "debian-11"}},
+			wantErr: ErrInvalidLabelOp,
+		},
+		{
+			name: "key added and removed",
+			req: BulkLabelRequest{
+				Selector: LabelSelector{Template: "debian-11"},
+				Add:      map[string]string{"team": "infra"},
+				Remove:   []string{"team"},
+			},
+			wantErr: ErrInvalidLabelOp,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newService(mockStore{}, mockRegistry{}, nil)
+			if _, err := s.BulkLabel(context.Background(), tt.req); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestServiceBulkLabelValidatesLabelKeys(t *testing.T) {
+	s := newService(mockStore{}, mockRegistry{}, nil)
+
+	_, err := s.BulkLabel(context.Background(), BulkLabelRequest{
+		Selector: LabelSelector{MatchLabels: map[string]string{"Bad Key": "x"}},
+		Add:      map[string]string{"-team": "infra"},
+		Remove:   []string{"ok", "UPPER"},
+	})
+
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		t.Fatalf("expected validation errors, got %v", err)
+	}
+	if len(verrs) != 3 {
+		t.Errorf("expected 3 invalid keys, got %d: %v", len(verrs), verrs)
+	}
+}
+
+func TestServiceCreateEnforcesPluginAllowlist(t *testing.T) {
+	tests := []struct {
+		name    string
+		plugin  string
+		wantErr error
+	}{
+		{name: "default plugin", plugin: ""},
+		{name: "allowed plugin", plugin: "proxmox"},
+		{name: "registered but not allowed", plugin: "fake", wantErr: ErrPluginNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stored := false
+			s := newService(
+				mockStore{
+					createFn: func(_ context.Context, req CreateProjectRequest) (*Project, error) {
+						stored = true
+						return &Project{ID: "p-1", Name: req.Name, ProvisionParams: req.ProvisionParams}, nil
+					},
+				},
+				mockRegistry{
+					getFn: func(string) (plugin.Plugin, error) {
+						return mockPlugin{}, nil
+					},
+				},
+				nil,
+				WithAllowedPlugins("proxmox"),
+			)
+
+			_, err := s.Create(context.Background(), CreateProjectRequest{
+				Name:            "Alpha",
+				UnixName:        "alpha",
+				ProvisionParams: &ProvisionParams{Plugin: tt.plugin},
+			})
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("Create() error = %v, want %v", err, tt.wantErr)
+			}
+			if stored != (tt.wantErr == nil) {
+				t.Errorf("project stored = %v, want %v", stored, tt.wantErr == nil)
+			}
+		})
+	}
+}
+
+func TestServiceReprovisionRejectsDisallowedPlugin(t *testing.T) {
+	s := newService(
+		mockStore{
+			getByID: func(context.Context, string) (*Project, error) {
+				return &Project{ID: "p-1", Name: "Alpha", ProvisionParams: &ProvisionParams{Plugin: "fake"}}, nil
+			},
+		},
+		mockRegistry{
+			getFn: func(string) (plugin.Plugin, error) {
+				t.Error("a disallowed plugin must not be looked up for provisioning")
+				return mockPlugin{}, nil
+			},
+		},
+		nil,
+		WithAllowedPlugins("proxmox"),
+	)
+
+	if _, err := s.Reprovision(context.Background(), "p-1"); !errors.Is(err, ErrPluginNotAllowed) {
+		t.Fatalf("expected ErrPluginNotAllowed, got %v", err)
+	}
+}
+
+func TestServiceCreatePassesNodeToPlugin(t *testing.T) {
+	var gotNode string
+	s := newService(
+		mockStore{
+			createFn: func(_ context.Context, req CreateProjectRequest) (*Project, error) {
+				return &Project{ID: "p-1", Name: req.Name, ProvisionParams: req.ProvisionParams}, nil
+			},
+		},
+		mockRegistry{
+			getFn: func(string) (plugin.Plugin, error) {
+				return mockPlugin{
+					provisionFn: func(_ context.Context, req plugin.ProvisionRequest) (*plugin.ProvisionResult, error) {
+						gotNode = req.Node
+						return &plugin.ProvisionResult{ResourceID: "res-1"}, nil
+					},
+				}, nil
+			},
+		},
+		nil,
+		WithAllowedNodes("pve-01", "pve-02"),
+	)
+
+	project, err := s.Create(context.Background(), CreateProjectRequest{
+		Name:            "Alpha",
+		UnixName:        "alpha",
+		ProvisionParams: &ProvisionParams{Node: "pve-02"},
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if gotNode != "pve-02" {
+		t.Errorf("plugin asked for node %q, want pve-02", gotNode)
+	}
+	if project.ProvisionParams.Node != "pve-02" {
+		t.Errorf("stored node = %q, want pve-02", project.ProvisionParams.Node)
+	}
+}
+
+func TestServiceCreatePassesSSHKeysToPlugin(t *testing.T) {
+	const key = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIAABAgMEBQYHCAkKCwwNDg8QERITFBUWFxgZGhscHR4f ops"
+	var gotKeys []string
+	s := newService(
+		mockStore{
+			createFn: func(_ context.Context, req CreateProjectRequest) (*Project, error) {
+				return &Project{ID: "p-1", Name: req.Name, ProvisionParams: req.ProvisionParams}, nil
+			},
+		},
+		mockRegistry{
+			getFn: func(string) (plugin.Plugin, error) {
+				return mockPlugin{
+					provisionFn: func(_ context.Context, req plugin.ProvisionRequest) (*plugin.ProvisionResult, error) {
+						gotKeys = req.SSHKeys
+						return &plugin.ProvisionResult{ResourceID: "res-1"}, nil
+					},
+				}, nil
+			},
+		},
+		nil,
+	)
+
+	_, err := s.Create(context.Background(), CreateProjectRequest{
+		Name:            "Alpha",
+		UnixName:        "alpha",
+		ProvisionParams: &ProvisionParams{SSHKeys: []string{key}},
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if !slices.Equal(gotKeys, []string{key}) {
+		t.Errorf("plugin got keys %v, want %v", gotKeys, []string{key})
+	}
+}
+
+func TestServiceUpdateBlocksDeactivatingLiveResources(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  ProvisionStatus
+		force   bool
+		wantErr error
+	}{
+		{name: "provisioned", status: StatusProvisioned, wantErr: ErrHasLiveResources},
+		{name: "provisioning", status: StatusProvisioning, wantErr: ErrHasLiveResources},
+		{name: "provisioned forced", status: StatusProvisioned, force: true},
+		{name: "failed", status: StatusFailed},
+		{name: "pending", status: StatusPending},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated := false
+			s := newService(
+				mockStore{
+					getByID: func(context.Context, string) (*Project, error) {
+						return &Project{ID: "p-1", Active: true, Status: tt.status}, nil
+					},
+					updateFn: func(_ context.Context, id string, req UpdateProjectRequest) (*Project, error) {
+						updated = true
+						return &Project{ID: id, Active: *req.Active, Status: tt.status}, nil
+					},
+				},
+				mockRegistry{},
+				nil,
+			)
+
+			inactive := false
+			_, err := s.Update(context.Background(), "p-1", UpdateProjectRequest{Active: &inactive}, tt.force)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("Update() error = %v, want %v", err, tt.wantErr)
+			}
+			if updated != (tt.wantErr == nil) {
+				t.Errorf("store updated = %v, want %v", updated, tt.wantErr == nil)
+			}
+		})
+	}
+}
+
+func TestServiceCreateFollowsUnixNamePolicy(t *testing.T) {
+	dotted := UnixNamePolicy{
+		Pattern:   regexp.MustCompile(`^[a-z]+(\.[a-z]+)*$`),
+		MinLength: 5,
+		MaxLength: 20,
+	}
+
+	tests := []struct {
+		name      string
+		unixName  string
+		defaultOK bool
+		customOK  bool
+	}{
+		{name: "hyphenated", unixName: "team-alpha", defaultOK: true},
+		{name: "dotted", unixName: "team.alpha", customOK: true},
+		{name: "too short for custom", unixName: "abc", defaultOK: true},
+		{name: "too long for custom", unixName: "averyveryverylongteamname", defaultOK: true},
+	}
+
+	create := func(s *Service, unixName string) error {
+		_, err := s.Create(context.Background(), CreateProjectRequest{Name: "Alpha", UnixName: unixName})
+		return err
+	}
+	newTestService := func(opts ...ServiceOption) *Service {
+		return newService(
+			mockStore{
+				createFn: func(_ context.Context, req CreateProjectRequest) (*Project, error) {
+					return &Project{ID: "p-1", Name: req.Name, UnixName: req.UnixName}, nil
+				},
+			},
+			mockRegistry{
+				getFn: func(string) (plugin.Plugin, error) {
+					return nil, plugin.ErrPluginNotFound
+				},
+			},
+			nil,
+			opts...,
+		)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := create(newTestService(), tt.unixName); (err == nil) != tt.defaultOK {
+				t.Errorf("default policy: Create(%q) error = %v, want ok=%v", tt.unixName, err, tt.defaultOK)
+			}
+			if err := create(newTestService(WithUnixNamePolicy(dotted)), tt.unixName); (err == nil) != tt.customOK {
+				t.Errorf("custom policy: Create(%q) error = %v, want ok=%v", tt.unixName, err, tt.customOK)
+			}
+		})
+	}
+}
+
+func TestServiceRefreshCountsCachesStatusCounts(t *testing.T) {
+	calls := 0
+	s := newService(
+		mockStore{
+			countFn: func(context.Context) (map[ProvisionStatus]int64, error) {
+				calls++
+				return map[ProvisionStatus]int64{StatusProvisioned: 4, StatusFailed: 1}, nil
+			},
+		},
+		mockRegistry{},
+		nil,
+	)
+
+	if stats := s.Stats(); stats.ByStatus != nil || stats.CountedAt != nil {
+		t.Fatalf("expected no counts before a refresh, got %+v", stats)
+	}
+	if err := s.RefreshCounts(context.Background()); err != nil {
+		t.Fatalf("RefreshCounts() error = %v", err)
+	}
+
+	stats := s.Stats()
+	s.Stats()
+	if stats.ByStatus[StatusProvisioned] != 4 || stats.ByStatus[StatusFailed] != 1 || stats.CountedAt == nil {
+		t.Errorf("unexpected cached counts: %+v", stats)
+	}
+	if calls != 1 {
+		t.Errorf("expected Stats to serve cached counts, store counted %d times", calls)
+	}
+}
+
+func TestServiceCreateUniqueNames(t *testing.T) {
+	tests := []struct {
+		name        string
+		unique      bool
+		taken       bool
+		wantErr     error
+		wantCreated bool
+	}{
+		{name: "off allows a taken name", unique: false, taken: true, wantCreated: true},
+		{name: "on allows a free name", unique: true, taken: false, wantCreated: true},
+		{name: "on rejects a taken name", unique: true, taken: true, wantErr: ErrNameExists},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var created bool
+			var locked, checked string
+			store := mockStore{
+				createFn: func(_ context.Context, req CreateProjectRequest) (*Project, error) {
+					created = true
+					return &Project{ID: "p-1", Name: req.Name}, nil
+				},
+				lockFn: func(_ context.Context, name string) {
+					locked = name
+				},
+			}
+			if tt.unique {
+				store.nameFn = func(_ context.Context, name, _ string) (bool, error) {
+					checked = name
+					return tt.taken, nil
+				}
+			}
+			s := newService(store, mockRegistry{}, nil, WithUniqueNames(tt.unique))
+
+			_, err := s.Create(context.Background(), CreateProjectRequest{Name: "Billing", UnixName: "billing-2"})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Create() error = %v, want %v", err, tt.wantErr)
+			}
+			if created != tt.wantCreated {
+				t.Errorf("store create called = %v, want %v", created, tt.wantCreated)
+			}
+			if tt.unique && (locked != "Billing" || checked != "Billing") {
+				t.Errorf("locked %q and checked %q, want the name checked under its lock", locked, checked)
+			}
+		})
+	}
+}
+
+func TestServiceUpdateUniqueNamesExcludesItself(t *testing.T) {
+	var exceptID string
+	s := newService(
+		mockStore{
+			nameFn: func(_ context.Context, _, except string) (bool, error) {
+				exceptID = except
+				return true, nil
+			},
+			updateFn: func(context.Context, string, UpdateProjectRequest) (*Project, error) {
+				t.Error("a rename to a taken name must not be stored")
+				return nil, nil
+			},
+		},
+		mockRegistry{},
+		nil,
+		WithUniqueNames(true),
+	)
+
+	name := "Billing"
+	_, err := s.Update(context.Background(), "p-1", UpdateProjectRequest{Name: &name}, false)
+	if !errors.Is(err, ErrNameExists) {
+		t.Fatalf("Update() error = %v, want ErrNameExists", err)
+	}
+	if exceptID != "p-1" {
+		t.Errorf("name checked excluding %q, want the renamed project", exceptID)
+	}
+}
+
+func TestServiceRelatedCapsThePage(t *testing.T) {
+	tests := []struct {
+		name   string
+		labels map[string]string
+		limit  int32
+		want   int32 // limit passed to the store; 0 means not queried
+	}{
+		{name: "default", labels: map[string]string{"team": "web"}, limit: 0, want: MaxRelatedProjects},
+		{name: "within cap", labels: map[string]string{"team": "web"}, limit: 10, want: 10},
+		{name: "above cap", labels: map[string]string{"team": "web"}, limit: 1000, want: MaxRelatedProjects},
+		{name: "no labels", labels: map[string]string{}, limit: 10, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var queried int32
+			s := newService(
+				mockStore{
+					getByID: func(_ context.Context, id string) (*Project, error) {
+						return &Project{ID: id, Labels: tt.labels}, nil
+					},
+					relFn: func(_ context.Context, _ string, limit, _ int32) ([]*RelatedProject, error) {
+						queried = limit
+						return []*RelatedProject{}, nil
+					},
+				},
+				mockRegistry{},
+				nil,
+			)
+
+			related, err := s.Related(context.Background(), "p-1", tt.limit, 0)
+			if err != nil {
+				t.Fatalf("Related() error = %v", err)
+			}
+			if related.Data == nil {
+				t.Error("Related() returned nil data, want an empty page")
+			}
+			if queried != tt.want {
+				t.Errorf("store queried with limit %d, want %d", queried, tt.want)
+			}
+		})
+	}
+}
+
+func TestServiceProvisionPendingRecordsOutcomeAfterCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var stored ProvisionStatus
+	s := newService(
+		mockStore{
+			statusFn: func(ctx context.Context, _ string, status ProvisionStatus) error {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				stored = status
+				return nil
+			},
+		},
+		mockRegistry{
+			getFn: func(string) (plugin.Plugin, error) {
+				return mockPlugin{
+					provisionFn: func(ctx context.Context, _ plugin.ProvisionRequest) (*plugin.ProvisionResult, error) {
+						// Shutdown gives up on the operation mid-call.
+						cancel()
+						<-ctx.Done()
+						return nil, ctx.Err()
+					},
+				}, nil
+			},
+		},
+		nil,
+	)
+
+	err := s.ProvisionPending(ctx, &Project{ID: "p-1", Name: "Alpha", Status: StatusPending})
+	if !errors.Is(err, ErrProvisionFailed) {
+		t.Fatalf("expected ErrProvisionFailed, got %v", err)
+	}
+	if stored != StatusFailed {
+		t.Errorf("stored status %q, want failed despite the cancelled context", stored)
+	}
+}
@@ -0,0 +1,64 @@
+package projects
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Stats is a point-in-time snapshot of the service counters.
+// Counters are cumulative since the service was constructed.
+type Stats struct {
+	Created            int64 `json:"created"`
+	ProvisionSucceeded int64 `json:"provision_succeeded"`
+	ProvisionFailed    int64 `json:"provision_failed"`
+	ProvisionsInFlight int64 `json:"provisions_in_flight"`
+
+	// ByStatus counts stored projects per status as of CountedAt. It is
+	// cached, and only recomputed by RefreshCounts.
+	ByStatus  map[ProvisionStatus]int64 `json:"by_status,omitempty"`
+	CountedAt *time.Time                `json:"counted_at,omitempty"`
+}
+
+// counters holds the live, concurrency-safe service counters.
+type counters struct {
+	created            atomic.Int64
+	provisionSucceeded atomic.Int64
+	provisionFailed    atomic.Int64
+	provisionsInFlight atomic.Int64
+
+	statusCounts atomic.Pointer[statusCounts]
+}
+
+// statusCounts is a cached per-status project count.
+type statusCounts struct {
+	byStatus  map[ProvisionStatus]int64
+	countedAt time.Time
+}
+
+func (c *counters) snapshot() Stats {
+	stats := Stats{
+		Created:            c.created.Load(),
+		ProvisionSucceeded: c.provisionSucceeded.Load(),
+		ProvisionFailed:    c.provisionFailed.Load(),
+		ProvisionsInFlight: c.provisionsInFlight.Load(),
+	}
+	if counts := c.statusCounts.Load(); counts != nil {
+		countedAt := counts.countedAt
+		stats.ByStatus = counts.byStatus
+		stats.CountedAt = &countedAt
+	}
+	return stats
+}
+
+// RefreshCounts recomputes the cached per-status project counts reported
+// by Stats.
+func (s *Service) RefreshCounts(ctx context.Context) error {
+	byStatus, err := s.store.CountByStatus(ctx)
+	if err != nil {
+		return fmt.Errorf("count projects by status: %w", err)
+	}
+	s.counters.statusCounts.Store(&statusCounts{byStatus: byStatus, countedAt: time.Now().UTC()})
+	return nil
+}
@@ -0,0 +1,172 @@
+package projects
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/searge/quokka/internal/plugin"
+	"golang.org/x/sync/singleflight"
+)
+
+var (
+	ErrNotProvisioned = errors.New("project has no provisioned resource")
+	ErrStatusFailed   = errors.New("resource status lookup failed")
+)
+
+// statusTimeout bounds one plugin Status call. Callers sharing the call
+// stop waiting when their own context ends; the call itself runs on.
+const statusTimeout = 10 * time.Second
+
+// statusCache coalesces concurrent Status calls for the same resource into
+// one plugin call, and serves its result for ttl afterwards. Dashboards
+// polling many projects at once then cost one call per resource, not one
+// per viewer. Failed lookups are shared with the callers already waiting
+// but not cached.
+type statusCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	group singleflight.Group
+
+	mu      sync.Mutex
+	entries map[string]statusEntry
+}
+
+type statusEntry struct {
+	result  *plugin.StatusResult
+	expires time.Time
+}
+
+func newStatusCache(ttl time.Duration) *statusCache {
+	return &statusCache{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]statusEntry),
+	}
+}
+
+// statusKey identifies a resource across plugins. Pure function.
+func statusKey(pluginName, resourceID string) string {
+	return pluginName + "/" + resourceID
+}
+
+// get returns the cached status for key, or calls fetch, joining a call
+// already in flight for key if there is one.
+func (c *statusCache) get(ctx context.Context, key string, fetch func(context.Context) (*plugin.StatusResult, error)) (*plugin.StatusResult, error) {
+	if result, ok := c.lookup(key); ok {
+		return result, nil
+	}
+
+	// The call outlives any one caller: it must not be cancelled because
+	// the caller that happened to start it went away.
+	callCtx := context.WithoutCancel(ctx)
+	ch := c.group.DoChan(key, func() (any, error) {
+		callCtx, cancel := context.WithTimeout(callCtx, statusTimeout)
+		defer cancel()
+
+		result, err := fetch(callCtx)
+		if err != nil {
+			return nil, err
+		}
+		c.store(key, result)
+		return result, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.(*plugin.StatusResult), nil
+	}
+}
+
+func (c *statusCache) lookup(key string) (*plugin.StatusResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !c.now().Before(e.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return e.result, true
+}
+
+func (c *statusCache) store(key string, result *plugin.StatusResult) {
+	if c.ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	for k, e := range c.entries {
+		if !now.Before(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = statusEntry{result: result, expires: now.Add(c.ttl)}
+}
+
+// WithStatusCacheTTL serves resource status lookups from memory for ttl
+// after they complete. Concurrent lookups of one resource share a single
+// plugin call regardless; a zero ttl only disables reuse afterwards.
+func WithStatusCacheTTL(ttl time.Duration) ServiceOption {
+	return func(s *Service) {
+		s.statuses = newStatusCache(ttl)
+	}
+}
+
+// ResourceStatus asks the project's plugin for the current state of the
+// resource backing it. Returns ErrNotProvisioned if the project has none
+// and ErrProvisioningDisabled if provisioning is off.
+// Network info the plugin reports is recorded on the project; if it
+// reports none, the last recorded network is returned instead.
+func (s *Service) ResourceStatus(ctx context.Context, id string) (*plugin.StatusResult, error) {
+	if err := s.checkProvisioning(); err != nil {
+		return nil, err
+	}
+	project, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if project.ResourceID == "" {
+		return nil, ErrNotProvisioned
+	}
+
+	name := withProvisionDefaults(project.ProvisionParams).Plugin
+	p, err := s.registry.Get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.statuses.get(ctx, statusKey(name, project.ResourceID), func(ctx context.Context) (*plugin.StatusResult, error) {
+		return p.Status(ctx, project.ResourceID)
+	})
+	if err != nil {
+		if errors.Is(err, ctx.Err()) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %w", ErrStatusFailed, err)
+	}
+	if !result.Network.Empty() {
+		s.setNetwork(ctx, project, result.Network)
+		return result, nil
+	}
+	if project.Network.Empty() {
+		return result, nil
+	}
+	// The result may be shared through the cache: copy it, don't fill it in.
+	withNetwork := *result
+	withNetwork.Network = project.Network
+	return &withNetwork, nil
+}
@@ -0,0 +1,306 @@
+package projects
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/searge/quokka/internal/plugin"
+)
+
+const statusProjectID = "0d9c4a8e-6f1b-4d8a-b3e2-5a7c9e1f2b30"
+
+// newStatusService returns a service whose one project is backed by
+// resourceID, answering Status through statusFn.
+func newStatusService(resourceID string, statusFn func(context.Context, string) (*plugin.StatusResult, error), opts ...ServiceOption) *Service {
+	return newService(
+		mockStore{
+			getByID: func(_ context.Context, id string) (*Project, error) {
+				return &Project{ID: id, ResourceID: resourceID}, nil
+			},
+		},
+		mockRegistry{
+			getFn: func(string) (plugin.Plugin, error) {
+				return mockPlugin{statusFn: statusFn}, nil
+			},
+		},
+		nil,
+		opts...,
+	)
+}
+
+func TestResourceStatusCoalescesConcurrentLookups(t *testing.T) {
+	const callers = 20
+	var calls atomic.Int32
+	started, release := make(chan struct{}), make(chan struct{})
+	s := newStatusService("vm-100", func(context.Context, string) (*plugin.StatusResult, error) {
+		if calls.Add(1) == 1 {
+			close(started)
+		}
+		<-release
+		return &plugin.StatusResult{Status: "running"}, nil
+	})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, callers)
+	for range callers {
+		wg.Go(func() {
+			result, err := s.ResourceStatus(context.Background(), statusProjectID)
+			if err == nil && result.Status != "running" {
+				err = errors.New("unexpected status " + result.Status)
+			}
+			errs <- err
+		})
+	}
+
+	// Let every caller join the in-flight lookup before it completes.
+	<-started
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("ResourceStatus: %v", err)
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("plugin Status called %d times, want 1", got)
+	}
+}
+
+func TestResourceStatusReusesResultWithinTTL(t *testing.T) {
+	var calls atomic.Int32
+	s := newStatusService("vm-100", func(context.Context, string) (*plugin.StatusResult, error) {
+		calls.Add(1)
+		return &plugin.StatusResult{Status: "running"}, nil
+	}, WithStatusCacheTTL(time.Minute))
+
+	now := time.Now()
+	s.statuses.now = func() time.Time { return now }
+
+	for range 3 {
+		if _, err := s.ResourceStatus(context.Background(), statusProjectID); err != nil {
+			t.Fatalf("ResourceStatus: %v", err)
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("plugin Status called %d times within ttl, want 1", got)
+	}
+
+	now = now.Add(time.Minute)
+	if _, err := s.ResourceStatus(context.Background(), statusProjectID); err != nil {
+		t.Fatalf("ResourceStatus: %v", err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("plugin Status called %d times after ttl, want 2", got)
+	}
+}
+
+func TestResourceStatusDoesNotCacheFailures(t *testing.T) {
+	var calls atomic.Int32
+	s := newStatusService("vm-100", func(context.Context, string) (*plugin.StatusResult, error) {
+		calls.Add(1)
+		return nil, errors.New("proxmox unreachable")
+	}, WithStatusCacheTTL(time.Minute))
+
+	for range 2 {
+		_, err := s.ResourceStatus(context.Background(), statusProjectID)
+		if !errors.Is(err, ErrStatusFailed) {
+			t.Fatalf("expected ErrStatusFailed, got %v", err)
+		}
+	}
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("plugin Status called %d times, want 2", got)
+	}
+}
+
+func TestResourceStatusRequiresResource(t *testing.T) {
+	s := newStatusService("", func(context.Context, string) (*plugin.StatusResult, error) {
+		t.Fatal("a project without a resource must not reach the plugin")
+		return nil, nil
+	})
+
+	_, err := s.ResourceStatus(context.Background(), statusProjectID)
+	if !errors.Is(err, ErrNotProvisioned) {
+		t.Fatalf("expected ErrNotProvisioned, got %v", err)
+	}
+}
+
+func TestProvisionRecordsResourceID(t *testing.T) {
+	var recorded, recordedNode string
+	s := newService(
+		mockStore{
+			resFn: func(_ context.Context, _, resourceID, node string) error {
+				recorded, recordedNode = resourceID, node
+				return nil
+			},
+		},
+		mockRegistry{
+			getFn: func(string) (plugin.Plugin, error) {
+				return mockPlugin{
+					provisionFn: func(context.Context, plugin.ProvisionRequest) (*plugin.ProvisionResult, error) {
+						return &plugin.ProvisionResult{ResourceID: "res-1", Metadata: map[string]string{"node": "pve-02"}}, nil
+					},
+				}, nil
+			},
+		},
+		nil,
+	)
+
+	// The node the plugin placed the resource on wins over the one asked for.
+	project := &Project{ID: statusProjectID, Name: "Demo", ProvisionParams: &ProvisionParams{Node: "pve-01"}}
+	if _, err := s.provision(context.Background(), project, nil, false); err != nil {
+		t.Fatalf("provision: %v", err)
+	}
+	if recorded != "res-1" || project.ResourceID != "res-1" {
+		t.Fatalf("resource id recorded as %q, project has %q, want res-1", recorded, project.ResourceID)
+	}
+	if recordedNode != "pve-02" || project.Node != "pve-02" {
+		t.Errorf("node recorded as %q, project has %q, want pve-02", recordedNode, project.Node)
+	}
+}
+
+func TestResourceStatusRecordsReportedNetwork(t *testing.T) {
+	network := &plugin.NetworkInfo{Hostname: "demo.lan", IPAddresses: []string{"10.0.0.5"}}
+	var recorded []*plugin.NetworkInfo
+	s := newService(
+		mockStore{
+			getByID: func(_ context.Context, id string) (*Project, error) {
+				return &Project{ID: id, ResourceID: "vm-100"}, nil
+			},
+			netFn: func(_ context.Context, _ string, n *plugin.NetworkInfo) error {
+				recorded = append(recorded, n)
+				return nil
+			},
+		},
+		mockRegistry{
+			getFn: func(string) (plugin.Plugin, error) {
+				return mockPlugin{statusFn: func(context.Context, string) (*plugin.StatusResult, error) {
+					return &plugin.StatusResult{Status: "running", Network: network}, nil
+				}}, nil
+			},
+		},
+		nil,
+	)
+
+	result, err := s.ResourceStatus(context.Background(), statusProjectID)
+	if err != nil {
+		t.Fatalf("ResourceStatus: %v", err)
+	}
+	if !result.Network.Equal(network) {
+		t.Errorf("result network = %+v, want %+v", result.Network, network)
+	}
+	if len(recorded) != 1 || !recorded[0].Equal(network) {
+		t.Errorf("recorded networks = %v, want one %+v", recorded, network)
+	}
+}
+
+func TestResourceStatusFallsBackToRecordedNetwork(t *testing.T) {
+	stored := &plugin.NetworkInfo{IPAddresses: []string{"10.0.0.5"}}
+	tests := []struct {
+		name   string
+		stored *plugin.NetworkInfo
+		want   *plugin.NetworkInfo
+	}{
+		{name: "recorded earlier", stored: stored, want: stored},
+		{name: "never reported"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pluginResult := &plugin.StatusResult{Status: "running"}
+			s := newService(
+				mockStore{
+					getByID: func(_ context.Context, id string) (*Project, error) {
+						return &Project{ID: id, ResourceID: "vm-100", Network: tt.stored}, nil
+					},
+					netFn: func(context.Context, string, *plugin.NetworkInfo) error {
+						t.Error("a status without network info must not overwrite the recorded one")
+						return nil
+					},
+				},
+				mockRegistry{
+					getFn: func(string) (plugin.Plugin, error) {
+						return mockPlugin{statusFn: func(context.Context, string) (*plugin.StatusResult, error) {
+							return pluginResult, nil
+						}}, nil
+					},
+				},
+				nil,
+			)
+
+			result, err := s.ResourceStatus(context.Background(), statusProjectID)
+			if err != nil {
+				t.Fatalf("ResourceStatus: %v", err)
+			}
+			if !result.Network.Equal(tt.want) {
+				t.Errorf("result network = %+v, want %+v", result.Network, tt.want)
+			}
+			if pluginResult.Network != nil {
+				t.Error("the plugin's result must not be modified")
+			}
+		})
+	}
+}
+
+func TestProvisionRecordsNetwork(t *testing.T) {
+	network := &plugin.NetworkInfo{IPAddresses: []string{"10.0.0.5"}}
+	var recorded *plugin.NetworkInfo
+	s := newService(
+		mockStore{
+			netFn: func(_ context.Context, _ string, n *plugin.NetworkInfo) error {
+				recorded = n
+				return nil
+			},
+		},
+		mockRegistry{
+			getFn: func(string) (plugin.Plugin, error) {
+				return mockPlugin{provisionFn: func(context.Context, plugin.ProvisionRequest) (*plugin.ProvisionResult, error) {
+					return &plugin.ProvisionResult{ResourceID: "res-1", Network: network}, nil
+				}}, nil
+			},
+		},
+		nil,
+	)
+
+	project := &Project{ID: statusProjectID, Name: "Demo"}
+	if _, err := s.provision(context.Background(), project, nil, false); err != nil {
+		t.Fatalf("provision: %v", err)
+	}
+	if !recorded.Equal(network) || !project.Network.Equal(network) {
+		t.Fatalf("network recorded as %+v, project has %+v, want %+v", recorded, project.Network, network)
+	}
+}
+
+func TestHandlerStatusMapsPluginErrorClasses(t *testing.T) {
+	tests := []struct {
+		class plugin.ErrorClass
+		want  int
+	}{
+		{plugin.ClassInvalidInput, http.StatusUnprocessableEntity},
+		{plugin.ClassNotFound, http.StatusNotFound},
+		{plugin.ClassTransient, http.StatusServiceUnavailable},
+		{plugin.ClassUnauthorized, http.StatusBadGateway},
+		{plugin.ClassUnknown, http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.class), func(t *testing.T) {
+			s := newStatusService("vm-100", func(context.Context, string) (*plugin.StatusResult, error) {
+				return nil, &plugin.PluginError{Plugin: "proxmox", Op: plugin.OpStatus, Class: tt.class, Err: errors.New("cli failed")}
+			})
+			h := NewHandler(s, nil)
+
+			rr := httptest.NewRecorder()
+			h.Status(rr, newGetRequestWithID(statusProjectID))
+			if rr.Code != tt.want {
+				t.Fatalf("expected %d, got %d: %s", tt.want, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
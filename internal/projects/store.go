@@ -1,8 +1,13 @@
 package projects
 
 import (
+	"cmp"
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"slices"
+	"strconv"
 	"time"
 
 	"github.com/google/uuid"
@@ -10,21 +15,124 @@ import (
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/searge/quokka/internal/platform"
+	"github.com/searge/quokka/internal/plugin"
 	"github.com/searge/quokka/internal/projects/db"
 )
 
 // Store provides data access for project entities via sqlc.
 type Store struct {
-	pool    *pgxpool.Pool
-	queries *db.Queries
+	pool         *pgxpool.Pool
+	queries      *db.Queries
+	queryTimeout time.Duration
+	idVersion    uuid.Version
+	// archiveUnixNames frees a deleted project's unix_name; see
+	// WithArchivedUnixNames.
+	archiveUnixNames bool
+	// keyring encrypts the provision resources named by encryptedFields;
+	// see WithFieldEncryption.
+	keyring         *platform.Keyring
+	encryptedFields []string
+}
+
+// StoreOption configures optional Store behaviour.
+type StoreOption func(*Store)
+
+// WithQueryTimeout bounds every store query by d, independently of the
+// caller's deadline. A zero duration leaves queries bounded only by ctx.
+func WithQueryTimeout(d time.Duration) StoreOption {
+	return func(s *Store) {
+		s.queryTimeout = d
+	}
+}
+
+// WithIDVersion rejects project IDs that are not of UUID version v.
+// Zero accepts any version.
+func WithIDVersion(v uuid.Version) StoreOption {
+	return func(s *Store) {
+		s.idVersion = v
+	}
+}
+
+// WithArchivedUnixNames renames projects as they are deleted, appending
+// -deleted-<unix milliseconds> to their unix_name, so the name can be
+// reused right away rather than once the project is purged. The original
+// is kept in the archived_unix_name column.
+func WithArchivedUnixNames(enabled bool) StoreOption {
+	return func(s *Store) {
+		s.archiveUnixNames = enabled
+	}
 }
 
 // NewStore initializes a new Store instance.
-func NewStore(pool *pgxpool.Pool) *Store {
-	return &Store{
+func NewStore(pool *pgxpool.Pool, opts ...StoreOption) *Store {
+	s := &Store{
 		pool:    pool,
-		queries: db.New(pool),
+		queries: db.New(platform.NewPoolDB(pool)),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// queryContext derives the context for a single query. The parent deadline
+// still applies if it is sooner than the configured query timeout.
+func (s *Store) queryContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.queryTimeout <= 0 {
+		return context.WithCancel(ctx)
 	}
+	return context.WithTimeout(ctx, s.queryTimeout)
+}
+
+// Ping checks that the store can run a query, for readiness probes.
+func (s *Store) Ping(ctx context.Context) error {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	if _, err := s.queries.Ping(ctx); err != nil {
+		return fmt.Errorf("ping projects store: %w", err)
+	}
+	return nil
+}
+
+// CountByStatus counts live projects per provisioning status.
+func (s *Store) CountByStatus(ctx context.Context) (map[ProvisionStatus]int64, error) {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	rows, err := s.queries.CountProjectsByStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[ProvisionStatus]int64, len(rows))
+	for _, row := range rows {
+		counts[ProvisionStatus(row.Status)] = row.Count
+	}
+	return counts, nil
+}
+
+// CountActiveByOrg counts the organization's active, non-deleted projects.
+func (s *Store) CountActiveByOrg(ctx context.Context, orgID string) (int64, error) {
+	uid, err := uuid.Parse(orgID)
+	if err != nil {
+		return 0, ErrUnknownOrganization
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	return s.queries.CountActiveProjectsByOrg(ctx, pgtype.UUID{Bytes: uid, Valid: true})
+}
+
+// Vacuum reclaims space left by updated and purged rows and refreshes the
+// planner statistics of the projects table. It is maintenance, not a query:
+// it is not bound by the query timeout and cannot run in a transaction.
+func (s *Store) Vacuum(ctx context.Context) error {
+	if _, err := s.pool.Exec(ctx, "VACUUM (ANALYZE) projects"); err != nil {
+		return fmt.Errorf("vacuum projects: %w", err)
+	}
+	return nil
 }
 
 // Create inserts a new project.
@@ -36,62 +144,262 @@ func (s *Store) Create(ctx context.Context, req CreateProjectRequest) (*Project,
 		desc = pgtype.Text{String: req.Description, Valid: true}
 	}
 
+	provisionParams, err := s.encodeProvisionParams(req.ProvisionParams)
+	if err != nil {
+		return nil, err
+	}
+	labels, err := encodeLabels(req.Labels)
+	if err != nil {
+		return nil, err
+	}
+
 	params := db.CreateProjectParams{
-		ID:          pgtype.UUID{Bytes: id, Valid: true},
-		Name:        req.Name,
-		UnixName:    req.UnixName,
-		Description: desc,
-		Active:      true,
-		CreatedAt:   pgtype.Timestamptz{Time: time.Now(), Valid: true},
-		UpdatedAt:   pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		ID:              pgtype.UUID{Bytes: id, Valid: true},
+		Name:            req.Name,
+		UnixName:        req.UnixName,
+		Description:     desc,
+		Active:          true,
+		CreatedAt:       pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		UpdatedAt:       pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		ProvisionParams: provisionParams,
+		Labels:          labels,
 	}
+	if req.OrgID != "" {
+		orgID, err := uuid.Parse(req.OrgID)
+		if err != nil {
+			return nil, ErrUnknownOrganization
+		}
+		params.OrgID = pgtype.UUID{Bytes: orgID, Valid: true}
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
 
 	row, err := s.queries.CreateProject(ctx, params)
 	if err != nil {
 		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
-			return nil, ErrProjectExists
+		if errors.As(err, &pgErr) {
+			switch pgErr.Code {
+			case "23505":
+				return nil, ErrProjectExists
+			case "23503":
+				return nil, ErrUnknownOrganization
+			}
 		}
 		return nil, err
 	}
 
-	return mapToDomainProject(row), nil
+	return s.toDomainProject(row)
+}
+
+// NameExists reports whether a project other than exceptID has name,
+// compared case-insensitively. An empty exceptID excludes no project.
+func (s *Store) NameExists(ctx context.Context, name, exceptID string) (bool, error) {
+	params := db.CheckProjectExistsByNameParams{Name: name}
+	if exceptID != "" {
+		uid, err := parseProjectID(exceptID, s.idVersion)
+		if err != nil {
+			return false, err
+		}
+		params.ExceptID = pgtype.UUID{Bytes: uid, Valid: true}
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	return s.queries.CheckProjectExistsByName(ctx, params)
+}
+
+// LockOrganization locks the row of organization orgID until the
+// transaction the store is bound to ends; outside one it has no effect.
+func (s *Store) LockOrganization(ctx context.Context, orgID string) error {
+	uid, err := uuid.Parse(orgID)
+	if err != nil {
+		return ErrUnknownOrganization
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	return s.queries.LockOrganization(ctx, pgtype.UUID{Bytes: uid, Valid: true})
+}
+
+// WithNameLock runs fn against a store bound to a single transaction that
+// holds a lock on name, case-insensitively, until it ends. Creates and
+// renames to the same name made through it therefore run one at a time.
+// The transaction commits if fn succeeds and rolls back otherwise.
+func (s *Store) WithNameLock(ctx context.Context, name string, fn func(projectStore) error) error {
+	return s.inTx(ctx, func(tx *Store) error {
+		lockCtx, cancel := s.queryContext(ctx)
+		defer cancel()
+		if err := tx.queries.LockProjectName(lockCtx, name); err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+}
+
+// Atomically runs fn against a store bound to a single transaction, which
+// commits if fn succeeds and rolls back otherwise.
+func (s *Store) Atomically(ctx context.Context, fn func(projectStore) error) error {
+	return s.inTx(ctx, func(tx *Store) error {
+		return fn(tx)
+	})
+}
+
+// inTx runs fn against a copy of s whose queries go through one
+// transaction, committing it if fn succeeds.
+func (s *Store) inTx(ctx context.Context, fn func(tx *Store) error) (err error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(ctx); rbErr != nil && !errors.Is(rbErr, pgx.ErrTxClosed) {
+			err = errors.Join(err, rbErr)
+		}
+	}()
+
+	txStore := *s
+	txStore.queries = s.queries.WithTx(tx)
+	if err := fn(&txStore); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
 // GetByID retrieves a project by its unique ID.
 func (s *Store) GetByID(ctx context.Context, id string) (*Project, error) {
-	uid, err := uuid.Parse(id)
+	uid, err := parseProjectID(id, s.idVersion)
 	if err != nil {
-		return nil, ErrInvalidProjectID
+		return nil, err
 	}
 
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
 	row, err := s.queries.GetProject(ctx, pgtype.UUID{Bytes: uid, Valid: true})
 	if err != nil {
 		return nil, err
 	}
 
-	return mapToDomainProject(row), nil
+	return s.toDomainProject(row)
+}
+
+// GetByIDs retrieves every project whose ID is in ids with a single query.
+// IDs that match no row are simply absent from the result.
+func (s *Store) GetByIDs(ctx context.Context, ids []string) ([]*Project, error) {
+	uids := make([]pgtype.UUID, len(ids))
+	for i, id := range ids {
+		uid, err := parseProjectID(id, s.idVersion)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", err, id)
+		}
+		uids[i] = pgtype.UUID{Bytes: uid, Valid: true}
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	rows, err := s.queries.GetProjectsByIDs(ctx, uids)
+	if err != nil {
+		return nil, err
+	}
+
+	projects := make([]*Project, len(rows))
+	for i, row := range rows {
+		p, err := s.toDomainProject(row)
+		if err != nil {
+			return nil, err
+		}
+		projects[i] = p
+	}
+	return projects, nil
 }
 
 // GetByUnixName retrieves a project by its unix name.
 func (s *Store) GetByUnixName(ctx context.Context, unixName string) (*Project, error) {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
 	row, err := s.queries.GetProjectByUnixName(ctx, unixName)
 	if err != nil {
 		return nil, err
 	}
-	return mapToDomainProject(row), nil
+	return s.toDomainProject(row)
 }
 
 // ExistsByUnixName checks if a project unix name is already taken.
 func (s *Store) ExistsByUnixName(ctx context.Context, unixName string) (bool, error) {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
 	return s.queries.CheckProjectExistsByUnixName(ctx, unixName)
 }
 
-// List retrieves a list of active projects securely.
-func (s *Store) List(ctx context.Context, limit, offset int32) ([]*Project, error) {
-	rows, err := s.queries.ListProjects(ctx, db.ListProjectsParams{
-		Limit:  limit,
-		Offset: offset,
+// List retrieves the window of projects filter selects, newest first.
+// filter is used as given: its limit and offset are not normalized.
+func (s *Store) List(ctx context.Context, filter ListFilter) ([]*Project, error) {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	rows, err := s.queries.ListProjects(ctx, listParams(filter))
+	if err != nil {
+		return nil, err
+	}
+
+	projects := make([]*Project, len(rows))
+	for i, row := range rows {
+		p, err := s.toDomainProject(row)
+		if err != nil {
+			return nil, err
+		}
+		projects[i] = p
+	}
+
+	return projects, nil
+}
+
+// Count returns how many projects List pages through for filter; its
+// limit and offset are ignored.
+func (s *Store) Count(ctx context.Context, filter ListFilter) (int64, error) {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	return s.queries.CountProjects(ctx, countParams(filter))
+}
+
+// listParams builds the ListProjects query arguments for filter.
+// Pure function.
+func listParams(filter ListFilter) db.ListProjectsParams {
+	return db.ListProjectsParams{
+		Statuses: statusFilter(filter.Statuses),
+		Node:     filter.Node,
+		Limit:    filter.Limit,
+		Offset:   filter.Offset,
+	}
+}
+
+// countParams builds the CountProjects query arguments for filter, which
+// match listParams' without the window. Pure function.
+func countParams(filter ListFilter) db.CountProjectsParams {
+	return db.CountProjectsParams{
+		Statuses: statusFilter(filter.Statuses),
+		Node:     filter.Node,
+	}
+}
+
+// ListForReconcile returns up to limit live projects last checked before
+// olderThan, or never, least recently checked first. Marking each one with
+// MarkChecked once it is reconciled moves it to the back of the line, so
+// every tick works through a bounded batch and no project is starved.
+func (s *Store) ListForReconcile(ctx context.Context, olderThan time.Time, limit int32) ([]*Project, error) {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	rows, err := s.queries.ListProjectsForReconcile(ctx, db.ListProjectsForReconcileParams{
+		OlderThan: pgtype.Timestamptz{Time: olderThan, Valid: true},
+		Limit:     limit,
 	})
 	if err != nil {
 		return nil, err
@@ -99,17 +407,152 @@ func (s *Store) List(ctx context.Context, limit, offset int32) ([]*Project, erro
 
 	projects := make([]*Project, len(rows))
 	for i, row := range rows {
-		projects[i] = mapToDomainProject(row)
+		p, err := s.toDomainProject(row)
+		if err != nil {
+			return nil, err
+		}
+		projects[i] = p
 	}
+	return projects, nil
+}
 
+// ListSelected returns up to limit live projects matching sel, oldest
+// first. sel matches like it does for UpdateLabels.
+func (s *Store) ListSelected(ctx context.Context, sel LabelSelector, limit int32) ([]*Project, error) {
+	ids, err := s.selectorIDs(sel)
+	if err != nil {
+		return nil, err
+	}
+	matchJSON, err := encodeLabels(sel.MatchLabels)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	rows, err := s.queries.ListProjectsBySelector(ctx, db.ListProjectsBySelectorParams{
+		Ids:         ids,
+		MatchLabels: matchJSON,
+		Template:    sel.Template,
+		Limit:       limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	projects := make([]*Project, len(rows))
+	for i, row := range rows {
+		p, err := s.toDomainProject(row)
+		if err != nil {
+			return nil, err
+		}
+		projects[i] = p
+	}
 	return projects, nil
 }
 
+// MarkChecked records that project id was reconciled at checkedAt. It is
+// not a change to the project, so updated_at is left alone.
+func (s *Store) MarkChecked(ctx context.Context, id string, checkedAt time.Time) error {
+	uid, err := parseProjectID(id, s.idVersion)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	rowsAffected, err := s.queries.MarkProjectChecked(ctx, db.MarkProjectCheckedParams{
+		ID:            pgtype.UUID{Bytes: uid, Valid: true},
+		LastCheckedAt: pgtype.Timestamptz{Time: checkedAt, Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// statusFilter converts statuses to the text[] the list queries filter on.
+// Pure function.
+func statusFilter(statuses []ProvisionStatus) []string {
+	filter := make([]string, len(statuses))
+	for i, status := range statuses {
+		filter[i] = string(status)
+	}
+	return filter
+}
+
+// Related retrieves a page of the other active projects sharing at least
+// one label with project id, those sharing the most first.
+func (s *Store) Related(ctx context.Context, id string, limit, offset int32) ([]*RelatedProject, error) {
+	uid, err := parseProjectID(id, s.idVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	rows, err := s.queries.ListRelatedProjects(ctx, db.ListRelatedProjectsParams{
+		ID:     pgtype.UUID{Bytes: uid, Valid: true},
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	related := make([]*RelatedProject, len(rows))
+	for i, row := range rows {
+		p, err := s.toDomainProject(db.Project{
+			ID:               row.ID,
+			Name:             row.Name,
+			UnixName:         row.UnixName,
+			Description:      row.Description,
+			Active:           row.Active,
+			CreatedAt:        row.CreatedAt,
+			UpdatedAt:        row.UpdatedAt,
+			ProvisionParams:  row.ProvisionParams,
+			Labels:           row.Labels,
+			Status:           row.Status,
+			DeletedAt:        row.DeletedAt,
+			OrgID:            row.OrgID,
+			ResourceID:       row.ResourceID,
+			ArchivedUnixName: row.ArchivedUnixName,
+			Network:          row.Network,
+			LastCheckedAt:    row.LastCheckedAt,
+			Node:             row.Node,
+		})
+		if err != nil {
+			return nil, err
+		}
+		related[i] = &RelatedProject{Project: p, SharedLabels: row.SharedLabels}
+	}
+	return related, nil
+}
+
+// CountRelated returns how many projects Related pages through for
+// project id.
+func (s *Store) CountRelated(ctx context.Context, id string) (int64, error) {
+	uid, err := parseProjectID(id, s.idVersion)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	return s.queries.CountRelatedProjects(ctx, pgtype.UUID{Bytes: uid, Valid: true})
+}
+
 // Update amends the details of an existing project.
 func (s *Store) Update(ctx context.Context, id string, req UpdateProjectRequest) (*Project, error) {
-	uid, err := uuid.Parse(id)
+	uid, err := parseProjectID(id, s.idVersion)
 	if err != nil {
-		return nil, ErrInvalidProjectID
+		return nil, err
 	}
 
 	params := db.UpdateProjectParams{
@@ -127,21 +570,329 @@ func (s *Store) Update(ctx context.Context, id string, req UpdateProjectRequest)
 		params.Active = pgtype.Bool{Bool: *req.Active, Valid: true}
 	}
 
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
 	row, err := s.queries.UpdateProject(ctx, params)
 	if err != nil {
 		return nil, err
 	}
 
-	return mapToDomainProject(row), nil
+	return s.toDomainProject(row)
+}
+
+// Transfer moves project id from organization fromOrgID, empty for none,
+// to toOrgID. It returns pgx.ErrNoRows if the project does not exist or no
+// longer belongs to fromOrgID, and ErrUnknownOrganization if toOrgID does
+// not exist or either organization ID is malformed.
+func (s *Store) Transfer(ctx context.Context, id, fromOrgID, toOrgID string) (*Project, error) {
+	uid, err := parseProjectID(id, s.idVersion)
+	if err != nil {
+		return nil, err
+	}
+	to, err := uuid.Parse(toOrgID)
+	if err != nil {
+		return nil, ErrUnknownOrganization
+	}
+
+	params := db.TransferProjectParams{
+		ToOrgID:   pgtype.UUID{Bytes: to, Valid: true},
+		UpdatedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		ID:        pgtype.UUID{Bytes: uid, Valid: true},
+	}
+	if fromOrgID != "" {
+		from, err := uuid.Parse(fromOrgID)
+		if err != nil {
+			return nil, ErrUnknownOrganization
+		}
+		params.FromOrgID = pgtype.UUID{Bytes: from, Valid: true}
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	row, err := s.queries.TransferProject(ctx, params)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return nil, ErrUnknownOrganization
+		}
+		return nil, err
+	}
+	return s.toDomainProject(row)
+}
+
+// SetStatus records the provisioning status of a project.
+func (s *Store) SetStatus(ctx context.Context, id string, status ProvisionStatus) error {
+	uid, err := parseProjectID(id, s.idVersion)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	rowsAffected, err := s.queries.UpdateProjectStatus(ctx, db.UpdateProjectStatusParams{
+		ID:        pgtype.UUID{Bytes: uid, Valid: true},
+		Status:    string(status),
+		UpdatedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// SetNetwork records how to reach the resource backing a project. A nil
+// or empty network clears it.
+func (s *Store) SetNetwork(ctx context.Context, id string, network *plugin.NetworkInfo) error {
+	uid, err := parseProjectID(id, s.idVersion)
+	if err != nil {
+		return err
+	}
+	raw, err := encodeNetwork(network)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	rowsAffected, err := s.queries.SetProjectNetwork(ctx, db.SetProjectNetworkParams{
+		ID:        pgtype.UUID{Bytes: uid, Valid: true},
+		Network:   raw,
+		UpdatedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// SetResource records the plugin resource backing a project and the node
+// it was placed on, if known.
+func (s *Store) SetResource(ctx context.Context, id, resourceID, node string) error {
+	uid, err := parseProjectID(id, s.idVersion)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	rowsAffected, err := s.queries.SetProjectResource(ctx, db.SetProjectResourceParams{
+		ID:         pgtype.UUID{Bytes: uid, Valid: true},
+		ResourceID: pgtype.Text{String: resourceID, Valid: resourceID != ""},
+		Node:       pgtype.Text{String: node, Valid: node != ""},
+		UpdatedAt:  pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// SetProvisionParams replaces the provisioning request stored on project
+// id, encrypting its designated resources. Returns pgx.ErrNoRows if the
+// project does not exist.
+func (s *Store) SetProvisionParams(ctx context.Context, id string, params *ProvisionParams) error {
+	uid, err := parseProjectID(id, s.idVersion)
+	if err != nil {
+		return err
+	}
+	encoded, err := s.encodeProvisionParams(params)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	rowsAffected, err := s.queries.SetProjectProvisionParams(ctx, db.SetProjectProvisionParamsParams{
+		ID:              pgtype.UUID{Bytes: uid, Valid: true},
+		ProvisionParams: encoded,
+		UpdatedAt:       pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// TransitionStatus moves a project from status from to status to in one
+// compare-and-swap statement. It reports false, without changing anything,
+// if the project is not currently in from, so concurrent callers cannot
+// both claim the same transition. Returns pgx.ErrNoRows if the project
+// does not exist.
+func (s *Store) TransitionStatus(ctx context.Context, id string, from, to ProvisionStatus) (bool, error) {
+	uid, err := parseProjectID(id, s.idVersion)
+	if err != nil {
+		return false, err
+	}
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	row, err := s.queries.TransitionProjectStatus(ctx, db.TransitionProjectStatusParams{
+		To:        string(to),
+		UpdatedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		ID:        pgtype.UUID{Bytes: uid, Valid: true},
+		From:      string(from),
+	})
+	if err != nil {
+		return false, err
+	}
+	if !row.Found {
+		return false, pgx.ErrNoRows
+	}
+	return row.Transitioned, nil
+}
+
+// TransitionStaleStatus is TransitionStatus for a project left unchanged
+// since changedBefore: it reports false, changing nothing, if the project
+// is not in status from or was changed since.
+func (s *Store) TransitionStaleStatus(ctx context.Context, id string, from, to ProvisionStatus, changedBefore time.Time) (bool, error) {
+	uid, err := parseProjectID(id, s.idVersion)
+	if err != nil {
+		return false, err
+	}
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	row, err := s.queries.TransitionStaleProjectStatus(ctx, db.TransitionStaleProjectStatusParams{
+		To:            string(to),
+		UpdatedAt:     pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		ID:            pgtype.UUID{Bytes: uid, Valid: true},
+		From:          string(from),
+		ChangedBefore: pgtype.Timestamptz{Time: changedBefore, Valid: true},
+	})
+	if err != nil {
+		return false, err
+	}
+	if !row.Found {
+		return false, pgx.ErrNoRows
+	}
+	return row.Transitioned, nil
+}
+
+// UpdateLabels adds and removes labels on every project matching sel in a
+// single statement, returning the IDs of the projects it changed.
+func (s *Store) UpdateLabels(ctx context.Context, sel LabelSelector, add map[string]string, remove []string) ([]string, error) {
+	ids, err := s.selectorIDs(sel)
+	if err != nil {
+		return nil, err
+	}
+	addJSON, err := encodeLabels(add)
+	if err != nil {
+		return nil, err
+	}
+	matchJSON, err := encodeLabels(sel.MatchLabels)
+	if err != nil {
+		return nil, err
+	}
+	if remove == nil {
+		remove = []string{}
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	changed, err := s.queries.UpdateProjectLabels(ctx, db.UpdateProjectLabelsParams{
+		Add:         addJSON,
+		Remove:      remove,
+		UpdatedAt:   pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		Ids:         ids,
+		MatchLabels: matchJSON,
+		Template:    sel.Template,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return projectIDStrings(changed), nil
+}
+
+// projectIDStrings formats ids as strings. Pure function.
+func projectIDStrings(ids []pgtype.UUID) []string {
+	out := make([]string, len(ids))
+	for i, id := range ids {
+		out[i] = uuid.UUID(id.Bytes).String()
+	}
+	return out
+}
+
+// selectorIDs parses the project IDs sel picks.
+func (s *Store) selectorIDs(sel LabelSelector) ([]pgtype.UUID, error) {
+	ids := make([]pgtype.UUID, len(sel.IDs))
+	for i, id := range sel.IDs {
+		uid, err := parseProjectID(id, s.idVersion)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", err, id)
+		}
+		ids[i] = pgtype.UUID{Bytes: uid, Valid: true}
+	}
+	return ids, nil
+}
+
+// SyncOrgLabels moves the labels of every live project in organization
+// orgID from the previous default labels to the current ones in one
+// statement, keeping labels that differ from their previous default.
+// Returns the IDs of the projects it changed.
+func (s *Store) SyncOrgLabels(ctx context.Context, orgID string, previous, current map[string]string) ([]string, error) {
+	uid, err := uuid.Parse(orgID)
+	if err != nil {
+		return nil, ErrUnknownOrganization
+	}
+	previousJSON, err := encodeLabels(previous)
+	if err != nil {
+		return nil, err
+	}
+	currentJSON, err := encodeLabels(current)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	changed, err := s.queries.SyncOrgProjectLabels(ctx, db.SyncOrgProjectLabelsParams{
+		UpdatedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		Current:   currentJSON,
+		Previous:  previousJSON,
+		OrgID:     pgtype.UUID{Bytes: uid, Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return projectIDStrings(changed), nil
 }
 
-// Delete removes a project permanently.
+// Delete soft-deletes a project: it disappears from every read but keeps
+// its row until PurgeDeleted removes it. Its unix_name stays taken until
+// then, unless the store archives unix names.
 func (s *Store) Delete(ctx context.Context, id string) error {
-	uid, err := uuid.Parse(id)
+	uid, err := parseProjectID(id, s.idVersion)
 	if err != nil {
-		return ErrInvalidProjectID
+		return err
 	}
-	rowsAffected, err := s.queries.DeleteProject(ctx, pgtype.UUID{Bytes: uid, Valid: true})
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	now := time.Now()
+	params := db.DeleteProjectParams{
+		DeletedAt: pgtype.Timestamptz{Time: now, Valid: true},
+		ID:        pgtype.UUID{Bytes: uid, Valid: true},
+	}
+	if s.archiveUnixNames {
+		params.UnixNameSuffix = deletedUnixNameSuffix(now)
+	}
+	rowsAffected, err := s.queries.DeleteProject(ctx, params)
 	if err != nil {
 		return err
 	}
@@ -151,14 +902,264 @@ func (s *Store) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
-func mapToDomainProject(row db.Project) *Project {
-	return &Project{
-		ID:          uuid.UUID(row.ID.Bytes).String(),
-		Name:        row.Name,
-		UnixName:    row.UnixName,
-		Description: row.Description.String,
-		Active:      row.Active,
-		CreatedAt:   row.CreatedAt.Time,
-		UpdatedAt:   row.UpdatedAt.Time,
+// deletedUnixNameSuffix is appended to the unix_name of a project deleted
+// at t to free the name. Pure function.
+func deletedUnixNameSuffix(t time.Time) string {
+	return "-deleted-" + strconv.FormatInt(t.UnixMilli(), 10)
+}
+
+// PurgeDeleted hard-deletes every project soft-deleted before the cutoff and
+// returns what it removed, under their original unix names.
+func (s *Store) PurgeDeleted(ctx context.Context, before time.Time) ([]PurgedProject, error) {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	rows, err := s.queries.PurgeDeletedProjects(ctx, pgtype.Timestamptz{Time: before, Valid: true})
+	if err != nil {
+		return nil, err
+	}
+	purged := make([]PurgedProject, len(rows))
+	for i, row := range rows {
+		purged[i] = PurgedProject{
+			ID:        uuid.UUID(row.ID.Bytes).String(),
+			UnixName:  row.UnixName,
+			DeletedAt: row.DeletedAt.Time,
+		}
+	}
+	return purged, nil
+}
+
+// EnqueueEvents adds events to the outbox, for a Relay to publish. Called
+// through Atomically, they are only stored if the change they describe is.
+// The designated resources of their project snapshots are stored
+// encrypted, like the project's own.
+func (s *Store) EnqueueEvents(ctx context.Context, events ...Event) error {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	for _, event := range events {
+		uid, err := parseProjectID(event.ProjectID, 0)
+		if err != nil {
+			return err
+		}
+		if event.Project != nil {
+			snapshot := *event.Project
+			if snapshot.ProvisionParams, err = s.sealResources(snapshot.ProvisionParams); err != nil {
+				return fmt.Errorf("encode event: %w", err)
+			}
+			event.Project = &snapshot
+		}
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("encode event: %w", err)
+		}
+		err = s.queries.EnqueueOutboxEvent(ctx, db.EnqueueOutboxEventParams{
+			EventType: event.Type,
+			ProjectID: pgtype.UUID{Bytes: uid, Valid: true},
+			Payload:   payload,
+			CreatedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ClaimEvents leases up to limit outbox events due at now, oldest first,
+// until leaseUntil. An event neither marked sent nor failed by then, e.g.
+// because the process died publishing it, is claimed again.
+func (s *Store) ClaimEvents(ctx context.Context, now, leaseUntil time.Time, limit int32) ([]OutboxEvent, error) {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	rows, err := s.queries.ClaimOutboxEvents(ctx, db.ClaimOutboxEventsParams{
+		LeaseUntil: pgtype.Timestamptz{Time: leaseUntil, Valid: true},
+		Now:        pgtype.Timestamptz{Time: now, Valid: true},
+		MaxEvents:  limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// UPDATE ... RETURNING does not keep the subquery's order.
+	slices.SortFunc(rows, func(a, b db.EventsOutbox) int { return cmp.Compare(a.ID, b.ID) })
+
+	claimed := make([]OutboxEvent, len(rows))
+	for i, row := range rows {
+		claimed[i] = OutboxEvent{ID: row.ID, Attempts: int(row.Attempts)}
+		if err := json.Unmarshal(row.Payload, &claimed[i].Event); err != nil {
+			return nil, fmt.Errorf("decode outbox event %d: %w", row.ID, err)
+		}
+		if project := claimed[i].Event.Project; project != nil {
+			if err := s.openResources(project.ProvisionParams); err != nil {
+				return nil, fmt.Errorf("decode outbox event %d: %w", row.ID, err)
+			}
+		}
+	}
+	return claimed, nil
+}
+
+// MarkEventSent records that outbox event id was published at sentAt.
+func (s *Store) MarkEventSent(ctx context.Context, id int64, sentAt time.Time) error {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	return s.queries.MarkOutboxEventSent(ctx, db.MarkOutboxEventSentParams{
+		ID:     id,
+		SentAt: pgtype.Timestamptz{Time: sentAt, Valid: true},
+	})
+}
+
+// DeleteSentEvents removes the outbox events sent before the cutoff and
+// returns how many it removed.
+func (s *Store) DeleteSentEvents(ctx context.Context, before time.Time) (int64, error) {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	return s.queries.DeleteSentOutboxEvents(ctx, pgtype.Timestamptz{Time: before, Valid: true})
+}
+
+// MarkEventFailed records that publishing outbox event id failed with
+// cause, and schedules the next attempt for retryAt.
+func (s *Store) MarkEventFailed(ctx context.Context, id int64, retryAt time.Time, cause error) error {
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+
+	return s.queries.MarkOutboxEventFailed(ctx, db.MarkOutboxEventFailedParams{
+		ID:            id,
+		NextAttemptAt: pgtype.Timestamptz{Time: retryAt, Valid: true},
+		LastError:     pgtype.Text{String: cause.Error(), Valid: true},
+	})
+}
+
+// parseProjectID parses id and rejects values that can never name a project:
+// malformed strings, the nil UUID and, if version is set, other versions.
+// Pure function.
+func parseProjectID(id string, version uuid.Version) (uuid.UUID, error) {
+	uid, err := uuid.Parse(id)
+	if err != nil || uid == uuid.Nil {
+		return uuid.Nil, ErrInvalidProjectID
+	}
+	if version != 0 && uid.Version() != version {
+		return uuid.Nil, ErrInvalidProjectID
+	}
+	return uid, nil
+}
+
+// encodeProvisionParams serializes params for the provision_params column.
+// A nil params is stored as NULL.
+// Pure function.
+func encodeProvisionParams(params *ProvisionParams) ([]byte, error) {
+	if params == nil {
+		return nil, nil
+	}
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("encode provision params: %w", err)
+	}
+	return raw, nil
+}
+
+// decodeProvisionParams is the inverse of encodeProvisionParams.
+// Pure function.
+func decodeProvisionParams(raw []byte) (*ProvisionParams, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var params ProvisionParams
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, fmt.Errorf("decode provision params: %w", err)
+	}
+	return &params, nil
+}
+
+// encodeLabels serializes labels as a JSON object; nil becomes {}.
+// Pure function.
+func encodeLabels(labels map[string]string) ([]byte, error) {
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	raw, err := json.Marshal(labels)
+	if err != nil {
+		return nil, fmt.Errorf("encode labels: %w", err)
+	}
+	return raw, nil
+}
+
+// encodeNetwork serializes network for the network column. A nil or
+// empty network is stored as NULL.
+// Pure function.
+func encodeNetwork(network *plugin.NetworkInfo) ([]byte, error) {
+	if network.Empty() {
+		return nil, nil
+	}
+	raw, err := json.Marshal(network)
+	if err != nil {
+		return nil, fmt.Errorf("encode network: %w", err)
+	}
+	return raw, nil
+}
+
+// decodeNetwork is the inverse of encodeNetwork.
+// Pure function.
+func decodeNetwork(raw []byte) (*plugin.NetworkInfo, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var network plugin.NetworkInfo
+	if err := json.Unmarshal(raw, &network); err != nil {
+		return nil, fmt.Errorf("decode network: %w", err)
+	}
+	return &network, nil
+}
+
+// decodeLabels is the inverse of encodeLabels.
+// Pure function.
+func decodeLabels(raw []byte) (map[string]string, error) {
+	labels := map[string]string{}
+	if len(raw) == 0 {
+		return labels, nil
+	}
+	if err := json.Unmarshal(raw, &labels); err != nil {
+		return nil, fmt.Errorf("decode labels: %w", err)
+	}
+	return labels, nil
+}
+
+func mapToDomainProject(row db.Project) (*Project, error) {
+	provisionParams, err := decodeProvisionParams(row.ProvisionParams)
+	if err != nil {
+		return nil, err
+	}
+	labels, err := decodeLabels(row.Labels)
+	if err != nil {
+		return nil, err
+	}
+	network, err := decodeNetwork(row.Network)
+	if err != nil {
+		return nil, err
+	}
+	project := &Project{
+		ID:              uuid.UUID(row.ID.Bytes).String(),
+		Name:            row.Name,
+		UnixName:        row.UnixName,
+		Description:     row.Description.String,
+		Active:          row.Active,
+		CreatedAt:       row.CreatedAt.Time,
+		UpdatedAt:       row.UpdatedAt.Time,
+		Status:          ProvisionStatus(row.Status),
+		Labels:          labels,
+		ProvisionParams: provisionParams,
+		Network:         network,
+	}
+	if row.OrgID.Valid {
+		project.OrgID = uuid.UUID(row.OrgID.Bytes).String()
+	}
+	project.ResourceID = row.ResourceID.String
+	project.Node = row.Node.String
+	if row.LastCheckedAt.Valid {
+		checked := row.LastCheckedAt.Time
+		project.LastCheckedAt = &checked
 	}
+	return project, nil
 }
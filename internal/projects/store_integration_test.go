@@ -0,0 +1,1143 @@
+//go:build integration
+
+package projects
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/searge/quokka/internal/platform"
+	"github.com/searge/quokka/internal/plugin"
+)
+
+func newIntegrationPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("DATABASE_URL is not set")
+	}
+
+	pool, err := pgxpool.New(context.Background(), dbURL)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+func TestStoreListHonoursQueryTimeoutWhenBlocked(t *testing.T) {
+	pool := newIntegrationPool(t)
+	ctx := context.Background()
+
+	// Hold an exclusive lock in a separate transaction so any read blocks.
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		t.Fatalf("failed to begin locking tx: %v", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil {
+			t.Logf("failed to roll back locking tx: %v", err)
+		}
+	}()
+
+	if _, err := tx.Exec(ctx, "LOCK TABLE projects IN ACCESS EXCLUSIVE MODE"); err != nil {
+		t.Fatalf("failed to lock projects table: %v", err)
+	}
+
+	store := NewStore(pool, WithQueryTimeout(200*time.Millisecond))
+
+	start := time.Now()
+	_, err = store.List(ctx, ListFilter{Limit: 10})
+	elapsed := time.Since(start)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed > 2*time.Second {
+		t.Fatalf("query was not bounded by the timeout, took %v", elapsed)
+	}
+}
+
+func TestStorePing(t *testing.T) {
+	store := NewStore(newIntegrationPool(t), WithQueryTimeout(time.Second))
+
+	if err := store.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := store.Ping(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected Ping to fail with a cancelled context, got %v", err)
+	}
+}
+
+func TestStoreDeleteIsSoftUntilPurged(t *testing.T) {
+	pool := newIntegrationPool(t)
+	ctx := context.Background()
+	store := NewStore(pool)
+
+	unixName := "soft-" + strings.ReplaceAll(time.Now().Format("150405.000000"), ".", "")
+	p, err := store.Create(ctx, CreateProjectRequest{Name: "Soft", UnixName: unixName})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := store.Delete(ctx, p.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	if _, err := store.GetByID(ctx, p.ID); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("expected a deleted project to be hidden, got %v", err)
+	}
+	if err := store.Delete(ctx, p.ID); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("expected deleting twice to report not found, got %v", err)
+	}
+
+	purged, err := store.PurgeDeleted(ctx, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("PurgeDeleted() error = %v", err)
+	}
+	found := false
+	for _, row := range purged {
+		found = found || row.ID == p.ID
+	}
+	if !found {
+		t.Errorf("expected %s among purged projects, got %v", p.ID, purged)
+	}
+}
+
+func TestStoreDeleteArchivesUnixName(t *testing.T) {
+	pool := newIntegrationPool(t)
+	ctx := context.Background()
+	unixName := "reuse-" + strings.ReplaceAll(time.Now().Format("150405.000000"), ".", "")
+
+	t.Run("name stays taken by default", func(t *testing.T) {
+		store := NewStore(pool)
+		p, err := store.Create(ctx, CreateProjectRequest{Name: "Reuse", UnixName: unixName + "-kept"})
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		if err := store.Delete(ctx, p.ID); err != nil {
+			t.Fatalf("Delete() error = %v", err)
+		}
+		if _, err := store.Create(ctx, CreateProjectRequest{Name: "Reuse", UnixName: unixName + "-kept"}); !errors.Is(err, ErrProjectExists) {
+			t.Fatalf("expected ErrProjectExists, got %v", err)
+		}
+	})
+
+	t.Run("name is freed for reuse", func(t *testing.T) {
+		store := NewStore(pool, WithArchivedUnixNames(true))
+		first, err := store.Create(ctx, CreateProjectRequest{Name: "Reuse", UnixName: unixName})
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		if err := store.Delete(ctx, first.ID); err != nil {
+			t.Fatalf("Delete() error = %v", err)
+		}
+
+		second, err := store.Create(ctx, CreateProjectRequest{Name: "Reuse", UnixName: unixName})
+		if err != nil {
+			t.Fatalf("recreating %s: %v", unixName, err)
+		}
+		t.Cleanup(func() {
+			if err := store.Delete(ctx, second.ID); err != nil {
+				t.Logf("failed to delete %s: %v", second.ID, err)
+			}
+		})
+
+		var renamed, archived string
+		err = pool.QueryRow(ctx, `SELECT unix_name, archived_unix_name FROM projects WHERE id = $1`, first.ID).Scan(&renamed, &archived)
+		if err != nil {
+			t.Fatalf("failed to read the deleted project: %v", err)
+		}
+		if archived != unixName || !strings.HasPrefix(renamed, unixName+"-deleted-") {
+			t.Errorf("unix_name = %q, archived_unix_name = %q; want %s-deleted-... and %s", renamed, archived, unixName, unixName)
+		}
+
+		purged, err := store.PurgeDeleted(ctx, time.Now().Add(time.Minute))
+		if err != nil {
+			t.Fatalf("PurgeDeleted() error = %v", err)
+		}
+		for _, row := range purged {
+			if row.ID == first.ID && row.UnixName != unixName {
+				t.Errorf("purged unix_name = %q, want the original %q", row.UnixName, unixName)
+			}
+		}
+	})
+}
+
+func TestStoreUpdateLabelsAppliesToSelectedProjects(t *testing.T) {
+	pool := newIntegrationPool(t)
+	ctx := context.Background()
+	store := NewStore(pool)
+
+	suffix := time.Now().Format("150405.000000")
+	create := func(name string, labels map[string]string, template string) *Project {
+		t.Helper()
+		p, err := store.Create(ctx, CreateProjectRequest{
+			Name:            name,
+			UnixName:        name + "-" + strings.ReplaceAll(suffix, ".", ""),
+			Labels:          labels,
+			ProvisionParams: &ProvisionParams{Plugin: "proxmox", Template: template},
+		})
+		if err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+		t.Cleanup(func() {
+			if err := store.Delete(ctx, p.ID); err != nil {
+				t.Logf("failed to delete %s: %v", name, err)
+			}
+		})
+		return p
+	}
+
+	prodOld := create("prod-old", map[string]string{"env": "prod", "legacy": "yes"}, "debian-11")
+	prodNew := create("prod-new", map[string]string{"env": "prod"}, "debian-12")
+	devOld := create("dev-old", map[string]string{"env": "dev", "legacy": "yes"}, "debian-11")
+	ids := []string{prodOld.ID, prodNew.ID, devOld.ID}
+
+	// Add: only prod projects on the old template match.
+	changed, err := store.UpdateLabels(ctx, LabelSelector{
+		IDs:         ids,
+		MatchLabels: map[string]string{"env": "prod"},
+		Template:    "debian-11",
+	}, map[string]string{"migrate": "debian-12"}, nil)
+	if err != nil {
+		t.Fatalf("UpdateLabels(add) error = %v", err)
+	}
+	if !reflect.DeepEqual(changed, []string{prodOld.ID}) {
+		t.Fatalf("add changed %v, want [%s]", changed, prodOld.ID)
+	}
+
+	// Remove: every selected project carrying legacy=yes.
+	changed, err = store.UpdateLabels(ctx, LabelSelector{
+		IDs:         ids,
+		MatchLabels: map[string]string{"legacy": "yes"},
+	}, nil, []string{"legacy"})
+	if err != nil {
+		t.Fatalf("UpdateLabels(remove) error = %v", err)
+	}
+	if len(changed) != 2 {
+		t.Fatalf("remove changed %d projects, want 2", len(changed))
+	}
+
+	got, err := store.GetByIDs(ctx, ids)
+	if err != nil {
+		t.Fatalf("GetByIDs() error = %v", err)
+	}
+	want := map[string]map[string]string{
+		prodOld.ID: {"env": "prod", "migrate": "debian-12"},
+		prodNew.ID: {"env": "prod"},
+		devOld.ID:  {"env": "dev"},
+	}
+	for _, p := range got {
+		if !reflect.DeepEqual(p.Labels, want[p.ID]) {
+			t.Errorf("%s labels = %v, want %v", p.Name, p.Labels, want[p.ID])
+		}
+	}
+}
+
+func TestStoreListSelectedAndSetProvisionParams(t *testing.T) {
+	pool := newIntegrationPool(t)
+	ctx := context.Background()
+	store := NewStore(pool)
+
+	suffix := strings.ReplaceAll(time.Now().Format("150405.000000"), ".", "")
+	create := func(name, template string) *Project {
+		t.Helper()
+		p, err := store.Create(ctx, CreateProjectRequest{
+			Name:            name,
+			UnixName:        name + "-" + suffix,
+			Labels:          map[string]string{"fleet": suffix},
+			ProvisionParams: &ProvisionParams{Plugin: "proxmox", Template: template},
+		})
+		if err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+		t.Cleanup(func() {
+			if err := store.Delete(ctx, p.ID); err != nil {
+				t.Logf("failed to delete %s: %v", name, err)
+			}
+		})
+		return p
+	}
+
+	first := create("fleet-first", "debian-12")
+	second := create("fleet-second", "debian-12")
+	create("fleet-other", "debian-11")
+
+	sel := LabelSelector{MatchLabels: map[string]string{"fleet": suffix}, Template: "debian-12"}
+	got, err := store.ListSelected(ctx, sel, 10)
+	if err != nil {
+		t.Fatalf("ListSelected() error = %v", err)
+	}
+	if len(got) != 2 || got[0].ID != first.ID || got[1].ID != second.ID {
+		t.Fatalf("ListSelected() = %v, want the two debian-12 projects oldest first", got)
+	}
+	if got, err := store.ListSelected(ctx, sel, 1); err != nil || len(got) != 1 {
+		t.Fatalf("ListSelected(limit 1) = %d projects, %v, want 1", len(got), err)
+	}
+
+	params := &ProvisionParams{Plugin: "proxmox", Template: "debian-13", Resources: map[string]interface{}{"cores": 4.0}}
+	if err := store.SetProvisionParams(ctx, first.ID, params); err != nil {
+		t.Fatalf("SetProvisionParams() error = %v", err)
+	}
+	updated, err := store.GetByID(ctx, first.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if !reflect.DeepEqual(updated.ProvisionParams, params) {
+		t.Errorf("provision params = %+v, want %+v", updated.ProvisionParams, params)
+	}
+	if err := store.SetProvisionParams(ctx, "2a4e6b16-8a62-4d57-a05b-9f59248dbdb2", params); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("expected pgx.ErrNoRows for a missing project, got %v", err)
+	}
+}
+
+func TestStoreRelatedRanksBySharedLabels(t *testing.T) {
+	pool := newIntegrationPool(t)
+	ctx := context.Background()
+	store := NewStore(pool)
+
+	// Label values unique to this run keep other projects out of the ranking.
+	suffix := strings.ReplaceAll(time.Now().Format("150405.000000"), ".", "")
+	team, env := "web-"+suffix, "prod-"+suffix
+	create := func(name string, labels map[string]string) *Project {
+		t.Helper()
+		p, err := store.Create(ctx, CreateProjectRequest{Name: name, UnixName: name + "-" + suffix, Labels: labels})
+		if err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+		t.Cleanup(func() {
+			if err := store.Delete(ctx, p.ID); err != nil {
+				t.Logf("failed to delete %s: %v", name, err)
+			}
+		})
+		return p
+	}
+
+	source := create("source", map[string]string{"team": team, "env": env})
+	both := create("both", map[string]string{"team": team, "env": env, "tier": "db"})
+	teamOnly := create("team-only", map[string]string{"team": team, "env": "dev-" + suffix})
+	create("disjoint", map[string]string{"team": "ops-" + suffix})
+	create("unlabelled", nil)
+	inactive := create("inactive", map[string]string{"team": team})
+	active := false
+	if _, err := store.Update(ctx, inactive.ID, UpdateProjectRequest{Active: &active}); err != nil {
+		t.Fatalf("failed to deactivate: %v", err)
+	}
+
+	related, err := store.Related(ctx, source.ID, 10, 0)
+	if err != nil {
+		t.Fatalf("Related() error = %v", err)
+	}
+	var got []string
+	for _, rp := range related {
+		got = append(got, fmt.Sprintf("%s/%d", rp.ID, rp.SharedLabels))
+	}
+	want := []string{both.ID + "/2", teamOnly.ID + "/1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("related = %v, want %v", got, want)
+	}
+
+	page, err := store.Related(ctx, source.ID, 1, 1)
+	if err != nil {
+		t.Fatalf("Related(page 2) error = %v", err)
+	}
+	if len(page) != 1 || page[0].ID != teamOnly.ID {
+		t.Fatalf("second page = %v, want only %s", page, teamOnly.ID)
+	}
+
+	total, err := store.CountRelated(ctx, source.ID)
+	if err != nil {
+		t.Fatalf("CountRelated() error = %v", err)
+	}
+	if total != int64(len(want)) {
+		t.Fatalf("CountRelated() = %d, want %d", total, len(want))
+	}
+}
+
+func TestStoreListFiltersByStatus(t *testing.T) {
+	pool := newIntegrationPool(t)
+	ctx := context.Background()
+	store := NewStore(pool)
+
+	suffix := strings.ReplaceAll(time.Now().Format("150405.000000"), ".", "")
+	ids := make(map[ProvisionStatus]string)
+	for _, status := range []ProvisionStatus{StatusPending, StatusFailed, StatusProvisioning} {
+		p, err := store.Create(ctx, CreateProjectRequest{Name: string(status), UnixName: string(status) + "-" + suffix})
+		if err != nil {
+			t.Fatalf("failed to create %s project: %v", status, err)
+		}
+		t.Cleanup(func() {
+			if err := store.Delete(ctx, p.ID); err != nil {
+				t.Logf("failed to delete %s: %v", p.Name, err)
+			}
+		})
+		if err := store.SetStatus(ctx, p.ID, status); err != nil {
+			t.Fatalf("SetStatus(%s) error = %v", status, err)
+		}
+		ids[status] = p.ID
+	}
+
+	got, err := store.List(ctx, ListFilter{Statuses: []ProvisionStatus{StatusFailed, StatusProvisioning}, Limit: 1000})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	seen := make(map[string]bool)
+	for _, p := range got {
+		if p.Status != StatusFailed && p.Status != StatusProvisioning {
+			t.Errorf("%s has status %q outside the filter", p.ID, p.Status)
+		}
+		seen[p.ID] = true
+	}
+	if !seen[ids[StatusFailed]] || !seen[ids[StatusProvisioning]] || seen[ids[StatusPending]] {
+		t.Errorf("filtered list %v does not match the seeded statuses %v", seen, ids)
+	}
+}
+
+func TestStoreListFiltersByNode(t *testing.T) {
+	pool := newIntegrationPool(t)
+	ctx := context.Background()
+	store := NewStore(pool)
+
+	suffix := strings.ReplaceAll(time.Now().Format("150405.000000"), ".", "")
+	node := "pve-" + suffix
+	// Projects are listed by where their resource was placed, not by the
+	// node they asked for.
+	seeds := []struct {
+		name   string
+		pinned string
+		placed string
+		status ProvisionStatus
+	}{
+		{name: "on-node", pinned: node, placed: node, status: StatusProvisioned},
+		{name: "on-node-failed", placed: node, status: StatusFailed},
+		{name: "moved-away", pinned: node, placed: node + "-other", status: StatusProvisioned},
+		{name: "elsewhere", placed: node + "-other", status: StatusProvisioned},
+		{name: "unprovisioned", pinned: node, status: StatusPending},
+	}
+	ids := make(map[string]string)
+	for _, seed := range seeds {
+		p, err := store.Create(ctx, CreateProjectRequest{
+			Name:            seed.name,
+			UnixName:        seed.name + "-" + suffix,
+			ProvisionParams: &ProvisionParams{Node: seed.pinned},
+		})
+		if err != nil {
+			t.Fatalf("failed to create %s project: %v", seed.name, err)
+		}
+		t.Cleanup(func() {
+			if err := store.Delete(ctx, p.ID); err != nil {
+				t.Logf("failed to delete %s: %v", p.Name, err)
+			}
+		})
+		if err := store.SetStatus(ctx, p.ID, seed.status); err != nil {
+			t.Fatalf("SetStatus(%s) error = %v", seed.status, err)
+		}
+		if seed.placed != "" {
+			if err := store.SetResource(ctx, p.ID, "vm-"+seed.name, seed.placed); err != nil {
+				t.Fatalf("SetResource() error = %v", err)
+			}
+		}
+		ids[p.ID] = seed.name
+	}
+
+	tests := []struct {
+		name     string
+		statuses []ProvisionStatus
+		want     []string
+	}{
+		{name: "node only", want: []string{"on-node", "on-node-failed"}},
+		{name: "node and status", statuses: []ProvisionStatus{StatusProvisioned}, want: []string{"on-node"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.List(ctx, ListFilter{Statuses: tt.statuses, Node: node, Limit: 1000})
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			var names []string
+			for _, p := range got {
+				if p.Node != node {
+					t.Errorf("%s is on %q, not %s", p.ID, p.Node, node)
+				}
+				names = append(names, ids[p.ID])
+			}
+			slices.Sort(names)
+			if !slices.Equal(names, tt.want) {
+				t.Errorf("listed %v, want %v", names, tt.want)
+			}
+
+			total, err := store.Count(ctx, ListFilter{Statuses: tt.statuses, Node: node})
+			if err != nil {
+				t.Fatalf("Count() error = %v", err)
+			}
+			if total != int64(len(tt.want)) {
+				t.Errorf("Count() = %d, want %d", total, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestServiceConcurrentCreateOfSameUnixName(t *testing.T) {
+	pool := newIntegrationPool(t)
+	ctx := context.Background()
+	store := NewStore(pool)
+
+	var provisions atomic.Int32
+	svc := newService(store, mockRegistry{
+		getFn: func(string) (plugin.Plugin, error) {
+			return mockPlugin{
+				provisionFn: func(context.Context, plugin.ProvisionRequest) (*plugin.ProvisionResult, error) {
+					provisions.Add(1)
+					return &plugin.ProvisionResult{ResourceID: "r-1"}, nil
+				},
+			}, nil
+		},
+	}, nil)
+
+	unixName := "race-" + strings.ReplaceAll(time.Now().Format("150405.000000"), ".", "")
+	const racers = 2
+
+	var (
+		wg      sync.WaitGroup
+		start   = make(chan struct{})
+		results = make([]*Project, racers)
+		errs    = make([]error, racers)
+	)
+	for i := range racers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			results[i], errs[i] = svc.Create(ctx, CreateProjectRequest{Name: "Race", UnixName: unixName})
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	var created *Project
+	var conflicts int
+	for i := range racers {
+		switch {
+		case errs[i] == nil:
+			if created != nil {
+				t.Fatal("both concurrent creates succeeded")
+			}
+			created = results[i]
+		case errors.Is(errs[i], ErrProjectExists):
+			conflicts++
+		default:
+			t.Fatalf("unexpected create error: %v", errs[i])
+		}
+	}
+	if created == nil || conflicts != racers-1 {
+		t.Fatalf("expected exactly one success and %d ErrProjectExists, got success=%v conflicts=%d", racers-1, created != nil, conflicts)
+	}
+	t.Cleanup(func() {
+		if err := store.Delete(ctx, created.ID); err != nil {
+			t.Logf("failed to delete %s: %v", created.ID, err)
+		}
+	})
+
+	// The losing create must not reach the plugin.
+	if got := provisions.Load(); got != 1 {
+		t.Errorf("provisioned %d resources, want 1", got)
+	}
+}
+
+func TestServiceConcurrentCreateOfSameNameWhenUnique(t *testing.T) {
+	pool := newIntegrationPool(t)
+	ctx := context.Background()
+	store := NewStore(pool)
+	svc := newService(store, mockRegistry{
+		getFn: func(string) (plugin.Plugin, error) {
+			return nil, plugin.ErrPluginNotFound
+		},
+	}, nil, WithUniqueNames(true))
+
+	suffix := strings.ReplaceAll(time.Now().Format("150405.000000"), ".", "")
+	names := []string{"Unique " + suffix, "UNIQUE " + suffix}
+
+	var (
+		wg      sync.WaitGroup
+		start   = make(chan struct{})
+		results = make([]*Project, len(names))
+		errs    = make([]error, len(names))
+	)
+	for i, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			results[i], errs[i] = svc.Create(ctx, CreateProjectRequest{Name: name, UnixName: fmt.Sprintf("unique-%s-%d", suffix, i)})
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	var created, conflicts int
+	for i := range names {
+		switch {
+		case errs[i] == nil:
+			created++
+			id := results[i].ID
+			t.Cleanup(func() {
+				if err := store.Delete(ctx, id); err != nil {
+					t.Logf("failed to delete %s: %v", id, err)
+				}
+			})
+		case errors.Is(errs[i], ErrNameExists):
+			conflicts++
+		default:
+			t.Fatalf("unexpected create error: %v", errs[i])
+		}
+	}
+	if created != 1 || conflicts != 1 {
+		t.Fatalf("expected one success and one ErrNameExists, got %d and %d", created, conflicts)
+	}
+}
+
+func TestStoreTransitionStatus(t *testing.T) {
+	pool := newIntegrationPool(t)
+	ctx := context.Background()
+	store := NewStore(pool)
+
+	p, err := store.Create(ctx, CreateProjectRequest{
+		Name:     "Transition",
+		UnixName: "transition-" + strings.ReplaceAll(time.Now().Format("150405.000000"), ".", ""),
+	})
+	if err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := store.Delete(ctx, p.ID); err != nil {
+			t.Logf("failed to delete %s: %v", p.ID, err)
+		}
+	})
+
+	t.Run("moves out of the expected state", func(t *testing.T) {
+		ok, err := store.TransitionStatus(ctx, p.ID, StatusPending, StatusProvisioning)
+		if err != nil || !ok {
+			t.Fatalf("TransitionStatus() = %v, %v; want true, nil", ok, err)
+		}
+		got, err := store.GetByID(ctx, p.ID)
+		if err != nil {
+			t.Fatalf("GetByID() error = %v", err)
+		}
+		if got.Status != StatusProvisioning {
+			t.Errorf("status = %q, want %q", got.Status, StatusProvisioning)
+		}
+	})
+
+	t.Run("is a no-op from another state", func(t *testing.T) {
+		ok, err := store.TransitionStatus(ctx, p.ID, StatusPending, StatusFailed)
+		if err != nil || ok {
+			t.Fatalf("TransitionStatus() = %v, %v; want false, nil", ok, err)
+		}
+		got, err := store.GetByID(ctx, p.ID)
+		if err != nil {
+			t.Fatalf("GetByID() error = %v", err)
+		}
+		if got.Status != StatusProvisioning {
+			t.Errorf("status changed to %q", got.Status)
+		}
+	})
+
+	t.Run("reports a missing project", func(t *testing.T) {
+		_, err := store.TransitionStatus(ctx, "2a4e6b16-8a62-4d57-a05b-9f59248dbdb2", StatusPending, StatusProvisioning)
+		if !errors.Is(err, pgx.ErrNoRows) {
+			t.Fatalf("expected pgx.ErrNoRows, got %v", err)
+		}
+	})
+
+	t.Run("only one concurrent claim wins", func(t *testing.T) {
+		const claimers = 8
+		var (
+			wg   sync.WaitGroup
+			wins atomic.Int32
+		)
+		for range claimers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ok, err := store.TransitionStatus(ctx, p.ID, StatusProvisioning, StatusProvisioned)
+				if err != nil {
+					t.Errorf("TransitionStatus() error = %v", err)
+				}
+				if ok {
+					wins.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+		if got := wins.Load(); got != 1 {
+			t.Errorf("%d claims succeeded, want exactly 1", got)
+		}
+	})
+
+	t.Run("leaves a recently changed project when stale", func(t *testing.T) {
+		ok, err := store.TransitionStaleStatus(ctx, p.ID, StatusProvisioned, StatusCancelled, time.Now().Add(-time.Minute))
+		if err != nil || ok {
+			t.Fatalf("TransitionStaleStatus() = %v, %v; want false, nil", ok, err)
+		}
+		ok, err = store.TransitionStaleStatus(ctx, p.ID, StatusProvisioned, StatusCancelled, time.Now().Add(time.Minute))
+		if err != nil || !ok {
+			t.Fatalf("TransitionStaleStatus() = %v, %v; want true, nil", ok, err)
+		}
+	})
+}
+
+func TestStoreTransfer(t *testing.T) {
+	pool := newIntegrationPool(t)
+	ctx := context.Background()
+	store := NewStore(pool)
+
+	suffix := strings.ReplaceAll(time.Now().Format("150405.000000"), ".", "")
+	orgIDs := []string{"3a9c5e71-0b2d-4f86-9e14-7c6a2d8f0b35", "5d7f1b93-2e4a-4c68-b0f6-9e8c4a0d2f57"}
+	for i, id := range orgIDs {
+		if _, err := pool.Exec(ctx, `INSERT INTO organizations (id, name, unix_name) VALUES ($1, $2, $3)`,
+			id, "Transfer", fmt.Sprintf("transfer-%s-%d", suffix, i)); err != nil {
+			t.Fatalf("failed to create organization: %v", err)
+		}
+	}
+
+	p, err := store.Create(ctx, CreateProjectRequest{Name: "Transfer", UnixName: "transfer-" + suffix, OrgID: orgIDs[0]})
+	if err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := pool.Exec(ctx, `DELETE FROM projects WHERE id = $1`, p.ID); err != nil {
+			t.Logf("failed to delete %s: %v", p.ID, err)
+		}
+		if _, err := pool.Exec(ctx, `DELETE FROM organizations WHERE id = ANY($1)`, orgIDs); err != nil {
+			t.Logf("failed to delete organizations: %v", err)
+		}
+	})
+
+	t.Run("moves from the expected owner", func(t *testing.T) {
+		got, err := store.Transfer(ctx, p.ID, orgIDs[0], orgIDs[1])
+		if err != nil {
+			t.Fatalf("Transfer() error = %v", err)
+		}
+		if got.OrgID != orgIDs[1] {
+			t.Errorf("org_id = %q, want %q", got.OrgID, orgIDs[1])
+		}
+	})
+
+	t.Run("refuses a stale owner", func(t *testing.T) {
+		if _, err := store.Transfer(ctx, p.ID, orgIDs[0], orgIDs[0]); !errors.Is(err, pgx.ErrNoRows) {
+			t.Fatalf("expected pgx.ErrNoRows, got %v", err)
+		}
+	})
+
+	t.Run("rejects an unknown organization", func(t *testing.T) {
+		_, err := store.Transfer(ctx, p.ID, orgIDs[1], "7e1a3c95-4f6b-4d8a-a2c0-1b9e5d7f3a68")
+		if !errors.Is(err, ErrUnknownOrganization) {
+			t.Fatalf("expected ErrUnknownOrganization, got %v", err)
+		}
+	})
+
+	t.Run("rejects a malformed organization ID", func(t *testing.T) {
+		for _, ids := range [][2]string{{"not-a-uuid", orgIDs[1]}, {orgIDs[1], "not-a-uuid"}} {
+			if _, err := store.Transfer(ctx, p.ID, ids[0], ids[1]); !errors.Is(err, ErrUnknownOrganization) {
+				t.Errorf("Transfer(%q, %q) error = %v, want ErrUnknownOrganization", ids[0], ids[1], err)
+			}
+		}
+	})
+}
+
+func TestStoreSyncOrgLabels(t *testing.T) {
+	pool := newIntegrationPool(t)
+	ctx := context.Background()
+	store := NewStore(pool)
+
+	suffix := strings.ReplaceAll(time.Now().Format("150405.000000"), ".", "")
+	const orgID = "8b2d4f61-7a3c-4e95-b1d8-2c6e0a9f4b13"
+	if _, err := pool.Exec(ctx, `INSERT INTO organizations (id, name, unix_name) VALUES ($1, $2, $3)`,
+		orgID, "Sync", "sync-"+suffix); err != nil {
+		t.Fatalf("failed to create organization: %v", err)
+	}
+
+	previous := map[string]string{"env": "dev", "owner": "ops"}
+	inherited, err := store.Create(ctx, CreateProjectRequest{
+		Name: "Inherited", UnixName: "sync-inherited-" + suffix, OrgID: orgID,
+		Labels: map[string]string{"env": "dev", "owner": "ops", "app": "api"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+	overridden, err := store.Create(ctx, CreateProjectRequest{
+		Name: "Overridden", UnixName: "sync-overridden-" + suffix, OrgID: orgID,
+		Labels: map[string]string{"env": "staging", "owner": "ops"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+	other, err := store.Create(ctx, CreateProjectRequest{
+		Name: "Other", UnixName: "sync-other-" + suffix,
+		Labels: map[string]string{"env": "dev"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+	t.Cleanup(func() {
+		ids := []string{inherited.ID, overridden.ID, other.ID}
+		if _, err := pool.Exec(ctx, `DELETE FROM projects WHERE id = ANY($1)`, ids); err != nil {
+			t.Logf("failed to delete projects: %v", err)
+		}
+		if _, err := pool.Exec(ctx, `DELETE FROM organizations WHERE id = $1`, orgID); err != nil {
+			t.Logf("failed to delete organization: %v", err)
+		}
+	})
+
+	current := map[string]string{"env": "prod", "cost-center": "42"}
+	changed, err := store.SyncOrgLabels(ctx, orgID, previous, current)
+	if err != nil {
+		t.Fatalf("SyncOrgLabels() error = %v", err)
+	}
+	if len(changed) != 2 {
+		t.Errorf("changed %v, want 2 projects", changed)
+	}
+
+	want := map[string]map[string]string{
+		inherited.ID:  {"env": "prod", "cost-center": "42", "app": "api"},
+		overridden.ID: {"env": "staging", "cost-center": "42"},
+		other.ID:      {"env": "dev"},
+	}
+	for id, labels := range want {
+		got, err := store.GetByID(ctx, id)
+		if err != nil {
+			t.Fatalf("GetByID(%s) error = %v", id, err)
+		}
+		if !reflect.DeepEqual(got.Labels, labels) {
+			t.Errorf("labels of %s = %v, want %v", got.Name, got.Labels, labels)
+		}
+	}
+
+	// Nothing changes when the defaults have not
+	if changed, err := store.SyncOrgLabels(ctx, orgID, current, current); err != nil || len(changed) != 0 {
+		t.Errorf("second sync changed %v (err %v), want none", changed, err)
+	}
+}
+
+func TestStoreSetNetwork(t *testing.T) {
+	pool := newIntegrationPool(t)
+	ctx := context.Background()
+	store := NewStore(pool)
+	unixName := "net-" + strings.ReplaceAll(time.Now().Format("150405.000000"), ".", "")
+
+	p, err := store.Create(ctx, CreateProjectRequest{Name: "Network", UnixName: unixName})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	t.Cleanup(func() { _ = store.Delete(context.Background(), p.ID) })
+	if p.Network != nil {
+		t.Fatalf("a new project should have no network, got %+v", p.Network)
+	}
+
+	network := &plugin.NetworkInfo{Hostname: "net.lan", IPAddresses: []string{"10.0.0.5", "fd00::5"}}
+	if err := store.SetNetwork(ctx, p.ID, network); err != nil {
+		t.Fatalf("SetNetwork() error = %v", err)
+	}
+	got, err := store.GetByID(ctx, p.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if !got.Network.Equal(network) {
+		t.Fatalf("network = %+v, want %+v", got.Network, network)
+	}
+
+	if err := store.SetNetwork(ctx, p.ID, nil); err != nil {
+		t.Fatalf("SetNetwork(nil) error = %v", err)
+	}
+	if got, _ := store.GetByID(ctx, p.ID); got.Network != nil {
+		t.Fatalf("network = %+v after clearing, want nil", got.Network)
+	}
+}
+
+func TestStoreListForReconcile(t *testing.T) {
+	pool := newIntegrationPool(t)
+	ctx := context.Background()
+	store := NewStore(pool)
+	suffix := strings.ReplaceAll(time.Now().Format("150405.000000"), ".", "")
+
+	// Checked long ago, so no other project sits between the two checks.
+	longAgo := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	cutoff := longAgo.Add(24 * time.Hour)
+	seeds := []struct {
+		name    string
+		checked time.Time
+	}{
+		{name: "stale", checked: longAgo},
+		{name: "fresh", checked: cutoff.Add(time.Hour)},
+		{name: "unchecked"},
+	}
+	ids := make(map[string]string)
+	for _, seed := range seeds {
+		p, err := store.Create(ctx, CreateProjectRequest{Name: seed.name, UnixName: "rec-" + seed.name + "-" + suffix})
+		if err != nil {
+			t.Fatalf("failed to create %s project: %v", seed.name, err)
+		}
+		t.Cleanup(func() { _ = store.Delete(context.Background(), p.ID) })
+		if !seed.checked.IsZero() {
+			if err := store.MarkChecked(ctx, p.ID, seed.checked); err != nil {
+				t.Fatalf("MarkChecked(%s) error = %v", seed.name, err)
+			}
+		}
+		ids[seed.name] = p.ID
+	}
+
+	// positions returns where each seeded project is in the batch.
+	positions := func() map[string]int {
+		t.Helper()
+		batch, err := store.ListForReconcile(ctx, cutoff, 100000)
+		if err != nil {
+			t.Fatalf("ListForReconcile() error = %v", err)
+		}
+		found := make(map[string]int)
+		for i, p := range batch {
+			for name, id := range ids {
+				if p.ID == id {
+					found[name] = i
+				}
+			}
+		}
+		return found
+	}
+
+	found := positions()
+	if _, ok := found["fresh"]; ok {
+		t.Error("a project checked after the cutoff was returned")
+	}
+	stale, staleOK := found["stale"]
+	unchecked, uncheckedOK := found["unchecked"]
+	if !staleOK || !uncheckedOK {
+		t.Fatalf("stale and unchecked projects missing from the batch: %v", found)
+	}
+	if unchecked > stale {
+		t.Errorf("never checked project at %d after the stale one at %d, want it first", unchecked, stale)
+	}
+
+	checkedAt := time.Now().UTC().Truncate(time.Microsecond)
+	if err := store.MarkChecked(ctx, ids["stale"], checkedAt); err != nil {
+		t.Fatalf("MarkChecked() error = %v", err)
+	}
+	if _, ok := positions()["stale"]; ok {
+		t.Error("a project was returned again after it was marked checked")
+	}
+	got, err := store.GetByID(ctx, ids["stale"])
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if got.LastCheckedAt == nil || !got.LastCheckedAt.Equal(checkedAt) {
+		t.Errorf("last_checked_at = %v, want %s", got.LastCheckedAt, checkedAt)
+	}
+
+	if err := store.MarkChecked(ctx, "2a4e6b16-8a62-4d57-a05b-9f59248dbdb2", checkedAt); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("MarkChecked() on a missing project error = %v, want pgx.ErrNoRows", err)
+	}
+}
+
+func TestStoreEventOutbox(t *testing.T) {
+	pool := newIntegrationPool(t)
+	ctx := context.Background()
+	store := NewStore(pool)
+	unixName := "outbox-" + strings.ReplaceAll(time.Now().Format("150405.000000"), ".", "")
+
+	// claim returns the outbox events of projectID due at now, leaving other
+	// tests' events to them once the lease runs out.
+	claim := func(t *testing.T, projectID string, now time.Time) []OutboxEvent {
+		t.Helper()
+		claimed, err := store.ClaimEvents(ctx, now, now.Add(time.Minute), 1000)
+		if err != nil {
+			t.Fatalf("ClaimEvents() error = %v", err)
+		}
+		var own []OutboxEvent
+		for _, e := range claimed {
+			if e.Event.ProjectID == projectID {
+				own = append(own, e)
+			}
+		}
+		return own
+	}
+
+	t.Run("rolls events back with the change", func(t *testing.T) {
+		var created *Project
+		errAbort := errors.New("abort")
+		err := store.Atomically(ctx, func(tx projectStore) error {
+			var err error
+			created, err = tx.Create(ctx, CreateProjectRequest{Name: "Outbox", UnixName: unixName + "-rb"})
+			if err != nil {
+				return err
+			}
+			if err := tx.EnqueueEvents(ctx, newEvent(EventCreated, created, nil)); err != nil {
+				return err
+			}
+			return errAbort
+		})
+		if !errors.Is(err, errAbort) {
+			t.Fatalf("Atomically() error = %v, want errAbort", err)
+		}
+		if _, err := store.GetByID(ctx, created.ID); !errors.Is(err, pgx.ErrNoRows) {
+			t.Errorf("GetByID() error = %v, want the project rolled back", err)
+		}
+		if got := claim(t, created.ID, time.Now()); len(got) != 0 {
+			t.Errorf("claimed %d events of a rolled back change", len(got))
+		}
+	})
+
+	t.Run("leases, retries and marks events sent", func(t *testing.T) {
+		var created *Project
+		err := store.Atomically(ctx, func(tx projectStore) error {
+			var err error
+			created, err = tx.Create(ctx, CreateProjectRequest{Name: "Outbox", UnixName: unixName})
+			if err != nil {
+				return err
+			}
+			return tx.EnqueueEvents(ctx, newEvent(EventCreated, created, nil), transferEvent(created, ""))
+		})
+		if err != nil {
+			t.Fatalf("Atomically() error = %v", err)
+		}
+		t.Cleanup(func() {
+			if _, err := pool.Exec(ctx, `DELETE FROM events_outbox WHERE project_id = $1`, created.ID); err != nil {
+				t.Logf("failed to delete outbox events: %v", err)
+			}
+			_ = store.Delete(context.Background(), created.ID)
+		})
+
+		now := time.Now()
+		got := claim(t, created.ID, now)
+		if len(got) != 2 || got[0].Event.Type != EventCreated || got[1].Event.Type != EventTransferred {
+			t.Fatalf("claimed %+v, want project.created then project.transferred", got)
+		}
+		if got[0].Event.Project == nil || got[0].Event.Project.UnixName != unixName {
+			t.Errorf("event project = %+v, want the created project", got[0].Event.Project)
+		}
+		if again := claim(t, created.ID, now); len(again) != 0 {
+			t.Fatalf("claimed %d leased events again", len(again))
+		}
+
+		if err := store.MarkEventSent(ctx, got[0].ID, now); err != nil {
+			t.Fatalf("MarkEventSent() error = %v", err)
+		}
+		if err := store.MarkEventFailed(ctx, got[1].ID, now, errors.New("broker unavailable")); err != nil {
+			t.Fatalf("MarkEventFailed() error = %v", err)
+		}
+		retried := claim(t, created.ID, now.Add(time.Second))
+		if len(retried) != 1 || retried[0].ID != got[1].ID || retried[0].Attempts != 1 {
+			t.Fatalf("claimed %+v, want only the failed event with one attempt", retried)
+		}
+
+		if _, err := store.DeleteSentEvents(ctx, now.Add(time.Second)); err != nil {
+			t.Fatalf("DeleteSentEvents() error = %v", err)
+		}
+		rows, err := pool.Query(ctx, `SELECT event_type FROM events_outbox WHERE project_id = $1`, created.ID)
+		if err != nil {
+			t.Fatalf("Query() error = %v", err)
+		}
+		left, err := pgx.CollectRows(rows, pgx.RowTo[string])
+		if err != nil {
+			t.Fatalf("CollectRows() error = %v", err)
+		}
+		if len(left) != 1 || left[0] != EventTransferred {
+			t.Errorf("outbox keeps %v, want only the unsent project.transferred", left)
+		}
+	})
+}
+
+func TestStoreEncryptsDesignatedResourcesAtRest(t *testing.T) {
+	pool := newIntegrationPool(t)
+	ctx := context.Background()
+	keyring, err := platform.ParseKeyring([]string{"k1:" + base64.StdEncoding.EncodeToString(make([]byte, 32))})
+	if err != nil {
+		t.Fatalf("ParseKeyring() error = %v", err)
+	}
+	store := NewStore(pool, WithFieldEncryption(keyring, "root_password"))
+	suffix := strings.ReplaceAll(time.Now().Format("150405.000000"), ".", "")
+
+	p, err := store.Create(ctx, CreateProjectRequest{
+		Name:     "Encrypted",
+		UnixName: "enc-" + suffix,
+		ProvisionParams: &ProvisionParams{
+			Plugin:    "proxmox",
+			Resources: map[string]interface{}{"root_password": "hunter2", "cores": float64(2)},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	t.Cleanup(func() { _ = store.Delete(context.Background(), p.ID) })
+	if got := p.ProvisionParams.Resources["root_password"]; got != "hunter2" {
+		t.Errorf("Create() returned root_password %v, want it decrypted", got)
+	}
+
+	var stored string
+	if err := pool.QueryRow(ctx, "SELECT provision_params->'resources'->>'root_password' FROM projects WHERE id = $1", p.ID).Scan(&stored); err != nil {
+		t.Fatalf("failed to read the stored value: %v", err)
+	}
+	if !platform.IsEncrypted(stored) {
+		t.Errorf("stored root_password = %q, want ciphertext", stored)
+	}
+
+	got, err := store.GetByID(ctx, p.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if got.ProvisionParams.Resources["root_password"] != "hunter2" || got.ProvisionParams.Resources["cores"] != float64(2) {
+		t.Errorf("GetByID() resources = %v, want them as created", got.ProvisionParams.Resources)
+	}
+
+	t.Run("in outbox events", func(t *testing.T) {
+		if err := store.EnqueueEvents(ctx, newEvent(EventCreated, got, nil)); err != nil {
+			t.Fatalf("EnqueueEvents() error = %v", err)
+		}
+		t.Cleanup(func() {
+			if _, err := pool.Exec(ctx, `DELETE FROM events_outbox WHERE project_id = $1`, p.ID); err != nil {
+				t.Logf("failed to delete outbox events: %v", err)
+			}
+		})
+		if got.ProvisionParams.Resources["root_password"] != "hunter2" {
+			t.Errorf("EnqueueEvents() changed the caller's project to %v", got.ProvisionParams.Resources)
+		}
+
+		var payload string
+		if err := pool.QueryRow(ctx, "SELECT payload::text FROM events_outbox WHERE project_id = $1", p.ID).Scan(&payload); err != nil {
+			t.Fatalf("failed to read the stored event: %v", err)
+		}
+		if strings.Contains(payload, "hunter2") {
+			t.Errorf("stored event payload holds the plaintext root_password: %s", payload)
+		}
+
+		now := time.Now()
+		claimed, err := store.ClaimEvents(ctx, now, now.Add(time.Minute), 1000)
+		if err != nil {
+			t.Fatalf("ClaimEvents() error = %v", err)
+		}
+		for _, e := range claimed {
+			if e.Event.ProjectID != p.ID {
+				continue
+			}
+			if got := e.Event.Project.ProvisionParams.Resources["root_password"]; got != "hunter2" {
+				t.Errorf("claimed event root_password = %v, want it decrypted", got)
+			}
+			return
+		}
+		t.Errorf("ClaimEvents() did not return the event of %s", p.ID)
+	})
+}
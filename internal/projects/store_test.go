@@ -0,0 +1,111 @@
+package projects
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/searge/quokka/internal/projects/db"
+)
+
+func TestParseProjectID(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      string
+		version uuid.Version
+		wantErr bool
+	}{
+		{name: "valid v4", id: "2a4e6b16-8a62-4d57-a05b-9f59248dbdb2"},
+		{name: "valid v4 with version enforced", id: "2a4e6b16-8a62-4d57-a05b-9f59248dbdb2", version: 4},
+		{name: "v1 without version enforced", id: "c232ab00-9414-11ec-b3c8-9f6bdeced846"},
+		{name: "v1 with v4 enforced", id: "c232ab00-9414-11ec-b3c8-9f6bdeced846", version: 4, wantErr: true},
+		{name: "nil uuid", id: "00000000-0000-0000-0000-000000000000", wantErr: true},
+		{name: "malformed", id: "not-a-uuid", wantErr: true},
+		{name: "empty", id: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uid, err := parseProjectID(tt.id, tt.version)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidProjectID) {
+					t.Fatalf("expected ErrInvalidProjectID, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseProjectID() error = %v", err)
+			}
+			if uid.String() != tt.id {
+				t.Errorf("parsed %q, want %q", uid, tt.id)
+			}
+		})
+	}
+}
+
+func TestProvisionParamsRoundTrip(t *testing.T) {
+	want := &ProvisionParams{
+		Plugin:    "proxmox",
+		Template:  "debian-12",
+		Resources: map[string]interface{}{"cores": float64(4), "storage": "ssd"},
+	}
+
+	raw, err := encodeProvisionParams(want)
+	if err != nil {
+		t.Fatalf("encodeProvisionParams() error = %v", err)
+	}
+	got, err := decodeProvisionParams(raw)
+	if err != nil {
+		t.Fatalf("decodeProvisionParams() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip = %+v, want %+v", got, want)
+	}
+}
+
+func TestProvisionParamsNilIsStoredAsNull(t *testing.T) {
+	raw, err := encodeProvisionParams(nil)
+	if err != nil || raw != nil {
+		t.Fatalf("encodeProvisionParams(nil) = %q, %v; want nil, nil", raw, err)
+	}
+	got, err := decodeProvisionParams(nil)
+	if err != nil || got != nil {
+		t.Fatalf("decodeProvisionParams(nil) = %+v, %v; want nil, nil", got, err)
+	}
+}
+
+func TestDeletedUnixNameSuffix(t *testing.T) {
+	deletedAt := time.Date(2026, 10, 16, 9, 30, 0, 123_000_000, time.UTC)
+	if got, want := deletedUnixNameSuffix(deletedAt), "-deleted-1792143000123"; got != want {
+		t.Errorf("deletedUnixNameSuffix() = %q, want %q", got, want)
+	}
+}
+
+func TestListParamsFromFilter(t *testing.T) {
+	filter := ListFilter{
+		Statuses: []ProvisionStatus{StatusFailed, StatusProvisioning},
+		Node:     "pve-03",
+		Limit:    20,
+		Offset:   40,
+	}
+
+	list := listParams(filter)
+	want := db.ListProjectsParams{Statuses: []string{"failed", "provisioning"}, Node: "pve-03", Limit: 20, Offset: 40}
+	if !reflect.DeepEqual(list, want) {
+		t.Errorf("listParams() = %+v, want %+v", list, want)
+	}
+	count := countParams(filter)
+	if !reflect.DeepEqual(count, db.CountProjectsParams{Statuses: want.Statuses, Node: want.Node}) {
+		t.Errorf("countParams() = %+v does not match listParams() %+v", count, list)
+	}
+}
+
+func TestListParamsWithoutStatusesMatchesAll(t *testing.T) {
+	// The query treats an empty array as "any status"; a NULL array would
+	// match nothing.
+	if got := listParams(ListFilter{}).Statuses; got == nil || len(got) != 0 {
+		t.Errorf("listParams().Statuses = %#v, want an empty non-nil slice", got)
+	}
+}
@@ -0,0 +1,88 @@
+package projects
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/searge/quokka/internal/plugin"
+)
+
+var (
+	ErrTagsDisabled  = errors.New("resource tagging is not enabled")
+	ErrTagSyncFailed = errors.New("tag sync failed")
+)
+
+// AllTagLabels, given to WithTagLabels, tags resources with every label.
+const AllTagLabels = "*"
+
+// WithTagLabels tags the resource of each project with its labels under
+// keys, or with all of them if keys include AllTagLabels, when it is
+// provisioned and on SyncTags. No keys tags nothing and disables SyncTags.
+func WithTagLabels(keys ...string) ServiceOption {
+	return func(s *Service) {
+		s.tagLabels = keys
+	}
+}
+
+// TagSyncResult is what SyncTags set on a project's resource.
+type TagSyncResult struct {
+	ResourceID string            `json:"resource_id"`
+	Tags       map[string]string `json:"tags"`
+}
+
+// SyncTags replaces the tags of project id's resource with its current
+// labels, as selected by WithTagLabels, e.g. after they were changed.
+// Returns ErrProvisioningDisabled if provisioning is off,
+// ErrTagsDisabled if no labels are selected, ErrNotProvisioned if
+// the project has no resource, and an error wrapping
+// errors.ErrUnsupported if its plugin cannot tag resources. Plugin
+// failures are wrapped in ErrTagSyncFailed.
+func (s *Service) SyncTags(ctx context.Context, id string) (*TagSyncResult, error) {
+	if err := s.checkProvisioning(); err != nil {
+		return nil, err
+	}
+	if len(s.tagLabels) == 0 {
+		return nil, ErrTagsDisabled
+	}
+	project, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if project.ResourceID == "" {
+		return nil, ErrNotProvisioned
+	}
+
+	p, err := s.registry.Get(withProvisionDefaults(project.ProvisionParams).Plugin)
+	if err != nil {
+		return nil, err
+	}
+	tags := tagsFor(project.Labels, s.tagLabels)
+	if err := plugin.SetTags(ctx, p, project.ResourceID, tags); err != nil {
+		if errors.Is(err, errors.ErrUnsupported) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %w", ErrTagSyncFailed, err)
+	}
+	return &TagSyncResult{ResourceID: project.ResourceID, Tags: tags}, nil
+}
+
+// tagsFor returns the labels under keys, or all of them if keys include
+// AllTagLabels. Returns an empty map, never nil, if none are selected.
+// Pure function.
+func tagsFor(labels map[string]string, keys []string) map[string]string {
+	if slices.Contains(keys, AllTagLabels) {
+		tags := make(map[string]string, len(labels))
+		maps.Copy(tags, labels)
+		return tags
+	}
+	tags := make(map[string]string, len(keys))
+	for _, key := range keys {
+		if value, ok := labels[key]; ok {
+			tags[key] = value
+		}
+	}
+	return tags
+}
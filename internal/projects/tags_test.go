@@ -0,0 +1,143 @@
+package projects
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/searge/quokka/internal/plugin"
+)
+
+// taggingPlugin is a mockPlugin that can tag resources.
+type taggingPlugin struct {
+	mockPlugin
+	setTagsFn func(ctx context.Context, resourceID string, tags map[string]string) error
+}
+
+func (m taggingPlugin) SetTags(ctx context.Context, resourceID string, tags map[string]string) error {
+	return m.setTagsFn(ctx, resourceID, tags)
+}
+
+// newTagService returns a service whose one project carries labels and is
+// backed by resourceID, provisioned by p.
+func newTagService(resourceID string, labels map[string]string, p plugin.Plugin, opts ...ServiceOption) *Service {
+	return newService(
+		mockStore{
+			getByID: func(_ context.Context, id string) (*Project, error) {
+				return &Project{ID: id, ResourceID: resourceID, Labels: labels}, nil
+			},
+		},
+		mockRegistry{
+			getFn: func(string) (plugin.Plugin, error) {
+				return p, nil
+			},
+		},
+		nil,
+		opts...,
+	)
+}
+
+func TestTagsFor(t *testing.T) {
+	labels := map[string]string{"team": "payments", "env": "prod", "owner": "ana"}
+	tests := []struct {
+		name string
+		keys []string
+		want map[string]string
+	}{
+		{name: "no keys", want: map[string]string{}},
+		{name: "subset", keys: []string{"team", "env", "missing"}, want: map[string]string{"team": "payments", "env": "prod"}},
+		{name: "all", keys: []string{AllTagLabels}, want: labels},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tagsFor(labels, tt.keys)
+			if got == nil || !maps.Equal(got, tt.want) {
+				t.Errorf("tagsFor(%q) = %v, want %v", tt.keys, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServiceProvisionPassesLabelsAsTags(t *testing.T) {
+	var got map[string]string
+	p := mockPlugin{provisionFn: func(_ context.Context, req plugin.ProvisionRequest) (*plugin.ProvisionResult, error) {
+		got = req.Tags
+		return &plugin.ProvisionResult{ResourceID: "vm-1"}, nil
+	}}
+	s := newTagService("vm-1", map[string]string{"team": "payments", "owner": "ana"}, p, WithTagLabels("team"))
+
+	if _, err := s.Reprovision(context.Background(), statusProjectID); err != nil {
+		t.Fatalf("Reprovision() error = %v", err)
+	}
+	if want := map[string]string{"team": "payments"}; !maps.Equal(got, want) {
+		t.Errorf("provisioned with tags %v, want %v", got, want)
+	}
+}
+
+func TestServiceSyncTags(t *testing.T) {
+	var tagged string
+	var got map[string]string
+	p := taggingPlugin{setTagsFn: func(_ context.Context, resourceID string, tags map[string]string) error {
+		tagged, got = resourceID, tags
+		return nil
+	}}
+	s := newTagService("vm-1", map[string]string{"team": "payments", "owner": "ana"}, p, WithTagLabels(AllTagLabels))
+
+	result, err := s.SyncTags(context.Background(), statusProjectID)
+	if err != nil {
+		t.Fatalf("SyncTags() error = %v", err)
+	}
+	want := map[string]string{"team": "payments", "owner": "ana"}
+	if tagged != "vm-1" || !maps.Equal(got, want) {
+		t.Errorf("tagged %q with %v, want vm-1 with %v", tagged, got, want)
+	}
+	if result.ResourceID != "vm-1" || !maps.Equal(result.Tags, want) {
+		t.Errorf("SyncTags() = %+v, want the tags set on vm-1", result)
+	}
+}
+
+func TestHandlerSyncTagsErrors(t *testing.T) {
+	tagger := taggingPlugin{setTagsFn: func(context.Context, string, map[string]string) error {
+		return &plugin.PluginError{Plugin: "proxmox", Op: plugin.OpTag, Class: plugin.ClassTransient, Err: errors.New("cluster busy")}
+	}}
+	labels := map[string]string{"team": "payments"}
+	tests := []struct {
+		name     string
+		service  *Service
+		wantCode int
+		wantErr  string
+	}{
+		{name: "tagging disabled", service: newTagService("vm-1", labels, tagger),
+			wantCode: http.StatusBadRequest, wantErr: "TAGS_UNAVAILABLE"},
+		{name: "not provisioned", service: newTagService("", labels, tagger, WithTagLabels("team")),
+			wantCode: http.StatusConflict, wantErr: "NOT_PROVISIONED"},
+		{name: "plugin cannot tag", service: newTagService("vm-1", labels, mockPlugin{}, WithTagLabels("team")),
+			wantCode: http.StatusUnprocessableEntity, wantErr: "TAGS_UNSUPPORTED"},
+		{name: "plugin failure", service: newTagService("vm-1", labels, tagger, WithTagLabels("team")),
+			wantCode: http.StatusServiceUnavailable, wantErr: "TAG_SYNC_FAILED"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(tt.service, nil)
+
+			rr := httptest.NewRecorder()
+			h.Routes().ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/"+statusProjectID+"/tags/sync", nil))
+
+			var body struct {
+				Error struct {
+					Code string `json:"code"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+				t.Fatalf("failed to decode response body: %v", err)
+			}
+			if rr.Code != tt.wantCode || body.Error.Code != tt.wantErr {
+				t.Fatalf("expected %d %s, got %d %s", tt.wantCode, tt.wantErr, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package projects
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/searge/quokka/internal/platform"
+)
+
+// ErrProvisionThrottled is returned, wrapped in a ThrottleError, when an
+// organization has used up its provisioning budget for the current window.
+var ErrProvisionThrottled = errors.New("organization provisioning throttled")
+
+// ThrottleError tells how long an organization must wait before it may
+// create another project.
+type ThrottleError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottleError) Error() string {
+	return fmt.Sprintf("%v, retry in %s", ErrProvisionThrottled, e.RetryAfter.Round(time.Second))
+}
+
+func (e *ThrottleError) Unwrap() error {
+	return ErrProvisionThrottled
+}
+
+// WithProvisionThrottle allows each organization at most limit creates per
+// window, counted from its first create in the window, so runaway
+// automation cannot overload the backend. Unlike a quota it caps the rate
+// of creates, not how many projects exist. Projects without an
+// organization are never throttled. A limit of zero or less disables it.
+func WithProvisionThrottle(limit int, window time.Duration) ServiceOption {
+	return func(s *Service) {
+		s.throttle = platform.NewRateLimiter(limit, window)
+	}
+}
+
+// checkThrottle spends one create from the organization's budget, or
+// returns a ThrottleError if it has none left. A create that is not
+// stored after all is given back with refundThrottle.
+func (s *Service) checkThrottle(orgID string) error {
+	if !s.throttle.Enabled() || orgID == "" {
+		return nil
+	}
+	state, ok := s.throttle.Take(throttleKey(orgID))
+	if !ok {
+		return &ThrottleError{RetryAfter: max(time.Until(state.Reset), 0)}
+	}
+	return nil
+}
+
+// refundThrottle gives back the create checkThrottle spent for a project
+// that was not stored.
+func (s *Service) refundThrottle(orgID string) {
+	if !s.throttle.Enabled() || orgID == "" {
+		return
+	}
+	s.throttle.Refund(throttleKey(orgID))
+}
+
+// throttleKey returns the budget orgID is counted against: its canonical
+// form, so spellings of one organization's ID share a budget.
+// Pure function.
+func throttleKey(orgID string) string {
+	if uid, err := uuid.Parse(orgID); err == nil {
+		return uid.String()
+	}
+	return orgID
+}
@@ -0,0 +1,148 @@
+package projects
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/searge/quokka/internal/plugin"
+)
+
+// newThrottledService returns a service allowing limit creates per window
+// to each organization, counting the creates that reached the store.
+func newThrottledService(limit int, window time.Duration, created *int) *Service {
+	return newService(
+		mockStore{
+			createFn: func(_ context.Context, req CreateProjectRequest) (*Project, error) {
+				*created++
+				return &Project{ID: "p-1", UnixName: req.UnixName, OrgID: req.OrgID}, nil
+			},
+		},
+		mockRegistry{
+			getFn: func(string) (plugin.Plugin, error) {
+				return nil, plugin.ErrPluginNotFound
+			},
+		},
+		nil,
+		WithProvisionThrottle(limit, window),
+	)
+}
+
+func TestServiceCreateThrottlesPerOrganization(t *testing.T) {
+	const otherOrgID = "9e2d7c41-5a3b-4f6e-8d1c-2b4a6c8e0f12"
+	var created int
+	s := newThrottledService(2, time.Minute, &created)
+	create := func(orgID string) error {
+		_, err := s.Create(context.Background(), CreateProjectRequest{Name: "Valid Name", UnixName: "valid-name", OrgID: orgID})
+		return err
+	}
+
+	// Up to the limit, creates go through.
+	for i := range 2 {
+		if err := create(testOrgID); err != nil {
+			t.Fatalf("create %d: error = %v", i+1, err)
+		}
+	}
+
+	// Past it, they are refused before anything is stored.
+	err := create(testOrgID)
+	var throttled *ThrottleError
+	if !errors.As(err, &throttled) || !errors.Is(err, ErrProvisionThrottled) {
+		t.Fatalf("create 3: error = %v, want a ThrottleError", err)
+	}
+	if throttled.RetryAfter <= 0 || throttled.RetryAfter > time.Minute {
+		t.Errorf("RetryAfter = %s, want within the window", throttled.RetryAfter)
+	}
+	if created != 2 {
+		t.Errorf("store create called %d times, want 2", created)
+	}
+
+	// Other organizations, and projects without one, have budgets of their own.
+	if err := create(otherOrgID); err != nil {
+		t.Errorf("create for another organization: error = %v", err)
+	}
+	for i := range 3 {
+		if err := create(""); err != nil {
+			t.Errorf("create %d without an organization: error = %v", i+1, err)
+		}
+	}
+}
+
+func TestServiceThrottleSharesBudgetAcrossSpellings(t *testing.T) {
+	s := newService(mockStore{}, mockRegistry{}, nil, WithProvisionThrottle(1, time.Minute))
+
+	if err := s.checkThrottle(strings.ToUpper(testOrgID)); err != nil {
+		t.Fatalf("first create: error = %v", err)
+	}
+	if err := s.checkThrottle(testOrgID); !errors.Is(err, ErrProvisionThrottled) {
+		t.Errorf("create with the canonical ID: error = %v, want ErrProvisionThrottled", err)
+	}
+}
+
+func TestServiceCreateFailedInsertSpendsNoBudget(t *testing.T) {
+	fail := true
+	s := newService(
+		mockStore{
+			createFn: func(_ context.Context, req CreateProjectRequest) (*Project, error) {
+				if fail {
+					return nil, errors.New("connection reset")
+				}
+				return &Project{ID: "p-1", UnixName: req.UnixName, OrgID: req.OrgID}, nil
+			},
+		},
+		mockRegistry{},
+		nil,
+		WithProvisionThrottle(1, time.Minute),
+	)
+	req := CreateProjectRequest{Name: "Valid Name", UnixName: "valid-name", OrgID: testOrgID}
+
+	for i := range 3 {
+		if _, err := s.CreatePending(context.Background(), req); err == nil || errors.Is(err, ErrProvisionThrottled) {
+			t.Fatalf("failed create %d: error = %v, want the insert error", i+1, err)
+		}
+	}
+	fail = false
+	if _, err := s.CreatePending(context.Background(), req); err != nil {
+		t.Errorf("create after failed inserts: error = %v, want the budget untouched", err)
+	}
+}
+
+func TestServiceCreateWithoutThrottle(t *testing.T) {
+	var created int
+	s := newThrottledService(0, time.Minute, &created)
+
+	for i := range 5 {
+		if _, err := s.Create(context.Background(), CreateProjectRequest{Name: "Valid Name", UnixName: "valid-name", OrgID: testOrgID}); err != nil {
+			t.Fatalf("create %d: error = %v", i+1, err)
+		}
+	}
+}
+
+func TestHandlerCreateProvisionThrottled(t *testing.T) {
+	var created int
+	h := NewHandler(newThrottledService(1, time.Minute, &created), nil)
+
+	body := `{"name":"Valid Name","unix_name":"valid-name","org_id":"` + testOrgID + `"}`
+	for i, want := range []int{http.StatusCreated, http.StatusTooManyRequests} {
+		rr := httptest.NewRecorder()
+		h.Routes().ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+
+		if rr.Code != want {
+			t.Fatalf("create %d: expected %d, got %d: %s", i+1, want, rr.Code, rr.Body.String())
+		}
+		if want != http.StatusTooManyRequests {
+			continue
+		}
+		if !strings.Contains(rr.Body.String(), "PROVISION_THROTTLED") {
+			t.Errorf("expected PROVISION_THROTTLED code, got %s", rr.Body.String())
+		}
+		if secs, err := strconv.Atoi(rr.Header().Get("Retry-After")); err != nil || secs < 1 || secs > 60 {
+			t.Errorf("Retry-After = %q, want 1 to 60 seconds", rr.Header().Get("Retry-After"))
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package projects
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/searge/quokka/internal/platform"
+)
+
+var (
+	ErrTransferForbidden = errors.New("only an admin may transfer a project")
+	ErrOwnerChanged      = errors.New("project owner changed during the transfer")
+)
+
+// TransferProjectRequest names the organization a project moves to.
+type TransferProjectRequest struct {
+	OrgID string `json:"org_id" validate:"required,uuid"`
+}
+
+// Transfer moves project id to the organization in req, on behalf of
+// actor, and publishes project.transferred. Only an admin may transfer a
+// project: the organization a caller claims to act for is not
+// authenticated, and anyone can read which one owns a project. The new
+// organization must exist and, if the project is active, have room in its
+// quota. Transferring a project to its current owner changes nothing.
+func (s *Service) Transfer(ctx context.Context, id string, req TransferProjectRequest, actor platform.Actor) (*Project, error) {
+	if !actor.Admin {
+		return nil, ErrTransferForbidden
+	}
+	// Stored IDs are canonical, so the one asked for must be too before it
+	// is compared with the owner's.
+	if orgID, err := uuid.Parse(req.OrgID); err == nil {
+		req.OrgID = orgID.String()
+	}
+	if err := s.validate.Struct(req); err != nil {
+		return nil, err
+	}
+
+	project, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if project.OrgID == req.OrgID {
+		return project, nil
+	}
+	quotaOrg := ""
+	if project.Active {
+		quotaOrg = req.OrgID
+	}
+
+	var transferred *Project
+	err = s.commitQuota(ctx, "", quotaOrg, func(store projectStore) ([]Event, error) {
+		if err := s.checkQuota(ctx, store, quotaOrg); err != nil {
+			return nil, err
+		}
+		var err error
+		transferred, err = store.Transfer(ctx, id, project.OrgID, req.OrgID)
+		if err != nil {
+			return nil, err
+		}
+		return []Event{transferEvent(transferred, project.OrgID)}, nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrQuotaExceeded) || errors.Is(err, ErrUnknownOrganization) {
+			return nil, err
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			// The project was there a moment ago: either it was deleted or
+			// someone else moved it first.
+			if _, getErr := s.Get(ctx, id); getErr != nil {
+				return nil, getErr
+			}
+			return nil, ErrOwnerChanged
+		}
+		return nil, fmt.Errorf("transfer project: %w", err)
+	}
+	return transferred, nil
+}
@@ -0,0 +1,178 @@
+package projects
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/searge/quokka/internal/platform"
+)
+
+const (
+	transferProjectID = "8b3e1f60-2c7d-4a95-b0e4-6d1a9c2f7e83"
+	transferFromOrg   = "1c4a7e2b-9d3f-4b68-8e05-3f7b2a6d9c14"
+	transferToOrg     = "6f2d9b81-4e7a-4c3d-a1b5-8c0e2f4a7d96"
+)
+
+// transferStore holds one project owned by transferFromOrg and records the
+// transfers it is asked to make.
+func transferStore(moved *[]string) mockStore {
+	return mockStore{
+		getByID: func(_ context.Context, id string) (*Project, error) {
+			return &Project{ID: id, Name: "alpha", Active: true, OrgID: transferFromOrg}, nil
+		},
+		xferFn: func(_ context.Context, id, from, to string) (*Project, error) {
+			*moved = append(*moved, from+"->"+to)
+			return &Project{ID: id, Name: "alpha", Active: true, OrgID: to}, nil
+		},
+	}
+}
+
+func newTransferRequest(body string, headers map[string]string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/"+transferProjectID+"/transfer", strings.NewReader(body))
+	for k, v := range headers {
+		r.Header.Set(k, v)
+	}
+	return r
+}
+
+func TestHandlerTransferByAdmin(t *testing.T) {
+	var moved []string
+	events := &recordingPublisher{}
+	svc := newService(transferStore(&moved), mockRegistry{}, nil, WithEventPublisher(events))
+	h := NewHandler(svc, nil, WithAdminToken("s3cret"))
+
+	rr := httptest.NewRecorder()
+	h.Routes().ServeHTTP(rr, newTransferRequest(`{"org_id":"`+transferToOrg+`"}`,
+		map[string]string{"Authorization": "Bearer s3cret"}))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var project Project
+	if err := json.Unmarshal(rr.Body.Bytes(), &project); err != nil {
+		t.Fatalf("failed to decode response body: %v", err)
+	}
+	if project.OrgID != transferToOrg {
+		t.Errorf("org_id = %q, want %q", project.OrgID, transferToOrg)
+	}
+	if len(moved) != 1 || moved[0] != transferFromOrg+"->"+transferToOrg {
+		t.Errorf("store transfers = %v, want one from the current owner", moved)
+	}
+	if len(events.events) != 1 || events.events[0].Type != EventTransferred {
+		t.Fatalf("events = %v, want %s", events.types(), EventTransferred)
+	}
+	if got := events.events[0].PreviousOrgID; got != transferFromOrg {
+		t.Errorf("previous_org_id = %q, want %q", got, transferFromOrg)
+	}
+}
+
+func TestHandlerTransferRequiresAdmin(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+	}{
+		{name: "anonymous"},
+		{name: "owning organization", headers: map[string]string{"X-Org-ID": transferFromOrg}},
+		{name: "wrong admin token", headers: map[string]string{"Authorization": "Bearer guess"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var moved []string
+			events := &recordingPublisher{}
+			svc := newService(transferStore(&moved), mockRegistry{}, nil, WithEventPublisher(events))
+			h := NewHandler(svc, nil, WithAdminToken("s3cret"))
+
+			rr := httptest.NewRecorder()
+			h.Routes().ServeHTTP(rr, newTransferRequest(`{"org_id":"`+transferToOrg+`"}`, tt.headers))
+
+			if rr.Code != http.StatusUnauthorized {
+				t.Fatalf("expected 401, got %d: %s", rr.Code, rr.Body.String())
+			}
+			var body platform.APIError
+			if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+				t.Fatalf("failed to decode response body: %v", err)
+			}
+			if body.Error.Code != "ADMIN_REQUIRED" {
+				t.Errorf("error code = %q, want ADMIN_REQUIRED", body.Error.Code)
+			}
+			if len(moved) != 0 || len(events.events) != 0 {
+				t.Errorf("an unauthorized transfer moved %v and published %v", moved, events.types())
+			}
+		})
+	}
+}
+
+func TestHandlerTransferErrors(t *testing.T) {
+	admin := map[string]string{"Authorization": "Bearer s3cret"}
+	tests := []struct {
+		name     string
+		body     string
+		xferErr  error
+		wantCode int
+		wantErr  string
+	}{
+		{name: "missing org", body: `{}`, wantCode: http.StatusUnprocessableEntity, wantErr: "VALIDATION_FAILED"},
+		{name: "unknown org", body: `{"org_id":"` + transferToOrg + `"}`, xferErr: ErrUnknownOrganization,
+			wantCode: http.StatusUnprocessableEntity, wantErr: "UNKNOWN_ORGANIZATION"},
+		{name: "moved concurrently", body: `{"org_id":"` + transferToOrg + `"}`, xferErr: pgx.ErrNoRows,
+			wantCode: http.StatusConflict, wantErr: "OWNER_CHANGED"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := mockStore{
+				getByID: func(_ context.Context, id string) (*Project, error) {
+					return &Project{ID: id, OrgID: transferFromOrg}, nil
+				},
+				xferFn: func(context.Context, string, string, string) (*Project, error) {
+					return nil, tt.xferErr
+				},
+			}
+			h := NewHandler(newService(store, mockRegistry{}, nil), nil, WithAdminToken("s3cret"))
+
+			rr := httptest.NewRecorder()
+			h.Routes().ServeHTTP(rr, newTransferRequest(tt.body, admin))
+
+			if rr.Code != tt.wantCode {
+				t.Fatalf("expected %d, got %d: %s", tt.wantCode, rr.Code, rr.Body.String())
+			}
+			if !strings.Contains(rr.Body.String(), tt.wantErr) {
+				t.Errorf("expected error %s, got %s", tt.wantErr, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestServiceTransferToCurrentOwnerChangesNothing(t *testing.T) {
+	var moved []string
+	events := &recordingPublisher{}
+	s := newService(transferStore(&moved), mockRegistry{}, nil, WithEventPublisher(events))
+
+	project, err := s.Transfer(context.Background(), transferProjectID,
+		TransferProjectRequest{OrgID: strings.ToUpper(transferFromOrg)}, platform.Actor{Admin: true})
+	if err != nil {
+		t.Fatalf("Transfer() error = %v", err)
+	}
+	if project.OrgID != transferFromOrg || len(moved) != 0 || len(events.events) != 0 {
+		t.Errorf("got %+v, moved %v, events %v; want no change", project, moved, events.types())
+	}
+}
+
+func TestServiceTransferIgnoresClaimedOrganization(t *testing.T) {
+	var moved []string
+	s := newService(transferStore(&moved), mockRegistry{}, nil)
+
+	_, err := s.Transfer(context.Background(), transferProjectID,
+		TransferProjectRequest{OrgID: transferToOrg}, platform.Actor{OrgID: transferFromOrg})
+	if !errors.Is(err, ErrTransferForbidden) {
+		t.Fatalf("Transfer() error = %v, want ErrTransferForbidden", err)
+	}
+	if len(moved) != 0 {
+		t.Errorf("store transfers = %v, want none", moved)
+	}
+}
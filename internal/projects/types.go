@@ -1,6 +1,10 @@
 package projects
 
-import "time"
+import (
+	"time"
+
+	"github.com/searge/quokka/internal/plugin"
+)
 
 // Project represents the core domain entity for a client project.
 type Project struct {
@@ -11,13 +15,154 @@ type Project struct {
 	Active      bool      `json:"active"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+
+	// OrgID is the organization that owns the project, if any.
+	OrgID string `json:"org_id,omitempty"`
+
+	// Status is where the project is in its provisioning lifecycle.
+	Status ProvisionStatus `json:"status"`
+
+	// ResourceID identifies the plugin resource backing the project.
+	// Set once provisioning succeeds.
+	ResourceID string `json:"resource_id,omitempty"`
+
+	// Node is where the plugin placed the resource, as it reported it
+	// once provisioning succeeded; empty if it did not say.
+	Node string `json:"node,omitempty"`
+
+	// Network is how to reach the resource, as last reported by the
+	// plugin. Nil until the plugin reports an address.
+	Network *plugin.NetworkInfo `json:"network,omitempty"`
+
+	// LastCheckedAt is when the project was last reconciled with its
+	// resource. Nil until it first is.
+	LastCheckedAt *time.Time `json:"last_checked_at,omitempty"`
+
+	// Labels are free-form key/value tags used to select projects.
+	Labels map[string]string `json:"labels"`
+
+	// ProvisionParams is the provisioning request stored at create time.
+	// Read-only: it is replayed as-is on reprovision.
+	ProvisionParams *ProvisionParams `json:"provision_params,omitempty"`
+
+	// DescriptionHTML is the sanitized HTML rendering of Description.
+	// Only populated on request (?render=html); never stored.
+	DescriptionHTML string `json:"description_html,omitempty"`
+}
+
+// PurgedProject identifies a soft-deleted project that was removed for good.
+type PurgedProject struct {
+	ID        string
+	UnixName  string
+	DeletedAt time.Time
+}
+
+// ProvisionStatus is the provisioning state of a project.
+type ProvisionStatus string
+
+const (
+	// StatusPending projects have not been provisioned yet.
+	StatusPending ProvisionStatus = "pending"
+	// StatusProvisioning projects have a provisioning call in flight.
+	StatusProvisioning ProvisionStatus = "provisioning"
+	// StatusProvisioned projects were provisioned successfully.
+	StatusProvisioned ProvisionStatus = "provisioned"
+	// StatusFailed projects failed their last provisioning attempt.
+	StatusFailed ProvisionStatus = "failed"
+	// StatusCancelled projects had their last provisioning attempt
+	// cancelled while it ran.
+	StatusCancelled ProvisionStatus = "cancelled"
+	// StatusUnmanaged projects were created while provisioning was
+	// disabled; their resources, if any, are managed outside Quokka.
+	StatusUnmanaged ProvisionStatus = "unmanaged"
+)
+
+// Valid reports whether s is one of the known statuses.
+func (s ProvisionStatus) Valid() bool {
+	switch s {
+	case StatusPending, StatusProvisioning, StatusProvisioned, StatusFailed, StatusCancelled, StatusUnmanaged:
+		return true
+	default:
+		return false
+	}
+}
+
+// HasLiveResources reports whether projects in status s may own running
+// infrastructure: a provisioning call is in flight or has succeeded.
+func (s ProvisionStatus) HasLiveResources() bool {
+	return s == StatusProvisioning || s == StatusProvisioned
+}
+
+// Settled reports whether provisioning has finished, one way or another.
+// Unmanaged projects are settled from the start: nothing provisions them.
+func (s ProvisionStatus) Settled() bool {
+	return s == StatusProvisioned || s == StatusFailed || s == StatusCancelled || s == StatusUnmanaged
+}
+
+// In returns a copy of the project with its timestamps expressed in loc.
+// Presentation only: the stored values are unaffected.
+func (p Project) In(loc *time.Location) *Project {
+	p.CreatedAt = p.CreatedAt.In(loc)
+	p.UpdatedAt = p.UpdatedAt.In(loc)
+	if p.LastCheckedAt != nil {
+		checked := p.LastCheckedAt.In(loc)
+		p.LastCheckedAt = &checked
+	}
+	return &p
+}
+
+// BatchGetResult is the outcome of fetching several projects by ID.
+type BatchGetResult struct {
+	Projects []*Project `json:"projects"`
+	NotFound []string   `json:"not_found"`
+}
+
+// RelatedProject is a project found through the labels it shares with
+// another one.
+type RelatedProject struct {
+	*Project
+	// SharedLabels counts the labels, key and value, both projects carry.
+	SharedLabels int64 `json:"shared_labels"`
+}
+
+// ProvisionParams describes what a project asks its plugin to provision.
+type ProvisionParams struct {
+	Plugin    string                 `json:"plugin"`
+	Template  string                 `json:"template,omitempty"`
+	Resources map[string]interface{} `json:"resources,omitempty"`
+	// Node pins the resource to a cluster node, which must be one of the
+	// allowed nodes. Empty uses the plugin's default node.
+	Node string `json:"node,omitempty"`
+	// Idempotent reuses an existing resource on (re)provision, if any.
+	Idempotent bool `json:"idempotent,omitempty"`
+	// SSHKeys are public keys installed on the resource, alongside the
+	// plugin's configured defaults.
+	SSHKeys []string `json:"ssh_keys,omitempty" validate:"omitempty,max=32,dive,ssh_public_key"`
 }
 
 // CreateProjectRequest is the input payload for creating a new project.
 type CreateProjectRequest struct {
 	Name        string `json:"name" validate:"required,min=3,max=255"`
-	UnixName    string `json:"unix_name" validate:"required,min=3,max=100,unix_name"`
+	UnixName    string `json:"unix_name" validate:"required,unix_name_length,unix_name"`
 	Description string `json:"description,omitempty"`
+
+	// DescriptionTemplate renders Description as a text/template before it
+	// is stored; DescriptionVars adds variables to it. See renderDescription.
+	DescriptionTemplate bool              `json:"description_template,omitempty"`
+	DescriptionVars     map[string]string `json:"description_vars,omitempty"`
+
+	// OrgID assigns the project to an organization, whose quota it then
+	// counts against.
+	OrgID string `json:"org_id,omitempty" validate:"omitempty,uuid"`
+
+	Labels map[string]string `json:"labels,omitempty" validate:"omitempty,dive,keys,label_key,endkeys,max=63"`
+
+	// ProvisionParams defaults to the proxmox plugin with no template.
+	ProvisionParams *ProvisionParams `json:"provision_params,omitempty"`
+
+	// Priority orders an async create in the provisioning queue. Empty
+	// falls back to the organization's policy, then normal.
+	Priority string `json:"priority,omitempty" validate:"omitempty,oneof=low normal high urgent"`
 }
 
 // UpdateProjectRequest is the payload for updating an existing project.
@@ -26,3 +171,28 @@ type UpdateProjectRequest struct {
 	Description *string `json:"description,omitempty"`
 	Active      *bool   `json:"active,omitempty"`
 }
+
+// LabelSelector picks projects for a bulk operation. Every criterion that is
+// set must match; at least one must be set.
+type LabelSelector struct {
+	IDs         []string          `json:"ids,omitempty" validate:"omitempty,max=100"`
+	MatchLabels map[string]string `json:"match_labels,omitempty" validate:"omitempty,dive,keys,label_key,endkeys,max=63"`
+	Template    string            `json:"template,omitempty"`
+}
+
+// IsEmpty reports whether the selector would match every project.
+func (s LabelSelector) IsEmpty() bool {
+	return len(s.IDs) == 0 && len(s.MatchLabels) == 0 && s.Template == ""
+}
+
+// BulkLabelRequest adds and removes labels on every selected project.
+type BulkLabelRequest struct {
+	Selector LabelSelector     `json:"selector"`
+	Add      map[string]string `json:"add,omitempty" validate:"omitempty,dive,keys,label_key,endkeys,max=63"`
+	Remove   []string          `json:"remove,omitempty" validate:"omitempty,dive,label_key"`
+}
+
+// BulkLabelResult reports how many projects a bulk label change touched.
+type BulkLabelResult struct {
+	Affected int64 `json:"affected"`
+}
@@ -0,0 +1,53 @@
+package projects
+
+import (
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/searge/quokka/internal/platform"
+)
+
+// Suggest derives a unix name following p from a rejected name: it is
+// lowercased, every run of other characters becomes one hyphen, and it is
+// cut to the maximum length, so "Alpha Project!" becomes "alpha-project".
+// ok is false if the result still breaks p, e.g. is too short or p does
+// not allow hyphens, or is name itself. Pure function.
+func (p UnixNamePolicy) Suggest(name string) (suggestion string, ok bool) {
+	var b strings.Builder
+	pendingHyphen := false
+	for _, r := range strings.ToLower(name) {
+		if ('a' <= r && r <= 'z') || ('0' <= r && r <= '9') {
+			if pendingHyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			pendingHyphen = false
+			b.WriteRune(r)
+			continue
+		}
+		pendingHyphen = true
+	}
+
+	suggestion = b.String()
+	if p.MaxLength > 0 && len(suggestion) > p.MaxLength {
+		suggestion = strings.TrimRight(suggestion[:p.MaxLength], "-")
+	}
+	if suggestion == name || len(suggestion) < p.MinLength || !p.Pattern.MatchString(suggestion) {
+		return "", false
+	}
+	return suggestion, true
+}
+
+// withUnixNameSuggestion attaches a suggested unix name to err when verrs
+// rejected the unix name of req and the policy can derive a valid one.
+func (s *Service) withUnixNameSuggestion(err error, verrs validator.ValidationErrors, req CreateProjectRequest) error {
+	for _, fe := range verrs {
+		if fe.StructField() != "UnixName" || fe.Tag() == "required" {
+			continue
+		}
+		if suggestion, ok := s.unixNames.Suggest(req.UnixName); ok {
+			return &platform.SuggestionError{Err: err, Suggestions: map[string]string{"unix_name": suggestion}}
+		}
+		break
+	}
+	return err
+}
@@ -0,0 +1,119 @@
+package projects
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/searge/quokka/internal/platform"
+)
+
+func TestUnixNamePolicySuggestsValidNames(t *testing.T) {
+	policy := DefaultUnixNamePolicy()
+	validate := newValidator(policy)
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "Alpha Project!", want: "alpha-project"},
+		{name: "Bad_Name", want: "bad-name"},
+		{name: "  my.app  v2 ", want: "my-app-v2"},
+		{name: "__team__", want: "team"},
+		{name: "CI/CD pipeline", want: "ci-cd-pipeline"},
+		{name: strings.Repeat("ab ", 60), want: strings.Repeat("ab-", 33) + "a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validate.Struct(CreateProjectRequest{Name: "Alpha", UnixName: tt.name}); err == nil {
+				t.Fatalf("%q is valid already", tt.name)
+			}
+			got, ok := policy.Suggest(tt.name)
+			if !ok || got != tt.want {
+				t.Fatalf("Suggest(%q) = %q, %v; want %q", tt.name, got, ok, tt.want)
+			}
+			if err := validate.Struct(CreateProjectRequest{Name: "Alpha", UnixName: got}); err != nil {
+				t.Errorf("suggestion %q is itself invalid: %v", got, err)
+			}
+		})
+	}
+}
+
+func TestUnixNamePolicySuggestsNothingItCannotFix(t *testing.T) {
+	noHyphens := UnixNamePolicy{Pattern: regexp.MustCompile(`^[a-z0-9]+$`), MinLength: 3, MaxLength: 100}
+	tests := []struct {
+		name   string
+		policy UnixNamePolicy
+		input  string
+	}{
+		{name: "too short", policy: DefaultUnixNamePolicy(), input: "A!"},
+		{name: "nothing usable", policy: DefaultUnixNamePolicy(), input: "!!!"},
+		{name: "already slugged", policy: DefaultUnixNamePolicy(), input: "ab"},
+		{name: "pattern without hyphens", policy: noHyphens, input: "Alpha Project"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, ok := tt.policy.Suggest(tt.input); ok {
+				t.Errorf("Suggest(%q) = %q, want no suggestion", tt.input, got)
+			}
+		})
+	}
+}
+
+func TestHandlerCreateSuggestsUnixName(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		wantCode string
+	}{
+		{name: "only the unix name is wrong", body: `{"name":"Alpha","unix_name":"Alpha Project"}`, wantCode: "INVALID_UNIX_NAME"},
+		{name: "among other failures", body: `{"unix_name":"Alpha Project"}`, wantCode: "VALIDATION_FAILED"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(newService(mockStore{}, mockRegistry{}, nil), nil)
+
+			rr := httptest.NewRecorder()
+			h.Create(rr, httptest.NewRequest(http.MethodPost, "/projects", strings.NewReader(tt.body)))
+
+			var resp platform.APIError
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response body: %v", err)
+			}
+			if rr.Code != http.StatusUnprocessableEntity || resp.Error.Code != tt.wantCode {
+				t.Fatalf("expected 422 %s, got %d %q", tt.wantCode, rr.Code, resp.Error.Code)
+			}
+			if got := suggestionFor(resp.Error.Fields, "unix_name"); got != "alpha-project" {
+				t.Errorf("unix_name suggestion = %q, want alpha-project; fields %+v", got, resp.Error.Fields)
+			}
+		})
+	}
+}
+
+func TestHandlerValidateSuggestsUnixName(t *testing.T) {
+	h := NewHandler(newService(mockStore{}, mockRegistry{}, nil), nil)
+
+	rr := httptest.NewRecorder()
+	h.Validate(rr, httptest.NewRequest(http.MethodPost, "/projects/validate",
+		strings.NewReader(`{"name":"Alpha","unix_name":"Alpha_Project"}`)))
+
+	var report ValidationReport
+	if err := json.Unmarshal(rr.Body.Bytes(), &report); err != nil {
+		t.Fatalf("failed to decode response body: %v", err)
+	}
+	if got := suggestionFor(report.Fields, "unix_name"); got != "alpha-project" {
+		t.Errorf("unix_name suggestion = %q, want alpha-project; fields %+v", got, report.Fields)
+	}
+}
+
+func suggestionFor(fields []platform.FieldError, field string) string {
+	for _, f := range fields {
+		if f.Field == field {
+			return f.Suggestion
+		}
+	}
+	return ""
+}
@@ -0,0 +1,109 @@
+package projects
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/searge/quokka/internal/platform"
+)
+
+// ValidationReport is the outcome of checking a create request without
+// acting on it.
+type ValidationReport struct {
+	Valid  bool                  `json:"valid"`
+	Fields []platform.FieldError `json:"fields,omitempty"`
+}
+
+// createProblem is a create rule that a request with well-formed fields can
+// still break.
+type createProblem struct {
+	field, rule string
+	err         error
+}
+
+// createProblems checks req against the service's create rules beyond its
+// field tags, in the order ValidateCreate reports them. It needs no I/O.
+func (s *Service) createProblems(req CreateProjectRequest) []createProblem {
+	var problems []createProblem
+	if err := s.checkUnixNameReserved(req.UnixName); err != nil {
+		problems = append(problems, createProblem{"unix_name", "reserved", err})
+	}
+	if _, err := renderDescription(req); err != nil {
+		problems = append(problems, createProblem{"description", "template", err})
+	}
+	params := withProvisionDefaults(req.ProvisionParams)
+	if err := s.checkPluginAllowed(params.Plugin); err != nil {
+		problems = append(problems, createProblem{"provision_params.plugin", "allowed", err})
+	}
+	if err := s.checkNodeAllowed(params.Node); err != nil {
+		problems = append(problems, createProblem{"provision_params.node", "allowed", err})
+	}
+	if err := s.checkTemplateExists(params.Template); err != nil {
+		problems = append(problems, createProblem{"provision_params.template", "exists", err})
+	}
+	if err := s.checkPluginRequest(req, params); err != nil {
+		problems = append(problems, createProblem{rejectedField(err), "plugin", err})
+	}
+	return problems
+}
+
+// CheckCreate runs every check Create would, including that the unix name
+// and, if required, the display name are free and that the organization has
+// quota left, and reports all failures at once. Defaults are applied first.
+// Nothing is created or provisioned. The error is only set if a check could
+// not be run.
+func (s *Service) CheckCreate(ctx context.Context, req CreateProjectRequest) (*ValidationReport, error) {
+	req = s.applyDefaults(req)
+
+	var fields []platform.FieldError
+	invalid := make(map[string]bool)
+
+	var verrs validator.ValidationErrors
+	if err := s.validate.Struct(req); errors.As(err, &verrs) {
+		fields = platform.ValidationFields(verrs)
+		suggestions := platform.Suggestions(s.withUnixNameSuggestion(err, verrs, req))
+		for i, f := range fields {
+			invalid[f.Field] = true
+			fields[i].Suggestion = suggestions[f.Field]
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	for _, p := range s.createProblems(req) {
+		fields = append(fields, platform.FieldError{Field: p.field, Rule: p.rule, Message: p.err.Error()})
+	}
+
+	if !invalid["unix_name"] {
+		taken, err := s.store.ExistsByUnixName(ctx, req.UnixName)
+		if err != nil {
+			return nil, err
+		}
+		if taken {
+			fields = append(fields, platform.FieldError{Field: "unix_name", Rule: "unique", Message: ErrProjectExists.Error()})
+		}
+	}
+	if s.uniqueNames && !invalid["name"] {
+		if err := checkNameFree(ctx, s.store, req.Name, ""); err != nil {
+			if !errors.Is(err, ErrNameExists) {
+				return nil, err
+			}
+			fields = append(fields, platform.FieldError{Field: "name", Rule: "unique", Message: err.Error()})
+		}
+	}
+	if !invalid["org_id"] {
+		if err := s.checkQuota(ctx, s.store, req.OrgID); err != nil {
+			switch {
+			case errors.Is(err, ErrUnknownOrganization):
+				fields = append(fields, platform.FieldError{Field: "org_id", Rule: "exists", Message: err.Error()})
+			case errors.Is(err, ErrQuotaExceeded):
+				fields = append(fields, platform.FieldError{Field: "org_id", Rule: "quota", Message: err.Error()})
+			default:
+				return nil, err
+			}
+		}
+	}
+
+	return &ValidationReport{Valid: len(fields) == 0, Fields: fields}, nil
+}
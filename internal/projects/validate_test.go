@@ -0,0 +1,184 @@
+package projects
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/searge/quokka/internal/plugin"
+)
+
+// noCreateStore fails the test if anything tries to create a project.
+func noCreateStore(t *testing.T, takenUnixNames ...string) mockStore {
+	return mockStore{
+		createFn: func(context.Context, CreateProjectRequest) (*Project, error) {
+			t.Error("validation must not create a project")
+			return nil, nil
+		},
+		unixFn: func(_ context.Context, unixName string) (bool, error) {
+			return slices.Contains(takenUnixNames, unixName), nil
+		},
+		nameFn: func(_ context.Context, name, _ string) (bool, error) {
+			return name == "Taken", nil
+		},
+		activeFn: func(context.Context, string) (int64, error) {
+			return 1, nil
+		},
+	}
+}
+
+// noProvisionRegistry fails the test if anything tries to provision.
+func noProvisionRegistry(t *testing.T) mockRegistry {
+	return mockRegistry{
+		getFn: func(string) (plugin.Plugin, error) {
+			return mockPlugin{
+				provisionFn: func(context.Context, plugin.ProvisionRequest) (*plugin.ProvisionResult, error) {
+					t.Error("validation must not provision")
+					return nil, errors.New("unexpected provision")
+				},
+			}, nil
+		},
+	}
+}
+
+// checkingPlugin is a mockPlugin refusing requests for unix names longer
+// than eight characters, or with tags containing a space, as a plugin with
+// its own naming rules would.
+type checkingPlugin struct {
+	mockPlugin
+}
+
+func (checkingPlugin) ValidateRequest(req plugin.ProvisionRequest) error {
+	if len(req.UnixName) > 8 {
+		return &plugin.RequestError{Field: "unix_name", Err: errors.New("too long")}
+	}
+	for _, value := range req.Tags {
+		if strings.Contains(value, " ") {
+			return &plugin.RequestError{Field: "tags", Err: errors.New("space in tag")}
+		}
+	}
+	return nil
+}
+
+// postValidate sends body to POST /projects/validate and decodes the report.
+func postValidate(t *testing.T, s *Service, body string) ValidationReport {
+	t.Helper()
+
+	h := NewHandler(s, nil)
+	rr := httptest.NewRecorder()
+	h.Validate(rr, httptest.NewRequest(http.MethodPost, "/projects/validate", strings.NewReader(body)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var report ValidationReport
+	if err := json.Unmarshal(rr.Body.Bytes(), &report); err != nil {
+		t.Fatalf("failed to decode response body: %v", err)
+	}
+	return report
+}
+
+func TestHandlerValidateAcceptsValidPayload(t *testing.T) {
+	s := newService(noCreateStore(t), noProvisionRegistry(t), nil, WithUniqueNames(true))
+
+	report := postValidate(t, s, `{"name":"Alpha","unix_name":"alpha"}`)
+	if !report.Valid || len(report.Fields) != 0 {
+		t.Fatalf("expected a valid report, got %+v", report)
+	}
+}
+
+func TestHandlerValidateReportsEveryProblem(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		opts []ServiceOption
+		want []string // field/rule
+	}{
+		{
+			name: "malformed fields",
+			body: `{"name":"Al","unix_name":"Bad_Name"}`,
+			want: []string{"name/min", "unix_name/unix_name"},
+		},
+		{
+			name: "taken names",
+			body: `{"name":"Taken","unix_name":"taken"}`,
+			opts: []ServiceOption{WithUniqueNames(true)},
+			want: []string{"unix_name/unique", "name/unique"},
+		},
+		{
+			name: "disallowed placement and broken template",
+			body: `{"name":"Alpha","unix_name":"alpha","description":"{{.Missing}}","description_template":true,` +
+				`"provision_params":{"plugin":"fake","node":"pve-09"}}`,
+			opts: []ServiceOption{WithAllowedPlugins("proxmox"), WithAllowedNodes("pve-01")},
+			want: []string{"description/template", "provision_params.plugin/allowed", "provision_params.node/allowed"},
+		},
+		{
+			name: "reserved name and unknown template",
+			body: `{"name":"Alpha","unix_name":"admin","provision_params":{"template":"ubuntu-18.04"}}`,
+			opts: []ServiceOption{WithReservedUnixNames("Admin"), WithTemplates("debian-12")},
+			want: []string{"unix_name/reserved", "provision_params.template/exists"},
+		},
+		{
+			name: "quota exceeded",
+			body: `{"name":"Alpha","unix_name":"alpha","org_id":"` + testOrgID + `"}`,
+			opts: []ServiceOption{WithQuotas(fixedQuotas{limit: 1, limited: true, known: true})},
+			want: []string{"org_id/quota"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newService(noCreateStore(t, "taken"), noProvisionRegistry(t), nil, tt.opts...)
+
+			report := postValidate(t, s, tt.body)
+			if report.Valid {
+				t.Fatalf("expected an invalid report, got %+v", report)
+			}
+			var got []string
+			for _, f := range report.Fields {
+				got = append(got, f.Field+"/"+f.Rule)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("fields = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandlerValidateReportsRequestThePluginRefuses(t *testing.T) {
+	s := newService(noCreateStore(t), mockRegistry{
+		getFn: func(string) (plugin.Plugin, error) { return checkingPlugin{}, nil },
+	}, nil)
+
+	report := postValidate(t, s, `{"name":"Alpha","unix_name":"alpha-project"}`)
+	if report.Valid || len(report.Fields) != 1 ||
+		report.Fields[0].Field != "unix_name" || report.Fields[0].Rule != "plugin" {
+		t.Fatalf("expected unix_name/plugin, got %+v", report)
+	}
+
+	report = postValidate(t, s, `{"name":"Alpha","unix_name":"alpha"}`)
+	if !report.Valid {
+		t.Errorf("expected a name the plugin accepts to pass, got %+v", report)
+	}
+}
+
+func TestHandlerValidateReportsLabelsThePluginCannotTag(t *testing.T) {
+	s := newService(noCreateStore(t), mockRegistry{
+		getFn: func(string) (plugin.Plugin, error) { return checkingPlugin{}, nil },
+	}, nil, WithTagLabels("team"))
+
+	report := postValidate(t, s, `{"name":"Alpha","unix_name":"alpha","labels":{"team":"web team","note":"not a tag"}}`)
+	if report.Valid || len(report.Fields) != 1 ||
+		report.Fields[0].Field != "labels" || report.Fields[0].Rule != "plugin" {
+		t.Fatalf("expected labels/plugin, got %+v", report)
+	}
+
+	// Labels that are not tags are not the plugin's concern.
+	report = postValidate(t, s, `{"name":"Alpha","unix_name":"alpha","labels":{"team":"web","note":"not a tag"}}`)
+	if !report.Valid {
+		t.Errorf("expected labels the plugin can tag to pass, got %+v", report)
+	}
+}
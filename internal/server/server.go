@@ -0,0 +1,386 @@
+// Package server wires the domains into the HTTP API server and runs it.
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/searge/quokka/internal/audit"
+	"github.com/searge/quokka/internal/backup"
+	"github.com/searge/quokka/internal/config"
+	"github.com/searge/quokka/internal/integration"
+	"github.com/searge/quokka/internal/operations"
+	"github.com/searge/quokka/internal/orgs"
+	"github.com/searge/quokka/internal/platform"
+	"github.com/searge/quokka/internal/plugin"
+	"github.com/searge/quokka/internal/projects"
+)
+
+// maintenanceLockKey is the PostgreSQL advisory lock serializing
+// POST /admin/maintenance across instances.
+const maintenanceLockKey int64 = 0x71756f6b6b61 // "quokka"
+
+// operationHeartbeat is how often operations are kept alive and
+// abandoned ones failed. Missing three beats takes as long as a project
+// must be left provisioning before it is taken for stale.
+const operationHeartbeat = 20 * time.Second
+
+// Deps are the dependencies Run would otherwise build from its config.
+// Nil fields are built as usual; those given are left for the caller to
+// close.
+type Deps struct {
+	// Version is reported by the health endpoints.
+	Version string
+	// Pool replaces the connection pool to cfg.Database.URL.
+	Pool *pgxpool.Pool
+	// Registry replaces the plugins configured in cfg.
+	Registry *plugin.Registry
+	// Listener replaces listening on cfg.Server.Addr.
+	Listener net.Listener
+}
+
+// Run serves the API described by cfg, which must be valid, until ctx is
+// done, then shuts the server down gracefully. It returns early if a
+// dependency cannot be set up or the server fails.
+func Run(ctx context.Context, cfg config.Config, deps Deps) error {
+	// Background jobs stop with the server, however it ends
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	startedAt := time.Now()
+
+	// Initialize Logger
+	logger, logCloser, err := platform.NewLogger(cfg)
+	if err != nil {
+		return fmt.Errorf("initialize logger: %w", err)
+	}
+	defer func() {
+		if err := logCloser.Close(); err != nil {
+			slog.Error("failed to close log output", "error", err)
+		}
+	}()
+	slog.SetDefault(logger)
+
+	// Setup database connection
+	dbpool := deps.Pool
+	if dbpool == nil {
+		dbpool, err = platform.NewDatabasePool(ctx, cfg.Database, logger)
+		if err != nil {
+			return fmt.Errorf("initialize database: %w", err)
+		}
+		defer dbpool.Close()
+	}
+
+	// Initialize Plugin Registry with the configured plugins, their
+	// operations also kept for the admin audit stream when it is on. The
+	// stream carries every instance's entries, shared through the database.
+	auditFeed := audit.NewPGFeed(dbpool, logger)
+	auditLog := audit.NewLog(audit.DefaultHistory,
+		audit.WithFeed(auditFeed),
+		audit.WithRedactedFields(cfg.Projects.EncryptedFields...),
+	)
+	if cfg.Server.AuditStream {
+		go auditFeed.Listen(ctx, auditLog)
+	}
+	pluginRegistry := deps.Registry
+	if pluginRegistry == nil {
+		var pluginRecorder plugin.Recorder
+		if cfg.Plugins.Audit {
+			pluginRecorder = plugin.NewLogRecorder(logger)
+			if cfg.Server.AuditStream {
+				pluginRecorder = plugin.MultiRecorder{pluginRecorder, auditLog}
+			}
+		}
+		pluginRegistry, err = integration.NewRegistry(cfg, pluginRecorder, plugin.WithCallLogging(logger))
+		if err != nil {
+			return fmt.Errorf("initialize plugins: %w", err)
+		}
+	}
+
+	// Organizations own projects and set their quotas
+	orgService := orgs.NewService(
+		orgs.NewStore(dbpool, orgs.WithQueryTimeout(cfg.Database.QueryTimeout)),
+		logger,
+		orgs.WithDefaultQuota(cfg.Orgs.DefaultMaxActiveProjects),
+	)
+	orgHandler := orgs.NewHandler(orgService, logger, orgs.WithAdminToken(cfg.Server.AdminToken))
+
+	// Initialize Projects Domain
+	projectStoreOpts := []projects.StoreOption{
+		projects.WithQueryTimeout(cfg.Database.QueryTimeout),
+		projects.WithIDVersion(uuid.Version(cfg.Projects.IDVersion)),
+		projects.WithArchivedUnixNames(cfg.Projects.ArchiveDeletedUnixNames),
+	}
+	if len(cfg.Projects.EncryptionKeys) > 0 {
+		keyring, err := platform.ParseKeyring(cfg.Projects.EncryptionKeys)
+		if err != nil {
+			return fmt.Errorf("initialize field encryption: %w", err)
+		}
+		projectStoreOpts = append(projectStoreOpts, projects.WithFieldEncryption(keyring, cfg.Projects.EncryptedFields...))
+	}
+	projectStore := projects.NewStore(dbpool, projectStoreOpts...)
+	projectEvents := projects.NewBroadcaster()
+	projectPublisher := projects.MultiPublisher{projects.NewLogPublisher(logger), projectEvents}
+	if cfg.Server.AuditStream {
+		projectPublisher = append(projectPublisher, auditLog)
+	}
+	projectService := projects.NewService(projectStore, pluginRegistry, logger,
+		projects.WithUnixNamePolicy(projects.UnixNamePolicy{
+			// Validate has already checked that the pattern compiles
+			Pattern:   regexp.MustCompile(cfg.Projects.UnixNamePattern),
+			MinLength: cfg.Projects.UnixNameMinLength,
+			MaxLength: cfg.Projects.UnixNameMaxLength,
+		}),
+		projects.WithEventPublisher(projectPublisher),
+		projects.WithDeferredCreateEvents(cfg.Projects.DeferCreateEvents),
+		projects.WithEventOutbox(cfg.Projects.EventOutbox),
+		projects.WithAllowedPlugins(cfg.Plugins.Allowed...),
+		projects.WithAllowedNodes(cfg.Proxmox.Nodes...),
+		projects.WithTemplates(cfg.Proxmox.Templates...),
+		projects.WithReservedUnixNames(cfg.Projects.ReservedUnixNames...),
+		projects.WithQuotas(orgService),
+		projects.WithProvisionThrottle(cfg.Orgs.ProvisionLimit, cfg.Orgs.ProvisionWindow),
+		projects.WithOrgPriorities(orgService),
+		projects.WithOrgLabels(orgService),
+		projects.WithProvisioning(cfg.ProvisioningEnabled),
+		projects.WithUniqueNames(cfg.Projects.UniqueNames),
+		projects.WithStatusCacheTTL(cfg.Projects.StatusCacheTTL),
+		projects.WithDefaultProvisionEstimate(cfg.Projects.DefaultProvisionEstimate),
+		projects.WithTagLabels(cfg.Projects.TagLabels...),
+		projects.WithRedactedResources(cfg.Projects.EncryptedFields...),
+		projects.WithDefaulters(
+			projects.DefaultLabels(cfg.Projects.DefaultLabels),
+			projects.DefaultDescription(cfg.Projects.DefaultDescription),
+		),
+	)
+
+	// Publish the project events written to the outbox
+	if cfg.Projects.EventOutbox {
+		relay := projects.NewRelay(projectStore, projectPublisher, logger,
+			projects.WithSentRetention(cfg.Projects.EventOutboxRetention))
+		go relay.Run(ctx, cfg.Projects.EventRelayInterval)
+	}
+
+	// Hard-delete soft-deleted projects once past retention
+	if cfg.Projects.PurgeEnabled {
+		purger := projects.NewPurger(projectStore, cfg.Projects.DeletedRetention, logger)
+		go purger.Run(ctx, cfg.Projects.PurgeInterval)
+	}
+
+	// Long-running operations, e.g. provisioning behind POST /projects
+	operationService := operations.NewService(
+		operations.NewStore(dbpool, operations.WithQueryTimeout(cfg.Database.QueryTimeout)),
+		logger,
+		operations.WithWorkers(cfg.Projects.AsyncWorkers),
+		operations.WithQueueDepth(cfg.Projects.AsyncQueueDepth),
+		operations.WithAbandonedHandler(func(ctx context.Context, op *operations.Operation) {
+			if err := projectService.FailAbandoned(ctx, op); err != nil {
+				logger.Warn("failed to settle project of abandoned operation", "operation_id", op.ID, "error", err)
+			}
+		}),
+	)
+	operationHandler := operations.NewHandler(operationService, logger)
+
+	// Operations held here are kept alive, and those whose instance
+	// stopped failed, starting with any left pending before a restart. The
+	// heartbeat outlives ctx to keep draining operations alive.
+	heartbeatCtx, stopHeartbeat := context.WithCancel(context.WithoutCancel(ctx))
+	defer stopHeartbeat()
+	go operationService.Run(heartbeatCtx, operationHeartbeat)
+
+	// Full-fleet backups for disaster recovery, admins only
+	backupHandler := backup.NewHandler(
+		backup.NewService(backup.NewStore(dbpool, backup.WithQueryTimeout(cfg.Database.QueryTimeout)), logger),
+		logger,
+	)
+	auditHandler := audit.NewHandler(auditLog, logger)
+	// One limit shared by every route but the event streams, which stay
+	// open for as long as their client watches
+	inFlight := platform.ConcurrencyLimit(cfg.MaxInFlightRequests(), time.Second)
+	// Field renaming reads request bodies, so only requests the limit
+	// admitted are renamed
+	naming := platform.JSONFieldNaming(platform.FieldNaming(cfg.Server.FieldNaming), platform.MaxRenamedBody)
+	limited := func(next http.Handler) http.Handler { return inFlight(naming(next)) }
+	projectHandler := projects.NewHandler(projectService, logger,
+		projects.WithOperations(operationService),
+		projects.WithCreateDedup(cfg.Projects.CreateDedupWindow),
+		projects.WithEventStream(projectEvents),
+		projects.WithRequestLimit(limited),
+		projects.WithAdminToken(cfg.Server.AdminToken),
+		projects.WithTestingMode(cfg.TestingMode),
+	)
+	if cfg.TestingMode {
+		logger.Warn("testing mode is on: creates honor the " + projects.PluginOverrideHeader + " header")
+	}
+	if !cfg.ProvisioningEnabled {
+		logger.Info("provisioning is disabled: new projects are stored as unmanaged")
+	}
+
+	// Initialize the router
+	router := platform.NewRouter(logger)
+	router.Use(platform.EnvironmentHeader(cfg.Environment))
+
+	// Health endpoints: liveness never touches dependencies, readiness does.
+	// Plugins that provision are only reported: a backend outage must not
+	// take the instances serving reads out of rotation.
+	health := platform.HealthOptions{
+		Version:   deps.Version,
+		StartedAt: startedAt,
+		Checks: map[string]platform.HealthCheck{
+			"database": projectStore.Ping,
+		},
+	}
+	if cfg.ProvisioningEnabled {
+		health.ReportGroups = append(health.ReportGroups, func(ctx context.Context) map[string]platform.HealthCheckResult {
+			results := make(map[string]platform.HealthCheckResult)
+			for _, res := range pluginRegistry.HealthAll(ctx) {
+				results["plugin:"+res.Name] = platform.NewHealthCheckResult(res.Err, res.Latency)
+			}
+			return results
+		})
+	}
+	router.Get(cfg.Server.HealthPath, platform.LivenessHandler(health))
+	router.Get(cfg.Server.HealthPath+"/live", platform.LivenessHandler(health))
+	router.Get(cfg.Server.HealthPath+"/ready", platform.ReadinessHandler(health))
+
+	// Operation queue gauges for Prometheus, alongside the health checks
+	router.Get("/metrics", platform.MetricsHandler(
+		platform.Gauge{
+			Name:  "quokka_operations_queue_depth",
+			Help:  "Operations accepted but waiting for a worker.",
+			Value: func() float64 { return float64(operationService.QueueStats().Depth) },
+		},
+		platform.Gauge{
+			Name:  "quokka_operations_in_flight",
+			Help:  "Operations being run by a worker.",
+			Value: func() float64 { return float64(operationService.QueueStats().InFlight) },
+		},
+		platform.Gauge{
+			Name:  "quokka_operations_oldest_queued_seconds",
+			Help:  "How long the longest-waiting operation has been queued.",
+			Value: func() float64 { return operationService.QueueStats().OldestQueuedSeconds },
+		},
+	))
+
+	// Writes are blocked while read-only; the admin toggle stays reachable
+	readOnly := platform.NewReadOnly(cfg.Server.ReadOnly)
+
+	// API version 1, rate limited per client and bounded by the in-flight
+	// limit; health checks stay outside
+	rateLimit := platform.NewRateLimiter(cfg.Server.RateLimit, cfg.Server.RateLimitWindow)
+	router.Route("/api/v1", func(r chi.Router) {
+		r.Use(rateLimit.Middleware)
+		r.With(limited).Get("/stats", platform.StatsHandler(startedAt, map[string]platform.StatsSource{
+			"projects":   func() any { return projectService.Stats() },
+			"operations": func() any { return operationService.QueueStats() },
+		}))
+		// Everything under /admin is for admins only
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(platform.RequireAdmin(cfg.Server.AdminToken))
+			if cfg.Server.AuditStream {
+				r.Get("/audit/stream", auditHandler.Stream)
+			}
+			r.Group(func(r chi.Router) {
+				r.Use(limited)
+				r.Get("/read-only", readOnly.StatusHandler)
+				r.Put("/read-only", readOnly.ToggleHandler)
+				r.Post("/maintenance", platform.MaintenanceHandler(
+					platform.NewAdvisoryLock(dbpool, maintenanceLockKey),
+					[]platform.MaintenanceTask{
+						{Name: "projects.vacuum", Run: projectStore.Vacuum},
+						{Name: "projects.refresh_counts", Run: projectService.RefreshCounts},
+					},
+					logger,
+				))
+				r.Get("/backup", backupHandler.Backup)
+			})
+			// A restore streams its archive, which field renaming would
+			// read whole before the handler lifts the read timeout
+			r.With(inFlight, readOnly.Middleware).Post("/restore", backupHandler.Restore)
+		})
+		r.With(readOnly.Middleware).Mount("/projects", projectHandler.Routes())
+		r.With(limited).Mount("/operations", operationHandler.Routes())
+		r.With(limited, readOnly.Middleware).Mount("/orgs", orgHandler.Routes())
+	})
+
+	// Listen before serving, so a taken address fails Run right away
+	listener := deps.Listener
+	if listener == nil {
+		listener, err = net.Listen("tcp", cfg.Server.Addr)
+		if err != nil {
+			return fmt.Errorf("listen: %w", err)
+		}
+	}
+
+	// Configure the HTTP server, counting connections for the shutdown report
+	conns := &platform.ConnCounter{}
+	srv := &http.Server{
+		ConnState:         conns.ConnState,
+		Handler:           router,
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       15 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       60 * time.Second,
+		MaxHeaderBytes:    1 << 20, // 1 MB
+	}
+
+	// Run server in a goroutine
+	serveErr := make(chan error, 1)
+	go func() {
+		logger.Info("server listening", "addr", listener.Addr().String(), "tls", cfg.Server.TLSEnabled())
+		if cfg.Server.TLSEnabled() {
+			serveErr <- srv.ServeTLS(listener, cfg.Server.TLSCertFile, cfg.Server.TLSKeyFile)
+		} else {
+			serveErr <- srv.Serve(listener)
+		}
+	}()
+
+	// Wait for cancellation, or for the server to fail on its own
+	select {
+	case err := <-serveErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("serve: %w", err)
+		}
+	case <-ctx.Done():
+	}
+	logger.Info("shutting down server gracefully")
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancelShutdown()
+
+	shutdownStart := time.Now()
+	openConns, runningJobs := conns.Open(), operationService.Running()
+	var report platform.ShutdownReport
+
+	// Operations are drained while the server is still up, so clients can
+	// keep polling them; requests that try to queue more meanwhile get 503
+	// with a Retry-After. Whatever outlasts the timeout is cancelled, and
+	// given a moment to record how it ended before the pool closes.
+	if err := operationService.Shutdown(shutdownCtx); err != nil {
+		logger.Warn("operations cancelled at shutdown", "error", err)
+	}
+	stopHeartbeat()
+	report.JobsAbandoned = operationService.Running()
+	report.JobsCompleted = max(runningJobs-report.JobsAbandoned, 0)
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Error("server shutdown error", "error", err)
+		report.ConnectionsAbandoned = conns.Open()
+	}
+	report.ConnectionsDrained = max(openConns-report.ConnectionsAbandoned, 0)
+
+	report.Duration = time.Since(shutdownStart)
+	report.Log(context.Background(), logger)
+
+	logger.Info("server stopped")
+	return nil
+}
@@ -0,0 +1,303 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/searge/quokka/internal/config"
+	"github.com/searge/quokka/internal/platform"
+	"github.com/searge/quokka/internal/plugin"
+	"github.com/searge/quokka/internal/projects"
+)
+
+// testConfig returns a valid config that keeps the server quiet.
+func testConfig(dbURL string) config.Config {
+	cfg := config.Default()
+	cfg.Database.URL = dbURL
+	cfg.LogLevel = "error"
+	return cfg
+}
+
+// startServer runs the whole stack on a free local port against pool and
+// an empty plugin registry. The returned function stops it and reports
+// what Run returned.
+func startServer(t *testing.T, cfg config.Config, pool *pgxpool.Pool) (baseURL string, stop func() error) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- Run(ctx, cfg, Deps{
+			Version:  "test",
+			Pool:     pool,
+			Registry: plugin.NewRegistry(),
+			Listener: listener,
+		})
+	}()
+
+	stop = func() error {
+		cancel()
+		select {
+		case err := <-done:
+			return err
+		case <-time.After(10 * time.Second):
+			t.Fatal("Run did not return after the context was cancelled")
+			return nil
+		}
+	}
+	return "http://" + listener.Addr().String(), stop
+}
+
+func TestRunServesUntilCancelled(t *testing.T) {
+	// The pool connects lazily: liveness never touches the database.
+	const dbURL = "postgres://quokka@127.0.0.1:1/quokka?sslmode=disable"
+	pool, err := pgxpool.New(context.Background(), dbURL)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	baseURL, stop := startServer(t, testConfig(dbURL), pool)
+
+	resp, err := http.Get(baseURL + "/api/v1/health/live?verbose=true")
+	if err != nil {
+		t.Fatalf("GET /api/v1/health/live: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	var body struct {
+		Version string `json:"version"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode response body: %v", err)
+	}
+	if body.Version != "test" {
+		t.Errorf("version = %q, want %q", body.Version, "test")
+	}
+
+	if err := stop(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if _, err := http.Get(baseURL + "/api/v1/health/live"); err == nil {
+		t.Error("server still answering after shutdown")
+	}
+}
+
+func TestRunFailsWhenAddressIsTaken(t *testing.T) {
+	taken, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer taken.Close()
+
+	const dbURL = "postgres://quokka@127.0.0.1:1/quokka?sslmode=disable"
+	pool, err := pgxpool.New(context.Background(), dbURL)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	cfg := testConfig(dbURL)
+	cfg.Server.Addr = taken.Addr().String()
+	err = Run(context.Background(), cfg, Deps{Pool: pool, Registry: plugin.NewRegistry()})
+	if err == nil || !strings.Contains(err.Error(), "listen") {
+		t.Fatalf("Run() error = %v, want a listen error", err)
+	}
+}
+
+func TestRunRequiresAdminForAdminEndpoints(t *testing.T) {
+	// Nothing may reach the database or change server state: an
+	// unauthorized caller is turned away first.
+	const dbURL = "postgres://quokka@127.0.0.1:1/quokka?sslmode=disable"
+	pool, err := pgxpool.New(context.Background(), dbURL)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	cfg := testConfig(dbURL)
+	cfg.Server.AdminToken = "s3cret"
+	cfg.Server.AuditStream = true
+	baseURL, stop := startServer(t, cfg, pool)
+	defer func() {
+		if err := stop(); err != nil {
+			t.Errorf("Run() error = %v", err)
+		}
+	}()
+
+	endpoints := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{method: http.MethodPost, path: "/api/v1/admin/maintenance"},
+		{method: http.MethodGet, path: "/api/v1/admin/read-only"},
+		{method: http.MethodPut, path: "/api/v1/admin/read-only", body: `{"enabled":true}`},
+		{method: http.MethodGet, path: "/api/v1/admin/backup"},
+		{method: http.MethodPost, path: "/api/v1/admin/restore", body: `{}`},
+		{method: http.MethodGet, path: "/api/v1/admin/audit/stream"},
+		{method: http.MethodPut, path: "/api/v1/orgs/0d1f6a2e-7c3b-4e58-9a40-2b6c8e1f3d57/quota", body: `{"max_active_projects":100}`},
+	}
+	for _, ep := range endpoints {
+		for name, auth := range map[string]string{"anonymous": "", "wrong token": "Bearer guess"} {
+			t.Run(ep.method+" "+ep.path+" "+name, func(t *testing.T) {
+				req, err := http.NewRequest(ep.method, baseURL+ep.path, strings.NewReader(ep.body))
+				if err != nil {
+					t.Fatalf("failed to build request: %v", err)
+				}
+				if auth != "" {
+					req.Header.Set("Authorization", auth)
+				}
+				resp, err := http.DefaultClient.Do(req)
+				if err != nil {
+					t.Fatalf("%s %s: %v", ep.method, ep.path, err)
+				}
+				defer resp.Body.Close()
+				if resp.StatusCode != http.StatusUnauthorized {
+					t.Fatalf("expected 401, got %d", resp.StatusCode)
+				}
+			})
+		}
+	}
+}
+
+func TestRunServesNoAuditStreamWhenOff(t *testing.T) {
+	const dbURL = "postgres://quokka@127.0.0.1:1/quokka?sslmode=disable"
+	pool, err := pgxpool.New(context.Background(), dbURL)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	cfg := testConfig(dbURL)
+	cfg.Server.AdminToken = "s3cret"
+	baseURL, stop := startServer(t, cfg, pool)
+	defer func() {
+		if err := stop(); err != nil {
+			t.Errorf("Run() error = %v", err)
+		}
+	}()
+
+	req, err := http.NewRequest(http.MethodGet, baseURL+"/api/v1/admin/audit/stream", nil)
+	if err != nil {
+		t.Fatalf("failed to build request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer s3cret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET audit stream: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestRunAuditStreamTakesNoInFlightSlot(t *testing.T) {
+	const dbURL = "postgres://quokka@127.0.0.1:1/quokka?sslmode=disable"
+	pool, err := pgxpool.New(context.Background(), dbURL)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	cfg := testConfig(dbURL)
+	cfg.Server.AdminToken = "s3cret"
+	cfg.Server.AuditStream = true
+	cfg.Server.MaxInFlight = 1
+	baseURL, stop := startServer(t, cfg, pool)
+	defer func() {
+		if err := stop(); err != nil {
+			t.Errorf("Run() error = %v", err)
+		}
+	}()
+
+	get := func(path string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, baseURL+path, nil)
+		if err != nil {
+			t.Fatalf("failed to build request: %v", err)
+		}
+		req.Header.Set("Authorization", "Bearer s3cret")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		return resp
+	}
+
+	stream := get("/api/v1/admin/audit/stream")
+	defer stream.Body.Close()
+	if stream.StatusCode != http.StatusOK {
+		t.Fatalf("audit stream: expected 200, got %d", stream.StatusCode)
+	}
+
+	resp := get("/api/v1/admin/read-only")
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 beside an open stream, got %d", resp.StatusCode)
+	}
+}
+
+func TestRunPassesRestoreUploadsThroughUnread(t *testing.T) {
+	const dbURL = "postgres://quokka@127.0.0.1:1/quokka?sslmode=disable"
+	pool, err := pgxpool.New(context.Background(), dbURL)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	cfg := testConfig(dbURL)
+	cfg.Server.AdminToken = "s3cret"
+	baseURL, stop := startServer(t, cfg, pool)
+	defer func() {
+		if err := stop(); err != nil {
+			t.Errorf("Run() error = %v", err)
+		}
+	}()
+
+	// A body field renaming would refuse reaches the restore handler,
+	// which rejects it as no archive
+	body := `{"projects":"` + strings.Repeat("x", platform.MaxRenamedBody) + `"}`
+	req, err := http.NewRequest(http.MethodPost, baseURL+"/api/v1/admin/restore", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("failed to build request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer s3cret")
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST restore: %v", err)
+	}
+	defer resp.Body.Close()
+	var got struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatalf("failed to decode response body: %v", err)
+	}
+	if resp.StatusCode != http.StatusBadRequest || got.Error.Code != "INVALID_ARCHIVE" {
+		t.Fatalf("expected 400 INVALID_ARCHIVE, got %d %s", resp.StatusCode, got.Error.Code)
+	}
+}
+
+func TestMinShutdownTimeoutCoversProvisioning(t *testing.T) {
+	if config.MinShutdownTimeout < projects.ProvisionTimeout {
+		t.Errorf("config.MinShutdownTimeout = %s, shorter than projects.ProvisionTimeout %s",
+			config.MinShutdownTimeout, projects.ProvisionTimeout)
+	}
+}
@@ -0,0 +1,60 @@
+package watch
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// maxEventLine bounds a single line of the stream, i.e. one event's data.
+const maxEventLine = 1 << 20
+
+// sseEvent is one dispatched server-sent event.
+type sseEvent struct {
+	Event string
+	Data  string
+}
+
+// readEvents parses a text/event-stream from r, passing each complete event
+// to fn until fn returns false or the stream ends. Comments, ids and retry
+// hints are ignored; multi-line data is joined with newlines. Returns nil
+// when fn stops the read, io.ErrUnexpectedEOF when the stream ends.
+func readEvents(r io.Reader, fn func(sseEvent) bool) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), maxEventLine)
+
+	var event string
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			// A blank line dispatches the event, if it carried data
+			if len(data) > 0 {
+				if event == "" {
+					event = "message"
+				}
+				if !fn(sseEvent{Event: event, Data: strings.Join(data, "\n")}) {
+					return nil
+				}
+			}
+			event, data = "", nil
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return io.ErrUnexpectedEOF
+}
@@ -0,0 +1,77 @@
+package watch
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadEvents(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream string
+		want   []sseEvent
+	}{
+		{
+			name:   "named events",
+			stream: "event: a\ndata: 1\n\nevent: b\ndata: 2\n\n",
+			want:   []sseEvent{{Event: "a", Data: "1"}, {Event: "b", Data: "2"}},
+		},
+		{
+			name:   "default event name",
+			stream: "data: hello\n\n",
+			want:   []sseEvent{{Event: "message", Data: "hello"}},
+		},
+		{
+			name:   "multi-line data",
+			stream: "event: a\ndata: one\ndata: two\n\n",
+			want:   []sseEvent{{Event: "a", Data: "one\ntwo"}},
+		},
+		{
+			name:   "comments ids and retry are ignored",
+			stream: ": keepalive\n\nid: 7\nretry: 1000\nevent: a\ndata:no-space\n\n",
+			want:   []sseEvent{{Event: "a", Data: "no-space"}},
+		},
+		{
+			name:   "events without data are not dispatched",
+			stream: "event: a\n\nevent: b\ndata: 2\n\n",
+			want:   []sseEvent{{Event: "b", Data: "2"}},
+		},
+		{
+			name:   "incomplete trailing event is dropped",
+			stream: "event: a\ndata: 1\n\nevent: b\ndata: 2\n",
+			want:   []sseEvent{{Event: "a", Data: "1"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []sseEvent
+			err := readEvents(strings.NewReader(tt.stream), func(ev sseEvent) bool {
+				got = append(got, ev)
+				return true
+			})
+			if !errors.Is(err, io.ErrUnexpectedEOF) {
+				t.Errorf("readEvents() error = %v, want io.ErrUnexpectedEOF at end of stream", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("events = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadEventsStopsWhenAsked(t *testing.T) {
+	var got int
+	err := readEvents(strings.NewReader("data: 1\n\ndata: 2\n\n"), func(sseEvent) bool {
+		got++
+		return false
+	})
+	if err != nil {
+		t.Fatalf("readEvents() error = %v", err)
+	}
+	if got != 1 {
+		t.Errorf("received %d events, want 1", got)
+	}
+}
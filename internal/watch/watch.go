@@ -0,0 +1,171 @@
+// Package watch follows a project's event stream from the CLI until its
+// provisioning settles, reconnecting through transient failures.
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/searge/quokka/internal/platform"
+	"github.com/searge/quokka/internal/projects"
+)
+
+// ErrProjectNotFound is returned when the watched project does not exist.
+var ErrProjectNotFound = errors.New("project not found")
+
+// Options configures Project.
+type Options struct {
+	// BaseURL is the API root, e.g. http://localhost:8080/api/v1.
+	BaseURL string
+	// Client defaults to a client without a timeout, as streams are long-lived.
+	Client *http.Client
+	// RetryDelay is the wait before reconnecting.
+	RetryDelay time.Duration
+	// MaxRetries is how many reconnects in a row may fail before giving up.
+	MaxRetries int
+	// OnReadOnly, if set, is called for each response that reports the
+	// API in read-only maintenance mode, per platform.ReadOnlyHeader.
+	OnReadOnly func()
+}
+
+// Project follows the events of project id, passing each to fn, until the
+// project's provisioning settles; it returns the event that settled it. A
+// project that has already settled returns its status at once. Dropped
+// connections and server errors are retried; an unknown project or a
+// rejected request is not.
+func Project(ctx context.Context, opts Options, id string, fn func(projects.Event)) (projects.Event, error) {
+	if opts.Client == nil {
+		opts.Client = &http.Client{}
+	}
+
+	failures := 0
+	for {
+		final, connected, err := stream(ctx, opts, id, fn)
+		if err == nil {
+			return final, nil
+		}
+		if ctx.Err() != nil {
+			return projects.Event{}, ctx.Err()
+		}
+		var permanent *requestError
+		if errors.As(err, &permanent) && !permanent.transient() {
+			return projects.Event{}, err
+		}
+
+		if connected {
+			failures = 0
+		}
+		failures++
+		if failures > opts.MaxRetries {
+			return projects.Event{}, fmt.Errorf("watch project %s: giving up after %d attempts: %w", id, failures, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return projects.Event{}, ctx.Err()
+		case <-time.After(opts.RetryDelay):
+		}
+	}
+}
+
+// stream reads one connection's events until the project settles or the
+// connection ends. connected reports whether the stream was opened.
+func stream(ctx context.Context, opts Options, id string, fn func(projects.Event)) (final projects.Event, connected bool, err error) {
+	endpoint := strings.TrimSuffix(opts.BaseURL, "/") + "/projects/" + url.PathEscape(id) + "/events"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return projects.Event{}, false, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := opts.Client.Do(req)
+	if err != nil {
+		return projects.Event{}, false, err
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+	if opts.OnReadOnly != nil && resp.Header.Get(platform.ReadOnlyHeader) == "true" {
+		opts.OnReadOnly()
+	}
+	if resp.StatusCode != http.StatusOK {
+		return projects.Event{}, false, newRequestError(resp)
+	}
+
+	var settledBy *projects.Event
+	var decodeErr error
+	readErr := readEvents(resp.Body, func(raw sseEvent) bool {
+		var ev projects.Event
+		if err := json.Unmarshal([]byte(raw.Data), &ev); err != nil {
+			decodeErr = fmt.Errorf("decode %s event: %w", raw.Event, err)
+			return false
+		}
+		fn(ev)
+		if settled(ev) {
+			settledBy = &ev
+			return false
+		}
+		return true
+	})
+	switch {
+	case settledBy != nil:
+		return *settledBy, true, nil
+	case decodeErr != nil:
+		return projects.Event{}, true, decodeErr
+	default:
+		return projects.Event{}, true, fmt.Errorf("event stream closed: %w", readErr)
+	}
+}
+
+// settled reports whether ev shows the project's provisioning finished.
+// Pure function.
+func settled(ev projects.Event) bool {
+	switch ev.Type {
+	case projects.EventProvisioned, projects.EventProvisionFailed, projects.EventProvisionCancelled:
+		return true
+	case projects.EventStatus:
+		return ev.Project != nil && ev.Project.Status.Settled()
+	default:
+		return false
+	}
+}
+
+// requestError is a non-200 answer to the stream request.
+type requestError struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func newRequestError(resp *http.Response) error {
+	reqErr := &requestError{Status: resp.StatusCode}
+	var body platform.APIError
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&body); err == nil {
+		reqErr.Code, reqErr.Message = body.Error.Code, body.Error.Message
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %w", ErrProjectNotFound, reqErr)
+	}
+	return reqErr
+}
+
+func (e *requestError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("unexpected status %d", e.Status)
+	}
+	return fmt.Sprintf("%s (%d %s)", e.Message, e.Status, e.Code)
+}
+
+// transient reports whether retrying the request may succeed.
+func (e *requestError) transient() bool {
+	return e.Status == http.StatusTooManyRequests || e.Status >= http.StatusInternalServerError
+}
@@ -0,0 +1,189 @@
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/searge/quokka/internal/platform"
+	"github.com/searge/quokka/internal/projects"
+)
+
+func statusEvent(status projects.ProvisionStatus) projects.Event {
+	return projects.Event{Type: projects.EventStatus, ProjectID: "p-1", Project: &projects.Project{ID: "p-1", UnixName: "demo", Status: status}}
+}
+
+// writeEvents writes events to w as one server-sent event stream.
+func writeEvents(t *testing.T, w http.ResponseWriter, events ...projects.Event) {
+	t.Helper()
+	w.Header().Set("Content-Type", "text/event-stream")
+	for _, ev := range events {
+		data, err := json.Marshal(ev)
+		if err != nil {
+			t.Errorf("failed to encode event: %v", err)
+			return
+		}
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, data); err != nil {
+			t.Errorf("failed to write event: %v", err)
+			return
+		}
+	}
+}
+
+func testOptions(url string) Options {
+	return Options{BaseURL: url, RetryDelay: time.Millisecond, MaxRetries: 2}
+}
+
+func TestSettled(t *testing.T) {
+	tests := []struct {
+		name string
+		ev   projects.Event
+		want bool
+	}{
+		{name: "pending status", ev: statusEvent(projects.StatusPending), want: false},
+		{name: "provisioning status", ev: statusEvent(projects.StatusProvisioning), want: false},
+		{name: "provisioned status", ev: statusEvent(projects.StatusProvisioned), want: true},
+		{name: "failed status", ev: statusEvent(projects.StatusFailed), want: true},
+		{name: "status without project", ev: projects.Event{Type: projects.EventStatus}, want: false},
+		{name: "created", ev: projects.Event{Type: projects.EventCreated}, want: false},
+		{name: "provisioned", ev: projects.Event{Type: projects.EventProvisioned}, want: true},
+		{name: "provision failed", ev: projects.Event{Type: projects.EventProvisionFailed}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := settled(tt.ev); got != tt.want {
+				t.Errorf("settled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProjectReconnectsUntilSettled(t *testing.T) {
+	var connections atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/projects/p-1/events" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		switch connections.Add(1) {
+		case 1:
+			// Drops the connection before the project settles
+			writeEvents(t, w, statusEvent(projects.StatusProvisioning))
+		case 2:
+			platform.RespondError(w, http.StatusServiceUnavailable, "SERVER_BUSY", "busy")
+		default:
+			writeEvents(t, w, statusEvent(projects.StatusProvisioning), projects.Event{Type: projects.EventProvisioned, ProjectID: "p-1"})
+		}
+	}))
+	defer srv.Close()
+
+	var seen []string
+	final, err := Project(context.Background(), testOptions(srv.URL), "p-1", func(ev projects.Event) {
+		seen = append(seen, ev.Type)
+	})
+	if err != nil {
+		t.Fatalf("Project() error = %v", err)
+	}
+	if final.Type != projects.EventProvisioned {
+		t.Errorf("final event = %s, want %s", final.Type, projects.EventProvisioned)
+	}
+	if got := connections.Load(); got != 3 {
+		t.Errorf("connections = %d, want 3", got)
+	}
+	if len(seen) != 3 {
+		t.Errorf("events seen = %v, want 3", seen)
+	}
+}
+
+func TestProjectReturnsAtOnceWhenAlreadySettled(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		writeEvents(t, w, statusEvent(projects.StatusFailed))
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("flush failed: %v", err)
+		}
+		// Keep the stream open: the watcher must not wait for more
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	final, err := Project(context.Background(), testOptions(srv.URL), "p-1", func(projects.Event) {})
+	if err != nil {
+		t.Fatalf("Project() error = %v", err)
+	}
+	if final.Project == nil || final.Project.Status != projects.StatusFailed {
+		t.Errorf("unexpected final event: %+v", final)
+	}
+}
+
+func TestProjectReportsReadOnlyMode(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set(platform.ReadOnlyHeader, "true")
+		writeEvents(t, w, statusEvent(projects.StatusProvisioned))
+	}))
+	defer srv.Close()
+
+	opts := testOptions(srv.URL)
+	reported := 0
+	opts.OnReadOnly = func() { reported++ }
+	if _, err := Project(context.Background(), opts, "p-1", func(projects.Event) {}); err != nil {
+		t.Fatalf("Project() error = %v", err)
+	}
+	if reported != 1 {
+		t.Errorf("OnReadOnly called %d times, want 1", reported)
+	}
+}
+
+func TestProjectDoesNotRetryPermanentErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr error
+	}{
+		{name: "unknown project", status: http.StatusNotFound, wantErr: ErrProjectNotFound},
+		{name: "invalid id", status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var connections atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				connections.Add(1)
+				platform.RespondError(w, tt.status, "ERR", "rejected")
+			}))
+			defer srv.Close()
+
+			_, err := Project(context.Background(), testOptions(srv.URL), "p-1", func(projects.Event) {})
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+			if got := connections.Load(); got != 1 {
+				t.Errorf("connections = %d, want 1", got)
+			}
+		})
+	}
+}
+
+func TestProjectGivesUpAfterMaxRetries(t *testing.T) {
+	var connections atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		connections.Add(1)
+		platform.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "boom")
+	}))
+	defer srv.Close()
+
+	if _, err := Project(context.Background(), testOptions(srv.URL), "p-1", func(projects.Event) {}); err == nil {
+		t.Fatal("expected Project to give up")
+	}
+	if got := connections.Load(); got != 3 {
+		t.Errorf("connections = %d, want the first attempt plus 2 retries", got)
+	}
+}
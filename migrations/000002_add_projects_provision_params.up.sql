@@ -0,0 +1,2 @@
+-- The provisioning request a project was created with, replayed on reprovision.
+ALTER TABLE projects ADD COLUMN provision_params JSONB;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS projects_labels_idx;
+ALTER TABLE projects DROP COLUMN IF EXISTS labels;
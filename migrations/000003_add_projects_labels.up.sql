@@ -0,0 +1,2 @@
+ALTER TABLE projects ADD COLUMN labels JSONB NOT NULL DEFAULT '{}';
+CREATE INDEX projects_labels_idx ON projects USING GIN (labels);
@@ -0,0 +1,4 @@
+-- Where a project is in its provisioning lifecycle.
+ALTER TABLE projects ADD COLUMN status TEXT NOT NULL DEFAULT 'pending'
+    CHECK (status IN ('pending', 'provisioning', 'provisioned', 'failed'));
+CREATE INDEX projects_status_idx ON projects (status);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS projects_deleted_at_idx;
+ALTER TABLE projects DROP COLUMN IF EXISTS deleted_at;
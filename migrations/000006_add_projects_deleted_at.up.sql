@@ -0,0 +1,3 @@
+-- Soft delete: deleted projects keep their row until purged after retention.
+ALTER TABLE projects ADD COLUMN deleted_at TIMESTAMPTZ;
+CREATE INDEX projects_deleted_at_idx ON projects (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS projects_org_id_idx;
+ALTER TABLE projects DROP COLUMN IF EXISTS org_id;
+DROP TABLE IF EXISTS organizations;
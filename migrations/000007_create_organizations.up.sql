@@ -0,0 +1,13 @@
+-- Organizations own projects and carry per-organization limits.
+CREATE TABLE IF NOT EXISTS organizations (
+    id UUID PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    unix_name VARCHAR(100) NOT NULL UNIQUE,
+    -- NULL falls back to the configured default quota.
+    max_active_projects INTEGER CHECK (max_active_projects >= 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE projects ADD COLUMN org_id UUID REFERENCES organizations (id);
+CREATE INDEX projects_org_id_idx ON projects (org_id);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS projects_lower_name_idx;
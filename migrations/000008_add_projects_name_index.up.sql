@@ -0,0 +1,2 @@
+-- Supports case-insensitive display name lookups when names must be unique.
+CREATE INDEX IF NOT EXISTS projects_lower_name_idx ON projects (lower(name)) WHERE deleted_at IS NULL;
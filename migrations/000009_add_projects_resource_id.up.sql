@@ -0,0 +1,2 @@
+-- The plugin resource backing a project, recorded once provisioning succeeds.
+ALTER TABLE projects ADD COLUMN resource_id TEXT;
@@ -0,0 +1,4 @@
+UPDATE projects SET status = 'failed' WHERE status = 'cancelled';
+ALTER TABLE projects DROP CONSTRAINT IF EXISTS projects_status_check;
+ALTER TABLE projects ADD CONSTRAINT projects_status_check
+    CHECK (status IN ('pending', 'provisioning', 'provisioned', 'failed'));
@@ -0,0 +1,4 @@
+-- Provisioning can be cancelled while it runs.
+ALTER TABLE projects DROP CONSTRAINT IF EXISTS projects_status_check;
+ALTER TABLE projects ADD CONSTRAINT projects_status_check
+    CHECK (status IN ('pending', 'provisioning', 'provisioned', 'failed', 'cancelled'));
@@ -0,0 +1,3 @@
+-- Priority of the organization's queued provisioning; NULL is normal.
+ALTER TABLE organizations ADD COLUMN provision_priority TEXT
+    CHECK (provision_priority IN ('low', 'normal', 'high', 'urgent'));
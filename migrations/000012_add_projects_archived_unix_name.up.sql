@@ -0,0 +1,3 @@
+-- The unix_name a soft-deleted project had before it was renamed to free it
+-- for reuse; NULL when it kept its name.
+ALTER TABLE projects ADD COLUMN archived_unix_name VARCHAR(100);
@@ -0,0 +1,3 @@
+-- How to reach the resource backing a project (hostname, IP addresses), as
+-- its plugin last reported it.
+ALTER TABLE projects ADD COLUMN network JSONB;
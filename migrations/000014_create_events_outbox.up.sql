@@ -0,0 +1,17 @@
+-- Project events waiting to be published, written in the same transaction
+-- as the change they describe so none is lost if the process dies before
+-- delivering it. The relay leases due rows by pushing next_attempt_at
+-- ahead, then marks them sent or schedules a retry.
+CREATE TABLE IF NOT EXISTS events_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    event_type TEXT NOT NULL,
+    project_id UUID NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_error TEXT,
+    sent_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS events_outbox_pending_idx ON events_outbox (next_attempt_at, id) WHERE sent_at IS NULL;
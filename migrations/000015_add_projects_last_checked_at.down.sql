@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS projects_last_checked_at_idx;
+ALTER TABLE projects DROP COLUMN IF EXISTS last_checked_at;
@@ -0,0 +1,6 @@
+-- When the reconciler last compared a project with its resource; NULL until
+-- it first does. Reconciliation works through the least recently checked
+-- projects first.
+ALTER TABLE projects ADD COLUMN last_checked_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS projects_last_checked_at_idx ON projects (last_checked_at NULLS FIRST, id) WHERE deleted_at IS NULL;
@@ -0,0 +1,4 @@
+-- When an operation is expected to finish, estimated from past runs of the
+-- same kind of work when it was accepted; NULL when there was nothing to
+-- estimate from.
+ALTER TABLE operations ADD COLUMN estimated_completion TIMESTAMPTZ;
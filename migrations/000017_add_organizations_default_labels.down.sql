@@ -0,0 +1,3 @@
+ALTER TABLE organizations
+    DROP COLUMN IF EXISTS synced_labels,
+    DROP COLUMN IF EXISTS default_labels;
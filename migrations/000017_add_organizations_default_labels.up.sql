@@ -0,0 +1,5 @@
+-- Labels the organization's projects inherit, and the defaults as they were
+-- last synced to its existing projects.
+ALTER TABLE organizations
+    ADD COLUMN default_labels JSONB NOT NULL DEFAULT '{}',
+    ADD COLUMN synced_labels JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1,4 @@
+-- Projects created while provisioning is disabled are unmanaged.
+ALTER TABLE projects DROP CONSTRAINT IF EXISTS projects_status_check;
+ALTER TABLE projects ADD CONSTRAINT projects_status_check
+    CHECK (status IN ('pending', 'provisioning', 'provisioned', 'failed', 'cancelled', 'unmanaged'));
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS events_outbox_sent_idx;
@@ -0,0 +1,3 @@
+-- Lets the relay delete sent events past their retention without scanning
+-- the pending ones.
+CREATE INDEX IF NOT EXISTS events_outbox_sent_idx ON events_outbox (sent_at) WHERE sent_at IS NOT NULL;
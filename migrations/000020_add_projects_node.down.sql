@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS projects_node_idx;
+ALTER TABLE projects DROP COLUMN IF EXISTS node;
@@ -0,0 +1,7 @@
+-- The node the resource backing a project was placed on, as its plugin
+-- reported it once provisioning succeeded. Projects provisioned before it
+-- was recorded are taken to be on the node they asked for, if any.
+ALTER TABLE projects ADD COLUMN node TEXT;
+UPDATE projects SET node = provision_params->>'node' WHERE resource_id IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS projects_node_idx ON projects (node) WHERE deleted_at IS NULL;
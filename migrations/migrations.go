@@ -0,0 +1,11 @@
+// Package migrations embeds the golang-migrate SQL files, so the binary
+// knows which schema version it was built against.
+package migrations
+
+import "embed"
+
+// FS holds the up and down migrations, named NNNNNN_description.up.sql
+// and NNNNNN_description.down.sql.
+//
+//go:embed *.sql
+var FS embed.FS
@@ -38,6 +38,32 @@ func Header(title string) string {
 	return fmt.Sprintf("%s\n  %s\n%s", line, StyleHeader.Render(title), line)
 }
 
+// Banner renders message in a colored box as wide as Header, wrapping it
+// to fit, for notices that must stand out from regular output, e.g. that
+// the API is down for maintenance. level is info, warn, error or success;
+// any other level renders as info.
+// Pure function: returns a string.
+func Banner(message string, level string) string {
+	color := colorInfo
+	switch level {
+	case "warn":
+		color = colorWarn
+	case "error":
+		color = colorError
+	case "success":
+		color = colorSuccess
+	}
+	// Width includes the padding but not the border
+	return lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(color).
+		Foreground(color).
+		Bold(true).
+		Padding(0, 1).
+		Width(lineWidth - 2).
+		Render(message)
+}
+
 // Success renders a COMPLETE status message.
 // Pure function: returns a string.
 func Success(message string) string {
@@ -62,6 +88,17 @@ func Info(message string) string {
 	return fmt.Sprintf("%s: %s", StyleDim.Render("INFO"), message)
 }
 
+// spinnerFrames animate Spinner, one frame per call.
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// Spinner renders frame n of a progress spinner next to message. Callers
+// redraw it in place with an increasing n while work is in progress.
+// Pure function: returns a string.
+func Spinner(n int, message string) string {
+	frame := spinnerFrames[(n%len(spinnerFrames)+len(spinnerFrames))%len(spinnerFrames)]
+	return fmt.Sprintf("%s %s", StyleHeader.Render(frame), message)
+}
+
 // KeyValue renders a key-value pair, left-aligned with fixed key width.
 // Pure function: returns a string.
 func KeyValue(key, value string) string {
@@ -4,6 +4,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/charmbracelet/lipgloss"
 	"github.com/searge/quokka/pkg/display"
 )
 
@@ -18,6 +19,34 @@ func TestHeader(t *testing.T) {
 	}
 }
 
+func TestBanner(t *testing.T) {
+	out := display.Banner("The API is in read-only mode", "warn")
+	if !strings.Contains(out, "The API is in read-only mode") {
+		t.Error("Banner should contain the message")
+	}
+	lines := strings.Split(out, "\n")
+	if len(lines) != 3 {
+		t.Errorf("Banner should box a short message in 3 lines, got %d", len(lines))
+	}
+	if !strings.HasPrefix(lines[0], "╭") || !strings.HasPrefix(lines[2], "╰") {
+		t.Errorf("Banner should draw a box, got\n%s", out)
+	}
+}
+
+func TestBannerWrapsToWidth(t *testing.T) {
+	message := strings.Repeat("maintenance ", 20)
+	out := display.Banner(message, "unknown-level")
+	lines := strings.Split(out, "\n")
+	if len(lines) <= 3 {
+		t.Errorf("Banner should wrap a long message over several lines, got %d", len(lines))
+	}
+	for _, line := range lines {
+		if w := lipgloss.Width(line); w != 64 {
+			t.Errorf("Banner line %q is %d wide, want 64", line, w)
+		}
+	}
+}
+
 func TestSuccess(t *testing.T) {
 	out := display.Success("done")
 	if !strings.Contains(out, "done") {
@@ -34,3 +63,16 @@ func TestKeyValue(t *testing.T) {
 		t.Error("KeyValue should contain both key and value")
 	}
 }
+
+func TestSpinnerCyclesFrames(t *testing.T) {
+	first := display.Spinner(0, "provisioning")
+	if !strings.Contains(first, "provisioning") {
+		t.Error("Spinner should contain the message")
+	}
+	if display.Spinner(1, "provisioning") == first {
+		t.Error("consecutive Spinner frames should differ")
+	}
+	if display.Spinner(10, "provisioning") != first {
+		t.Error("Spinner should wrap around to the first frame")
+	}
+}